@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"strconv"
+
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventSubscriptionHandler 事件订阅处理器（店主/运营/平台通用，各自管理自己的回调地址）
+type EventSubscriptionHandler struct {
+	subscriptionService *services.WebhookSubscriptionService
+}
+
+// NewEventSubscriptionHandler 创建事件订阅处理器
+func NewEventSubscriptionHandler() *EventSubscriptionHandler {
+	return &EventSubscriptionHandler{
+		subscriptionService: services.NewWebhookSubscriptionService(),
+	}
+}
+
+// ListSubscriptions 获取我的事件订阅
+// GET /api/v1/balance/admin/webhooks/subscriptions
+func (h *EventSubscriptionHandler) ListSubscriptions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	subs, err := h.subscriptionService.ListSubscriptions(c.Request.Context(), userID.(int64))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, subs)
+}
+
+// CreateSubscription 登记事件订阅（签名密钥仅在登记和轮换时返回）
+// POST /api/v1/balance/admin/webhooks/subscriptions
+func (h *EventSubscriptionHandler) CreateSubscription(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	userType, _ := c.Get("user_type")
+
+	var req services.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	sub, err := h.subscriptionService.CreateSubscription(c.Request.Context(), userID.(int64), userType.(int8), &req)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, sub)
+}
+
+// UpdateSubscription 修改事件订阅
+// PUT /api/v1/balance/admin/webhooks/subscriptions/:id
+func (h *EventSubscriptionHandler) UpdateSubscription(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的订阅ID")
+		return
+	}
+
+	var req services.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	sub, err := h.subscriptionService.UpdateSubscription(c.Request.Context(), userID.(int64), id, &req)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, sub)
+}
+
+// DeleteSubscription 删除事件订阅
+// DELETE /api/v1/balance/admin/webhooks/subscriptions/:id
+func (h *EventSubscriptionHandler) DeleteSubscription(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的订阅ID")
+		return
+	}
+
+	if err := h.subscriptionService.DeleteSubscription(c.Request.Context(), userID.(int64), id); err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// RotateSecret 轮换签名密钥
+// POST /api/v1/balance/admin/webhooks/subscriptions/:id/rotate-secret
+func (h *EventSubscriptionHandler) RotateSecret(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的订阅ID")
+		return
+	}
+
+	sub, err := h.subscriptionService.RotateSecret(c.Request.Context(), userID.(int64), id)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, sub)
+}
+
+// ListDeliveries 获取投递记录
+// GET /api/v1/balance/admin/webhooks/deliveries?subscription_id=1&status=2&page=1&page_size=20
+func (h *EventSubscriptionHandler) ListDeliveries(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	subscriptionID, _ := strconv.ParseUint(c.Query("subscription_id"), 10, 64)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := h.subscriptionService.ListDeliveries(c.Request.Context(), userID.(int64), subscriptionID, int8(status), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, deliveries, total, page, pageSize)
+}
+
+// Redeliver 重新投递（死信或已送达的记录）
+// POST /api/v1/balance/admin/webhooks/deliveries/:id/redeliver
+func (h *EventSubscriptionHandler) Redeliver(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的投递记录ID")
+		return
+	}
+
+	delivery, err := h.subscriptionService.Redeliver(c.Request.Context(), userID.(int64), id)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, delivery)
+}
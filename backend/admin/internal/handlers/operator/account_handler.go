@@ -22,8 +22,8 @@ func NewAccountHandler() *AccountHandler {
 	}
 }
 
-// GetAccount 获取运营账户信息
-// GET /operator/account
+// GetAccount 获取运营账户信息（按币种，默认 TWD）
+// GET /operator/account?currency=MYR
 func (h *AccountHandler) GetAccount(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -32,7 +32,7 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 	}
 	operatorID := userID.(int64)
 
-	account, err := h.accountService.GetOrCreateOperatorAccount(c.Request.Context(), operatorID)
+	account, err := h.accountService.GetOrCreateOperatorAccount(c.Request.Context(), operatorID, c.Query("currency"))
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -70,6 +70,66 @@ func (h *AccountHandler) GetTransactions(c *gin.Context) {
 	utils.SuccessWithPage(c, transactions, total, page, pageSize)
 }
 
+// GetHolds 获取账户上的风控暂扣
+// GET /operator/account/holds?status=0&page=1&page_size=20
+func (h *AccountHandler) GetHolds(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	holds, total, err := h.accountService.GetHolds(c.Request.Context(), models.AccountTypeOperator, operatorID, int8(status), "", "", page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, holds, total, page, pageSize)
+}
+
+// GetPenalties 获取本账户的罚款/补贴单
+// GET /operator/account/penalties?status=-1&page=1&page_size=20
+func (h *AccountHandler) GetPenalties(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := h.accountService.GetPenaltyBonusEntries(c.Request.Context(), c.Query("type"), models.AccountTypeOperator, operatorID, int8(status), "", "", page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, entries, total, page, pageSize)
+}
+
 // ApplyWithdraw 申请提现
 // POST /operator/withdraw/apply
 func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
@@ -82,6 +142,8 @@ func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
 
 	var req struct {
 		Amount              float64 `json:"amount" binding:"required,gt=0"`
+		Currency            string  `json:"currency"`        // 提现的子账户币种，默认 TWD
+		PayoutCurrency      string  `json:"payout_currency"` // 到账币种，默认与 currency 相同
 		CollectionAccountID uint64  `json:"collection_account_id" binding:"required"`
 		Remark              string  `json:"remark"`
 	}
@@ -92,7 +154,7 @@ func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
 	}
 
 	amount := utils.ToDecimal(req.Amount)
-	application, err := h.accountService.ApplyWithdraw(c.Request.Context(), operatorID, models.AccountTypeOperator, amount, req.CollectionAccountID, req.Remark)
+	application, err := h.accountService.ApplyWithdraw(c.Request.Context(), operatorID, models.AccountTypeOperator, amount, req.Currency, req.PayoutCurrency, req.CollectionAccountID, req.Remark)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -101,6 +163,130 @@ func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
 	utils.Success(c, application)
 }
 
+// QuoteWithdraw 提现试算：申请前展示手续费、到账金额、剩余额度及不能提现的原因
+// GET /operator/withdraw/quote?amount=1000&currency=TWD&payout_currency=TWD&collection_account_id=1
+func (h *AccountHandler) QuoteWithdraw(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	var req struct {
+		Amount              float64 `form:"amount" binding:"required,gt=0"`
+		Currency            string  `form:"currency"`
+		PayoutCurrency      string  `form:"payout_currency"`
+		CollectionAccountID uint64  `form:"collection_account_id"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	quote, err := h.accountService.QuoteWithdraw(c.Request.Context(), operatorID, models.AccountTypeOperator, utils.ToDecimal(req.Amount), req.Currency, req.PayoutCurrency, req.CollectionAccountID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, quote)
+}
+
+// GetAutoWithdrawSettings 获取自动提现设置
+// GET /operator/withdraw/auto
+func (h *AccountHandler) GetAutoWithdrawSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	settings, err := h.accountService.ListAutoWithdrawSettings(c.Request.Context(), operatorID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, settings)
+}
+
+// SaveAutoWithdrawSetting 保存自动提现设置（运营账户，同一币种只有一条，重复保存即修改）
+// POST /operator/withdraw/auto
+func (h *AccountHandler) SaveAutoWithdrawSetting(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	var req struct {
+		Currency            string  `json:"currency"`        // 提现币种，默认 TWD
+		PayoutCurrency      string  `json:"payout_currency"` // 到账币种，默认与 currency 相同
+		CollectionAccountID uint64  `json:"collection_account_id" binding:"required"`
+		Frequency           string  `json:"frequency" binding:"required,oneof=weekly monthly"`
+		DayOfWeek           int     `json:"day_of_week"`  // weekly 时每周几执行（0周日~6周六）
+		DayOfMonth          int     `json:"day_of_month"` // monthly 时每月几号执行（1~28）
+		Threshold           float64 `json:"threshold" binding:"gte=0"`
+		KeepMinimum         float64 `json:"keep_minimum" binding:"gte=0"`
+		Enabled             bool    `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	setting, err := h.accountService.SaveAutoWithdrawSetting(c.Request.Context(), operatorID, services.AutoWithdrawSettingRequest{
+		AccountType:         models.AccountTypeOperator,
+		Currency:            req.Currency,
+		PayoutCurrency:      req.PayoutCurrency,
+		CollectionAccountID: req.CollectionAccountID,
+		Frequency:           req.Frequency,
+		DayOfWeek:           req.DayOfWeek,
+		DayOfMonth:          req.DayOfMonth,
+		Threshold:           utils.ToDecimal(req.Threshold),
+		KeepMinimum:         utils.ToDecimal(req.KeepMinimum),
+		Enabled:             req.Enabled,
+	})
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, setting)
+}
+
+// GetAutoWithdrawRuns 获取自动提现执行记录（含创建的提现申请单号或跳过原因）
+// GET /operator/withdraw/auto/runs
+func (h *AccountHandler) GetAutoWithdrawRuns(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	runs, total, err := h.accountService.GetAutoWithdrawRuns(c.Request.Context(), operatorID, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, runs, total, page, pageSize)
+}
+
 // GetWithdrawApplications 获取提现申请列表
 // GET /operator/withdraw/list?status=-1&page=1&page_size=20
 func (h *AccountHandler) GetWithdrawApplications(c *gin.Context) {
@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+
+	"balance/backend/internal/models"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceHandler 运营结算发票处理器
+type InvoiceHandler struct {
+	invoiceService *services.InvoiceService
+}
+
+// NewInvoiceHandler 创建运营结算发票处理器
+func NewInvoiceHandler() *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: services.NewInvoiceService(),
+	}
+}
+
+// ListInvoices 获取结算发票列表（含按币种汇总）
+// GET /operator/invoices?period_type=monthly&period=2026-09&currency=TWD&status=-1&page=1&page_size=20
+func (h *InvoiceHandler) ListInvoices(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	invoices, total, totals, err := h.invoiceService.ListInvoices(c.Request.Context(), services.InvoiceQuery{
+		PartyType:  models.InvoicePartyOperator,
+		AdminID:    &adminID,
+		PeriodType: c.Query("period_type"),
+		Period:     c.Query("period"),
+		Currency:   c.Query("currency"),
+		Status:     int8(status),
+	}, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"list":      invoices,
+		"totals":    totals,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetInvoice 获取结算发票及明细
+// GET /operator/invoices/:invoice_no
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), c.Param("invoice_no"), models.InvoicePartyOperator, &adminID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, invoice)
+}
+
+// ExportInvoice 下载结算发票（format: csv/html）
+// GET /operator/invoices/:invoice_no/export?format=html
+func (h *InvoiceHandler) ExportInvoice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), c.Param("invoice_no"), models.InvoicePartyOperator, &adminID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", services.InvoiceFormatCSV)
+	data, contentType, err := services.EncodeInvoice(invoice, format)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.File(c, fmt.Sprintf("%s.%s", invoice.InvoiceNo, format), contentType, data)
+}
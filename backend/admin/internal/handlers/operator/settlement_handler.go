@@ -50,6 +50,61 @@ func (h *SettlementHandler) GetSettlements(c *gin.Context) {
 	utils.SuccessWithPage(c, settlements, total, page, pageSize)
 }
 
+// GetSettlementDetail 获取订单结算详情（含调账明细）
+// GET /operator/settlements/detail?shop_id=xxx&order_sn=xxx
+func (h *SettlementHandler) GetSettlementDetail(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	shopID, err := strconv.ParseUint(c.Query("shop_id"), 10, 64)
+	if err != nil || shopID == 0 {
+		utils.BadRequest(c, "店铺ID无效")
+		return
+	}
+	orderSN := c.Query("order_sn")
+	if orderSN == "" {
+		utils.BadRequest(c, "订单编号不能为空")
+		return
+	}
+
+	settlement, err := h.settlementService.GetSettlementDetail(c.Request.Context(), operatorID, "operator", shopID, orderSN)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, settlement)
+}
+
+// PreviewSettlement 预览订单结算分账（不写入数据，可传入假设的结算金额/分成比例）
+// POST /operator/settlements/preview
+func (h *SettlementHandler) PreviewSettlement(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	operatorID := userID.(int64)
+
+	var req services.SettlementPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	preview, err := h.settlementService.PreviewSettlement(c.Request.Context(), operatorID, "operator", &req)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, preview)
+}
+
 // GetSettlementStats 获取结算统计
 // GET /operator/settlements/stats
 func (h *SettlementHandler) GetSettlementStats(c *gin.Context) {
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/models"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentHandler 第三方支付回调处理器（无需登录，由各网关验签或向网关核实）
+type PaymentHandler struct {
+	accountService         *services.AccountService
+	prepaymentCheckService *services.PrepaymentCheckService
+}
+
+// NewPaymentHandler 创建第三方支付回调处理器
+func NewPaymentHandler() *PaymentHandler {
+	return &PaymentHandler{
+		accountService:         services.NewAccountService(),
+		prepaymentCheckService: services.NewPrepaymentCheckService(),
+	}
+}
+
+// Notify 支付网关回调（GET 为用户浏览器跳转，参数在查询字符串；POST 为服务端异步通知，参数在请求体）
+// GET/POST /payment/notify/:method
+func (h *PaymentHandler) Notify(c *gin.Context) {
+	method := c.Param("method")
+
+	var rawBody []byte
+	if c.Request.Method == http.MethodGet {
+		rawBody = []byte(c.Request.URL.RawQuery)
+	} else {
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+		if err != nil {
+			utils.BadRequest(c, "读取回调内容失败")
+			return
+		}
+		rawBody = data
+	}
+
+	record, err := h.accountService.HandlePaymentNotification(c.Request.Context(), method, rawBody)
+	if err != nil {
+		fmt.Printf("[PaymentNotify] 处理回调失败: method=%s, err=%v\n", method, err)
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	// 入账成功后，异步补扣历史「预付款不足」的订单
+	if record.Status == models.ApplicationStatusApproved {
+		go func(ownerID int64) {
+			bgCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			okCount, failCount, backfillErr := h.prepaymentCheckService.BackfillInsufficientOrders(bgCtx, ownerID)
+			if backfillErr != nil {
+				fmt.Printf("[PaymentNotify] 预付款补扣异常: adminID=%d, err=%v\n", ownerID, backfillErr)
+			}
+			if okCount > 0 || failCount > 0 {
+				fmt.Printf("[PaymentNotify] 预付款补扣完成: adminID=%d, 成功=%d, 失败=%d\n", ownerID, okCount, failCount)
+			}
+		}(record.AdminID)
+	}
+
+	// 浏览器跳转回来的回调，处理完后带上结果跳回前端页面
+	if c.Request.Method == http.MethodGet {
+		if returnURL := paymentReturnURL(method); returnURL != "" {
+			query := url.Values{
+				"application_no": {record.ApplicationNo},
+				"status":         {fmt.Sprintf("%d", record.Status)},
+			}
+			c.Redirect(http.StatusFound, returnURL+"?"+query.Encode())
+			return
+		}
+	}
+
+	utils.Success(c, gin.H{
+		"application_no": record.ApplicationNo,
+		"status":         record.Status,
+	})
+}
+
+// paymentReturnURL 支付完成后跳转的前端页面（按支付方式配置）
+func paymentReturnURL(method string) string {
+	cfg := config.Get()
+	if cfg == nil {
+		return ""
+	}
+	switch method {
+	case utils.PayMethodLinePay:
+		return cfg.Payment.LinePay.ReturnURL
+	default:
+		return ""
+	}
+}
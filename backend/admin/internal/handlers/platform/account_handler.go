@@ -3,6 +3,7 @@ package platform
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"balance/backend/internal/database"
 	"balance/backend/internal/models"
@@ -16,20 +17,22 @@ import (
 
 // AccountHandler 平台账户管理处理器
 type AccountHandler struct {
-	db             *gorm.DB
-	accountService *services.AccountService
+	db                    *gorm.DB
+	accountService        *services.AccountService
+	reconciliationService *services.ReconciliationService
 }
 
 // NewAccountHandler 创建平台账户管理处理器
 func NewAccountHandler() *AccountHandler {
 	return &AccountHandler{
-		db:             database.GetDB(),
-		accountService: services.NewAccountService(),
+		db:                    database.GetDB(),
+		accountService:        services.NewAccountService(),
+		reconciliationService: services.NewReconciliationService(),
 	}
 }
 
 // ListPrepaymentAccounts 获取预付款账户列表
-// GET /platform/accounts/prepayment?page=1&page_size=20
+// GET /platform/accounts/prepayment?page=1&page_size=20&currency=MYR
 func (h *AccountHandler) ListPrepaymentAccounts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
@@ -44,10 +47,14 @@ func (h *AccountHandler) ListPrepaymentAccounts(c *gin.Context) {
 	var accounts []models.PrepaymentAccount
 	var total int64
 
-	h.db.Model(&models.PrepaymentAccount{}).Count(&total)
+	query := h.db.Model(&models.PrepaymentAccount{})
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ?", services.NormalizeCurrency(currency))
+	}
+	query.Count(&total)
 
 	offset := (page - 1) * pageSize
-	if err := h.db.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&accounts).Error; err != nil {
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&accounts).Error; err != nil {
 		utils.Error(c, 500, err.Error())
 		return
 	}
@@ -134,10 +141,14 @@ func (h *AccountHandler) ListOperatorAccounts(c *gin.Context) {
 	var accounts []models.OperatorAccount
 	var total int64
 
-	h.db.Model(&models.OperatorAccount{}).Count(&total)
+	query := h.db.Model(&models.OperatorAccount{})
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ?", services.NormalizeCurrency(currency))
+	}
+	query.Count(&total)
 
 	offset := (page - 1) * pageSize
-	if err := h.db.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&accounts).Error; err != nil {
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&accounts).Error; err != nil {
 		utils.Error(c, 500, err.Error())
 		return
 	}
@@ -165,9 +176,11 @@ func (h *AccountHandler) ListOperatorAccounts(c *gin.Context) {
 
 // RechargeRequest 充值请求
 type RechargeRequest struct {
-	AdminID int64   `json:"admin_id" binding:"required"`
-	Amount  float64 `json:"amount" binding:"required,gt=0"`
-	Remark  string  `json:"remark"`
+	AdminID        int64   `json:"admin_id" binding:"required"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	Currency       string  `json:"currency"` // 充值币种，默认 TWD
+	Remark         string  `json:"remark"`
+	IdempotencyKey string  `json:"idempotency_key"` // 可选：客户端重试时携带相同的键，不会重复入账
 }
 
 // RechargePrepayment 预付款充值
@@ -185,7 +198,12 @@ func (h *AccountHandler) RechargePrepayment(c *gin.Context) {
 		remark = "平台充值"
 	}
 
-	tx, err := h.accountService.RechargePrepayment(c.Request.Context(), req.AdminID, amount, remark, 0)
+	idempotencyKey := ""
+	if req.IdempotencyKey != "" {
+		idempotencyKey = "platform_recharge:" + req.IdempotencyKey
+	}
+
+	tx, err := h.accountService.RechargePrepayment(c.Request.Context(), req.AdminID, amount, req.Currency, remark, 0, idempotencyKey)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -196,9 +214,10 @@ func (h *AccountHandler) RechargePrepayment(c *gin.Context) {
 
 // PayDepositRequest 缴纳保证金请求
 type PayDepositRequest struct {
-	AdminID int64   `json:"admin_id" binding:"required"`
-	Amount  float64 `json:"amount" binding:"required,gt=0"`
-	Remark  string  `json:"remark"`
+	AdminID        int64   `json:"admin_id" binding:"required"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	Remark         string  `json:"remark"`
+	IdempotencyKey string  `json:"idempotency_key"` // 可选：客户端重试时携带相同的键，不会重复入账
 }
 
 // PayDeposit 缴纳保证金
@@ -216,7 +235,70 @@ func (h *AccountHandler) PayDeposit(c *gin.Context) {
 		remark = "保证金缴纳"
 	}
 
-	tx, err := h.accountService.PayDeposit(c.Request.Context(), req.AdminID, amount, remark, 0)
+	idempotencyKey := ""
+	if req.IdempotencyKey != "" {
+		idempotencyKey = "platform_deposit:" + req.IdempotencyKey
+	}
+
+	tx, err := h.accountService.PayDeposit(c.Request.Context(), req.AdminID, amount, remark, 0, idempotencyKey)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, tx)
+}
+
+// SetDepositRequiredAmount 设置店主应缴保证金（余额低于应缴金额时账户自动变为不足）
+// POST /platform/accounts/deposit/required
+func (h *AccountHandler) SetDepositRequiredAmount(c *gin.Context) {
+	var req struct {
+		AdminID        int64   `json:"admin_id" binding:"required"`
+		RequiredAmount float64 `json:"required_amount" binding:"gte=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 400, "参数错误: "+err.Error())
+		return
+	}
+
+	account, err := h.accountService.SetDepositRequiredAmount(c.Request.Context(), req.AdminID, decimal.NewFromFloat(req.RequiredAmount))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, account)
+}
+
+// DeductDepositRequest 从保证金扣除罚款请求
+type DeductDepositRequest struct {
+	AdminID        int64   `json:"admin_id" binding:"required"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	Remark         string  `json:"remark" binding:"required"`
+	IdempotencyKey string  `json:"idempotency_key"` // 可选：客户端重试时携带相同的键，不会重复扣除
+}
+
+// DeductDeposit 从保证金扣除罚款
+// POST /platform/accounts/deposit/deduct
+func (h *AccountHandler) DeductDeposit(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req DeductDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 400, "参数错误: "+err.Error())
+		return
+	}
+
+	idempotencyKey := ""
+	if req.IdempotencyKey != "" {
+		idempotencyKey = services.DepositDeductIdempotencyKey(req.IdempotencyKey)
+	}
+
+	tx, err := h.accountService.DeductDeposit(c.Request.Context(), req.AdminID, decimal.NewFromFloat(req.Amount), req.Remark, userID.(int64), idempotencyKey)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -284,29 +366,35 @@ func (h *AccountHandler) GetAccountTransactions(c *gin.Context) {
 	utils.SuccessWithPage(c, allTransactions, total, page, pageSize)
 }
 
-// GetAccountStats 获取账户统计
-// GET /platform/accounts/stats
+// GetAccountStats 获取账户统计（按币种汇总，不同币种金额不相加）
+// GET /platform/accounts/stats?currency=MYR
 func (h *AccountHandler) GetAccountStats(c *gin.Context) {
+	currency := services.NormalizeCurrency(c.Query("currency"))
+	byCurrency := func(model interface{}) *gorm.DB {
+		return h.db.Model(model).Where("currency = ?", currency)
+	}
+
 	var prepaymentTotal, depositTotal, operatorTotal decimal.Decimal
 
-	h.db.Model(&models.PrepaymentAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&prepaymentTotal)
-	h.db.Model(&models.DepositAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&depositTotal)
-	h.db.Model(&models.OperatorAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&operatorTotal)
+	byCurrency(&models.PrepaymentAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&prepaymentTotal)
+	byCurrency(&models.DepositAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&depositTotal)
+	byCurrency(&models.OperatorAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&operatorTotal)
 
 	var prepaymentCount, depositCount, operatorCount int64
-	h.db.Model(&models.PrepaymentAccount{}).Count(&prepaymentCount)
-	h.db.Model(&models.DepositAccount{}).Count(&depositCount)
-	h.db.Model(&models.OperatorAccount{}).Count(&operatorCount)
+	byCurrency(&models.PrepaymentAccount{}).Count(&prepaymentCount)
+	byCurrency(&models.DepositAccount{}).Count(&depositCount)
+	byCurrency(&models.OperatorAccount{}).Count(&operatorCount)
 
 	// 获取平台佣金账户
-	platformCommission, _ := h.accountService.GetOrCreatePlatformCommissionAccount(c.Request.Context())
+	platformCommission, _ := h.accountService.GetOrCreatePlatformCommissionAccount(c.Request.Context(), currency)
 	// 获取店主佣金账户总额
 	var shopOwnerCommissionTotal decimal.Decimal
 	var shopOwnerCommissionCount int64
-	h.db.Model(&models.ShopOwnerCommissionAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&shopOwnerCommissionTotal)
-	h.db.Model(&models.ShopOwnerCommissionAccount{}).Count(&shopOwnerCommissionCount)
+	byCurrency(&models.ShopOwnerCommissionAccount{}).Select("COALESCE(SUM(balance), 0)").Scan(&shopOwnerCommissionTotal)
+	byCurrency(&models.ShopOwnerCommissionAccount{}).Count(&shopOwnerCommissionCount)
 
 	utils.Success(c, gin.H{
+		"currency": currency,
 		"prepayment": gin.H{
 			"total_balance": prepaymentTotal,
 			"account_count": prepaymentCount,
@@ -374,13 +462,14 @@ func (h *AccountHandler) ApproveWithdraw(c *gin.Context) {
 		return
 	}
 
-	err := h.accountService.ApproveWithdraw(c.Request.Context(), req.ApplicationID, auditBy, req.AuditRemark)
+	progress, err := h.accountService.ApproveWithdraw(c.Request.Context(), req.ApplicationID, auditBy, req.AuditRemark)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
 	}
 
-	utils.Success(c, nil)
+	// 未达到所需审批人数时申请仍为待审核，completed 为 false
+	utils.Success(c, progress)
 }
 
 // RejectWithdraw 拒绝提现
@@ -412,6 +501,292 @@ func (h *AccountHandler) RejectWithdraw(c *gin.Context) {
 	utils.Success(c, nil)
 }
 
+// GetDepositRefundApplications 获取保证金退还申请列表
+// GET /platform/deposit/refund/list?admin_id=1&status=-1&page=1&page_size=20
+func (h *AccountHandler) GetDepositRefundApplications(c *gin.Context) {
+	adminID, _ := strconv.ParseInt(c.Query("admin_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	applications, total, err := h.accountService.GetDepositRefundApplications(c.Request.Context(), adminID, int8(status), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, applications, total, page, pageSize)
+}
+
+// ApproveDepositRefund 审批通过保证金退还（通过即出账）
+// POST /platform/deposit/refund/approve
+func (h *AccountHandler) ApproveDepositRefund(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		ApplicationID uint64 `json:"application_id" binding:"required"`
+		AuditRemark   string `json:"audit_remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	application, err := h.accountService.ApproveDepositRefund(c.Request.Context(), req.ApplicationID, userID.(int64), req.AuditRemark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, application)
+}
+
+// RejectDepositRefund 拒绝保证金退还
+// POST /platform/deposit/refund/reject
+func (h *AccountHandler) RejectDepositRefund(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		ApplicationID uint64 `json:"application_id" binding:"required"`
+		AuditRemark   string `json:"audit_remark" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.accountService.RejectDepositRefund(c.Request.Context(), req.ApplicationID, userID.(int64), req.AuditRemark); err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// CreateHoldRequest 创建风控暂扣请求
+type CreateHoldRequest struct {
+	AccountType string     `json:"account_type" binding:"required,oneof=operator shop_owner_commission"`
+	AdminID     int64      `json:"admin_id" binding:"required"`
+	Currency    string     `json:"currency"`
+	Amount      float64    `json:"amount" binding:"required,gt=0"`
+	ShopID      uint64     `json:"shop_id"`
+	OrderSN     string     `json:"order_sn"`
+	ReturnSN    string     `json:"return_sn"`
+	Reason      string     `json:"reason" binding:"required"`
+	ExpiresAt   *time.Time `json:"expires_at"` // 可选：到期自动释放（RFC3339）
+}
+
+// GetHolds 获取风控暂扣列表
+// GET /platform/accounts/holds?account_type=operator&admin_id=1&status=0&order_sn=xxx&return_sn=xxx&page=1&page_size=20
+func (h *AccountHandler) GetHolds(c *gin.Context) {
+	adminID, _ := strconv.ParseInt(c.Query("admin_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	holds, total, err := h.accountService.GetHolds(c.Request.Context(), c.Query("account_type"), adminID, int8(status),
+		c.Query("order_sn"), c.Query("return_sn"), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, holds, total, page, pageSize)
+}
+
+// CreateHold 创建风控暂扣（争议期间暂扣运营/店主佣金账户的部分金额，暂扣金额不可提现）
+// POST /platform/accounts/holds
+func (h *AccountHandler) CreateHold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req CreateHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 400, "参数错误: "+err.Error())
+		return
+	}
+
+	hold, err := h.accountService.CreateHold(c.Request.Context(), services.AccountHoldRequest{
+		AccountType: req.AccountType,
+		AdminID:     req.AdminID,
+		Currency:    req.Currency,
+		Amount:      decimal.NewFromFloat(req.Amount),
+		ShopID:      req.ShopID,
+		OrderSN:     req.OrderSN,
+		ReturnSN:    req.ReturnSN,
+		Reason:      req.Reason,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   userID.(int64),
+	})
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, hold)
+}
+
+// ReleaseHold 手动释放风控暂扣
+// POST /platform/accounts/holds/release
+func (h *AccountHandler) ReleaseHold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		HoldID uint64 `json:"hold_id" binding:"required"`
+		Remark string `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	hold, err := h.accountService.ReleaseHold(c.Request.Context(), req.HoldID, userID.(int64), req.Remark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, hold)
+}
+
+// GetReversals 获取流水冲正申请列表
+// GET /platform/accounts/reversals?status=0&transaction_no=xxx&page=1&page_size=20
+func (h *AccountHandler) GetReversals(c *gin.Context) {
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	reversals, total, err := h.accountService.GetReversals(c.Request.Context(), int8(status), c.Query("transaction_no"), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, reversals, total, page, pageSize)
+}
+
+// RequestReversal 发起流水冲正申请（整笔冲正，需其他平台用户审批）
+// POST /platform/accounts/reversals
+func (h *AccountHandler) RequestReversal(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		TransactionNo string `json:"transaction_no" binding:"required"`
+		Reason        string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	reversal, err := h.accountService.RequestReversal(c.Request.Context(), req.TransactionNo, req.Reason, userID.(int64))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, reversal)
+}
+
+// ApproveReversal 审批通过流水冲正
+// POST /platform/accounts/reversals/approve
+func (h *AccountHandler) ApproveReversal(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		ApplicationID uint64 `json:"application_id" binding:"required"`
+		AuditRemark   string `json:"audit_remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	reversal, err := h.accountService.ApproveReversal(c.Request.Context(), req.ApplicationID, userID.(int64), req.AuditRemark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, reversal)
+}
+
+// RejectReversal 拒绝流水冲正
+// POST /platform/accounts/reversals/reject
+func (h *AccountHandler) RejectReversal(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		ApplicationID uint64 `json:"application_id" binding:"required"`
+		AuditRemark   string `json:"audit_remark" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.accountService.RejectReversal(c.Request.Context(), req.ApplicationID, userID.(int64), req.AuditRemark); err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
 // ConfirmWithdrawPaid 确认提现已打款
 // POST /platform/withdraw/confirm_paid
 func (h *AccountHandler) ConfirmWithdrawPaid(c *gin.Context) {
@@ -440,10 +815,10 @@ func (h *AccountHandler) ConfirmWithdrawPaid(c *gin.Context) {
 	utils.Success(c, nil)
 }
 
-// GetPlatformCommissionAccount 获取平台佣金账户
-// GET /platform/account/commission
+// GetPlatformCommissionAccount 获取平台佣金账户（按币种，默认 TWD）
+// GET /platform/account/commission?currency=MYR
 func (h *AccountHandler) GetPlatformCommissionAccount(c *gin.Context) {
-	account, err := h.accountService.GetOrCreatePlatformCommissionAccount(c.Request.Context())
+	account, err := h.accountService.GetOrCreatePlatformCommissionAccount(c.Request.Context(), c.Query("currency"))
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -475,6 +850,72 @@ func (h *AccountHandler) GetPlatformCommissionTransactions(c *gin.Context) {
 	utils.SuccessWithPage(c, transactions, total, page, pageSize)
 }
 
+// ListJournals 获取记账凭证列表
+// GET /platform/accounts/journals?biz_type=settlement&order_sn=xxx&page=1&page_size=20
+func (h *AccountHandler) ListJournals(c *gin.Context) {
+	bizType := c.Query("biz_type")
+	orderSN := c.Query("order_sn")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	journals, total, err := h.accountService.GetJournals(c.Request.Context(), bizType, orderSN, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, journals, total, page, pageSize)
+}
+
+// GetJournalDetail 获取记账凭证详情（含借贷分录）
+// GET /platform/accounts/journals/:journal_no
+func (h *AccountHandler) GetJournalDetail(c *gin.Context) {
+	journal, err := h.accountService.GetJournalDetail(c.Request.Context(), c.Param("journal_no"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, journal)
+}
+
+// GetReconciliation 获取账户对账差异报告（默认最近一次已完成的对账批次）
+// GET /platform/accounts/reconciliation?run_no=xxx&account_type=operator&page=1&page_size=20
+func (h *AccountHandler) GetReconciliation(c *gin.Context) {
+	runNo := c.Query("run_no")
+	accountType := c.Query("account_type")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	run, drifts, total, err := h.reconciliationService.GetDrifts(c.Request.Context(), runNo, accountType, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"run":       run,
+		"list":      drifts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
 // GetRechargeRecords 获取充值申请列表 (平台审核)
 // GET /platform/recharge/list?status=0&page=1&page_size=20
 func (h *AccountHandler) GetRechargeRecords(c *gin.Context) {
@@ -519,13 +960,14 @@ func (h *AccountHandler) ApproveRecharge(c *gin.Context) {
 		return
 	}
 
-	err := h.accountService.ApproveRecharge(c.Request.Context(), req.ApplicationID, auditBy, req.AuditRemark)
+	progress, err := h.accountService.ApproveRecharge(c.Request.Context(), req.ApplicationID, auditBy, req.AuditRemark)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
 	}
 
-	utils.Success(c, nil)
+	// 未达到所需审批人数时申请仍为待审核，completed 为 false
+	utils.Success(c, progress)
 }
 
 // RejectRecharge 拒绝充值
@@ -556,3 +998,43 @@ func (h *AccountHandler) RejectRecharge(c *gin.Context) {
 
 	utils.Success(c, nil)
 }
+
+// GetPendingApprovals 审批收件箱：当前平台用户可审批的提现、线下充值和财务审核流水（不含本人申请和本人已审批的）
+// GET /platform/approvals/pending
+func (h *AccountHandler) GetPendingApprovals(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	approverID := userID.(int64)
+
+	items, err := h.accountService.GetPendingApprovals(c.Request.Context(), approverID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, items)
+}
+
+// GetApprovalTrail 获取申请的多级审批轨迹
+// GET /platform/approvals/trail?operation=withdraw&target_no=WD1234567890
+func (h *AccountHandler) GetApprovalTrail(c *gin.Context) {
+	var req struct {
+		Operation string `form:"operation" binding:"required,oneof=withdraw recharge finance_audit"`
+		TargetNo  string `form:"target_no" binding:"required"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	records, err := h.accountService.GetApprovalTrail(c.Request.Context(), req.Operation, req.TargetNo)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, records)
+}
@@ -2,6 +2,7 @@ package platform
 
 import (
 	"strconv"
+	"time"
 
 	"balance/backend/internal/database"
 	"balance/backend/internal/models"
@@ -56,8 +57,8 @@ func (h *CollectionHandler) GetCollectionAccounts(c *gin.Context) {
 		walletList = append(walletList, gin.H{
 			"id":         w.ID,
 			"name":       w.AccountName,
-			"account":    w.AccountNo,
-			"payee":      w.Payee,
+			"account":    utils.MaskAccountNo(w.AccountNo),
+			"payee":      utils.MaskName(w.Payee),
 			"status":     status,
 			"is_default": w.IsDefault,
 		})
@@ -72,8 +73,8 @@ func (h *CollectionHandler) GetCollectionAccounts(c *gin.Context) {
 		bankList = append(bankList, gin.H{
 			"id":          b.ID,
 			"name":        b.BankName,
-			"account":     b.AccountNo,
-			"payee":       b.Payee,
+			"account":     utils.MaskAccountNo(b.AccountNo),
+			"payee":       utils.MaskName(b.Payee),
 			"bank_branch": b.BankBranch,
 			"status":      status,
 			"is_default":  b.IsDefault,
@@ -161,6 +162,13 @@ func (h *CollectionHandler) UpdateCollectionAccount(c *gin.Context) {
 		return
 	}
 
+	// 收款信息变更后重新计算提现冷却期
+	if account.AccountName != req.AccountName || account.AccountNo != req.AccountNo || account.BankName != req.BankName ||
+		account.BankBranch != req.BankBranch || account.Payee != req.Payee {
+		now := time.Now()
+		account.ChangedAt = &now
+	}
+
 	account.AccountName = req.AccountName
 	account.AccountNo = req.AccountNo
 	account.BankName = req.BankName
@@ -6,6 +6,7 @@ import (
 
 	"balance/backend/internal/database"
 	"balance/backend/internal/models"
+	"balance/backend/internal/services"
 	"balance/backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,13 +16,15 @@ import (
 
 // CooperationHandler 合作管理处理器（店铺-运营分配）
 type CooperationHandler struct {
-	db *gorm.DB
+	db                *gorm.DB
+	settlementService *services.SettlementService
 }
 
 // NewCooperationHandler 创建合作管理处理器
 func NewCooperationHandler() *CooperationHandler {
 	return &CooperationHandler{
-		db: database.GetDB(),
+		db:                database.GetDB(),
+		settlementService: services.NewSettlementService(),
 	}
 }
 
@@ -106,16 +109,11 @@ func (h *CooperationHandler) ListCooperations(c *gin.Context) {
 			item.OperatorName = operator.UserName
 		}
 
-		// 获取分成配置
-		var config models.ProfitShareConfig
-		if h.db.Where("shop_id = ? AND operator_id = ? AND status = 1", r.ShopID, r.OperatorID).First(&config).Error == nil {
-			item.PlatformShareRate = config.PlatformShareRate.String()
-			item.OperatorShareRate = config.OperatorShareRate.String()
-			item.ShopOwnerShareRate = config.ShopOwnerShareRate.String()
-		} else {
-			item.PlatformShareRate = "5.00"
-			item.OperatorShareRate = "45.00"
-			item.ShopOwnerShareRate = "50.00"
+		// 获取当前生效的分成配置（未配置时为默认比例）
+		if config, err := h.settlementService.GetEffectiveProfitShareConfig(c.Request.Context(), r.ShopID, r.OperatorID, time.Time{}); err == nil {
+			item.PlatformShareRate = config.PlatformShareRate.StringFixed(2)
+			item.OperatorShareRate = config.OperatorShareRate.StringFixed(2)
+			item.ShopOwnerShareRate = config.ShopOwnerShareRate.StringFixed(2)
 		}
 
 		// 状态文本
@@ -177,6 +175,25 @@ func (h *CooperationHandler) CreateCooperation(c *gin.Context) {
 		return
 	}
 
+	// 分成比例（未传的使用默认值）
+	platformRate := decimal.NewFromFloat(5.00)
+	operatorRate := decimal.NewFromFloat(45.00)
+	shopOwnerRate := decimal.NewFromFloat(50.00)
+
+	if req.PlatformShareRate > 0 {
+		platformRate = decimal.NewFromFloat(req.PlatformShareRate)
+	}
+	if req.OperatorShareRate > 0 {
+		operatorRate = decimal.NewFromFloat(req.OperatorShareRate)
+	}
+	if req.ShopOwnerShareRate > 0 {
+		shopOwnerRate = decimal.NewFromFloat(req.ShopOwnerShareRate)
+	}
+	if err := services.ValidateProfitShareRates(platformRate, operatorRate, shopOwnerRate); err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
 	// 检查是否已存在合作关系
 	var existing models.ShopOperatorRelation
 	if err := h.db.Where("shop_id = ? AND operator_id = ?", req.ShopID, req.OperatorID).First(&existing).Error; err == nil {
@@ -205,44 +222,34 @@ func (h *CooperationHandler) CreateCooperation(c *gin.Context) {
 		return
 	}
 
-	// 创建分成配置
-	platformRate := decimal.NewFromFloat(5.00)
-	operatorRate := decimal.NewFromFloat(45.00)
-	shopOwnerRate := decimal.NewFromFloat(50.00)
-
-	if req.PlatformShareRate > 0 {
-		platformRate = decimal.NewFromFloat(req.PlatformShareRate)
-	}
-	if req.OperatorShareRate > 0 {
-		operatorRate = decimal.NewFromFloat(req.OperatorShareRate)
-	}
-	if req.ShopOwnerShareRate > 0 {
-		shopOwnerRate = decimal.NewFromFloat(req.ShopOwnerShareRate)
-	}
-
+	// 创建首个分成配置版本（立即生效）
 	config := &models.ProfitShareConfig{
 		ShopID:             req.ShopID,
 		OperatorID:         req.OperatorID,
 		PlatformShareRate:  platformRate,
 		OperatorShareRate:  operatorRate,
 		ShopOwnerShareRate: shopOwnerRate,
-		Status:             1,
-		EffectiveFrom:      time.Now(),
+		Remark:             "创建合作",
+		CreatedBy:          c.GetInt64("user_id"),
+	}
+	if err := h.settlementService.CreateProfitShareConfig(c.Request.Context(), config); err != nil {
+		utils.Error(c, 500, "创建分成配置失败: "+err.Error())
+		return
 	}
-	h.db.Create(config)
 
 	utils.Success(c, relation)
 }
 
 // UpdateCooperationRequest 更新合作请求
 type UpdateCooperationRequest struct {
-	Status             *int8   `json:"status"`
-	PlatformShareRate  float64 `json:"platform_share_rate"`
-	OperatorShareRate  float64 `json:"operator_share_rate"`
-	ShopOwnerShareRate float64 `json:"shop_owner_share_rate"`
+	Status             *int8      `json:"status"`
+	PlatformShareRate  float64    `json:"platform_share_rate"`
+	OperatorShareRate  float64    `json:"operator_share_rate"`
+	ShopOwnerShareRate float64    `json:"shop_owner_share_rate"`
+	EffectiveFrom      *time.Time `json:"effective_from"` // 新比例生效时间（RFC3339），为空立即生效
 }
 
-// UpdateCooperation 更新合作关系
+// UpdateCooperation 更新合作关系（调整分成比例时新增配置版本，不修改已有版本）
 // PUT /platform/cooperations/:id
 func (h *CooperationHandler) UpdateCooperation(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -263,29 +270,45 @@ func (h *CooperationHandler) UpdateCooperation(c *gin.Context) {
 		return
 	}
 
+	// 调整分成配置：以当前生效版本为基础新增版本
+	if req.PlatformShareRate > 0 || req.OperatorShareRate > 0 || req.ShopOwnerShareRate > 0 {
+		current, err := h.settlementService.GetEffectiveProfitShareConfig(c.Request.Context(), relation.ShopID, relation.OperatorID, time.Time{})
+		if err != nil {
+			utils.Error(c, 500, err.Error())
+			return
+		}
+		config := &models.ProfitShareConfig{
+			ShopID:             relation.ShopID,
+			OperatorID:         relation.OperatorID,
+			PlatformShareRate:  current.PlatformShareRate,
+			OperatorShareRate:  current.OperatorShareRate,
+			ShopOwnerShareRate: current.ShopOwnerShareRate,
+			CreatedBy:          c.GetInt64("user_id"),
+		}
+		if req.PlatformShareRate > 0 {
+			config.PlatformShareRate = decimal.NewFromFloat(req.PlatformShareRate)
+		}
+		if req.OperatorShareRate > 0 {
+			config.OperatorShareRate = decimal.NewFromFloat(req.OperatorShareRate)
+		}
+		if req.ShopOwnerShareRate > 0 {
+			config.ShopOwnerShareRate = decimal.NewFromFloat(req.ShopOwnerShareRate)
+		}
+		if req.EffectiveFrom != nil {
+			config.EffectiveFrom = *req.EffectiveFrom
+		}
+		if err := h.settlementService.ScheduleProfitShareConfig(c.Request.Context(), config); err != nil {
+			utils.Error(c, 400, err.Error())
+			return
+		}
+	}
+
 	// 更新状态
 	if req.Status != nil {
 		relation.Status = *req.Status
 	}
 	h.db.Save(&relation)
 
-	// 更新分成配置
-	if req.PlatformShareRate > 0 || req.OperatorShareRate > 0 || req.ShopOwnerShareRate > 0 {
-		var config models.ProfitShareConfig
-		if h.db.Where("shop_id = ? AND operator_id = ? AND status = 1", relation.ShopID, relation.OperatorID).First(&config).Error == nil {
-			if req.PlatformShareRate > 0 {
-				config.PlatformShareRate = decimal.NewFromFloat(req.PlatformShareRate)
-			}
-			if req.OperatorShareRate > 0 {
-				config.OperatorShareRate = decimal.NewFromFloat(req.OperatorShareRate)
-			}
-			if req.ShopOwnerShareRate > 0 {
-				config.ShopOwnerShareRate = decimal.NewFromFloat(req.ShopOwnerShareRate)
-			}
-			h.db.Save(&config)
-		}
-	}
-
 	utils.Success(c, relation)
 }
 
@@ -310,6 +333,211 @@ func (h *CooperationHandler) CancelCooperation(c *gin.Context) {
 	utils.Success(c, gin.H{"message": "合作关系已取消"})
 }
 
+// GetProfitShareConfigs 获取合作的分成配置版本历史
+// GET /platform/cooperations/:id/profit-share
+func (h *CooperationHandler) GetProfitShareConfigs(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的ID")
+		return
+	}
+
+	var relation models.ShopOperatorRelation
+	if err := h.db.First(&relation, id).Error; err != nil {
+		utils.Error(c, 404, "合作关系不存在")
+		return
+	}
+
+	configs, err := h.settlementService.GetProfitShareConfigHistory(c.Request.Context(), relation.ShopID, relation.OperatorID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+	current, err := h.settlementService.GetEffectiveProfitShareConfig(c.Request.Context(), relation.ShopID, relation.OperatorID, time.Time{})
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"current":  current,
+		"versions": configs,
+	})
+}
+
+// ScheduleProfitShareConfigRequest 排期分成比例调整请求
+type ScheduleProfitShareConfigRequest struct {
+	PlatformShareRate  float64    `json:"platform_share_rate"`
+	OperatorShareRate  float64    `json:"operator_share_rate"`
+	ShopOwnerShareRate float64    `json:"shop_owner_share_rate"`
+	EffectiveFrom      *time.Time `json:"effective_from"` // 生效时间（RFC3339），为空立即生效
+	Remark             string     `json:"remark"`
+}
+
+// ScheduleProfitShareConfig 排期分成比例调整（新增版本，三项比例之和须为100）
+// POST /platform/cooperations/:id/profit-share
+func (h *CooperationHandler) ScheduleProfitShareConfig(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的ID")
+		return
+	}
+
+	var req ScheduleProfitShareConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 400, "参数错误: "+err.Error())
+		return
+	}
+
+	var relation models.ShopOperatorRelation
+	if err := h.db.First(&relation, id).Error; err != nil {
+		utils.Error(c, 404, "合作关系不存在")
+		return
+	}
+
+	config := &models.ProfitShareConfig{
+		ShopID:             relation.ShopID,
+		OperatorID:         relation.OperatorID,
+		PlatformShareRate:  decimal.NewFromFloat(req.PlatformShareRate),
+		OperatorShareRate:  decimal.NewFromFloat(req.OperatorShareRate),
+		ShopOwnerShareRate: decimal.NewFromFloat(req.ShopOwnerShareRate),
+		Remark:             req.Remark,
+		CreatedBy:          c.GetInt64("user_id"),
+	}
+	if req.EffectiveFrom != nil {
+		config.EffectiveFrom = *req.EffectiveFrom
+	}
+	if err := h.settlementService.ScheduleProfitShareConfig(c.Request.Context(), config); err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, config)
+}
+
+// CancelProfitShareConfig 撤销未生效的分成配置版本
+// DELETE /platform/cooperations/:id/profit-share/:config_id
+func (h *CooperationHandler) CancelProfitShareConfig(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的ID")
+		return
+	}
+	configID, err := strconv.ParseUint(c.Param("config_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的配置ID")
+		return
+	}
+
+	var relation models.ShopOperatorRelation
+	if err := h.db.First(&relation, id).Error; err != nil {
+		utils.Error(c, 404, "合作关系不存在")
+		return
+	}
+	var config models.ProfitShareConfig
+	if err := h.db.Where("id = ? AND shop_id = ? AND operator_id = ?", configID, relation.ShopID, relation.OperatorID).First(&config).Error; err != nil {
+		utils.Error(c, 404, "分成配置不存在")
+		return
+	}
+
+	cancelled, err := h.settlementService.CancelProfitShareConfig(c.Request.Context(), config.ID)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, cancelled)
+}
+
+// GetProfitShareRules 获取合作的分成规则（当前生效规则 + 历史）
+// GET /platform/cooperations/:id/profit-share-rules
+func (h *CooperationHandler) GetProfitShareRules(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的ID")
+		return
+	}
+
+	rules, err := h.settlementService.GetProfitShareRules(c.Request.Context(), id)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+	var current *models.ProfitShareRule
+	for i := range rules {
+		if rules[i].Status == models.ProfitShareRuleActive {
+			current = &rules[i]
+			break
+		}
+	}
+
+	utils.Success(c, gin.H{
+		"current": current,
+		"rules":   rules,
+	})
+}
+
+// CreateProfitShareRuleRequest 设置分成规则请求
+type CreateProfitShareRuleRequest struct {
+	Name             string                   `json:"name"`
+	Tiers            []models.ProfitShareTier `json:"tiers"`              // 利润分档，为空则按分成配置比例
+	MinPlatformFee   decimal.Decimal          `json:"min_platform_fee"`   // 平台每单最低分成
+	MaxPlatformFee   decimal.Decimal          `json:"max_platform_fee"`   // 平台每单最高分成，0为不封顶
+	FixedPlatformFee decimal.Decimal          `json:"fixed_platform_fee"` // 平台每单固定费用
+	LossPolicy       string                   `json:"loss_policy"`        // proportional/operator/shop_owner/platform/operator_shop_owner
+	Remark           string                   `json:"remark"`
+}
+
+// CreateProfitShareRule 设置合作的分成规则（替换当前规则，之后结算的订单生效）
+// POST /platform/cooperations/:id/profit-share-rules
+func (h *CooperationHandler) CreateProfitShareRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的ID")
+		return
+	}
+
+	var req CreateProfitShareRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 400, "参数错误: "+err.Error())
+		return
+	}
+
+	rule := &models.ProfitShareRule{
+		Name:             req.Name,
+		TierList:         req.Tiers,
+		MinPlatformFee:   req.MinPlatformFee,
+		MaxPlatformFee:   req.MaxPlatformFee,
+		FixedPlatformFee: req.FixedPlatformFee,
+		LossPolicy:       req.LossPolicy,
+		Remark:           req.Remark,
+		CreatedBy:        c.GetInt64("user_id"),
+	}
+	if err := h.settlementService.CreateProfitShareRule(c.Request.Context(), id, rule); err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, rule)
+}
+
+// DisableProfitShareRule 停用合作的分成规则（之后结算的订单按分成配置比例直接分成）
+// DELETE /platform/cooperations/:id/profit-share-rules
+func (h *CooperationHandler) DisableProfitShareRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, 400, "无效的ID")
+		return
+	}
+
+	if err := h.settlementService.DisableProfitShareRule(c.Request.Context(), id); err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "分成规则已停用"})
+}
+
 // GetCooperationStats 获取合作统计
 // GET /platform/cooperations/stats
 func (h *CooperationHandler) GetCooperationStats(c *gin.Context) {
@@ -7,6 +7,7 @@ import (
 
 	"balance/backend/internal/database"
 	"balance/backend/internal/models"
+	"balance/backend/internal/services"
 	"balance/backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -16,13 +17,15 @@ import (
 
 // FinanceAuditHandler 财务审核处理器
 type FinanceAuditHandler struct {
-	db *gorm.DB
+	db             *gorm.DB
+	accountService *services.AccountService
 }
 
 // NewFinanceAuditHandler 创建财务审核处理器
 func NewFinanceAuditHandler() *FinanceAuditHandler {
 	return &FinanceAuditHandler{
-		db: database.GetDB(),
+		db:             database.GetDB(),
+		accountService: services.NewAccountService(),
 	}
 }
 
@@ -226,59 +229,40 @@ type ApproveAuditRequest struct {
 	Remark        string `json:"remark"`
 }
 
-// ApproveAudit 审批操作
+// ApproveAudit 审批操作（多级审批由 AccountService 校验：申请人不能审批自己的记录，金额达到门槛需多个不同审批人）
 // POST /platform/finance/audit/approve
 func (h *FinanceAuditHandler) ApproveAudit(c *gin.Context) {
-	var req ApproveAuditRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.Error(c, 400, "参数错误: "+err.Error())
-		return
-	}
-
-	// 遍历所有分表查找交易记录
-	var tx models.AccountTransaction
-	var foundTable string
-	for i := 0; i < database.ShardCount; i++ {
-		txTable := fmt.Sprintf("account_transactions_%d", i)
-		if err := h.db.Table(txTable).First(&tx, req.TransactionID).Error; err == nil {
-			foundTable = txTable
-			break
-		}
-	}
-	if foundTable == "" {
-		utils.Error(c, 404, "交易记录不存在")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
 		return
 	}
+	auditBy := userID.(int64)
 
-	if tx.Status != 0 {
-		utils.Error(c, 400, "该记录已审批")
+	var req ApproveAuditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 400, "参数错误: "+err.Error())
 		return
 	}
-
-	newStatus := int8(0)
-	if req.Action == "approve" {
-		newStatus = 1
-	} else if req.Action == "reject" {
-		newStatus = 2
-	} else {
+	if req.Action != models.ApprovalActionApprove && req.Action != models.ApprovalActionReject {
 		utils.Error(c, 400, "无效的操作")
 		return
 	}
 
-	updates := map[string]interface{}{"status": newStatus}
-	if req.Remark != "" {
-		updates["remark"] = req.Remark
+	progress, err := h.accountService.AuditTransaction(c.Request.Context(), req.TransactionID, req.Action == models.ApprovalActionApprove, auditBy, req.Remark)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
 	}
 
-	h.db.Table(foundTable).Where("id = ?", tx.ID).Updates(updates)
-
-	utils.Success(c, gin.H{"message": "审批成功"})
+	utils.Success(c, gin.H{"message": "审批成功", "progress": progress})
 }
 
 // CreateWithdrawRequest 创建提现申请请求
 type CreateWithdrawRequest struct {
 	AccountType string  `json:"account_type" binding:"required"` // prepayment/deposit/operator
 	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Currency    string  `json:"currency"` // 子账户币种，默认 TWD
 	Remark      string  `json:"remark"`
 }
 
@@ -299,31 +283,32 @@ func (h *FinanceAuditHandler) CreateWithdrawApplication(c *gin.Context) {
 	}
 
 	amount := decimal.NewFromFloat(req.Amount)
+	currency := services.NormalizeCurrency(req.Currency)
 
 	// 检查余额
 	var balance decimal.Decimal
 	switch req.AccountType {
 	case "prepayment":
 		var account models.PrepaymentAccount
-		if err := h.db.Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := h.db.Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			utils.Error(c, 400, "账户不存在")
 			return
 		}
 		balance = account.Balance
 	case "deposit":
 		var account models.DepositAccount
-		if err := h.db.Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := h.db.Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			utils.Error(c, 400, "账户不存在")
 			return
 		}
 		balance = account.Balance
 	case "operator":
 		var account models.OperatorAccount
-		if err := h.db.Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := h.db.Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			utils.Error(c, 400, "账户不存在")
 			return
 		}
-		balance = account.Balance
+		balance = services.WithdrawableBalance(account.Balance, account.HeldAmount)
 	default:
 		utils.Error(c, 400, "无效的账户类型")
 		return
@@ -335,10 +320,13 @@ func (h *FinanceAuditHandler) CreateWithdrawApplication(c *gin.Context) {
 	}
 
 	// 创建提现申请
+	transactionNo := generateTransactionNo("WD")
 	tx := &models.AccountTransaction{
-		TransactionNo:   generateTransactionNo("WD"),
+		TransactionNo:   transactionNo,
+		IdempotencyKey:  transactionNo,
 		AccountType:     req.AccountType,
 		AdminID:         adminID,
+		Currency:        currency,
 		TransactionType: "withdraw",
 		Amount:          amount.Neg(), // 负数表示支出
 		BalanceBefore:   balance,
@@ -0,0 +1,97 @@
+package platform
+
+import (
+	"strconv"
+	"time"
+
+	"balance/backend/internal/models"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// FxRateHandler 汇率管理处理器
+type FxRateHandler struct {
+	fxService *services.FxRateService
+}
+
+// NewFxRateHandler 创建汇率管理处理器
+func NewFxRateHandler() *FxRateHandler {
+	return &FxRateHandler{
+		fxService: services.NewFxRateService(),
+	}
+}
+
+// ListFxRates 获取汇率列表
+// GET /platform/fx-rates?base_currency=TWD&quote_currency=MYR&page=1&page_size=20
+func (h *FxRateHandler) ListFxRates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	rates, total, err := h.fxService.ListRates(c.Request.Context(), c.Query("base_currency"), c.Query("quote_currency"), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, rates, total, page, pageSize)
+}
+
+// CreateFxRateRequest 录入汇率请求
+type CreateFxRateRequest struct {
+	BaseCurrency  string  `json:"base_currency" binding:"required"`
+	QuoteCurrency string  `json:"quote_currency" binding:"required"`
+	Rate          float64 `json:"rate" binding:"required,gt=0"`
+	EffectiveFrom string  `json:"effective_from"` // 格式 2006-01-02 15:04:05，为空立即生效
+	Source        string  `json:"source"`
+	Remark        string  `json:"remark"`
+}
+
+// CreateFxRate 录入汇率
+// POST /platform/fx-rates
+func (h *FxRateHandler) CreateFxRate(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req CreateFxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	fx := &models.FxRate{
+		BaseCurrency:  req.BaseCurrency,
+		QuoteCurrency: req.QuoteCurrency,
+		Rate:          decimal.NewFromFloat(req.Rate),
+		Source:        req.Source,
+		Remark:        req.Remark,
+		CreatedBy:     userID.(int64),
+	}
+	if req.EffectiveFrom != "" {
+		effectiveFrom, err := time.ParseInLocation("2006-01-02 15:04:05", req.EffectiveFrom, time.Local)
+		if err != nil {
+			utils.BadRequest(c, "生效时间格式错误")
+			return
+		}
+		fx.EffectiveFrom = effectiveFrom
+	}
+
+	if err := h.fxService.CreateRate(c.Request.Context(), fx); err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, fx)
+}
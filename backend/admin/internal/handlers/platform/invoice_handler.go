@@ -0,0 +1,169 @@
+package platform
+
+import (
+	"fmt"
+	"strconv"
+
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceHandler 平台结算发票处理器（开具、付款登记、作废、下载）
+type InvoiceHandler struct {
+	invoiceService *services.InvoiceService
+}
+
+// NewInvoiceHandler 创建平台结算发票处理器
+func NewInvoiceHandler() *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: services.NewInvoiceService(),
+	}
+}
+
+// ListInvoices 获取结算发票列表（含按币种汇总）
+// GET /platform/invoices?party_type=operator&admin_id=1&period_type=monthly&period=2026-09&currency=TWD&status=-1&page=1&page_size=20
+func (h *InvoiceHandler) ListInvoices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	q := services.InvoiceQuery{
+		PartyType:  c.Query("party_type"),
+		PeriodType: c.Query("period_type"),
+		Period:     c.Query("period"),
+		Currency:   c.Query("currency"),
+		Status:     int8(status),
+	}
+	if adminIDStr := c.Query("admin_id"); adminIDStr != "" {
+		adminID, err := strconv.ParseInt(adminIDStr, 10, 64)
+		if err != nil {
+			utils.BadRequest(c, "无效的用户ID")
+			return
+		}
+		q.AdminID = &adminID
+	}
+
+	invoices, total, totals, err := h.invoiceService.ListInvoices(c.Request.Context(), q, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"list":      invoices,
+		"totals":    totals,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GenerateInvoicesRequest 开具发票请求
+type GenerateInvoicesRequest struct {
+	PeriodType string `json:"period_type" binding:"required,oneof=weekly monthly"`
+	Period     string `json:"period" binding:"required"` // monthly: YYYY-MM，weekly: YYYY-Www
+}
+
+// GenerateInvoices 开具指定周期的运营/店主发票（已有未作废发票的对象跳过）
+// POST /platform/invoices/generate
+func (h *InvoiceHandler) GenerateInvoices(c *gin.Context) {
+	var req GenerateInvoicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.invoiceService.GenerateInvoices(c.Request.Context(), req.PeriodType, req.Period, c.GetInt64("user_id"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// GetInvoice 获取结算发票及明细
+// GET /platform/invoices/:invoice_no
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), c.Param("invoice_no"), "", nil)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, invoice)
+}
+
+// ExportInvoice 下载结算发票（format: csv/html）
+// GET /platform/invoices/:invoice_no/export?format=html
+func (h *InvoiceHandler) ExportInvoice(c *gin.Context) {
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), c.Param("invoice_no"), "", nil)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", services.InvoiceFormatCSV)
+	data, contentType, err := services.EncodeInvoice(invoice, format)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.File(c, fmt.Sprintf("%s.%s", invoice.InvoiceNo, format), contentType, data)
+}
+
+// MarkInvoicePaid 登记发票已付款
+// POST /platform/invoices/:invoice_no/paid
+func (h *InvoiceHandler) MarkInvoicePaid(c *gin.Context) {
+	var req struct {
+		PaymentRef string `json:"payment_ref" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	invoice, err := h.invoiceService.MarkInvoicePaid(c.Request.Context(), c.Param("invoice_no"), req.PaymentRef)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, invoice)
+}
+
+// VoidInvoice 作废发票（作废后可重新开具该周期发票）
+// POST /platform/invoices/:invoice_no/void
+func (h *InvoiceHandler) VoidInvoice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	invoice, err := h.invoiceService.VoidInvoice(c.Request.Context(), c.Param("invoice_no"), userID.(int64), req.Reason)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, invoice)
+}
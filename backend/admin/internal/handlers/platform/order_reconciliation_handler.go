@@ -0,0 +1,170 @@
+package platform
+
+import (
+	"strconv"
+
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderReconciliationHandler 平台订单三方对账处理器（虾皮结算明细 vs 钱包入账 vs 平台结算单）
+type OrderReconciliationHandler struct {
+	orderReconService *services.OrderReconciliationService
+}
+
+// NewOrderReconciliationHandler 创建平台订单对账处理器
+func NewOrderReconciliationHandler() *OrderReconciliationHandler {
+	return &OrderReconciliationHandler{
+		orderReconService: services.NewOrderReconciliationService(),
+	}
+}
+
+// GetRuns 获取订单对账批次列表
+// GET /platform/reconciliation/orders/runs?page=1&page_size=20
+func (h *OrderReconciliationHandler) GetRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	runs, total, err := h.orderReconService.GetOrderReconciliationRuns(c.Request.Context(), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, runs, total, page, pageSize)
+}
+
+// StartRun 手动执行一次订单对账
+// POST /platform/reconciliation/orders/runs
+func (h *OrderReconciliationHandler) StartRun(c *gin.Context) {
+	var req struct {
+		Days int `json:"days"` // 对账窗口天数，默认30天
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if req.Days < 0 || req.Days > 180 {
+		utils.BadRequest(c, "对账窗口天数需在1-180之间")
+		return
+	}
+
+	run, err := h.orderReconService.ReconcileOrders(c.Request.Context(), req.Days, c.GetInt64("user_id"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, run)
+}
+
+// GetMismatches 获取订单对账差异报告（默认最近一次已完成的对账批次）
+// GET /platform/reconciliation/orders?run_no=xxx&mismatch_type=amount_diff&shop_id=1&status=0&page=1&page_size=20
+func (h *OrderReconciliationHandler) GetMismatches(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	shopID, _ := strconv.ParseUint(c.Query("shop_id"), 10, 64)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	report, err := h.orderReconService.GetOrderMismatches(c.Request.Context(), services.OrderMismatchQuery{
+		RunNo:        c.Query("run_no"),
+		MismatchType: c.Query("mismatch_type"),
+		ShopID:       shopID,
+		Status:       int8(status),
+		Page:         page,
+		PageSize:     pageSize,
+	})
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"run":       report.Run,
+		"summary":   report.Summary,
+		"list":      report.List,
+		"total":     report.Total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// RaiseAdjustment 对金额差异一键发起调账（按钱包入账与结算金额的实时差额）
+// POST /platform/reconciliation/orders/:id/adjust
+func (h *OrderReconciliationHandler) RaiseAdjustment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的差异ID")
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	mismatch, err := h.orderReconService.RaiseMismatchAdjustment(c.Request.Context(), id, userID.(int64), req.Remark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, mismatch)
+}
+
+// IgnoreMismatch 忽略对账差异
+// POST /platform/reconciliation/orders/:id/ignore
+func (h *OrderReconciliationHandler) IgnoreMismatch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "无效的差异ID")
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	mismatch, err := h.orderReconService.IgnoreMismatch(c.Request.Context(), id, userID.(int64), req.Remark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, mismatch)
+}
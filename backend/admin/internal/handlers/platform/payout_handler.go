@@ -0,0 +1,167 @@
+package platform
+
+import (
+	"strconv"
+
+	"balance/backend/internal/models"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PayoutHandler 平台提现银行批量代付处理器
+type PayoutHandler struct {
+	payoutService *services.PayoutBatchService
+}
+
+// NewPayoutHandler 创建平台代付处理器
+func NewPayoutHandler() *PayoutHandler {
+	return &PayoutHandler{
+		payoutService: services.NewPayoutBatchService(),
+	}
+}
+
+// GetLayouts 获取可用的代付文件格式及对应银行
+// GET /platform/payout/layouts
+func (h *PayoutHandler) GetLayouts(c *gin.Context) {
+	utils.Success(c, services.ListPayoutLayouts())
+}
+
+// GetCandidates 预览可入批次的已审核提现
+// GET /platform/payout/candidates?layout=generic&currency=TWD
+func (h *PayoutHandler) GetCandidates(c *gin.Context) {
+	layout := c.Query("layout")
+	currency := c.Query("currency")
+	if layout == "" || currency == "" {
+		utils.BadRequest(c, "layout 和 currency 不能为空")
+		return
+	}
+
+	items, err := h.payoutService.ListPayoutCandidates(c.Request.Context(), layout, currency)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	maskPayoutItems(items)
+	utils.Success(c, items)
+}
+
+// GetBatches 获取代付批次列表
+// GET /platform/payout/batches?status=0&page=1&page_size=20
+func (h *PayoutHandler) GetBatches(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	batches, total, err := h.payoutService.ListPayoutBatches(c.Request.Context(), int8(status), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, batches, total, page, pageSize)
+}
+
+// CreateBatch 锁定已审核提现生成代付批次
+// POST /platform/payout/batches
+func (h *PayoutHandler) CreateBatch(c *gin.Context) {
+	var req services.PayoutBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	batch, err := h.payoutService.CreatePayoutBatch(c.Request.Context(), &req, c.GetInt64("user_id"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	maskPayoutItems(batch.Items)
+	utils.Success(c, batch)
+}
+
+// GetBatch 获取代付批次详情
+// GET /platform/payout/batches/:batch_no
+func (h *PayoutHandler) GetBatch(c *gin.Context) {
+	batch, err := h.payoutService.GetPayoutBatch(c.Request.Context(), c.Param("batch_no"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	maskPayoutItems(batch.Items)
+	utils.Success(c, batch)
+}
+
+// maskPayoutItems 代付明细收款信息脱敏展示（完整信息只出现在下载的代付文件中）
+func maskPayoutItems(items []models.PayoutBatchItem) {
+	for i := range items {
+		items[i].AccountNo = utils.MaskAccountNo(items[i].AccountNo)
+		items[i].Payee = utils.MaskName(items[i].Payee)
+	}
+}
+
+// DownloadFile 下载银行代付文件
+// GET /platform/payout/batches/:batch_no/file
+func (h *PayoutHandler) DownloadFile(c *gin.Context) {
+	filename, data, err := h.payoutService.ExportPayoutFile(c.Request.Context(), c.Param("batch_no"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.File(c, filename, "text/csv; charset=utf-8", data)
+}
+
+// ImportResults 导入银行回单（multipart 表单字段 file），成功的标记已打款，失败的退回暂扣金额
+// POST /platform/payout/batches/:batch_no/results
+func (h *PayoutHandler) ImportResults(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "请上传银行回单文件")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.BadRequest(c, "读取回单文件失败: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	result, err := h.payoutService.ImportPayoutResults(c.Request.Context(), c.Param("batch_no"), file, userID.(int64))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// CancelBatch 取消尚未导入回单的代付批次，提现申请解除锁定
+// POST /platform/payout/batches/:batch_no/cancel
+func (h *PayoutHandler) CancelBatch(c *gin.Context) {
+	batch, err := h.payoutService.CancelPayoutBatch(c.Request.Context(), c.Param("batch_no"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	maskPayoutItems(batch.Items)
+	utils.Success(c, batch)
+}
@@ -1,11 +1,11 @@
 package platform
 
 import (
-	"fmt"
 	"strconv"
 
 	"balance/backend/internal/database"
 	"balance/backend/internal/models"
+	"balance/backend/internal/services"
 	"balance/backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,40 +15,39 @@ import (
 
 // PenaltyHandler 罚补账户处理器
 type PenaltyHandler struct {
-	db *gorm.DB
+	db             *gorm.DB
+	accountService *services.AccountService
 }
 
 // NewPenaltyHandler 创建罚补账户处理器
 func NewPenaltyHandler() *PenaltyHandler {
 	return &PenaltyHandler{
-		db: database.GetDB(),
+		db:             database.GetDB(),
+		accountService: services.NewAccountService(),
 	}
 }
 
-// GetPenaltyStats 获取罚补账户统计 - 遍历所有分表
+// GetPenaltyStats 获取罚补统计（按币种：待审核笔数、待扣罚款/待发补贴、已结算金额）
 // GET /platform/penalty/stats
 func (h *PenaltyHandler) GetPenaltyStats(c *gin.Context) {
-	var balance decimal.Decimal
-
-	// 遍历所有分表统计
-	for i := 0; i < database.ShardCount; i++ {
-		settlementTable := fmt.Sprintf("order_settlements_%d", i)
-		var b decimal.Decimal
-		h.db.Table(settlementTable).
-			Where("status = ?", models.OrderSettlementCompleted).
-			Select("COALESCE(SUM(platform_share), 0)").Scan(&b)
-		balance = balance.Add(b)
+	stats, err := h.accountService.GetPenaltyBonusStats(c.Request.Context())
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
 	}
 
-	utils.Success(c, gin.H{
-		"balance": balance,
-	})
+	utils.Success(c, stats)
 }
 
-// GetPenaltyList 获取罚补交易列表
-// GET /platform/penalty/list?type=all&page=1&page_size=20
+// GetPenaltyList 获取罚补单列表
+// GET /platform/penalty/list?type=all&account_type=operator&admin_id=1&status=-1&reason_code=late_ship&order_sn=xxx&page=1&page_size=20
 func (h *PenaltyHandler) GetPenaltyList(c *gin.Context) {
-	transType := c.DefaultQuery("type", "all")
+	entryType := c.DefaultQuery("type", "all")
+	if entryType == "all" {
+		entryType = ""
+	}
+	adminID, _ := strconv.ParseInt(c.Query("admin_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
@@ -59,69 +58,41 @@ func (h *PenaltyHandler) GetPenaltyList(c *gin.Context) {
 		pageSize = 20
 	}
 
-	var allTransactions []models.AccountTransaction
-	var total int64
-
-	// 遍历所有分表
-	for i := 0; i < database.ShardCount; i++ {
-		txTable := fmt.Sprintf("account_transactions_%d", i)
-		query := h.db.Table(txTable)
-
-		switch transType {
-		case "recharge":
-			query = query.Where("transaction_type = ?", "recharge")
-		case "withdraw":
-			query = query.Where("transaction_type = ?", "withdraw")
-		case "penalty":
-			query = query.Where("transaction_type = ?", "penalty")
-		case "subsidy":
-			query = query.Where("transaction_type = ?", "subsidy")
-		}
-
-		var count int64
-		query.Count(&count)
-		total += count
-
-		var transactions []models.AccountTransaction
-		query.Order("created_at DESC").Find(&transactions)
-		allTransactions = append(allTransactions, transactions...)
+	entries, total, err := h.accountService.GetPenaltyBonusEntries(c.Request.Context(), entryType, c.Query("account_type"), adminID, int8(status),
+		c.Query("reason_code"), c.Query("order_sn"), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
 	}
 
-	// 内存分页
-	offset := (page - 1) * pageSize
-	end := offset + pageSize
-	if offset >= len(allTransactions) {
-		allTransactions = []models.AccountTransaction{}
-	} else {
-		if end > len(allTransactions) {
-			end = len(allTransactions)
-		}
-		allTransactions = allTransactions[offset:end]
+	adminIDs := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		adminIDs = append(adminIDs, e.AdminID)
+	}
+	var admins []models.Admin
+	if len(adminIDs) > 0 {
+		h.db.Where("id IN ?", adminIDs).Find(&admins)
+	}
+	names := make(map[int64]string, len(admins))
+	for _, a := range admins {
+		names[a.ID] = a.UserName
 	}
-	transactions := allTransactions
-
-	list := make([]gin.H, 0, len(transactions))
-	for _, tx := range transactions {
-		var admin models.Admin
-		h.db.Where("id = ?", tx.AdminID).First(&admin)
 
-		role := "店主"
-		if admin.UserType == 5 {
-			role = "运营"
-		} else if admin.UserType == 9 {
-			role = "平台"
+	list := make([]gin.H, 0, len(entries))
+	for _, e := range entries {
+		role := "运营"
+		if e.AccountType == models.AccountTypeShopOwnerCommission {
+			role = "店主"
 		}
-
 		list = append(list, gin.H{
-			"date":       tx.CreatedAt.Format("2006-01-02 15:04:05"),
-			"role":       role,
-			"name":       admin.UserName,
-			"type":       tx.TransactionType,
-			"channel":    "系统",
-			"order_no":   tx.TransactionNo,
-			"amount":     tx.Amount,
-			"balance":    tx.BalanceAfter,
-			"status":     "已完成",
+			"entry":    e,
+			"date":     e.CreatedAt.Format("2006-01-02 15:04:05"),
+			"role":     role,
+			"name":     names[e.AdminID],
+			"type":     e.EntryType,
+			"order_no": e.OrderSN,
+			"amount":   e.Amount,
+			"status":   e.Status,
 		})
 	}
 
@@ -130,59 +101,105 @@ func (h *PenaltyHandler) GetPenaltyList(c *gin.Context) {
 
 // CreatePenaltyRequest 创建罚款/补贴请求
 type CreatePenaltyRequest struct {
-	AdminID int64   `json:"admin_id" binding:"required"`
-	Type    string  `json:"type" binding:"required"` // penalty/subsidy
-	Amount  float64 `json:"amount" binding:"required,gt=0"`
-	Remark  string  `json:"remark"`
+	AdminID     int64   `json:"admin_id" binding:"required"`
+	AccountType string  `json:"account_type" binding:"required"` // operator/shop_owner_commission
+	Type        string  `json:"type" binding:"required"`         // penalty/subsidy
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Currency    string  `json:"currency"` // 结算币种，默认 TWD
+	ReasonCode  string  `json:"reason_code" binding:"required"`
+	ShopID      uint64  `json:"shop_id"`
+	OrderSN     string  `json:"order_sn"`
+	Evidence    string  `json:"evidence"` // 证据说明/图片链接（与订单号至少填一个）
+	Remark      string  `json:"remark"`
 }
 
-// CreatePenalty 创建罚款/补贴
+// CreatePenalty 创建罚款/补贴（待审核，审核通过后从对象账户的回款中扣除或发放）
 // POST /platform/penalty/create
 func (h *PenaltyHandler) CreatePenalty(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
 	var req CreatePenaltyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 400, "参数错误: "+err.Error())
 		return
 	}
 
-	amount := decimal.NewFromFloat(req.Amount)
-	if req.Type == "penalty" {
-		amount = amount.Neg() // 罚款为负数
+	entry, err := h.accountService.CreatePenaltyBonus(c.Request.Context(), services.PenaltyBonusRequest{
+		EntryType:   req.Type,
+		AccountType: req.AccountType,
+		AdminID:     req.AdminID,
+		Currency:    req.Currency,
+		Amount:      decimal.NewFromFloat(req.Amount),
+		ReasonCode:  req.ReasonCode,
+		ShopID:      req.ShopID,
+		OrderSN:     req.OrderSN,
+		Evidence:    req.Evidence,
+		Remark:      req.Remark,
+		RequestedBy: userID.(int64),
+	})
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
 	}
 
-	// 获取用户账户
-	var account models.PrepaymentAccount
-	if err := h.db.Where("admin_id = ?", req.AdminID).First(&account).Error; err != nil {
-		utils.Error(c, 400, "用户账户不存在")
+	utils.Success(c, entry)
+}
+
+// ApprovePenalty 审核通过罚补单（发起人不能自己审核）
+// POST /platform/penalty/approve
+func (h *PenaltyHandler) ApprovePenalty(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
 		return
 	}
 
-	// 创建交易记录
-	tx := &models.AccountTransaction{
-		TransactionNo:   generateTransactionNo("PN"),
-		AccountType:     models.AccountTypePrepayment,
-		AdminID:         req.AdminID,
-		TransactionType: req.Type,
-		Amount:          amount,
-		BalanceBefore:   account.Balance,
-		BalanceAfter:    account.Balance.Add(amount),
-		Remark:          req.Remark,
-		Status:          1,
+	var req struct {
+		EntryID     uint64 `json:"entry_id" binding:"required"`
+		AuditRemark string `json:"audit_remark"`
 	}
 
-	txTable := database.GetAccountTransactionTableName(req.AdminID)
-	err := h.db.Transaction(func(dbTx *gorm.DB) error {
-		if err := dbTx.Table(txTable).Create(tx).Error; err != nil {
-			return err
-		}
-		account.Balance = account.Balance.Add(amount)
-		return dbTx.Save(&account).Error
-	})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
 
+	entry, err := h.accountService.ApprovePenaltyBonus(c.Request.Context(), req.EntryID, userID.(int64), req.AuditRemark)
 	if err != nil {
-		utils.Error(c, 500, "操作失败: "+err.Error())
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, entry)
+}
+
+// RejectPenalty 拒绝罚补单
+// POST /platform/penalty/reject
+func (h *PenaltyHandler) RejectPenalty(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		EntryID     uint64 `json:"entry_id" binding:"required"`
+		AuditRemark string `json:"audit_remark" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.accountService.RejectPenaltyBonus(c.Request.Context(), req.EntryID, userID.(int64), req.AuditRemark); err != nil {
+		utils.Error(c, 500, err.Error())
 		return
 	}
 
-	utils.Success(c, tx)
+	utils.Success(c, nil)
 }
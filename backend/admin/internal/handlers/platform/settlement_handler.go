@@ -2,7 +2,9 @@ package platform
 
 import (
 	"strconv"
+	"time"
 
+	"balance/backend/internal/models"
 	"balance/backend/internal/services"
 	"balance/backend/internal/utils"
 
@@ -44,6 +46,47 @@ func (h *SettlementHandler) GetSettlements(c *gin.Context) {
 	utils.SuccessWithPage(c, settlements, total, page, pageSize)
 }
 
+// GetSettlementDetail 获取订单结算详情（含调账明细）
+// GET /platform/settlements/detail?shop_id=xxx&order_sn=xxx
+func (h *SettlementHandler) GetSettlementDetail(c *gin.Context) {
+	shopID, err := strconv.ParseUint(c.Query("shop_id"), 10, 64)
+	if err != nil || shopID == 0 {
+		utils.BadRequest(c, "店铺ID无效")
+		return
+	}
+	orderSN := c.Query("order_sn")
+	if orderSN == "" {
+		utils.BadRequest(c, "订单编号不能为空")
+		return
+	}
+
+	settlement, err := h.settlementService.GetSettlementDetail(c.Request.Context(), 0, "platform", shopID, orderSN)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, settlement)
+}
+
+// PreviewSettlement 预览订单结算分账（不写入数据，可传入假设的结算金额/分成比例）
+// POST /platform/settlements/preview
+func (h *SettlementHandler) PreviewSettlement(c *gin.Context) {
+	var req services.SettlementPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	preview, err := h.settlementService.PreviewSettlement(c.Request.Context(), 0, "platform", &req)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, preview)
+}
+
 // GetSettlementStats 获取结算统计
 // GET /platform/settlements/stats
 func (h *SettlementHandler) GetSettlementStats(c *gin.Context) {
@@ -56,21 +99,154 @@ func (h *SettlementHandler) GetSettlementStats(c *gin.Context) {
 	utils.Success(c, stats)
 }
 
-// ProcessSettlement 手动触发结算处理
+// ProcessSettlement 手动触发结算处理（创建一个手动结算批次，在后台执行）
 // POST /platform/settlements/process
 func (h *SettlementHandler) ProcessSettlement(c *gin.Context) {
-	count, err := h.settlementService.ProcessShopeeSettlement(c.Request.Context())
+	run, err := h.settlementService.StartSettlementRunAsync(c.Request.Context(), models.SettlementRunTypeSettlement, c.GetInt64("user_id"))
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"run":     run,
+		"message": "结算批次已在后台执行，可通过批次详情查看进度",
+	})
+}
+
+// GetSettlementRuns 获取结算批次列表
+// GET /platform/settlements/runs?run_type=settlement&status=1&page=1&page_size=20
+func (h *SettlementHandler) GetSettlementRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	runs, total, err := h.settlementService.GetSettlementRuns(c.Request.Context(), c.Query("run_type"), int8(status), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, runs, total, page, pageSize)
+}
+
+// StartSettlementRunRequest 手动创建结算批次请求
+type StartSettlementRunRequest struct {
+	RunType string `json:"run_type" binding:"required,oneof=settlement adjustment"`
+}
+
+// StartSettlementRun 手动创建结算批次（结算或调账）并在后台执行，立即返回批次
+// POST /platform/settlements/runs
+func (h *SettlementHandler) StartSettlementRun(c *gin.Context) {
+	var req StartSettlementRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	run, err := h.settlementService.StartSettlementRunAsync(c.Request.Context(), req.RunType, c.GetInt64("user_id"))
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, run)
+}
+
+// GetSettlementRun 获取结算批次及明细（status: 0待处理 1成功 2跳过 3失败，-1全部）
+// GET /platform/settlements/runs/:run_no?status=3&page=1&page_size=20
+func (h *SettlementHandler) GetSettlementRun(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	run, items, total, err := h.settlementService.GetSettlementRunItems(c.Request.Context(), c.Param("run_no"), int8(status), page, pageSize)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
 	}
 
 	utils.Success(c, gin.H{
-		"processed_count": count,
-		"message":         "结算处理完成",
+		"run":       run,
+		"items":     items,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
 	})
 }
 
+// ResumeSettlementRun 在后台续跑中断批次的未处理明细
+// POST /platform/settlements/runs/:run_no/resume
+func (h *SettlementHandler) ResumeSettlementRun(c *gin.Context) {
+	run, err := h.settlementService.ResumeSettlementRun(c.Request.Context(), c.Param("run_no"))
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, run)
+}
+
+// RetrySettlementRun 在后台重试批次中处理失败的明细
+// POST /platform/settlements/runs/:run_no/retry
+func (h *SettlementHandler) RetrySettlementRun(c *gin.Context) {
+	run, err := h.settlementService.RetrySettlementRunFailures(c.Request.Context(), c.Param("run_no"))
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, run)
+}
+
+// GetUnsettledOrders 获取近期未成功结算/调账的订单及原因（按订单取最近一次处理结果）
+// GET /platform/settlements/runs/unsettled?run_type=settlement&since=2024-01-01&status=3&page=1&page_size=20
+func (h *SettlementHandler) GetUnsettledOrders(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	runType := c.DefaultQuery("run_type", models.SettlementRunTypeSettlement)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	since := time.Now().AddDate(0, 0, -1)
+	if v := c.Query("since"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			utils.BadRequest(c, "since 格式应为 YYYY-MM-DD")
+			return
+		}
+		since = t
+	}
+
+	items, total, err := h.settlementService.GetUnsettledOrders(c.Request.Context(), runType, since, int8(status), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, items, total, page, pageSize)
+}
+
 // GetPendingSettlements 获取待结算订单
 // GET /platform/settlements/pending?page=1&page_size=20
 func (h *SettlementHandler) GetPendingSettlements(c *gin.Context) {
@@ -0,0 +1,217 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"balance/backend/internal/models"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatementHandler 平台月结账单处理器（生成、签核、校验、导出）
+type StatementHandler struct {
+	statementService *services.StatementService
+}
+
+// NewStatementHandler 创建平台月结账单处理器
+func NewStatementHandler() *StatementHandler {
+	return &StatementHandler{
+		statementService: services.NewStatementService(),
+	}
+}
+
+// query 构建账单查询条件
+func (h *StatementHandler) query(c *gin.Context) (services.StatementQuery, error) {
+	q := services.StatementQuery{
+		Period:   c.Query("period"),
+		Currency: c.Query("currency"),
+	}
+	if accountType := c.Query("account_type"); accountType != "" {
+		q.AccountTypes = []string{accountType}
+	}
+	if adminIDStr := c.Query("admin_id"); adminIDStr != "" {
+		adminID, err := strconv.ParseInt(adminIDStr, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("无效的用户ID")
+		}
+		q.AdminID = &adminID
+	}
+	return q, nil
+}
+
+// ListPeriods 获取账期列表
+// GET /platform/statements/periods?page=1&page_size=20
+func (h *StatementHandler) ListPeriods(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	periods, total, err := h.statementService.ListPeriods(c.Request.Context(), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, periods, total, page, pageSize)
+}
+
+// GenerateStatements 手动生成指定账期的月结账单（定时任务每月1号自动生成上月账单）
+// POST /platform/statements/generate
+func (h *StatementHandler) GenerateStatements(c *gin.Context) {
+	var req struct {
+		Period string `json:"period" binding:"required"` // YYYY-MM
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	period, err := h.statementService.GenerateMonthlyStatements(c.Request.Context(), req.Period)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, period)
+}
+
+// VerifyPeriod 校验账期账单未被修改（校验和、账期摘要、按流水重算）
+// GET /platform/statements/periods/:period/verify
+func (h *StatementHandler) VerifyPeriod(c *gin.Context) {
+	result, err := h.statementService.VerifyPeriod(c.Request.Context(), c.Param("period"))
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// SignOffPeriod 财务签核账期
+// POST /platform/statements/periods/:period/sign-off
+func (h *StatementHandler) SignOffPeriod(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.statementService.SignOffPeriod(c.Request.Context(), c.Param("period"), userID.(int64), req.Remark)
+	if err != nil {
+		if result != nil {
+			// 校验未通过时同时返回校验明细
+			c.JSON(http.StatusOK, utils.Response{Code: utils.CodeBadRequest, Message: err.Error(), Data: result})
+			return
+		}
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// ListStatements 获取月结账单列表
+// GET /platform/statements?period=2026-09&account_type=operator&admin_id=1&currency=TWD&page=1&page_size=20
+func (h *StatementHandler) ListStatements(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	q, err := h.query(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	statements, total, err := h.statementService.ListStatements(c.Request.Context(), q, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, statements, total, page, pageSize)
+}
+
+// GetStatement 获取月结账单详情
+// GET /platform/statements/:statement_no
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	statement, err := h.statementService.GetStatement(c.Request.Context(), c.Param("statement_no"), nil, nil)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, statement)
+}
+
+// ExportStatement 导出单张月结账单
+// GET /platform/statements/:statement_no/export?format=csv
+func (h *StatementHandler) ExportStatement(c *gin.Context) {
+	statement, err := h.statementService.GetStatement(c.Request.Context(), c.Param("statement_no"), nil, nil)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", services.StatementFormatCSV)
+	data, contentType, err := services.EncodeStatements([]models.AccountStatement{*statement}, format)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.File(c, fmt.Sprintf("%s.%s", statement.StatementNo, format), contentType, data)
+}
+
+// ExportStatements 导出指定账期的月结账单（可按账户类型/用户/币种过滤）
+// GET /platform/statements/export?period=2026-09&format=csv
+func (h *StatementHandler) ExportStatements(c *gin.Context) {
+	q, err := h.query(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if q.Period == "" {
+		utils.BadRequest(c, "请指定账期")
+		return
+	}
+
+	statements, err := h.statementService.ExportStatements(c.Request.Context(), q)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", services.StatementFormatCSV)
+	data, contentType, err := services.EncodeStatements(statements, format)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.File(c, fmt.Sprintf("statements_%s.%s", q.Period, format), contentType, data)
+}
@@ -30,8 +30,8 @@ func NewAccountHandler() *AccountHandler {
 	}
 }
 
-// GetPrepaymentAccount 获取预付款账户信息
-// GET /shopower/account/prepayment
+// GetPrepaymentAccount 获取预付款账户信息（按币种，默认 TWD）
+// GET /shopower/account/prepayment?currency=MYR
 func (h *AccountHandler) GetPrepaymentAccount(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -40,7 +40,7 @@ func (h *AccountHandler) GetPrepaymentAccount(c *gin.Context) {
 	}
 	adminID := userID.(int64)
 
-	account, err := h.accountService.GetOrCreatePrepaymentAccount(c.Request.Context(), adminID)
+	account, err := h.accountService.GetOrCreatePrepaymentAccount(c.Request.Context(), adminID, c.Query("currency"))
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -166,6 +166,61 @@ func (h *AccountHandler) GetSettlements(c *gin.Context) {
 	utils.SuccessWithPage(c, settlements, total, page, pageSize)
 }
 
+// GetSettlementDetail 获取订单结算详情（含调账明细）
+// GET /shopower/settlements/detail?shop_id=xxx&order_sn=xxx
+func (h *AccountHandler) GetSettlementDetail(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	shopID, err := strconv.ParseUint(c.Query("shop_id"), 10, 64)
+	if err != nil || shopID == 0 {
+		utils.BadRequest(c, "店铺ID无效")
+		return
+	}
+	orderSN := c.Query("order_sn")
+	if orderSN == "" {
+		utils.BadRequest(c, "订单编号不能为空")
+		return
+	}
+
+	settlement, err := h.settlementService.GetSettlementDetail(c.Request.Context(), adminID, "shop_owner", shopID, orderSN)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, settlement)
+}
+
+// PreviewSettlement 预览订单结算分账（不写入数据，可传入假设的结算金额/分成比例）
+// POST /shopower/settlements/preview
+func (h *AccountHandler) PreviewSettlement(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	var req services.SettlementPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	preview, err := h.settlementService.PreviewSettlement(c.Request.Context(), adminID, "shop_owner", &req)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, preview)
+}
+
 // GetSettlementStats 获取结算统计
 // GET /shopower/settlements/stats
 func (h *AccountHandler) GetSettlementStats(c *gin.Context) {
@@ -195,7 +250,7 @@ func (h *AccountHandler) GetCommissionAccount(c *gin.Context) {
 	}
 	adminID := userID.(int64)
 
-	account, err := h.accountService.GetOrCreateShopOwnerCommissionAccount(c.Request.Context(), adminID)
+	account, err := h.accountService.GetOrCreateShopOwnerCommissionAccount(c.Request.Context(), adminID, c.Query("currency"))
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -233,6 +288,66 @@ func (h *AccountHandler) GetCommissionTransactions(c *gin.Context) {
 	utils.SuccessWithPage(c, transactions, total, page, pageSize)
 }
 
+// GetCommissionHolds 获取佣金账户上的风控暂扣
+// GET /shopower/account/commission/holds?status=0&page=1&page_size=20
+func (h *AccountHandler) GetCommissionHolds(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	holds, total, err := h.accountService.GetHolds(c.Request.Context(), models.AccountTypeShopOwnerCommission, adminID, int8(status), "", "", page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, holds, total, page, pageSize)
+}
+
+// GetCommissionPenalties 获取佣金账户的罚款/补贴单
+// GET /shopower/account/commission/penalties?status=-1&page=1&page_size=20
+func (h *AccountHandler) GetCommissionPenalties(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := h.accountService.GetPenaltyBonusEntries(c.Request.Context(), c.Query("type"), models.AccountTypeShopOwnerCommission, adminID, int8(status), "", "", page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, entries, total, page, pageSize)
+}
+
 // GetAllAccounts 获取所有账户汇总
 // GET /shopower/account/summary
 func (h *AccountHandler) GetAllAccounts(c *gin.Context) {
@@ -243,14 +358,19 @@ func (h *AccountHandler) GetAllAccounts(c *gin.Context) {
 	}
 	adminID := userID.(int64)
 
-	prepayment, _ := h.accountService.GetOrCreatePrepaymentAccount(c.Request.Context(), adminID)
+	prepayment, _ := h.accountService.GetOrCreatePrepaymentAccount(c.Request.Context(), adminID, models.DefaultCurrency)
 	deposit, _ := h.accountService.GetOrCreateDepositAccount(c.Request.Context(), adminID)
-	commission, _ := h.accountService.GetOrCreateShopOwnerCommissionAccount(c.Request.Context(), adminID)
+	commission, _ := h.accountService.GetOrCreateShopOwnerCommissionAccount(c.Request.Context(), adminID, models.DefaultCurrency)
+	// 各币种子账户
+	prepaymentAccounts, _ := h.accountService.ListPrepaymentAccounts(c.Request.Context(), adminID)
+	commissionAccounts, _ := h.accountService.ListShopOwnerCommissionAccounts(c.Request.Context(), adminID)
 
 	utils.Success(c, gin.H{
-		"prepayment": prepayment,
-		"deposit":    deposit,
-		"commission": commission,
+		"prepayment":          prepayment,
+		"deposit":             deposit,
+		"commission":          commission,
+		"prepayment_accounts": prepaymentAccounts,
+		"commission_accounts": commissionAccounts,
 	})
 }
 
@@ -267,6 +387,8 @@ func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
 	var req struct {
 		AccountType         string  `json:"account_type" binding:"required"`
 		Amount              float64 `json:"amount" binding:"required,gt=0"`
+		Currency            string  `json:"currency"`        // 提现的子账户币种，默认 TWD
+		PayoutCurrency      string  `json:"payout_currency"` // 到账币种，默认与 currency 相同
 		CollectionAccountID uint64  `json:"collection_account_id" binding:"required"`
 		Remark              string  `json:"remark"`
 	}
@@ -277,13 +399,172 @@ func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
 	}
 
 	// 验证账户类型
-	if req.AccountType != models.AccountTypeShopOwnerCommission && req.AccountType != models.AccountTypeDeposit {
-		utils.BadRequest(c, "店主只能从佣金账户或保证金账户提现")
+	if req.AccountType != models.AccountTypeShopOwnerCommission {
+		utils.BadRequest(c, "店主只能从佣金账户提现，保证金请提交保证金退还申请")
 		return
 	}
 
 	amount := utils.ToDecimal(req.Amount)
-	application, err := h.accountService.ApplyWithdraw(c.Request.Context(), adminID, req.AccountType, amount, req.CollectionAccountID, req.Remark)
+	application, err := h.accountService.ApplyWithdraw(c.Request.Context(), adminID, req.AccountType, amount, req.Currency, req.PayoutCurrency, req.CollectionAccountID, req.Remark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, application)
+}
+
+// QuoteWithdraw 提现试算：申请前展示手续费、到账金额、剩余额度及不能提现的原因
+// GET /shopower/withdraw/quote?account_type=shop_owner_commission&amount=1000&currency=TWD&collection_account_id=1
+func (h *AccountHandler) QuoteWithdraw(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	var req struct {
+		AccountType         string  `form:"account_type" binding:"required"`
+		Amount              float64 `form:"amount" binding:"required,gt=0"`
+		Currency            string  `form:"currency"`
+		PayoutCurrency      string  `form:"payout_currency"`
+		CollectionAccountID uint64  `form:"collection_account_id"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if req.AccountType != models.AccountTypeShopOwnerCommission {
+		utils.BadRequest(c, "店主只能从佣金账户提现，保证金请提交保证金退还申请")
+		return
+	}
+
+	quote, err := h.accountService.QuoteWithdraw(c.Request.Context(), adminID, req.AccountType, utils.ToDecimal(req.Amount), req.Currency, req.PayoutCurrency, req.CollectionAccountID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, quote)
+}
+
+// GetAutoWithdrawSettings 获取自动提现设置
+// GET /shopower/withdraw/auto
+func (h *AccountHandler) GetAutoWithdrawSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	settings, err := h.accountService.ListAutoWithdrawSettings(c.Request.Context(), adminID)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, settings)
+}
+
+// SaveAutoWithdrawSetting 保存自动提现设置（店主佣金账户，同一币种只有一条，重复保存即修改）
+// POST /shopower/withdraw/auto
+func (h *AccountHandler) SaveAutoWithdrawSetting(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	var req struct {
+		Currency            string  `json:"currency"`        // 提现币种，默认 TWD
+		PayoutCurrency      string  `json:"payout_currency"` // 到账币种，默认与 currency 相同
+		CollectionAccountID uint64  `json:"collection_account_id" binding:"required"`
+		Frequency           string  `json:"frequency" binding:"required,oneof=weekly monthly"`
+		DayOfWeek           int     `json:"day_of_week"`  // weekly 时每周几执行（0周日~6周六）
+		DayOfMonth          int     `json:"day_of_month"` // monthly 时每月几号执行（1~28）
+		Threshold           float64 `json:"threshold" binding:"gte=0"`
+		KeepMinimum         float64 `json:"keep_minimum" binding:"gte=0"`
+		Enabled             bool    `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	setting, err := h.accountService.SaveAutoWithdrawSetting(c.Request.Context(), adminID, services.AutoWithdrawSettingRequest{
+		AccountType:         models.AccountTypeShopOwnerCommission,
+		Currency:            req.Currency,
+		PayoutCurrency:      req.PayoutCurrency,
+		CollectionAccountID: req.CollectionAccountID,
+		Frequency:           req.Frequency,
+		DayOfWeek:           req.DayOfWeek,
+		DayOfMonth:          req.DayOfMonth,
+		Threshold:           utils.ToDecimal(req.Threshold),
+		KeepMinimum:         utils.ToDecimal(req.KeepMinimum),
+		Enabled:             req.Enabled,
+	})
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, setting)
+}
+
+// GetAutoWithdrawRuns 获取自动提现执行记录（含创建的提现申请单号或跳过原因）
+// GET /shopower/withdraw/auto/runs
+func (h *AccountHandler) GetAutoWithdrawRuns(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	runs, total, err := h.accountService.GetAutoWithdrawRuns(c.Request.Context(), adminID, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, runs, total, page, pageSize)
+}
+
+// ApplyDepositRefund 申请退还保证金（超出应缴保证金的部分，名下已无店铺时可全额申请）
+// POST /shopower/deposit/refund/apply
+func (h *AccountHandler) ApplyDepositRefund(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	var req struct {
+		Amount              float64 `json:"amount" binding:"required,gt=0"`
+		CollectionAccountID uint64  `json:"collection_account_id" binding:"required"`
+		Reason              string  `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	application, err := h.accountService.ApplyDepositRefund(c.Request.Context(), adminID, utils.ToDecimal(req.Amount), req.CollectionAccountID, req.Reason)
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
@@ -292,6 +573,36 @@ func (h *AccountHandler) ApplyWithdraw(c *gin.Context) {
 	utils.Success(c, application)
 }
 
+// GetDepositRefundApplications 获取保证金退还申请列表
+// GET /shopower/deposit/refund/list?status=-1&page=1&page_size=20
+func (h *AccountHandler) GetDepositRefundApplications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	applications, total, err := h.accountService.GetDepositRefundApplications(c.Request.Context(), adminID, int8(status), page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, applications, total, page, pageSize)
+}
+
 // GetWithdrawApplications 获取提现申请列表
 // GET /shopower/withdraw/list?status=-1&page=1&page_size=20
 func (h *AccountHandler) GetWithdrawApplications(c *gin.Context) {
@@ -333,11 +644,13 @@ func (h *AccountHandler) Recharge(c *gin.Context) {
 	adminID := userID.(int64)
 
 	var req struct {
-		AccountType   string  `json:"account_type" binding:"required"`
-		Amount        float64 `json:"amount" binding:"required,gt=0"`
-		PaymentMethod string  `json:"payment_method"`
-		PaymentProof  string  `json:"payment_proof"`
-		Remark        string  `json:"remark"`
+		AccountType    string  `json:"account_type" binding:"required"`
+		Amount         float64 `json:"amount" binding:"required,gt=0"`
+		PaymentMethod  string  `json:"payment_method"`
+		PaymentProof   string  `json:"payment_proof"`
+		Remark         string  `json:"remark"`
+		Currency       string  `json:"currency"` // 充值币种（仅预付款），默认 TWD
+		IdempotencyKey string  `json:"idempotency_key"` // 可选：客户端重试时携带相同的键，不会重复入账
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -357,21 +670,26 @@ func (h *AccountHandler) Recharge(c *gin.Context) {
 		remark = "店主充值"
 	}
 
+	idempotencyKey := ""
+	if req.IdempotencyKey != "" {
+		idempotencyKey = fmt.Sprintf("shopower_recharge:%d:%s", adminID, req.IdempotencyKey)
+	}
+
 	// 充值无需审核，直接入账
 	var transaction interface{}
 	var err error
 	switch req.AccountType {
 	case models.AccountTypePrepayment:
-		transaction, err = h.accountService.RechargePrepayment(c.Request.Context(), adminID, amount, remark, adminID)
+		transaction, err = h.accountService.RechargePrepayment(c.Request.Context(), adminID, amount, req.Currency, remark, adminID, idempotencyKey)
 	case models.AccountTypeDeposit:
-		transaction, err = h.accountService.PayDeposit(c.Request.Context(), adminID, amount, remark, adminID)
+		transaction, err = h.accountService.PayDeposit(c.Request.Context(), adminID, amount, remark, adminID, idempotencyKey)
 	}
 	if err != nil {
 		utils.Error(c, 500, err.Error())
 		return
 	}
 
-	// 充值预付款成功后，异步补扣历史「预付款不足」的订单
+	// 充值预付款成功后，异步补扣历史「预付款不足」的订单（补缴保证金由 PayDeposit 触发补扣）
 	if req.AccountType == models.AccountTypePrepayment {
 		go func(ownerID int64) {
 			bgCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -389,6 +707,42 @@ func (h *AccountHandler) Recharge(c *gin.Context) {
 	utils.Success(c, transaction)
 }
 
+// GetPaymentMethods 获取支付方式及启用状态（线上支付方式按配置启用）
+// GET /shopower/recharge/methods
+func (h *AccountHandler) GetPaymentMethods(c *gin.Context) {
+	utils.Success(c, utils.AllPaymentMethods())
+}
+
+// OnlineRecharge 线上充值预付款：创建第三方支付订单，返回支付链接，付款成功后由支付回调自动入账
+// POST /shopower/recharge/online
+func (h *AccountHandler) OnlineRecharge(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	var req struct {
+		Amount        float64 `json:"amount" binding:"required,gt=0"`
+		Currency      string  `json:"currency"` // 充值币种，默认 TWD
+		PaymentMethod string  `json:"payment_method" binding:"required"`
+		Remark        string  `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	record, err := h.accountService.CreateOnlineRecharge(c.Request.Context(), adminID, utils.ToDecimal(req.Amount), req.Currency, req.PaymentMethod, req.Remark)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, record)
+}
+
 // GetRechargeRecords 获取充值申请列表
 // GET /shopower/recharge/list?status=-1&page=1&page_size=20
 func (h *AccountHandler) GetRechargeRecords(c *gin.Context) {
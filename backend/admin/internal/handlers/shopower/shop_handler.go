@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"balance/backend/internal/consts"
+	"balance/backend/internal/services"
 	"balance/backend/internal/services/shopower"
 	"balance/backend/internal/utils"
 
@@ -23,7 +24,7 @@ type ShopHandler struct {
 // NewShopHandler 创建店铺处理器
 func NewShopHandler() *ShopHandler {
 	return &ShopHandler{
-		shopService: shopower.NewShopService(),
+		shopService: services.NewShopServiceWithDepositCheck(),
 	}
 }
 
@@ -0,0 +1,165 @@
+package shopower
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"balance/backend/internal/models"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shopOwnerStatementTypes 店主可查看的账单账户类型
+var shopOwnerStatementTypes = []string{
+	models.AccountTypePrepayment,
+	models.AccountTypeDeposit,
+	models.AccountTypeShopOwnerCommission,
+}
+
+// StatementHandler 店主月结账单处理器
+type StatementHandler struct {
+	statementService *services.StatementService
+}
+
+// NewStatementHandler 创建店主月结账单处理器
+func NewStatementHandler() *StatementHandler {
+	return &StatementHandler{
+		statementService: services.NewStatementService(),
+	}
+}
+
+// query 构建当前店主的账单查询条件（account_type 只能在店主账户类型内筛选）
+func (h *StatementHandler) query(c *gin.Context, adminID int64) (services.StatementQuery, error) {
+	q := services.StatementQuery{
+		Period:       c.Query("period"),
+		Currency:     c.Query("currency"),
+		AccountTypes: shopOwnerStatementTypes,
+		AdminID:      &adminID,
+	}
+	if accountType := c.Query("account_type"); accountType != "" {
+		if !slices.Contains(shopOwnerStatementTypes, accountType) {
+			return q, fmt.Errorf("无效的账户类型")
+		}
+		q.AccountTypes = []string{accountType}
+	}
+	return q, nil
+}
+
+// ListStatements 获取月结账单列表
+// GET /shopower/statements?period=2026-09&account_type=prepayment&currency=TWD&page=1&page_size=20
+func (h *StatementHandler) ListStatements(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	q, err := h.query(c, adminID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	statements, total, err := h.statementService.ListStatements(c.Request.Context(), q, page, pageSize)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessWithPage(c, statements, total, page, pageSize)
+}
+
+// GetStatement 获取月结账单详情（含按交易类型汇总的明细）
+// GET /shopower/statements/:statement_no
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	statement, err := h.statementService.GetStatement(c.Request.Context(), c.Param("statement_no"), &adminID, shopOwnerStatementTypes)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	utils.Success(c, statement)
+}
+
+// ExportStatement 导出单张月结账单
+// GET /shopower/statements/:statement_no/export?format=csv
+func (h *StatementHandler) ExportStatement(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	statement, err := h.statementService.GetStatement(c.Request.Context(), c.Param("statement_no"), &adminID, shopOwnerStatementTypes)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", services.StatementFormatCSV)
+	data, contentType, err := services.EncodeStatements([]models.AccountStatement{*statement}, format)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.File(c, fmt.Sprintf("%s.%s", statement.StatementNo, format), contentType, data)
+}
+
+// ExportStatements 导出指定账期的全部月结账单
+// GET /shopower/statements/export?period=2026-09&format=csv
+func (h *StatementHandler) ExportStatements(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	adminID := userID.(int64)
+
+	q, err := h.query(c, adminID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if q.Period == "" {
+		utils.BadRequest(c, "请指定账期")
+		return
+	}
+
+	statements, err := h.statementService.ExportStatements(c.Request.Context(), q)
+	if err != nil {
+		utils.Error(c, 500, err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", services.StatementFormatCSV)
+	data, contentType, err := services.EncodeStatements(statements, format)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.File(c, fmt.Sprintf("statements_%s.%s", q.Period, format), contentType, data)
+}
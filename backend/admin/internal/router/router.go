@@ -56,11 +56,26 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 		shopowerShopHandler := shopower.NewShopHandler()
 		admin.GET(consts.RouteAuthCallback, shopowerShopHandler.AuthCallback)
 
+		// 第三方支付回调（无需登录，由网关验签或向网关核实）
+		paymentHandler := handlers.NewPaymentHandler()
+		admin.GET(consts.RoutePaymentNotify, paymentHandler.Notify)
+		admin.POST(consts.RoutePaymentNotify, paymentHandler.Notify)
+
 		// 需要登录的公共接口
 		authGroup := admin.Group("")
 		authGroup.Use(handlers.JWTAuthMiddleware())
 		{
 			authGroup.GET(consts.RouteAuthMe, authHandler.GetCurrentUser)
+
+			// 事件订阅（回调地址登记、投递记录与重投）
+			eventSubscriptionHandler := handlers.NewEventSubscriptionHandler()
+			authGroup.GET(consts.RouteWebhookSubscriptions, eventSubscriptionHandler.ListSubscriptions)
+			authGroup.POST(consts.RouteWebhookSubscriptions, eventSubscriptionHandler.CreateSubscription)
+			authGroup.PUT(consts.RouteWebhookSubscriptionDetail, eventSubscriptionHandler.UpdateSubscription)
+			authGroup.DELETE(consts.RouteWebhookSubscriptionDetail, eventSubscriptionHandler.DeleteSubscription)
+			authGroup.POST(consts.RouteWebhookSubscriptionRotate, eventSubscriptionHandler.RotateSecret)
+			authGroup.GET(consts.RouteWebhookDeliveries, eventSubscriptionHandler.ListDeliveries)
+			authGroup.POST(consts.RouteWebhookDeliveryRedeliver, eventSubscriptionHandler.Redeliver)
 		}
 
 		// ==================== 店主路由 (shopower) ====================
@@ -127,18 +142,45 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 				shopowerAuth.GET("/account/prepayment/transactions", shopowerAccountHandler.GetPrepaymentTransactions)
 				shopowerAuth.GET("/account/deposit/transactions", shopowerAccountHandler.GetDepositTransactions)
 				shopowerAuth.GET("/account/commission/transactions", shopowerAccountHandler.GetCommissionTransactions)
+				shopowerAuth.GET("/account/commission/holds", shopowerAccountHandler.GetCommissionHolds)
+				shopowerAuth.GET("/account/commission/penalties", shopowerAccountHandler.GetCommissionPenalties)
 
 				// 结算管理
 				shopowerAuth.GET("/settlements", shopowerAccountHandler.GetSettlements)
 				shopowerAuth.GET("/settlements/stats", shopowerAccountHandler.GetSettlementStats)
+				shopowerAuth.GET("/settlements/detail", shopowerAccountHandler.GetSettlementDetail)
+				shopowerAuth.POST("/settlements/preview", shopowerAccountHandler.PreviewSettlement)
 
 				// 提现管理
+				shopowerAuth.GET("/withdraw/quote", shopowerAccountHandler.QuoteWithdraw)
 				shopowerAuth.POST("/withdraw/apply", shopowerAccountHandler.ApplyWithdraw)
 				shopowerAuth.GET("/withdraw/list", shopowerAccountHandler.GetWithdrawApplications)
+				shopowerAuth.GET("/withdraw/auto", shopowerAccountHandler.GetAutoWithdrawSettings)
+				shopowerAuth.POST("/withdraw/auto", shopowerAccountHandler.SaveAutoWithdrawSetting)
+				shopowerAuth.GET("/withdraw/auto/runs", shopowerAccountHandler.GetAutoWithdrawRuns)
+
+				// 保证金退还
+				shopowerAuth.POST("/deposit/refund/apply", shopowerAccountHandler.ApplyDepositRefund)
+				shopowerAuth.GET("/deposit/refund/list", shopowerAccountHandler.GetDepositRefundApplications)
 
 				// 充值管理
 				shopowerAuth.POST("/recharge", shopowerAccountHandler.Recharge)
+				shopowerAuth.GET("/recharge/methods", shopowerAccountHandler.GetPaymentMethods)
+				shopowerAuth.POST("/recharge/online", shopowerAccountHandler.OnlineRecharge)
 				shopowerAuth.GET("/recharge/list", shopowerAccountHandler.GetRechargeRecords)
+
+				// 月结账单
+				shopowerStatementHandler := shopower.NewStatementHandler()
+				shopowerAuth.GET("/statements", shopowerStatementHandler.ListStatements)
+				shopowerAuth.GET("/statements/export", shopowerStatementHandler.ExportStatements)
+				shopowerAuth.GET("/statements/:statement_no", shopowerStatementHandler.GetStatement)
+				shopowerAuth.GET("/statements/:statement_no/export", shopowerStatementHandler.ExportStatement)
+
+				// 结算发票（店主分成结算单）
+				shopowerInvoiceHandler := shopower.NewInvoiceHandler()
+				shopowerAuth.GET("/invoices", shopowerInvoiceHandler.ListInvoices)
+				shopowerAuth.GET("/invoices/:invoice_no", shopowerInvoiceHandler.GetInvoice)
+				shopowerAuth.GET("/invoices/:invoice_no/export", shopowerInvoiceHandler.ExportInvoice)
 			}
 		}
 
@@ -167,15 +209,36 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			operatorSettlementHandler := operator.NewSettlementHandler()
 			operatorGroup.GET("/settlements", operatorSettlementHandler.GetSettlements)
 			operatorGroup.GET("/settlements/stats", operatorSettlementHandler.GetSettlementStats)
+			operatorGroup.GET("/settlements/detail", operatorSettlementHandler.GetSettlementDetail)
+			operatorGroup.POST("/settlements/preview", operatorSettlementHandler.PreviewSettlement)
 
 			// 账户管理
 			operatorAccountHandler := operator.NewAccountHandler()
 			operatorGroup.GET("/account", operatorAccountHandler.GetAccount)
 			operatorGroup.GET("/account/transactions", operatorAccountHandler.GetTransactions)
+			operatorGroup.GET("/account/holds", operatorAccountHandler.GetHolds)
+			operatorGroup.GET("/account/penalties", operatorAccountHandler.GetPenalties)
 
 			// 提现管理
+			operatorGroup.GET("/withdraw/quote", operatorAccountHandler.QuoteWithdraw)
 			operatorGroup.POST("/withdraw/apply", operatorAccountHandler.ApplyWithdraw)
 			operatorGroup.GET("/withdraw/list", operatorAccountHandler.GetWithdrawApplications)
+			operatorGroup.GET("/withdraw/auto", operatorAccountHandler.GetAutoWithdrawSettings)
+			operatorGroup.POST("/withdraw/auto", operatorAccountHandler.SaveAutoWithdrawSetting)
+			operatorGroup.GET("/withdraw/auto/runs", operatorAccountHandler.GetAutoWithdrawRuns)
+
+			// 月结账单
+			operatorStatementHandler := operator.NewStatementHandler()
+			operatorGroup.GET("/statements", operatorStatementHandler.ListStatements)
+			operatorGroup.GET("/statements/export", operatorStatementHandler.ExportStatements)
+			operatorGroup.GET("/statements/:statement_no", operatorStatementHandler.GetStatement)
+			operatorGroup.GET("/statements/:statement_no/export", operatorStatementHandler.ExportStatement)
+
+			// 结算发票
+			operatorInvoiceHandler := operator.NewInvoiceHandler()
+			operatorGroup.GET("/invoices", operatorInvoiceHandler.ListInvoices)
+			operatorGroup.GET("/invoices/:invoice_no", operatorInvoiceHandler.GetInvoice)
+			operatorGroup.GET("/invoices/:invoice_no/export", operatorInvoiceHandler.ExportInvoice)
 		}
 
 		// ==================== 平台路由 (platform) ====================
@@ -205,8 +268,16 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			platformSettlementHandler := platform.NewSettlementHandler()
 			platformGroup.GET("/settlements", platformSettlementHandler.GetSettlements)
 			platformGroup.GET("/settlements/stats", platformSettlementHandler.GetSettlementStats)
+			platformGroup.GET("/settlements/detail", platformSettlementHandler.GetSettlementDetail)
+			platformGroup.POST("/settlements/preview", platformSettlementHandler.PreviewSettlement)
 			platformGroup.GET("/settlements/pending", platformSettlementHandler.GetPendingSettlements)
 			platformGroup.POST("/settlements/process", platformSettlementHandler.ProcessSettlement)
+			platformGroup.GET("/settlements/runs", platformSettlementHandler.GetSettlementRuns)
+			platformGroup.POST("/settlements/runs", platformSettlementHandler.StartSettlementRun)
+			platformGroup.GET("/settlements/runs/unsettled", platformSettlementHandler.GetUnsettledOrders)
+			platformGroup.GET("/settlements/runs/:run_no", platformSettlementHandler.GetSettlementRun)
+			platformGroup.POST("/settlements/runs/:run_no/resume", platformSettlementHandler.ResumeSettlementRun)
+			platformGroup.POST("/settlements/runs/:run_no/retry", platformSettlementHandler.RetrySettlementRun)
 
 			// 合作管理（店铺-运营分配）
 			platformCooperationHandler := platform.NewCooperationHandler()
@@ -214,6 +285,12 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			platformGroup.POST("/cooperations", platformCooperationHandler.CreateCooperation)
 			platformGroup.PUT("/cooperations/:id", platformCooperationHandler.UpdateCooperation)
 			platformGroup.DELETE("/cooperations/:id", platformCooperationHandler.CancelCooperation)
+			platformGroup.GET("/cooperations/:id/profit-share", platformCooperationHandler.GetProfitShareConfigs)
+			platformGroup.POST("/cooperations/:id/profit-share", platformCooperationHandler.ScheduleProfitShareConfig)
+			platformGroup.DELETE("/cooperations/:id/profit-share/:config_id", platformCooperationHandler.CancelProfitShareConfig)
+			platformGroup.GET("/cooperations/:id/profit-share-rules", platformCooperationHandler.GetProfitShareRules)
+			platformGroup.POST("/cooperations/:id/profit-share-rules", platformCooperationHandler.CreateProfitShareRule)
+			platformGroup.DELETE("/cooperations/:id/profit-share-rules", platformCooperationHandler.DisableProfitShareRule)
 			platformGroup.GET("/cooperations/stats", platformCooperationHandler.GetCooperationStats)
 			platformGroup.GET("/operators", platformCooperationHandler.GetOperatorList)
 			platformGroup.GET("/shop-owners", platformCooperationHandler.GetShopOwnerList)
@@ -225,8 +302,20 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			platformGroup.GET("/accounts/operator", platformAccountHandler.ListOperatorAccounts)
 			platformGroup.POST("/accounts/prepayment/recharge", platformAccountHandler.RechargePrepayment)
 			platformGroup.POST("/accounts/deposit/pay", platformAccountHandler.PayDeposit)
+			platformGroup.POST("/accounts/deposit/required", platformAccountHandler.SetDepositRequiredAmount)
+			platformGroup.POST("/accounts/deposit/deduct", platformAccountHandler.DeductDeposit)
 			platformGroup.GET("/accounts/transactions", platformAccountHandler.GetAccountTransactions)
 			platformGroup.GET("/accounts/stats", platformAccountHandler.GetAccountStats)
+			platformGroup.GET("/accounts/journals", platformAccountHandler.ListJournals)
+			platformGroup.GET("/accounts/journals/:journal_no", platformAccountHandler.GetJournalDetail)
+			platformGroup.GET("/accounts/reconciliation", platformAccountHandler.GetReconciliation)
+			platformGroup.GET("/accounts/reversals", platformAccountHandler.GetReversals)
+			platformGroup.POST("/accounts/reversals", platformAccountHandler.RequestReversal)
+			platformGroup.POST("/accounts/reversals/approve", platformAccountHandler.ApproveReversal)
+			platformGroup.POST("/accounts/reversals/reject", platformAccountHandler.RejectReversal)
+			platformGroup.GET("/accounts/holds", platformAccountHandler.GetHolds)
+			platformGroup.POST("/accounts/holds", platformAccountHandler.CreateHold)
+			platformGroup.POST("/accounts/holds/release", platformAccountHandler.ReleaseHold)
 			platformGroup.GET("/account/commission", platformAccountHandler.GetPlatformCommissionAccount)
 			platformGroup.GET("/account/commission/transactions", platformAccountHandler.GetPlatformCommissionTransactions)
 
@@ -236,6 +325,26 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			platformGroup.POST("/withdraw/reject", platformAccountHandler.RejectWithdraw)
 			platformGroup.POST("/withdraw/confirm_paid", platformAccountHandler.ConfirmWithdrawPaid)
 
+			// 多级审批
+			platformGroup.GET("/approvals/pending", platformAccountHandler.GetPendingApprovals)
+			platformGroup.GET("/approvals/trail", platformAccountHandler.GetApprovalTrail)
+
+			// 提现银行批量代付
+			platformPayoutHandler := platform.NewPayoutHandler()
+			platformGroup.GET("/payout/layouts", platformPayoutHandler.GetLayouts)
+			platformGroup.GET("/payout/candidates", platformPayoutHandler.GetCandidates)
+			platformGroup.GET("/payout/batches", platformPayoutHandler.GetBatches)
+			platformGroup.POST("/payout/batches", platformPayoutHandler.CreateBatch)
+			platformGroup.GET("/payout/batches/:batch_no", platformPayoutHandler.GetBatch)
+			platformGroup.GET("/payout/batches/:batch_no/file", platformPayoutHandler.DownloadFile)
+			platformGroup.POST("/payout/batches/:batch_no/results", platformPayoutHandler.ImportResults)
+			platformGroup.POST("/payout/batches/:batch_no/cancel", platformPayoutHandler.CancelBatch)
+
+			// 保证金退还审核
+			platformGroup.GET("/deposit/refund/list", platformAccountHandler.GetDepositRefundApplications)
+			platformGroup.POST("/deposit/refund/approve", platformAccountHandler.ApproveDepositRefund)
+			platformGroup.POST("/deposit/refund/reject", platformAccountHandler.RejectDepositRefund)
+
 			// 充值审核
 			platformGroup.GET("/recharge/list", platformAccountHandler.GetRechargeRecords)
 			platformGroup.POST("/recharge/approve", platformAccountHandler.ApproveRecharge)
@@ -259,6 +368,8 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			platformGroup.GET("/penalty/stats", platformPenaltyHandler.GetPenaltyStats)
 			platformGroup.GET("/penalty/list", platformPenaltyHandler.GetPenaltyList)
 			platformGroup.POST("/penalty/create", platformPenaltyHandler.CreatePenalty)
+			platformGroup.POST("/penalty/approve", platformPenaltyHandler.ApprovePenalty)
+			platformGroup.POST("/penalty/reject", platformPenaltyHandler.RejectPenalty)
 
 			// 收款账户
 			platformCollectionHandler := platform.NewCollectionHandler()
@@ -268,6 +379,39 @@ func SetupRouter(mode string, cfg *config.Config) *gin.Engine {
 			platformGroup.DELETE("/collection/accounts/:id", platformCollectionHandler.DeleteCollectionAccount)
 			platformGroup.POST("/collection/accounts/:id/default", platformCollectionHandler.SetDefaultAccount)
 
+			// 月结账单
+			platformStatementHandler := platform.NewStatementHandler()
+			platformGroup.GET("/statements/periods", platformStatementHandler.ListPeriods)
+			platformGroup.POST("/statements/generate", platformStatementHandler.GenerateStatements)
+			platformGroup.GET("/statements/periods/:period/verify", platformStatementHandler.VerifyPeriod)
+			platformGroup.POST("/statements/periods/:period/sign-off", platformStatementHandler.SignOffPeriod)
+			platformGroup.GET("/statements", platformStatementHandler.ListStatements)
+			platformGroup.GET("/statements/export", platformStatementHandler.ExportStatements)
+			platformGroup.GET("/statements/:statement_no", platformStatementHandler.GetStatement)
+			platformGroup.GET("/statements/:statement_no/export", platformStatementHandler.ExportStatement)
+
+			// 结算发票
+			platformInvoiceHandler := platform.NewInvoiceHandler()
+			platformGroup.GET("/invoices", platformInvoiceHandler.ListInvoices)
+			platformGroup.POST("/invoices/generate", platformInvoiceHandler.GenerateInvoices)
+			platformGroup.GET("/invoices/:invoice_no", platformInvoiceHandler.GetInvoice)
+			platformGroup.GET("/invoices/:invoice_no/export", platformInvoiceHandler.ExportInvoice)
+			platformGroup.POST("/invoices/:invoice_no/paid", platformInvoiceHandler.MarkInvoicePaid)
+			platformGroup.POST("/invoices/:invoice_no/void", platformInvoiceHandler.VoidInvoice)
+
+			// 订单三方对账
+			platformOrderReconHandler := platform.NewOrderReconciliationHandler()
+			platformGroup.GET("/reconciliation/orders", platformOrderReconHandler.GetMismatches)
+			platformGroup.GET("/reconciliation/orders/runs", platformOrderReconHandler.GetRuns)
+			platformGroup.POST("/reconciliation/orders/runs", platformOrderReconHandler.StartRun)
+			platformGroup.POST("/reconciliation/orders/:id/adjust", platformOrderReconHandler.RaiseAdjustment)
+			platformGroup.POST("/reconciliation/orders/:id/ignore", platformOrderReconHandler.IgnoreMismatch)
+
+			// 汇率管理
+			platformFxRateHandler := platform.NewFxRateHandler()
+			platformGroup.GET("/fx-rates", platformFxRateHandler.ListFxRates)
+			platformGroup.POST("/fx-rates", platformFxRateHandler.CreateFxRate)
+
 			// 模拟数据生成器（仅沙箱环境可用）
 			platformMockHandler := platform.NewMockHandler(cfg)
 			platformGroup.POST("/mock/orders", platformMockHandler.GenerateMockOrders)
@@ -15,6 +15,7 @@ import (
 	"balance/backend/internal/config"
 	"balance/backend/internal/database"
 	"balance/backend/internal/ratelimit"
+	"balance/backend/internal/utils"
 )
 
 func main() {
@@ -26,6 +27,10 @@ func main() {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
+	if err := utils.InitFieldCipher(&cfg.Crypto); err != nil {
+		log.Fatalf("初始化字段加密失败: %v", err)
+	}
+
 	if err := database.InitMySQL(&cfg.MySQL); err != nil {
 		log.Fatalf("初始化MySQL失败: %v", err)
 	}
@@ -36,6 +41,8 @@ func main() {
 	}
 	log.Println("Redis连接成功")
 
+	utils.InitPaymentGateways(&cfg.Payment)
+
 	if err := ratelimit.Init(); err != nil {
 		log.Printf("Sentinel 初始化警告: %v", err)
 	}
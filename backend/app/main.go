@@ -16,6 +16,7 @@ import (
 	"balance/backend/internal/database"
 	"balance/backend/internal/ratelimit"
 	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
 )
 
 func main() {
@@ -29,6 +30,11 @@ func main() {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
+	// 初始化字段加密（收款账户、店铺令牌加密存储）
+	if err := utils.InitFieldCipher(&cfg.Crypto); err != nil {
+		log.Fatalf("初始化字段加密失败: %v", err)
+	}
+
 	// 初始化MySQL
 	if err := database.InitMySQL(&cfg.MySQL); err != nil {
 		log.Fatalf("初始化MySQL失败: %v", err)
@@ -28,6 +28,10 @@ func main() {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
+	if err := utils.InitFieldCipher(&cfg.Crypto); err != nil {
+		log.Fatalf("初始化字段加密失败: %v", err)
+	}
+
 	if err := database.InitMySQL(&cfg.MySQL); err != nil {
 		log.Fatalf("初始化MySQL失败: %v", err)
 	}
@@ -38,6 +42,9 @@ func main() {
 	}
 	log.Println("Redis连接成功")
 
+	// 线上充值轮询需要查询支付网关
+	utils.InitPaymentGateways(&cfg.Payment)
+
 	if err := ratelimit.Init(); err != nil {
 		log.Printf("Sentinel 初始化警告: %v", err)
 	}
@@ -48,6 +55,7 @@ func main() {
 	maintenanceLogger, closeMaintenanceLog, _ := utils.NewFileLogger(logDir, "maintenance.log")
 	metricsLogger, closeMetricsLog, _ := utils.NewFileLogger(logDir, "metrics.log")
 	orderStatsLogger, closeOrderStatsLog, _ := utils.NewFileLogger(logDir, "order_stats.log")
+	eventDispatchLogger, closeEventDispatchLog, _ := utils.NewFileLogger(logDir, "event_dispatch.log")
 
 	// 1. 分布式同步调度器（订单+退货巡检，带分布式锁）
 	distributedSyncScheduler := services.NewDistributedSyncScheduler(database.GetDB(), database.GetRedis(), distributedSyncLogger)
@@ -57,7 +65,7 @@ func main() {
 	financeSyncScheduler := sync.NewScheduler(10, financeSyncLogger)
 	financeSyncScheduler.Start()
 
-	// 3. 维护任务调度器（日志归档、每日统计、虾皮结算/调账，带分布式锁）
+	// 3. 维护任务调度器（日志归档、每日统计、虾皮结算/调账、线上充值轮询，带分布式锁）
 	maintenanceScheduler := services.NewMaintenanceScheduler(maintenanceLogger)
 	maintenanceScheduler.Start()
 
@@ -69,6 +77,10 @@ func main() {
 	orderStatsScheduler := services.NewOrderStatsScheduler(orderStatsLogger)
 	orderStatsScheduler.Start()
 
+	// 6. 领域事件投递器（发件箱分发 + 签名回调投递，每 5 秒，带分布式锁）
+	eventDispatcher := services.NewEventDispatcher(eventDispatchLogger)
+	eventDispatcher.Start()
+
 	log.Println("[Cron] 定时任务服务已启动，等待退出信号...")
 
 	quit := make(chan os.Signal, 1)
@@ -82,6 +94,7 @@ func main() {
 	maintenanceScheduler.Stop()
 	metricsCollector.Stop()
 	orderStatsScheduler.Stop()
+	eventDispatcher.Stop()
 
 	database.CloseRedis()
 	database.Close()
@@ -101,6 +114,9 @@ func main() {
 	if closeOrderStatsLog != nil {
 		closeOrderStatsLog()
 	}
+	if closeEventDispatchLog != nil {
+		closeEventDispatchLog()
+	}
 
 	log.Println("[Cron] 定时任务服务已退出")
 	os.Exit(0)
@@ -9,23 +9,104 @@ import (
 
 // Config 全局配置结构
 type Config struct {
-	App     AppConfig     `yaml:"app"`
-	MySQL   MySQLConfig   `yaml:"mysql"`
-	Redis   RedisConfig   `yaml:"redis"`
-	Shopee  ShopeeConfig  `yaml:"shopee"`
-	Log     LogConfig     `yaml:"log"`
-	JWT     JWTConfig     `yaml:"jwt"`
-	Email   EmailConfig   `yaml:"email"`
-	Payment PaymentConfig `yaml:"payment"`
+	App      AppConfig      `yaml:"app"`
+	MySQL    MySQLConfig    `yaml:"mysql"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Shopee   ShopeeConfig   `yaml:"shopee"`
+	Log      LogConfig      `yaml:"log"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Email    EmailConfig    `yaml:"email"`
+	Payment  PaymentConfig  `yaml:"payment"`
+	Payout   PayoutConfig   `yaml:"payout"`
+	Withdraw WithdrawConfig `yaml:"withdraw"`
+	Approval ApprovalConfig `yaml:"approval"`
+	Crypto   CryptoConfig   `yaml:"crypto"`
+}
+
+// CryptoConfig 字段级加密配置（收款账户号码/收款人、虾皮令牌等敏感字段）
+// 主密钥为 base64 编码的 32 字节 AES-256 密钥；轮换时新增密钥并切换 active_key_id，执行 keyrotate 重新加密后再删除旧密钥
+type CryptoConfig struct {
+	ActiveKeyID string            `yaml:"active_key_id"` // 新写入数据使用的主密钥 ID，为空表示不加密
+	Keys        map[string]string `yaml:"keys"`          // 主密钥 ID → 密钥
+}
+
+// ApprovalConfig 多级审批配置
+type ApprovalConfig struct {
+	Policies []ApprovalPolicyConfig `yaml:"policies"`
+}
+
+// ApprovalPolicyConfig 审批门槛（按操作类型 + 币种匹配，币种为空表示该操作的默认门槛；金额达到 min_amount 需 approvers 个不同审批人，取满足的最高档，未命中时 1 人审批）
+type ApprovalPolicyConfig struct {
+	Operation string  `yaml:"operation"`  // withdraw / recharge / finance_audit
+	Currency  string  `yaml:"currency"`   // 申请币种
+	MinAmount float64 `yaml:"min_amount"` // 金额门槛（含）
+	Approvers int     `yaml:"approvers"`  // 需要的不同审批人数
+}
+
+// WithdrawConfig 提现策略配置
+type WithdrawConfig struct {
+	Policies []WithdrawPolicyConfig `yaml:"policies"`
+}
+
+// WithdrawPolicyConfig 提现策略（按账户类型 + 币种匹配，币种为空表示该账户类型的默认策略；金额字段为 0 表示不限制）
+type WithdrawPolicyConfig struct {
+	AccountType   string  `yaml:"account_type"`   // operator / shop_owner_commission / platform_commission / deposit
+	Currency      string  `yaml:"currency"`       // 提现账户币种
+	FeeFlat       float64 `yaml:"fee_flat"`       // 每笔固定手续费
+	FeeRate       float64 `yaml:"fee_rate"`       // 按比例手续费，0.005 表示 0.5%
+	FeeMin        float64 `yaml:"fee_min"`        // 手续费下限
+	FeeMax        float64 `yaml:"fee_max"`        // 手续费上限
+	MinAmount     float64 `yaml:"min_amount"`     // 单笔最低提现金额
+	MaxAmount     float64 `yaml:"max_amount"`     // 单笔最高提现金额
+	DailyLimit    float64 `yaml:"daily_limit"`    // 每日累计提现上限
+	MonthlyLimit  float64 `yaml:"monthly_limit"`  // 每月累计提现上限
+	DailyCount    int     `yaml:"daily_count"`    // 每日提现次数上限
+	CooldownHours int     `yaml:"cooldown_hours"` // 收款账户新增或变更后多少小时内不能提现
+}
+
+// PayoutConfig 提现银行批量代付配置（各银行的代付文件/回单格式）
+type PayoutConfig struct {
+	DefaultLayout string                        `yaml:"default_layout"` // 收款银行未配置格式时使用的格式名
+	Banks         map[string]string             `yaml:"banks"`          // 收款银行名称 → 格式名
+	Layouts       map[string]PayoutLayoutConfig `yaml:"layouts"`        // 格式名 → 代付文件格式
+}
+
+// PayoutLayoutConfig 代付文件格式（CSV）
+type PayoutLayoutConfig struct {
+	Delimiter    string               `yaml:"delimiter"`     // 分隔符，默认逗号
+	Header       bool                 `yaml:"header"`        // 是否输出标题行
+	AmountFormat string               `yaml:"amount_format"` // decimal(两位小数) / cents(最小货币单位整数)
+	DateFormat   string               `yaml:"date_format"`   // date 字段格式，默认 20060102
+	Columns      []PayoutColumnConfig `yaml:"columns"`       // 代付文件列
+	Result       PayoutResultConfig   `yaml:"result"`        // 银行回单格式
+}
+
+// PayoutColumnConfig 代付文件列：Field 取值于明细字段，Field 为空时输出固定值 Value
+type PayoutColumnConfig struct {
+	Title string `yaml:"title"`
+	Field string `yaml:"field"` // batch_no/line_no/reference/bank_name/bank_branch/account_no/account_name/payee/amount/currency/remark/date
+	Value string `yaml:"value"`
+}
+
+// PayoutResultConfig 银行回单格式（CSV，首行为标题行，按标题匹配列）
+type PayoutResultConfig struct {
+	ReferenceColumn string   `yaml:"reference_column"` // 代付参考号（提现申请单号）列
+	StatusColumn    string   `yaml:"status_column"`    // 结果状态列
+	MessageColumn   string   `yaml:"message_column"`   // 失败原因列
+	BankRefColumn   string   `yaml:"bank_ref_column"`  // 银行流水号列
+	SuccessValues   []string `yaml:"success_values"`   // 视为成功的状态值
+	FailureValues   []string `yaml:"failure_values"`   // 视为失败的状态值，其余状态视为处理中
 }
 
 // PaymentConfig 第三方支付配置（预留，待对接时填入真实密钥）
 type PaymentConfig struct {
-	PayPal  PayPalConfig  `yaml:"paypal"`
-	Alipay  AlipayConfig  `yaml:"alipay"`
-	LinePay LinePayConfig `yaml:"linepay"`
-	Visa    VisaConfig    `yaml:"visa"`
-	Wechat  WechatConfig  `yaml:"wechat"`
+	PayPal             PayPalConfig  `yaml:"paypal"`
+	Alipay             AlipayConfig  `yaml:"alipay"`
+	LinePay            LinePayConfig `yaml:"linepay"`
+	Visa               VisaConfig    `yaml:"visa"`
+	Wechat             WechatConfig  `yaml:"wechat"`
+	PollAfterMinutes   int           `yaml:"poll_after_minutes"`   // 线上充值待支付超过该分钟数后主动查询网关（补漏回调），默认 10
+	ExpireAfterMinutes int           `yaml:"expire_after_minutes"` // 线上充值超过该分钟数仍未支付则关闭，默认 60
 }
 
 // PayPalConfig PayPal 配置
@@ -56,7 +137,9 @@ type LinePayConfig struct {
 	Sandbox       bool   `yaml:"sandbox"`
 	ChannelID     string `yaml:"channel_id"`
 	ChannelSecret string `yaml:"channel_secret"`
-	ReturnURL     string `yaml:"return_url"`
+	APIBaseURL    string `yaml:"api_base_url"` // 可选：覆盖 API 地址（联调桩服务），为空时按 sandbox 选择官方地址
+	NotifyURL     string `yaml:"notify_url"`   // 付款授权后 LINE Pay 跳转回本服务的地址（/payment/notify/linepay）
+	ReturnURL     string `yaml:"return_url"`   // 入账完成后跳转的前端页面
 	CancelURL     string `yaml:"cancel_url"`
 }
 
@@ -204,3 +287,8 @@ func Load(path string) (*Config, error) {
 func Get() *Config {
 	return globalConfig
 }
+
+// Set 设置全局配置（测试中注入配置使用，如指向离线虾皮模拟服务）
+func Set(cfg *Config) {
+	globalConfig = cfg
+}
@@ -164,6 +164,22 @@ const (
 	RouteAuthResetPassword = "/auth/reset-password"
 )
 
+// ==================== 第三方支付回调路由（无需登录） ====================
+
+const (
+	RoutePaymentNotify = "/payment/notify/:method"
+)
+
+// ==================== 事件订阅路由（登录用户通用） ====================
+
+const (
+	RouteWebhookSubscriptions      = "/webhooks/subscriptions"
+	RouteWebhookSubscriptionDetail = "/webhooks/subscriptions/:id"
+	RouteWebhookSubscriptionRotate = "/webhooks/subscriptions/:id/rotate-secret"
+	RouteWebhookDeliveries         = "/webhooks/deliveries"
+	RouteWebhookDeliveryRedeliver  = "/webhooks/deliveries/:id/redeliver"
+)
+
 // ==================== 店主路由 (shopower) ====================
 
 const (
@@ -1,11 +1,13 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"balance/backend/internal/config"
 
+	gomysql "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -53,6 +55,12 @@ func GetDB() *gorm.DB {
 	return db
 }
 
+// IsDuplicateKeyError 是否为唯一索引冲突错误 (MySQL 1062)
+func IsDuplicateKeyError(err error) bool {
+	var mysqlErr *gomysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
 // Close 关闭数据库连接
 func Close() error {
 	if db != nil {
@@ -46,6 +46,11 @@ func GetOrderSettlementTableName(shopID uint64) string {
 	return fmt.Sprintf("order_settlements_%d", GetShardIndex(shopID))
 }
 
+// GetOrderSettlementAdjustmentTableName 获取结算调账明细表名
+func GetOrderSettlementAdjustmentTableName(shopID uint64) string {
+	return fmt.Sprintf("order_settlement_adjustments_%d", GetShardIndex(shopID))
+}
+
 // GetOrderShipmentRecordTableName 获取订单发货记录表名
 func GetOrderShipmentRecordTableName(shopID uint64) string {
 	return fmt.Sprintf("order_shipment_records_%d", GetShardIndex(shopID))
@@ -96,6 +101,11 @@ func (s *ShardedDB) OrderSettlementTable(shopID uint64) *gorm.DB {
 	return s.db.Table(GetOrderSettlementTableName(shopID))
 }
 
+// OrderSettlementAdjustmentTable 获取指定shop_id的结算调账明细表DB
+func (s *ShardedDB) OrderSettlementAdjustmentTable(shopID uint64) *gorm.DB {
+	return s.db.Table(GetOrderSettlementAdjustmentTableName(shopID))
+}
+
 // OrderShipmentRecordTable 获取指定shop_id的订单发货记录表DB
 func (s *ShardedDB) OrderShipmentRecordTable(shopID uint64) *gorm.DB {
 	return s.db.Table(GetOrderShipmentRecordTableName(shopID))
@@ -59,6 +59,46 @@ var (
 		[]string{"status"},
 	)
 
+	AccountReconciliationDriftAccounts = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "account_reconciliation_drift_accounts",
+			Help: "Number of accounts whose balances drift from their transaction ledger in the latest reconciliation",
+		},
+		[]string{"account_type"},
+	)
+
+	OrderReconciliationMismatches = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "order_reconciliation_mismatches",
+			Help: "Number of orders whose escrow detail, wallet income and settlement disagree in the latest order reconciliation",
+		},
+		[]string{"mismatch_type"},
+	)
+
+	WebhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Total number of outgoing event webhook delivery attempts",
+		},
+		[]string{"event_type", "result"},
+	)
+
+	OnlineRechargePollResults = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "online_recharge_poll_results_total",
+			Help: "Total number of online recharges handled by the pending-payment poller, by outcome",
+		},
+		[]string{"payment_method", "result"},
+	)
+
+	AutoWithdrawRuns = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_withdraw_runs_total",
+			Help: "Total number of scheduled automatic withdrawal runs, by account type and outcome",
+		},
+		[]string{"account_type", "result"},
+	)
+
 	// ==================== 系统指标 ====================
 
 	// 分布式锁
@@ -6,15 +6,15 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-// PrepaymentAccount 预付款账户（店主发货前预付成本）
+// PrepaymentAccount 预付款账户（店主发货前预付成本，按币种分账户）
 type PrepaymentAccount struct {
 	ID            uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
-	AdminID       int64           `gorm:"not null;uniqueIndex;comment:店铺老板ID" json:"admin_id"`
+	AdminID       int64           `gorm:"not null;uniqueIndex:uk_admin_currency;comment:店铺老板ID" json:"admin_id"`
 	Balance       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:可用余额" json:"balance"`
 	PendingAmount decimal.Decimal `gorm:"type:decimal(15,2);column:pending_amount;not null;default:0.00;comment:待结算金额(订单入系统时已扣除)" json:"pending_amount"`
 	TotalRecharge decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计充值" json:"total_recharge"`
 	TotalConsume  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计消费" json:"total_consume"`
-	Currency      string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Currency      string          `gorm:"size:10;not null;default:'TWD';uniqueIndex:uk_admin_currency;comment:货币代码(每个用户每个币种一个账户)" json:"currency"`
 	Status        int8            `gorm:"not null;default:1;comment:状态(1正常/2暂停)" json:"status"`
 	CreatedAt     time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt     time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
@@ -29,7 +29,7 @@ type DepositAccount struct {
 	ID             uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
 	AdminID        int64           `gorm:"not null;uniqueIndex;comment:店铺老板ID" json:"admin_id"`
 	Balance        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:保证金余额" json:"balance"`
-	RequiredAmount decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:应缴保证金" json:"required_amount"`
+	RequiredAmount decimal.Decimal `gorm:"type:decimal(15,2);not null;default:15000.00;comment:应缴保证金" json:"required_amount"`
 	Currency       string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
 	Status         int8            `gorm:"not null;default:1;comment:状态(1正常/2不足/3暂停)" json:"status"`
 	CreatedAt      time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
@@ -40,15 +40,23 @@ func (DepositAccount) TableName() string {
 	return "deposit_accounts"
 }
 
-// OperatorAccount 运营老板账户（运营收到的成本+分成）
+// 保证金账户状态常量（余额低于应缴保证金时自动置为不足，暂停只能由平台手动设置）
+const (
+	DepositStatusNormal       = 1 // 正常
+	DepositStatusInsufficient = 2 // 不足（暂停绑定新店铺与订单入系统）
+	DepositStatusSuspended    = 3 // 暂停
+)
+
+// OperatorAccount 运营老板账户（运营收到的成本+分成，按币种分账户）
 type OperatorAccount struct {
 	ID             uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
-	AdminID        int64           `gorm:"not null;uniqueIndex;comment:运营老板ID" json:"admin_id"`
+	AdminID        int64           `gorm:"not null;uniqueIndex:uk_admin_currency;comment:运营老板ID" json:"admin_id"`
 	Balance        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:可用余额" json:"balance"`
 	PendingAmount  decimal.Decimal `gorm:"type:decimal(15,2);column:pending_amount;not null;default:0.00;comment:待结算金额(提现申请时暂扣)" json:"pending_amount"`
+	HeldAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:风控暂扣金额(生效中的暂扣合计，计入余额但不可提现)" json:"held_amount"`
 	TotalEarnings  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计收益" json:"total_earnings"`
 	TotalWithdrawn decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计提现" json:"total_withdrawn"`
-	Currency       string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Currency       string          `gorm:"size:10;not null;default:'TWD';uniqueIndex:uk_admin_currency;comment:货币代码(每个用户每个币种一个账户)" json:"currency"`
 	Status         int8            `gorm:"not null;default:1;comment:状态(1正常/2暂停)" json:"status"`
 	CreatedAt      time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt      time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
@@ -62,8 +70,10 @@ func (OperatorAccount) TableName() string {
 type AccountTransaction struct {
 	ID              uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
 	TransactionNo   string          `gorm:"size:64;not null;uniqueIndex;comment:流水号" json:"transaction_no"`
+	IdempotencyKey  string          `gorm:"size:128;not null;uniqueIndex;comment:幂等键(调用方未指定时等于流水号)" json:"idempotency_key"`
 	AccountType     string          `gorm:"size:20;not null;index;comment:账户类型" json:"account_type"`
 	AdminID         int64           `gorm:"not null;index;comment:账户所属用户ID" json:"admin_id"`
+	Currency        string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
 	TransactionType string          `gorm:"size:30;not null;index;comment:交易类型" json:"transaction_type"`
 	Amount          decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:金额(正入账/负出账)" json:"amount"`
 	BalanceBefore   decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:交易前余额" json:"balance_before"`
@@ -74,21 +84,24 @@ type AccountTransaction struct {
 	OperatorID      int64           `gorm:"not null;default:0;comment:操作人ID" json:"operator_id"`
 	Status          int8            `gorm:"not null;default:1;index;comment:状态(0待审批/1已完成/2已拒绝)" json:"status"`
 	CreatedAt       time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+
+	Replayed bool `gorm:"-" json:"-"` // 幂等重放：本次调用未动账，返回的是首次入账的流水
 }
 
 func (AccountTransaction) TableName() string {
 	return "account_transactions"
 }
 
-// ShopOwnerCommissionAccount 店主佣金账户（店主利润分成）
+// ShopOwnerCommissionAccount 店主佣金账户（店主利润分成，按币种分账户）
 type ShopOwnerCommissionAccount struct {
 	ID             uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
-	AdminID        int64           `gorm:"not null;uniqueIndex;comment:店铺老板ID" json:"admin_id"`
+	AdminID        int64           `gorm:"not null;uniqueIndex:uk_admin_currency;comment:店铺老板ID" json:"admin_id"`
 	Balance        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:可用余额" json:"balance"`
 	PendingAmount  decimal.Decimal `gorm:"type:decimal(15,2);column:pending_amount;not null;default:0.00;comment:待结算金额(提现申请时暂扣)" json:"pending_amount"`
+	HeldAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:风控暂扣金额(生效中的暂扣合计，计入余额但不可提现)" json:"held_amount"`
 	TotalEarnings  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计收益" json:"total_earnings"`
 	TotalWithdrawn decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计提现" json:"total_withdrawn"`
-	Currency       string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Currency       string          `gorm:"size:10;not null;default:'TWD';uniqueIndex:uk_admin_currency;comment:货币代码(每个用户每个币种一个账户)" json:"currency"`
 	Status         int8            `gorm:"not null;default:1;comment:状态(1正常/2暂停)" json:"status"`
 	CreatedAt      time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt      time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
@@ -98,14 +111,14 @@ func (ShopOwnerCommissionAccount) TableName() string {
 	return "shop_owner_commission_accounts"
 }
 
-// PlatformCommissionAccount 平台佣金账户（平台利润分成，单例）
+// PlatformCommissionAccount 平台佣金账户（平台利润分成，每个币种一个）
 type PlatformCommissionAccount struct {
 	ID             uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
 	Balance        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:可用余额" json:"balance"`
 	PendingAmount  decimal.Decimal `gorm:"type:decimal(15,2);column:pending_amount;not null;default:0.00;comment:待结算金额(提现申请时暂扣)" json:"pending_amount"`
 	TotalEarnings  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计收益" json:"total_earnings"`
 	TotalWithdrawn decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计提现" json:"total_withdrawn"`
-	Currency       string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Currency       string          `gorm:"size:10;not null;default:'TWD';uniqueIndex;comment:货币代码(每个币种一个账户)" json:"currency"`
 	Status         int8            `gorm:"not null;default:1;comment:状态(1正常/2暂停)" json:"status"`
 	CreatedAt      time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt      time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
@@ -115,14 +128,14 @@ func (PlatformCommissionAccount) TableName() string {
 	return "platform_commission_accounts"
 }
 
-// PenaltyBonusAccount 罚补账户（运营罚款和补贴）
+// PenaltyBonusAccount 罚补账户（每个用户每币种一条，汇总已审核罚补单的待结算金额）
 type PenaltyBonusAccount struct {
-	ID           uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
-	AdminID      int64           `gorm:"not null;uniqueIndex;comment:用户ID" json:"admin_id"`
+	ID           uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	AdminID      int64           `gorm:"not null;uniqueIndex:uk_admin_currency;comment:用户ID" json:"admin_id"`
 	Balance      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:余额(正待付罚款/负待发补贴)" json:"balance"`
 	TotalPenalty decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计罚款" json:"total_penalty"`
 	TotalBonus   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:累计补贴" json:"total_bonus"`
-	Currency     string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Currency     string          `gorm:"size:10;not null;default:'TWD';uniqueIndex:uk_admin_currency;comment:货币代码" json:"currency"`
 	Status       int8            `gorm:"not null;default:1;comment:状态(1正常/2暂停)" json:"status"`
 	CreatedAt    time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt    time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
@@ -140,10 +153,14 @@ type WithdrawApplication struct {
 	AccountType         string          `gorm:"size:30;not null;index;comment:账户类型" json:"account_type"`
 	Amount              decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:提现金额" json:"amount"`
 	Fee                 decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:手续费" json:"fee"`
-	ActualAmount        decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:实际到账金额" json:"actual_amount"`
-	Currency            string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	ActualAmount        decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:实际到账金额(到账币种)" json:"actual_amount"`
+	Currency            string          `gorm:"size:10;not null;default:'TWD';comment:提现账户币种" json:"currency"`
+	PayoutCurrency      string          `gorm:"size:10;not null;default:'TWD';comment:到账币种" json:"payout_currency"`
+	FxRate              decimal.Decimal `gorm:"type:decimal(18,8);not null;default:1.00000000;comment:提现币种→到账币种汇率(申请时锁定)" json:"fx_rate"`
+	FxRateID            uint64          `gorm:"not null;default:0;comment:使用的汇率记录ID(0为同币种)" json:"fx_rate_id"`
 	CollectionAccountID uint64          `gorm:"not null;comment:收款账户ID" json:"collection_account_id"`
-	Status              int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1已通过/2已拒绝/3已打款)" json:"status"`
+	Status              int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1已通过/2已拒绝/3已打款/4打款失败)" json:"status"`
+	PayoutBatchNo       string          `gorm:"size:64;not null;default:'';index;comment:锁定的代付批次号(空为未入批次)" json:"payout_batch_no"`
 	AuditRemark         string          `gorm:"size:500;not null;default:'';comment:审核备注" json:"audit_remark"`
 	AuditBy             int64           `gorm:"not null;default:0;comment:审核人ID" json:"audit_by"`
 	AuditAt             *time.Time      `gorm:"comment:审核时间" json:"audit_at"`
@@ -151,6 +168,8 @@ type WithdrawApplication struct {
 	Remark              string          `gorm:"size:500;not null;default:'';comment:申请备注" json:"remark"`
 	CreatedAt           time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
 	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	Approvals []ApprovalRecord `gorm:"-" json:"approvals,omitempty"` // 多级审批轨迹（列表查询时加载）
 }
 
 func (WithdrawApplication) TableName() string {
@@ -165,29 +184,177 @@ type RechargeRecord struct {
 	AccountType   string          `gorm:"size:30;not null;index;comment:账户类型(prepayment/deposit)" json:"account_type"`
 	Amount        decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:充值金额" json:"amount"`
 	Currency      string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
-	PaymentMethod string          `gorm:"size:30;not null;comment:支付方式(bank_transfer/cash)" json:"payment_method"`
+	PaymentMethod string          `gorm:"size:30;not null;comment:支付方式(bank_transfer/cash/linepay等)" json:"payment_method"`
 	PaymentProof  string          `gorm:"size:500;not null;default:'';comment:支付凭证图片URL" json:"payment_proof"`
-	Status        int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1已通过/2已拒绝)" json:"status"`
+	PaymentID     string          `gorm:"size:100;not null;default:'';index;comment:第三方支付单号(线上充值)" json:"payment_id"`
+	PaymentURL    string          `gorm:"size:1000;not null;default:'';comment:第三方支付页面链接(线上充值)" json:"payment_url"`
+	PaidAt        *time.Time      `gorm:"comment:线上支付完成时间" json:"paid_at"`
+	NextPollAt    *time.Time      `gorm:"comment:线上充值下次轮询网关的时间(为空时按创建时间)" json:"next_poll_at"`
+	Status        int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1已通过/2已拒绝，线上充值0为待支付)" json:"status"`
 	AuditRemark   string          `gorm:"size:500;not null;default:'';comment:审核备注" json:"audit_remark"`
 	AuditBy       int64           `gorm:"not null;default:0;comment:审核人ID" json:"audit_by"`
 	AuditAt       *time.Time      `gorm:"comment:审核时间" json:"audit_at"`
 	Remark        string          `gorm:"size:500;not null;default:'';comment:申请备注" json:"remark"`
 	CreatedAt     time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
 	UpdatedAt     time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	Approvals []ApprovalRecord `gorm:"-" json:"approvals,omitempty"` // 多级审批轨迹（列表查询时加载）
 }
 
 func (RechargeRecord) TableName() string {
 	return "recharge_record"
 }
 
+// TransactionReversal 流水冲正申请（发起人与审批人必须为不同的平台用户，只支持整笔冲正）
+type TransactionReversal struct {
+	ID                    uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	ApplicationNo         string          `gorm:"size:64;not null;uniqueIndex;comment:申请单号" json:"application_no"`
+	TransactionNo         string          `gorm:"size:64;not null;index;comment:被冲正的流水号" json:"transaction_no"`
+	TransactionID         uint64          `gorm:"not null;comment:被冲正的流水ID" json:"transaction_id"`
+	AccountType           string          `gorm:"size:30;not null;comment:账户类型" json:"account_type"`
+	AdminID               int64           `gorm:"not null;index;comment:账户所属用户ID" json:"admin_id"`
+	Currency              string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	TransactionType       string          `gorm:"size:30;not null;comment:被冲正流水的交易类型" json:"transaction_type"`
+	Amount                decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:冲正金额(与原流水金额相反)" json:"amount"`
+	Reason                string          `gorm:"size:500;not null;comment:冲正原因" json:"reason"`
+	Status                int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1已通过/2已拒绝)" json:"status"`
+	RequestedBy           int64           `gorm:"not null;comment:发起人ID" json:"requested_by"`
+	AuditBy               int64           `gorm:"not null;default:0;comment:审核人ID" json:"audit_by"`
+	AuditRemark           string          `gorm:"size:500;not null;default:'';comment:审核备注" json:"audit_remark"`
+	AuditAt               *time.Time      `gorm:"comment:审核时间" json:"audit_at"`
+	ReversalTransactionNo string          `gorm:"size:64;not null;default:'';comment:冲正流水号(审批通过后生成)" json:"reversal_transaction_no"`
+	CreatedAt             time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+	UpdatedAt             time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (TransactionReversal) TableName() string {
+	return "transaction_reversals"
+}
+
+// DepositRefundApplication 保证金退还申请（平台审批通过后直接出账）
+type DepositRefundApplication struct {
+	ID                  uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	ApplicationNo       string          `gorm:"size:64;not null;uniqueIndex;comment:申请单号" json:"application_no"`
+	AdminID             int64           `gorm:"not null;index;comment:店铺老板ID" json:"admin_id"`
+	Amount              decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:退还金额" json:"amount"`
+	Currency            string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	CollectionAccountID uint64          `gorm:"not null;comment:收款账户ID" json:"collection_account_id"`
+	Reason              string          `gorm:"size:500;not null;default:'';comment:申请原因" json:"reason"`
+	Status              int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1已退还/2已拒绝)" json:"status"`
+	AuditBy             int64           `gorm:"not null;default:0;comment:审核人ID" json:"audit_by"`
+	AuditRemark         string          `gorm:"size:500;not null;default:'';comment:审核备注" json:"audit_remark"`
+	AuditAt             *time.Time      `gorm:"comment:审核时间" json:"audit_at"`
+	TransactionNo       string          `gorm:"size:64;not null;default:'';comment:退还流水号" json:"transaction_no"`
+	CreatedAt           time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (DepositRefundApplication) TableName() string {
+	return "deposit_refund_applications"
+}
+
+// AccountHold 风控暂扣（争议期间暂扣运营/店主佣金账户的部分金额，不影响余额但不可提现）
+type AccountHold struct {
+	ID            uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	HoldNo        string          `gorm:"size:64;not null;uniqueIndex;comment:暂扣单号" json:"hold_no"`
+	AccountType   string          `gorm:"size:30;not null;index:idx_account;comment:账户类型(operator/shop_owner_commission)" json:"account_type"`
+	AdminID       int64           `gorm:"not null;index:idx_account;comment:账户所属用户ID" json:"admin_id"`
+	Currency      string          `gorm:"size:10;not null;default:'TWD';index:idx_account;comment:货币代码" json:"currency"`
+	Amount        decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:暂扣金额" json:"amount"`
+	ShopID        uint64          `gorm:"not null;default:0;comment:店铺ID" json:"shop_id"`
+	OrderSN       string          `gorm:"size:64;not null;default:'';index;comment:关联订单号" json:"order_sn"`
+	ReturnSN      string          `gorm:"size:64;not null;default:'';index;comment:关联退货单号" json:"return_sn"`
+	Reason        string          `gorm:"size:500;not null;comment:暂扣原因" json:"reason"`
+	Status        int8            `gorm:"not null;default:0;index:idx_status_expires;comment:状态(0生效中/1已释放/2已过期)" json:"status"`
+	ExpiresAt     *time.Time      `gorm:"index:idx_status_expires;comment:到期时间(为空则不自动过期)" json:"expires_at"`
+	CreatedBy     int64           `gorm:"not null;default:0;comment:创建人ID(0为系统)" json:"created_by"`
+	ReleasedBy    int64           `gorm:"not null;default:0;comment:释放人ID(0为系统)" json:"released_by"`
+	ReleaseRemark string          `gorm:"size:500;not null;default:'';comment:释放说明" json:"release_remark"`
+	ReleasedAt    *time.Time      `gorm:"comment:释放/过期时间" json:"released_at"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt     time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (AccountHold) TableName() string {
+	return "account_holds"
+}
+
+// 风控暂扣状态常量
+const (
+	HoldStatusActive   = 0 // 生效中
+	HoldStatusReleased = 1 // 已释放
+	HoldStatusExpired  = 2 // 已过期
+)
+
+// PenaltyBonusEntry 罚补单（对运营/店主的罚款或补贴，审核通过后从其下次回款中扣除/随回款发放）
+type PenaltyBonusEntry struct {
+	ID            uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	EntryNo       string          `gorm:"size:64;not null;uniqueIndex;comment:罚补单号" json:"entry_no"`
+	DedupKey      string          `gorm:"size:128;not null;uniqueIndex;comment:去重键(自动生成的提案按来源去重)" json:"-"`
+	EntryType     string          `gorm:"size:20;not null;index;comment:类型(penalty罚款/subsidy补贴)" json:"entry_type"`
+	AccountType   string          `gorm:"size:30;not null;index:idx_account;comment:结算账户类型(operator/shop_owner_commission)" json:"account_type"`
+	AdminID       int64           `gorm:"not null;index:idx_account;comment:对象用户ID" json:"admin_id"`
+	Currency      string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Amount        decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:金额(恒为正)" json:"amount"`
+	ReasonCode    string          `gorm:"size:30;not null;index;comment:原因代码" json:"reason_code"`
+	ShopID        uint64          `gorm:"not null;default:0;comment:店铺ID" json:"shop_id"`
+	OrderSN       string          `gorm:"size:64;not null;default:'';index;comment:关联订单号" json:"order_sn"`
+	Evidence      string          `gorm:"size:1000;not null;default:'';comment:证据(说明/图片链接)" json:"evidence"`
+	Remark        string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	Source        string          `gorm:"size:20;not null;default:'manual';comment:来源(manual手工/auto系统)" json:"source"`
+	Status        int8            `gorm:"not null;default:0;index;comment:状态(0待审核/1待结算/2已拒绝/3已结算/4已冲正)" json:"status"`
+	RequestedBy   int64           `gorm:"not null;default:0;comment:发起人ID(0为系统)" json:"requested_by"`
+	AuditBy       int64           `gorm:"not null;default:0;comment:审核人ID" json:"audit_by"`
+	AuditRemark   string          `gorm:"size:500;not null;default:'';comment:审核备注" json:"audit_remark"`
+	AuditAt       *time.Time      `gorm:"comment:审核时间" json:"audit_at"`
+	TransactionNo string          `gorm:"size:64;not null;default:'';comment:结算流水号" json:"transaction_no"`
+	SettledAt     *time.Time      `gorm:"comment:结算时间" json:"settled_at"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+	UpdatedAt     time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (PenaltyBonusEntry) TableName() string {
+	return "penalty_bonus_entries"
+}
+
+// 罚补单状态常量
+const (
+	PenaltyBonusStatusPending  = 0 // 待审核
+	PenaltyBonusStatusApproved = 1 // 已审核待结算（罚款等待账户余额足够时扣除）
+	PenaltyBonusStatusRejected = 2 // 已拒绝
+	PenaltyBonusStatusSettled  = 3 // 已结算
+	PenaltyBonusStatusReversed = 4 // 已冲正（结算流水被冲正）
+)
+
+// 罚补原因代码
+const (
+	PenaltyReasonLateShip   = "late_ship"  // 超时发货
+	PenaltyReasonFakeShip   = "fake_ship"  // 虚假发货
+	PenaltyReasonQuality    = "quality"    // 商品质量问题
+	PenaltyReasonService    = "service"    // 服务违规
+	PenaltyReasonCompensate = "compensate" // 平台补偿
+	PenaltyReasonIncentive  = "incentive"  // 激励奖励
+	PenaltyReasonOther      = "other"      // 其他
+)
+
+// 罚补单来源
+const (
+	PenaltyBonusSourceManual = "manual" // 平台手工录入
+	PenaltyBonusSourceAuto   = "auto"   // 系统自动生成（需审核）
+)
+
 // 提现/充值申请状态常量
 const (
 	ApplicationStatusPending  = 0 // 待审核
 	ApplicationStatusApproved = 1 // 已通过
 	ApplicationStatusRejected = 2 // 已拒绝
 	ApplicationStatusPaid     = 3 // 已打款 (仅提现)
+	ApplicationStatusFailed   = 4 // 打款失败，暂扣金额已退回 (仅提现)
 )
 
+// DefaultCurrency 默认币种（未指定币种的历史数据、保证金账户）
+const DefaultCurrency = "TWD"
+
 // 账户类型常量
 const (
 	AccountTypePrepayment          = "prepayment"            // 预付款账户
@@ -213,6 +380,9 @@ const (
 	TxTypeDepositRefund = "deposit_refund" // 保证金退还
 	TxTypeAdjustment    = "adjustment"     // 调账（虾皮退款/扣款）
 	TxTypeAdjustmentRefund = "adjustment_refund" // 调账返还（虾皮扣款时退还预付款）
+	TxTypePenalty       = "penalty"        // 罚款
+	TxTypeSubsidy       = "subsidy"        // 补贴
+	TxTypeReversal      = "reversal"       // 冲正（RelatedID 指向被冲正的流水）
 )
 
 // 账户状态常量
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ApprovalRecord 审批记录（多级审批的审批轨迹，同一申请每个审批人只能审批一次）
+type ApprovalRecord struct {
+	ID         uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	Operation  string          `gorm:"size:30;not null;uniqueIndex:uk_operation_target_approver;comment:操作类型(withdraw/recharge/finance_audit)" json:"operation"`
+	TargetNo   string          `gorm:"size:64;not null;uniqueIndex:uk_operation_target_approver;comment:申请单号/流水号" json:"target_no"`
+	ApproverID int64           `gorm:"not null;uniqueIndex:uk_operation_target_approver;index;comment:审批人ID" json:"approver_id"`
+	AdminID    int64           `gorm:"not null;comment:申请人ID" json:"admin_id"`
+	Currency   string          `gorm:"size:10;not null;comment:币种" json:"currency"`
+	Amount     decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:审批金额" json:"amount"`
+	Action     string          `gorm:"size:10;not null;comment:审批动作(approve/reject)" json:"action"`
+	Level      int             `gorm:"not null;comment:第几级审批" json:"level"`
+	Required   int             `gorm:"not null;comment:审批时需要的审批人数" json:"required"`
+	Remark     string          `gorm:"size:500;not null;default:'';comment:审批备注" json:"remark"`
+	CreatedAt  time.Time       `gorm:"autoCreateTime;comment:审批时间" json:"created_at"`
+}
+
+func (ApprovalRecord) TableName() string {
+	return "approval_records"
+}
+
+// 审批操作类型
+const (
+	ApprovalOperationWithdraw     = "withdraw"      // 提现申请
+	ApprovalOperationRecharge     = "recharge"      // 线下充值申请
+	ApprovalOperationFinanceAudit = "finance_audit" // 财务审核流水（手工出入账）
+)
+
+// 审批动作
+const (
+	ApprovalActionApprove = "approve"
+	ApprovalActionReject  = "reject"
+)
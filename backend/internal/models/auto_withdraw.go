@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AutoWithdrawSetting 自动提现设置（按周/月定时将账户可提现余额扫入指定收款账户，每个用户每个账户类型每个币种一条）
+type AutoWithdrawSetting struct {
+	ID                  uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	AdminID             int64           `gorm:"not null;uniqueIndex:uk_admin_account_currency;comment:用户ID" json:"admin_id"`
+	AccountType         string          `gorm:"size:30;not null;uniqueIndex:uk_admin_account_currency;comment:提现账户类型" json:"account_type"`
+	Currency            string          `gorm:"size:10;not null;default:'TWD';uniqueIndex:uk_admin_account_currency;comment:提现币种" json:"currency"`
+	PayoutCurrency      string          `gorm:"size:10;not null;default:'TWD';comment:到账币种" json:"payout_currency"`
+	CollectionAccountID uint64          `gorm:"not null;comment:收款账户ID" json:"collection_account_id"`
+	Frequency           string          `gorm:"size:10;not null;comment:频率(weekly/monthly)" json:"frequency"`
+	DayOfWeek           int             `gorm:"not null;default:1;comment:每周几执行(0周日~6周六)" json:"day_of_week"`
+	DayOfMonth          int             `gorm:"not null;default:1;comment:每月几号执行(1~28)" json:"day_of_month"`
+	Threshold           decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:触发金额(可提取金额达到该值才提现)" json:"threshold"`
+	KeepMinimum         decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:账户保留金额" json:"keep_minimum"`
+	Enabled             bool            `gorm:"not null;default:true;index;comment:是否启用" json:"enabled"`
+	NextRunAt           time.Time       `gorm:"not null;index;comment:下次执行时间" json:"next_run_at"`
+	LastRunAt           *time.Time      `gorm:"comment:最近执行时间" json:"last_run_at"`
+	LastResult          string          `gorm:"size:20;not null;default:'';comment:最近执行结果(created/skipped/failed)" json:"last_result"`
+	LastReason          string          `gorm:"size:500;not null;default:'';comment:最近跳过/失败原因" json:"last_reason"`
+	CreatedAt           time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (AutoWithdrawSetting) TableName() string {
+	return "auto_withdraw_settings"
+}
+
+// 自动提现频率
+const (
+	AutoWithdrawWeekly  = "weekly"
+	AutoWithdrawMonthly = "monthly"
+)
+
+// AutoWithdrawRun 自动提现执行记录（每个设置每个计划时间一条，记录创建的提现申请或跳过原因）
+type AutoWithdrawRun struct {
+	ID            uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	SettingID     uint64          `gorm:"not null;uniqueIndex:uk_setting_scheduled;comment:自动提现设置ID" json:"setting_id"`
+	ScheduledAt   time.Time       `gorm:"not null;uniqueIndex:uk_setting_scheduled;comment:计划执行时间" json:"scheduled_at"`
+	AdminID       int64           `gorm:"not null;index;comment:用户ID" json:"admin_id"`
+	AccountType   string          `gorm:"size:30;not null;comment:提现账户类型" json:"account_type"`
+	Currency      string          `gorm:"size:10;not null;comment:提现币种" json:"currency"`
+	Result        string          `gorm:"size:20;not null;comment:结果(created/skipped/failed)" json:"result"`
+	Amount        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:提现金额" json:"amount"`
+	ApplicationNo string          `gorm:"size:64;not null;default:'';comment:提现申请单号" json:"application_no"`
+	Reason        string          `gorm:"size:500;not null;default:'';comment:跳过/失败原因" json:"reason"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (AutoWithdrawRun) TableName() string {
+	return "auto_withdraw_runs"
+}
+
+// 自动提现执行结果
+const (
+	AutoWithdrawResultCreated = "created" // 已创建提现申请
+	AutoWithdrawResultSkipped = "skipped" // 不满足条件跳过（账户冻结、余额不足、存在风控暂扣等）
+	AutoWithdrawResultFailed  = "failed"  // 申请失败（超过提现限额、收款账户冷却期等）
+)
@@ -6,18 +6,19 @@ import (
 
 // CollectionAccount 收款账户模型（用户提现收款账户）
 type CollectionAccount struct {
-	ID          uint64    `gorm:"primaryKey;comment:主键ID" json:"id"`
-	AdminID     int64     `gorm:"not null;index;comment:用户ID" json:"admin_id"`
-	AccountType string    `gorm:"size:20;not null;comment:账户类型(wallet/bank)" json:"account_type"`
-	AccountName string    `gorm:"size:100;not null;comment:账户名称" json:"account_name"`
-	AccountNo   string    `gorm:"size:100;not null;comment:账户号码" json:"account_no"`
-	BankName    string    `gorm:"size:100;not null;default:'';comment:银行名称" json:"bank_name"`
-	BankBranch  string    `gorm:"size:200;not null;default:'';comment:银行支行" json:"bank_branch"`
-	Payee       string    `gorm:"size:100;not null;comment:收款人姓名" json:"payee"`
-	IsDefault   bool      `gorm:"default:false;comment:是否默认账户" json:"is_default"`
-	Status      int8      `gorm:"default:1;comment:状态(1正常/2未激活)" json:"status"`
-	CreatedAt   time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+	ID          uint64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	AdminID     int64      `gorm:"not null;index;comment:用户ID" json:"admin_id"`
+	AccountType string     `gorm:"size:20;not null;comment:账户类型(wallet/bank)" json:"account_type"`
+	AccountName string     `gorm:"size:100;not null;comment:账户名称" json:"account_name"`
+	AccountNo   string     `gorm:"size:512;not null;serializer:encrypted;comment:账户号码(加密存储)" json:"account_no"`
+	BankName    string     `gorm:"size:100;not null;default:'';comment:银行名称" json:"bank_name"`
+	BankBranch  string     `gorm:"size:200;not null;default:'';comment:银行支行" json:"bank_branch"`
+	Payee       string     `gorm:"size:512;not null;serializer:encrypted;comment:收款人姓名(加密存储)" json:"payee"`
+	IsDefault   bool       `gorm:"default:false;comment:是否默认账户" json:"is_default"`
+	Status      int8       `gorm:"default:1;comment:状态(1正常/2未激活)" json:"status"`
+	ChangedAt   *time.Time `gorm:"comment:收款信息最近变更时间(为空取创建时间，提现冷却期据此计算)" json:"changed_at"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
 }
 
 func (CollectionAccount) TableName() string {
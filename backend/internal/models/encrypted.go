@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"balance/backend/internal/utils"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer 字段加密序列化器（标签 serializer:encrypted）：写入时用当前主密钥加密，读取时解密；
+// 加密上线前写入的明文可正常读取，由 keyrotate 批量加密。
+// 注意：Updates(map) 不经过序列化器，加密字段必须用结构体更新。
+type EncryptedSerializer struct{}
+
+// Scan 读取时解密
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("加密字段 %s 类型不支持: %T", field.Name, dbValue)
+	}
+	plaintext, err := utils.DecryptField(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+// Value 写入时加密
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("加密字段 %s 类型不支持: %T", field.Name, fieldValue)
+	}
+	return utils.EncryptField(value)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FxRate 汇率（按生效时间取值：同一币种对取 effective_from <= 业务时间 的最新一条）
+type FxRate struct {
+	ID            uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	BaseCurrency  string          `gorm:"size:10;not null;index:idx_pair_effective;comment:基准币种" json:"base_currency"`
+	QuoteCurrency string          `gorm:"size:10;not null;index:idx_pair_effective;comment:报价币种" json:"quote_currency"`
+	Rate          decimal.Decimal `gorm:"type:decimal(18,8);not null;comment:汇率(1单位基准币种可兑换的报价币种数量)" json:"rate"`
+	EffectiveFrom time.Time       `gorm:"not null;index:idx_pair_effective;comment:生效时间" json:"effective_from"`
+	Source        string          `gorm:"size:30;not null;default:'manual';comment:来源(manual/bank等)" json:"source"`
+	Remark        string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	CreatedBy     int64           `gorm:"not null;default:0;comment:录入人ID" json:"created_by"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (FxRate) TableName() string {
+	return "fx_rates"
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SettlementInvoice 结算发票（运营/店主 + 周期 + 币种一张，汇总周期内结算单与调账，作废后可重新开具）
+// 运营发票金额为运营实际收入（成本 + 运营分成），店主发票金额为店主分成
+type SettlementInvoice struct {
+	ID              uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	InvoiceNo       string          `gorm:"size:64;not null;uniqueIndex;comment:发票号" json:"invoice_no"`
+	PartyType       string          `gorm:"size:20;not null;index:idx_party;comment:开票对象类型(operator/shop_owner)" json:"party_type"`
+	AdminID         int64           `gorm:"not null;index:idx_party;comment:开票对象用户ID" json:"admin_id"`
+	PeriodType      string          `gorm:"size:10;not null;index:idx_period;comment:周期类型(weekly/monthly)" json:"period_type"`
+	Period          string          `gorm:"size:10;not null;index:idx_period;comment:周期(YYYY-MM 或 YYYY-Www)" json:"period"`
+	PeriodStart     time.Time       `gorm:"not null;comment:周期开始时间(含)" json:"period_start"`
+	PeriodEnd       time.Time       `gorm:"not null;comment:周期结束时间(不含)" json:"period_end"`
+	Currency        string          `gorm:"size:10;not null;comment:结算币种" json:"currency"`
+	OrderCount      int64           `gorm:"not null;default:0;comment:结算订单数" json:"order_count"`
+	AdjustmentCount int64           `gorm:"not null;default:0;comment:调账笔数" json:"adjustment_count"`
+	EscrowAmount    decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:Shopee结算金额合计" json:"escrow_amount"`
+	CostAmount      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:成本合计(仅运营)" json:"cost_amount"`
+	ShareAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:分成合计(含调账)" json:"share_amount"`
+	TotalAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:发票金额" json:"total_amount"`
+	Status          int8            `gorm:"not null;default:0;index;comment:状态(0已开具/1已付款/2已作废)" json:"status"`
+	IssuedAt        time.Time       `gorm:"not null;comment:开具时间" json:"issued_at"`
+	PaidAt          *time.Time      `gorm:"comment:付款时间" json:"paid_at"`
+	PaymentRef      string          `gorm:"size:100;not null;default:'';comment:付款凭证号" json:"payment_ref"`
+	VoidedBy        int64           `gorm:"not null;default:0;comment:作废人ID" json:"voided_by"`
+	VoidedAt        *time.Time      `gorm:"comment:作废时间" json:"voided_at"`
+	VoidReason      string          `gorm:"size:500;not null;default:'';comment:作废原因" json:"void_reason"`
+	CreatedBy       int64           `gorm:"not null;default:0;comment:开具人ID(0为系统)" json:"created_by"`
+	CreatedAt       time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt       time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	Lines []SettlementInvoiceLine `gorm:"-" json:"lines,omitempty"`
+}
+
+func (SettlementInvoice) TableName() string {
+	return "settlement_invoices"
+}
+
+// SettlementInvoiceLine 结算发票明细（结算单或调账明细一条）
+type SettlementInvoiceLine struct {
+	ID              uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	InvoiceNo       string          `gorm:"size:64;not null;index;comment:发票号" json:"invoice_no"`
+	LineType        string          `gorm:"size:20;not null;comment:明细类型(settlement/adjustment)" json:"line_type"`
+	ShopID          uint64          `gorm:"not null;comment:店铺ID" json:"shop_id"`
+	OrderSN         string          `gorm:"size:64;not null;index;comment:订单编号" json:"order_sn"`
+	SettlementNo    string          `gorm:"size:64;not null;default:'';comment:结算单号" json:"settlement_no"`
+	TransactionType string          `gorm:"size:64;not null;default:'';comment:调账类型" json:"transaction_type"`
+	EscrowAmount    decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:Shopee结算金额(调账为调账金额)" json:"escrow_amount"`
+	CostAmount      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:成本(仅运营结算单)" json:"cost_amount"`
+	ShareRate       decimal.Decimal `gorm:"type:decimal(5,2);not null;default:0.00;comment:分成比例%" json:"share_rate"`
+	ShareAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:分成金额" json:"share_amount"`
+	Amount          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:明细金额" json:"amount"`
+	OccurredAt      time.Time       `gorm:"not null;comment:结算/调账时间" json:"occurred_at"`
+	CreatedAt       time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (SettlementInvoiceLine) TableName() string {
+	return "settlement_invoice_lines"
+}
+
+// 发票开票对象类型
+const (
+	InvoicePartyOperator  = "operator"   // 运营
+	InvoicePartyShopOwner = "shop_owner" // 店主
+)
+
+// 发票周期类型
+const (
+	InvoicePeriodWeekly  = "weekly"  // 按 ISO 周（周一至周日）
+	InvoicePeriodMonthly = "monthly" // 按自然月
+)
+
+// 发票明细类型
+const (
+	InvoiceLineSettlement = "settlement" // 结算单
+	InvoiceLineAdjustment = "adjustment" // 调账明细
+)
+
+// 发票状态
+const (
+	InvoiceIssued = 0 // 已开具
+	InvoicePaid   = 1 // 已付款
+	InvoiceVoided = 2 // 已作废
+)
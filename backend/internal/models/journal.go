@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountJournal 记账凭证（一次业务事件对应一张凭证，下挂按币种借贷平衡的分录）
+type AccountJournal struct {
+	ID          uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	JournalNo   string          `gorm:"size:64;not null;uniqueIndex;comment:凭证号" json:"journal_no"`
+	BizType     string          `gorm:"size:30;not null;index;comment:业务类型" json:"biz_type"`
+	OrderSN     string          `gorm:"size:64;not null;default:'';index;comment:关联订单号" json:"order_sn"`
+	BizNo       string          `gorm:"size:64;not null;default:'';index;comment:关联业务单号(结算单/申请单)" json:"biz_no"`
+	TotalAmount decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:借方合计(=贷方合计，多币种凭证为各币种之和)" json:"total_amount"`
+	Remark      string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	OperatorID  int64           `gorm:"not null;default:0;comment:操作人ID" json:"operator_id"`
+	CreatedAt   time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+
+	Postings []AccountJournalPosting `gorm:"-" json:"postings,omitempty"`
+}
+
+func (AccountJournal) TableName() string {
+	return "account_journals"
+}
+
+// AccountJournalPosting 凭证分录（单个账户的一笔借/贷）
+// 用户账户均为平台负债：贷方=余额增加，借方=余额减少
+type AccountJournalPosting struct {
+	ID            uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	JournalNo     string          `gorm:"size:64;not null;index;comment:凭证号" json:"journal_no"`
+	AccountType   string          `gorm:"size:30;not null;index:idx_account;comment:账户类型" json:"account_type"`
+	AdminID       int64           `gorm:"not null;index:idx_account;comment:账户所属用户ID(平台/清算科目为0)" json:"admin_id"`
+	Currency      string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Bucket        string          `gorm:"size:20;not null;comment:余额分类(available/pending)" json:"bucket"`
+	Direction     string          `gorm:"size:10;not null;comment:借贷方向(debit/credit)" json:"direction"`
+	Amount        decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:金额(恒为正)" json:"amount"`
+	TransactionNo string          `gorm:"size:64;not null;default:'';index;comment:关联账户流水号(清算科目为空)" json:"transaction_no"`
+	Remark        string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (AccountJournalPosting) TableName() string {
+	return "account_journal_postings"
+}
+
+// 凭证业务类型常量
+const (
+	JournalBizRecharge       = "recharge"        // 预付款充值
+	JournalBizDepositPay     = "deposit_pay"     // 保证金缴纳
+	JournalBizOrderFreeze    = "order_freeze"    // 订单入系统扣除预付款
+	JournalBizOrderRefund    = "order_refund"    // 订单取消/退款返还预付款
+	JournalBizSettlement     = "settlement"      // 订单结算分账
+	JournalBizAdjustment     = "adjustment"      // 虾皮调账
+	JournalBizWithdrawApply  = "withdraw_apply"  // 提现申请暂扣
+	JournalBizWithdrawReject = "withdraw_reject" // 提现拒绝返还
+	JournalBizWithdrawPaid   = "withdraw_paid"   // 提现打款
+	JournalBizReversal       = "reversal"        // 流水冲正
+	JournalBizDepositDeduct  = "deposit_deduct"  // 保证金扣罚
+	JournalBizDepositRefund  = "deposit_refund"  // 保证金退还
+	JournalBizPenaltyBonus   = "penalty_bonus"   // 罚补单结算
+)
+
+// 分录借贷方向
+const (
+	JournalDebit  = "debit"  // 借：账户余额减少
+	JournalCredit = "credit" // 贷：账户余额增加
+)
+
+// 分录余额分类
+const (
+	JournalBucketAvailable = "available" // 可用余额 (balance)
+	JournalBucketPending   = "pending"   // 待结算/暂扣金额 (pending_amount)
+)
+
+// 清算科目（系统外资金的对手方，不对应任何用户账户）
+const (
+	ClearingAccountBank            = "bank"                  // 银行存款：线下充值入金/提现出金
+	ClearingAccountEscrow          = AccountTypeEscrow       // 虾皮托管：结算/调账时虾皮打入的金额
+	ClearingAccountUnallocatedLoss = "unallocated_loss"      // 未分摊亏损：负利润订单中未向任何一方扣回的部分
+	ClearingAccountPenaltyBonus    = AccountTypePenaltyBonus // 罚补：平台对用户的罚款收入/补贴支出
+)
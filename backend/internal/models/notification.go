@@ -22,4 +22,6 @@ func (Notification) TableName() string {
 // 消息类型常量
 const (
 	NotifyTypePrepaymentLow = "prepayment_low" // 预付款不足
+	NotifyTypeDepositShort  = "deposit_short"  // 保证金不足
+	NotifyTypeAutoWithdraw  = "auto_withdraw"  // 自动提现结果
 )
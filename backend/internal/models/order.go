@@ -11,6 +11,7 @@ const (
 	PrepaymentUnchecked    = 0 // 未检查
 	PrepaymentSufficient   = 1 // 预付款充足
 	PrepaymentInsufficient = 2 // 预付款不足
+	PrepaymentDepositShort = 3 // 保证金不足（暂停入系统，补缴后自动补扣）
 )
 
 // Order 订单模型（从 Shopee 同步的订单信息，分表）
@@ -49,7 +50,7 @@ type Order struct {
 
 	// 预付款标记（订单进入 READY_TO_SHIP 时由系统自动检查并标记）
 	PrepaymentAmount    decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:实际预付款扣除金额" json:"prepayment_amount"`
-	PrepaymentStatus    int8            `gorm:"not null;default:0;index;comment:预付款状态(0未检查/1充足/2不足)" json:"prepayment_status"`
+	PrepaymentStatus    int8            `gorm:"not null;default:0;index;comment:预付款状态(0未检查/1充足/2不足/3保证金不足)" json:"prepayment_status"`
 	PrepaymentSnapshot  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:检查时的预付款总余额快照" json:"prepayment_snapshot"`
 	PrepaymentCheckedAt *time.Time      `gorm:"comment:预付款检查时间(即预付款账户的最后更新时间)" json:"prepayment_checked_at"`
 
@@ -61,6 +62,9 @@ type Order struct {
 	AdjustmentLabel1 string `gorm:"-" json:"adjustment_label_1,omitempty"` // 例如: "账款调整佣金：NT$8.00"
 	AdjustmentLabel2 string `gorm:"-" json:"adjustment_label_2,omitempty"` // 例如: "订单账款调整：NT$36.00"
 	AdjustmentLabel3 string `gorm:"-" json:"adjustment_label_3,omitempty"` // 例如: "虾皮订单账款调整：NT$46.00"
+
+	// 结算后虾皮调账明细（非数据库字段，来自 order_settlement_adjustments 分表）
+	SettlementAdjustments []OrderSettlementAdjustment `gorm:"-" json:"settlement_adjustments,omitempty"`
 }
 
 // TableName 指定表名
@@ -15,9 +15,15 @@ type OrderSettlement struct {
 	OrderID             uint64          `gorm:"not null;index;comment:订单ID" json:"order_id"`
 	ShopOwnerID         int64           `gorm:"not null;index;comment:店铺老板ID" json:"shop_owner_id"`
 	OperatorID          int64           `gorm:"not null;index;comment:运营老板ID" json:"operator_id"`
-	Currency            string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Currency            string          `gorm:"size:10;not null;default:'TWD';comment:结算币种(分账入账的币种)" json:"currency"`
+
+	// 币种折算（订单币种与结算币种不同时，按汇率折算后分账）
+	OrderCurrency       string          `gorm:"size:10;not null;default:'TWD';comment:订单币种" json:"order_currency"`
+	OrderEscrowAmount   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:订单币种下的Shopee结算金额" json:"order_escrow_amount"`
+	FxRate              decimal.Decimal `gorm:"type:decimal(18,8);not null;default:1.00000000;comment:订单币种→结算币种汇率" json:"fx_rate"`
+	FxRateID            uint64          `gorm:"not null;default:0;comment:使用的汇率记录ID(0为同币种)" json:"fx_rate_id"`
 
-	// 金额明细
+	// 金额明细(结算币种)
 	EscrowAmount        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:Shopee结算金额" json:"escrow_amount"`
 	GoodsCost           decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:商品成本" json:"goods_cost"`
 	ShippingCost        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:运费成本" json:"shipping_cost"`
@@ -29,6 +35,11 @@ type OrderSettlement struct {
 	OperatorShareRate   decimal.Decimal `gorm:"type:decimal(5,2);not null;default:0.00;comment:运营分成比例%" json:"operator_share_rate"`
 	ShopOwnerShareRate  decimal.Decimal `gorm:"type:decimal(5,2);not null;default:0.00;comment:店主分成比例%" json:"shop_owner_share_rate"`
 
+	// 分成依据（配置版本 + 规则快照，用于复算）
+	ProfitShareConfigID uint64          `gorm:"not null;default:0;comment:使用的分成配置版本ID(0为默认比例)" json:"profit_share_config_id"`
+	ProfitShareRuleID   uint64          `gorm:"not null;default:0;comment:使用的分成规则ID(0为按比例直接分成)" json:"profit_share_rule_id"`
+	ProfitShareRuleSnapshot string      `gorm:"type:text;comment:分成规则快照(JSON)" json:"profit_share_rule_snapshot,omitempty"`
+
 	// 分成金额
 	PlatformShare       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:平台分成金额" json:"platform_share"`
 	OperatorShare       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:运营分成金额" json:"operator_share"`
@@ -42,26 +53,9 @@ type OrderSettlement struct {
 	SettledAt           *time.Time      `gorm:"comment:结算时间" json:"settled_at"`
 	Remark              string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
 
-	// 调账预留字段（最多3次，写入同一结算记录）
-	AdjustmentCount     int8            `gorm:"not null;default:0;comment:已发生调账次数(0~3)" json:"adjustment_count"`
-	Adj1Amount          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第1次调账金额" json:"adj1_amount"`
-	Adj1PlatformShare   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第1次调账-平台分成" json:"adj1_platform_share"`
-	Adj1OperatorShare   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第1次调账-运营分成" json:"adj1_operator_share"`
-	Adj1ShopOwnerShare  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第1次调账-店主分成" json:"adj1_shop_owner_share"`
-	Adj1At              *time.Time      `gorm:"comment:第1次调账时间" json:"adj1_at"`
-	Adj1Remark          string          `gorm:"size:200;not null;default:'';comment:第1次调账备注" json:"adj1_remark"`
-	Adj2Amount          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第2次调账金额" json:"adj2_amount"`
-	Adj2PlatformShare   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第2次调账-平台分成" json:"adj2_platform_share"`
-	Adj2OperatorShare   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第2次调账-运营分成" json:"adj2_operator_share"`
-	Adj2ShopOwnerShare  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第2次调账-店主分成" json:"adj2_shop_owner_share"`
-	Adj2At              *time.Time      `gorm:"comment:第2次调账时间" json:"adj2_at"`
-	Adj2Remark          string          `gorm:"size:200;not null;default:'';comment:第2次调账备注" json:"adj2_remark"`
-	Adj3Amount          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第3次调账金额" json:"adj3_amount"`
-	Adj3PlatformShare   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第3次调账-平台分成" json:"adj3_platform_share"`
-	Adj3OperatorShare   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第3次调账-运营分成" json:"adj3_operator_share"`
-	Adj3ShopOwnerShare  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:第3次调账-店主分成" json:"adj3_shop_owner_share"`
-	Adj3At              *time.Time      `gorm:"comment:第3次调账时间" json:"adj3_at"`
-	Adj3Remark          string          `gorm:"size:200;not null;default:'';comment:第3次调账备注" json:"adj3_remark"`
+	// 调账汇总（明细见 order_settlement_adjustments 分表，条数不限）
+	AdjustmentCount     int             `gorm:"not null;default:0;comment:已发生调账次数" json:"adjustment_count"`
+	Adjustments         []OrderSettlementAdjustment `gorm:"-" json:"adjustments,omitempty"`
 
 	CreatedAt           time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
@@ -78,18 +72,45 @@ const (
 	OrderSettlementCancelled = 2 // 已取消
 )
 
-// ProfitShareConfig 利润分成配置（店铺与运营的分成比例配置）
+// OrderSettlementAdjustment 结算调账明细（虾皮每笔调账一条，按原结算单的分成比例或分成规则快照分账，分表）
+type OrderSettlementAdjustment struct {
+	ID                  uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	SettlementID        uint64          `gorm:"not null;index;comment:原结算记录ID" json:"settlement_id"`
+	ShopID              uint64          `gorm:"not null;uniqueIndex:uk_shop_transaction;comment:店铺ID" json:"shop_id"`
+	OrderSN             string          `gorm:"size:64;not null;index;comment:订单编号" json:"order_sn"`
+	TransactionID       int64           `gorm:"not null;uniqueIndex:uk_shop_transaction;comment:虾皮交易ID(来源财务收入；对账发起的调账为负的差异ID)" json:"transaction_id"`
+	TransactionType     string          `gorm:"size:64;not null;default:'';comment:调账类型" json:"transaction_type"`
+	Currency            string          `gorm:"size:10;not null;default:'TWD';comment:结算币种" json:"currency"`
+
+	// 金额(结算币种，正数补款/负数扣款)
+	OrderAmount         decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:订单币种下的调账金额" json:"order_amount"`
+	Amount              decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:调账金额" json:"amount"`
+	PlatformShare       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:平台分成" json:"platform_share"`
+	OperatorShare       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:运营分成" json:"operator_share"`
+	ShopOwnerShare      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:店主分成" json:"shop_owner_share"`
+
+	Remark              string          `gorm:"size:200;not null;default:'';comment:备注" json:"remark"`
+	CreatedAt           time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (OrderSettlementAdjustment) TableName() string {
+	return "order_settlement_adjustments"
+}
+
+// ProfitShareConfig 利润分成配置（店铺与运营的分成比例，按生效时间分版本，结算时按订单支付时间取当时生效的版本）
 type ProfitShareConfig struct {
 	ID                  uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
-	ShopID              uint64          `gorm:"not null;uniqueIndex:uk_shop_operator_config;comment:店铺ID" json:"shop_id"`
-	OperatorID          int64           `gorm:"not null;uniqueIndex:uk_shop_operator_config;comment:运营ID" json:"operator_id"`
+	ShopID              uint64          `gorm:"not null;index:idx_shop_operator_effective;comment:店铺ID" json:"shop_id"`
+	OperatorID          int64           `gorm:"not null;index:idx_shop_operator_effective;comment:运营ID" json:"operator_id"`
+	Version             int             `gorm:"not null;default:1;comment:版本号(同一店铺+运营递增)" json:"version"`
 	PlatformShareRate   decimal.Decimal `gorm:"type:decimal(5,2);not null;default:5.00;comment:平台分成比例%" json:"platform_share_rate"`
 	OperatorShareRate   decimal.Decimal `gorm:"type:decimal(5,2);not null;default:45.00;comment:运营分成比例%" json:"operator_share_rate"`
 	ShopOwnerShareRate  decimal.Decimal `gorm:"type:decimal(5,2);not null;default:50.00;comment:店主分成比例%" json:"shop_owner_share_rate"`
-	Status              int8            `gorm:"not null;default:1;comment:状态(1生效/2失效)" json:"status"`
-	EffectiveFrom       time.Time       `gorm:"not null;comment:生效时间" json:"effective_from"`
-	EffectiveTo         *time.Time      `gorm:"comment:失效时间" json:"effective_to"`
+	Status              int8            `gorm:"not null;default:1;comment:状态(1生效/2已撤销)" json:"status"`
+	EffectiveFrom       time.Time       `gorm:"not null;index:idx_shop_operator_effective;comment:生效时间" json:"effective_from"`
+	EffectiveTo         *time.Time      `gorm:"comment:失效时间(下一版本的生效时间，为空表示当前最新版本)" json:"effective_to"`
 	Remark              string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	CreatedBy           int64           `gorm:"not null;default:0;comment:创建人ID" json:"created_by"`
 	CreatedAt           time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
 }
@@ -98,6 +119,62 @@ func (ProfitShareConfig) TableName() string {
 	return "profit_share_configs"
 }
 
+// 分成配置状态常量
+const (
+	ProfitShareConfigActive    = 1 // 生效（含已排期未到生效时间的版本）
+	ProfitShareConfigCancelled = 2 // 已撤销（只能撤销未生效的版本）
+)
+
+// ProfitShareRule 利润分成规则（挂在合作关系上，按利润分档比例、平台每单保底/封顶/固定费用、亏损承担方计算分成）
+// 规则创建后不再修改，调整时新建规则替换旧规则，结算记录保存所用规则的快照
+type ProfitShareRule struct {
+	ID                  uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RelationID          uint64          `gorm:"not null;index;comment:合作关系ID" json:"relation_id"`
+	ShopID              uint64          `gorm:"not null;index:idx_shop_operator;comment:店铺ID" json:"shop_id"`
+	OperatorID          int64           `gorm:"not null;index:idx_shop_operator;comment:运营ID" json:"operator_id"`
+	Name                string          `gorm:"size:100;not null;default:'';comment:规则名称" json:"name"`
+	Tiers               string          `gorm:"type:text;comment:利润分档(JSON，为空则按分成配置比例)" json:"-"`
+	MinPlatformFee      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:平台每单最低分成" json:"min_platform_fee"`
+	MaxPlatformFee      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:平台每单最高分成(0为不封顶)" json:"max_platform_fee"`
+	FixedPlatformFee    decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:平台每单固定费用" json:"fixed_platform_fee"`
+	LossPolicy          string          `gorm:"size:32;not null;default:'proportional';comment:亏损承担方" json:"loss_policy"`
+	Status              int8            `gorm:"not null;default:1;comment:状态(1生效/2已停用)" json:"status"`
+	Remark              string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	CreatedBy           int64           `gorm:"not null;default:0;comment:创建人ID" json:"created_by"`
+	DisabledAt          *time.Time      `gorm:"comment:停用时间" json:"disabled_at"`
+	CreatedAt           time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	TierList            []ProfitShareTier `gorm:"-" json:"tiers"`
+}
+
+func (ProfitShareRule) TableName() string {
+	return "profit_share_rules"
+}
+
+// ProfitShareTier 利润分档：利润落在 (上一档上限, UpTo] 的部分按本档比例分成（超额累进）
+type ProfitShareTier struct {
+	UpTo               decimal.Decimal `json:"up_to"` // 本档利润上限，0 表示不封顶（只能是最后一档）
+	PlatformShareRate  decimal.Decimal `json:"platform_share_rate"`
+	OperatorShareRate  decimal.Decimal `json:"operator_share_rate"`
+	ShopOwnerShareRate decimal.Decimal `json:"shop_owner_share_rate"`
+}
+
+// 分成规则状态常量
+const (
+	ProfitShareRuleActive   = 1 // 生效
+	ProfitShareRuleDisabled = 2 // 已停用（被新规则替换或解除）
+)
+
+// 亏损承担方（利润为负时）
+const (
+	ProfitShareLossProportional      = "proportional"        // 三方按分成比例分摊
+	ProfitShareLossOperator          = "operator"            // 运营全额承担
+	ProfitShareLossShopOwner         = "shop_owner"          // 店主全额承担
+	ProfitShareLossPlatform          = "platform"            // 平台全额承担
+	ProfitShareLossOperatorShopOwner = "operator_shop_owner" // 运营与店主按比例分摊，平台不承担
+)
+
 // OrderShipmentRecord 订单发货记录（运营发货时创建，分表）
 type OrderShipmentRecord struct {
 	ID                  uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxEvent 领域事件发件箱（与业务数据在同一事务中写入，由 cron 投递器分发给订阅者）
+type OutboxEvent struct {
+	ID           uint64     `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	EventID      string     `gorm:"size:64;not null;uniqueIndex;comment:事件ID(投递时随事件下发，供订阅方去重)" json:"event_id"`
+	EventType    string     `gorm:"size:50;not null;index;comment:事件类型" json:"event_type"`
+	AggregateID  string     `gorm:"size:64;not null;default:'';comment:业务单号(订单号/提现申请号等)" json:"aggregate_id"`
+	AdminIDs     string     `gorm:"size:200;not null;default:'';comment:事件相关用户ID(逗号分隔，平台订阅接收全部事件)" json:"admin_ids"`
+	Payload      string     `gorm:"type:text;not null;comment:事件内容(JSON)" json:"payload"`
+	Status       int8       `gorm:"not null;default:0;index:idx_status_id;comment:状态(0待分发/1已分发)" json:"status"`
+	DispatchedAt *time.Time `gorm:"comment:分发时间" json:"dispatched_at"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// WebhookSubscription 事件订阅（每个用户可登记多个回调地址，按事件类型过滤）
+type WebhookSubscription struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	AdminID     int64     `gorm:"not null;index;comment:订阅用户ID" json:"admin_id"`
+	UserType    int8      `gorm:"not null;default:1;comment:订阅用户类型(1店主/5运营/9平台)" json:"user_type"`
+	URL         string    `gorm:"size:500;not null;comment:回调地址" json:"url"`
+	Secret      string    `gorm:"size:128;not null;comment:签名密钥" json:"-"`
+	EventTypes  string    `gorm:"size:500;not null;default:'*';comment:订阅的事件类型(逗号分隔，*为全部)" json:"event_types"`
+	Status      int8      `gorm:"not null;default:1;comment:状态(1启用/0停用)" json:"status"`
+	Description string    `gorm:"size:200;not null;default:'';comment:备注" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookDelivery 事件投递记录（事件 × 订阅一条，失败按退避重试，超过次数进入死信）
+type WebhookDelivery struct {
+	ID             uint64     `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	SubscriptionID uint64     `gorm:"not null;uniqueIndex:uk_subscription_event;comment:订阅ID" json:"subscription_id"`
+	AdminID        int64      `gorm:"not null;index;comment:订阅用户ID" json:"admin_id"`
+	EventID        string     `gorm:"size:64;not null;uniqueIndex:uk_subscription_event;comment:事件ID" json:"event_id"`
+	EventType      string     `gorm:"size:50;not null;comment:事件类型" json:"event_type"`
+	URL            string     `gorm:"size:500;not null;comment:回调地址" json:"url"`
+	Status         int8       `gorm:"not null;default:0;index:idx_status_next;comment:状态(0待投递/1已送达/2死信)" json:"status"`
+	Attempts       int        `gorm:"not null;default:0;comment:已尝试次数" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_status_next;comment:下次投递时间" json:"next_attempt_at"`
+	LastStatusCode int        `gorm:"not null;default:0;comment:最近一次响应状态码" json:"last_status_code"`
+	LastError      string     `gorm:"size:500;not null;default:'';comment:最近一次失败原因" json:"last_error"`
+	DeliveredAt    *time.Time `gorm:"comment:送达时间" json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// 领域事件类型
+const (
+	EventOrderSettled           = "order.settled"           // 订单结算完成
+	EventOrderRefunded          = "order.refunded"          // 退货退款返还预付款
+	EventWithdrawApproved       = "withdraw.approved"       // 提现审核通过
+	EventWithdrawPaid           = "withdraw.paid"           // 提现已打款
+	EventPrepaymentInsufficient = "prepayment.insufficient" // 预付款余额不足，订单未能冻结
+)
+
+// 发件箱事件状态
+const (
+	OutboxEventPending    = 0 // 待分发
+	OutboxEventDispatched = 1 // 已分发（已生成投递记录）
+)
+
+// 事件订阅状态
+const (
+	WebhookSubscriptionDisabled = 0 // 停用
+	WebhookSubscriptionActive   = 1 // 启用
+)
+
+// 事件投递状态
+const (
+	WebhookDeliveryPending   = 0 // 待投递（含等待重试）
+	WebhookDeliveryDelivered = 1 // 已送达
+	WebhookDeliveryDead      = 2 // 死信（超过最大重试次数，可手动重投）
+)
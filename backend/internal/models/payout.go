@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PayoutBatch 提现代付批次（同一代付格式 + 到账币种的已审核提现锁定为一批，生成银行代付文件，导入银行回单确认结果）
+type PayoutBatch struct {
+	ID               uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	BatchNo          string          `gorm:"size:64;not null;uniqueIndex;comment:批次号" json:"batch_no"`
+	Layout           string          `gorm:"size:50;not null;comment:代付文件格式名" json:"layout"`
+	Currency         string          `gorm:"size:10;not null;comment:到账币种" json:"currency"`
+	ItemCount        int             `gorm:"not null;default:0;comment:明细笔数" json:"item_count"`
+	TotalAmount      decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:代付总金额" json:"total_amount"`
+	PaidCount        int             `gorm:"not null;default:0;comment:已打款笔数" json:"paid_count"`
+	PaidAmount       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:已打款金额" json:"paid_amount"`
+	FailedCount      int             `gorm:"not null;default:0;comment:打款失败笔数" json:"failed_count"`
+	FailedAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:打款失败金额" json:"failed_amount"`
+	Status           int8            `gorm:"not null;default:0;index;comment:状态(0待回单/1已完成/2已取消)" json:"status"`
+	Remark           string          `gorm:"size:500;not null;default:'';comment:备注" json:"remark"`
+	CreatedBy        int64           `gorm:"not null;default:0;comment:创建人ID" json:"created_by"`
+	ResultImportedBy int64           `gorm:"not null;default:0;comment:最近导入回单人ID" json:"result_imported_by"`
+	ResultImportedAt *time.Time      `gorm:"comment:最近导入回单时间" json:"result_imported_at"`
+	CompletedAt      *time.Time      `gorm:"comment:完成/取消时间" json:"completed_at"`
+	CreatedAt        time.Time       `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+	UpdatedAt        time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	Items []PayoutBatchItem `gorm:"-" json:"items,omitempty"`
+}
+
+func (PayoutBatch) TableName() string {
+	return "payout_batches"
+}
+
+// PayoutBatchItem 代付批次明细（一笔提现申请一条，收款账户信息在入批次时快照）
+type PayoutBatchItem struct {
+	ID                  uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	BatchNo             string          `gorm:"size:64;not null;uniqueIndex:uk_batch_line;comment:批次号" json:"batch_no"`
+	LineNo              int             `gorm:"not null;uniqueIndex:uk_batch_line;comment:行号" json:"line_no"`
+	ApplicationID       uint64          `gorm:"not null;index;comment:提现申请ID" json:"application_id"`
+	ApplicationNo       string          `gorm:"size:64;not null;index;comment:提现申请单号(代付参考号)" json:"application_no"`
+	AdminID             int64           `gorm:"not null;comment:申请人ID" json:"admin_id"`
+	AccountType         string          `gorm:"size:30;not null;comment:提现账户类型" json:"account_type"`
+	CollectionAccountID uint64          `gorm:"not null;comment:收款账户ID" json:"collection_account_id"`
+	BankName            string          `gorm:"size:100;not null;default:'';comment:银行名称" json:"bank_name"`
+	BankBranch          string          `gorm:"size:200;not null;default:'';comment:银行支行" json:"bank_branch"`
+	AccountNo           string          `gorm:"size:512;not null;serializer:encrypted;comment:账户号码(加密存储)" json:"account_no"`
+	AccountName         string          `gorm:"size:100;not null;default:'';comment:账户名称" json:"account_name"`
+	Payee               string          `gorm:"size:512;not null;serializer:encrypted;comment:收款人姓名(加密存储)" json:"payee"`
+	Amount              decimal.Decimal `gorm:"type:decimal(15,2);not null;comment:代付金额(到账币种)" json:"amount"`
+	Currency            string          `gorm:"size:10;not null;comment:到账币种" json:"currency"`
+	Status              int8            `gorm:"not null;default:0;comment:状态(0待回单/1已打款/2打款失败/3已取消)" json:"status"`
+	BankRef             string          `gorm:"size:100;not null;default:'';comment:银行流水号" json:"bank_ref"`
+	FailReason          string          `gorm:"size:500;not null;default:'';comment:失败原因" json:"fail_reason"`
+	ProcessedAt         *time.Time      `gorm:"comment:回单处理时间" json:"processed_at"`
+	CreatedAt           time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt           time.Time       `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (PayoutBatchItem) TableName() string {
+	return "payout_batch_items"
+}
+
+// 代付批次状态
+const (
+	PayoutBatchPending   = 0 // 待回单（文件已生成，等待导入银行回单）
+	PayoutBatchCompleted = 1 // 已完成（全部明细已确认结果）
+	PayoutBatchCancelled = 2 // 已取消（导入回单前取消，提现申请解除锁定）
+)
+
+// 代付批次明细状态
+const (
+	PayoutItemPending   = 0 // 待回单
+	PayoutItemPaid      = 1 // 已打款
+	PayoutItemFailed    = 2 // 打款失败（暂扣金额已退回）
+	PayoutItemCancelled = 3 // 已取消
+)
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountReconciliationRun 账户对账批次（每次对账任务一条）
+type AccountReconciliationRun struct {
+	ID              uint64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RunNo           string     `gorm:"size:64;not null;uniqueIndex;comment:对账批次号" json:"run_no"`
+	CheckedAccounts int64      `gorm:"not null;default:0;comment:已核对账户数" json:"checked_accounts"`
+	DriftAccounts   int64      `gorm:"not null;default:0;comment:存在差异账户数" json:"drift_accounts"`
+	Status          int8       `gorm:"not null;default:0;comment:状态(0执行中/1已完成/2失败)" json:"status"`
+	ErrorMessage    string     `gorm:"size:500;not null;default:'';comment:失败原因" json:"error_message"`
+	StartedAt       time.Time  `gorm:"not null;comment:开始时间" json:"started_at"`
+	FinishedAt      *time.Time `gorm:"comment:完成时间" json:"finished_at"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+}
+
+func (AccountReconciliationRun) TableName() string {
+	return "account_reconciliation_runs"
+}
+
+// AccountReconciliationDrift 账户对账差异（账户余额字段与流水重算结果不一致）
+// TotalIn/TotalOut 对预付款账户为累计充值/累计消费，对收益类账户为累计收益/累计提现
+type AccountReconciliationDrift struct {
+	ID               uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RunNo            string          `gorm:"size:64;not null;index;comment:对账批次号" json:"run_no"`
+	AccountType      string          `gorm:"size:30;not null;index:idx_account;comment:账户类型" json:"account_type"`
+	AdminID          int64           `gorm:"not null;index:idx_account;comment:账户所属用户ID(平台为0)" json:"admin_id"`
+	Currency         string          `gorm:"size:10;not null;default:'TWD';comment:货币代码" json:"currency"`
+	Balance          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:账户可用余额" json:"balance"`
+	ExpectedBalance  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:流水重算可用余额" json:"expected_balance"`
+	PendingAmount    decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:账户待结算金额" json:"pending_amount"`
+	ExpectedPending  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:流水重算待结算金额" json:"expected_pending"`
+	TotalIn          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:账户累计入账" json:"total_in"`
+	ExpectedTotalIn  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:流水重算累计入账" json:"expected_total_in"`
+	TotalOut         decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:账户累计出账" json:"total_out"`
+	ExpectedTotalOut decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:流水重算累计出账" json:"expected_total_out"`
+	DriftFields      string          `gorm:"size:200;not null;default:'';comment:差异字段(逗号分隔)" json:"drift_fields"`
+	TxCount          int64           `gorm:"not null;default:0;comment:参与重算的流水笔数" json:"tx_count"`
+	CreatedAt        time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (AccountReconciliationDrift) TableName() string {
+	return "account_reconciliation_drifts"
+}
+
+// 对账批次状态常量
+const (
+	ReconciliationRunning  = 0 // 执行中
+	ReconciliationFinished = 1 // 已完成
+	ReconciliationFailed   = 2 // 失败
+)
+
+// OrderReconciliationRun 订单三方对账批次（虾皮结算明细 vs 钱包入账 vs 平台结算单，每次对账任务一条）
+type OrderReconciliationRun struct {
+	ID             uint64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RunNo          string     `gorm:"size:64;not null;uniqueIndex;comment:对账批次号" json:"run_no"`
+	WindowStart    time.Time  `gorm:"not null;comment:对账窗口开始时间" json:"window_start"`
+	WindowEnd      time.Time  `gorm:"not null;comment:对账窗口结束时间" json:"window_end"`
+	CheckedOrders  int64      `gorm:"not null;default:0;comment:已核对订单数" json:"checked_orders"`
+	MismatchOrders int64      `gorm:"not null;default:0;comment:存在差异订单数" json:"mismatch_orders"`
+	Status         int8       `gorm:"not null;default:0;comment:状态(0执行中/1已完成/2失败)" json:"status"`
+	ErrorMessage   string     `gorm:"size:500;not null;default:'';comment:失败原因" json:"error_message"`
+	CreatedBy      int64      `gorm:"not null;default:0;comment:触发人ID(0为系统)" json:"created_by"`
+	StartedAt      time.Time  `gorm:"not null;comment:开始时间" json:"started_at"`
+	FinishedAt     *time.Time `gorm:"comment:完成时间" json:"finished_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+}
+
+func (OrderReconciliationRun) TableName() string {
+	return "order_reconciliation_runs"
+}
+
+// OrderReconciliationMismatch 订单三方对账差异（金额均为订单币种）
+// SettledAmount 为平台结算单的订单币种结算金额加历次调账金额
+type OrderReconciliationMismatch struct {
+	ID             uint64          `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RunNo          string          `gorm:"size:64;not null;index;comment:对账批次号" json:"run_no"`
+	ShopID         uint64          `gorm:"not null;index:idx_shop_order;comment:店铺ID" json:"shop_id"`
+	OrderSN        string          `gorm:"size:64;not null;index:idx_shop_order;comment:订单编号" json:"order_sn"`
+	MismatchType   string          `gorm:"size:30;not null;index;comment:差异类型" json:"mismatch_type"`
+	Currency       string          `gorm:"size:10;not null;default:'';comment:订单币种" json:"currency"`
+	HasEscrow      bool            `gorm:"not null;default:false;comment:是否有虾皮结算明细" json:"has_escrow"`
+	EscrowAmount   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:虾皮结算明细金额" json:"escrow_amount"`
+	HasIncome      bool            `gorm:"not null;default:false;comment:是否有钱包入账" json:"has_income"`
+	IncomeAmount   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:钱包入账金额" json:"income_amount"`
+	SettlementNo   string          `gorm:"size:64;not null;default:'';comment:平台结算单号(空为未结算)" json:"settlement_no"`
+	SettledAmount  decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:平台结算金额(含调账)" json:"settled_amount"`
+	DiffAmount     decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:钱包入账-平台结算金额" json:"diff_amount"`
+	Status         int8            `gorm:"not null;default:0;index;comment:处理状态(0待处理/1已发起调账/2已忽略)" json:"status"`
+	AdjustmentTxID int64           `gorm:"not null;default:0;comment:发起调账的调账明细交易ID" json:"adjustment_tx_id"`
+	HandledBy      int64           `gorm:"not null;default:0;comment:处理人ID" json:"handled_by"`
+	HandledAt      *time.Time      `gorm:"comment:处理时间" json:"handled_at"`
+	Remark         string          `gorm:"size:500;not null;default:'';comment:处理备注" json:"remark"`
+	CreatedAt      time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (OrderReconciliationMismatch) TableName() string {
+	return "order_reconciliation_mismatches"
+}
+
+// 订单对账差异类型
+const (
+	OrderMismatchAmountDiff           = "amount_diff"            // 三方金额不一致
+	OrderMismatchMissingEscrow        = "missing_escrow"         // 有入账或结算但缺少虾皮结算明细
+	OrderMismatchMissingIncome        = "missing_income"         // 订单已完成且有结算明细但钱包未入账
+	OrderMismatchSettledWithoutIncome = "settled_without_income" // 已结算但钱包未入账
+)
+
+// 订单对账差异处理状态
+const (
+	OrderMismatchOpen     = 0 // 待处理
+	OrderMismatchAdjusted = 1 // 已发起调账
+	OrderMismatchIgnored  = 2 // 已忽略
+)
+
+// TransactionTypeReconciliationAdjust 对账差异发起的调账（写入结算调账明细的交易类型）
+const TransactionTypeReconciliationAdjust = "RECONCILIATION_ADJUSTMENT"
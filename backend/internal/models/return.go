@@ -63,3 +63,9 @@ func (r *Return) IsRefundConfirmed() bool {
 	return r.Status == ReturnStatusAccepted ||
 		r.Status == ReturnStatusRefundPaid
 }
+
+// IsClosed 退货是否已结束（CLOSED / CANCELLED），结束后关联的风控暂扣自动释放
+func (r *Return) IsClosed() bool {
+	return r.Status == ReturnStatusClosed ||
+		r.Status == ReturnStatusCancelled
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+)
+
+// SettlementRun 结算批次（定时任务或手动触发的每次虾皮结算/调账处理一条）
+type SettlementRun struct {
+	ID           uint64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RunNo        string     `gorm:"size:64;not null;uniqueIndex;comment:批次号" json:"run_no"`
+	RunType      string     `gorm:"size:20;not null;index;comment:批次类型(settlement结算/adjustment调账)" json:"run_type"`
+	Trigger      string     `gorm:"size:20;not null;default:'cron';comment:触发方式(cron/manual)" json:"trigger"`
+	TotalCount   int64      `gorm:"not null;default:0;comment:候选订单数" json:"total_count"`
+	PendingCount int64      `gorm:"not null;default:0;comment:待处理数" json:"pending_count"`
+	SuccessCount int64      `gorm:"not null;default:0;comment:成功数" json:"success_count"`
+	SkippedCount int64      `gorm:"not null;default:0;comment:跳过数" json:"skipped_count"`
+	FailedCount  int64      `gorm:"not null;default:0;comment:失败数" json:"failed_count"`
+	Status       int8       `gorm:"not null;default:0;index;comment:状态(0执行中/1已完成/2已中断)" json:"status"`
+	ErrorMessage string     `gorm:"size:500;not null;default:'';comment:中断原因" json:"error_message"`
+	CreatedBy    int64      `gorm:"not null;default:0;comment:触发人ID(0为系统)" json:"created_by"`
+	StartedAt    time.Time  `gorm:"not null;comment:开始时间" json:"started_at"`
+	FinishedAt   *time.Time `gorm:"comment:完成时间" json:"finished_at"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (SettlementRun) TableName() string {
+	return "settlement_runs"
+}
+
+// SettlementRunItem 结算批次明细（批次的每个候选订单/调账一条，记录处理结果与原因）
+type SettlementRunItem struct {
+	ID              uint64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	RunID           uint64     `gorm:"not null;index:idx_run_status;comment:批次ID" json:"run_id"`
+	ShopID          uint64     `gorm:"not null;comment:店铺ID" json:"shop_id"`
+	OrderSN         string     `gorm:"size:64;not null;index;comment:订单编号" json:"order_sn"`
+	FinanceIncomeID uint64     `gorm:"not null;default:0;comment:财务收入ID(调账批次)" json:"finance_income_id"`
+	TransactionID   int64      `gorm:"not null;default:0;comment:虾皮交易ID(调账批次)" json:"transaction_id"`
+	TransactionType string     `gorm:"size:64;not null;default:'';comment:交易类型(调账批次)" json:"transaction_type"`
+	Status          int8       `gorm:"not null;default:0;index:idx_run_status;comment:结果(0待处理/1成功/2跳过/3失败)" json:"status"`
+	Reason          string     `gorm:"size:500;not null;default:'';comment:跳过/失败原因" json:"reason"`
+	SettlementNo    string     `gorm:"size:64;not null;default:'';comment:结算单号" json:"settlement_no"`
+	Attempts        int        `gorm:"not null;default:0;comment:处理次数" json:"attempts"`
+	ProcessedAt     *time.Time `gorm:"comment:最近处理时间" json:"processed_at"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (SettlementRunItem) TableName() string {
+	return "settlement_run_items"
+}
+
+// 结算批次类型
+const (
+	SettlementRunTypeSettlement = "settlement" // 虾皮回款结算
+	SettlementRunTypeAdjustment = "adjustment" // 虾皮调账
+)
+
+// 结算批次触发方式
+const (
+	SettlementRunTriggerCron   = "cron"   // 定时任务
+	SettlementRunTriggerManual = "manual" // 平台手动触发
+)
+
+// 结算批次状态
+const (
+	SettlementRunRunning     = 0 // 执行中
+	SettlementRunFinished    = 1 // 已完成（明细可能含失败，可重试）
+	SettlementRunInterrupted = 2 // 已中断（超时/异常退出，可续跑）
+)
+
+// 结算批次明细结果
+const (
+	SettlementRunItemPending = 0 // 待处理
+	SettlementRunItemSuccess = 1 // 成功（已结算/已调账）
+	SettlementRunItemSkipped = 2 // 跳过（虾皮未回款、已结算等）
+	SettlementRunItemFailed  = 3 // 失败
+)
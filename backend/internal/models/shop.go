@@ -82,8 +82,8 @@ type ShopWithAuth struct {
 type ShopAuthorization struct {
 	ID               uint64    `gorm:"primaryKey;comment:主键ID" json:"id"`
 	ShopID           uint64    `gorm:"uniqueIndex;not null;comment:店铺ID" json:"shop_id"`
-	AccessToken      string    `gorm:"size:512;not null;comment:访问令牌" json:"access_token"`
-	RefreshToken     string    `gorm:"size:512;not null;comment:刷新令牌" json:"refresh_token"`
+	AccessToken      string    `gorm:"size:512;not null;serializer:encrypted;comment:访问令牌(加密存储)" json:"access_token"`
+	RefreshToken     string    `gorm:"size:512;not null;serializer:encrypted;comment:刷新令牌(加密存储)" json:"refresh_token"`
 	TokenType        string    `gorm:"size:50;not null;default:'Bearer';comment:令牌类型" json:"token_type"`
 	ExpiresAt        time.Time `gorm:"not null;index;comment:访问令牌过期时间" json:"expires_at"`
 	RefreshExpiresAt time.Time `gorm:"not null;comment:刷新令牌过期时间" json:"refresh_expires_at"`
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountStatementPeriod 月结账单期间（每个自然月一条，签核后账单不可重新生成）
+type AccountStatementPeriod struct {
+	ID             uint64     `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	Period         string     `gorm:"size:7;not null;uniqueIndex;comment:账期(YYYY-MM)" json:"period"`
+	Status         int8       `gorm:"not null;default:0;comment:状态(0生成中/1已生成/2已签核/3失败)" json:"status"`
+	StatementCount int64      `gorm:"not null;default:0;comment:账单数" json:"statement_count"`
+	Digest         string     `gorm:"size:64;not null;default:'';comment:全部账单校验和的汇总摘要(SHA-256)" json:"digest"`
+	ErrorMessage   string     `gorm:"size:500;not null;default:'';comment:失败原因" json:"error_message"`
+	GeneratedAt    *time.Time `gorm:"comment:生成完成时间" json:"generated_at"`
+	SignedBy       int64      `gorm:"not null;default:0;comment:签核人ID" json:"signed_by"`
+	SignedAt       *time.Time `gorm:"comment:签核时间" json:"signed_at"`
+	SignRemark     string     `gorm:"size:500;not null;default:'';comment:签核备注" json:"sign_remark"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+func (AccountStatementPeriod) TableName() string {
+	return "account_statement_periods"
+}
+
+// AccountStatement 月结账单（账户+币种+账期一条，生成后只读）
+// 余额口径与对账一致：预付款账户为可用余额（order_pay 从待结算扣除，不计入），其他账户为流水净额
+type AccountStatement struct {
+	ID             uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	StatementNo    string          `gorm:"size:64;not null;uniqueIndex;comment:账单号" json:"statement_no"`
+	Period         string          `gorm:"size:7;not null;uniqueIndex:uk_period_account;comment:账期(YYYY-MM)" json:"period"`
+	AccountType    string          `gorm:"size:30;not null;uniqueIndex:uk_period_account;comment:账户类型" json:"account_type"`
+	AdminID        int64           `gorm:"not null;uniqueIndex:uk_period_account;index:idx_admin_id;comment:账户所属用户ID(平台为0)" json:"admin_id"`
+	Currency       string          `gorm:"size:10;not null;uniqueIndex:uk_period_account;comment:货币代码" json:"currency"`
+	PeriodStart    time.Time       `gorm:"not null;comment:账期开始时间(含)" json:"period_start"`
+	PeriodEnd      time.Time       `gorm:"not null;comment:账期结束时间(不含)" json:"period_end"`
+	OpeningBalance decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:期初余额" json:"opening_balance"`
+	TotalIn        decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:本期入账合计" json:"total_in"`
+	TotalOut       decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:本期出账合计(正数)" json:"total_out"`
+	ClosingBalance decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:期末余额" json:"closing_balance"`
+	TxCount        int64           `gorm:"not null;default:0;comment:本期流水笔数" json:"tx_count"`
+	Checksum       string          `gorm:"size:64;not null;comment:账单内容校验和(SHA-256)" json:"checksum"`
+	CreatedAt      time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	Lines []AccountStatementLine `gorm:"-" json:"lines,omitempty"`
+}
+
+func (AccountStatement) TableName() string {
+	return "account_statements"
+}
+
+// AccountStatementLine 月结账单明细（按交易类型汇总本期流水）
+type AccountStatementLine struct {
+	ID              uint64          `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	StatementNo     string          `gorm:"size:64;not null;index;comment:账单号" json:"statement_no"`
+	TransactionType string          `gorm:"size:30;not null;comment:交易类型" json:"transaction_type"`
+	Amount          decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0.00;comment:本期金额合计(正=入账,负=出账)" json:"amount"`
+	TxCount         int64           `gorm:"not null;default:0;comment:本期流水笔数" json:"tx_count"`
+	CreatedAt       time.Time       `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
+
+func (AccountStatementLine) TableName() string {
+	return "account_statement_lines"
+}
+
+// 账单期间状态常量
+const (
+	StatementPeriodGenerating = 0 // 生成中
+	StatementPeriodGenerated  = 1 // 已生成
+	StatementPeriodSignedOff  = 2 // 已签核
+	StatementPeriodFailed     = 3 // 失败
+)
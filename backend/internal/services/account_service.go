@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,6 +20,7 @@ type AccountService struct {
 	db        *gorm.DB
 	shardedDB *database.ShardedDB
 	idGen     *utils.IDGenerator
+	fxService *FxRateService
 }
 
 // NewAccountService 创建账户服务
@@ -28,6 +30,7 @@ func NewAccountService() *AccountService {
 		db:        db,
 		shardedDB: database.NewShardedDB(db),
 		idGen:     utils.NewIDGenerator(database.GetRedis()),
+		fxService: NewFxRateService(),
 	}
 }
 
@@ -36,6 +39,36 @@ func (s *AccountService) GenerateTransactionNo(accountType string) string {
 	return fmt.Sprintf("%s%d%d", accountType[:3], time.Now().UnixNano(), time.Now().UnixMicro()%1000)
 }
 
+// OrderFreezeIdempotencyKey 订单扣除预付款的幂等键（同步、Webhook、充值后补扣多条路径共用，同一订单只扣一次）
+func OrderFreezeIdempotencyKey(shopID uint64, orderSN string) string {
+	return fmt.Sprintf("freeze:%d:%s", shopID, orderSN)
+}
+
+// OrderCancelRefundIdempotencyKey 订单发货前取消全额返还预付款的幂等键（Webhook 取消与退货退款路径共用）
+func OrderCancelRefundIdempotencyKey(shopID uint64, orderSN string) string {
+	return fmt.Sprintf("refund:%d:%s", shopID, orderSN)
+}
+
+// FindOrderFreezeInTx 按幂等键查找订单扣除预付款的流水（未扣款返回 nil）
+func (s *AccountService) FindOrderFreezeInTx(db *gorm.DB, adminID int64, shopID uint64, orderSN string) *models.AccountTransaction {
+	var freezeTx models.AccountTransaction
+	if err := db.Table(database.GetAccountTransactionTableName(adminID)).
+		Where("idempotency_key = ?", OrderFreezeIdempotencyKey(shopID, orderSN)).
+		First(&freezeTx).Error; err != nil {
+		return nil
+	}
+	return &freezeTx
+}
+
+// OrderFreezeCurrencyInTx 订单扣除预付款时使用的币种
+// 返还/结算必须回到扣款时的子账户；未找到扣款流水时返回 fallback
+func (s *AccountService) OrderFreezeCurrencyInTx(db *gorm.DB, adminID int64, shopID uint64, orderSN string, fallback string) string {
+	if freezeTx := s.FindOrderFreezeInTx(db, adminID, shopID, orderSN); freezeTx != nil && freezeTx.Currency != "" {
+		return freezeTx.Currency
+	}
+	return NormalizeCurrency(fallback)
+}
+
 // createTransaction 创建账户流水（使用分表，自动生成ID）
 func (s *AccountService) createTransaction(tx *gorm.DB, at *models.AccountTransaction) error {
 	if at.ID == 0 {
@@ -45,16 +78,65 @@ func (s *AccountService) createTransaction(tx *gorm.DB, at *models.AccountTransa
 		}
 		at.ID = uint64(id)
 	}
+	if at.IdempotencyKey == "" {
+		at.IdempotencyKey = at.TransactionNo
+	}
 	txTable := database.GetAccountTransactionTableName(at.AdminID)
 	return tx.Table(txTable).Create(at).Error
 }
 
+// findIdempotentTransaction 按幂等键查找已入账的流水（在账户行锁之后调用，未指定幂等键时返回 nil）
+// 幂等键已被其他账户/交易类型占用时返回错误，避免不同操作误用同一个键
+func (s *AccountService) findIdempotentTransaction(tx *gorm.DB, adminID int64, idempotencyKey string, accountType string, transactionType string) (*models.AccountTransaction, error) {
+	if idempotencyKey == "" {
+		return nil, nil
+	}
+	var existing models.AccountTransaction
+	err := tx.Table(database.GetAccountTransactionTableName(adminID)).Where("idempotency_key = ?", idempotencyKey).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.AccountType != accountType || existing.TransactionType != transactionType {
+		return nil, fmt.Errorf("幂等键 %s 已被 %s/%s 流水使用", idempotencyKey, existing.AccountType, existing.TransactionType)
+	}
+	existing.Replayed = true
+	return &existing, nil
+}
+
+// resolveIdempotent 处理动账事务结果
+// 并发重复调用时前置查询可能读不到对方刚提交的流水，由分表上幂等键唯一索引兜底：
+// 冲突时事务已回滚（嵌套事务回滚到保存点），加锁读取首次入账的流水返回
+func (s *AccountService) resolveIdempotent(db *gorm.DB, at *models.AccountTransaction, err error, idempotencyKey string) (*models.AccountTransaction, error) {
+	if err == nil {
+		return at, nil
+	}
+	if idempotencyKey == "" || at == nil || !database.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	var existing models.AccountTransaction
+	if findErr := db.Table(database.GetAccountTransactionTableName(at.AdminID)).
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("idempotency_key = ?", idempotencyKey).
+		First(&existing).Error; findErr != nil {
+		return nil, err
+	}
+	if existing.AccountType != at.AccountType || existing.TransactionType != at.TransactionType {
+		return nil, fmt.Errorf("幂等键 %s 已被 %s/%s 流水使用", idempotencyKey, existing.AccountType, existing.TransactionType)
+	}
+	existing.Replayed = true
+	return &existing, nil
+}
+
 // ==================== 预付款账户 ====================
 
-// GetOrCreatePrepaymentAccount 获取或创建预付款账户
-func (s *AccountService) GetOrCreatePrepaymentAccount(ctx context.Context, adminID int64) (*models.PrepaymentAccount, error) {
+// GetOrCreatePrepaymentAccount 获取或创建预付款账户（按币种）
+func (s *AccountService) GetOrCreatePrepaymentAccount(ctx context.Context, adminID int64, currency string) (*models.PrepaymentAccount, error) {
+	currency = NormalizeCurrency(currency)
 	var account models.PrepaymentAccount
-	err := s.db.Where("admin_id = ?", adminID).First(&account).Error
+	err := s.db.Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error
 	if err == nil {
 		return &account, nil
 	}
@@ -71,7 +153,7 @@ func (s *AccountService) GetOrCreatePrepaymentAccount(ctx context.Context, admin
 		ID:       uint64(id),
 		AdminID:  adminID,
 		Balance:  decimal.Zero,
-		Currency: "TWD",
+		Currency: currency,
 		Status:   models.AccountStatusNormal,
 	}
 	if err := s.db.Create(&account).Error; err != nil {
@@ -80,9 +162,16 @@ func (s *AccountService) GetOrCreatePrepaymentAccount(ctx context.Context, admin
 	return &account, nil
 }
 
-// GetPrepaymentBalance 获取预付款余额
-func (s *AccountService) GetPrepaymentBalance(ctx context.Context, adminID int64) (decimal.Decimal, decimal.Decimal, error) {
-	account, err := s.GetOrCreatePrepaymentAccount(ctx, adminID)
+// ListPrepaymentAccounts 获取用户所有币种的预付款账户
+func (s *AccountService) ListPrepaymentAccounts(ctx context.Context, adminID int64) ([]models.PrepaymentAccount, error) {
+	var accounts []models.PrepaymentAccount
+	err := s.db.Where("admin_id = ?", adminID).Order("currency ASC").Find(&accounts).Error
+	return accounts, err
+}
+
+// GetPrepaymentBalance 获取预付款余额（按币种）
+func (s *AccountService) GetPrepaymentBalance(ctx context.Context, adminID int64, currency string) (decimal.Decimal, decimal.Decimal, error) {
+	account, err := s.GetOrCreatePrepaymentAccount(ctx, adminID, currency)
 	if err != nil {
 		return decimal.Zero, decimal.Zero, err
 	}
@@ -90,16 +179,17 @@ func (s *AccountService) GetPrepaymentBalance(ctx context.Context, adminID int64
 }
 
 // RechargePrepayment 预付款充值
-func (s *AccountService) RechargePrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, remark string, operatorID int64) (*models.AccountTransaction, error) {
+func (s *AccountService) RechargePrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, remark string, operatorID int64, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("充值金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var tx *models.AccountTransaction
 	err := s.db.Transaction(func(db *gorm.DB) error {
 		var account models.PrepaymentAccount
 		// 使用 FOR UPDATE 行锁防止并发更新
-		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				id, idErr := s.idGen.GeneratePrepaymentAccountID(ctx)
 				if idErr != nil {
@@ -108,7 +198,7 @@ func (s *AccountService) RechargePrepayment(ctx context.Context, adminID int64,
 				account = models.PrepaymentAccount{
 					ID:       uint64(id),
 					AdminID:  adminID,
-					Currency: "TWD",
+					Currency: currency,
 					Status:   models.AccountStatusNormal,
 				}
 				if err := db.Create(&account).Error; err != nil {
@@ -119,6 +209,13 @@ func (s *AccountService) RechargePrepayment(ctx context.Context, adminID int64,
 			}
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(db, adminID, idempotencyKey, models.AccountTypePrepayment, models.TxTypeRecharge)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		balanceBefore := account.Balance
 		account.Balance = account.Balance.Add(amount)
 		account.TotalRecharge = account.TotalRecharge.Add(amount)
@@ -130,8 +227,10 @@ func (s *AccountService) RechargePrepayment(ctx context.Context, adminID int64,
 		// 记录流水
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePrepayment),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePrepayment,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeRecharge,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -139,33 +238,51 @@ func (s *AccountService) RechargePrepayment(ctx context.Context, adminID int64,
 			Remark:          remark,
 			OperatorID:      operatorID,
 		}
-		return s.createTransaction(db, tx)
+		if err := s.createTransaction(db, tx); err != nil {
+			return err
+		}
+
+		// 记账：银行入金 → 预付款可用余额
+		_, err = s.PostJournalInTx(db, ctx, NewJournal(models.JournalBizRecharge, "", tx.TransactionNo, remark).
+			SetCurrency(currency).
+			SetOperator(operatorID).
+			Debit(models.ClearingAccountBank, 0, models.JournalBucketAvailable, amount, "", remark).
+			AddTransaction(tx))
+		return err
 	})
 
-	return tx, err
+	return s.resolveIdempotent(s.db, tx, err, idempotencyKey)
 }
 
 // FreezePrepayment 冻结预付款 (发货时调用)
 // 独立事务版本：内部会开启新事务
-func (s *AccountService) FreezePrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.FreezePrepaymentInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) FreezePrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.FreezePrepaymentInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // FreezePrepaymentInTx 冻结预付款（事务参与版本：复用调用方传入的 db/tx）
 // 当调用方已持有事务时使用此方法，避免嵌套独立事务导致连接池死锁
-func (s *AccountService) FreezePrepaymentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) FreezePrepaymentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("冻结金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var tx *models.AccountTransaction
 	err := db.Transaction(func(innerTx *gorm.DB) error {
 		var account models.PrepaymentAccount
 		// 使用 FOR UPDATE 行锁防止并发更新
-		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			return fmt.Errorf("预付款账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(innerTx, adminID, idempotencyKey, models.AccountTypePrepayment, models.TxTypeFreeze)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		if account.Status != models.AccountStatusNormal {
 			return fmt.Errorf("预付款账户已冻结")
 		}
@@ -185,8 +302,10 @@ func (s *AccountService) FreezePrepaymentInTx(db *gorm.DB, ctx context.Context,
 		// 记录流水
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePrepayment),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePrepayment,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeFreeze,
 			Amount:          amount.Neg(),
 			BalanceBefore:   balanceBefore,
@@ -194,31 +313,45 @@ func (s *AccountService) FreezePrepaymentInTx(db *gorm.DB, ctx context.Context,
 			RelatedOrderSN:  orderSN,
 			Remark:          remark,
 		}
-		return s.createTransaction(innerTx, tx)
+		if err := s.createTransaction(innerTx, tx); err != nil {
+			return err
+		}
+
+		// 记账：预付款可用余额 → 待结算
+		_, err = s.PostJournalInTx(innerTx, ctx, NewJournal(models.JournalBizOrderFreeze, orderSN, "", remark).AddTransaction(tx))
+		return err
 	})
 
-	return tx, err
+	return s.resolveIdempotent(db, tx, err, idempotencyKey)
 }
 
 // RefundPrepayment 返还预付款（发货前取消/退货退款时调用）
 // 订单入系统时已扣除预付款，取消或退款时将金额加回店铺老板预付款账户
-func (s *AccountService) RefundPrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.RefundPrepaymentInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) RefundPrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.RefundPrepaymentInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // RefundPrepaymentInTx 返还预付款（事务参与版本）
-func (s *AccountService) RefundPrepaymentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) RefundPrepaymentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("返还金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var tx *models.AccountTransaction
 	err := db.Transaction(func(innerTx *gorm.DB) error {
 		var account models.PrepaymentAccount
-		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			return fmt.Errorf("预付款账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(innerTx, adminID, idempotencyKey, models.AccountTypePrepayment, models.TxTypeOrderRefund)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		if account.PendingAmount.LessThan(amount) {
 			return fmt.Errorf("待结算金额不足，无法返还")
 		}
@@ -233,8 +366,10 @@ func (s *AccountService) RefundPrepaymentInTx(db *gorm.DB, ctx context.Context,
 
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePrepayment),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePrepayment,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeOrderRefund,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -242,30 +377,44 @@ func (s *AccountService) RefundPrepaymentInTx(db *gorm.DB, ctx context.Context,
 			RelatedOrderSN:  orderSN,
 			Remark:          remark,
 		}
-		return s.createTransaction(innerTx, tx)
+		if err := s.createTransaction(innerTx, tx); err != nil {
+			return err
+		}
+
+		// 记账：预付款待结算 → 可用余额
+		_, err = s.PostJournalInTx(innerTx, ctx, NewJournal(models.JournalBizOrderRefund, orderSN, "", remark).AddTransaction(tx))
+		return err
 	})
 
-	return tx, err
+	return s.resolveIdempotent(db, tx, err, idempotencyKey)
 }
 
 // SettlePrepayment 结算预付款 (订单完成时，从冻结金额扣除)
-func (s *AccountService) SettlePrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.SettlePrepaymentInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) SettlePrepayment(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.SettlePrepaymentInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // SettlePrepaymentInTx 结算预付款（事务参与版本）
-func (s *AccountService) SettlePrepaymentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) SettlePrepaymentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("结算金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var at *models.AccountTransaction
 	err := db.Transaction(func(tx *gorm.DB) error {
 		var account models.PrepaymentAccount
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			return fmt.Errorf("预付款账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(tx, adminID, idempotencyKey, models.AccountTypePrepayment, models.TxTypeOrderPay)
+		if err != nil || existing != nil {
+			at = existing
+			return err
+		}
+
 		if account.PendingAmount.LessThan(amount) {
 			return fmt.Errorf("冻结金额不足")
 		}
@@ -280,8 +429,10 @@ func (s *AccountService) SettlePrepaymentInTx(db *gorm.DB, ctx context.Context,
 
 		at = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePrepayment),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePrepayment,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeOrderPay,
 			Amount:          amount.Neg(),
 			BalanceBefore:   balanceBefore,
@@ -292,23 +443,31 @@ func (s *AccountService) SettlePrepaymentInTx(db *gorm.DB, ctx context.Context,
 		return s.createTransaction(tx, at)
 	})
 
-	return at, err
+	return s.resolveIdempotent(db, at, err, idempotencyKey)
 }
 
 // RefundPrepaymentAdjustmentInTx 调账返还预付款（事务参与版本）
 // 当虾皮调账扣款时，将多扣的金额返还给店铺老板预付款账户
-func (s *AccountService) RefundPrepaymentAdjustmentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) RefundPrepaymentAdjustmentInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("返还金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var at *models.AccountTransaction
 	err := db.Transaction(func(tx *gorm.DB) error {
 		var account models.PrepaymentAccount
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			return fmt.Errorf("预付款账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(tx, adminID, idempotencyKey, models.AccountTypePrepayment, models.TxTypeAdjustmentRefund)
+		if err != nil || existing != nil {
+			at = existing
+			return err
+		}
+
 		balanceBefore := account.Balance
 		account.Balance = account.Balance.Add(amount)
 
@@ -318,8 +477,10 @@ func (s *AccountService) RefundPrepaymentAdjustmentInTx(db *gorm.DB, ctx context
 
 		at = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePrepayment),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePrepayment,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeAdjustmentRefund,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -330,15 +491,16 @@ func (s *AccountService) RefundPrepaymentAdjustmentInTx(db *gorm.DB, ctx context
 		return s.createTransaction(tx, at)
 	})
 
-	return at, err
+	return s.resolveIdempotent(db, at, err, idempotencyKey)
 }
 
 // ==================== 运营账户 ====================
 
-// GetOrCreateOperatorAccount 获取或创建运营账户
-func (s *AccountService) GetOrCreateOperatorAccount(ctx context.Context, adminID int64) (*models.OperatorAccount, error) {
+// GetOrCreateOperatorAccount 获取或创建运营账户（按币种）
+func (s *AccountService) GetOrCreateOperatorAccount(ctx context.Context, adminID int64, currency string) (*models.OperatorAccount, error) {
+	currency = NormalizeCurrency(currency)
 	var account models.OperatorAccount
-	err := s.db.Where("admin_id = ?", adminID).First(&account).Error
+	err := s.db.Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error
 	if err == nil {
 		return &account, nil
 	}
@@ -355,7 +517,7 @@ func (s *AccountService) GetOrCreateOperatorAccount(ctx context.Context, adminID
 		ID:       uint64(id),
 		AdminID:  adminID,
 		Balance:  decimal.Zero,
-		Currency: "TWD",
+		Currency: currency,
 		Status:   models.AccountStatusNormal,
 	}
 	if err := s.db.Create(&account).Error; err != nil {
@@ -365,20 +527,21 @@ func (s *AccountService) GetOrCreateOperatorAccount(ctx context.Context, adminID
 }
 
 // AddOperatorIncome 增加运营收入 (结算时调用)
-func (s *AccountService) AddOperatorIncome(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.AddOperatorIncomeInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) AddOperatorIncome(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.AddOperatorIncomeInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // AddOperatorIncomeInTx 增加运营收入（事务参与版本）
-func (s *AccountService) AddOperatorIncomeInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) AddOperatorIncomeInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("收入金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var at *models.AccountTransaction
 	err := db.Transaction(func(tx *gorm.DB) error {
 		var account models.OperatorAccount
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				id, idErr := s.idGen.GenerateOperatorAccountID(ctx)
 				if idErr != nil {
@@ -387,7 +550,7 @@ func (s *AccountService) AddOperatorIncomeInTx(db *gorm.DB, ctx context.Context,
 				account = models.OperatorAccount{
 					ID:       uint64(id),
 					AdminID:  adminID,
-					Currency: "TWD",
+					Currency: currency,
 					Status:   models.AccountStatusNormal,
 				}
 				if err := tx.Create(&account).Error; err != nil {
@@ -398,6 +561,13 @@ func (s *AccountService) AddOperatorIncomeInTx(db *gorm.DB, ctx context.Context,
 			}
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(tx, adminID, idempotencyKey, models.AccountTypeOperator, models.TxTypeCostSettle)
+		if err != nil || existing != nil {
+			at = existing
+			return err
+		}
+
 		balanceBefore := account.Balance
 		account.Balance = account.Balance.Add(amount)
 		account.TotalEarnings = account.TotalEarnings.Add(amount)
@@ -408,8 +578,10 @@ func (s *AccountService) AddOperatorIncomeInTx(db *gorm.DB, ctx context.Context,
 
 		at = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypeOperator),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypeOperator,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeCostSettle,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -420,27 +592,35 @@ func (s *AccountService) AddOperatorIncomeInTx(db *gorm.DB, ctx context.Context,
 		return s.createTransaction(tx, at)
 	})
 
-	return at, err
+	return s.resolveIdempotent(db, at, err, idempotencyKey)
 }
 
 // DeductOperatorBalance 扣除运营账户余额 (调账扣款时调用)
-func (s *AccountService) DeductOperatorBalance(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.DeductOperatorBalanceInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) DeductOperatorBalance(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.DeductOperatorBalanceInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // DeductOperatorBalanceInTx 扣除运营账户余额（事务参与版本）
-func (s *AccountService) DeductOperatorBalanceInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) DeductOperatorBalanceInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("扣款金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var tx *models.AccountTransaction
 	err := db.Transaction(func(innerTx *gorm.DB) error {
 		var account models.OperatorAccount
-		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			return fmt.Errorf("运营账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(innerTx, adminID, idempotencyKey, models.AccountTypeOperator, models.TxTypeAdjustment)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		if account.Balance.LessThan(amount) {
 			return fmt.Errorf("运营账户余额不足")
 		}
@@ -454,8 +634,10 @@ func (s *AccountService) DeductOperatorBalanceInTx(db *gorm.DB, ctx context.Cont
 
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypeOperator),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypeOperator,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeAdjustment,
 			Amount:          amount.Neg(),
 			BalanceBefore:   balanceBefore,
@@ -466,7 +648,7 @@ func (s *AccountService) DeductOperatorBalanceInTx(db *gorm.DB, ctx context.Cont
 		return s.createTransaction(innerTx, tx)
 	})
 
-	return tx, err
+	return s.resolveIdempotent(db, tx, err, idempotencyKey)
 }
 
 // ==================== 保证金账户 ====================
@@ -488,20 +670,22 @@ func (s *AccountService) GetOrCreateDepositAccount(ctx context.Context, adminID
 		return nil, fmt.Errorf("生成保证金账户ID失败: %w", idErr)
 	}
 	account = models.DepositAccount{
-		ID:       uint64(id),
-		AdminID:  adminID,
-		Balance:  decimal.Zero,
-		Currency: "TWD",
-		Status:   models.AccountStatusNormal,
+		ID:             uint64(id),
+		AdminID:        adminID,
+		Balance:        decimal.Zero,
+		RequiredAmount: DefaultDepositRequiredAmount,
+		Currency:       models.DefaultCurrency,
 	}
+	refreshDepositStatus(&account)
 	if err := s.db.Create(&account).Error; err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-// PayDeposit 缴纳保证金
-func (s *AccountService) PayDeposit(ctx context.Context, adminID int64, amount decimal.Decimal, remark string, operatorID int64) (*models.AccountTransaction, error) {
+// PayDeposit 缴纳保证金（保证金账户不分币种，按账户自身币种入账）
+// 入账后异步补扣因保证金不足暂停入系统的订单（平台代缴、店主缴纳、线下缴纳审核通过共用）
+func (s *AccountService) PayDeposit(ctx context.Context, adminID int64, amount decimal.Decimal, remark string, operatorID int64, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("保证金金额必须大于0")
 	}
@@ -517,11 +701,12 @@ func (s *AccountService) PayDeposit(ctx context.Context, adminID int64, amount d
 					return fmt.Errorf("生成保证金账户ID失败: %w", idErr)
 				}
 				account = models.DepositAccount{
-					ID:       uint64(did),
-					AdminID:  adminID,
-					Currency: "TWD",
-					Status:   models.AccountStatusNormal,
+					ID:             uint64(did),
+					AdminID:        adminID,
+					RequiredAmount: DefaultDepositRequiredAmount,
+					Currency:       models.DefaultCurrency,
 				}
+				refreshDepositStatus(&account)
 				if err := db.Create(&account).Error; err != nil {
 					return err
 				}
@@ -530,13 +715,18 @@ func (s *AccountService) PayDeposit(ctx context.Context, adminID int64, amount d
 			}
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(db, adminID, idempotencyKey, models.AccountTypeDeposit, models.TxTypeDepositPay)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		balanceBefore := account.Balance
 		account.Balance = account.Balance.Add(amount)
 
-		// 检查是否达到应缴金额
-		if account.Balance.GreaterThanOrEqual(account.RequiredAmount) {
-			account.Status = models.AccountStatusNormal
-		}
+		// 达到应缴金额后恢复正常
+		refreshDepositStatus(&account)
 
 		if err := db.Save(&account).Error; err != nil {
 			return err
@@ -545,8 +735,10 @@ func (s *AccountService) PayDeposit(ctx context.Context, adminID int64, amount d
 		// 记录流水
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypeDeposit),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypeDeposit,
 			AdminID:         adminID,
+			Currency:        account.Currency,
 			TransactionType: models.TxTypeDepositPay,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -554,10 +746,27 @@ func (s *AccountService) PayDeposit(ctx context.Context, adminID int64, amount d
 			Remark:          remark,
 			OperatorID:      operatorID,
 		}
-		return s.createTransaction(db, tx)
+		if err := s.createTransaction(db, tx); err != nil {
+			return err
+		}
+
+		// 记账：银行入金 → 保证金可用余额
+		_, err = s.PostJournalInTx(db, ctx, NewJournal(models.JournalBizDepositPay, "", tx.TransactionNo, remark).
+			SetCurrency(account.Currency).
+			SetOperator(operatorID).
+			Debit(models.ClearingAccountBank, 0, models.JournalBucketAvailable, amount, "", remark).
+			AddTransaction(tx))
+		return err
 	})
 
-	return tx, err
+	tx, err = s.resolveIdempotent(s.db, tx, err, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if !tx.Replayed {
+		s.backfillAfterDepositChange(adminID)
+	}
+	return tx, nil
 }
 
 // ==================== 查询 ====================
@@ -590,10 +799,11 @@ func (s *AccountService) GetAccountTransactions(ctx context.Context, accountType
 
 // ==================== 店主佣金账户 ====================
 
-// GetOrCreateShopOwnerCommissionAccount 获取或创建店主佣金账户
-func (s *AccountService) GetOrCreateShopOwnerCommissionAccount(ctx context.Context, adminID int64) (*models.ShopOwnerCommissionAccount, error) {
+// GetOrCreateShopOwnerCommissionAccount 获取或创建店主佣金账户（按币种）
+func (s *AccountService) GetOrCreateShopOwnerCommissionAccount(ctx context.Context, adminID int64, currency string) (*models.ShopOwnerCommissionAccount, error) {
+	currency = NormalizeCurrency(currency)
 	var account models.ShopOwnerCommissionAccount
-	err := s.db.Where("admin_id = ?", adminID).First(&account).Error
+	err := s.db.Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error
 	if err == nil {
 		return &account, nil
 	}
@@ -609,7 +819,7 @@ func (s *AccountService) GetOrCreateShopOwnerCommissionAccount(ctx context.Conte
 		ID:       uint64(cid),
 		AdminID:  adminID,
 		Balance:  decimal.Zero,
-		Currency: "TWD",
+		Currency: currency,
 		Status:   models.AccountStatusNormal,
 	}
 	if err := s.db.Create(&account).Error; err != nil {
@@ -618,21 +828,29 @@ func (s *AccountService) GetOrCreateShopOwnerCommissionAccount(ctx context.Conte
 	return &account, nil
 }
 
+// ListShopOwnerCommissionAccounts 获取店主所有币种的佣金账户
+func (s *AccountService) ListShopOwnerCommissionAccounts(ctx context.Context, adminID int64) ([]models.ShopOwnerCommissionAccount, error) {
+	var accounts []models.ShopOwnerCommissionAccount
+	err := s.db.Where("admin_id = ?", adminID).Order("currency ASC").Find(&accounts).Error
+	return accounts, err
+}
+
 // AddShopOwnerCommission 增加店主佣金 (结算时调用)
-func (s *AccountService) AddShopOwnerCommission(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.AddShopOwnerCommissionInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) AddShopOwnerCommission(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.AddShopOwnerCommissionInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // AddShopOwnerCommissionInTx 增加店主佣金（事务参与版本）
-func (s *AccountService) AddShopOwnerCommissionInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) AddShopOwnerCommissionInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("佣金金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var at *models.AccountTransaction
 	err := db.Transaction(func(tx *gorm.DB) error {
 		var account models.ShopOwnerCommissionAccount
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				scid, idErr := s.idGen.GenerateShopOwnerCommissionAccountID(ctx)
 				if idErr != nil {
@@ -641,7 +859,7 @@ func (s *AccountService) AddShopOwnerCommissionInTx(db *gorm.DB, ctx context.Con
 				account = models.ShopOwnerCommissionAccount{
 					ID:       uint64(scid),
 					AdminID:  adminID,
-					Currency: "TWD",
+					Currency: currency,
 					Status:   models.AccountStatusNormal,
 				}
 				if err := tx.Create(&account).Error; err != nil {
@@ -652,6 +870,13 @@ func (s *AccountService) AddShopOwnerCommissionInTx(db *gorm.DB, ctx context.Con
 			}
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(tx, adminID, idempotencyKey, models.AccountTypeShopOwnerCommission, models.TxTypeProfitShare)
+		if err != nil || existing != nil {
+			at = existing
+			return err
+		}
+
 		balanceBefore := account.Balance
 		account.Balance = account.Balance.Add(amount)
 		account.TotalEarnings = account.TotalEarnings.Add(amount)
@@ -662,8 +887,10 @@ func (s *AccountService) AddShopOwnerCommissionInTx(db *gorm.DB, ctx context.Con
 
 		at = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypeShopOwnerCommission),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypeShopOwnerCommission,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeProfitShare,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -674,27 +901,35 @@ func (s *AccountService) AddShopOwnerCommissionInTx(db *gorm.DB, ctx context.Con
 		return s.createTransaction(tx, at)
 	})
 
-	return at, err
+	return s.resolveIdempotent(db, at, err, idempotencyKey)
 }
 
 // DeductShopOwnerCommission 扣除店主佣金 (调账扣款时调用)
-func (s *AccountService) DeductShopOwnerCommission(ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.DeductShopOwnerCommissionInTx(s.db, ctx, adminID, amount, orderSN, remark)
+func (s *AccountService) DeductShopOwnerCommission(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.DeductShopOwnerCommissionInTx(s.db, ctx, adminID, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // DeductShopOwnerCommissionInTx 扣除店主佣金（事务参与版本）
-func (s *AccountService) DeductShopOwnerCommissionInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) DeductShopOwnerCommissionInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("扣款金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var tx *models.AccountTransaction
 	err := db.Transaction(func(innerTx *gorm.DB) error {
 		var account models.ShopOwnerCommissionAccount
-		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 			return fmt.Errorf("店主佣金账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(innerTx, adminID, idempotencyKey, models.AccountTypeShopOwnerCommission, models.TxTypeAdjustment)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		if account.Balance.LessThan(amount) {
 			return fmt.Errorf("店主佣金账户余额不足")
 		}
@@ -708,8 +943,10 @@ func (s *AccountService) DeductShopOwnerCommissionInTx(db *gorm.DB, ctx context.
 
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypeShopOwnerCommission),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypeShopOwnerCommission,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeAdjustment,
 			Amount:          amount.Neg(),
 			BalanceBefore:   balanceBefore,
@@ -720,15 +957,16 @@ func (s *AccountService) DeductShopOwnerCommissionInTx(db *gorm.DB, ctx context.
 		return s.createTransaction(innerTx, tx)
 	})
 
-	return tx, err
+	return s.resolveIdempotent(db, tx, err, idempotencyKey)
 }
 
 // ==================== 平台佣金账户 ====================
 
-// GetOrCreatePlatformCommissionAccount 获取或创建平台佣金账户 (单例)
-func (s *AccountService) GetOrCreatePlatformCommissionAccount(ctx context.Context) (*models.PlatformCommissionAccount, error) {
+// GetOrCreatePlatformCommissionAccount 获取或创建平台佣金账户 (每个币种一个)
+func (s *AccountService) GetOrCreatePlatformCommissionAccount(ctx context.Context, currency string) (*models.PlatformCommissionAccount, error) {
+	currency = NormalizeCurrency(currency)
 	var account models.PlatformCommissionAccount
-	err := s.db.First(&account).Error
+	err := s.db.Where("currency = ?", currency).First(&account).Error
 	if err == nil {
 		return &account, nil
 	}
@@ -743,7 +981,7 @@ func (s *AccountService) GetOrCreatePlatformCommissionAccount(ctx context.Contex
 	account = models.PlatformCommissionAccount{
 		ID:       uint64(pid),
 		Balance:  decimal.Zero,
-		Currency: "TWD",
+		Currency: currency,
 		Status:   models.AccountStatusNormal,
 	}
 	if err := s.db.Create(&account).Error; err != nil {
@@ -753,20 +991,21 @@ func (s *AccountService) GetOrCreatePlatformCommissionAccount(ctx context.Contex
 }
 
 // AddPlatformCommission 增加平台佣金 (结算时调用)
-func (s *AccountService) AddPlatformCommission(ctx context.Context, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.AddPlatformCommissionInTx(s.db, ctx, amount, orderSN, remark)
+func (s *AccountService) AddPlatformCommission(ctx context.Context, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.AddPlatformCommissionInTx(s.db, ctx, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // AddPlatformCommissionInTx 增加平台佣金（事务参与版本）
-func (s *AccountService) AddPlatformCommissionInTx(db *gorm.DB, ctx context.Context, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) AddPlatformCommissionInTx(db *gorm.DB, ctx context.Context, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("佣金金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var at *models.AccountTransaction
 	err := db.Transaction(func(tx *gorm.DB) error {
 		var account models.PlatformCommissionAccount
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("currency = ?", currency).First(&account).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				pcid, idErr := s.idGen.GeneratePlatformCommissionAccountID(ctx)
 				if idErr != nil {
@@ -774,7 +1013,7 @@ func (s *AccountService) AddPlatformCommissionInTx(db *gorm.DB, ctx context.Cont
 				}
 				account = models.PlatformCommissionAccount{
 					ID:       uint64(pcid),
-					Currency: "TWD",
+					Currency: currency,
 					Status:   models.AccountStatusNormal,
 				}
 				if err := tx.Create(&account).Error; err != nil {
@@ -785,6 +1024,13 @@ func (s *AccountService) AddPlatformCommissionInTx(db *gorm.DB, ctx context.Cont
 			}
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(tx, 0, idempotencyKey, models.AccountTypePlatformCommission, models.TxTypePlatformFee)
+		if err != nil || existing != nil {
+			at = existing
+			return err
+		}
+
 		balanceBefore := account.Balance
 		account.Balance = account.Balance.Add(amount)
 		account.TotalEarnings = account.TotalEarnings.Add(amount)
@@ -795,8 +1041,10 @@ func (s *AccountService) AddPlatformCommissionInTx(db *gorm.DB, ctx context.Cont
 
 		at = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePlatformCommission),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePlatformCommission,
 			AdminID:         0,
+			Currency:        currency,
 			TransactionType: models.TxTypePlatformFee,
 			Amount:          amount,
 			BalanceBefore:   balanceBefore,
@@ -807,27 +1055,35 @@ func (s *AccountService) AddPlatformCommissionInTx(db *gorm.DB, ctx context.Cont
 		return s.createTransaction(tx, at)
 	})
 
-	return at, err
+	return s.resolveIdempotent(db, at, err, idempotencyKey)
 }
 
 // DeductPlatformCommission 扣除平台佣金 (调账扣款时调用)
-func (s *AccountService) DeductPlatformCommission(ctx context.Context, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
-	return s.DeductPlatformCommissionInTx(s.db, ctx, amount, orderSN, remark)
+func (s *AccountService) DeductPlatformCommission(ctx context.Context, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
+	return s.DeductPlatformCommissionInTx(s.db, ctx, amount, currency, orderSN, remark, idempotencyKey)
 }
 
 // DeductPlatformCommissionInTx 扣除平台佣金（事务参与版本）
-func (s *AccountService) DeductPlatformCommissionInTx(db *gorm.DB, ctx context.Context, amount decimal.Decimal, orderSN string, remark string) (*models.AccountTransaction, error) {
+func (s *AccountService) DeductPlatformCommissionInTx(db *gorm.DB, ctx context.Context, amount decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("扣款金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	var tx *models.AccountTransaction
 	err := db.Transaction(func(innerTx *gorm.DB) error {
 		var account models.PlatformCommissionAccount
-		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account).Error; err != nil {
+		if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("currency = ?", currency).First(&account).Error; err != nil {
 			return fmt.Errorf("平台佣金账户不存在")
 		}
 
+		// 幂等：同一幂等键已入账则直接返回原流水，不重复动账
+		existing, err := s.findIdempotentTransaction(innerTx, 0, idempotencyKey, models.AccountTypePlatformCommission, models.TxTypeAdjustment)
+		if err != nil || existing != nil {
+			tx = existing
+			return err
+		}
+
 		if account.Balance.LessThan(amount) {
 			return fmt.Errorf("平台佣金账户余额不足")
 		}
@@ -841,8 +1097,10 @@ func (s *AccountService) DeductPlatformCommissionInTx(db *gorm.DB, ctx context.C
 
 		tx = &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(models.AccountTypePlatformCommission),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     models.AccountTypePlatformCommission,
 			AdminID:         0,
+			Currency:        currency,
 			TransactionType: models.TxTypeAdjustment,
 			Amount:          amount.Neg(),
 			BalanceBefore:   balanceBefore,
@@ -853,59 +1111,36 @@ func (s *AccountService) DeductPlatformCommissionInTx(db *gorm.DB, ctx context.C
 		return s.createTransaction(innerTx, tx)
 	})
 
-	return tx, err
+	return s.resolveIdempotent(db, tx, err, idempotencyKey)
 }
 // ==================== 提现功能 ====================
 
+// ErrDepositWithdraw 保证金不走提现，需提交保证金退还申请（审批时校验可退金额）
+var ErrDepositWithdraw = errors.New("保证金请通过保证金退还申请提取")
+
 // GenerateApplicationNo 生成申请单号
 func (s *AccountService) GenerateApplicationNo(prefix string) string {
 	return fmt.Sprintf("%s%d%d", prefix, time.Now().UnixNano(), time.Now().UnixMicro()%1000)
 }
 
 // ApplyWithdraw 申请提现
-func (s *AccountService) ApplyWithdraw(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, collectionAccountID uint64, remark string) (*models.WithdrawApplication, error) {
-	if amount.LessThanOrEqual(decimal.Zero) {
-		return nil, fmt.Errorf("提现金额必须大于0")
+// currency 为提现的子账户币种；payoutCurrency 为到账币种（为空时与 currency 相同），
+// 两者不同时按申请时生效的汇率折算到账金额，汇率记录在申请单上，后续打款不再重新取价
+// 手续费、限额与收款账户冷却期按提现策略校验（见 QuoteWithdraw），手续费从提现金额中扣除
+// 保证金只能通过保证金退还申请（ApplyDepositRefund）提取，不走提现
+func (s *AccountService) ApplyWithdraw(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, currency string, payoutCurrency string, collectionAccountID uint64, remark string) (*models.WithdrawApplication, error) {
+	if accountType == models.AccountTypeDeposit {
+		return nil, ErrDepositWithdraw
 	}
-
-	// 检查余额
-	var availableBalance decimal.Decimal
-	switch accountType {
-	case models.AccountTypeOperator:
-		account, err := s.GetOrCreateOperatorAccount(ctx, adminID)
-		if err != nil {
-			return nil, err
-		}
-		availableBalance = account.Balance
-	case models.AccountTypeShopOwnerCommission:
-		account, err := s.GetOrCreateShopOwnerCommissionAccount(ctx, adminID)
-		if err != nil {
-			return nil, err
-		}
-		availableBalance = account.Balance
-	case models.AccountTypeDeposit:
-		account, err := s.GetOrCreateDepositAccount(ctx, adminID)
-		if err != nil {
-			return nil, err
-		}
-		// 保证金提现：只能提取超出15000的部分
-		minDeposit := decimal.NewFromInt(15000)
-		if account.Balance.LessThanOrEqual(minDeposit) {
-			return nil, fmt.Errorf("保证金余额不足，最低需保留 %s TWD", minDeposit.String())
-		}
-		availableBalance = account.Balance.Sub(minDeposit)
-	default:
-		return nil, fmt.Errorf("不支持的账户类型: %s", accountType)
+	if collectionAccountID == 0 {
+		return nil, fmt.Errorf("收款账户不存在")
 	}
-
-	if availableBalance.LessThan(amount) {
-		return nil, fmt.Errorf("可提现余额不足，当前可提现: %s", availableBalance.String())
+	quote, err := s.QuoteWithdraw(ctx, adminID, accountType, amount, currency, payoutCurrency, collectionAccountID)
+	if err != nil {
+		return nil, err
 	}
-
-	// 检查收款账户
-	var collectionAccount models.CollectionAccount
-	if err := s.db.Where("id = ? AND admin_id = ?", collectionAccountID, adminID).First(&collectionAccount).Error; err != nil {
-		return nil, fmt.Errorf("收款账户不存在")
+	if !quote.Allowed {
+		return nil, errors.New(quote.Reason)
 	}
 
 	// 创建提现申请
@@ -918,96 +1153,141 @@ func (s *AccountService) ApplyWithdraw(ctx context.Context, adminID int64, accou
 		ApplicationNo:       s.GenerateApplicationNo("WD"),
 		AdminID:             adminID,
 		AccountType:         accountType,
-		Amount:              amount,
-		Fee:                 decimal.Zero, // 暂不收取手续费
-		ActualAmount:        amount,
-		Currency:            "TWD",
+		Amount:              quote.Amount,
+		Fee:                 quote.Fee,
+		ActualAmount:        quote.ActualAmount,
+		Currency:            quote.Currency,
+		PayoutCurrency:      quote.PayoutCurrency,
+		FxRate:              quote.FxRate,
+		FxRateID:            quote.FxRateID,
 		CollectionAccountID: collectionAccountID,
 		Status:              models.ApplicationStatusPending,
 		Remark:              remark,
 	}
 
-	if err := s.db.Create(application).Error; err != nil {
-		return nil, err
-	}
-
-	// 冻结提现金额
-	err := s.freezeForWithdraw(ctx, adminID, accountType, amount)
+	err = s.db.Transaction(func(db *gorm.DB) error {
+		// 冻结提现金额（含手续费，打款时手续费转入平台佣金），账户行锁持有到事务结束，同一账户的申请在此串行
+		if err := s.freezeForWithdrawInTx(db, ctx, adminID, accountType, application.Amount, application.Currency, application.ApplicationNo); err != nil {
+			return err
+		}
+		// 持锁后重新校验累计限额与次数（试算时未加锁，并发申请可能都已通过试算）
+		if err := checkWithdrawUsageInTx(db, adminID, accountType, application.Currency, application.Amount, quote.Policy); err != nil {
+			return err
+		}
+		return db.Create(application).Error
+	})
 	if err != nil {
-		s.db.Delete(application)
 		return nil, err
 	}
 
 	return application, nil
 }
 
-// freezeForWithdraw 提现申请时暂扣金额
-func (s *AccountService) freezeForWithdraw(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal) error {
-	return s.db.Transaction(func(db *gorm.DB) error {
+// withdrawableBalance 账户当前可提现余额（已扣除提现中的金额与风控暂扣）
+func (s *AccountService) withdrawableBalance(ctx context.Context, adminID int64, accountType string, currency string) (decimal.Decimal, error) {
+	switch accountType {
+	case models.AccountTypeOperator:
+		account, err := s.GetOrCreateOperatorAccount(ctx, adminID, currency)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return WithdrawableBalance(account.Balance, account.HeldAmount), nil
+	case models.AccountTypeShopOwnerCommission:
+		account, err := s.GetOrCreateShopOwnerCommissionAccount(ctx, adminID, currency)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return WithdrawableBalance(account.Balance, account.HeldAmount), nil
+	default:
+		return decimal.Zero, fmt.Errorf("不支持的账户类型: %s", accountType)
+	}
+}
+
+// freezeForWithdrawInTx 提现申请时暂扣金额（事务参与版本，与申请单创建在同一事务内提交）
+func (s *AccountService) freezeForWithdrawInTx(db *gorm.DB, ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, currency string, applicationNo string) error {
+	return db.Transaction(func(db *gorm.DB) error {
 		switch accountType {
 		case models.AccountTypeOperator:
 			var account models.OperatorAccount
 			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 				return err
 			}
-			if account.Balance.LessThan(amount) {
-				return fmt.Errorf("余额不足")
+			if WithdrawableBalance(account.Balance, account.HeldAmount).LessThan(amount) {
+				return fmt.Errorf("可提现余额不足（含风控暂扣）")
 			}
 			account.Balance = account.Balance.Sub(amount)
 			account.PendingAmount = account.PendingAmount.Add(amount)
-			return db.Save(&account).Error
+			if err := db.Save(&account).Error; err != nil {
+				return err
+			}
 
 		case models.AccountTypeShopOwnerCommission:
 			var account models.ShopOwnerCommissionAccount
 			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 				return err
 			}
-			if account.Balance.LessThan(amount) {
-				return fmt.Errorf("余额不足")
+			if WithdrawableBalance(account.Balance, account.HeldAmount).LessThan(amount) {
+				return fmt.Errorf("可提现余额不足（含风控暂扣）")
 			}
 			account.Balance = account.Balance.Sub(amount)
 			account.PendingAmount = account.PendingAmount.Add(amount)
-			return db.Save(&account).Error
-
-		case models.AccountTypeDeposit:
-			var account models.DepositAccount
-			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := db.Save(&account).Error; err != nil {
 				return err
 			}
-			minDeposit := decimal.NewFromInt(15000)
-			if account.Balance.Sub(amount).LessThan(minDeposit) {
-				return fmt.Errorf("保证金余额不足")
-			}
-			account.Balance = account.Balance.Sub(amount)
-			return db.Save(&account).Error
+
+		default:
+			return fmt.Errorf("不支持的账户类型: %s", accountType)
 		}
-		return nil
+
+		// 记账：可用余额 → 提现暂扣
+		remark := fmt.Sprintf("提现申请: %s", applicationNo)
+		_, err := s.PostJournalInTx(db, ctx, NewJournal(models.JournalBizWithdrawApply, "", applicationNo, remark).
+			SetCurrency(currency).
+			Debit(accountType, adminID, models.JournalBucketAvailable, amount, "", remark).
+			Credit(accountType, adminID, models.JournalBucketPending, amount, "", remark))
+		return err
 	})
 }
 
-// ApproveWithdraw 审批通过提现
-func (s *AccountService) ApproveWithdraw(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) error {
-	return s.db.Transaction(func(db *gorm.DB) error {
+// ApproveWithdraw 审批通过提现（金额达到多级审批门槛时需多个不同审批人依次审批，人数满足后申请才通过）
+func (s *AccountService) ApproveWithdraw(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) (*ApprovalProgress, error) {
+	var progress *ApprovalProgress
+	err := s.db.Transaction(func(db *gorm.DB) error {
 		var application models.WithdrawApplication
 		// 使用 FOR UPDATE 行锁防止并发审批
 		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).First(&application).Error; err != nil {
 			return fmt.Errorf("提现申请不存在或已处理")
 		}
 
+		var err error
+		progress, err = s.recordApprovalInTx(db, models.ApprovalOperationWithdraw, application.ApplicationNo, application.AdminID, application.Currency, application.Amount, auditBy, models.ApprovalActionApprove, auditRemark)
+		if err != nil {
+			return err
+		}
+		if !progress.Completed {
+			return nil
+		}
+
 		now := time.Now()
 		application.Status = models.ApplicationStatusApproved
 		application.AuditBy = auditBy
 		application.AuditAt = &now
 		application.AuditRemark = auditRemark
 
-		return db.Save(&application).Error
+		if err := db.Save(&application).Error; err != nil {
+			return err
+		}
+		return EnqueueOutboxEvent(db, models.EventWithdrawApproved, application.ApplicationNo, []int64{application.AdminID}, &application)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
 }
 
-// RejectWithdraw 拒绝提现
+// RejectWithdraw 拒绝提现（多级审批中任一审批人拒绝即拒绝）
 func (s *AccountService) RejectWithdraw(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) error {
 	return s.db.Transaction(func(db *gorm.DB) error {
 		var application models.WithdrawApplication
@@ -1015,9 +1295,12 @@ func (s *AccountService) RejectWithdraw(ctx context.Context, applicationID uint6
 		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).First(&application).Error; err != nil {
 			return fmt.Errorf("提现申请不存在或已处理")
 		}
+		if _, err := s.recordApprovalInTx(db, models.ApprovalOperationWithdraw, application.ApplicationNo, application.AdminID, application.Currency, application.Amount, auditBy, models.ApprovalActionReject, auditRemark); err != nil {
+			return err
+		}
 
 		// 返还暂扣金额（拒绝时加回余额）
-		if err := s.unfreezeForWithdraw(ctx, application.AdminID, application.AccountType, application.Amount); err != nil {
+		if err := s.unfreezeForWithdrawInTx(db, ctx, application.AdminID, application.AccountType, application.Amount, application.Currency, application.ApplicationNo); err != nil {
 			return err
 		}
 
@@ -1031,40 +1314,46 @@ func (s *AccountService) RejectWithdraw(ctx context.Context, applicationID uint6
 	})
 }
 
-// unfreezeForWithdraw 提现拒绝时返还暂扣金额
-func (s *AccountService) unfreezeForWithdraw(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal) error {
-	return s.db.Transaction(func(db *gorm.DB) error {
+// unfreezeForWithdrawInTx 提现拒绝/打款失败时返还暂扣金额（事务参与版本，与申请状态变更在同一事务内提交）
+// 与申请时暂扣一致不写流水；调用方需已在事务内按原状态锁定申请并在同一事务内变更状态，以申请状态保证只返还一次
+func (s *AccountService) unfreezeForWithdrawInTx(db *gorm.DB, ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, currency string, applicationNo string) error {
+	return db.Transaction(func(innerTx *gorm.DB) error {
 		switch accountType {
 		case models.AccountTypeOperator:
 			var account models.OperatorAccount
 			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 				return err
 			}
 			account.Balance = account.Balance.Add(amount)
 			account.PendingAmount = account.PendingAmount.Sub(amount)
-			return db.Save(&account).Error
+			if err := innerTx.Save(&account).Error; err != nil {
+				return err
+			}
 
 		case models.AccountTypeShopOwnerCommission:
 			var account models.ShopOwnerCommissionAccount
 			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := innerTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
 				return err
 			}
 			account.Balance = account.Balance.Add(amount)
 			account.PendingAmount = account.PendingAmount.Sub(amount)
-			return db.Save(&account).Error
-
-		case models.AccountTypeDeposit:
-			var account models.DepositAccount
-			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := innerTx.Save(&account).Error; err != nil {
 				return err
 			}
-			account.Balance = account.Balance.Add(amount)
-			return db.Save(&account).Error
+
+		default:
+			return fmt.Errorf("不支持的账户类型: %s", accountType)
 		}
-		return nil
+
+		// 记账：提现暂扣 → 可用余额
+		remark := fmt.Sprintf("提现申请: %s", applicationNo)
+		_, err := s.PostJournalInTx(innerTx, ctx, NewJournal(models.JournalBizWithdrawReject, "", applicationNo, remark).
+			SetCurrency(currency).
+			Credit(accountType, adminID, models.JournalBucketAvailable, amount, "", remark).
+			Debit(accountType, adminID, models.JournalBucketPending, amount, "", remark))
+		return err
 	})
 }
 
@@ -1076,30 +1365,59 @@ func (s *AccountService) ConfirmWithdrawPaid(ctx context.Context, applicationID
 		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", applicationID, models.ApplicationStatusApproved).First(&application).Error; err != nil {
 			return fmt.Errorf("提现申请不存在或状态不正确")
 		}
-
-		// 从冻结金额中扣除并记录流水
-		if err := s.completeWithdraw(ctx, application.AdminID, application.AccountType, application.Amount, application.ApplicationNo); err != nil {
-			return err
+		if application.PayoutBatchNo != "" {
+			return fmt.Errorf("提现申请已锁定在代付批次%s中，请通过导入银行回单确认打款", application.PayoutBatchNo)
 		}
 
-		now := time.Now()
-		application.Status = models.ApplicationStatusPaid
-		application.PaidAt = &now
-
-		return db.Save(&application).Error
+		return s.markWithdrawPaidInTx(db, ctx, &application)
 	})
 }
 
-// completeWithdraw 完成提现
-func (s *AccountService) completeWithdraw(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, applicationNo string) error {
-	return s.db.Transaction(func(db *gorm.DB) error {
+// markWithdrawPaidInTx 提现出账并标记已打款（调用方已在事务内锁定状态为已通过的申请）
+// 出账、申请状态与 withdraw.paid 事件在调用方事务内一并提交
+func (s *AccountService) markWithdrawPaidInTx(db *gorm.DB, ctx context.Context, application *models.WithdrawApplication) error {
+	// 从冻结金额中扣除并记录流水（手续费转入平台佣金）
+	if err := s.completeWithdrawInTx(db, ctx, application.AdminID, application.AccountType, application.Amount, application.Fee, application.Currency, application.ApplicationNo); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	application.Status = models.ApplicationStatusPaid
+	application.PaidAt = &now
+
+	if err := db.Save(application).Error; err != nil {
+		return err
+	}
+	return EnqueueOutboxEvent(db, models.EventWithdrawPaid, application.ApplicationNo, []int64{application.AdminID}, application)
+}
+
+// markWithdrawFailedInTx 打款失败：退回暂扣金额并标记打款失败（调用方已在事务内锁定状态为已通过的申请）
+func (s *AccountService) markWithdrawFailedInTx(db *gorm.DB, ctx context.Context, application *models.WithdrawApplication, reason string) error {
+	if err := s.unfreezeForWithdrawInTx(db, ctx, application.AdminID, application.AccountType, application.Amount, application.Currency, application.ApplicationNo); err != nil {
+		return err
+	}
+
+	application.Status = models.ApplicationStatusFailed
+	application.AuditRemark = fmt.Sprintf("打款失败: %s", reason)
+
+	return db.Save(application).Error
+}
+
+// completeWithdrawInTx 完成提现（事务参与版本，与申请状态变更在同一事务内提交）
+// 同一提现申请已出账时直接返回，不重复出账
+func (s *AccountService) completeWithdrawInTx(db *gorm.DB, ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, fee decimal.Decimal, currency string, applicationNo string) error {
+	return db.Transaction(func(db *gorm.DB) error {
+		idempotencyKey := fmt.Sprintf("withdraw:%s", applicationNo) // 同一提现申请只出账一次
 		var balanceBefore decimal.Decimal
 
 		switch accountType {
 		case models.AccountTypeOperator:
 			var account models.OperatorAccount
 			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
+				return err
+			}
+			if existing, err := s.findIdempotentTransaction(db, adminID, idempotencyKey, accountType, models.TxTypeWithdraw); err != nil || existing != nil {
 				return err
 			}
 			balanceBefore = account.Balance
@@ -1112,7 +1430,10 @@ func (s *AccountService) completeWithdraw(ctx context.Context, adminID int64, ac
 		case models.AccountTypeShopOwnerCommission:
 			var account models.ShopOwnerCommissionAccount
 			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error; err != nil {
+				return err
+			}
+			if existing, err := s.findIdempotentTransaction(db, adminID, idempotencyKey, accountType, models.TxTypeWithdraw); err != nil || existing != nil {
 				return err
 			}
 			balanceBefore = account.Balance
@@ -1122,28 +1443,41 @@ func (s *AccountService) completeWithdraw(ctx context.Context, adminID int64, ac
 				return err
 			}
 
-		case models.AccountTypeDeposit:
-			var account models.DepositAccount
-			// 使用 FOR UPDATE 行锁防止并发更新
-			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
-				return err
-			}
-			balanceBefore = account.Balance
-			// 保证金已在申请时扣除，无需再扣
+		default:
+			return fmt.Errorf("不支持的账户类型: %s", accountType)
 		}
 
 		// 记录流水
 		tx := &models.AccountTransaction{
 			TransactionNo:   s.GenerateTransactionNo(accountType),
+			IdempotencyKey:  idempotencyKey,
 			AccountType:     accountType,
 			AdminID:         adminID,
+			Currency:        currency,
 			TransactionType: models.TxTypeWithdraw,
 			Amount:          amount.Neg(),
 			BalanceBefore:   balanceBefore,
 			BalanceAfter:    balanceBefore, // 提现不影响可用余额（申请时已暂扣）
 			Remark:          fmt.Sprintf("提现申请: %s", applicationNo),
 		}
-		return s.createTransaction(db, tx)
+		if err := s.createTransaction(db, tx); err != nil {
+			return err
+		}
+
+		// 记账：提现暂扣 → 银行出金（扣除手续费后的金额）+ 平台佣金（手续费）
+		journal := NewJournal(models.JournalBizWithdrawPaid, "", applicationNo, tx.Remark).
+			SetCurrency(currency).
+			AddTransaction(tx)
+		if fee.IsPositive() {
+			feeTx, err := s.AddPlatformCommissionInTx(db, ctx, fee, currency, "", fmt.Sprintf("提现手续费: %s", applicationNo), fmt.Sprintf("withdraw_fee:%s", applicationNo))
+			if err != nil {
+				return err
+			}
+			journal.AddTransaction(feeTx)
+		}
+		_, err := s.PostJournalInTx(db, ctx, journal.
+			Credit(models.ClearingAccountBank, 0, models.JournalBucketAvailable, amount.Sub(fee), "", tx.Remark))
+		return err
 	})
 }
 
@@ -1163,22 +1497,47 @@ func (s *AccountService) GetWithdrawApplications(ctx context.Context, adminID in
 	query.Count(&total)
 
 	offset := (page - 1) * pageSize
-	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&applications).Error
-	return applications, total, err
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&applications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 加载多级审批轨迹
+	nos := make([]string, len(applications))
+	for i := range applications {
+		nos[i] = applications[i].ApplicationNo
+	}
+	trails, err := s.approvalTrails(ctx, models.ApprovalOperationWithdraw, nos)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range applications {
+		applications[i].Approvals = trails[applications[i].ApplicationNo]
+	}
+	return applications, total, nil
 }
 
 // ==================== 充值申请功能 ====================
 
 // ApplyRecharge 申请充值 (线下充值)
-func (s *AccountService) ApplyRecharge(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, paymentMethod string, paymentProof string, remark string) (*models.RechargeRecord, error) {
+func (s *AccountService) ApplyRecharge(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, currency string, paymentMethod string, paymentProof string, remark string) (*models.RechargeRecord, error) {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, fmt.Errorf("充值金额必须大于0")
 	}
+	currency = NormalizeCurrency(currency)
 
 	// 验证账户类型
 	if accountType != models.AccountTypePrepayment && accountType != models.AccountTypeDeposit {
 		return nil, fmt.Errorf("只能充值预付款账户或保证金账户")
 	}
+	if accountType == models.AccountTypeDeposit {
+		deposit, err := s.GetOrCreateDepositAccount(ctx, adminID)
+		if err != nil {
+			return nil, err
+		}
+		if deposit.Currency != currency {
+			return nil, fmt.Errorf("保证金账户币种为 %s", deposit.Currency)
+		}
+	}
 
 	rcID, idErr := s.idGen.GenerateRechargeRecordID(ctx)
 	if idErr != nil {
@@ -1190,7 +1549,7 @@ func (s *AccountService) ApplyRecharge(ctx context.Context, adminID int64, accou
 		AdminID:       adminID,
 		AccountType:   accountType,
 		Amount:        amount,
-		Currency:      "TWD",
+		Currency:      currency,
 		PaymentMethod: paymentMethod,
 		PaymentProof:  paymentProof,
 		Status:        models.ApplicationStatusPending,
@@ -1204,22 +1563,36 @@ func (s *AccountService) ApplyRecharge(ctx context.Context, adminID int64, accou
 	return application, nil
 }
 
-// ApproveRecharge 审批通过充值
-func (s *AccountService) ApproveRecharge(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) error {
-	return s.db.Transaction(func(db *gorm.DB) error {
+// ApproveRecharge 审批通过充值（金额达到多级审批门槛时需多个不同审批人依次审批，人数满足后才入账）
+func (s *AccountService) ApproveRecharge(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) (*ApprovalProgress, error) {
+	var progress *ApprovalProgress
+	err := s.db.Transaction(func(db *gorm.DB) error {
 		var application models.RechargeRecord
 		// 使用 FOR UPDATE 行锁防止并发审批
 		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).First(&application).Error; err != nil {
 			return fmt.Errorf("充值申请不存在或已处理")
 		}
+		if application.PaymentID != "" {
+			return fmt.Errorf("线上充值以支付结果入账，无需审批")
+		}
 
-		// 执行充值
 		var err error
+		progress, err = s.recordApprovalInTx(db, models.ApprovalOperationRecharge, application.ApplicationNo, application.AdminID, application.Currency, application.Amount, auditBy, models.ApprovalActionApprove, auditRemark)
+		if err != nil {
+			return err
+		}
+		if !progress.Completed {
+			return nil
+		}
+
+		// 执行充值
 		switch application.AccountType {
 		case models.AccountTypePrepayment:
-			_, err = s.RechargePrepayment(ctx, application.AdminID, application.Amount, fmt.Sprintf("线下充值审核通过: %s", application.ApplicationNo), auditBy)
+			_, err = s.RechargePrepayment(ctx, application.AdminID, application.Amount, application.Currency, fmt.Sprintf("线下充值审核通过: %s", application.ApplicationNo), auditBy,
+				RechargeIdempotencyKey(application.ApplicationNo))
 		case models.AccountTypeDeposit:
-			_, err = s.PayDeposit(ctx, application.AdminID, application.Amount, fmt.Sprintf("线下保证金缴纳审核通过: %s", application.ApplicationNo), auditBy)
+			_, err = s.PayDeposit(ctx, application.AdminID, application.Amount, fmt.Sprintf("线下保证金缴纳审核通过: %s", application.ApplicationNo), auditBy,
+				RechargeIdempotencyKey(application.ApplicationNo))
 		}
 		if err != nil {
 			return fmt.Errorf("充值失败: %w", err)
@@ -1233,9 +1606,13 @@ func (s *AccountService) ApproveRecharge(ctx context.Context, applicationID uint
 
 		return db.Save(&application).Error
 	})
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
 }
 
-// RejectRecharge 拒绝充值
+// RejectRecharge 拒绝充值（多级审批中任一审批人拒绝即拒绝）
 func (s *AccountService) RejectRecharge(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) error {
 	return s.db.Transaction(func(db *gorm.DB) error {
 		var application models.RechargeRecord
@@ -1243,6 +1620,9 @@ func (s *AccountService) RejectRecharge(ctx context.Context, applicationID uint6
 		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).First(&application).Error; err != nil {
 			return fmt.Errorf("充值申请不存在或已处理")
 		}
+		if _, err := s.recordApprovalInTx(db, models.ApprovalOperationRecharge, application.ApplicationNo, application.AdminID, application.Currency, application.Amount, auditBy, models.ApprovalActionReject, auditRemark); err != nil {
+			return err
+		}
 
 		now := time.Now()
 		application.Status = models.ApplicationStatusRejected
@@ -1270,6 +1650,21 @@ func (s *AccountService) GetRechargeRecords(ctx context.Context, adminID int64,
 	query.Count(&total)
 
 	offset := (page - 1) * pageSize
-	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&applications).Error
-	return applications, total, err
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&applications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 加载多级审批轨迹
+	nos := make([]string, len(applications))
+	for i := range applications {
+		nos[i] = applications[i].ApplicationNo
+	}
+	trails, err := s.approvalTrails(ctx, models.ApprovalOperationRecharge, nos)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range applications {
+		applications[i].Approvals = trails[applications[i].ApplicationNo]
+	}
+	return applications, total, nil
 }
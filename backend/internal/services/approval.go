@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// approvalInboxLimit 待审批列表每种申请最多加载的条数
+const approvalInboxLimit = 500
+
+// ApprovalProgress 审批进度（Completed 为 true 表示审批人数已满足，申请已通过）
+type ApprovalProgress struct {
+	Operation string `json:"operation"`
+	TargetNo  string `json:"target_no"`
+	Required  int    `json:"required"`
+	Approved  int    `json:"approved"`
+	Completed bool   `json:"completed"`
+}
+
+// PendingApproval 待审批事项（审批收件箱中的一条）
+type PendingApproval struct {
+	Operation   string          `json:"operation"`
+	TargetID    uint64          `json:"target_id"`
+	TargetNo    string          `json:"target_no"`
+	AdminID     int64           `json:"admin_id"`
+	AccountType string          `json:"account_type"`
+	Currency    string          `json:"currency"`
+	Amount      decimal.Decimal `json:"amount"`
+	Required    int             `json:"required"`
+	Approved    int             `json:"approved"`
+	ApproverIDs []int64         `json:"approver_ids"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// RequiredApprovals 按审批门槛配置计算申请需要的不同审批人数（金额取绝对值）
+func RequiredApprovals(operation, currency string, amount decimal.Decimal) int {
+	var policies []config.ApprovalPolicyConfig
+	if cfg := config.Get(); cfg != nil {
+		policies = cfg.Approval.Policies
+	}
+	return requiredApprovals(policies, operation, currency, amount)
+}
+
+// requiredApprovals 优先使用该币种的门槛，没有时使用该操作的默认门槛；取金额达到的最高档，未命中时 1 人审批
+func requiredApprovals(policies []config.ApprovalPolicyConfig, operation, currency string, amount decimal.Decimal) int {
+	currency = NormalizeCurrency(currency)
+	amount = amount.Abs()
+
+	var matched []config.ApprovalPolicyConfig
+	for _, p := range policies {
+		if p.Operation == operation && p.Currency != "" && NormalizeCurrency(p.Currency) == currency {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) == 0 {
+		for _, p := range policies {
+			if p.Operation == operation && p.Currency == "" {
+				matched = append(matched, p)
+			}
+		}
+	}
+
+	required := 1
+	for _, p := range matched {
+		if amount.GreaterThanOrEqual(decimal.NewFromFloat(p.MinAmount)) && p.Approvers > required {
+			required = p.Approvers
+		}
+	}
+	return required
+}
+
+// recordApprovalInTx 在审批事务中记录一次审批（调用方须已锁定申请行）：申请人不能审批自己的申请，同一审批人只能审批一次。
+// 审批通过时返回当前进度，达到所需人数时 Completed 为 true；拒绝时 Completed 恒为 false。
+func (s *AccountService) recordApprovalInTx(db *gorm.DB, operation, targetNo string, adminID int64, currency string, amount decimal.Decimal, approverID int64, action, remark string) (*ApprovalProgress, error) {
+	if approverID == adminID {
+		return nil, fmt.Errorf("不能审批自己的申请")
+	}
+
+	var records []models.ApprovalRecord
+	if err := db.Where("operation = ? AND target_no = ?", operation, targetNo).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	approved := 0
+	for _, r := range records {
+		if r.ApproverID == approverID {
+			return nil, fmt.Errorf("您已审批过该申请，需由其他审批人继续审批")
+		}
+		if r.Action == models.ApprovalActionApprove {
+			approved++
+		}
+	}
+
+	progress := &ApprovalProgress{
+		Operation: operation,
+		TargetNo:  targetNo,
+		Required:  RequiredApprovals(operation, currency, amount),
+		Approved:  approved,
+	}
+	record := &models.ApprovalRecord{
+		Operation:  operation,
+		TargetNo:   targetNo,
+		ApproverID: approverID,
+		AdminID:    adminID,
+		Currency:   NormalizeCurrency(currency),
+		Amount:     amount.Abs(),
+		Action:     action,
+		Level:      approved + 1,
+		Required:   progress.Required,
+		Remark:     remark,
+	}
+	if err := db.Create(record).Error; err != nil {
+		if database.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("您已审批过该申请，需由其他审批人继续审批")
+		}
+		return nil, err
+	}
+
+	if action == models.ApprovalActionApprove {
+		progress.Approved++
+		progress.Completed = progress.Approved >= progress.Required
+	}
+	return progress, nil
+}
+
+// GetApprovalTrail 获取申请的审批轨迹（按审批顺序）
+func (s *AccountService) GetApprovalTrail(ctx context.Context, operation, targetNo string) ([]models.ApprovalRecord, error) {
+	var records []models.ApprovalRecord
+	err := s.db.WithContext(ctx).Where("operation = ? AND target_no = ?", operation, targetNo).Order("id ASC").Find(&records).Error
+	return records, err
+}
+
+// approvalTrails 批量加载审批轨迹，按申请单号分组
+func (s *AccountService) approvalTrails(ctx context.Context, operation string, targetNos []string) (map[string][]models.ApprovalRecord, error) {
+	trails := make(map[string][]models.ApprovalRecord)
+	if len(targetNos) == 0 {
+		return trails, nil
+	}
+	var records []models.ApprovalRecord
+	if err := s.db.WithContext(ctx).Where("operation = ? AND target_no IN ?", operation, targetNos).Order("id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		trails[r.TargetNo] = append(trails[r.TargetNo], r)
+	}
+	return trails, nil
+}
+
+// GetPendingApprovals 审批收件箱：列出 approverID 可以审批的待审批事项（排除本人的申请和本人已审批过的申请），按申请时间排序
+func (s *AccountService) GetPendingApprovals(ctx context.Context, approverID int64) ([]PendingApproval, error) {
+	var items []PendingApproval
+
+	var withdraws []models.WithdrawApplication
+	if err := s.db.WithContext(ctx).Where("status = ?", models.ApplicationStatusPending).
+		Order("created_at ASC").Limit(approvalInboxLimit).Find(&withdraws).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range withdraws {
+		items = append(items, PendingApproval{Operation: models.ApprovalOperationWithdraw, TargetID: a.ID, TargetNo: a.ApplicationNo,
+			AdminID: a.AdminID, AccountType: a.AccountType, Currency: a.Currency, Amount: a.Amount, CreatedAt: a.CreatedAt})
+	}
+
+	// 线上充值待支付状态由支付结果驱动，不需要审批
+	var recharges []models.RechargeRecord
+	if err := s.db.WithContext(ctx).Where("status = ? AND payment_id = ''", models.ApplicationStatusPending).
+		Order("created_at ASC").Limit(approvalInboxLimit).Find(&recharges).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range recharges {
+		items = append(items, PendingApproval{Operation: models.ApprovalOperationRecharge, TargetID: r.ID, TargetNo: r.ApplicationNo,
+			AdminID: r.AdminID, AccountType: r.AccountType, Currency: r.Currency, Amount: r.Amount, CreatedAt: r.CreatedAt})
+	}
+
+	for i := 0; i < database.ShardCount; i++ {
+		var transactions []models.AccountTransaction
+		if err := s.db.WithContext(ctx).Table(fmt.Sprintf("account_transactions_%d", i)).Where("status = ?", 0).
+			Order("created_at ASC").Limit(approvalInboxLimit).Find(&transactions).Error; err != nil {
+			return nil, err
+		}
+		for _, t := range transactions {
+			items = append(items, PendingApproval{Operation: models.ApprovalOperationFinanceAudit, TargetID: t.ID, TargetNo: t.TransactionNo,
+				AdminID: t.AdminID, AccountType: t.AccountType, Currency: t.Currency, Amount: t.Amount.Abs(), CreatedAt: t.CreatedAt})
+		}
+	}
+
+	targetNos := make(map[string][]string)
+	for _, item := range items {
+		targetNos[item.Operation] = append(targetNos[item.Operation], item.TargetNo)
+	}
+	trails := make(map[string]map[string][]models.ApprovalRecord)
+	for operation, nos := range targetNos {
+		trail, err := s.approvalTrails(ctx, operation, nos)
+		if err != nil {
+			return nil, err
+		}
+		trails[operation] = trail
+	}
+
+	inbox := make([]PendingApproval, 0, len(items))
+	for _, item := range items {
+		if item.AdminID == approverID {
+			continue
+		}
+		item.Required = RequiredApprovals(item.Operation, item.Currency, item.Amount)
+		item.ApproverIDs = []int64{}
+		mine := false
+		for _, r := range trails[item.Operation][item.TargetNo] {
+			if r.ApproverID == approverID {
+				mine = true
+			}
+			if r.Action == models.ApprovalActionApprove {
+				item.Approved++
+				item.ApproverIDs = append(item.ApproverIDs, r.ApproverID)
+			}
+		}
+		if !mine {
+			inbox = append(inbox, item)
+		}
+	}
+	sort.SliceStable(inbox, func(i, j int) bool { return inbox[i].CreatedAt.Before(inbox[j].CreatedAt) })
+	return inbox, nil
+}
+
+// AuditTransaction 审批财务审核流水（待审批的手工出入账）：通过需满足多级审批人数，任一审批人拒绝即拒绝
+func (s *AccountService) AuditTransaction(ctx context.Context, transactionID uint64, approve bool, auditBy int64, remark string) (*ApprovalProgress, error) {
+	var progress *ApprovalProgress
+	err := s.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		var tx models.AccountTransaction
+		var foundTable string
+		for i := 0; i < database.ShardCount; i++ {
+			txTable := fmt.Sprintf("account_transactions_%d", i)
+			err := db.Table(txTable).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", transactionID).First(&tx).Error
+			if err == nil {
+				foundTable = txTable
+				break
+			}
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+		}
+		if foundTable == "" {
+			return fmt.Errorf("交易记录不存在")
+		}
+		if tx.Status != 0 {
+			return fmt.Errorf("该记录已审批")
+		}
+
+		action, newStatus := models.ApprovalActionApprove, int8(1)
+		if !approve {
+			action, newStatus = models.ApprovalActionReject, int8(2)
+		}
+		var err error
+		progress, err = s.recordApprovalInTx(db, models.ApprovalOperationFinanceAudit, tx.TransactionNo, tx.AdminID, tx.Currency, tx.Amount, auditBy, action, remark)
+		if err != nil {
+			return err
+		}
+		if approve && !progress.Completed {
+			return nil
+		}
+
+		updates := map[string]interface{}{"status": newStatus}
+		if remark != "" {
+			updates["remark"] = remark
+		}
+		return db.Table(foundTable).Where("id = ?", tx.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
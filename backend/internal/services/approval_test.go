@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRequiredApprovals(t *testing.T) {
+	policies := []config.ApprovalPolicyConfig{
+		{Operation: models.ApprovalOperationWithdraw, Currency: "TWD", MinAmount: 50000, Approvers: 2},
+		{Operation: models.ApprovalOperationWithdraw, Currency: "TWD", MinAmount: 150000, Approvers: 3},
+		{Operation: models.ApprovalOperationWithdraw, MinAmount: 2000, Approvers: 2},
+		{Operation: models.ApprovalOperationFinanceAudit, Currency: "TWD", MinAmount: 50000, Approvers: 2},
+	}
+	tests := []struct {
+		name      string
+		operation string
+		currency  string
+		amount    string
+		want      int
+	}{
+		{"门槛以下", models.ApprovalOperationWithdraw, "TWD", "49999.99", 1},
+		{"达到门槛", models.ApprovalOperationWithdraw, "TWD", "50000", 2},
+		{"取最高档", models.ApprovalOperationWithdraw, "twd", "200000", 3},
+		// 币种没有专属门槛时使用默认门槛
+		{"默认门槛", models.ApprovalOperationWithdraw, "USD", "2000", 2},
+		{"默认门槛以下", models.ApprovalOperationWithdraw, "USD", "1999", 1},
+		{"未配置的操作", models.ApprovalOperationRecharge, "TWD", "1000000", 1},
+		// 出账流水金额为负数，按绝对值判断
+		{"负数金额", models.ApprovalOperationFinanceAudit, "TWD", "-60000", 2},
+		{"无默认门槛的币种", models.ApprovalOperationFinanceAudit, "USD", "60000", 1},
+	}
+	for _, tt := range tests {
+		got := requiredApprovals(policies, tt.operation, tt.currency, decimal.RequireFromString(tt.amount))
+		if got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
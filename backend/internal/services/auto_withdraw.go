@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/middleware"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	autoWithdrawRunHour   = 9   // 自动提现在计划日的执行时刻（本地时间）
+	autoWithdrawBatchSize = 200 // 每轮处理的到期设置数
+	autoWithdrawRemark    = "自动提现"
+)
+
+// AutoWithdrawSettingRequest 保存自动提现设置参数
+type AutoWithdrawSettingRequest struct {
+	AccountType         string          // operator / shop_owner_commission
+	Currency            string          // 提现币种，为空时默认 TWD
+	PayoutCurrency      string          // 到账币种，为空时与提现币种相同
+	CollectionAccountID uint64          // 收款账户ID
+	Frequency           string          // weekly / monthly
+	DayOfWeek           int             // 每周几执行（0周日~6周六）
+	DayOfMonth          int             // 每月几号执行（1~28）
+	Threshold           decimal.Decimal // 可提取金额达到该值才提现
+	KeepMinimum         decimal.Decimal // 账户保留金额
+	Enabled             bool
+}
+
+// AutoWithdrawResult 一轮自动提现的执行结果
+type AutoWithdrawResult struct {
+	Checked int
+	Created int
+	Skipped int
+	Failed  int
+}
+
+// nextAutoWithdrawRun 计算 after 之后的下一个计划执行时间（计划日的 autoWithdrawRunHour 点）
+func nextAutoWithdrawRun(frequency string, dayOfWeek, dayOfMonth int, after time.Time) time.Time {
+	loc := after.Location()
+	if frequency == models.AutoWithdrawMonthly {
+		next := time.Date(after.Year(), after.Month(), dayOfMonth, autoWithdrawRunHour, 0, 0, 0, loc)
+		if !next.After(after) {
+			next = time.Date(after.Year(), after.Month()+1, dayOfMonth, autoWithdrawRunHour, 0, 0, 0, loc)
+		}
+		return next
+	}
+	next := time.Date(after.Year(), after.Month(), after.Day(), autoWithdrawRunHour, 0, 0, 0, loc)
+	for int(next.Weekday()) != dayOfWeek || !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SaveAutoWithdrawSetting 创建或更新自动提现设置（每个用户每个账户类型每个币种一条），保存后按新计划重算下次执行时间
+func (s *AccountService) SaveAutoWithdrawSetting(ctx context.Context, adminID int64, req AutoWithdrawSettingRequest) (*models.AutoWithdrawSetting, error) {
+	if req.AccountType != models.AccountTypeOperator && req.AccountType != models.AccountTypeShopOwnerCommission {
+		return nil, fmt.Errorf("%s 账户不支持自动提现", req.AccountType)
+	}
+	switch req.Frequency {
+	case models.AutoWithdrawWeekly:
+		if req.DayOfWeek < 0 || req.DayOfWeek > 6 {
+			return nil, fmt.Errorf("每周执行日必须为 0~6")
+		}
+	case models.AutoWithdrawMonthly:
+		if req.DayOfMonth < 1 || req.DayOfMonth > 28 {
+			return nil, fmt.Errorf("每月执行日必须为 1~28")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的自动提现频率: %s", req.Frequency)
+	}
+	if req.Threshold.IsNegative() || req.KeepMinimum.IsNegative() {
+		return nil, fmt.Errorf("触发金额与保留金额不能为负数")
+	}
+	req.Currency = NormalizeCurrency(req.Currency)
+	if req.PayoutCurrency == "" {
+		req.PayoutCurrency = req.Currency
+	}
+	req.PayoutCurrency = NormalizeCurrency(req.PayoutCurrency)
+
+	var collectionAccount models.CollectionAccount
+	if err := s.db.WithContext(ctx).Where("id = ? AND admin_id = ?", req.CollectionAccountID, adminID).First(&collectionAccount).Error; err != nil {
+		return nil, fmt.Errorf("收款账户不存在")
+	}
+	if collectionAccount.Status != models.CollectionAccountStatusActive {
+		return nil, fmt.Errorf("收款账户未激活")
+	}
+
+	var setting models.AutoWithdrawSetting
+	err := s.db.WithContext(ctx).Where("admin_id = ? AND account_type = ? AND currency = ?", adminID, req.AccountType, req.Currency).First(&setting).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	setting.AdminID = adminID
+	setting.AccountType = req.AccountType
+	setting.Currency = req.Currency
+	setting.PayoutCurrency = req.PayoutCurrency
+	setting.CollectionAccountID = req.CollectionAccountID
+	setting.Frequency = req.Frequency
+	setting.DayOfWeek = req.DayOfWeek
+	setting.DayOfMonth = req.DayOfMonth
+	setting.Threshold = req.Threshold.Round(2)
+	setting.KeepMinimum = req.KeepMinimum.Round(2)
+	setting.Enabled = req.Enabled
+	setting.NextRunAt = nextAutoWithdrawRun(req.Frequency, req.DayOfWeek, req.DayOfMonth, time.Now())
+	if err := s.db.WithContext(ctx).Save(&setting).Error; err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// ListAutoWithdrawSettings 获取用户的自动提现设置
+func (s *AccountService) ListAutoWithdrawSettings(ctx context.Context, adminID int64) ([]models.AutoWithdrawSetting, error) {
+	var settings []models.AutoWithdrawSetting
+	err := s.db.WithContext(ctx).Where("admin_id = ?", adminID).Order("id ASC").Find(&settings).Error
+	return settings, err
+}
+
+// GetAutoWithdrawRuns 获取用户的自动提现执行记录
+func (s *AccountService) GetAutoWithdrawRuns(ctx context.Context, adminID int64, page, pageSize int) ([]models.AutoWithdrawRun, int64, error) {
+	var runs []models.AutoWithdrawRun
+	var total int64
+
+	query := s.db.WithContext(ctx).Model(&models.AutoWithdrawRun{}).Where("admin_id = ?", adminID)
+	query.Count(&total)
+
+	offset := (page - 1) * pageSize
+	err := query.Order("scheduled_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&runs).Error
+	return runs, total, err
+}
+
+// RunAutoWithdrawals 执行到期的自动提现：可提取金额（可提现余额 - 保留金额）达到触发金额时通过 ApplyWithdraw 创建提现申请，
+// 不满足条件时记录跳过原因；每次执行都通知用户。
+// 先以条件更新推进下次执行时间认领设置，中途失败宁可漏提一期也不会重复提现。
+func (s *AccountService) RunAutoWithdrawals(ctx context.Context) (*AutoWithdrawResult, error) {
+	now := time.Now()
+	var settings []models.AutoWithdrawSetting
+	if err := s.db.WithContext(ctx).Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").Limit(autoWithdrawBatchSize).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	result := &AutoWithdrawResult{}
+	for i := range settings {
+		if ctx.Err() != nil {
+			break
+		}
+		setting := &settings[i]
+		scheduledAt := setting.NextRunAt
+		claim := s.db.WithContext(ctx).Model(&models.AutoWithdrawSetting{}).
+			Where("id = ? AND enabled = ? AND next_run_at = ?", setting.ID, true, scheduledAt).
+			Update("next_run_at", nextAutoWithdrawRun(setting.Frequency, setting.DayOfWeek, setting.DayOfMonth, now))
+		if claim.Error != nil {
+			return result, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		result.Checked++
+
+		run := s.runAutoWithdraw(ctx, setting)
+		run.ScheduledAt = scheduledAt
+		switch run.Result {
+		case models.AutoWithdrawResultCreated:
+			result.Created++
+		case models.AutoWithdrawResultSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+		middleware.AutoWithdrawRuns.WithLabelValues(setting.AccountType, run.Result).Inc()
+
+		if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(run).Error; err != nil {
+			fmt.Printf("[AutoWithdraw] 设置=%d 记录执行结果失败: %v\n", setting.ID, err)
+		}
+		if err := s.db.WithContext(ctx).Model(&models.AutoWithdrawSetting{}).Where("id = ?", setting.ID).Updates(map[string]interface{}{
+			"last_run_at": &now,
+			"last_result": run.Result,
+			"last_reason": run.Reason,
+		}).Error; err != nil {
+			fmt.Printf("[AutoWithdraw] 设置=%d 更新执行结果失败: %v\n", setting.ID, err)
+		}
+		s.notifyAutoWithdraw(ctx, run)
+	}
+	return result, nil
+}
+
+// runAutoWithdraw 执行一个自动提现设置：校验账户状态、风控暂扣、收款账户与余额，满足条件时创建提现申请
+func (s *AccountService) runAutoWithdraw(ctx context.Context, setting *models.AutoWithdrawSetting) *models.AutoWithdrawRun {
+	run := &models.AutoWithdrawRun{
+		SettingID:   setting.ID,
+		AdminID:     setting.AdminID,
+		AccountType: setting.AccountType,
+		Currency:    setting.Currency,
+	}
+	skip := func(format string, args ...interface{}) *models.AutoWithdrawRun {
+		run.Result = models.AutoWithdrawResultSkipped
+		run.Reason = fmt.Sprintf(format, args...)
+		return run
+	}
+
+	var status int8
+	var balance, held decimal.Decimal
+	switch setting.AccountType {
+	case models.AccountTypeOperator:
+		account, err := s.GetOrCreateOperatorAccount(ctx, setting.AdminID, setting.Currency)
+		if err != nil {
+			run.Result, run.Reason = models.AutoWithdrawResultFailed, err.Error()
+			return run
+		}
+		status, balance, held = account.Status, account.Balance, account.HeldAmount
+	case models.AccountTypeShopOwnerCommission:
+		account, err := s.GetOrCreateShopOwnerCommissionAccount(ctx, setting.AdminID, setting.Currency)
+		if err != nil {
+			run.Result, run.Reason = models.AutoWithdrawResultFailed, err.Error()
+			return run
+		}
+		status, balance, held = account.Status, account.Balance, account.HeldAmount
+	default:
+		return skip("%s 账户不支持自动提现", setting.AccountType)
+	}
+
+	if status != models.AccountStatusNormal {
+		return skip("账户已冻结")
+	}
+	if held.IsPositive() {
+		return skip("存在生效中的风控暂扣 %s %s，暂扣释放后恢复自动提现", held.StringFixed(2), setting.Currency)
+	}
+
+	var collectionAccount models.CollectionAccount
+	if err := s.db.WithContext(ctx).Where("id = ? AND admin_id = ?", setting.CollectionAccountID, setting.AdminID).First(&collectionAccount).Error; err != nil {
+		return skip("收款账户不存在")
+	}
+	if collectionAccount.Status != models.CollectionAccountStatusActive {
+		return skip("收款账户未激活")
+	}
+
+	available := WithdrawableBalance(balance, held)
+	amount := available.Sub(setting.KeepMinimum)
+	if !amount.IsPositive() || amount.LessThan(setting.Threshold) {
+		return skip("可提现余额不足：可提现 %s，保留 %s，触发金额 %s", available.StringFixed(2), setting.KeepMinimum.StringFixed(2), setting.Threshold.StringFixed(2))
+	}
+	// 超过单笔上限时按上限提取，剩余部分留到下一期
+	if policy := WithdrawPolicyFor(setting.AccountType, setting.Currency); policy.MaxAmount.IsPositive() && amount.GreaterThan(policy.MaxAmount) {
+		amount = policy.MaxAmount
+	}
+	run.Amount = amount
+
+	application, err := s.ApplyWithdraw(ctx, setting.AdminID, setting.AccountType, amount, setting.Currency, setting.PayoutCurrency, setting.CollectionAccountID, autoWithdrawRemark)
+	if err != nil {
+		run.Result, run.Reason = models.AutoWithdrawResultFailed, err.Error()
+		return run
+	}
+	run.Result = models.AutoWithdrawResultCreated
+	run.ApplicationNo = application.ApplicationNo
+	return run
+}
+
+// notifyAutoWithdraw 通知用户本期自动提现结果
+func (s *AccountService) notifyAutoWithdraw(ctx context.Context, run *models.AutoWithdrawRun) {
+	notifyID, err := s.idGen.GenerateNotificationID(ctx)
+	if err != nil {
+		return
+	}
+	notification := &models.Notification{
+		ID:        uint64(notifyID),
+		AdminID:   run.AdminID,
+		Type:      models.NotifyTypeAutoWithdraw,
+		Title:     "【自动提现】本期未提现",
+		Content:   fmt.Sprintf("%s 账户（%s）本期自动提现未执行：%s", run.AccountType, run.Currency, run.Reason),
+		CreatedAt: time.Now(),
+	}
+	if run.Result == models.AutoWithdrawResultCreated {
+		notification.Title = "【自动提现】已提交提现申请"
+		notification.Content = fmt.Sprintf("%s 账户（%s）已自动提交提现申请 %s，金额 %s，审核通过后打款。",
+			run.AccountType, run.Currency, run.ApplicationNo, run.Amount.StringFixed(2))
+	}
+	if err := s.db.Create(notification).Error; err != nil {
+		fmt.Printf("[AutoWithdraw] 用户=%d 自动提现通知失败: %v\n", run.AdminID, err)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"balance/backend/internal/models"
+)
+
+func TestNextAutoWithdrawRun(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name      string
+		frequency string
+		dow, dom  int
+		after     string
+		want      string
+	}{
+		// 2026-10-18 为周日
+		{"每周当天未到点", models.AutoWithdrawWeekly, 0, 0, "2026-10-18 08:00", "2026-10-18 09:00"},
+		{"每周当天已过点", models.AutoWithdrawWeekly, 0, 0, "2026-10-18 09:00", "2026-10-25 09:00"},
+		{"每周下周一", models.AutoWithdrawWeekly, 1, 0, "2026-10-18 12:00", "2026-10-19 09:00"},
+		{"每月本月未到", models.AutoWithdrawMonthly, 0, 25, "2026-10-18 12:00", "2026-10-25 09:00"},
+		{"每月本月已过", models.AutoWithdrawMonthly, 0, 5, "2026-10-18 12:00", "2026-11-05 09:00"},
+		{"每月跨年", models.AutoWithdrawMonthly, 0, 1, "2026-12-01 09:30", "2027-01-01 09:00"},
+	}
+	for _, tt := range tests {
+		got := nextAutoWithdrawRun(tt.frequency, tt.dow, tt.dom, at(tt.after))
+		if !got.Equal(at(tt.want)) {
+			t.Errorf("%s: got %s, want %s", tt.name, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultDepositRequiredAmount 新建保证金账户的应缴保证金（平台可按店主单独调整）
+var DefaultDepositRequiredAmount = decimal.NewFromInt(15000)
+
+// DepositDeductIdempotencyKey 罚款从保证金扣除的幂等键（同一来源单号只扣一次）
+func DepositDeductIdempotencyKey(sourceNo string) string {
+	return "deposit_deduct:" + sourceNo
+}
+
+// refreshDepositStatus 按余额与应缴金额刷新保证金账户状态（暂停状态只能由平台解除，不自动变更）
+// 返回账户是否由正常变为不足
+func refreshDepositStatus(account *models.DepositAccount) bool {
+	if account.Status == models.DepositStatusSuspended {
+		return false
+	}
+	wasShort := account.Status == models.DepositStatusInsufficient
+	if account.Balance.LessThan(account.RequiredAmount) {
+		account.Status = models.DepositStatusInsufficient
+		return !wasShort
+	}
+	account.Status = models.DepositStatusNormal
+	return false
+}
+
+// IsDepositShort 店主保证金是否不足（余额低于应缴金额或账户被暂停），不足时暂停绑定新店铺与订单入系统
+func (s *AccountService) IsDepositShort(ctx context.Context, adminID int64) (bool, *models.DepositAccount, error) {
+	account, err := s.GetOrCreateDepositAccount(ctx, adminID)
+	if err != nil {
+		return false, nil, err
+	}
+	short := account.Status == models.DepositStatusSuspended || account.Balance.LessThan(account.RequiredAmount)
+	return short, account, nil
+}
+
+// depositRefundableInTx 保证金可退还金额：超出应缴保证金的部分；名下已无店铺时可全额退还
+func (s *AccountService) depositRefundableInTx(db *gorm.DB, account *models.DepositAccount) (decimal.Decimal, error) {
+	if account.Status == models.DepositStatusSuspended {
+		return decimal.Zero, nil
+	}
+	var shopCount int64
+	if err := db.Model(&models.Shop{}).Where("admin_id = ?", account.AdminID).Count(&shopCount).Error; err != nil {
+		return decimal.Zero, err
+	}
+	if shopCount == 0 {
+		return account.Balance, nil
+	}
+	refundable := account.Balance.Sub(account.RequiredAmount)
+	if refundable.LessThan(decimal.Zero) {
+		return decimal.Zero, nil
+	}
+	return refundable, nil
+}
+
+// SetDepositRequiredAmount 设置店主应缴保证金，并按新金额刷新账户状态
+func (s *AccountService) SetDepositRequiredAmount(ctx context.Context, adminID int64, requiredAmount decimal.Decimal) (*models.DepositAccount, error) {
+	if requiredAmount.LessThan(decimal.Zero) {
+		return nil, fmt.Errorf("应缴保证金不能为负数")
+	}
+	if _, err := s.GetOrCreateDepositAccount(ctx, adminID); err != nil {
+		return nil, err
+	}
+
+	var account models.DepositAccount
+	var becameShort bool
+	err := s.db.Transaction(func(db *gorm.DB) error {
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(&account).Error; err != nil {
+			return err
+		}
+		account.RequiredAmount = requiredAmount
+		becameShort = refreshDepositStatus(&account)
+		return db.Save(&account).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if becameShort {
+		s.notifyDepositShort(ctx, &account)
+	} else if account.Status == models.DepositStatusNormal {
+		// 调低应缴金额后保证金可能已补足，恢复暂停入系统的订单
+		s.backfillAfterDepositChange(adminID)
+	}
+	return &account, nil
+}
+
+// DeductDeposit 从保证金扣除罚款（保证金余额不足以扣除时返回错误）
+// 扣除后余额低于应缴金额时账户自动变为不足
+func (s *AccountService) DeductDeposit(ctx context.Context, adminID int64, amount decimal.Decimal, remark string, operatorID int64, idempotencyKey string) (*models.AccountTransaction, error) {
+	var tx *models.AccountTransaction
+	var account models.DepositAccount
+	var becameShort bool
+	err := s.db.Transaction(func(db *gorm.DB) error {
+		var err error
+		tx, becameShort, err = s.deductDepositInTx(db, ctx, &account, adminID, amount, remark, operatorID, idempotencyKey)
+		return err
+	})
+	tx, err = s.resolveIdempotent(s.db, tx, err, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if becameShort {
+		s.notifyDepositShort(ctx, &account)
+	}
+	return tx, nil
+}
+
+// DeductDepositInTx 从保证金扣除罚款（事务参与版本）
+func (s *AccountService) DeductDepositInTx(db *gorm.DB, ctx context.Context, adminID int64, amount decimal.Decimal, remark string, operatorID int64, idempotencyKey string) (*models.AccountTransaction, error) {
+	var account models.DepositAccount
+	tx, _, err := s.deductDepositInTx(db, ctx, &account, adminID, amount, remark, operatorID, idempotencyKey)
+	return tx, err
+}
+
+func (s *AccountService) deductDepositInTx(db *gorm.DB, ctx context.Context, account *models.DepositAccount, adminID int64, amount decimal.Decimal, remark string, operatorID int64, idempotencyKey string) (*models.AccountTransaction, bool, error) {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, false, fmt.Errorf("扣除金额必须大于0")
+	}
+	// 使用 FOR UPDATE 行锁防止并发更新
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", adminID).First(account).Error; err != nil {
+		return nil, false, fmt.Errorf("保证金账户不存在")
+	}
+
+	existing, err := s.findIdempotentTransaction(db, adminID, idempotencyKey, models.AccountTypeDeposit, models.TxTypePenalty)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+
+	if account.Balance.LessThan(amount) {
+		return nil, false, fmt.Errorf("保证金余额不足，当前余额: %s", account.Balance.String())
+	}
+	balanceBefore := account.Balance
+	account.Balance = account.Balance.Sub(amount)
+	becameShort := refreshDepositStatus(account)
+	if err := db.Save(account).Error; err != nil {
+		return nil, false, err
+	}
+
+	tx := &models.AccountTransaction{
+		TransactionNo:   s.GenerateTransactionNo(models.AccountTypeDeposit),
+		IdempotencyKey:  idempotencyKey,
+		AccountType:     models.AccountTypeDeposit,
+		AdminID:         adminID,
+		Currency:        account.Currency,
+		TransactionType: models.TxTypePenalty,
+		Amount:          amount.Neg(),
+		BalanceBefore:   balanceBefore,
+		BalanceAfter:    account.Balance,
+		Remark:          remark,
+		OperatorID:      operatorID,
+		Status:          1,
+	}
+	if err := s.createTransaction(db, tx); err != nil {
+		return tx, false, err
+	}
+
+	// 记账：保证金可用余额 → 罚补
+	_, err = s.PostJournalInTx(db, ctx, NewJournal(models.JournalBizDepositDeduct, "", tx.TransactionNo, remark).
+		SetCurrency(account.Currency).
+		SetOperator(operatorID).
+		AddTransaction(tx).
+		Credit(models.ClearingAccountPenaltyBonus, 0, models.JournalBucketAvailable, amount, "", remark))
+	return tx, becameShort, err
+}
+
+// ==================== 保证金退还 ====================
+
+// ApplyDepositRefund 申请退还保证金（只能退还超出应缴保证金的部分，名下已无店铺时可全额退还）
+// 申请时不暂扣，审批时重新校验可退金额
+func (s *AccountService) ApplyDepositRefund(ctx context.Context, adminID int64, amount decimal.Decimal, collectionAccountID uint64, reason string) (*models.DepositRefundApplication, error) {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("退还金额必须大于0")
+	}
+	account, err := s.GetOrCreateDepositAccount(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	refundable, err := s.depositRefundableInTx(s.db, account)
+	if err != nil {
+		return nil, err
+	}
+	if refundable.LessThan(amount) {
+		return nil, fmt.Errorf("可退还保证金不足，当前可退还: %s", refundable.String())
+	}
+
+	var collectionAccount models.CollectionAccount
+	if err := s.db.Where("id = ? AND admin_id = ?", collectionAccountID, adminID).First(&collectionAccount).Error; err != nil {
+		return nil, fmt.Errorf("收款账户不存在")
+	}
+
+	var count int64
+	s.db.Model(&models.DepositRefundApplication{}).
+		Where("admin_id = ? AND status = ?", adminID, models.ApplicationStatusPending).
+		Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("已有待审核的保证金退还申请")
+	}
+
+	rid, err := s.idGen.GenerateDepositRefundID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("生成保证金退还申请ID失败: %w", err)
+	}
+	application := &models.DepositRefundApplication{
+		ID:                  uint64(rid),
+		ApplicationNo:       s.GenerateApplicationNo("DR"),
+		AdminID:             adminID,
+		Amount:              amount,
+		Currency:            account.Currency,
+		CollectionAccountID: collectionAccountID,
+		Reason:              reason,
+		Status:              models.ApplicationStatusPending,
+	}
+	if err := s.db.Create(application).Error; err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+// ApproveDepositRefund 审批通过保证金退还：扣减保证金余额并记录 deposit_refund 流水
+func (s *AccountService) ApproveDepositRefund(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) (*models.DepositRefundApplication, error) {
+	var application models.DepositRefundApplication
+	err := s.db.Transaction(func(db *gorm.DB) error {
+		// 使用 FOR UPDATE 行锁防止并发审批
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).First(&application).Error; err != nil {
+			return fmt.Errorf("保证金退还申请不存在或已处理")
+		}
+
+		var account models.DepositAccount
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("admin_id = ?", application.AdminID).First(&account).Error; err != nil {
+			return fmt.Errorf("保证金账户不存在")
+		}
+		refundable, err := s.depositRefundableInTx(db, &account)
+		if err != nil {
+			return err
+		}
+		if refundable.LessThan(application.Amount) {
+			return fmt.Errorf("可退还保证金不足，当前可退还: %s", refundable.String())
+		}
+
+		balanceBefore := account.Balance
+		account.Balance = account.Balance.Sub(application.Amount)
+		refreshDepositStatus(&account)
+		if err := db.Save(&account).Error; err != nil {
+			return err
+		}
+
+		remark := fmt.Sprintf("保证金退还: %s", application.ApplicationNo)
+		tx := &models.AccountTransaction{
+			TransactionNo:   s.GenerateTransactionNo(models.AccountTypeDeposit),
+			IdempotencyKey:  fmt.Sprintf("deposit_refund:%s", application.ApplicationNo), // 同一申请只出账一次
+			AccountType:     models.AccountTypeDeposit,
+			AdminID:         application.AdminID,
+			Currency:        account.Currency,
+			TransactionType: models.TxTypeDepositRefund,
+			Amount:          application.Amount.Neg(),
+			BalanceBefore:   balanceBefore,
+			BalanceAfter:    account.Balance,
+			Remark:          remark,
+			OperatorID:      auditBy,
+			Status:          1,
+		}
+		if err := s.createTransaction(db, tx); err != nil {
+			return err
+		}
+
+		// 记账：保证金可用余额 → 银行出金
+		if _, err := s.PostJournalInTx(db, ctx, NewJournal(models.JournalBizDepositRefund, "", application.ApplicationNo, remark).
+			SetCurrency(account.Currency).
+			SetOperator(auditBy).
+			AddTransaction(tx).
+			Credit(models.ClearingAccountBank, 0, models.JournalBucketAvailable, application.Amount, "", remark)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		application.Status = models.ApplicationStatusApproved
+		application.AuditBy = auditBy
+		application.AuditRemark = auditRemark
+		application.AuditAt = &now
+		application.TransactionNo = tx.TransactionNo
+		return db.Save(&application).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// RejectDepositRefund 拒绝保证金退还申请
+func (s *AccountService) RejectDepositRefund(ctx context.Context, applicationID uint64, auditBy int64, auditRemark string) error {
+	now := time.Now()
+	res := s.db.Model(&models.DepositRefundApplication{}).
+		Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).
+		Updates(map[string]interface{}{
+			"status":       models.ApplicationStatusRejected,
+			"audit_by":     auditBy,
+			"audit_remark": auditRemark,
+			"audit_at":     now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("保证金退还申请不存在或已处理")
+	}
+	return nil
+}
+
+// GetDepositRefundApplications 获取保证金退还申请列表（adminID 为 0 时查全部）
+func (s *AccountService) GetDepositRefundApplications(ctx context.Context, adminID int64, status int8, page, pageSize int) ([]models.DepositRefundApplication, int64, error) {
+	var applications []models.DepositRefundApplication
+	var total int64
+
+	query := s.db.Model(&models.DepositRefundApplication{})
+	if adminID > 0 {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	query.Count(&total)
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&applications).Error
+	return applications, total, err
+}
+
+// backfillAfterDepositChange 保证金入账或应缴金额调整后，异步补扣「保证金不足」暂停入系统的订单
+// 保证金仍不足时补扣直接返回；补扣持有订单行锁并按订单幂等键冻结，多条路径重复触发不会重复扣款
+func (s *AccountService) backfillAfterDepositChange(adminID int64) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		okCount, failCount, err := NewPrepaymentCheckService().BackfillInsufficientOrders(ctx, adminID)
+		if err != nil {
+			fmt.Printf("[Deposit] 店主=%d 保证金变更后补扣订单异常: %v\n", adminID, err)
+		}
+		if okCount > 0 || failCount > 0 {
+			fmt.Printf("[Deposit] 店主=%d 保证金变更后补扣订单完成: 成功=%d, 失败=%d\n", adminID, okCount, failCount)
+		}
+	}()
+}
+
+// notifyDepositShort 保证金变为不足时通知店主
+func (s *AccountService) notifyDepositShort(ctx context.Context, account *models.DepositAccount) {
+	notifyID, err := s.idGen.GenerateNotificationID(ctx)
+	if err != nil {
+		return
+	}
+	notification := &models.Notification{
+		ID:      uint64(notifyID),
+		AdminID: account.AdminID,
+		Type:    models.NotifyTypeDepositShort,
+		Title:   "【保证金不足】请尽快补缴",
+		Content: fmt.Sprintf("您的保证金余额 %s %s 低于应缴金额 %s %s，补缴前将暂停绑定新店铺，新订单暂停入系统（补缴后自动补扣）。",
+			account.Balance.StringFixed(2), account.Currency, account.RequiredAmount.StringFixed(2), account.Currency),
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.Create(notification).Error; err != nil {
+		fmt.Printf("[Deposit] 店主=%d 保证金不足通知失败: %v\n", account.AdminID, err)
+	}
+}
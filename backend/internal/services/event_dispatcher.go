@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/middleware"
+	"balance/backend/internal/models"
+	"balance/backend/internal/utils"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	eventDispatchLockKey = "cron:event_dispatch"
+	eventDispatchLockTTL = 2 * time.Minute
+
+	eventFanOutBatchSize   = 200             // 每轮分发的发件箱事件数
+	eventDeliverBatchSize  = 20              // 每轮投递的记录数（单次超时 5 秒，保证一轮在锁有效期内完成）
+	eventDeliverTimeout    = 5 * time.Second // 单次投递超时
+	eventMaxAttempts       = 10              // 最大投递次数，超过后进入死信
+	eventRetryBaseInterval = 30 * time.Second
+	eventRetryMaxInterval  = 6 * time.Hour
+)
+
+// EventDispatcher 领域事件投递器（cron 进程内运行，多机部署时通过分布式锁保证单机执行）
+//  1. 分发：把待分发的发件箱事件按订阅展开为投递记录
+//  2. 投递：POST 签名 JSON 到订阅方回调地址，非 2xx 按指数退避重试，超过最大次数进入死信
+type EventDispatcher struct {
+	cron   *cron.Cron
+	db     *gorm.DB
+	client *http.Client
+	rs     *redsync.Redsync
+	logger *zap.SugaredLogger
+}
+
+// NewEventDispatcher 创建领域事件投递器
+func NewEventDispatcher(logger ...*zap.SugaredLogger) *EventDispatcher {
+	var l *zap.SugaredLogger
+	if len(logger) > 0 && logger[0] != nil {
+		l = logger[0]
+	} else {
+		l = utils.DefaultSugaredLogger()
+	}
+	return &EventDispatcher{
+		cron:   cron.New(cron.WithSeconds()),
+		db:     database.GetDB(),
+		client: &http.Client{Timeout: eventDeliverTimeout},
+		rs:     database.GetRedsync(),
+		logger: l,
+	}
+}
+
+// eventRetryDelay 第 attempts 次失败后的重试间隔：30s × 2^(attempts-1)，最长 6 小时
+func eventRetryDelay(attempts int) time.Duration {
+	delay := eventRetryBaseInterval
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= eventRetryMaxInterval {
+			return eventRetryMaxInterval
+		}
+	}
+	return delay
+}
+
+// tryRunWithLock 尝试获取分布式锁后执行，获取失败则跳过（其他节点正在执行）
+func (d *EventDispatcher) tryRunWithLock(fn func()) {
+	mutex := d.rs.NewMutex(eventDispatchLockKey, redsync.WithExpiry(eventDispatchLockTTL), redsync.WithTries(1))
+	if err := mutex.LockContext(context.Background()); err != nil {
+		return
+	}
+	defer mutex.Unlock()
+	fn()
+}
+
+// run 执行一轮分发与投递
+func (d *EventDispatcher) run() {
+	ctx := context.Background()
+	if err := d.fanOut(ctx); err != nil {
+		d.logger.Errorf("[EventDispatcher] 分发事件失败: %v", err)
+	}
+	d.deliverDue(ctx)
+}
+
+// fanOut 将待分发的发件箱事件展开为投递记录（唯一键 subscription_id + event_id 保证幂等）
+func (d *EventDispatcher) fanOut(ctx context.Context) error {
+	var events []models.OutboxEvent
+	if err := d.db.WithContext(ctx).Where("status = ?", models.OutboxEventPending).
+		Order("id ASC").Limit(eventFanOutBatchSize).Find(&events).Error; err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	var subs []models.WebhookSubscription
+	if err := d.db.WithContext(ctx).Where("status = ?", models.WebhookSubscriptionActive).Find(&subs).Error; err != nil {
+		return err
+	}
+
+	for i := range events {
+		event := &events[i]
+		now := time.Now()
+		var deliveries []models.WebhookDelivery
+		for j := range subs {
+			if !subscriptionMatches(&subs[j], event) {
+				continue
+			}
+			deliveries = append(deliveries, models.WebhookDelivery{
+				SubscriptionID: subs[j].ID,
+				AdminID:        subs[j].AdminID,
+				EventID:        event.EventID,
+				EventType:      event.EventType,
+				URL:            subs[j].URL,
+				Status:         models.WebhookDeliveryPending,
+				NextAttemptAt:  now,
+			})
+		}
+
+		err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if len(deliveries) > 0 {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+				"status":        models.OutboxEventDispatched,
+				"dispatched_at": &now,
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("event_id=%s: %w", event.EventID, err)
+		}
+	}
+	return nil
+}
+
+// deliverDue 投递到期的投递记录
+func (d *EventDispatcher) deliverDue(ctx context.Context) {
+	var deliveries []models.WebhookDelivery
+	if err := d.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(eventDeliverBatchSize).Find(&deliveries).Error; err != nil {
+		d.logger.Errorf("[EventDispatcher] 查询待投递记录失败: %v", err)
+		return
+	}
+	for i := range deliveries {
+		d.deliver(ctx, &deliveries[i])
+	}
+}
+
+// deliver 投递一条记录并更新投递结果
+func (d *EventDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	var sub models.WebhookSubscription
+	if err := d.db.WithContext(ctx).Where("id = ?", delivery.SubscriptionID).First(&sub).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			d.finish(ctx, delivery, models.WebhookDeliveryDead, 0, "订阅已删除")
+			return
+		}
+		d.logger.Errorf("[EventDispatcher] delivery_id=%d 查询订阅失败: %v", delivery.ID, err)
+		return
+	}
+	if sub.Status != models.WebhookSubscriptionActive {
+		d.finish(ctx, delivery, models.WebhookDeliveryDead, 0, "订阅已停用")
+		return
+	}
+
+	var event models.OutboxEvent
+	if err := d.db.WithContext(ctx).Where("event_id = ?", delivery.EventID).First(&event).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			d.finish(ctx, delivery, models.WebhookDeliveryDead, 0, "事件已过期清理")
+			return
+		}
+		d.logger.Errorf("[EventDispatcher] delivery_id=%d 查询事件失败: %v", delivery.ID, err)
+		return
+	}
+
+	statusCode, err := d.post(ctx, &sub, delivery, []byte(event.Payload))
+	if err == nil {
+		d.finish(ctx, delivery, models.WebhookDeliveryDelivered, statusCode, "")
+		middleware.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, "delivered").Inc()
+		return
+	}
+
+	if delivery.Attempts+1 >= eventMaxAttempts {
+		d.finish(ctx, delivery, models.WebhookDeliveryDead, statusCode, err.Error())
+		middleware.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, "dead").Inc()
+		d.logger.Warnf("[EventDispatcher] delivery_id=%d event_id=%s 超过最大投递次数，转入死信: %v", delivery.ID, delivery.EventID, err)
+		return
+	}
+	d.finish(ctx, delivery, models.WebhookDeliveryPending, statusCode, err.Error())
+	middleware.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, "retry").Inc()
+}
+
+// post 发送签名请求，2xx 视为送达
+func (d *EventDispatcher) post(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Balance-Event", delivery.EventType)
+	req.Header.Set("X-Balance-Event-Id", delivery.EventID)
+	req.Header.Set("X-Balance-Delivery", strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set("X-Balance-Signature", fmt.Sprintf("t=%d,v1=%s", timestamp, SignWebhookPayload(sub.Secret, timestamp, body)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("回调返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// finish 记录一次投递结果；仍为待投递时按退避计算下次投递时间
+func (d *EventDispatcher) finish(ctx context.Context, delivery *models.WebhookDelivery, status int8, statusCode int, lastError string) {
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+	if runes := []rune(lastError); len(runes) > 500 {
+		lastError = string(runes[:500])
+	}
+	updates := map[string]interface{}{
+		"status":           status,
+		"attempts":         attempts,
+		"last_status_code": statusCode,
+		"last_error":       lastError,
+	}
+	switch status {
+	case models.WebhookDeliveryDelivered:
+		updates["delivered_at"] = &now
+	case models.WebhookDeliveryPending:
+		updates["next_attempt_at"] = now.Add(eventRetryDelay(attempts))
+	}
+	// 以取出时的状态和次数作为条件，避免覆盖投递期间发生的手动重投
+	if err := d.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.WebhookDeliveryPending, delivery.Attempts).
+		Updates(updates).Error; err != nil {
+		d.logger.Errorf("[EventDispatcher] delivery_id=%d 更新投递结果失败: %v", delivery.ID, err)
+	}
+}
+
+// Start 启动领域事件投递器
+func (d *EventDispatcher) Start() {
+	d.logger.Info("[EventDispatcher] 启动领域事件投递器...")
+
+	// 每 5 秒分发并投递一轮（分布式锁）
+	_, err := d.cron.AddFunc("*/5 * * * * *", func() {
+		d.tryRunWithLock(d.run)
+	})
+	if err != nil {
+		d.logger.Errorf("[EventDispatcher] 添加定时任务失败: %v", err)
+		return
+	}
+
+	d.cron.Start()
+	d.logger.Info("[EventDispatcher] 领域事件投递器已启动")
+}
+
+// Stop 停止领域事件投递器（等待正在执行的一轮结束）
+func (d *EventDispatcher) Stop() {
+	d.logger.Info("[EventDispatcher] 停止领域事件投递器...")
+	<-d.cron.Stop().Done()
+	d.logger.Info("[EventDispatcher] 领域事件投递器已停止")
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// encryptedColumns 加密存储的字段（serializer:encrypted），主密钥轮换时需要重新加密
+var encryptedColumns = []struct {
+	Table   string
+	Columns []string
+}{
+	{Table: "collection_accounts", Columns: []string{"account_no", "payee"}},
+	{Table: "shop_authorizations", Columns: []string{"access_token", "refresh_token"}},
+	{Table: "payout_batch_items", Columns: []string{"account_no", "payee"}},
+}
+
+// FieldRotationResult 单表字段重新加密结果
+type FieldRotationResult struct {
+	Table   string `json:"table"`
+	Scanned int    `json:"scanned"` // 扫描行数
+	Rotated int    `json:"rotated"` // 重新加密的行数（dry-run 时为需要重新加密的行数）
+}
+
+// FieldEncryptionService 字段加密维护服务
+type FieldEncryptionService struct {
+	db *gorm.DB
+}
+
+// NewFieldEncryptionService 创建字段加密维护服务
+func NewFieldEncryptionService() *FieldEncryptionService {
+	return &FieldEncryptionService{
+		db: database.GetDB(),
+	}
+}
+
+// RotateAll 将所有加密字段中的明文和旧主密钥密文按批用当前主密钥重新加密。
+// 直接读写原始列值（不经过序列化器），按原值条件更新，避免覆盖并发写入；可重复执行。
+// Redis 中的令牌缓存无需处理：旧主密钥下线后解密失败会回源数据库并用当前主密钥重新缓存。
+func (s *FieldEncryptionService) RotateAll(ctx context.Context, batchSize int, dryRun bool) ([]FieldRotationResult, error) {
+	cipher := utils.GetFieldCipher()
+	if cipher == nil {
+		return nil, fmt.Errorf("未配置字段加密密钥(crypto.active_key_id)")
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	results := make([]FieldRotationResult, 0, len(encryptedColumns))
+	for _, target := range encryptedColumns {
+		result, err := s.rotateTable(ctx, cipher, target.Table, target.Columns, batchSize, dryRun)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// rotateTable 按主键分批重新加密一张表的加密字段
+func (s *FieldEncryptionService) rotateTable(ctx context.Context, cipher *utils.FieldCipher, table string, columns []string, batchSize int, dryRun bool) (FieldRotationResult, error) {
+	result := FieldRotationResult{Table: table}
+	var lastID uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		rows, err := s.db.WithContext(ctx).Table(table).Select("id, "+strings.Join(columns, ", ")).
+			Where("id > ?", lastID).Order("id ASC").Limit(batchSize).Rows()
+		if err != nil {
+			return result, fmt.Errorf("读取%s失败: %w", table, err)
+		}
+		type rawRow struct {
+			id     uint64
+			values []sql.NullString
+		}
+		var batch []rawRow
+		for rows.Next() {
+			row := rawRow{values: make([]sql.NullString, len(columns))}
+			dest := []interface{}{&row.id}
+			for i := range row.values {
+				dest = append(dest, &row.values[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return result, fmt.Errorf("读取%s失败: %w", table, err)
+			}
+			batch = append(batch, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return result, fmt.Errorf("读取%s失败: %w", table, err)
+		}
+
+		for _, row := range batch {
+			lastID = row.id
+			result.Scanned++
+
+			updates := make(map[string]interface{})
+			query := s.db.WithContext(ctx).Table(table).Where("id = ?", row.id)
+			for i, column := range columns {
+				rotated, changed, err := cipher.Rotate(row.values[i].String)
+				if err != nil {
+					return result, fmt.Errorf("%s id=%d 字段%s重新加密失败: %w", table, row.id, column, err)
+				}
+				if changed {
+					updates[column] = rotated
+					query = query.Where(column+" = ?", row.values[i].String)
+				}
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if dryRun {
+				result.Rotated++
+				continue
+			}
+			update := query.Updates(updates)
+			if update.Error != nil {
+				return result, fmt.Errorf("更新%s id=%d 失败: %w", table, row.id, update.Error)
+			}
+			// 影响行数为 0 说明该行已被并发修改（新值已由序列化器加密），无需重试
+			if update.RowsAffected > 0 {
+				result.Rotated++
+			}
+		}
+
+		if len(batch) < batchSize {
+			return result, nil
+		}
+	}
+}
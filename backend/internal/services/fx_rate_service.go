@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// NormalizeCurrency 规范化币种代码（去空格转大写，空值取默认币种）
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return models.DefaultCurrency
+	}
+	return currency
+}
+
+// FxRateService 汇率服务
+type FxRateService struct {
+	db *gorm.DB
+}
+
+// NewFxRateService 创建汇率服务
+func NewFxRateService() *FxRateService {
+	return &FxRateService{
+		db: database.GetDB(),
+	}
+}
+
+// GetRateInTx 获取 from→to 在 at 时刻生效的汇率（同币种返回 1）
+// 优先取正向币种对，未配置时取反向币种对的倒数；返回值 rateID 为使用的汇率记录ID
+func (s *FxRateService) GetRateInTx(db *gorm.DB, from, to string, at time.Time) (rate decimal.Decimal, rateID uint64, err error) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == to {
+		return decimal.NewFromInt(1), 0, nil
+	}
+
+	var fx models.FxRate
+	err = db.Where("base_currency = ? AND quote_currency = ? AND effective_from <= ?", from, to, at).
+		Order("effective_from DESC, id DESC").First(&fx).Error
+	if err == nil {
+		return fx.Rate, fx.ID, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return decimal.Zero, 0, err
+	}
+
+	err = db.Where("base_currency = ? AND quote_currency = ? AND effective_from <= ?", to, from, at).
+		Order("effective_from DESC, id DESC").First(&fx).Error
+	if err == gorm.ErrRecordNotFound {
+		return decimal.Zero, 0, fmt.Errorf("未配置 %s→%s 在 %s 生效的汇率", from, to, at.Format("2006-01-02 15:04:05"))
+	}
+	if err != nil {
+		return decimal.Zero, 0, err
+	}
+	return decimal.NewFromInt(1).DivRound(fx.Rate, 8), fx.ID, nil
+}
+
+// ConvertInTx 按 at 时刻生效的汇率折算金额（保留两位小数）
+func (s *FxRateService) ConvertInTx(db *gorm.DB, amount decimal.Decimal, from, to string, at time.Time) (converted, rate decimal.Decimal, rateID uint64, err error) {
+	rate, rateID, err = s.GetRateInTx(db, from, to, at)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, 0, err
+	}
+	return amount.Mul(rate).Round(2), rate, rateID, nil
+}
+
+// CreateRate 录入汇率（生效时间为空时立即生效；历史生效时间不回溯影响已入账业务）
+func (s *FxRateService) CreateRate(ctx context.Context, fx *models.FxRate) error {
+	fx.BaseCurrency = NormalizeCurrency(fx.BaseCurrency)
+	fx.QuoteCurrency = NormalizeCurrency(fx.QuoteCurrency)
+	if fx.BaseCurrency == fx.QuoteCurrency {
+		return fmt.Errorf("基准币种与报价币种不能相同")
+	}
+	if fx.Rate.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("汇率必须大于0")
+	}
+	if fx.EffectiveFrom.IsZero() {
+		fx.EffectiveFrom = time.Now()
+	}
+	if fx.Source == "" {
+		fx.Source = "manual"
+	}
+	return s.db.WithContext(ctx).Create(fx).Error
+}
+
+// ListRates 获取汇率列表（按币种对过滤，生效时间倒序）
+func (s *FxRateService) ListRates(ctx context.Context, baseCurrency, quoteCurrency string, page, pageSize int) ([]models.FxRate, int64, error) {
+	var rates []models.FxRate
+	var total int64
+
+	query := s.db.Model(&models.FxRate{})
+	if baseCurrency != "" {
+		query = query.Where("base_currency = ?", NormalizeCurrency(baseCurrency))
+	}
+	if quoteCurrency != "" {
+		query = query.Where("quote_currency = ?", NormalizeCurrency(quoteCurrency))
+	}
+
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("effective_from DESC, id DESC").Offset(offset).Limit(pageSize).Find(&rates).Error
+	return rates, total, err
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// holdExpireBatchSize 每次过期处理的最大暂扣数
+const holdExpireBatchSize = 500
+
+// AccountHoldRequest 创建风控暂扣参数
+type AccountHoldRequest struct {
+	AccountType string          // operator / shop_owner_commission
+	AdminID     int64           // 账户所属用户ID
+	Currency    string          // 账户币种，为空时默认 TWD
+	Amount      decimal.Decimal // 暂扣金额
+	ShopID      uint64          // 店铺ID
+	OrderSN     string          // 关联订单号（与退货单号至少填一个）
+	ReturnSN    string          // 关联退货单号，退货关闭/取消时自动释放
+	Reason      string          // 暂扣原因
+	ExpiresAt   *time.Time      // 到期时间，为空则需手动释放
+	CreatedBy   int64           // 创建人ID（0为系统）
+}
+
+// WithdrawableBalance 可提现余额：余额已扣除提现中的金额，再扣除生效中的风控暂扣
+func WithdrawableBalance(balance, heldAmount decimal.Decimal) decimal.Decimal {
+	return balance.Sub(heldAmount)
+}
+
+// CreateHold 对运营/店主佣金账户创建风控暂扣（不变动余额，只限制提现）
+func (s *AccountService) CreateHold(ctx context.Context, req AccountHoldRequest) (*models.AccountHold, error) {
+	if req.Amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("暂扣金额必须大于0")
+	}
+	if req.OrderSN == "" && req.ReturnSN == "" {
+		return nil, fmt.Errorf("暂扣需关联订单号或退货单号")
+	}
+	if req.ReturnSN != "" && req.ShopID == 0 {
+		return nil, fmt.Errorf("关联退货单时需指定店铺ID")
+	}
+	if req.Reason == "" {
+		return nil, fmt.Errorf("请填写暂扣原因")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("到期时间必须晚于当前时间")
+	}
+	req.Currency = NormalizeCurrency(req.Currency)
+
+	// 确保账户存在
+	switch req.AccountType {
+	case models.AccountTypeOperator:
+		if _, err := s.GetOrCreateOperatorAccount(ctx, req.AdminID, req.Currency); err != nil {
+			return nil, err
+		}
+	case models.AccountTypeShopOwnerCommission:
+		if _, err := s.GetOrCreateShopOwnerCommissionAccount(ctx, req.AdminID, req.Currency); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s 账户不支持风控暂扣", req.AccountType)
+	}
+
+	holdID, err := s.idGen.GenerateAccountHoldID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("生成暂扣ID失败: %w", err)
+	}
+	hold := &models.AccountHold{
+		ID:          uint64(holdID),
+		HoldNo:      s.GenerateApplicationNo("HD"),
+		AccountType: req.AccountType,
+		AdminID:     req.AdminID,
+		Currency:    req.Currency,
+		Amount:      req.Amount,
+		ShopID:      req.ShopID,
+		OrderSN:     req.OrderSN,
+		ReturnSN:    req.ReturnSN,
+		Reason:      req.Reason,
+		Status:      models.HoldStatusActive,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   req.CreatedBy,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.adjustHeldAmountInTx(tx, hold.AccountType, hold.AdminID, hold.Currency, hold.Amount); err != nil {
+			return err
+		}
+		return tx.Create(hold).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// ReleaseHold 手动释放风控暂扣
+func (s *AccountService) ReleaseHold(ctx context.Context, holdID uint64, releasedBy int64, remark string) (*models.AccountHold, error) {
+	var hold models.AccountHold
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, holdID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("暂扣记录不存在")
+			}
+			return err
+		}
+		if hold.Status != models.HoldStatusActive {
+			return fmt.Errorf("暂扣已释放或已过期")
+		}
+		return s.releaseHoldInTx(tx, &hold, models.HoldStatusReleased, releasedBy, remark)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseHoldsByReturn 释放关联退货单的全部生效中暂扣（退货关闭/取消时由 ReturnService 调用，可重复调用）
+func (s *AccountService) ReleaseHoldsByReturn(ctx context.Context, shopID uint64, returnSN string, remark string) (int, error) {
+	var ids []uint64
+	if err := s.db.Model(&models.AccountHold{}).
+		Where("shop_id = ? AND return_sn = ? AND status = ?", shopID, returnSN, models.HoldStatusActive).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return s.finishHolds(ids, models.HoldStatusReleased, remark)
+}
+
+// ExpireHolds 将已到期的生效中暂扣标记为过期并释放暂扣金额
+func (s *AccountService) ExpireHolds(ctx context.Context) (int, error) {
+	var ids []uint64
+	if err := s.db.Model(&models.AccountHold{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", models.HoldStatusActive, time.Now()).
+		Order("expires_at ASC").Limit(holdExpireBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return s.finishHolds(ids, models.HoldStatusExpired, "暂扣到期自动释放")
+}
+
+// finishHolds 逐条结束暂扣（每条独立事务，已被其他流程结束的跳过）
+func (s *AccountService) finishHolds(ids []uint64, status int8, remark string) (int, error) {
+	count := 0
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var hold models.AccountHold
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, id).Error; err != nil {
+				return err
+			}
+			if hold.Status != models.HoldStatusActive {
+				return nil
+			}
+			if err := s.releaseHoldInTx(tx, &hold, status, 0, remark); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, fmt.Errorf("释放暂扣 %d 失败: %w", id, err)
+		}
+	}
+	return count, nil
+}
+
+// releaseHoldInTx 结束暂扣并扣减账户暂扣金额（事务参与版本，调用方需已锁定暂扣记录）
+func (s *AccountService) releaseHoldInTx(db *gorm.DB, hold *models.AccountHold, status int8, releasedBy int64, remark string) error {
+	if err := s.adjustHeldAmountInTx(db, hold.AccountType, hold.AdminID, hold.Currency, hold.Amount.Neg()); err != nil {
+		return err
+	}
+	now := time.Now()
+	hold.Status = status
+	hold.ReleasedBy = releasedBy
+	hold.ReleaseRemark = remark
+	hold.ReleasedAt = &now
+	return db.Save(hold).Error
+}
+
+// adjustHeldAmountInTx 调整账户暂扣金额（事务参与版本，FOR UPDATE 行锁）
+func (s *AccountService) adjustHeldAmountInTx(db *gorm.DB, accountType string, adminID int64, currency string, delta decimal.Decimal) error {
+	var model interface{}
+	switch accountType {
+	case models.AccountTypeOperator:
+		model = &models.OperatorAccount{}
+	case models.AccountTypeShopOwnerCommission:
+		model = &models.ShopOwnerCommissionAccount{}
+	default:
+		return fmt.Errorf("%s 账户不支持风控暂扣", accountType)
+	}
+
+	var held decimal.Decimal
+	row := db.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("held_amount").Where("admin_id = ? AND currency = ?", adminID, currency).Row()
+	if err := row.Scan(&held); err != nil {
+		return fmt.Errorf("账户不存在")
+	}
+	held = held.Add(delta)
+	if held.LessThan(decimal.Zero) {
+		held = decimal.Zero
+	}
+	return db.Model(model).Where("admin_id = ? AND currency = ?", adminID, currency).
+		Update("held_amount", held).Error
+}
+
+// GetHolds 获取风控暂扣列表（adminID 为 0 时不过滤用户，status 为 -1 时不过滤状态）
+func (s *AccountService) GetHolds(ctx context.Context, accountType string, adminID int64, status int8, orderSN string, returnSN string, page, pageSize int) ([]models.AccountHold, int64, error) {
+	var holds []models.AccountHold
+	var total int64
+
+	query := s.db.Model(&models.AccountHold{})
+	if accountType != "" {
+		query = query.Where("account_type = ?", accountType)
+	}
+	if adminID > 0 {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if orderSN != "" {
+		query = query.Where("order_sn = ?", orderSN)
+	}
+	if returnSN != "" {
+		query = query.Where("return_sn = ?", returnSN)
+	}
+
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&holds).Error
+	return holds, total, err
+}
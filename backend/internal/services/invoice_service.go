@@ -0,0 +1,515 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// InvoiceService 结算发票服务（按周期汇总运营/店主的结算单与调账明细开具发票）
+type InvoiceService struct {
+	db *gorm.DB
+}
+
+// NewInvoiceService 创建结算发票服务
+func NewInvoiceService() *InvoiceService {
+	return &InvoiceService{
+		db: database.GetDB(),
+	}
+}
+
+// InvoiceQuery 发票查询条件（AdminID 为空时不按用户过滤，Status 为 -1 表示全部）
+type InvoiceQuery struct {
+	PartyType  string
+	AdminID    *int64
+	PeriodType string
+	Period     string
+	Currency   string
+	Status     int8
+}
+
+// InvoiceGenerateResult 发票开具结果
+type InvoiceGenerateResult struct {
+	PeriodType string                     `json:"period_type"`
+	Period     string                     `json:"period"`
+	Issued     []models.SettlementInvoice `json:"issued"`
+	Skipped    int                        `json:"skipped"` // 已有未作废发票而跳过的开票对象数
+}
+
+// InvoiceCurrencyTotal 按币种汇总的发票金额
+type InvoiceCurrencyTotal struct {
+	Currency     string          `json:"currency"`
+	InvoiceCount int64           `json:"invoice_count"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	PaidAmount   decimal.Decimal `json:"paid_amount"`
+}
+
+// ParseInvoicePeriod 解析发票周期，返回 [start, end) 时间范围
+// 月度周期格式为 YYYY-MM，周度周期为 ISO 周 YYYY-Www（周一开始）
+func ParseInvoicePeriod(periodType, period string) (start, end time.Time, err error) {
+	switch periodType {
+	case models.InvoicePeriodMonthly:
+		return ParseStatementPeriod(period)
+	case models.InvoicePeriodWeekly:
+		var year, week int
+		if _, err := fmt.Sscanf(period, "%4d-W%2d", &year, &week); err != nil || week < 1 || week > 53 ||
+			fmt.Sprintf("%04d-W%02d", year, week) != period {
+			return time.Time{}, time.Time{}, fmt.Errorf("周期格式错误，应为 YYYY-Www")
+		}
+		// 1月4日所在的周为该年第1周
+		jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, time.Local)
+		offset := (int(jan4.Weekday()) + 6) % 7
+		start = jan4.AddDate(0, 0, -offset+(week-1)*7)
+		if y, w := start.ISOWeek(); y != year || w != week {
+			return time.Time{}, time.Time{}, fmt.Errorf("周期 %s 不存在", period)
+		}
+		return start, start.AddDate(0, 0, 7), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("不支持的周期类型: %s", periodType)
+	}
+}
+
+// PreviousInvoicePeriod 上一个完整周期（上周或上月）
+func PreviousInvoicePeriod(periodType string, now time.Time) string {
+	if periodType == models.InvoicePeriodWeekly {
+		year, week := now.AddDate(0, 0, -7).ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	}
+	return PreviousStatementPeriod(now)
+}
+
+// invoicePrefix 发票号前缀：INV-{O运营/S店主}-{周期}-，同前缀下按序号递增
+func invoicePrefix(partyType, period string) string {
+	code := "O"
+	if partyType == models.InvoicePartyShopOwner {
+		code = "S"
+	}
+	return fmt.Sprintf("INV-%s-%s-", code, strings.Replace(period, "-", "", 1))
+}
+
+type invoiceKey struct {
+	PartyType string
+	AdminID   int64
+	Currency  string
+}
+
+// GenerateInvoices 开具指定周期的运营/店主发票
+// 周期内有结算或调账的每个开票对象+币种开具一张；已有未作废发票的跳过，作废后重新执行即可重开
+func (s *InvoiceService) GenerateInvoices(ctx context.Context, periodType, period string, createdBy int64) (*InvoiceGenerateResult, error) {
+	start, end, err := ParseInvoicePeriod(periodType, period)
+	if err != nil {
+		return nil, err
+	}
+	if end.After(time.Now()) {
+		return nil, fmt.Errorf("周期 %s 尚未结束", period)
+	}
+
+	var buckets map[invoiceKey]*models.SettlementInvoice
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var buildErr error
+		buckets, buildErr = s.collectInvoiceLines(tx, start, end)
+		return buildErr
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]invoiceKey, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].PartyType != keys[j].PartyType {
+			return keys[i].PartyType < keys[j].PartyType
+		}
+		if keys[i].AdminID != keys[j].AdminID {
+			return keys[i].AdminID < keys[j].AdminID
+		}
+		return keys[i].Currency < keys[j].Currency
+	})
+
+	result := &InvoiceGenerateResult{PeriodType: periodType, Period: period, Issued: []models.SettlementInvoice{}}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		seq := make(map[string]int)
+		for _, k := range keys {
+			var exists int64
+			if err := tx.Model(&models.SettlementInvoice{}).
+				Where("party_type = ? AND admin_id = ? AND period_type = ? AND period = ? AND currency = ? AND status != ?",
+					k.PartyType, k.AdminID, periodType, period, k.Currency, models.InvoiceVoided).
+				Count(&exists).Error; err != nil {
+				return err
+			}
+			if exists > 0 {
+				result.Skipped++
+				continue
+			}
+
+			prefix := invoicePrefix(k.PartyType, period)
+			if _, ok := seq[prefix]; !ok {
+				var last string
+				if err := tx.Model(&models.SettlementInvoice{}).Where("invoice_no LIKE ?", prefix+"%").
+					Select("COALESCE(MAX(invoice_no), '')").Scan(&last).Error; err != nil {
+					return err
+				}
+				if last != "" {
+					seq[prefix], _ = strconv.Atoi(strings.TrimPrefix(last, prefix))
+				}
+			}
+			seq[prefix]++
+
+			inv := buckets[k]
+			inv.InvoiceNo = fmt.Sprintf("%s%05d", prefix, seq[prefix])
+			inv.PeriodType = periodType
+			inv.Period = period
+			inv.PeriodStart = start
+			inv.PeriodEnd = end
+			inv.Status = models.InvoiceIssued
+			inv.IssuedAt = now
+			inv.CreatedBy = createdBy
+			sort.Slice(inv.Lines, func(i, j int) bool {
+				return inv.Lines[i].OccurredAt.Before(inv.Lines[j].OccurredAt)
+			})
+			for i := range inv.Lines {
+				inv.Lines[i].InvoiceNo = inv.InvoiceNo
+			}
+
+			if err := tx.Create(inv).Error; err != nil {
+				return fmt.Errorf("保存发票失败: %w", err)
+			}
+			if err := tx.CreateInBatches(inv.Lines, 500).Error; err != nil {
+				return fmt.Errorf("保存发票明细失败: %w", err)
+			}
+			issued := *inv
+			issued.Lines = nil
+			result.Issued = append(result.Issued, issued)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// collectInvoiceLines 遍历结算分表，按开票对象+币种归集周期内的结算单与调账明细
+func (s *InvoiceService) collectInvoiceLines(tx *gorm.DB, start, end time.Time) (map[invoiceKey]*models.SettlementInvoice, error) {
+	buckets := make(map[invoiceKey]*models.SettlementInvoice)
+	add := func(partyType string, adminID int64, currency string, line models.SettlementInvoiceLine) {
+		if adminID == 0 {
+			return
+		}
+		k := invoiceKey{PartyType: partyType, AdminID: adminID, Currency: currency}
+		inv, ok := buckets[k]
+		if !ok {
+			inv = &models.SettlementInvoice{PartyType: partyType, AdminID: adminID, Currency: currency}
+			buckets[k] = inv
+		}
+		if line.LineType == models.InvoiceLineSettlement {
+			inv.OrderCount++
+		} else {
+			inv.AdjustmentCount++
+		}
+		inv.EscrowAmount = inv.EscrowAmount.Add(line.EscrowAmount)
+		inv.CostAmount = inv.CostAmount.Add(line.CostAmount)
+		inv.ShareAmount = inv.ShareAmount.Add(line.ShareAmount)
+		inv.TotalAmount = inv.TotalAmount.Add(line.Amount)
+		inv.Lines = append(inv.Lines, line)
+	}
+
+	for i := 0; i < database.ShardCount; i++ {
+		shard := uint64(i)
+		var settlements []models.OrderSettlement
+		if err := tx.Table(database.GetOrderSettlementTableName(shard)).
+			Where("status = ? AND settled_at >= ? AND settled_at < ?", models.OrderSettlementCompleted, start, end).
+			Order("settled_at ASC, id ASC").
+			Find(&settlements).Error; err != nil {
+			return nil, err
+		}
+		for _, st := range settlements {
+			base := models.SettlementInvoiceLine{
+				LineType:     models.InvoiceLineSettlement,
+				ShopID:       st.ShopID,
+				OrderSN:      st.OrderSN,
+				SettlementNo: st.SettlementNo,
+				EscrowAmount: st.EscrowAmount,
+				OccurredAt:   *st.SettledAt,
+			}
+			opLine := base
+			opLine.CostAmount = st.TotalCost
+			opLine.ShareRate = st.OperatorShareRate
+			opLine.ShareAmount = st.OperatorShare
+			opLine.Amount = st.OperatorIncome
+			add(models.InvoicePartyOperator, st.OperatorID, st.Currency, opLine)
+
+			soLine := base
+			soLine.ShareRate = st.ShopOwnerShareRate
+			soLine.ShareAmount = st.ShopOwnerShare
+			soLine.Amount = st.ShopOwnerShare
+			add(models.InvoicePartyShopOwner, st.ShopOwnerID, st.Currency, soLine)
+		}
+
+		var adjustments []struct {
+			models.OrderSettlementAdjustment
+			SettlementNo       string
+			OperatorID         int64
+			ShopOwnerID        int64
+			OperatorShareRate  decimal.Decimal
+			ShopOwnerShareRate decimal.Decimal
+		}
+		if err := tx.Table(database.GetOrderSettlementAdjustmentTableName(shard)+" AS a").
+			Select("a.*, s.settlement_no, s.operator_id, s.shop_owner_id, s.operator_share_rate, s.shop_owner_share_rate").
+			Joins("JOIN "+database.GetOrderSettlementTableName(shard)+" AS s ON s.id = a.settlement_id").
+			Where("a.created_at >= ? AND a.created_at < ?", start, end).
+			Order("a.created_at ASC, a.id ASC").
+			Find(&adjustments).Error; err != nil {
+			return nil, err
+		}
+		for _, adj := range adjustments {
+			base := models.SettlementInvoiceLine{
+				LineType:        models.InvoiceLineAdjustment,
+				ShopID:          adj.ShopID,
+				OrderSN:         adj.OrderSN,
+				SettlementNo:    adj.SettlementNo,
+				TransactionType: adj.TransactionType,
+				EscrowAmount:    adj.Amount,
+				OccurredAt:      adj.CreatedAt,
+			}
+			opLine := base
+			opLine.ShareRate = adj.OperatorShareRate
+			opLine.ShareAmount = adj.OperatorShare
+			opLine.Amount = adj.OperatorShare
+			add(models.InvoicePartyOperator, adj.OperatorID, adj.Currency, opLine)
+
+			soLine := base
+			soLine.ShareRate = adj.ShopOwnerShareRate
+			soLine.ShareAmount = adj.ShopOwnerShare
+			soLine.Amount = adj.ShopOwnerShare
+			add(models.InvoicePartyShopOwner, adj.ShopOwnerID, adj.Currency, soLine)
+		}
+	}
+	return buckets, nil
+}
+
+func (s *InvoiceService) buildQuery(db *gorm.DB, q InvoiceQuery) *gorm.DB {
+	query := db.Model(&models.SettlementInvoice{})
+	if q.PartyType != "" {
+		query = query.Where("party_type = ?", q.PartyType)
+	}
+	if q.AdminID != nil {
+		query = query.Where("admin_id = ?", *q.AdminID)
+	}
+	if q.PeriodType != "" {
+		query = query.Where("period_type = ?", q.PeriodType)
+	}
+	if q.Period != "" {
+		query = query.Where("period = ?", q.Period)
+	}
+	if q.Currency != "" {
+		query = query.Where("currency = ?", NormalizeCurrency(q.Currency))
+	}
+	if q.Status >= 0 {
+		query = query.Where("status = ?", q.Status)
+	}
+	return query
+}
+
+// ListInvoices 获取发票列表（不含明细）及按币种汇总的金额
+func (s *InvoiceService) ListInvoices(ctx context.Context, q InvoiceQuery, page, pageSize int) ([]models.SettlementInvoice, int64, []InvoiceCurrencyTotal, error) {
+	var invoices []models.SettlementInvoice
+	var total int64
+
+	if err := s.buildQuery(s.db.WithContext(ctx), q).Count(&total).Error; err != nil {
+		return nil, 0, nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := s.buildQuery(s.db.WithContext(ctx), q).Order("issued_at DESC, id DESC").
+		Offset(offset).Limit(pageSize).Find(&invoices).Error; err != nil {
+		return nil, 0, nil, err
+	}
+
+	// 作废发票不计入金额汇总
+	totals := []InvoiceCurrencyTotal{}
+	err := s.buildQuery(s.db.WithContext(ctx), q).
+		Select("currency, COUNT(*) AS invoice_count, COALESCE(SUM(total_amount), 0) AS total_amount, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN total_amount ELSE 0 END), 0) AS paid_amount", models.InvoicePaid).
+		Where("status != ?", models.InvoiceVoided).
+		Group("currency").Order("currency ASC").
+		Scan(&totals).Error
+	return invoices, total, totals, err
+}
+
+// GetInvoice 获取发票及明细（partyType/adminID 非空时校验发票归属）
+func (s *InvoiceService) GetInvoice(ctx context.Context, invoiceNo string, partyType string, adminID *int64) (*models.SettlementInvoice, error) {
+	var invoice models.SettlementInvoice
+	query := s.buildQuery(s.db.WithContext(ctx), InvoiceQuery{PartyType: partyType, AdminID: adminID, Status: -1})
+	if err := query.Where("invoice_no = ?", invoiceNo).First(&invoice).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("发票不存在")
+		}
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Where("invoice_no = ?", invoiceNo).
+		Order("occurred_at ASC, id ASC").Find(&invoice.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// MarkInvoicePaid 标记发票已付款
+func (s *InvoiceService) MarkInvoicePaid(ctx context.Context, invoiceNo, paymentRef string) (*models.SettlementInvoice, error) {
+	now := time.Now()
+	return s.transitInvoice(ctx, invoiceNo, models.InvoiceIssued, "发票非已开具状态，不能标记付款", map[string]interface{}{
+		"status":      models.InvoicePaid,
+		"paid_at":     now,
+		"payment_ref": paymentRef,
+	})
+}
+
+// VoidInvoice 作废发票（已付款的发票不能作废），作废后可重新开具该周期发票
+func (s *InvoiceService) VoidInvoice(ctx context.Context, invoiceNo string, voidedBy int64, reason string) (*models.SettlementInvoice, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("请填写作废原因")
+	}
+	now := time.Now()
+	return s.transitInvoice(ctx, invoiceNo, models.InvoiceIssued, "仅已开具未付款的发票可以作废", map[string]interface{}{
+		"status":      models.InvoiceVoided,
+		"voided_by":   voidedBy,
+		"voided_at":   now,
+		"void_reason": reason,
+	})
+}
+
+// transitInvoice 按当前状态条件更新发票状态
+func (s *InvoiceService) transitInvoice(ctx context.Context, invoiceNo string, from int8, conflictMsg string, updates map[string]interface{}) (*models.SettlementInvoice, error) {
+	result := s.db.WithContext(ctx).Model(&models.SettlementInvoice{}).
+		Where("invoice_no = ? AND status = ?", invoiceNo, from).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	var invoice models.SettlementInvoice
+	if err := s.db.WithContext(ctx).Where("invoice_no = ?", invoiceNo).First(&invoice).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("发票不存在")
+		}
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%s", conflictMsg)
+	}
+	return &invoice, nil
+}
+
+// 发票导出格式
+const (
+	InvoiceFormatCSV  = "csv"
+	InvoiceFormatHTML = "html"
+)
+
+var invoiceStatusNames = map[int8]string{
+	models.InvoiceIssued: "已开具",
+	models.InvoicePaid:   "已付款",
+	models.InvoiceVoided: "已作废",
+}
+
+var invoicePartyNames = map[string]string{
+	models.InvoicePartyOperator:  "运营",
+	models.InvoicePartyShopOwner: "店主",
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money":  func(d decimal.Decimal) string { return d.StringFixed(2) },
+	"date":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"time":   func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"status": func(s int8) string { return invoiceStatusNames[s] },
+	"party":  func(p string) string { return invoicePartyNames[p] },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.InvoiceNo}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 24px; }
+table { border-collapse: collapse; width: 100%; margin-top: 12px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td.num { text-align: right; }
+.void { color: #c00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h2>结算发票 {{.InvoiceNo}}</h2>
+{{if eq .Status 2}}<p class="void">已作废：{{.VoidReason}}</p>{{end}}
+<table>
+<tr><th>开票对象</th><td>{{party .PartyType}} #{{.AdminID}}</td><th>状态</th><td>{{status .Status}}</td></tr>
+<tr><th>周期</th><td>{{.Period}}（{{date .PeriodStart}} 至 {{date .PeriodEnd}}，不含）</td><th>开具时间</th><td>{{time .IssuedAt}}</td></tr>
+<tr><th>币种</th><td>{{.Currency}}</td><th>付款凭证</th><td>{{.PaymentRef}}</td></tr>
+<tr><th>结算订单数</th><td class="num">{{.OrderCount}}</td><th>调账笔数</th><td class="num">{{.AdjustmentCount}}</td></tr>
+<tr><th>Shopee结算金额</th><td class="num">{{money .EscrowAmount}}</td><th>成本合计</th><td class="num">{{money .CostAmount}}</td></tr>
+<tr><th>分成合计</th><td class="num">{{money .ShareAmount}}</td><th>发票金额</th><td class="num"><strong>{{money .TotalAmount}}</strong></td></tr>
+</table>
+<table>
+<tr><th>时间</th><th>类型</th><th>店铺ID</th><th>订单编号</th><th>结算单号</th><th>调账类型</th><th>结算金额</th><th>成本</th><th>分成比例%</th><th>分成金额</th><th>金额</th></tr>
+{{range .Lines}}<tr><td>{{time .OccurredAt}}</td><td>{{if eq .LineType "adjustment"}}调账{{else}}结算{{end}}</td><td>{{.ShopID}}</td><td>{{.OrderSN}}</td><td>{{.SettlementNo}}</td><td>{{.TransactionType}}</td><td class="num">{{money .EscrowAmount}}</td><td class="num">{{money .CostAmount}}</td><td class="num">{{money .ShareRate}}</td><td class="num">{{money .ShareAmount}}</td><td class="num">{{money .Amount}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// EncodeInvoice 将发票编码为下载文件内容，返回内容及 Content-Type
+// CSV 每行一条明细（发票字段重复列出），无明细的发票输出一行空明细
+func EncodeInvoice(invoice *models.SettlementInvoice, format string) ([]byte, string, error) {
+	switch format {
+	case InvoiceFormatHTML:
+		var buf bytes.Buffer
+		if err := invoiceTemplate.Execute(&buf, invoice); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/html; charset=utf-8", nil
+	case InvoiceFormatCSV, "":
+		var buf bytes.Buffer
+		buf.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，兼容 Excel 打开
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{
+			"invoice_no", "party_type", "admin_id", "period_type", "period", "currency", "status",
+			"order_count", "adjustment_count", "escrow_amount", "cost_amount", "share_amount", "total_amount",
+			"line_type", "occurred_at", "shop_id", "order_sn", "settlement_no", "transaction_type",
+			"line_escrow_amount", "line_cost_amount", "share_rate", "line_share_amount", "line_amount",
+		})
+		head := []string{
+			invoice.InvoiceNo, invoice.PartyType, strconv.FormatInt(invoice.AdminID, 10), invoice.PeriodType, invoice.Period,
+			invoice.Currency, invoiceStatusNames[invoice.Status],
+			strconv.FormatInt(invoice.OrderCount, 10), strconv.FormatInt(invoice.AdjustmentCount, 10),
+			invoice.EscrowAmount.StringFixed(2), invoice.CostAmount.StringFixed(2),
+			invoice.ShareAmount.StringFixed(2), invoice.TotalAmount.StringFixed(2),
+		}
+		if len(invoice.Lines) == 0 {
+			_ = w.Write(append(head, "", "", "", "", "", "", "", "", "", "", ""))
+		}
+		for _, line := range invoice.Lines {
+			row := append(append([]string{}, head...),
+				line.LineType, line.OccurredAt.Format("2006-01-02 15:04:05"), strconv.FormatUint(line.ShopID, 10),
+				line.OrderSN, line.SettlementNo, line.TransactionType,
+				line.EscrowAmount.StringFixed(2), line.CostAmount.StringFixed(2), line.ShareRate.StringFixed(2),
+				line.ShareAmount.StringFixed(2), line.Amount.StringFixed(2))
+			_ = w.Write(row)
+		}
+		w.Flush()
+		return buf.Bytes(), "text/csv; charset=utf-8", w.Error()
+	default:
+		return nil, "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"balance/backend/internal/models"
+)
+
+func TestParseInvoicePeriod(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return d
+	}
+	tests := []struct {
+		periodType, period string
+		start, end         string
+	}{
+		{models.InvoicePeriodMonthly, "2026-09", "2026-09-01", "2026-10-01"},
+		{models.InvoicePeriodWeekly, "2026-W01", "2025-12-29", "2026-01-05"},
+		{models.InvoicePeriodWeekly, "2026-W38", "2026-09-14", "2026-09-21"},
+		{models.InvoicePeriodWeekly, "2020-W53", "2020-12-28", "2021-01-04"},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseInvoicePeriod(tt.periodType, tt.period)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.period, err)
+		}
+		if !start.Equal(day(tt.start)) || !end.Equal(day(tt.end)) {
+			t.Errorf("%s = [%s, %s), want [%s, %s)", tt.period, start.Format("2006-01-02"), end.Format("2006-01-02"), tt.start, tt.end)
+		}
+	}
+
+	for _, period := range []string{"2026-W54", "2026-W0", "2026-38", "2025-W53"} {
+		if _, _, err := ParseInvoicePeriod(models.InvoicePeriodWeekly, period); err == nil {
+			t.Errorf("invalid weekly period %s accepted", period)
+		}
+	}
+
+	if got := PreviousInvoicePeriod(models.InvoicePeriodWeekly, day("2026-01-02")); got != "2025-W52" {
+		t.Errorf("previous week = %s, want 2025-W52", got)
+	}
+}
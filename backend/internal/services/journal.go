@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// JournalBuilder 记账凭证构建器
+// 一次业务事件（订单扣款、结算、调账、提现）的所有账户变动挂在同一张凭证下，
+// 入库前校验借贷平衡，不平衡则整个事务回滚
+type JournalBuilder struct {
+	journal  *models.AccountJournal
+	currency string // Debit/Credit 分录的币种，AddTransaction 按流水自身币种
+	replayed bool   // 含幂等重放的流水：凭证已在首次入账时生成
+}
+
+// NewJournal 创建记账凭证
+func NewJournal(bizType, orderSN, bizNo, remark string) *JournalBuilder {
+	return &JournalBuilder{
+		journal: &models.AccountJournal{
+			BizType: bizType,
+			OrderSN: orderSN,
+			BizNo:   bizNo,
+			Remark:  remark,
+		},
+		currency: models.DefaultCurrency,
+	}
+}
+
+// SetCurrency 设置后续 Debit/Credit 分录的币种
+func (b *JournalBuilder) SetCurrency(currency string) *JournalBuilder {
+	b.currency = NormalizeCurrency(currency)
+	return b
+}
+
+// SetOperator 设置操作人
+func (b *JournalBuilder) SetOperator(operatorID int64) *JournalBuilder {
+	b.journal.OperatorID = operatorID
+	return b
+}
+
+// Debit 添加借方分录（账户余额减少）
+func (b *JournalBuilder) Debit(accountType string, adminID int64, bucket string, amount decimal.Decimal, transactionNo string, remark string) *JournalBuilder {
+	return b.add(models.JournalDebit, accountType, adminID, b.currency, bucket, amount, transactionNo, remark)
+}
+
+// Credit 添加贷方分录（账户余额增加）
+func (b *JournalBuilder) Credit(accountType string, adminID int64, bucket string, amount decimal.Decimal, transactionNo string, remark string) *JournalBuilder {
+	return b.add(models.JournalCredit, accountType, adminID, b.currency, bucket, amount, transactionNo, remark)
+}
+
+func (b *JournalBuilder) add(direction, accountType string, adminID int64, currency string, bucket string, amount decimal.Decimal, transactionNo string, remark string) *JournalBuilder {
+	if amount.IsZero() {
+		return b
+	}
+	b.journal.Postings = append(b.journal.Postings, models.AccountJournalPosting{
+		AccountType:   accountType,
+		AdminID:       adminID,
+		Currency:      currency,
+		Bucket:        bucket,
+		Direction:     direction,
+		Amount:        amount,
+		TransactionNo: transactionNo,
+		Remark:        remark,
+	})
+	return b
+}
+
+// AddTransaction 按账户流水生成分录
+//   - freeze: 可用余额 → 待结算（同账户内借可用、贷待结算）
+//   - order_refund: 待结算 → 可用余额
+//   - order_pay / withdraw: 从待结算中扣除
+//   - 其他类型: 按金额正负记可用余额的贷/借
+func (b *JournalBuilder) AddTransaction(at *models.AccountTransaction) *JournalBuilder {
+	if at == nil {
+		return b
+	}
+	if at.Replayed {
+		b.replayed = true
+		return b
+	}
+	amount := at.Amount.Abs()
+	currency := NormalizeCurrency(at.Currency)
+	entry := func(direction, bucket string) {
+		b.add(direction, at.AccountType, at.AdminID, currency, bucket, amount, at.TransactionNo, at.Remark)
+	}
+	switch at.TransactionType {
+	case models.TxTypeFreeze:
+		entry(models.JournalDebit, models.JournalBucketAvailable)
+		entry(models.JournalCredit, models.JournalBucketPending)
+	case models.TxTypeOrderRefund:
+		entry(models.JournalDebit, models.JournalBucketPending)
+		entry(models.JournalCredit, models.JournalBucketAvailable)
+	case models.TxTypeOrderPay, models.TxTypeWithdraw:
+		entry(models.JournalDebit, models.JournalBucketPending)
+	default:
+		if at.Amount.GreaterThan(decimal.Zero) {
+			entry(models.JournalCredit, models.JournalBucketAvailable)
+		} else {
+			entry(models.JournalDebit, models.JournalBucketAvailable)
+		}
+	}
+	return b
+}
+
+// Validate 校验凭证：至少一条分录、金额为正、每个币种借贷合计相等
+func (b *JournalBuilder) Validate() error {
+	if len(b.journal.Postings) == 0 {
+		return fmt.Errorf("记账凭证没有分录")
+	}
+	debit, credit := make(map[string]decimal.Decimal), make(map[string]decimal.Decimal)
+	var currencies []string
+	total := decimal.Zero
+	for _, p := range b.journal.Postings {
+		if p.Amount.LessThanOrEqual(decimal.Zero) {
+			return fmt.Errorf("分录金额必须大于0: %s/%d %s", p.AccountType, p.AdminID, p.Amount.String())
+		}
+		if _, ok := debit[p.Currency]; !ok {
+			currencies = append(currencies, p.Currency)
+			debit[p.Currency], credit[p.Currency] = decimal.Zero, decimal.Zero
+		}
+		switch p.Direction {
+		case models.JournalDebit:
+			debit[p.Currency] = debit[p.Currency].Add(p.Amount)
+			total = total.Add(p.Amount)
+		case models.JournalCredit:
+			credit[p.Currency] = credit[p.Currency].Add(p.Amount)
+		default:
+			return fmt.Errorf("无效的借贷方向: %s", p.Direction)
+		}
+	}
+	for _, c := range currencies {
+		if !debit[c].Equal(credit[c]) {
+			return fmt.Errorf("记账凭证借贷不平衡(%s): 借方%s, 贷方%s", c, debit[c].String(), credit[c].String())
+		}
+	}
+	b.journal.TotalAmount = total
+	return nil
+}
+
+// PostJournalInTx 记账（事务参与版本：与账户变动在同一事务内写入凭证和分录，借贷不平衡时返回错误使事务回滚）
+// 业务事件为幂等重放时不重复生成凭证，返回 nil
+func (s *AccountService) PostJournalInTx(db *gorm.DB, ctx context.Context, b *JournalBuilder) (*models.AccountJournal, error) {
+	if b.replayed {
+		return nil, nil
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	journal := b.journal
+	id, err := s.idGen.GenerateAccountJournalID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("生成凭证ID失败: %w", err)
+	}
+	journal.ID = uint64(id)
+	journal.JournalNo = s.GenerateApplicationNo("JN")
+
+	if err := db.Create(journal).Error; err != nil {
+		return nil, fmt.Errorf("创建记账凭证失败: %w", err)
+	}
+	for i := range journal.Postings {
+		pid, err := s.idGen.GenerateJournalPostingID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("生成分录ID失败: %w", err)
+		}
+		journal.Postings[i].ID = uint64(pid)
+		journal.Postings[i].JournalNo = journal.JournalNo
+	}
+	if err := db.Create(&journal.Postings).Error; err != nil {
+		return nil, fmt.Errorf("创建凭证分录失败: %w", err)
+	}
+	return journal, nil
+}
+
+// GetJournals 获取记账凭证列表（按业务类型/订单号过滤）
+func (s *AccountService) GetJournals(ctx context.Context, bizType, orderSN string, page, pageSize int) ([]models.AccountJournal, int64, error) {
+	var journals []models.AccountJournal
+	var total int64
+
+	query := s.db.Model(&models.AccountJournal{})
+	if bizType != "" {
+		query = query.Where("biz_type = ?", bizType)
+	}
+	if orderSN != "" {
+		query = query.Where("order_sn = ?", orderSN)
+	}
+
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&journals).Error
+	return journals, total, err
+}
+
+// GetJournalDetail 获取记账凭证及其分录
+func (s *AccountService) GetJournalDetail(ctx context.Context, journalNo string) (*models.AccountJournal, error) {
+	var journal models.AccountJournal
+	if err := s.db.Where("journal_no = ?", journalNo).First(&journal).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("记账凭证不存在")
+		}
+		return nil, err
+	}
+	if err := s.db.Where("journal_no = ?", journalNo).Order("id ASC").Find(&journal.Postings).Error; err != nil {
+		return nil, err
+	}
+	return &journal, nil
+}
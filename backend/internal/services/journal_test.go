@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestJournalBuilder_Validate(t *testing.T) {
+	d := decimal.RequireFromString
+	tests := []struct {
+		name    string
+		build   func() *JournalBuilder
+		wantErr bool
+	}{
+		{
+			name: "empty journal",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizSettlement, "SN1", "", "")
+			},
+			wantErr: true,
+		},
+		{
+			name: "freeze balances within account",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizOrderFreeze, "SN1", "", "").AddTransaction(&models.AccountTransaction{
+					AccountType: models.AccountTypePrepayment, AdminID: 1, TransactionType: models.TxTypeFreeze, Amount: d("-100"),
+				})
+			},
+		},
+		{
+			name: "settlement with escrow clearing",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizSettlement, "SN1", "", "").
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypePrepayment, AdminID: 1, TransactionType: models.TxTypeOrderPay, Amount: d("-80")}).
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypeOperator, AdminID: 2, TransactionType: models.TxTypeCostSettle, Amount: d("89")}).
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypeShopOwnerCommission, AdminID: 1, TransactionType: models.TxTypeProfitShare, Amount: d("10")}).
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypePlatformCommission, TransactionType: models.TxTypePlatformFee, Amount: d("1")}).
+					Debit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, d("20"), "", "")
+			},
+		},
+		{
+			name: "unbalanced settlement",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizSettlement, "SN1", "", "").
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypePrepayment, AdminID: 1, TransactionType: models.TxTypeOrderPay, Amount: d("-80")}).
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypeOperator, AdminID: 2, TransactionType: models.TxTypeCostSettle, Amount: d("89")})
+			},
+			wantErr: true,
+		},
+		{
+			name: "balanced per currency",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizSettlement, "SN1", "", "").
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypeOperator, AdminID: 2, Currency: "MYR", TransactionType: models.TxTypeCostSettle, Amount: d("30")}).
+					SetCurrency("MYR").
+					Debit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, d("30"), "", "")
+			},
+		},
+		{
+			name: "balanced only across currencies",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizSettlement, "SN1", "", "").
+					AddTransaction(&models.AccountTransaction{AccountType: models.AccountTypeOperator, AdminID: 2, Currency: "MYR", TransactionType: models.TxTypeCostSettle, Amount: d("30")}).
+					Debit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, d("30"), "", "")
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative posting amount",
+			build: func() *JournalBuilder {
+				return NewJournal(models.JournalBizAdjustment, "SN1", "", "").
+					Debit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, d("-5"), "", "").
+					Credit(models.AccountTypeOperator, 2, models.JournalBucketAvailable, d("-5"), "", "")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build().Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
 	"balance/backend/internal/database"
+	"balance/backend/internal/models"
 	"balance/backend/internal/utils"
 
 	"github.com/go-redsync/redsync/v4"
@@ -23,6 +25,12 @@ type MaintenanceScheduler struct {
 	archiveService    *ArchiveService
 	statsService      *StatsService
 	settlementService *SettlementService
+	reconcileService  *ReconciliationService
+	orderReconService *OrderReconciliationService
+	invoiceService    *InvoiceService
+	webhookService    *WebhookSubscriptionService
+	statementService  *StatementService
+	accountService    *AccountService
 	rs                *redsync.Redsync
 	wg                sync.WaitGroup
 	stopChan          chan struct{}
@@ -42,6 +50,12 @@ func NewMaintenanceScheduler(logger ...*zap.SugaredLogger) *MaintenanceScheduler
 		archiveService:    NewArchiveService(),
 		statsService:      NewStatsService(),
 		settlementService: NewSettlementService(),
+		reconcileService:  NewReconciliationService(),
+		orderReconService: NewOrderReconciliationService(),
+		invoiceService:    NewInvoiceService(),
+		webhookService:    NewWebhookSubscriptionService(),
+		statementService:  NewStatementService(),
+		accountService:    NewAccountService(),
 		rs:                database.GetRedsync(),
 		stopChan:          make(chan struct{}),
 		logger:            l,
@@ -105,6 +119,18 @@ func (s *MaintenanceScheduler) Start() {
 			} else {
 				s.logger.Infof("[Maintenance] 清理过期归档完成，删除 %d 条记录", count)
 			}
+			runCount, err := s.settlementService.CleanupSettlementRuns(ctx, 90)
+			if err != nil {
+				s.logger.Infof("[Maintenance] 清理过期结算批次失败: %v", err)
+			} else {
+				s.logger.Infof("[Maintenance] 清理过期结算批次完成，删除 %d 个批次", runCount)
+			}
+			eventCount, err := s.webhookService.CleanupDeliveredEvents(ctx, 90)
+			if err != nil {
+				s.logger.Infof("[Maintenance] 清理过期事件失败: %v", err)
+			} else {
+				s.logger.Infof("[Maintenance] 清理过期事件完成，删除 %d 条记录", eventCount)
+			}
 		})
 	})
 	if err != nil {
@@ -113,36 +139,237 @@ func (s *MaintenanceScheduler) Start() {
 
 	// 每10分钟处理一次虾皮结算（分布式锁）
 	_, err = s.cron.AddFunc("0 */10 * * * *", func() {
-		s.tryRunWithLock("maintenance:settlement", func() {
+		// 与手动批次共用执行锁，执行期间自动续期
+		unlock, err := s.settlementService.lockSettlementRun(models.SettlementRunTypeSettlement)
+		if err != nil {
+			s.logger.Infof("[Maintenance] %s: 其他节点正在执行，跳过", settlementRunLockKey(models.SettlementRunTypeSettlement))
+			return
+		}
+		defer unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), settlementRunTimeout)
+		defer cancel()
+		run, err := s.settlementService.StartSettlementRun(ctx, models.SettlementRunTypeSettlement, models.SettlementRunTriggerCron, 0)
+		if err != nil {
+			s.logger.Infof("[Maintenance] 处理虾皮结算失败: %v", err)
+		} else if run.SuccessCount > 0 || run.FailedCount > 0 {
+			s.logger.Infof("[Maintenance] 处理虾皮结算完成，批次 %s 结算 %d 笔，失败 %d 笔", run.RunNo, run.SuccessCount, run.FailedCount)
+		}
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加虾皮结算任务失败: %v", err)
+	}
+
+	// 每10分钟处理一次虾皮调账（分布式锁）
+	_, err = s.cron.AddFunc("0 */10 * * * *", func() {
+		// 与手动批次共用执行锁，执行期间自动续期
+		unlock, err := s.settlementService.lockSettlementRun(models.SettlementRunTypeAdjustment)
+		if err != nil {
+			s.logger.Infof("[Maintenance] %s: 其他节点正在执行，跳过", settlementRunLockKey(models.SettlementRunTypeAdjustment))
+			return
+		}
+		defer unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), settlementRunTimeout)
+		defer cancel()
+		run, err := s.settlementService.StartSettlementRun(ctx, models.SettlementRunTypeAdjustment, models.SettlementRunTriggerCron, 0)
+		if err != nil {
+			s.logger.Infof("[Maintenance] 处理虾皮调账失败: %v", err)
+		} else if run.SuccessCount > 0 || run.FailedCount > 0 {
+			s.logger.Infof("[Maintenance] 处理虾皮调账完成，批次 %s 处理 %d 笔，失败 %d 笔", run.RunNo, run.SuccessCount, run.FailedCount)
+		}
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加虾皮调账任务失败: %v", err)
+	}
+
+	// 每10分钟释放一次到期的风控暂扣（分布式锁）
+	_, err = s.cron.AddFunc("0 */10 * * * *", func() {
+		s.tryRunWithLock("maintenance:hold_expire", func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 			defer cancel()
-			count, err := s.settlementService.ProcessShopeeSettlement(ctx)
+			count, err := s.accountService.ExpireHolds(ctx)
 			if err != nil {
-				s.logger.Infof("[Maintenance] 处理虾皮结算失败: %v", err)
+				s.logger.Infof("[Maintenance] 释放到期风控暂扣失败: %v", err)
 			} else if count > 0 {
-				s.logger.Infof("[Maintenance] 处理虾皮结算完成，结算 %d 笔订单", count)
+				s.logger.Infof("[Maintenance] 释放到期风控暂扣完成，释放 %d 笔", count)
 			}
 		})
 	})
 	if err != nil {
-		s.logger.Infof("[Maintenance] 添加虾皮结算任务失败: %v", err)
+		s.logger.Infof("[Maintenance] 添加风控暂扣过期任务失败: %v", err)
 	}
 
-	// 每10分钟处理一次虾皮调账（分布式锁）
+	// 每10分钟结算一次已审核的罚补单（罚款在账户余额足够时扣除，分布式锁）
 	_, err = s.cron.AddFunc("0 */10 * * * *", func() {
-		s.tryRunWithLock("maintenance:adjustment", func() {
+		s.tryRunWithLock("maintenance:penalty_settle", func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 			defer cancel()
-			count, err := s.settlementService.ProcessShopeeAdjustments(ctx)
+			count, err := s.accountService.SettleAllApprovedPenaltyBonus(ctx)
 			if err != nil {
-				s.logger.Infof("[Maintenance] 处理虾皮调账失败: %v", err)
+				s.logger.Infof("[Maintenance] 结算罚补单失败: %v", err)
 			} else if count > 0 {
-				s.logger.Infof("[Maintenance] 处理虾皮调账完成，处理 %d 笔调账", count)
+				s.logger.Infof("[Maintenance] 结算罚补单完成，结算 %d 笔", count)
 			}
 		})
 	})
 	if err != nil {
-		s.logger.Infof("[Maintenance] 添加虾皮调账任务失败: %v", err)
+		s.logger.Infof("[Maintenance] 添加罚补结算任务失败: %v", err)
+	}
+
+	// 每5分钟轮询待支付的线上充值：补漏支付回调、关闭超时未支付的申请（分布式锁）
+	_, err = s.cron.AddFunc("0 */5 * * * *", func() {
+		s.tryRunWithLock("maintenance:recharge_poll", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 4*time.Minute)
+			defer cancel()
+			result, err := s.accountService.PollPendingOnlineRecharges(ctx)
+			if err != nil {
+				s.logger.Infof("[Maintenance] 轮询线上充值失败: %v", err)
+				return
+			}
+			if result.Checked > 0 {
+				s.logger.Infof("[Maintenance] 轮询线上充值完成，查询 %d 笔，补单入账 %d 笔，关闭 %d 笔，过期 %d 笔，失败 %d 笔",
+					result.Checked, result.Approved, result.Closed, result.Expired, result.Failed)
+			}
+			for _, m := range result.Failures {
+				s.logger.Infof("[Maintenance] 线上充值 %s 轮询失败: %s", m.ApplicationNo, m.Reason)
+			}
+			for _, m := range result.Mismatches {
+				s.logger.Warnf("[Maintenance] 线上充值与网关不一致: %s %s payment_id=%s: %s", m.ApplicationNo, m.PaymentMethod, m.PaymentID, m.Reason)
+			}
+		})
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加线上充值轮询任务失败: %v", err)
+	}
+
+	// 每10分钟执行到期的自动提现：按设置创建提现申请或记录跳过原因，并通知用户（分布式锁）
+	_, err = s.cron.AddFunc("0 */10 * * * *", func() {
+		s.tryRunWithLock("maintenance:auto_withdraw", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 4*time.Minute)
+			defer cancel()
+			result, err := s.accountService.RunAutoWithdrawals(ctx)
+			if err != nil {
+				s.logger.Infof("[Maintenance] 自动提现失败: %v", err)
+				return
+			}
+			if result.Checked > 0 {
+				s.logger.Infof("[Maintenance] 自动提现完成，执行 %d 个设置，创建申请 %d 笔，跳过 %d 个，失败 %d 个",
+					result.Checked, result.Created, result.Skipped, result.Failed)
+			}
+		})
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加自动提现任务失败: %v", err)
+	}
+
+	// 每小时扫描超时发货订单，生成待审核的罚款提案（分布式锁）
+	_, err = s.cron.AddFunc("0 30 * * * *", func() {
+		s.tryRunWithLock("maintenance:late_ship", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			count, err := s.accountService.GenerateLateShipPenalties(ctx)
+			if err != nil {
+				s.logger.Infof("[Maintenance] 生成超时发货罚款提案失败: %v", err)
+			} else if count > 0 {
+				s.logger.Infof("[Maintenance] 生成超时发货罚款提案 %d 笔", count)
+			}
+		})
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加超时发货扫描任务失败: %v", err)
+	}
+
+	// 每天凌晨5点执行账户对账：按流水重算余额并记录差异（分布式锁）
+	_, err = s.cron.AddFunc("0 0 5 * * *", func() {
+		s.tryRunWithLock("maintenance:reconciliation", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			defer cancel()
+			run, err := s.reconcileService.ReconcileAccounts(ctx)
+			if err != nil {
+				s.logger.Infof("[Maintenance] 账户对账失败: %v", err)
+			} else {
+				s.logger.Infof("[Maintenance] 账户对账完成，核对 %d 个账户，差异 %d 个", run.CheckedAccounts, run.DriftAccounts)
+			}
+		})
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加账户对账任务失败: %v", err)
+	}
+
+	// 每天凌晨5点15分核对前一天处理过的线上充值与网关结果（分布式锁）
+	_, err = s.cron.AddFunc("0 15 5 * * *", func() {
+		s.tryRunWithLock("maintenance:recharge_audit", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			defer cancel()
+			mismatches, err := s.accountService.AuditOnlineRecharges(ctx, time.Now().Add(-24*time.Hour))
+			if err != nil {
+				s.logger.Infof("[Maintenance] 线上充值核对失败: %v", err)
+				return
+			}
+			s.logger.Infof("[Maintenance] 线上充值核对完成，不一致 %d 笔", len(mismatches))
+			for _, m := range mismatches {
+				s.logger.Warnf("[Maintenance] 线上充值与网关不一致: %s %s payment_id=%s: %s", m.ApplicationNo, m.PaymentMethod, m.PaymentID, m.Reason)
+			}
+		})
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加线上充值核对任务失败: %v", err)
+	}
+
+	// 每天凌晨5点30分执行订单三方对账：虾皮结算明细 vs 钱包入账 vs 平台结算单（最近30天，与手动对账共用执行锁）
+	_, err = s.cron.AddFunc("0 30 5 * * *", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		run, err := s.orderReconService.ReconcileOrders(ctx, 30, 0)
+		if errors.Is(err, ErrOrderReconciliationBusy) {
+			s.logger.Infof("[Maintenance] %s: 其他节点正在执行，跳过", orderReconcileLockKey)
+		} else if err != nil {
+			s.logger.Infof("[Maintenance] 订单对账失败: %v", err)
+		} else {
+			s.logger.Infof("[Maintenance] 订单对账完成，核对 %d 个订单，差异 %d 个", run.CheckedOrders, run.MismatchOrders)
+		}
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加订单对账任务失败: %v", err)
+	}
+
+	// 每月1号凌晨6点生成上月月结账单（在当日对账之后，分布式锁）
+	_, err = s.cron.AddFunc("0 0 6 1 * *", func() {
+		s.tryRunWithLock("maintenance:statement", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			defer cancel()
+			period, err := s.statementService.GenerateMonthlyStatements(ctx, PreviousStatementPeriod(time.Now()))
+			if err != nil {
+				s.logger.Infof("[Maintenance] 生成月结账单失败: %v", err)
+			} else {
+				s.logger.Infof("[Maintenance] 生成 %s 月结账单完成，共 %d 张", period.Period, period.StatementCount)
+			}
+		})
+	})
+	if err != nil {
+		s.logger.Infof("[Maintenance] 添加月结账单任务失败: %v", err)
+	}
+
+	// 每周一凌晨6点30分开具上周发票，每月1号凌晨6点30分开具上月发票（分布式锁）
+	for spec, periodType := range map[string]string{
+		"0 30 6 * * 1": models.InvoicePeriodWeekly,
+		"0 30 6 1 * *": models.InvoicePeriodMonthly,
+	} {
+		periodType := periodType
+		_, err = s.cron.AddFunc(spec, func() {
+			s.tryRunWithLock("maintenance:invoice:"+periodType, func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+				defer cancel()
+				result, err := s.invoiceService.GenerateInvoices(ctx, periodType, PreviousInvoicePeriod(periodType, time.Now()), 0)
+				if err != nil {
+					s.logger.Infof("[Maintenance] 开具%s发票失败: %v", periodType, err)
+				} else {
+					s.logger.Infof("[Maintenance] 开具 %s 发票完成，新开 %d 张，跳过 %d 张", result.Period, len(result.Issued), result.Skipped)
+				}
+			})
+		})
+		if err != nil {
+			s.logger.Infof("[Maintenance] 添加%s发票任务失败: %v", periodType, err)
+		}
 	}
 
 	s.cron.Start()
@@ -94,7 +94,7 @@ func (m *MetricsCollector) collectShardTableMetrics() {
 	tables := []string{
 		"orders", "order_items", "order_addresses",
 		"order_escrows", "order_escrow_items",
-		"order_settlements", "order_shipment_records",
+		"order_settlements", "order_settlement_adjustments", "order_shipment_records",
 		"shipments", "finance_incomes", "operation_logs",
 		"account_transactions",
 	}
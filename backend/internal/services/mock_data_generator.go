@@ -79,7 +79,8 @@ func (g *MockDataGenerator) GenerateMockOrders(ctx context.Context, req *MockOrd
 
 	// 获取分润配置
 	var profitConfig models.ProfitShareConfig
-	g.db.Where("shop_id = ?", req.ShopID).First(&profitConfig)
+	g.db.Where("shop_id = ? AND status = ? AND effective_from <= ?", req.ShopID, models.ProfitShareConfigActive, time.Now()).
+		Order("effective_from DESC").First(&profitConfig)
 	if profitConfig.ID == 0 {
 		// 使用默认分润配置
 		profitConfig.PlatformShareRate = decimal.NewFromFloat(5.00)   // 5%
@@ -214,9 +214,12 @@ func (s *ShipmentService) getAccessToken(ctx context.Context, shopID uint64) (st
 	rdb := database.GetRedis()
 	cacheKey := fmt.Sprintf("shop:token:%d", shopID)
 
+	// 缓存中的令牌为密文，解密失败时回源数据库
 	token, err := rdb.Get(ctx, cacheKey).Result()
 	if err == nil && token != "" {
-		return token, nil
+		if token, err := utils.DecryptField(token); err == nil {
+			return token, nil
+		}
 	}
 
 	var auth models.ShopAuthorization
@@ -228,7 +231,9 @@ func (s *ShipmentService) getAccessToken(ctx context.Context, shopID uint64) (st
 		return "", fmt.Errorf("访问令牌已过期")
 	}
 
-	rdb.Set(ctx, cacheKey, auth.AccessToken, time.Until(auth.ExpiresAt))
+	if cached, err := utils.EncryptField(auth.AccessToken); err == nil {
+		rdb.Set(ctx, cacheKey, cached, time.Until(auth.ExpiresAt))
+	}
 	return auth.AccessToken, nil
 }
 
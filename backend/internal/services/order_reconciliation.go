@@ -0,0 +1,528 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/consts"
+	"balance/backend/internal/database"
+	"balance/backend/internal/middleware"
+	"balance/backend/internal/models"
+	"balance/backend/internal/utils"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// orderReconcileBatchSize 按订单号 IN 查询的批大小
+const orderReconcileBatchSize = 500
+
+// orderReconcileLockKey 订单对账执行锁（定时任务与手动发起共用）
+const orderReconcileLockKey = "maintenance:order_reconciliation"
+
+// ErrOrderReconciliationBusy 订单对账正在其他节点或请求中执行
+var ErrOrderReconciliationBusy = errors.New("订单对账正在执行，请稍后重试")
+
+// orderMismatchTypes 订单对账差异类型（用于指标与汇总）
+var orderMismatchTypes = []string{
+	models.OrderMismatchAmountDiff,
+	models.OrderMismatchMissingEscrow,
+	models.OrderMismatchMissingIncome,
+	models.OrderMismatchSettledWithoutIncome,
+}
+
+// OrderReconciliationService 订单三方对账服务
+// 逐单比对虾皮结算明细(get_escrow_detail)、钱包入账(ESCROW_VERIFIED_ADD)与平台结算单的订单币种结算金额
+type OrderReconciliationService struct {
+	db                *gorm.DB
+	settlementService *SettlementService
+}
+
+// NewOrderReconciliationService 创建订单对账服务
+func NewOrderReconciliationService() *OrderReconciliationService {
+	return &OrderReconciliationService{
+		db:                database.GetDB(),
+		settlementService: NewSettlementService(),
+	}
+}
+
+// orderThreeWay 单个订单的三方金额（均为订单币种）
+type orderThreeWay struct {
+	ShopID  uint64
+	OrderSN string
+
+	Currency     string
+	HasEscrow    bool
+	EscrowAmount decimal.Decimal
+	HasIncome    bool
+	IncomeAmount decimal.Decimal
+
+	SettlementID  uint64
+	SettlementNo  string
+	SettledAmount decimal.Decimal // 结算单订单币种结算金额 + 对账发起的调账金额
+
+	Completed bool // 订单已完成（用于判断钱包是否应已入账）
+}
+
+// classify 判定差异类型，一致时返回空
+//   - 已结算但钱包未入账: settled_without_income
+//   - 有入账或结算但缺少虾皮结算明细: missing_escrow
+//   - 订单已完成、有结算明细但钱包未入账且未结算: missing_income
+//   - 结算明细/钱包入账/平台结算金额任意两者不一致: amount_diff
+func (o *orderThreeWay) classify() string {
+	hasSettlement := o.SettlementNo != ""
+	switch {
+	case hasSettlement && !o.HasIncome:
+		return models.OrderMismatchSettledWithoutIncome
+	case !o.HasEscrow && (o.HasIncome || hasSettlement):
+		return models.OrderMismatchMissingEscrow
+	case o.HasEscrow && !o.HasIncome:
+		if o.Completed {
+			return models.OrderMismatchMissingIncome
+		}
+		return ""
+	}
+	if o.HasEscrow && !o.EscrowAmount.Equal(o.IncomeAmount) {
+		return models.OrderMismatchAmountDiff
+	}
+	if hasSettlement && !o.SettledAmount.Equal(o.IncomeAmount) {
+		return models.OrderMismatchAmountDiff
+	}
+	return ""
+}
+
+// diff 钱包入账与平台结算金额之差（两者都存在时才有意义）
+func (o *orderThreeWay) diff() decimal.Decimal {
+	if o.SettlementNo == "" || !o.HasIncome {
+		return decimal.Zero
+	}
+	return o.IncomeAmount.Sub(o.SettledAmount)
+}
+
+func orderKey(shopID uint64, orderSN string) string {
+	return fmt.Sprintf("%d:%s", shopID, orderSN)
+}
+
+// ReconcileOrders 对最近 days 天内有结算明细、钱包入账或结算单的订单执行三方对账
+// 窗口内出现的订单会补齐窗口外的另两方数据，避免跨窗口的订单被误判缺失
+// 执行期间持有订单对账锁（自动续期），已有对账在执行时返回 ErrOrderReconciliationBusy
+func (s *OrderReconciliationService) ReconcileOrders(ctx context.Context, days int, createdBy int64) (*models.OrderReconciliationRun, error) {
+	mutex := database.GetRedsync().NewMutex(orderReconcileLockKey,
+		redsync.WithExpiry(maintenanceLockTTL),
+		redsync.WithTries(1),
+	)
+	unlock, acquired := utils.TryLockWithAutoExtend(ctx, mutex, maintenanceLockTTL/3)
+	if !acquired {
+		return nil, ErrOrderReconciliationBusy
+	}
+	defer unlock()
+
+	if days <= 0 {
+		days = 30
+	}
+	now := time.Now()
+	run := &models.OrderReconciliationRun{
+		RunNo:       fmt.Sprintf("OR%d%d", now.UnixNano(), time.Now().UnixMicro()%1000),
+		WindowStart: now.AddDate(0, 0, -days),
+		WindowEnd:   now,
+		Status:      models.ReconciliationRunning,
+		CreatedBy:   createdBy,
+		StartedAt:   now,
+	}
+	if err := s.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("创建对账批次失败: %w", err)
+	}
+
+	var mismatches []models.OrderReconciliationMismatch
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < database.ShardCount; i++ {
+			orders, err := s.loadShard(tx, uint64(i), run.WindowStart)
+			if err != nil {
+				return err
+			}
+			run.CheckedOrders += int64(len(orders))
+			for _, o := range orders {
+				if t := o.classify(); t != "" {
+					mismatches = append(mismatches, buildOrderMismatch(run.RunNo, t, o))
+				}
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if err == nil && len(mismatches) > 0 {
+		err = s.db.WithContext(ctx).CreateInBatches(mismatches, 100).Error
+	}
+	if err != nil {
+		run.Status = models.ReconciliationFailed
+		run.ErrorMessage = err.Error()
+		if runes := []rune(run.ErrorMessage); len(runes) > 500 {
+			run.ErrorMessage = string(runes[:500])
+		}
+		s.db.Save(run)
+		return run, err
+	}
+
+	run.Status = models.ReconciliationFinished
+	run.MismatchOrders = int64(len(mismatches))
+	if err := s.db.WithContext(ctx).Save(run).Error; err != nil {
+		return run, err
+	}
+
+	byType := make(map[string]int)
+	for _, m := range mismatches {
+		byType[m.MismatchType]++
+	}
+	for _, t := range orderMismatchTypes {
+		middleware.OrderReconciliationMismatches.WithLabelValues(t).Set(float64(byType[t]))
+	}
+
+	return run, nil
+}
+
+// loadShard 收集分表内窗口期的候选订单并补齐三方数据
+func (s *OrderReconciliationService) loadShard(tx *gorm.DB, shard uint64, since time.Time) ([]*orderThreeWay, error) {
+	type orderRef struct {
+		ShopID  uint64
+		OrderSN string
+	}
+	var keys, escrowKeys, incomeKeys, settlementKeys []orderRef
+	if err := tx.Table(database.GetOrderEscrowTableName(shard)).Select("shop_id, order_sn").
+		Where("updated_at >= ?", since).Find(&escrowKeys).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Table(database.GetFinanceIncomeTableName(shard)).Select("DISTINCT shop_id, order_sn").
+		Where("transaction_type = ? AND order_sn != '' AND transaction_time >= ?", models.TransactionTypeEscrowVerifiedAdd, since.Unix()).
+		Find(&incomeKeys).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Table(database.GetOrderSettlementTableName(shard)).Select("shop_id, order_sn").
+		Where("status = ? AND settled_at >= ?", models.OrderSettlementCompleted, since).
+		Find(&settlementKeys).Error; err != nil {
+		return nil, err
+	}
+	keys = append(append(append(keys, escrowKeys...), incomeKeys...), settlementKeys...)
+
+	orders := make(map[string]*orderThreeWay)
+	var list []*orderThreeWay
+	var orderSNs []string
+	for _, k := range keys {
+		key := orderKey(k.ShopID, k.OrderSN)
+		if _, ok := orders[key]; ok {
+			continue
+		}
+		o := &orderThreeWay{ShopID: k.ShopID, OrderSN: k.OrderSN}
+		orders[key] = o
+		list = append(list, o)
+		orderSNs = append(orderSNs, k.OrderSN)
+	}
+
+	for start := 0; start < len(orderSNs); start += orderReconcileBatchSize {
+		end := start + orderReconcileBatchSize
+		if end > len(orderSNs) {
+			end = len(orderSNs)
+		}
+		if err := s.fillThreeWay(tx, shard, orderSNs[start:end], orders); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// fillThreeWay 按订单号批量填充结算明细、钱包入账、结算单（含对账调账）与订单状态
+func (s *OrderReconciliationService) fillThreeWay(tx *gorm.DB, shard uint64, orderSNs []string, orders map[string]*orderThreeWay) error {
+	var escrows []models.OrderEscrow
+	if err := tx.Table(database.GetOrderEscrowTableName(shard)).
+		Select("shop_id, order_sn, currency, escrow_amount").
+		Where("order_sn IN ?", orderSNs).Find(&escrows).Error; err != nil {
+		return err
+	}
+	for _, e := range escrows {
+		if o, ok := orders[orderKey(e.ShopID, e.OrderSN)]; ok {
+			o.HasEscrow = true
+			o.EscrowAmount = e.EscrowAmount
+			o.Currency = e.Currency
+		}
+	}
+
+	var incomes []struct {
+		ShopID  uint64
+		OrderSN string
+		Amount  decimal.Decimal
+	}
+	if err := tx.Table(database.GetFinanceIncomeTableName(shard)).
+		Select("shop_id, order_sn, SUM(amount) AS amount").
+		Where("transaction_type = ? AND order_sn IN ?", models.TransactionTypeEscrowVerifiedAdd, orderSNs).
+		Group("shop_id, order_sn").Find(&incomes).Error; err != nil {
+		return err
+	}
+	for _, in := range incomes {
+		if o, ok := orders[orderKey(in.ShopID, in.OrderSN)]; ok {
+			o.HasIncome = true
+			o.IncomeAmount = in.Amount
+		}
+	}
+
+	var settlements []models.OrderSettlement
+	if err := tx.Table(database.GetOrderSettlementTableName(shard)).
+		Select("id, shop_id, order_sn, settlement_no, order_currency, order_escrow_amount").
+		Where("status = ? AND order_sn IN ?", models.OrderSettlementCompleted, orderSNs).
+		Find(&settlements).Error; err != nil {
+		return err
+	}
+	settlementIDs := make([]uint64, 0, len(settlements))
+	bySettlementID := make(map[uint64]*orderThreeWay)
+	for _, st := range settlements {
+		o, ok := orders[orderKey(st.ShopID, st.OrderSN)]
+		if !ok {
+			continue
+		}
+		o.SettlementID = st.ID
+		o.SettlementNo = st.SettlementNo
+		o.SettledAmount = st.OrderEscrowAmount
+		if o.Currency == "" {
+			o.Currency = st.OrderCurrency
+		}
+		settlementIDs = append(settlementIDs, st.ID)
+		bySettlementID[st.ID] = o
+	}
+	if len(settlementIDs) > 0 {
+		// 只累加对账发起的调账：虾皮调账对应独立的钱包流水，不计入 ESCROW_VERIFIED_ADD
+		var adjustments []struct {
+			SettlementID uint64
+			Amount       decimal.Decimal
+		}
+		if err := tx.Table(database.GetOrderSettlementAdjustmentTableName(shard)).
+			Select("settlement_id, SUM(order_amount) AS amount").
+			Where("settlement_id IN ? AND transaction_type = ?", settlementIDs, models.TransactionTypeReconciliationAdjust).
+			Group("settlement_id").Find(&adjustments).Error; err != nil {
+			return err
+		}
+		for _, adj := range adjustments {
+			if o, ok := bySettlementID[adj.SettlementID]; ok {
+				o.SettledAmount = o.SettledAmount.Add(adj.Amount)
+			}
+		}
+	}
+
+	var completed []models.Order
+	if err := tx.Table(database.GetOrderTableName(shard)).Select("shop_id, order_sn").
+		Where("order_sn IN ? AND order_status = ?", orderSNs, consts.OrderStatusCompleted).
+		Find(&completed).Error; err != nil {
+		return err
+	}
+	for _, od := range completed {
+		if o, ok := orders[orderKey(od.ShopID, od.OrderSN)]; ok {
+			o.Completed = true
+		}
+	}
+	return nil
+}
+
+// loadOrderThreeWay 实时读取单个订单的三方数据（发起调账前复核）
+func (s *OrderReconciliationService) loadOrderThreeWay(tx *gorm.DB, shopID uint64, orderSN string) (*orderThreeWay, error) {
+	o := &orderThreeWay{ShopID: shopID, OrderSN: orderSN}
+	orders := map[string]*orderThreeWay{orderKey(shopID, orderSN): o}
+	if err := s.fillThreeWay(tx, shopID, []string{orderSN}, orders); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func buildOrderMismatch(runNo, mismatchType string, o *orderThreeWay) models.OrderReconciliationMismatch {
+	return models.OrderReconciliationMismatch{
+		RunNo:         runNo,
+		ShopID:        o.ShopID,
+		OrderSN:       o.OrderSN,
+		MismatchType:  mismatchType,
+		Currency:      o.Currency,
+		HasEscrow:     o.HasEscrow,
+		EscrowAmount:  o.EscrowAmount,
+		HasIncome:     o.HasIncome,
+		IncomeAmount:  o.IncomeAmount,
+		SettlementNo:  o.SettlementNo,
+		SettledAmount: o.SettledAmount,
+		DiffAmount:    o.diff(),
+		Status:        models.OrderMismatchOpen,
+	}
+}
+
+// RaiseMismatchAdjustment 对金额差异一键发起调账：按钱包入账与平台结算金额的实时差额追加一笔结算调账
+// 调账明细的交易ID取差异记录ID的负数，重复发起不会重复动账
+func (s *OrderReconciliationService) RaiseMismatchAdjustment(ctx context.Context, mismatchID uint64, adminID int64, remark string) (*models.OrderReconciliationMismatch, error) {
+	var mismatch models.OrderReconciliationMismatch
+	if err := s.db.First(&mismatch, mismatchID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("对账差异不存在")
+		}
+		return nil, err
+	}
+	if mismatch.Status != models.OrderMismatchOpen {
+		return nil, fmt.Errorf("对账差异已处理")
+	}
+	if mismatch.MismatchType != models.OrderMismatchAmountDiff || mismatch.SettlementNo == "" {
+		return nil, fmt.Errorf("仅已结算订单的金额差异可发起调账")
+	}
+
+	current, err := s.loadOrderThreeWay(s.db, mismatch.ShopID, mismatch.OrderSN)
+	if err != nil {
+		return nil, err
+	}
+	diff := current.diff()
+	if current.SettlementNo == "" || !current.HasIncome || diff.IsZero() {
+		return nil, fmt.Errorf("钱包入账与结算金额已一致，无需调账")
+	}
+
+	if remark == "" {
+		remark = fmt.Sprintf("对账调账: %s", mismatch.RunNo)
+	}
+	txID := -int64(mismatch.ID)
+	skipReason, err := s.settlementService.applySettlementAdjustment(ctx, settlementAdjustmentInput{
+		ShopID:          mismatch.ShopID,
+		OrderSN:         mismatch.OrderSN,
+		TransactionID:   txID,
+		TransactionType: models.TransactionTypeReconciliationAdjust,
+		OrderAmount:     diff,
+		Remark:          remark,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("发起调账失败: %w", err)
+	}
+	if skipReason != "" {
+		return nil, fmt.Errorf("发起调账失败: %s", skipReason)
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&models.OrderReconciliationMismatch{}).
+		Where("id = ? AND status = ?", mismatch.ID, models.OrderMismatchOpen).
+		Updates(map[string]interface{}{
+			"status":           models.OrderMismatchAdjusted,
+			"adjustment_tx_id": txID,
+			"handled_by":       adminID,
+			"handled_at":       now,
+			"remark":           remark,
+		}).Error; err != nil {
+		return nil, err
+	}
+	err = s.db.First(&mismatch, mismatch.ID).Error
+	return &mismatch, err
+}
+
+// IgnoreMismatch 忽略对账差异（需填写原因，如虾皮侧数据延迟）
+func (s *OrderReconciliationService) IgnoreMismatch(ctx context.Context, mismatchID uint64, adminID int64, remark string) (*models.OrderReconciliationMismatch, error) {
+	if remark == "" {
+		return nil, fmt.Errorf("请填写忽略原因")
+	}
+	now := time.Now()
+	result := s.db.Model(&models.OrderReconciliationMismatch{}).
+		Where("id = ? AND status = ?", mismatchID, models.OrderMismatchOpen).
+		Updates(map[string]interface{}{
+			"status":     models.OrderMismatchIgnored,
+			"handled_by": adminID,
+			"handled_at": now,
+			"remark":     remark,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	var mismatch models.OrderReconciliationMismatch
+	if err := s.db.First(&mismatch, mismatchID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("对账差异不存在")
+		}
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("对账差异已处理")
+	}
+	return &mismatch, nil
+}
+
+// GetOrderReconciliationRuns 获取订单对账批次列表
+func (s *OrderReconciliationService) GetOrderReconciliationRuns(ctx context.Context, page, pageSize int) ([]models.OrderReconciliationRun, int64, error) {
+	var runs []models.OrderReconciliationRun
+	var total int64
+	query := s.db.Model(&models.OrderReconciliationRun{})
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&runs).Error
+	return runs, total, err
+}
+
+// OrderMismatchQuery 订单对账差异查询条件（RunNo 为空时取最近一次已完成批次，Status 为 -1 表示全部）
+type OrderMismatchQuery struct {
+	RunNo        string
+	MismatchType string
+	ShopID       uint64
+	Status       int8
+	Page         int
+	PageSize     int
+}
+
+// OrderMismatchReport 订单对账差异报告
+type OrderMismatchReport struct {
+	Run     *models.OrderReconciliationRun       `json:"run"`
+	Summary map[string]int64                     `json:"summary"` // 按差异类型统计
+	List    []models.OrderReconciliationMismatch `json:"list"`
+	Total   int64                                `json:"total"`
+}
+
+// GetOrderMismatches 获取订单对账差异报告
+func (s *OrderReconciliationService) GetOrderMismatches(ctx context.Context, q OrderMismatchQuery) (*OrderMismatchReport, error) {
+	report := &OrderMismatchReport{
+		Summary: make(map[string]int64),
+		List:    []models.OrderReconciliationMismatch{},
+	}
+	var run models.OrderReconciliationRun
+	query := s.db.Model(&models.OrderReconciliationRun{})
+	if q.RunNo != "" {
+		query = query.Where("run_no = ?", q.RunNo)
+	} else {
+		query = query.Where("status = ?", models.ReconciliationFinished)
+	}
+	if err := query.Order("id DESC").First(&run).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return report, nil
+		}
+		return nil, err
+	}
+	report.Run = &run
+
+	var rows []struct {
+		MismatchType string
+		Count        int64
+	}
+	if err := s.db.Model(&models.OrderReconciliationMismatch{}).
+		Select("mismatch_type, COUNT(*) AS count").
+		Where("run_no = ?", run.RunNo).
+		Group("mismatch_type").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range orderMismatchTypes {
+		report.Summary[t] = 0
+	}
+	for _, r := range rows {
+		report.Summary[r.MismatchType] = r.Count
+	}
+
+	listQuery := s.db.Model(&models.OrderReconciliationMismatch{}).Where("run_no = ?", run.RunNo)
+	if q.MismatchType != "" {
+		listQuery = listQuery.Where("mismatch_type = ?", q.MismatchType)
+	}
+	if q.ShopID > 0 {
+		listQuery = listQuery.Where("shop_id = ?", q.ShopID)
+	}
+	if q.Status >= 0 {
+		listQuery = listQuery.Where("status = ?", q.Status)
+	}
+	listQuery.Count(&report.Total)
+	offset := (q.Page - 1) * q.PageSize
+	err := listQuery.Order("id ASC").Offset(offset).Limit(q.PageSize).Find(&report.List).Error
+	return report, err
+}
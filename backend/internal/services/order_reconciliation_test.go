@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderThreeWayClassify(t *testing.T) {
+	d := decimal.RequireFromString
+	cases := []struct {
+		name      string
+		escrow    string // 为空表示没有虾皮结算明细
+		income    string // 为空表示钱包未入账
+		settled   string // 为空表示平台未结算
+		completed bool
+		want      string
+		wantDiff  string
+	}{
+		{name: "all three match", escrow: "500", income: "500", settled: "500", completed: true, wantDiff: "0"},
+		{name: "escrow only before completion", escrow: "500", wantDiff: "0"},
+		{name: "escrow and income not yet settled", escrow: "500", income: "500", completed: true, wantDiff: "0"},
+		{name: "nothing yet", wantDiff: "0"},
+		{name: "settled without income", escrow: "500", settled: "500", completed: true, want: models.OrderMismatchSettledWithoutIncome, wantDiff: "0"},
+		{name: "settled without income or escrow", settled: "500", want: models.OrderMismatchSettledWithoutIncome, wantDiff: "0"},
+		{name: "income without escrow", income: "500", want: models.OrderMismatchMissingEscrow, wantDiff: "0"},
+		{name: "settled and income without escrow", income: "500", settled: "500", want: models.OrderMismatchMissingEscrow, wantDiff: "0"},
+		{name: "completed without income", escrow: "500", completed: true, want: models.OrderMismatchMissingIncome, wantDiff: "0"},
+		{name: "escrow differs from income", escrow: "500", income: "480", completed: true, want: models.OrderMismatchAmountDiff, wantDiff: "0"},
+		{name: "settled differs from income", escrow: "500", income: "500", settled: "450", completed: true, want: models.OrderMismatchAmountDiff, wantDiff: "50"},
+		{name: "settled over income", escrow: "480", income: "480", settled: "500", completed: true, want: models.OrderMismatchAmountDiff, wantDiff: "-20"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &orderThreeWay{ShopID: 1, OrderSN: "251001AAAA0001", Currency: "TWD", Completed: tc.completed}
+			if tc.escrow != "" {
+				o.HasEscrow, o.EscrowAmount = true, d(tc.escrow)
+			}
+			if tc.income != "" {
+				o.HasIncome, o.IncomeAmount = true, d(tc.income)
+			}
+			if tc.settled != "" {
+				o.SettlementID, o.SettlementNo, o.SettledAmount = 1, "ST1", d(tc.settled)
+			}
+			if got := o.classify(); got != tc.want {
+				t.Errorf("classify() = %q, want %q", got, tc.want)
+			}
+			if got := o.diff(); !got.Equal(d(tc.wantDiff)) {
+				t.Errorf("diff() = %s, want %s", got, tc.wantDiff)
+			}
+		})
+	}
+}
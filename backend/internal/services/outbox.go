@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxWebhookSubscriptionsPerAdmin 每个用户最多登记的回调地址数
+const maxWebhookSubscriptionsPerAdmin = 10
+
+// webhookEventTypes 可订阅的事件类型
+var webhookEventTypes = []string{
+	models.EventOrderSettled,
+	models.EventOrderRefunded,
+	models.EventWithdrawApproved,
+	models.EventWithdrawPaid,
+	models.EventPrepaymentInsufficient,
+}
+
+// OutboxEnvelope 投递给订阅方的事件报文
+type OutboxEnvelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// EnqueueOutboxEvent 在业务事务内写入一条领域事件（随业务事务一起提交或回滚）
+// adminIDs 为事件相关的店主/运营，其登记的订阅会收到该事件；平台订阅接收全部事件
+func EnqueueOutboxEvent(tx *gorm.DB, eventType, aggregateID string, adminIDs []int64, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化事件内容失败: %w", err)
+	}
+
+	now := time.Now()
+	event := &models.OutboxEvent{
+		EventID:     "evt_" + generateSalt(32),
+		EventType:   eventType,
+		AggregateID: aggregateID,
+		AdminIDs:    joinAdminIDs(adminIDs),
+		Status:      models.OutboxEventPending,
+		CreatedAt:   now,
+	}
+	payload, err := json.Marshal(OutboxEnvelope{
+		ID:        event.EventID,
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      raw,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化事件报文失败: %w", err)
+	}
+	event.Payload = string(payload)
+
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("写入事件发件箱失败: %w", err)
+	}
+	return nil
+}
+
+// SignWebhookPayload 计算事件报文签名：HMAC-SHA256(secret, "{timestamp}.{body}") 的十六进制
+// 订阅方按 X-Balance-Signature 头 "t={timestamp},v1={signature}" 验签
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// joinAdminIDs 去重后以逗号拼接（首尾带逗号，便于按 ",id," 匹配）
+func joinAdminIDs(adminIDs []int64) string {
+	seen := make(map[int64]bool, len(adminIDs))
+	parts := make([]string, 0, len(adminIDs))
+	for _, id := range adminIDs {
+		if id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "," + strings.Join(parts, ",") + ","
+}
+
+// subscriptionMatches 判断订阅是否应收到该事件
+func subscriptionMatches(sub *models.WebhookSubscription, event *models.OutboxEvent) bool {
+	if sub.UserType != models.UserTypePlatform &&
+		!strings.Contains(event.AdminIDs, ","+strconv.FormatInt(sub.AdminID, 10)+",") {
+		return false
+	}
+	if sub.EventTypes == "*" {
+		return true
+	}
+	for _, t := range strings.Split(sub.EventTypes, ",") {
+		if strings.TrimSpace(t) == event.EventType {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeEventTypes 校验并规范化订阅的事件类型列表，空表示全部
+func normalizeEventTypes(eventTypes []string) (string, error) {
+	if len(eventTypes) == 0 {
+		return "*", nil
+	}
+	seen := make(map[string]bool, len(eventTypes))
+	result := make([]string, 0, len(eventTypes))
+	for _, t := range eventTypes {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return "*", nil
+		}
+		valid := false
+		for _, known := range webhookEventTypes {
+			if t == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("不支持的事件类型: %s", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return strings.Join(result, ","), nil
+}
+
+// validateWebhookURL 校验回调地址（仅允许 http/https 绝对地址）
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("回调地址格式错误，需为 http(s) 绝对地址")
+	}
+	return nil
+}
+
+// WebhookSubscriptionService 事件订阅管理服务（订阅登记、投递记录查询与重投）
+type WebhookSubscriptionService struct {
+	db *gorm.DB
+}
+
+// NewWebhookSubscriptionService 创建事件订阅管理服务
+func NewWebhookSubscriptionService() *WebhookSubscriptionService {
+	return &WebhookSubscriptionService{
+		db: database.GetDB(),
+	}
+}
+
+// WebhookSubscriptionRequest 登记/修改事件订阅请求
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	EventTypes  []string `json:"event_types"` // 为空表示订阅全部事件
+	Status      *int8    `json:"status"`      // 仅修改时有效：1启用/0停用
+	Description string   `json:"description"`
+}
+
+// WebhookSubscriptionWithSecret 登记订阅或轮换密钥时返回的订阅（仅此时返回明文密钥）
+type WebhookSubscriptionWithSecret struct {
+	*models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// ListSubscriptions 获取用户登记的事件订阅
+func (s *WebhookSubscriptionService) ListSubscriptions(ctx context.Context, adminID int64) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := s.db.WithContext(ctx).Where("admin_id = ?", adminID).Order("id DESC").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("查询事件订阅失败: %w", err)
+	}
+	return subs, nil
+}
+
+// CreateSubscription 登记事件订阅，返回签名密钥
+func (s *WebhookSubscriptionService) CreateSubscription(ctx context.Context, adminID int64, userType int8, req *WebhookSubscriptionRequest) (*WebhookSubscriptionWithSecret, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	eventTypes, err := normalizeEventTypes(req.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.WebhookSubscription{}).Where("admin_id = ?", adminID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("查询事件订阅失败: %w", err)
+	}
+	if count >= maxWebhookSubscriptionsPerAdmin {
+		return nil, fmt.Errorf("最多登记%d个回调地址", maxWebhookSubscriptionsPerAdmin)
+	}
+
+	sub := &models.WebhookSubscription{
+		AdminID:     adminID,
+		UserType:    userType,
+		URL:         req.URL,
+		Secret:      "whsec_" + generateSalt(40),
+		EventTypes:  eventTypes,
+		Status:      models.WebhookSubscriptionActive,
+		Description: req.Description,
+	}
+	if err := s.db.WithContext(ctx).Create(sub).Error; err != nil {
+		return nil, fmt.Errorf("登记事件订阅失败: %w", err)
+	}
+	return &WebhookSubscriptionWithSecret{WebhookSubscription: sub, Secret: sub.Secret}, nil
+}
+
+// getOwnSubscription 获取用户自己的订阅
+func (s *WebhookSubscriptionService) getOwnSubscription(ctx context.Context, adminID int64, id uint64) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := s.db.WithContext(ctx).Where("id = ? AND admin_id = ?", id, adminID).First(&sub).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("事件订阅不存在")
+		}
+		return nil, fmt.Errorf("查询事件订阅失败: %w", err)
+	}
+	return &sub, nil
+}
+
+// UpdateSubscription 修改事件订阅（回调地址、事件类型、启停）
+func (s *WebhookSubscriptionService) UpdateSubscription(ctx context.Context, adminID int64, id uint64, req *WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	sub, err := s.getOwnSubscription(ctx, adminID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	eventTypes, err := normalizeEventTypes(req.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"url":         req.URL,
+		"event_types": eventTypes,
+		"description": req.Description,
+	}
+	if req.Status != nil {
+		if *req.Status != models.WebhookSubscriptionActive && *req.Status != models.WebhookSubscriptionDisabled {
+			return nil, fmt.Errorf("无效的订阅状态")
+		}
+		updates["status"] = *req.Status
+	}
+	if err := s.db.WithContext(ctx).Model(sub).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("修改事件订阅失败: %w", err)
+	}
+	return s.getOwnSubscription(ctx, adminID, id)
+}
+
+// DeleteSubscription 删除事件订阅（未送达的投递记录在投递时转入死信）
+func (s *WebhookSubscriptionService) DeleteSubscription(ctx context.Context, adminID int64, id uint64) error {
+	sub, err := s.getOwnSubscription(ctx, adminID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Delete(sub).Error; err != nil {
+		return fmt.Errorf("删除事件订阅失败: %w", err)
+	}
+	return nil
+}
+
+// RotateSecret 轮换签名密钥，旧密钥立即失效
+func (s *WebhookSubscriptionService) RotateSecret(ctx context.Context, adminID int64, id uint64) (*WebhookSubscriptionWithSecret, error) {
+	sub, err := s.getOwnSubscription(ctx, adminID, id)
+	if err != nil {
+		return nil, err
+	}
+	secret := "whsec_" + generateSalt(40)
+	if err := s.db.WithContext(ctx).Model(sub).Update("secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("轮换签名密钥失败: %w", err)
+	}
+	sub.Secret = secret
+	return &WebhookSubscriptionWithSecret{WebhookSubscription: sub, Secret: secret}, nil
+}
+
+// ListDeliveries 获取用户订阅的投递记录，status=-1 表示全部
+func (s *WebhookSubscriptionService) ListDeliveries(ctx context.Context, adminID int64, subscriptionID uint64, status int8, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("admin_id = ?", adminID)
+	if subscriptionID > 0 {
+		query = query.Where("subscription_id = ?", subscriptionID)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询投递记录失败: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询投递记录失败: %w", err)
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver 重新投递（死信或已送达的记录重置为待投递，由投递器下一轮发出）
+func (s *WebhookSubscriptionService) Redeliver(ctx context.Context, adminID int64, id uint64) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := s.db.WithContext(ctx).Where("id = ? AND admin_id = ?", id, adminID).First(&delivery).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("投递记录不存在")
+		}
+		return nil, fmt.Errorf("查询投递记录失败: %w", err)
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		return nil, fmt.Errorf("投递记录正在等待投递，无需重投")
+	}
+
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ?", delivery.ID, delivery.Status).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"last_error":      "",
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("重新投递失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("投递记录状态已变更，请刷新后重试")
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.LastError = ""
+	return &delivery, nil
+}
+
+// CleanupDeliveredEvents 清理 days 天前已送达的投递记录，以及已无未送达投递记录的已分发事件
+// 死信和待投递的记录保留，以便重投
+func (s *WebhookSubscriptionService) CleanupDeliveredEvents(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result := s.db.WithContext(ctx).Where("status = ? AND updated_at < ?", models.WebhookDeliveryDelivered, cutoff).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理投递记录失败: %w", result.Error)
+	}
+	deleted := result.RowsAffected
+
+	result = s.db.WithContext(ctx).
+		Where("status = ? AND created_at < ?", models.OutboxEventDispatched, cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = outbox_events.event_id AND d.status <> ?)", models.WebhookDeliveryDelivered).
+		Delete(&models.OutboxEvent{})
+	if result.Error != nil {
+		return deleted, fmt.Errorf("清理发件箱事件失败: %w", result.Error)
+	}
+	return deleted + result.RowsAffected, nil
+}
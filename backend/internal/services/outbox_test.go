@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"balance/backend/internal/models"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1700000000.{"id":"evt_1"}' | openssl dgst -sha256 -hmac whsec_test
+	got := SignWebhookPayload("whsec_test", 1700000000, []byte(`{"id":"evt_1"}`))
+	if want := "c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"; got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestEventRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{30, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := eventRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("eventRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	event := &models.OutboxEvent{EventType: models.EventOrderSettled, AdminIDs: joinAdminIDs([]int64{1001, 0, 2002, 1001})}
+	if event.AdminIDs != ",1001,2002," {
+		t.Fatalf("AdminIDs = %q", event.AdminIDs)
+	}
+
+	tests := []struct {
+		name string
+		sub  models.WebhookSubscription
+		want bool
+	}{
+		{"owner all events", models.WebhookSubscription{AdminID: 1001, UserType: models.UserTypeShopOwner, EventTypes: "*"}, true},
+		{"operator filtered", models.WebhookSubscription{AdminID: 2002, UserType: models.UserTypeOperator, EventTypes: "withdraw.paid,order.settled"}, true},
+		{"other event type", models.WebhookSubscription{AdminID: 2002, UserType: models.UserTypeOperator, EventTypes: "withdraw.paid"}, false},
+		{"id prefix not matched", models.WebhookSubscription{AdminID: 100, UserType: models.UserTypeShopOwner, EventTypes: "*"}, false},
+		{"platform receives all", models.WebhookSubscription{AdminID: 9, UserType: models.UserTypePlatform, EventTypes: "*"}, true},
+	}
+	for _, tt := range tests {
+		if got := subscriptionMatches(&tt.sub, event); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeEventTypes(t *testing.T) {
+	if got, _ := normalizeEventTypes(nil); got != "*" {
+		t.Errorf("empty = %q, want *", got)
+	}
+	got, err := normalizeEventTypes([]string{"order.settled", " withdraw.paid", "order.settled"})
+	if err != nil || got != "order.settled,withdraw.paid" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := normalizeEventTypes([]string{"order.created"}); err == nil {
+		t.Error("unknown event type accepted")
+	}
+}
@@ -0,0 +1,719 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PayoutLayoutGeneric 内置通用代付格式（未配置任何格式时使用）
+const PayoutLayoutGeneric = "generic"
+
+// 代付文件金额格式
+const (
+	PayoutAmountDecimal = "decimal" // 两位小数
+	PayoutAmountCents   = "cents"   // 最小货币单位整数（金额 × 100）
+)
+
+// genericPayoutLayout 内置通用代付格式：带标题行，回单按 reference/status/message/bank_ref 列匹配
+var genericPayoutLayout = config.PayoutLayoutConfig{
+	Header:       true,
+	AmountFormat: PayoutAmountDecimal,
+	Columns: []config.PayoutColumnConfig{
+		{Title: "reference", Field: "reference"},
+		{Title: "bank_name", Field: "bank_name"},
+		{Title: "bank_branch", Field: "bank_branch"},
+		{Title: "account_no", Field: "account_no"},
+		{Title: "payee", Field: "payee"},
+		{Title: "amount", Field: "amount"},
+		{Title: "currency", Field: "currency"},
+		{Title: "remark", Field: "remark"},
+	},
+	Result: config.PayoutResultConfig{
+		ReferenceColumn: "reference",
+		StatusColumn:    "status",
+		MessageColumn:   "message",
+		BankRefColumn:   "bank_ref",
+		SuccessValues:   []string{"SUCCESS"},
+		FailureValues:   []string{"FAILED"},
+	},
+}
+
+// payoutConfig 当前代付配置（未加载配置时为空配置）
+func payoutConfig() config.PayoutConfig {
+	if cfg := config.Get(); cfg != nil {
+		return cfg.Payout
+	}
+	return config.PayoutConfig{}
+}
+
+// payoutLayouts 可用的代付格式（内置 generic + 配置的格式，同名时以配置为准）
+func payoutLayouts() map[string]config.PayoutLayoutConfig {
+	layouts := map[string]config.PayoutLayoutConfig{PayoutLayoutGeneric: genericPayoutLayout}
+	for name, layout := range payoutConfig().Layouts {
+		layouts[name] = layout
+	}
+	return layouts
+}
+
+// payoutLayoutForBank 按收款银行名称取代付格式名
+func payoutLayoutForBank(bankName string) string {
+	cfg := payoutConfig()
+	if name, ok := cfg.Banks[strings.TrimSpace(bankName)]; ok {
+		return name
+	}
+	if cfg.DefaultLayout != "" {
+		return cfg.DefaultLayout
+	}
+	return PayoutLayoutGeneric
+}
+
+// PayoutLayoutInfo 代付格式及使用该格式的银行
+type PayoutLayoutInfo struct {
+	Name      string   `json:"name"`
+	Banks     []string `json:"banks"`
+	IsDefault bool     `json:"is_default"`
+}
+
+// ListPayoutLayouts 获取可用的代付格式
+func ListPayoutLayouts() []PayoutLayoutInfo {
+	cfg := payoutConfig()
+	defaultLayout := cfg.DefaultLayout
+	if defaultLayout == "" {
+		defaultLayout = PayoutLayoutGeneric
+	}
+
+	var result []PayoutLayoutInfo
+	for name := range payoutLayouts() {
+		info := PayoutLayoutInfo{Name: name, Banks: []string{}, IsDefault: name == defaultLayout}
+		for bank, layout := range cfg.Banks {
+			if layout == name {
+				info.Banks = append(info.Banks, bank)
+			}
+		}
+		sort.Strings(info.Banks)
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// PayoutBatchService 提现银行批量代付服务
+// 已审核的提现按收款银行的代付格式 + 到账币种锁定为批次，生成银行代付文件；导入银行回单后逐笔确认打款或退回暂扣金额
+type PayoutBatchService struct {
+	db             *gorm.DB
+	accountService *AccountService
+}
+
+// NewPayoutBatchService 创建代付批次服务
+func NewPayoutBatchService() *PayoutBatchService {
+	return &PayoutBatchService{
+		db:             database.GetDB(),
+		accountService: NewAccountService(),
+	}
+}
+
+// PayoutBatchRequest 创建代付批次请求
+type PayoutBatchRequest struct {
+	Layout         string   `json:"layout" binding:"required"`
+	Currency       string   `json:"currency" binding:"required"`
+	ApplicationIDs []uint64 `json:"application_ids"` // 为空则锁定该格式 + 币种下全部可代付的提现
+	Remark         string   `json:"remark"`
+}
+
+// collectPayoutItems 查找可代付的提现（已审核、未入批次、到账币种一致、收款账户为银行且银行使用该格式）
+// applicationIDs 非空时逐笔校验，任一不满足即报错；forUpdate 时锁定提现申请行
+func (s *PayoutBatchService) collectPayoutItems(tx *gorm.DB, layout, currency string, applicationIDs []uint64, forUpdate bool) ([]models.PayoutBatchItem, error) {
+	query := tx.Where("status = ? AND payout_batch_no = '' AND payout_currency = ?", models.ApplicationStatusApproved, currency)
+	if len(applicationIDs) > 0 {
+		query = query.Where("id IN ?", applicationIDs)
+	}
+	if forUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var applications []models.WithdrawApplication
+	if err := query.Order("id ASC").Find(&applications).Error; err != nil {
+		return nil, fmt.Errorf("查询提现申请失败: %w", err)
+	}
+	if len(applicationIDs) > 0 && len(applications) != len(applicationIDs) {
+		found := make(map[uint64]bool, len(applications))
+		for _, app := range applications {
+			found[app.ID] = true
+		}
+		for _, id := range applicationIDs {
+			if !found[id] {
+				return nil, fmt.Errorf("提现申请%d不存在、未审核通过、到账币种不是%s或已在其他代付批次中", id, currency)
+			}
+		}
+	}
+
+	accountIDs := make([]uint64, 0, len(applications))
+	for _, app := range applications {
+		accountIDs = append(accountIDs, app.CollectionAccountID)
+	}
+	var accounts []models.CollectionAccount
+	if len(accountIDs) > 0 {
+		if err := tx.Where("id IN ?", accountIDs).Find(&accounts).Error; err != nil {
+			return nil, fmt.Errorf("查询收款账户失败: %w", err)
+		}
+	}
+	accountMap := make(map[uint64]*models.CollectionAccount, len(accounts))
+	for i := range accounts {
+		accountMap[accounts[i].ID] = &accounts[i]
+	}
+
+	items := make([]models.PayoutBatchItem, 0, len(applications))
+	for _, app := range applications {
+		account := accountMap[app.CollectionAccountID]
+		if account == nil || account.AccountType != models.CollectionAccountTypeBank || payoutLayoutForBank(account.BankName) != layout {
+			if len(applicationIDs) > 0 {
+				return nil, fmt.Errorf("提现申请%s的收款账户不是使用%s格式的银行账户", app.ApplicationNo, layout)
+			}
+			continue
+		}
+		items = append(items, models.PayoutBatchItem{
+			ApplicationID:       app.ID,
+			ApplicationNo:       app.ApplicationNo,
+			AdminID:             app.AdminID,
+			AccountType:         app.AccountType,
+			CollectionAccountID: account.ID,
+			BankName:            account.BankName,
+			BankBranch:          account.BankBranch,
+			AccountNo:           account.AccountNo,
+			AccountName:         account.AccountName,
+			Payee:               account.Payee,
+			Amount:              app.ActualAmount,
+			Currency:            app.PayoutCurrency,
+			Status:              models.PayoutItemPending,
+		})
+	}
+	return items, nil
+}
+
+// ListPayoutCandidates 预览可入批次的提现（不锁定）
+func (s *PayoutBatchService) ListPayoutCandidates(ctx context.Context, layout, currency string) ([]models.PayoutBatchItem, error) {
+	if _, ok := payoutLayouts()[layout]; !ok {
+		return nil, fmt.Errorf("代付格式%s不存在", layout)
+	}
+	return s.collectPayoutItems(s.db.WithContext(ctx), layout, currency, nil, false)
+}
+
+// CreatePayoutBatch 锁定提现申请生成代付批次；批次内的提现只能通过导入银行回单确认打款
+func (s *PayoutBatchService) CreatePayoutBatch(ctx context.Context, req *PayoutBatchRequest, createdBy int64) (*models.PayoutBatch, error) {
+	if _, ok := payoutLayouts()[req.Layout]; !ok {
+		return nil, fmt.Errorf("代付格式%s不存在", req.Layout)
+	}
+
+	now := time.Now()
+	batch := &models.PayoutBatch{
+		BatchNo:   fmt.Sprintf("PB%s%03d", now.Format("20060102150405"), now.Nanosecond()/int(time.Millisecond)),
+		Layout:    req.Layout,
+		Currency:  req.Currency,
+		Status:    models.PayoutBatchPending,
+		Remark:    req.Remark,
+		CreatedBy: createdBy,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		items, err := s.collectPayoutItems(tx, req.Layout, req.Currency, req.ApplicationIDs, true)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return fmt.Errorf("没有可代付的提现申请")
+		}
+
+		applicationIDs := make([]uint64, 0, len(items))
+		for i := range items {
+			items[i].BatchNo = batch.BatchNo
+			items[i].LineNo = i + 1
+			batch.ItemCount++
+			batch.TotalAmount = batch.TotalAmount.Add(items[i].Amount)
+			applicationIDs = append(applicationIDs, items[i].ApplicationID)
+		}
+
+		if err := tx.Create(batch).Error; err != nil {
+			return fmt.Errorf("创建代付批次失败: %w", err)
+		}
+		if err := tx.CreateInBatches(&items, 200).Error; err != nil {
+			return fmt.Errorf("创建代付明细失败: %w", err)
+		}
+		if err := tx.Model(&models.WithdrawApplication{}).
+			Where("id IN ? AND payout_batch_no = ''", applicationIDs).
+			Update("payout_batch_no", batch.BatchNo).Error; err != nil {
+			return fmt.Errorf("锁定提现申请失败: %w", err)
+		}
+		batch.Items = items
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// ListPayoutBatches 获取代付批次列表，status=-1 表示全部
+func (s *PayoutBatchService) ListPayoutBatches(ctx context.Context, status int8, page, pageSize int) ([]models.PayoutBatch, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.PayoutBatch{})
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询代付批次失败: %w", err)
+	}
+
+	var batches []models.PayoutBatch
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&batches).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询代付批次失败: %w", err)
+	}
+	return batches, total, nil
+}
+
+// GetPayoutBatch 获取代付批次及明细
+func (s *PayoutBatchService) GetPayoutBatch(ctx context.Context, batchNo string) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := s.db.WithContext(ctx).Where("batch_no = ?", batchNo).First(&batch).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("代付批次不存在")
+		}
+		return nil, fmt.Errorf("查询代付批次失败: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Where("batch_no = ?", batchNo).Order("line_no ASC").Find(&batch.Items).Error; err != nil {
+		return nil, fmt.Errorf("查询代付明细失败: %w", err)
+	}
+	return &batch, nil
+}
+
+// ExportPayoutFile 生成代付批次的银行代付文件（按批次格式，可重复下载）
+func (s *PayoutBatchService) ExportPayoutFile(ctx context.Context, batchNo string) (string, []byte, error) {
+	batch, err := s.GetPayoutBatch(ctx, batchNo)
+	if err != nil {
+		return "", nil, err
+	}
+	layout, ok := payoutLayouts()[batch.Layout]
+	if !ok {
+		return "", nil, fmt.Errorf("代付格式%s已不在配置中", batch.Layout)
+	}
+
+	var pending []models.PayoutBatchItem
+	for _, item := range batch.Items {
+		if item.Status != models.PayoutItemCancelled {
+			pending = append(pending, item)
+		}
+	}
+	batch.Items = pending
+
+	data, err := EncodePayoutFile(batch, layout)
+	if err != nil {
+		return "", nil, err
+	}
+	return batch.BatchNo + ".csv", data, nil
+}
+
+// payoutDelimiter 代付文件分隔符（默认逗号）
+func payoutDelimiter(layout config.PayoutLayoutConfig) rune {
+	if layout.Delimiter == "" {
+		return ','
+	}
+	if layout.Delimiter == `\t` {
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(layout.Delimiter)
+	return r
+}
+
+// payoutFieldValue 代付文件字段取值
+func payoutFieldValue(batch *models.PayoutBatch, item *models.PayoutBatchItem, layout config.PayoutLayoutConfig, col config.PayoutColumnConfig) (string, error) {
+	switch col.Field {
+	case "":
+		return col.Value, nil
+	case "batch_no":
+		return batch.BatchNo, nil
+	case "line_no":
+		return strconv.Itoa(item.LineNo), nil
+	case "reference":
+		return item.ApplicationNo, nil
+	case "bank_name":
+		return item.BankName, nil
+	case "bank_branch":
+		return item.BankBranch, nil
+	case "account_no":
+		return item.AccountNo, nil
+	case "account_name":
+		return item.AccountName, nil
+	case "payee":
+		return item.Payee, nil
+	case "amount":
+		if layout.AmountFormat == PayoutAmountCents {
+			return item.Amount.Shift(2).StringFixed(0), nil
+		}
+		return item.Amount.StringFixed(2), nil
+	case "currency":
+		return item.Currency, nil
+	case "remark":
+		return batch.Remark, nil
+	case "date":
+		format := layout.DateFormat
+		if format == "" {
+			format = "20060102"
+		}
+		return batch.CreatedAt.Format(format), nil
+	default:
+		return "", fmt.Errorf("代付格式字段%s不支持", col.Field)
+	}
+}
+
+// EncodePayoutFile 按代付格式输出银行代付文件（CSV）
+func EncodePayoutFile(batch *models.PayoutBatch, layout config.PayoutLayoutConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = payoutDelimiter(layout)
+
+	if layout.Header {
+		titles := make([]string, len(layout.Columns))
+		for i, col := range layout.Columns {
+			titles[i] = col.Title
+		}
+		_ = w.Write(titles)
+	}
+	for i := range batch.Items {
+		row := make([]string, len(layout.Columns))
+		for j, col := range layout.Columns {
+			value, err := payoutFieldValue(batch, &batch.Items[i], layout, col)
+			if err != nil {
+				return nil, err
+			}
+			row[j] = value
+		}
+		_ = w.Write(row)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// PayoutResultRow 银行回单中的一笔结果
+type PayoutResultRow struct {
+	Reference string
+	Status    int8 // PayoutItemPaid / PayoutItemFailed / PayoutItemPending(处理中)
+	Message   string
+	BankRef   string
+}
+
+// ParsePayoutResults 按代付格式解析银行回单（首行为标题行，按标题匹配列）
+func ParsePayoutResults(r io.Reader, layout config.PayoutLayoutConfig) ([]PayoutResultRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = payoutDelimiter(layout)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取回单标题行失败: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, title := range header {
+		if i == 0 {
+			title = strings.TrimPrefix(title, "\xEF\xBB\xBF")
+		}
+		columns[strings.TrimSpace(title)] = i
+	}
+
+	spec := layout.Result
+	refIdx, ok := columns[spec.ReferenceColumn]
+	if !ok {
+		return nil, fmt.Errorf("回单缺少参考号列: %s", spec.ReferenceColumn)
+	}
+	statusIdx, ok := columns[spec.StatusColumn]
+	if !ok {
+		return nil, fmt.Errorf("回单缺少结果状态列: %s", spec.StatusColumn)
+	}
+	msgIdx, hasMsg := columns[spec.MessageColumn]
+	bankRefIdx, hasBankRef := columns[spec.BankRefColumn]
+
+	matches := func(values []string, v string) bool {
+		for _, candidate := range values {
+			if strings.EqualFold(strings.TrimSpace(candidate), v) {
+				return true
+			}
+		}
+		return false
+	}
+	cell := func(record []string, idx int) string {
+		if idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+
+	var rows []PayoutResultRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析回单失败: %w", err)
+		}
+		row := PayoutResultRow{Reference: cell(record, refIdx), Status: models.PayoutItemPending}
+		if row.Reference == "" {
+			continue
+		}
+		status := cell(record, statusIdx)
+		switch {
+		case matches(spec.SuccessValues, status):
+			row.Status = models.PayoutItemPaid
+		case matches(spec.FailureValues, status):
+			row.Status = models.PayoutItemFailed
+		}
+		if hasMsg {
+			row.Message = cell(record, msgIdx)
+		}
+		if hasBankRef {
+			row.BankRef = cell(record, bankRefIdx)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// PayoutImportResult 导入银行回单结果
+type PayoutImportResult struct {
+	Paid       int                 `json:"paid"`       // 本次确认打款笔数
+	Failed     int                 `json:"failed"`     // 本次确认失败并退回暂扣金额笔数
+	Processing int                 `json:"processing"` // 银行处理中（保持待回单）笔数
+	Duplicated int                 `json:"duplicated"` // 此前已处理过的笔数
+	Unknown    []string            `json:"unknown"`    // 不属于本批次的参考号
+	Errors     []string            `json:"errors"`     // 处理出错的明细
+	Batch      *models.PayoutBatch `json:"batch"`
+}
+
+// ImportPayoutResults 导入银行回单：成功的提现出账并标记已打款，失败的退回暂扣金额；每笔独立事务，可重复导入
+func (s *PayoutBatchService) ImportPayoutResults(ctx context.Context, batchNo string, r io.Reader, importedBy int64) (*PayoutImportResult, error) {
+	batch, err := s.GetPayoutBatch(ctx, batchNo)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == models.PayoutBatchCancelled {
+		return nil, fmt.Errorf("代付批次已取消")
+	}
+	layout, ok := payoutLayouts()[batch.Layout]
+	if !ok {
+		return nil, fmt.Errorf("代付格式%s已不在配置中", batch.Layout)
+	}
+
+	rows, err := ParsePayoutResults(r, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	itemMap := make(map[string]*models.PayoutBatchItem, len(batch.Items))
+	for i := range batch.Items {
+		itemMap[batch.Items[i].ApplicationNo] = &batch.Items[i]
+	}
+
+	result := &PayoutImportResult{Unknown: []string{}, Errors: []string{}}
+	for _, row := range rows {
+		item, ok := itemMap[row.Reference]
+		if !ok {
+			result.Unknown = append(result.Unknown, row.Reference)
+			continue
+		}
+		if item.Status != models.PayoutItemPending {
+			result.Duplicated++
+			continue
+		}
+		if row.Status == models.PayoutItemPending {
+			result.Processing++
+			continue
+		}
+
+		applied, err := s.applyPayoutResult(ctx, batch.BatchNo, item, row)
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", row.Reference, err))
+		case !applied:
+			result.Duplicated++
+		case row.Status == models.PayoutItemPaid:
+			result.Paid++
+		default:
+			result.Failed++
+		}
+	}
+
+	if err := s.refreshPayoutBatch(ctx, batch.BatchNo, importedBy); err != nil {
+		return nil, err
+	}
+	if result.Batch, err = s.GetPayoutBatch(ctx, batch.BatchNo); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyPayoutResult 确认一笔代付结果，返回 false 表示该明细已被处理
+func (s *PayoutBatchService) applyPayoutResult(ctx context.Context, batchNo string, item *models.PayoutBatchItem, row PayoutResultRow) (bool, error) {
+	applied := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked models.PayoutBatchItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", item.ID, models.PayoutItemPending).
+			First(&locked).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil // 并发导入已处理
+			}
+			return err
+		}
+
+		var application models.WithdrawApplication
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ? AND payout_batch_no = ?", locked.ApplicationID, models.ApplicationStatusApproved, batchNo).
+			First(&application).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("提现申请状态已变更")
+			}
+			return err
+		}
+
+		reason := row.Message
+		if runes := []rune(reason); len(runes) > 200 {
+			reason = string(runes[:200])
+		}
+		if row.Status == models.PayoutItemPaid {
+			if err := s.accountService.markWithdrawPaidInTx(tx, ctx, &application); err != nil {
+				return err
+			}
+		} else {
+			if reason == "" {
+				reason = "银行回单打款失败"
+			}
+			if err := s.accountService.markWithdrawFailedInTx(tx, ctx, &application, reason); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":       row.Status,
+			"bank_ref":     row.BankRef,
+			"processed_at": &now,
+		}
+		if row.Status == models.PayoutItemFailed {
+			updates["fail_reason"] = reason
+		}
+		if err := tx.Model(&models.PayoutBatchItem{}).Where("id = ?", locked.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// refreshPayoutBatch 按明细重算批次统计，无待回单明细时批次完成
+func (s *PayoutBatchService) refreshPayoutBatch(ctx context.Context, batchNo string, importedBy int64) error {
+	var stats []struct {
+		Status int8
+		Count  int
+		Amount decimal.Decimal
+	}
+	if err := s.db.WithContext(ctx).Model(&models.PayoutBatchItem{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("batch_no = ?", batchNo).Group("status").Scan(&stats).Error; err != nil {
+		return fmt.Errorf("统计代付明细失败: %w", err)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"paid_count":         0,
+		"paid_amount":        decimal.Zero,
+		"failed_count":       0,
+		"failed_amount":      decimal.Zero,
+		"result_imported_by": importedBy,
+		"result_imported_at": &now,
+	}
+	pending := 0
+	for _, st := range stats {
+		switch st.Status {
+		case models.PayoutItemPaid:
+			updates["paid_count"] = st.Count
+			updates["paid_amount"] = st.Amount
+		case models.PayoutItemFailed:
+			updates["failed_count"] = st.Count
+			updates["failed_amount"] = st.Amount
+		case models.PayoutItemPending:
+			pending = st.Count
+		}
+	}
+	if pending == 0 {
+		updates["status"] = models.PayoutBatchCompleted
+		updates["completed_at"] = &now
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.PayoutBatch{}).
+		Where("batch_no = ? AND status = ?", batchNo, models.PayoutBatchPending).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新代付批次失败: %w", err)
+	}
+	return nil
+}
+
+// CancelPayoutBatch 取消代付批次（仅限尚未导入任何结果的批次），提现申请解除锁定，可重新入批次或手动确认打款
+func (s *PayoutBatchService) CancelPayoutBatch(ctx context.Context, batchNo string) (*models.PayoutBatch, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var batch models.PayoutBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("batch_no = ?", batchNo).First(&batch).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("代付批次不存在")
+			}
+			return err
+		}
+		if batch.Status != models.PayoutBatchPending {
+			return fmt.Errorf("代付批次已完成或已取消")
+		}
+
+		var processed int64
+		if err := tx.Model(&models.PayoutBatchItem{}).
+			Where("batch_no = ? AND status IN ?", batchNo, []int8{models.PayoutItemPaid, models.PayoutItemFailed}).
+			Count(&processed).Error; err != nil {
+			return err
+		}
+		if processed > 0 {
+			return fmt.Errorf("代付批次已导入%d笔回单结果，不能取消", processed)
+		}
+
+		if err := tx.Model(&models.PayoutBatchItem{}).Where("batch_no = ?", batchNo).
+			Update("status", models.PayoutItemCancelled).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WithdrawApplication{}).
+			Where("payout_batch_no = ? AND status = ?", batchNo, models.ApplicationStatusApproved).
+			Update("payout_batch_no", "").Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Model(&batch).Updates(map[string]interface{}{
+			"status":       models.PayoutBatchCancelled,
+			"completed_at": &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPayoutBatch(ctx, batchNo)
+}
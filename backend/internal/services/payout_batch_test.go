@@ -0,0 +1,85 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestEncodePayoutFile(t *testing.T) {
+	batch := &models.PayoutBatch{
+		BatchNo:   "PB20261019103000123",
+		Remark:    "週結",
+		CreatedAt: time.Date(2026, 10, 19, 10, 30, 0, 0, time.Local),
+		Items: []models.PayoutBatchItem{
+			{LineNo: 1, ApplicationNo: "WD001", BankName: "中國信託", AccountNo: "0123456789", Payee: "王小明", Amount: decimal.RequireFromString("1234.5"), Currency: "TWD"},
+			{LineNo: 2, ApplicationNo: "WD002", BankName: "中國信託", AccountNo: "9876543210", Payee: "Lee, Amy", Amount: decimal.RequireFromString("88"), Currency: "TWD"},
+		},
+	}
+
+	data, err := EncodePayoutFile(batch, genericPayoutLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "reference,bank_name,bank_branch,account_no,payee,amount,currency,remark\n" +
+		"WD001,中國信託,,0123456789,王小明,1234.50,TWD,週結\n" +
+		"WD002,中國信託,,9876543210,\"Lee, Amy\",88.00,TWD,週結\n"
+	if string(data) != want {
+		t.Errorf("generic layout:\n%s\nwant:\n%s", data, want)
+	}
+
+	layout := config.PayoutLayoutConfig{
+		Delimiter:    "|",
+		AmountFormat: PayoutAmountCents,
+		Columns: []config.PayoutColumnConfig{
+			{Field: "date"}, {Field: "line_no"}, {Field: "reference"}, {Field: "amount"}, {Value: "822"},
+		},
+	}
+	data, err = EncodePayoutFile(batch, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "20261019|1|WD001|123450|822\n20261019|2|WD002|8800|822\n"
+	if string(data) != want {
+		t.Errorf("custom layout:\n%s\nwant:\n%s", data, want)
+	}
+
+	layout.Columns = append(layout.Columns, config.PayoutColumnConfig{Field: "swift"})
+	if _, err := EncodePayoutFile(batch, layout); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestParsePayoutResults(t *testing.T) {
+	file := "\xEF\xBB\xBFreference,status,message,bank_ref\n" +
+		"WD001,success,,B0001\n" +
+		"WD002,FAILED,帳號不存在,\n" +
+		"WD003,PROCESSING,,\n" +
+		",SUCCESS,,\n"
+	rows, err := ParsePayoutResults(strings.NewReader(file), genericPayoutLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PayoutResultRow{
+		{Reference: "WD001", Status: models.PayoutItemPaid, BankRef: "B0001"},
+		{Reference: "WD002", Status: models.PayoutItemFailed, Message: "帳號不存在"},
+		{Reference: "WD003", Status: models.PayoutItemPending},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	if _, err := ParsePayoutResults(strings.NewReader("ref,result\nWD001,SUCCESS\n"), genericPayoutLayout); err == nil {
+		t.Error("missing reference column accepted")
+	}
+}
@@ -0,0 +1,532 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LateShipPenaltyAmount 超时发货自动罚款提案的每单金额（按订单结算币种记）
+var LateShipPenaltyAmount = decimal.NewFromInt(100)
+
+const (
+	lateShipLookback            = 7 * 24 * time.Hour // 超时发货扫描的最晚发货时间回溯范围
+	penaltyBonusSettleBatchSize = 500                // 每次定时结算的最大罚补单数
+)
+
+// PenaltyBonusRequest 创建罚补单参数
+type PenaltyBonusRequest struct {
+	EntryType   string          // penalty / subsidy
+	AccountType string          // operator / shop_owner_commission
+	AdminID     int64           // 对象用户ID
+	Currency    string          // 结算币种，为空时默认 TWD
+	Amount      decimal.Decimal // 金额（正数）
+	ReasonCode  string          // 原因代码
+	ShopID      uint64          // 店铺ID
+	OrderSN     string          // 关联订单号
+	Evidence    string          // 证据（说明/图片链接）
+	Remark      string          // 备注
+	RequestedBy int64           // 发起人ID
+}
+
+// PenaltyBonusStats 罚补统计（按币种）
+type PenaltyBonusStats struct {
+	Currency           string          `json:"currency"`
+	PendingCount       int64           `json:"pending_count"`       // 待审核笔数
+	OutstandingPenalty decimal.Decimal `json:"outstanding_penalty"` // 已审核待扣罚款
+	OutstandingSubsidy decimal.Decimal `json:"outstanding_subsidy"` // 已审核待发补贴
+	SettledPenalty     decimal.Decimal `json:"settled_penalty"`     // 已扣罚款
+	SettledSubsidy     decimal.Decimal `json:"settled_subsidy"`     // 已发补贴
+	NetIncome          decimal.Decimal `json:"net_income"`          // 罚补净收入 = 已扣罚款 - 已发补贴
+}
+
+var penaltyBonusReasons = map[string]bool{
+	models.PenaltyReasonLateShip:   true,
+	models.PenaltyReasonFakeShip:   true,
+	models.PenaltyReasonQuality:    true,
+	models.PenaltyReasonService:    true,
+	models.PenaltyReasonCompensate: true,
+	models.PenaltyReasonIncentive:  true,
+	models.PenaltyReasonOther:      true,
+}
+
+// PenaltyBonusIdempotencyKey 罚补单结算流水的幂等键（同一罚补单只入账一次）
+func PenaltyBonusIdempotencyKey(entryNo string) string {
+	return "penalty_bonus:" + entryNo
+}
+
+// CreatePenaltyBonus 平台手工录入罚补单（待审核，需另一名平台用户审批）
+func (s *AccountService) CreatePenaltyBonus(ctx context.Context, req PenaltyBonusRequest) (*models.PenaltyBonusEntry, error) {
+	if req.EntryType != models.TxTypePenalty && req.EntryType != models.TxTypeSubsidy {
+		return nil, fmt.Errorf("罚补类型只能是 penalty 或 subsidy")
+	}
+	if req.AccountType != models.AccountTypeOperator && req.AccountType != models.AccountTypeShopOwnerCommission {
+		return nil, fmt.Errorf("罚补只能作用于运营账户或店主佣金账户")
+	}
+	if req.AdminID <= 0 {
+		return nil, fmt.Errorf("请指定罚补对象")
+	}
+	if req.Amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("罚补金额必须大于0")
+	}
+	if !penaltyBonusReasons[req.ReasonCode] {
+		return nil, fmt.Errorf("无效的原因代码: %s", req.ReasonCode)
+	}
+	if req.Evidence == "" && req.OrderSN == "" {
+		return nil, fmt.Errorf("请填写证据或关联订单号")
+	}
+
+	entry := &models.PenaltyBonusEntry{
+		EntryNo:     s.GenerateApplicationNo("PB"),
+		EntryType:   req.EntryType,
+		AccountType: req.AccountType,
+		AdminID:     req.AdminID,
+		Currency:    NormalizeCurrency(req.Currency),
+		Amount:      req.Amount,
+		ReasonCode:  req.ReasonCode,
+		ShopID:      req.ShopID,
+		OrderSN:     req.OrderSN,
+		Evidence:    req.Evidence,
+		Remark:      req.Remark,
+		Source:      models.PenaltyBonusSourceManual,
+		Status:      models.PenaltyBonusStatusPending,
+		RequestedBy: req.RequestedBy,
+	}
+	entry.DedupKey = entry.EntryNo
+	if err := s.createPenaltyBonusEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// createPenaltyBonusEntry 生成ID并写入罚补单
+func (s *AccountService) createPenaltyBonusEntry(ctx context.Context, entry *models.PenaltyBonusEntry) error {
+	id, err := s.idGen.GeneratePenaltyBonusEntryID(ctx)
+	if err != nil {
+		return fmt.Errorf("生成罚补单ID失败: %w", err)
+	}
+	entry.ID = uint64(id)
+	return s.db.Create(entry).Error
+}
+
+// ApprovePenaltyBonus 审批通过罚补单：计入罚补账户待结算金额，并尝试立即结算
+// 补贴立即入账；罚款在账户可提现余额足够时扣除，不足则等待后续回款
+func (s *AccountService) ApprovePenaltyBonus(ctx context.Context, entryID uint64, auditBy int64, auditRemark string) (*models.PenaltyBonusEntry, error) {
+	var entry models.PenaltyBonusEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, entryID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("罚补单不存在")
+			}
+			return err
+		}
+		if entry.Status != models.PenaltyBonusStatusPending {
+			return fmt.Errorf("罚补单状态不正确")
+		}
+		if entry.RequestedBy != 0 && entry.RequestedBy == auditBy {
+			return fmt.Errorf("罚补单需由发起人以外的平台用户审批")
+		}
+
+		outstanding := entry.Amount
+		penaltyDelta, subsidyDelta := entry.Amount, decimal.Zero
+		if entry.EntryType == models.TxTypeSubsidy {
+			outstanding = entry.Amount.Neg()
+			penaltyDelta, subsidyDelta = decimal.Zero, entry.Amount
+		}
+		if err := s.adjustPenaltyBonusAccountInTx(tx, ctx, entry.AdminID, entry.Currency, outstanding, penaltyDelta, subsidyDelta); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		entry.Status = models.PenaltyBonusStatusApproved
+		entry.AuditBy = auditBy
+		entry.AuditRemark = auditRemark
+		entry.AuditAt = &now
+		if err := tx.Save(&entry).Error; err != nil {
+			return err
+		}
+		_, err := s.settlePenaltyBonusInTx(tx, ctx, &entry, auditBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// RejectPenaltyBonus 拒绝罚补单
+func (s *AccountService) RejectPenaltyBonus(ctx context.Context, entryID uint64, auditBy int64, auditRemark string) error {
+	now := time.Now()
+	res := s.db.Model(&models.PenaltyBonusEntry{}).
+		Where("id = ? AND status = ?", entryID, models.PenaltyBonusStatusPending).
+		Updates(map[string]interface{}{
+			"status":       models.PenaltyBonusStatusRejected,
+			"audit_by":     auditBy,
+			"audit_remark": auditRemark,
+			"audit_at":     now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("罚补单不存在或状态不正确")
+	}
+	return nil
+}
+
+// SettleApprovedPenaltyBonus 结算指定账户已审核待结算的罚补单（回款入账后调用，可重复调用）
+func (s *AccountService) SettleApprovedPenaltyBonus(ctx context.Context, accountType string, adminID int64, currency string) (int, error) {
+	var ids []uint64
+	if err := s.db.Model(&models.PenaltyBonusEntry{}).
+		Where("account_type = ? AND admin_id = ? AND currency = ? AND status = ?", accountType, adminID, NormalizeCurrency(currency), models.PenaltyBonusStatusApproved).
+		Order("audit_at ASC").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return s.settlePenaltyBonusEntries(ctx, ids)
+}
+
+// SettleAllApprovedPenaltyBonus 结算全部已审核待结算的罚补单（定时任务调用，余额不足的罚款留待下次）
+func (s *AccountService) SettleAllApprovedPenaltyBonus(ctx context.Context) (int, error) {
+	var ids []uint64
+	if err := s.db.Model(&models.PenaltyBonusEntry{}).
+		Where("status = ?", models.PenaltyBonusStatusApproved).
+		Order("audit_at ASC").Limit(penaltyBonusSettleBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return s.settlePenaltyBonusEntries(ctx, ids)
+}
+
+// settlePenaltyBonusEntries 逐条结算罚补单（每条独立事务，已结算或余额不足的跳过）
+func (s *AccountService) settlePenaltyBonusEntries(ctx context.Context, ids []uint64) (int, error) {
+	count := 0
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var entry models.PenaltyBonusEntry
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, id).Error; err != nil {
+				return err
+			}
+			if entry.Status != models.PenaltyBonusStatusApproved {
+				return nil
+			}
+			settled, err := s.settlePenaltyBonusInTx(tx, ctx, &entry, 0)
+			if settled {
+				count++
+			}
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("结算罚补单 %d 失败: %w", id, err)
+		}
+	}
+	return count, nil
+}
+
+// settlePenaltyBonusInTx 对罚补对象账户写入罚款/补贴流水并记账（事务参与版本，调用方需已锁定罚补单）
+// 罚款只从可提现余额（扣除风控暂扣后）中扣除，不足时返回 false 不做变动
+func (s *AccountService) settlePenaltyBonusInTx(db *gorm.DB, ctx context.Context, entry *models.PenaltyBonusEntry, operatorID int64) (bool, error) {
+	var model interface{}
+	switch entry.AccountType {
+	case models.AccountTypeOperator:
+		if _, err := s.GetOrCreateOperatorAccount(ctx, entry.AdminID, entry.Currency); err != nil {
+			return false, err
+		}
+		model = &models.OperatorAccount{}
+	case models.AccountTypeShopOwnerCommission:
+		if _, err := s.GetOrCreateShopOwnerCommissionAccount(ctx, entry.AdminID, entry.Currency); err != nil {
+			return false, err
+		}
+		model = &models.ShopOwnerCommissionAccount{}
+	default:
+		return false, fmt.Errorf("%s 账户不支持罚补", entry.AccountType)
+	}
+
+	var balance, held decimal.Decimal
+	row := db.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("balance, held_amount").Where("admin_id = ? AND currency = ?", entry.AdminID, entry.Currency).Row()
+	if err := row.Scan(&balance, &held); err != nil {
+		return false, fmt.Errorf("账户不存在")
+	}
+
+	idempotencyKey := PenaltyBonusIdempotencyKey(entry.EntryNo)
+	at, err := s.findIdempotentTransaction(db, entry.AdminID, idempotencyKey, entry.AccountType, entry.EntryType)
+	if err != nil {
+		return false, err
+	}
+	if at == nil {
+		amount := entry.Amount
+		if entry.EntryType == models.TxTypePenalty {
+			if WithdrawableBalance(balance, held).LessThan(amount) {
+				return false, nil
+			}
+			amount = amount.Neg()
+		}
+		balanceAfter := balance.Add(amount)
+		if err := db.Model(model).Where("admin_id = ? AND currency = ?", entry.AdminID, entry.Currency).
+			Update("balance", balanceAfter).Error; err != nil {
+			return false, err
+		}
+
+		remark := fmt.Sprintf("罚补单 %s: %s", entry.EntryNo, entry.Remark)
+		at = &models.AccountTransaction{
+			TransactionNo:   s.GenerateTransactionNo(entry.AccountType),
+			IdempotencyKey:  idempotencyKey,
+			AccountType:     entry.AccountType,
+			AdminID:         entry.AdminID,
+			Currency:        entry.Currency,
+			TransactionType: entry.EntryType,
+			Amount:          amount,
+			BalanceBefore:   balance,
+			BalanceAfter:    balanceAfter,
+			RelatedID:       entry.ID,
+			RelatedOrderSN:  entry.OrderSN,
+			Remark:          remark,
+			OperatorID:      operatorID,
+			Status:          1,
+		}
+		if err := s.createTransaction(db, at); err != nil {
+			return false, err
+		}
+
+		// 记账：罚款 用户可用余额 → 罚补；补贴 罚补 → 用户可用余额
+		journal := NewJournal(models.JournalBizPenaltyBonus, entry.OrderSN, entry.EntryNo, remark).
+			SetCurrency(entry.Currency).
+			SetOperator(operatorID).
+			AddTransaction(at)
+		if entry.EntryType == models.TxTypePenalty {
+			journal.Credit(models.ClearingAccountPenaltyBonus, 0, models.JournalBucketAvailable, entry.Amount, "", remark)
+		} else {
+			journal.Debit(models.ClearingAccountPenaltyBonus, 0, models.JournalBucketAvailable, entry.Amount, "", remark)
+		}
+		if _, err := s.PostJournalInTx(db, ctx, journal); err != nil {
+			return false, err
+		}
+	}
+
+	// 结清罚补账户的待结算金额
+	outstanding := entry.Amount.Neg()
+	if entry.EntryType == models.TxTypeSubsidy {
+		outstanding = entry.Amount
+	}
+	if err := s.adjustPenaltyBonusAccountInTx(db, ctx, entry.AdminID, entry.Currency, outstanding, decimal.Zero, decimal.Zero); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	entry.Status = models.PenaltyBonusStatusSettled
+	entry.TransactionNo = at.TransactionNo
+	entry.SettledAt = &now
+	if err := db.Save(entry).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// reversePenaltyBonusInTx 罚补结算流水冲正时撤销罚补单（事务参与版本）：
+// 罚补单标记为已冲正，并冲减罚补账户的累计罚款/补贴（已结算的罚补单不再占用待结算金额）
+func (s *AccountService) reversePenaltyBonusInTx(db *gorm.DB, ctx context.Context, reversal *models.TransactionReversal) error {
+	var entry models.PenaltyBonusEntry
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("account_type = ? AND admin_id = ? AND transaction_no = ?", reversal.AccountType, reversal.AdminID, reversal.TransactionNo).
+		First(&entry).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("流水 %s 没有对应的罚补单", reversal.TransactionNo)
+		}
+		return err
+	}
+	if entry.Status != models.PenaltyBonusStatusSettled {
+		return fmt.Errorf("罚补单 %s 状态不正确，无法冲正", entry.EntryNo)
+	}
+
+	penaltyDelta, subsidyDelta := entry.Amount.Neg(), decimal.Zero
+	if entry.EntryType == models.TxTypeSubsidy {
+		penaltyDelta, subsidyDelta = decimal.Zero, entry.Amount.Neg()
+	}
+	if err := s.adjustPenaltyBonusAccountInTx(db, ctx, entry.AdminID, entry.Currency, decimal.Zero, penaltyDelta, subsidyDelta); err != nil {
+		return err
+	}
+
+	entry.Status = models.PenaltyBonusStatusReversed
+	return db.Save(&entry).Error
+}
+
+// adjustPenaltyBonusAccountInTx 调整罚补账户（余额正=待扣罚款、负=待发补贴；不存在时创建）
+func (s *AccountService) adjustPenaltyBonusAccountInTx(db *gorm.DB, ctx context.Context, adminID int64, currency string, balanceDelta, penaltyDelta, subsidyDelta decimal.Decimal) error {
+	var account models.PenaltyBonusAccount
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("admin_id = ? AND currency = ?", adminID, currency).First(&account).Error
+	if err == gorm.ErrRecordNotFound {
+		id, idErr := s.idGen.GeneratePenaltyBonusAccountID(ctx)
+		if idErr != nil {
+			return fmt.Errorf("生成罚补账户ID失败: %w", idErr)
+		}
+		account = models.PenaltyBonusAccount{
+			ID:       uint64(id),
+			AdminID:  adminID,
+			Currency: currency,
+			Status:   models.AccountStatusNormal,
+		}
+		if err := db.Create(&account).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	account.Balance = account.Balance.Add(balanceDelta)
+	account.TotalPenalty = account.TotalPenalty.Add(penaltyDelta)
+	account.TotalBonus = account.TotalBonus.Add(subsidyDelta)
+	return db.Save(&account).Error
+}
+
+// GenerateLateShipPenalties 扫描超过最晚发货时间仍未发货或延迟发货的订单，为运营生成待审核的罚款提案
+// 同一订单只生成一次提案（去重键唯一索引兜底），可重复调用
+func (s *AccountService) GenerateLateShipPenalties(ctx context.Context) (int, error) {
+	now := time.Now()
+	since := now.Add(-lateShipLookback)
+
+	type lateShipRow struct {
+		ShopID     uint64
+		OrderSN    string
+		OperatorID int64
+		Currency   string
+		ShipByDate time.Time
+		ShippedAt  *time.Time
+	}
+
+	created := 0
+	for i := 0; i < database.ShardCount; i++ {
+		var rows []lateShipRow
+		err := s.db.Table(fmt.Sprintf("order_shipment_records_%d AS r", i)).
+			Select("r.shop_id, r.order_sn, r.operator_id, r.currency, o.ship_by_date, r.shipped_at").
+			Joins(fmt.Sprintf("JOIN orders_%d AS o ON o.shop_id = r.shop_id AND o.order_sn = r.order_sn", i)).
+			Where("o.ship_by_date >= ? AND o.ship_by_date < ? AND r.operator_id > 0", since, now).
+			Where("((r.shipped_at IS NULL AND r.status IN ?) OR r.shipped_at > o.ship_by_date)",
+				[]int8{models.ShipmentRecordStatusPending, models.ShipmentRecordStatusFailed}).
+			Scan(&rows).Error
+		if err != nil {
+			return created, fmt.Errorf("扫描超时发货订单失败: %w", err)
+		}
+
+		for _, r := range rows {
+			evidence := fmt.Sprintf("最晚发货时间 %s，截至 %s 仍未发货", r.ShipByDate.Format("2006-01-02 15:04:05"), now.Format("2006-01-02 15:04:05"))
+			if r.ShippedAt != nil {
+				evidence = fmt.Sprintf("最晚发货时间 %s，实际发货时间 %s", r.ShipByDate.Format("2006-01-02 15:04:05"), r.ShippedAt.Format("2006-01-02 15:04:05"))
+			}
+			entry := &models.PenaltyBonusEntry{
+				EntryNo:     s.GenerateApplicationNo("PB"),
+				DedupKey:    fmt.Sprintf("%s:%d:%s", models.PenaltyReasonLateShip, r.ShopID, r.OrderSN),
+				EntryType:   models.TxTypePenalty,
+				AccountType: models.AccountTypeOperator,
+				AdminID:     r.OperatorID,
+				Currency:    NormalizeCurrency(r.Currency),
+				Amount:      LateShipPenaltyAmount,
+				ReasonCode:  models.PenaltyReasonLateShip,
+				ShopID:      r.ShopID,
+				OrderSN:     r.OrderSN,
+				Evidence:    evidence,
+				Remark:      "超时发货",
+				Source:      models.PenaltyBonusSourceAuto,
+				Status:      models.PenaltyBonusStatusPending,
+			}
+			if err := s.createPenaltyBonusEntry(ctx, entry); err != nil {
+				if database.IsDuplicateKeyError(err) {
+					continue
+				}
+				return created, err
+			}
+			created++
+		}
+	}
+	return created, nil
+}
+
+// GetPenaltyBonusEntries 获取罚补单列表（adminID 为 0 时不过滤用户，status 为 -1 时不过滤状态）
+func (s *AccountService) GetPenaltyBonusEntries(ctx context.Context, entryType string, accountType string, adminID int64, status int8, reasonCode string, orderSN string, page, pageSize int) ([]models.PenaltyBonusEntry, int64, error) {
+	var entries []models.PenaltyBonusEntry
+	var total int64
+
+	query := s.db.Model(&models.PenaltyBonusEntry{})
+	if entryType != "" {
+		query = query.Where("entry_type = ?", entryType)
+	}
+	if accountType != "" {
+		query = query.Where("account_type = ?", accountType)
+	}
+	if adminID > 0 {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if reasonCode != "" {
+		query = query.Where("reason_code = ?", reasonCode)
+	}
+	if orderSN != "" {
+		query = query.Where("order_sn = ?", orderSN)
+	}
+
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&entries).Error
+	return entries, total, err
+}
+
+// GetPenaltyBonusStats 按币种统计罚补单
+func (s *AccountService) GetPenaltyBonusStats(ctx context.Context) ([]PenaltyBonusStats, error) {
+	var rows []struct {
+		Currency  string
+		EntryType string
+		Status    int8
+		Cnt       int64
+		Amount    decimal.Decimal
+	}
+	if err := s.db.Model(&models.PenaltyBonusEntry{}).
+		Select("currency, entry_type, status, COUNT(*) AS cnt, COALESCE(SUM(amount), 0) AS amount").
+		Group("currency, entry_type, status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*PenaltyBonusStats)
+	order := make([]string, 0)
+	for _, r := range rows {
+		st, ok := byCurrency[r.Currency]
+		if !ok {
+			st = &PenaltyBonusStats{Currency: r.Currency}
+			byCurrency[r.Currency] = st
+			order = append(order, r.Currency)
+		}
+		penalty := r.EntryType == models.TxTypePenalty
+		switch r.Status {
+		case models.PenaltyBonusStatusPending:
+			st.PendingCount += r.Cnt
+		case models.PenaltyBonusStatusApproved:
+			if penalty {
+				st.OutstandingPenalty = st.OutstandingPenalty.Add(r.Amount)
+			} else {
+				st.OutstandingSubsidy = st.OutstandingSubsidy.Add(r.Amount)
+			}
+		case models.PenaltyBonusStatusSettled:
+			if penalty {
+				st.SettledPenalty = st.SettledPenalty.Add(r.Amount)
+			} else {
+				st.SettledSubsidy = st.SettledSubsidy.Add(r.Amount)
+			}
+		}
+	}
+	stats := make([]PenaltyBonusStats, 0, len(order))
+	for _, c := range order {
+		st := byCurrency[c]
+		st.NetIncome = st.SettledPenalty.Sub(st.SettledSubsidy)
+		stats = append(stats, *st)
+	}
+	return stats, nil
+}
@@ -102,7 +102,8 @@ func NewOrderServiceWithPrepaymentCheck() *shopower.OrderService {
 // 流程：
 //  1. 查找 shop -> shop_owner
 //  2. 在一个事务中：行锁订单 → 检查 prepayment_status=0 → 扣款/标记
-//  3. 余额不足时标记订单（prepayment_status=2），发通知
+//  3. 余额不足时标记订单（prepayment_status=2）并写入 prepayment.insufficient 事件，发通知
+//  4. 店主保证金不足时不扣款，标记订单（prepayment_status=3），补缴保证金后由补扣流程处理
 //
 // 返回 error 仅在系统错误时返回，预付款不足属于业务正常流程不返回 error
 func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, shopID uint64, orderSN string, orderAmount decimal.Decimal, orderTable string) error {
@@ -120,7 +121,7 @@ func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, sho
 
 	// ===== 第 2 步：查找店铺老板 =====
 	var shop models.Shop
-	if err := s.db.Select("admin_id").Where("shop_id = ?", shopID).First(&shop).Error; err != nil {
+	if err := s.db.Select("admin_id", "currency").Where("shop_id = ?", shopID).First(&shop).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil
 		}
@@ -131,17 +132,39 @@ func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, sho
 	}
 	shopOwnerID := shop.AdminID
 
+	// 保证金不足的店主暂停订单入系统
+	depositShort, _, err := s.accountService.IsDepositShort(ctx, shopOwnerID)
+	if err != nil {
+		return fmt.Errorf("查询保证金账户失败: %w", err)
+	}
+	if depositShort {
+		res := s.db.Table(orderTable).
+			Where("shop_id = ? AND order_sn = ? AND prepayment_status = ?", shopID, orderSN, models.PrepaymentUnchecked).
+			Updates(map[string]interface{}{
+				"prepayment_status":     models.PrepaymentDepositShort,
+				"prepayment_checked_at": time.Now(),
+			})
+		if res.Error != nil {
+			return fmt.Errorf("标记订单保证金不足失败: %w", res.Error)
+		}
+		if res.RowsAffected > 0 {
+			fmt.Printf("[PrepaymentCheck] 店铺=%d 订单=%s 店主=%d 保证金不足，暂停入系统\n", shopID, orderSN, shopOwnerID)
+		}
+		return nil
+	}
+
 	// ===== 第 3 步：在事务中「行锁订单 → 检查 → 扣款 → 标记」原子完成 =====
 	now := time.Now()
 	var insufficientBalance decimal.Decimal
 	var needNotify bool
+	var currency string
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		// 行锁订单行：防止 Sync 和 Webhook 并发处理同一订单
 		var lockedOrder models.Order
 		if err := tx.Table(orderTable).
 			Clauses(clause.Locking{Strength: "UPDATE"}).
-			Select("id", "prepayment_status", "total_amount", "prepayment_amount").
+			Select("id", "prepayment_status", "total_amount", "prepayment_amount", "currency").
 			Where("shop_id = ? AND order_sn = ?", shopID, orderSN).
 			First(&lockedOrder).Error; err != nil {
 			return fmt.Errorf("锁定订单失败: %w", err)
@@ -157,16 +180,19 @@ func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, sho
 			return nil // 已被其他协程处理，跳过
 		}
 
+		// 从订单币种的预付款子账户扣款（订单未记录币种时取店铺币种）
+		currency = orderCurrency(lockedOrder.Currency, shop.Currency)
+
 		// 获取预付款账户（在事务外获取余额快照即可，扣款在 FreezePrepayment 内部有自己的行锁）
-		account, err := s.accountService.GetOrCreatePrepaymentAccount(ctx, shopOwnerID)
+		account, err := s.accountService.GetOrCreatePrepaymentAccount(ctx, shopOwnerID, currency)
 		if err != nil {
 			return fmt.Errorf("获取预付款账户失败: %w", err)
 		}
 
 		if account.Balance.GreaterThanOrEqual(orderAmount) {
 			// 余额充足：冻结预付款（使用 InTx 版本，复用外层事务连接，避免连接池死锁）
-			_, err := s.accountService.FreezePrepaymentInTx(tx, ctx, shopOwnerID, orderAmount, orderSN,
-				fmt.Sprintf("订单预付款冻结(自动)-订单%s", orderSN))
+			_, err := s.accountService.FreezePrepaymentInTx(tx, ctx, shopOwnerID, orderAmount, currency, orderSN,
+				fmt.Sprintf("订单预付款冻结(自动)-订单%s", orderSN), OrderFreezeIdempotencyKey(shopID, orderSN))
 			if err != nil {
 				return fmt.Errorf("冻结预付款失败: %w", err)
 			}
@@ -174,7 +200,7 @@ func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, sho
 			// 获取冻结后余额快照（简单读查询，直接在事务内执行）
 			var acct models.PrepaymentAccount
 			var balanceAfter decimal.Decimal
-			if err := tx.Where("admin_id = ?", shopOwnerID).First(&acct).Error; err == nil {
+			if err := tx.Where("admin_id = ? AND currency = ?", shopOwnerID, currency).First(&acct).Error; err == nil {
 				balanceAfter = acct.Balance
 			}
 
@@ -188,16 +214,28 @@ func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, sho
 				}).Error
 		}
 
-		// 余额不足：标记订单
+		// 余额不足：标记订单，并写入 prepayment.insufficient 事件
 		insufficientBalance = account.Balance
 		needNotify = true
-		return tx.Table(orderTable).
+		if err := tx.Table(orderTable).
 			Where("id = ?", lockedOrder.ID).
 			Updates(map[string]interface{}{
 				"prepayment_status":     models.PrepaymentInsufficient,
 				"prepayment_snapshot":   account.Balance,
 				"prepayment_checked_at": now,
-			}).Error
+			}).Error; err != nil {
+			return err
+		}
+		return EnqueueOutboxEvent(tx, models.EventPrepaymentInsufficient, orderSN, []int64{shopOwnerID}, map[string]interface{}{
+			"shop_id":         shopID,
+			"order_sn":        orderSN,
+			"shop_owner_id":   shopOwnerID,
+			"currency":        currency,
+			"required_amount": orderAmount,
+			"balance":         account.Balance,
+			"shortage":        orderAmount.Sub(account.Balance),
+			"checked_at":      now,
+		})
 	})
 
 	if err != nil {
@@ -206,31 +244,38 @@ func (s *PrepaymentCheckService) CheckAndDeductForOrder(ctx context.Context, sho
 
 	// 通知在事务外发送（避免事务持有时间过长）
 	if needNotify {
-		s.notifyInsufficientBalance(ctx, shopID, shopOwnerID, orderSN, orderAmount, insufficientBalance, &now)
+		s.notifyInsufficientBalance(ctx, shopID, shopOwnerID, orderSN, currency, orderAmount, insufficientBalance, &now)
 	}
 
 	return nil
 }
 
-// BackfillInsufficientOrders 充值后补扣历史「预付款不足」订单
+// BackfillInsufficientOrders 充值后补扣历史「预付款不足」「保证金不足」订单
 //
-// 调用时机：店主充值预付款成功后
+// 调用时机：店主充值预付款或补缴保证金成功后
 //
 // 流程：
-//  1. 查找该店主名下所有店铺
-//  2. 对每个店铺，在对应分表中查出 prepayment_status=2 且 order_status=READY_TO_SHIP 的订单
-//  3. 按订单创建时间升序，逐笔尝试冻结预付款
+//  1. 保证金仍不足时不补扣；否则查找该店主名下所有店铺
+//  2. 对每个店铺，先把「保证金不足」订单转为「预付款不足」，再查出 prepayment_status=2 且 order_status=READY_TO_SHIP 的订单
+//  3. 按订单创建时间升序，逐笔尝试从订单币种的预付款子账户冻结
 //  4. 每笔订单在独立事务中：行锁订单行 -> CAS检查 -> 冻结预付款 -> 更新订单状态
-//  5. 余额不足时停止（后续订单也不可能够）
+//  5. 某币种余额不足时停止该币种的补扣（后续订单也不可能够），其他币种继续
 //
 // 并发安全：
 //   - 订单行使用 SELECT ... FOR UPDATE 行锁，防止同一订单被重复补扣
 //   - 预付款账户在 FreezePrepayment 内部也有 FOR UPDATE 行锁
 //   - 两者组合保证补扣的原子性和幂等性
 func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context, adminID int64) (successCount int, failCount int, err error) {
-	// 第 1 步：查找该店主名下所有店铺
+	// 第 1 步：保证金不足时不补扣，再查找该店主名下所有店铺
+	depositShort, _, err := s.accountService.IsDepositShort(ctx, adminID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("查询保证金账户失败: %w", err)
+	}
+	if depositShort {
+		return 0, 0, nil
+	}
 	var shops []models.Shop
-	if err := s.db.Select("shop_id").Where("admin_id = ?", adminID).Find(&shops).Error; err != nil {
+	if err := s.db.Select("shop_id", "currency").Where("admin_id = ?", adminID).Find(&shops).Error; err != nil {
 		return 0, 0, fmt.Errorf("查询店主店铺失败: %w", err)
 	}
 	if len(shops) == 0 {
@@ -242,14 +287,22 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 		ShopID     uint64
 		OrderSN    string
 		TableName  string
+		Currency   string
 	}
 	var pendingOrders []insufficientOrder
 
 	for _, shop := range shops {
 		tableName := database.GetOrderTableName(shop.ShopID)
+		// 保证金已补足：暂停入系统的订单按「预付款不足」补扣
+		if err := s.db.Table(tableName).
+			Where("shop_id = ? AND prepayment_status = ?", shop.ShopID, models.PrepaymentDepositShort).
+			Update("prepayment_status", models.PrepaymentInsufficient).Error; err != nil {
+			fmt.Printf("[BackfillPrepayment] 店铺 %d 恢复保证金不足订单失败: %v\n", shop.ShopID, err)
+			continue
+		}
 		var orders []models.Order
 		if err := s.db.Table(tableName).
-			Select("shop_id", "order_sn").
+			Select("shop_id", "order_sn", "currency").
 			Where("shop_id = ? AND prepayment_status = ? AND order_status = ?",
 				shop.ShopID, models.PrepaymentInsufficient, "READY_TO_SHIP").
 			Order("create_time ASC").
@@ -262,6 +315,7 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 				ShopID:    o.ShopID,
 				OrderSN:   o.OrderSN,
 				TableName: tableName,
+				Currency:  orderCurrency(o.Currency, shop.Currency),
 			})
 		}
 	}
@@ -270,14 +324,19 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 		return 0, 0, nil
 	}
 
-	// 第 3 步：逐笔补扣
+	// 第 3 步：逐笔补扣（按币种独立判断余额是否耗尽）
+	exhausted := make(map[string]bool)
 	for _, po := range pendingOrders {
+		if exhausted[po.Currency] {
+			failCount++
+			continue
+		}
 		deductErr := s.db.Transaction(func(tx *gorm.DB) error {
 			// 行锁订单行，防止并发补扣同一订单
 			var lockedOrder models.Order
 			if err := tx.Table(po.TableName).
 				Clauses(clause.Locking{Strength: "UPDATE"}).
-				Select("id", "prepayment_status", "total_amount", "prepayment_amount").
+				Select("id", "prepayment_status", "total_amount", "prepayment_amount", "currency").
 				Where("shop_id = ? AND order_sn = ?", po.ShopID, po.OrderSN).
 				First(&lockedOrder).Error; err != nil {
 				return fmt.Errorf("锁定订单失败: %w", err)
@@ -295,8 +354,8 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 			}
 
 			// 冻结预付款（使用 InTx 版本，复用外层事务连接，避免连接池死锁）
-			_, freezeErr := s.accountService.FreezePrepaymentInTx(tx, ctx, adminID, orderAmount, po.OrderSN,
-				fmt.Sprintf("订单预付款补扣(充值后)-订单%s", po.OrderSN))
+			_, freezeErr := s.accountService.FreezePrepaymentInTx(tx, ctx, adminID, orderAmount, po.Currency, po.OrderSN,
+				fmt.Sprintf("订单预付款补扣(充值后)-订单%s", po.OrderSN), OrderFreezeIdempotencyKey(po.ShopID, po.OrderSN))
 			if freezeErr != nil {
 				return freezeErr // 余额不足或其他错误
 			}
@@ -304,7 +363,7 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 			// 冻结成功，获取最新余额快照（在事务内读取）
 			var acct models.PrepaymentAccount
 			var balanceAfter decimal.Decimal
-			if err := tx.Where("admin_id = ?", adminID).First(&acct).Error; err == nil {
+			if err := tx.Where("admin_id = ? AND currency = ?", adminID, po.Currency).First(&acct).Error; err == nil {
 				balanceAfter = acct.Balance
 			}
 
@@ -324,10 +383,11 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 		})
 
 		if deductErr != nil {
-			// 如果是余额不足，停止后续补扣（余额只会越来越少）
-			fmt.Printf("[BackfillPrepayment] 订单 %s 补扣失败: %v，停止后续补扣\n", po.OrderSN, deductErr)
-			failCount += len(pendingOrders) - successCount - failCount
-			break
+			// 如果是余额不足，停止该币种后续补扣（余额只会越来越少）
+			fmt.Printf("[BackfillPrepayment] 订单 %s 补扣失败: %v，停止 %s 后续补扣\n", po.OrderSN, deductErr, po.Currency)
+			exhausted[po.Currency] = true
+			failCount++
+			continue
 		}
 		successCount++
 	}
@@ -335,8 +395,32 @@ func (s *PrepaymentCheckService) BackfillInsufficientOrders(ctx context.Context,
 	return successCount, failCount, nil
 }
 
+// CheckShopBinding 绑定新店铺前检查店主保证金（保证金不足或账户暂停时不允许绑定）
+func (s *PrepaymentCheckService) CheckShopBinding(ctx context.Context, adminID int64) error {
+	short, account, err := s.accountService.IsDepositShort(ctx, adminID)
+	if err != nil {
+		return fmt.Errorf("查询保证金账户失败: %w", err)
+	}
+	if short {
+		if account.Status == models.DepositStatusSuspended {
+			return fmt.Errorf("保证金账户已暂停，暂不能绑定新店铺")
+		}
+		return fmt.Errorf("保证金不足（余额 %s，应缴 %s %s），请先补缴保证金再绑定新店铺",
+			account.Balance.StringFixed(2), account.RequiredAmount.StringFixed(2), account.Currency)
+	}
+	return nil
+}
+
+// NewShopServiceWithDepositCheck 创建带保证金检查的店铺服务（绑定新店铺前检查店主保证金）
+func NewShopServiceWithDepositCheck() *shopower.ShopService {
+	svc := shopower.NewShopService()
+	checkSvc := NewPrepaymentCheckService()
+	svc.SetBindCheckFunc(checkSvc.CheckShopBinding)
+	return svc
+}
+
 // notifyInsufficientBalance 发送预付款不足通知（带冷却时间去重）
-func (s *PrepaymentCheckService) notifyInsufficientBalance(ctx context.Context, shopID uint64, shopOwnerID int64, orderSN string, required, available decimal.Decimal, now *time.Time) {
+func (s *PrepaymentCheckService) notifyInsufficientBalance(ctx context.Context, shopID uint64, shopOwnerID int64, orderSN string, currency string, required, available decimal.Decimal, now *time.Time) {
 	rdb := database.GetRedis()
 	notifyKey := fmt.Sprintf(consts.KeyPrepaymentNotified, shopID)
 
@@ -355,8 +439,8 @@ func (s *PrepaymentCheckService) notifyInsufficientBalance(ctx context.Context,
 
 	title := fmt.Sprintf("【预付款不足】%s", shopName)
 	content := fmt.Sprintf(
-		"您的店铺「%s」有新订单 %s 进入待发货状态，订单金额 %s，但预付款余额仅 %s，不足以覆盖此订单。请尽快充值预付款以确保正常发货。",
-		shopName, orderSN, required.StringFixed(2), available.StringFixed(2),
+		"您的店铺「%s」有新订单 %s 进入待发货状态，订单金额 %s %s，但 %s 预付款余额仅 %s，不足以覆盖此订单。请尽快充值预付款以确保正常发货。",
+		shopName, orderSN, required.StringFixed(2), currency, currency, available.StringFixed(2),
 	)
 
 	notifyID, _ := s.idGenerator.GenerateNotificationID(ctx)
@@ -372,3 +456,11 @@ func (s *PrepaymentCheckService) notifyInsufficientBalance(ctx context.Context,
 	}
 	s.db.Create(notification)
 }
+
+// orderCurrency 订单扣款/结算使用的币种：优先订单币种，其次店铺币种
+func orderCurrency(orderCurrency, shopCurrency string) string {
+	if orderCurrency != "" {
+		return NormalizeCurrency(orderCurrency)
+	}
+	return NormalizeCurrency(shopCurrency)
+}
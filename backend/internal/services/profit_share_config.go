@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultProfitShareConfig 未配置分成时使用的默认比例（平台 5% / 运营 45% / 店主 50%）
+func DefaultProfitShareConfig(shopID uint64, operatorID int64) *models.ProfitShareConfig {
+	return &models.ProfitShareConfig{
+		ShopID:             shopID,
+		OperatorID:         operatorID,
+		PlatformShareRate:  decimal.NewFromFloat(5),
+		OperatorShareRate:  decimal.NewFromFloat(45),
+		ShopOwnerShareRate: decimal.NewFromFloat(50),
+		Status:             models.ProfitShareConfigActive,
+	}
+}
+
+// ValidateProfitShareRates 校验分成比例：均不为负且总和为 100%
+func ValidateProfitShareRates(platformRate, operatorRate, shopOwnerRate decimal.Decimal) error {
+	if platformRate.IsNegative() || operatorRate.IsNegative() || shopOwnerRate.IsNegative() {
+		return fmt.Errorf("分成比例不能为负数")
+	}
+	total := platformRate.Add(operatorRate).Add(shopOwnerRate)
+	if !total.Equal(decimal.NewFromInt(100)) {
+		return fmt.Errorf("分成比例总和必须为100%%，当前为%s%%", total.String())
+	}
+	return nil
+}
+
+// getProfitShareConfigAt 获取指定时间生效的分成配置版本
+// 早于首个版本生效时间的订单（合作建立前已支付）按首个版本结算，没有任何版本时返回默认比例
+func (s *SettlementService) getProfitShareConfigAt(db *gorm.DB, shopID uint64, operatorID int64, at time.Time) (*models.ProfitShareConfig, error) {
+	var config models.ProfitShareConfig
+	err := db.Where("shop_id = ? AND operator_id = ? AND status = ?", shopID, operatorID, models.ProfitShareConfigActive).
+		Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", at, at).
+		Order("effective_from DESC").
+		First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		err = db.Where("shop_id = ? AND operator_id = ? AND status = ?", shopID, operatorID, models.ProfitShareConfigActive).
+			Order("effective_from ASC").
+			First(&config).Error
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		return DefaultProfitShareConfig(shopID, operatorID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetEffectiveProfitShareConfig 获取指定时间生效的分成配置（at 为零值时取当前）
+func (s *SettlementService) GetEffectiveProfitShareConfig(ctx context.Context, shopID uint64, operatorID int64, at time.Time) (*models.ProfitShareConfig, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return s.getProfitShareConfigAt(s.db, shopID, operatorID, at)
+}
+
+// profitShareEffectiveTime 订单取分成配置的时间点：支付时间，缺失时依次取发货时间、当前时间
+func (s *SettlementService) profitShareEffectiveTime(db *gorm.DB, record *models.OrderShipmentRecord) time.Time {
+	var order models.Order
+	if err := db.Table(database.GetOrderTableName(record.ShopID)).Select("pay_time").
+		Where("shop_id = ? AND order_sn = ?", record.ShopID, record.OrderSN).
+		First(&order).Error; err == nil && order.PayTime != nil {
+		return *order.PayTime
+	}
+	if record.ShippedAt != nil {
+		return *record.ShippedAt
+	}
+	return time.Now()
+}
+
+// CreateProfitShareConfig 创建立即生效的分成配置版本
+func (s *SettlementService) CreateProfitShareConfig(ctx context.Context, config *models.ProfitShareConfig) error {
+	config.EffectiveFrom = time.Now()
+	return s.ScheduleProfitShareConfig(ctx, config)
+}
+
+// ScheduleProfitShareConfig 新增分成配置版本（生效时间为空表示立即生效，不允许早于当前时间以免追溯已支付订单）
+// 同一店铺+运营的版本按生效时间首尾相接，每个版本的失效时间为下一版本的生效时间
+func (s *SettlementService) ScheduleProfitShareConfig(ctx context.Context, config *models.ProfitShareConfig) error {
+	if err := ValidateProfitShareRates(config.PlatformShareRate, config.OperatorShareRate, config.ShopOwnerShareRate); err != nil {
+		return err
+	}
+	now := time.Now()
+	if config.EffectiveFrom.IsZero() {
+		config.EffectiveFrom = now
+	}
+	if config.EffectiveFrom.Before(now.Add(-time.Minute)) {
+		return fmt.Errorf("生效时间不能早于当前时间，已支付订单按原分成比例结算")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定合作关系，串行化同一店铺+运营的版本变更
+		var relation models.ShopOperatorRelation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("shop_id = ? AND operator_id = ?", config.ShopID, config.OperatorID).
+			First(&relation).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("合作关系不存在")
+			}
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.ProfitShareConfig{}).
+			Where("shop_id = ? AND operator_id = ? AND status = ? AND effective_from = ?",
+				config.ShopID, config.OperatorID, models.ProfitShareConfigActive, config.EffectiveFrom).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("该生效时间已有分成配置版本")
+		}
+
+		var maxVersion int
+		if err := tx.Model(&models.ProfitShareConfig{}).
+			Where("shop_id = ? AND operator_id = ?", config.ShopID, config.OperatorID).
+			Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+
+		id, err := s.idGen.GenerateProfitShareConfigID(ctx)
+		if err != nil {
+			return fmt.Errorf("生成分成配置ID失败: %w", err)
+		}
+		config.ID = uint64(id)
+		config.Version = maxVersion + 1
+		config.Status = models.ProfitShareConfigActive
+		config.EffectiveTo = nil
+		if err := tx.Create(config).Error; err != nil {
+			return err
+		}
+		return s.relinkProfitShareVersionsInTx(tx, config.ShopID, config.OperatorID)
+	})
+}
+
+// CancelProfitShareConfig 撤销尚未生效的分成配置版本（已生效的版本不可撤销，只能排期新版本覆盖）
+func (s *SettlementService) CancelProfitShareConfig(ctx context.Context, configID uint64) (*models.ProfitShareConfig, error) {
+	var config models.ProfitShareConfig
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&config, configID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("分成配置不存在")
+			}
+			return err
+		}
+		var relation models.ShopOperatorRelation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("shop_id = ? AND operator_id = ?", config.ShopID, config.OperatorID).
+			First(&relation).Error; err != nil {
+			return fmt.Errorf("合作关系不存在")
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&config, configID).Error; err != nil {
+			return err
+		}
+		if config.Status != models.ProfitShareConfigActive {
+			return fmt.Errorf("分成配置已撤销")
+		}
+		if !config.EffectiveFrom.After(time.Now()) {
+			return fmt.Errorf("分成配置已生效，不能撤销")
+		}
+
+		config.Status = models.ProfitShareConfigCancelled
+		config.EffectiveTo = nil
+		if err := tx.Save(&config).Error; err != nil {
+			return err
+		}
+		return s.relinkProfitShareVersionsInTx(tx, config.ShopID, config.OperatorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// relinkProfitShareVersionsInTx 按生效时间重排有效版本的失效时间（事务参与版本，调用方需已锁定合作关系）
+func (s *SettlementService) relinkProfitShareVersionsInTx(db *gorm.DB, shopID uint64, operatorID int64) error {
+	var versions []models.ProfitShareConfig
+	if err := db.Where("shop_id = ? AND operator_id = ? AND status = ?", shopID, operatorID, models.ProfitShareConfigActive).
+		Order("effective_from ASC").Find(&versions).Error; err != nil {
+		return err
+	}
+	for i := range versions {
+		var effectiveTo *time.Time
+		if i+1 < len(versions) {
+			next := versions[i+1].EffectiveFrom
+			effectiveTo = &next
+		}
+		current := versions[i].EffectiveTo
+		if (current == nil && effectiveTo == nil) || (current != nil && effectiveTo != nil && current.Equal(*effectiveTo)) {
+			continue
+		}
+		if err := db.Model(&models.ProfitShareConfig{}).Where("id = ?", versions[i].ID).
+			Update("effective_to", effectiveTo).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProfitShareConfigHistory 获取店铺+运营的全部分成配置版本（含已撤销，按生效时间倒序）
+func (s *SettlementService) GetProfitShareConfigHistory(ctx context.Context, shopID uint64, operatorID int64) ([]models.ProfitShareConfig, error) {
+	var configs []models.ProfitShareConfig
+	err := s.db.Where("shop_id = ? AND operator_id = ?", shopID, operatorID).
+		Order("effective_from DESC, version DESC").
+		Find(&configs).Error
+	return configs, err
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProfitShareAllocation 三方分成金额（结算币种）
+type ProfitShareAllocation struct {
+	PlatformShare  decimal.Decimal `json:"platform_share"`
+	OperatorShare  decimal.Decimal `json:"operator_share"`
+	ShopOwnerShare decimal.Decimal `json:"shop_owner_share"`
+}
+
+// AllocateProfit 按分成配置与分成规则计算三方分成
+//
+// rule 为 nil 时沿用配置比例直接分成（负利润也按比例分摊）；有规则时：
+//   - 利润为正：按分档超额累进计算平台/运营分成，平台再加每单固定费用并按保底/封顶调整（不超过利润），
+//     平台分成的调整额由运营与店主按各自分成占比承担
+//   - 利润为负：按规则的亏损承担方分配，不收取固定费用和保底
+//
+// 平台与运营分成四舍五入到分，店主分成取余数，保证三方合计等于利润
+func AllocateProfit(profit decimal.Decimal, config *models.ProfitShareConfig, rule *models.ProfitShareRule) (ProfitShareAllocation, error) {
+	if rule == nil {
+		return splitByRates(profit, config.PlatformShareRate, config.OperatorShareRate), nil
+	}
+
+	tiers := rule.TierList
+	if len(tiers) == 0 {
+		tiers = []models.ProfitShareTier{{
+			PlatformShareRate:  config.PlatformShareRate,
+			OperatorShareRate:  config.OperatorShareRate,
+			ShopOwnerShareRate: config.ShopOwnerShareRate,
+		}}
+	}
+
+	if profit.IsNegative() {
+		return allocateLoss(profit, tiers[0], rule.LossPolicy)
+	}
+
+	// 1. 分档累进：每档只对落在本档区间内的利润部分按本档比例分成
+	hundred := decimal.NewFromInt(100)
+	platform, operator := decimal.Zero, decimal.Zero
+	remaining, lower := profit, decimal.Zero
+	for _, tier := range tiers {
+		if !remaining.IsPositive() {
+			break
+		}
+		portion := remaining
+		if !tier.UpTo.IsZero() {
+			if width := tier.UpTo.Sub(lower); portion.GreaterThan(width) {
+				portion = width
+			}
+			lower = tier.UpTo
+		}
+		platform = platform.Add(portion.Mul(tier.PlatformShareRate).Div(hundred))
+		operator = operator.Add(portion.Mul(tier.OperatorShareRate).Div(hundred))
+		remaining = remaining.Sub(portion)
+	}
+	platform = platform.Round(2)
+	operator = operator.Round(2)
+	shopOwner := profit.Sub(platform).Sub(operator)
+
+	// 2. 平台固定费用 + 保底/封顶，平台分成不超过利润
+	finalPlatform := platform.Add(rule.FixedPlatformFee)
+	if finalPlatform.LessThan(rule.MinPlatformFee) {
+		finalPlatform = rule.MinPlatformFee
+	}
+	if rule.MaxPlatformFee.IsPositive() && finalPlatform.GreaterThan(rule.MaxPlatformFee) {
+		finalPlatform = rule.MaxPlatformFee
+	}
+	if finalPlatform.GreaterThan(profit) {
+		finalPlatform = profit
+	}
+
+	// 3. 平台分成调整额由运营与店主按分成占比承担
+	if delta := finalPlatform.Sub(platform); !delta.IsZero() {
+		if base := operator.Add(shopOwner); base.IsPositive() {
+			operator = operator.Sub(delta.Mul(operator).Div(base).Round(2))
+		}
+		platform = finalPlatform
+		shopOwner = profit.Sub(platform).Sub(operator)
+	}
+
+	return ProfitShareAllocation{PlatformShare: platform, OperatorShare: operator, ShopOwnerShare: shopOwner}, nil
+}
+
+// AllocateAdjustment 计算一笔调账的三方分摊：profit 为调账前的利润（原结算利润+已发生的调账）
+// 无规则时按配置比例分摊调账金额；有规则时按调账前后利润分别分成取差额，分档、固定费用、保底/封顶和亏损承担方同样适用于调账
+func AllocateAdjustment(profit, amount decimal.Decimal, config *models.ProfitShareConfig, rule *models.ProfitShareRule) (ProfitShareAllocation, error) {
+	if rule == nil {
+		return splitByRates(amount, config.PlatformShareRate, config.OperatorShareRate), nil
+	}
+	before, err := AllocateProfit(profit, config, rule)
+	if err != nil {
+		return ProfitShareAllocation{}, err
+	}
+	after, err := AllocateProfit(profit.Add(amount), config, rule)
+	if err != nil {
+		return ProfitShareAllocation{}, err
+	}
+	return ProfitShareAllocation{
+		PlatformShare:  after.PlatformShare.Sub(before.PlatformShare),
+		OperatorShare:  after.OperatorShare.Sub(before.OperatorShare),
+		ShopOwnerShare: after.ShopOwnerShare.Sub(before.ShopOwnerShare),
+	}, nil
+}
+
+// splitByRates 按平台/运营比例分成，店主取余数
+func splitByRates(profit, platformRate, operatorRate decimal.Decimal) ProfitShareAllocation {
+	hundred := decimal.NewFromInt(100)
+	platform := profit.Mul(platformRate).Div(hundred).Round(2)
+	operator := profit.Mul(operatorRate).Div(hundred).Round(2)
+	return ProfitShareAllocation{
+		PlatformShare:  platform,
+		OperatorShare:  operator,
+		ShopOwnerShare: profit.Sub(platform).Sub(operator),
+	}
+}
+
+// allocateLoss 按亏损承担方分配负利润（比例取首档，即最接近零利润的分档）
+func allocateLoss(loss decimal.Decimal, tier models.ProfitShareTier, policy string) (ProfitShareAllocation, error) {
+	switch policy {
+	case "", models.ProfitShareLossProportional:
+		return splitByRates(loss, tier.PlatformShareRate, tier.OperatorShareRate), nil
+	case models.ProfitShareLossOperator:
+		return ProfitShareAllocation{OperatorShare: loss}, nil
+	case models.ProfitShareLossShopOwner:
+		return ProfitShareAllocation{ShopOwnerShare: loss}, nil
+	case models.ProfitShareLossPlatform:
+		return ProfitShareAllocation{PlatformShare: loss}, nil
+	case models.ProfitShareLossOperatorShopOwner:
+		base := tier.OperatorShareRate.Add(tier.ShopOwnerShareRate)
+		if !base.IsPositive() {
+			return ProfitShareAllocation{ShopOwnerShare: loss}, nil
+		}
+		operator := loss.Mul(tier.OperatorShareRate).Div(base).Round(2)
+		return ProfitShareAllocation{OperatorShare: operator, ShopOwnerShare: loss.Sub(operator)}, nil
+	default:
+		return ProfitShareAllocation{}, fmt.Errorf("不支持的亏损承担方: %s", policy)
+	}
+}
+
+// ValidateProfitShareRule 校验分成规则：分档上限递增且仅最后一档可不封顶，每档比例合计100%，费用不为负
+func ValidateProfitShareRule(rule *models.ProfitShareRule) error {
+	lower := decimal.Zero
+	for i, tier := range rule.TierList {
+		if err := ValidateProfitShareRates(tier.PlatformShareRate, tier.OperatorShareRate, tier.ShopOwnerShareRate); err != nil {
+			return fmt.Errorf("第%d档: %w", i+1, err)
+		}
+		if tier.UpTo.IsZero() {
+			if i != len(rule.TierList)-1 {
+				return fmt.Errorf("第%d档: 只有最后一档可以不设利润上限", i+1)
+			}
+			continue
+		}
+		if !tier.UpTo.GreaterThan(lower) {
+			return fmt.Errorf("第%d档: 利润上限必须大于上一档", i+1)
+		}
+		lower = tier.UpTo
+	}
+
+	if rule.MinPlatformFee.IsNegative() || rule.MaxPlatformFee.IsNegative() || rule.FixedPlatformFee.IsNegative() {
+		return fmt.Errorf("平台费用不能为负数")
+	}
+	if rule.MaxPlatformFee.IsPositive() && rule.MaxPlatformFee.LessThan(rule.MinPlatformFee) {
+		return fmt.Errorf("平台最高分成不能低于最低分成")
+	}
+
+	switch rule.LossPolicy {
+	case models.ProfitShareLossProportional, models.ProfitShareLossOperator, models.ProfitShareLossShopOwner,
+		models.ProfitShareLossPlatform, models.ProfitShareLossOperatorShopOwner:
+	default:
+		return fmt.Errorf("不支持的亏损承担方: %s", rule.LossPolicy)
+	}
+	return nil
+}
+
+// loadProfitShareRuleTiers 解析规则的分档 JSON
+func loadProfitShareRuleTiers(rule *models.ProfitShareRule) error {
+	rule.TierList = []models.ProfitShareTier{}
+	if rule.Tiers == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(rule.Tiers), &rule.TierList); err != nil {
+		return fmt.Errorf("解析分成规则分档失败: %w", err)
+	}
+	return nil
+}
+
+// profitShareRuleSnapshot 结算记录保存的规则快照（含分档），无规则时为空
+func profitShareRuleSnapshot(rule *models.ProfitShareRule) string {
+	if rule == nil {
+		return ""
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// parseProfitShareRuleSnapshot 解析结算记录保存的规则快照，快照为空（未使用规则）时返回 nil
+func parseProfitShareRuleSnapshot(snapshot string) (*models.ProfitShareRule, error) {
+	if snapshot == "" {
+		return nil, nil
+	}
+	var rule models.ProfitShareRule
+	if err := json.Unmarshal([]byte(snapshot), &rule); err != nil {
+		return nil, fmt.Errorf("解析分成规则快照失败: %w", err)
+	}
+	return &rule, nil
+}
+
+// getProfitShareRuleAt 获取店铺+运营在指定时间生效的分成规则（含之后已停用的规则），没有规则时返回 nil
+// 规则生效区间为 [创建时间, 停用时间)，规则调整不追溯已支付的订单
+func (s *SettlementService) getProfitShareRuleAt(db *gorm.DB, shopID uint64, operatorID int64, at time.Time) (*models.ProfitShareRule, error) {
+	var rule models.ProfitShareRule
+	err := db.Where("shop_id = ? AND operator_id = ?", shopID, operatorID).
+		Where("created_at <= ? AND (disabled_at IS NULL OR disabled_at > ?)", at, at).
+		Order("created_at DESC, id DESC").
+		First(&rule).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := loadProfitShareRuleTiers(&rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// getProfitShareRule 按ID获取分成规则（须属于指定店铺+运营）
+func (s *SettlementService) getProfitShareRule(db *gorm.DB, ruleID uint64, shopID uint64, operatorID int64) (*models.ProfitShareRule, error) {
+	var rule models.ProfitShareRule
+	if err := db.Where("id = ? AND shop_id = ? AND operator_id = ?", ruleID, shopID, operatorID).
+		First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("分成规则不存在")
+		}
+		return nil, err
+	}
+	if err := loadProfitShareRuleTiers(&rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateProfitShareRule 为合作关系设置新的分成规则（原生效规则停用，之后支付的订单按新规则分成）
+func (s *SettlementService) CreateProfitShareRule(ctx context.Context, relationID uint64, rule *models.ProfitShareRule) error {
+	if rule.LossPolicy == "" {
+		rule.LossPolicy = models.ProfitShareLossProportional
+	}
+	if err := ValidateProfitShareRule(rule); err != nil {
+		return err
+	}
+	tiers, err := json.Marshal(rule.TierList)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定合作关系，串行化同一合作的规则变更
+		var relation models.ShopOperatorRelation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&relation, relationID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("合作关系不存在")
+			}
+			return err
+		}
+		if err := s.disableProfitShareRulesInTx(tx, relation.ShopID, relation.OperatorID); err != nil {
+			return err
+		}
+
+		id, err := s.idGen.GenerateProfitShareRuleID(ctx)
+		if err != nil {
+			return fmt.Errorf("生成分成规则ID失败: %w", err)
+		}
+		rule.ID = uint64(id)
+		rule.RelationID = relation.ID
+		rule.ShopID = relation.ShopID
+		rule.OperatorID = relation.OperatorID
+		rule.Tiers = string(tiers)
+		rule.Status = models.ProfitShareRuleActive
+		rule.DisabledAt = nil
+		return tx.Create(rule).Error
+	})
+}
+
+// DisableProfitShareRule 停用合作关系当前的分成规则（之后支付的订单按分成配置比例直接分成）
+func (s *SettlementService) DisableProfitShareRule(ctx context.Context, relationID uint64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var relation models.ShopOperatorRelation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&relation, relationID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("合作关系不存在")
+			}
+			return err
+		}
+		return s.disableProfitShareRulesInTx(tx, relation.ShopID, relation.OperatorID)
+	})
+}
+
+// disableProfitShareRulesInTx 停用店铺+运营所有生效中的规则
+func (s *SettlementService) disableProfitShareRulesInTx(tx *gorm.DB, shopID uint64, operatorID int64) error {
+	now := time.Now()
+	return tx.Model(&models.ProfitShareRule{}).
+		Where("shop_id = ? AND operator_id = ? AND status = ?", shopID, operatorID, models.ProfitShareRuleActive).
+		Updates(map[string]interface{}{
+			"status":      models.ProfitShareRuleDisabled,
+			"disabled_at": &now,
+		}).Error
+}
+
+// GetProfitShareRules 获取合作关系的分成规则历史（按创建时间倒序）
+func (s *SettlementService) GetProfitShareRules(ctx context.Context, relationID uint64) ([]models.ProfitShareRule, error) {
+	var rules []models.ProfitShareRule
+	if err := s.db.Where("relation_id = ?", relationID).Order("id DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if err := loadProfitShareRuleTiers(&rules[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAllocateProfit(t *testing.T) {
+	d := decimal.RequireFromString
+	config := &models.ProfitShareConfig{
+		PlatformShareRate:  d("5"),
+		OperatorShareRate:  d("45"),
+		ShopOwnerShareRate: d("50"),
+	}
+	tiers := []models.ProfitShareTier{
+		{UpTo: d("100"), PlatformShareRate: d("10"), OperatorShareRate: d("40"), ShopOwnerShareRate: d("50")},
+		{PlatformShareRate: d("20"), OperatorShareRate: d("30"), ShopOwnerShareRate: d("50")},
+	}
+
+	tests := []struct {
+		name                          string
+		profit                        string
+		rule                          *models.ProfitShareRule
+		platform, operator, shopOwner string
+	}{
+		{"无规则按比例", "100", nil, "5", "45", "50"},
+		{"无规则负利润按比例分摊", "-100", nil, "-5", "-45", "-50"},
+		{"规则无分档按配置比例", "100", &models.ProfitShareRule{LossPolicy: models.ProfitShareLossProportional}, "5", "45", "50"},
+		// 100 内 10/40/50，超出部分 100 按 20/30/50
+		{"分档累进", "200", &models.ProfitShareRule{TierList: tiers}, "30", "70", "100"},
+		{"分档首档内", "50", &models.ProfitShareRule{TierList: tiers}, "5", "20", "25"},
+		// 平台 5 + 固定 3 = 8，增加的 3 由运营/店主按 45:50 承担
+		{"固定费用", "100", &models.ProfitShareRule{FixedPlatformFee: d("3")}, "8", "43.58", "48.42"},
+		{"保底", "100", &models.ProfitShareRule{MinPlatformFee: d("10")}, "10", "42.63", "47.37"},
+		{"保底不超过利润", "6", &models.ProfitShareRule{MinPlatformFee: d("10")}, "6", "0", "0"},
+		{"封顶", "1000", &models.ProfitShareRule{MaxPlatformFee: d("20")}, "20", "464.21", "515.79"},
+		{"亏损运营承担", "-80", &models.ProfitShareRule{LossPolicy: models.ProfitShareLossOperator, MinPlatformFee: d("10")}, "0", "-80", "0"},
+		{"亏损店主承担", "-80", &models.ProfitShareRule{LossPolicy: models.ProfitShareLossShopOwner}, "0", "0", "-80"},
+		{"亏损平台承担", "-80", &models.ProfitShareRule{LossPolicy: models.ProfitShareLossPlatform}, "-80", "0", "0"},
+		{"亏损运营店主分摊", "-95", &models.ProfitShareRule{LossPolicy: models.ProfitShareLossOperatorShopOwner}, "0", "-45", "-50"},
+		{"亏损按首档比例分摊", "-100", &models.ProfitShareRule{TierList: tiers, LossPolicy: models.ProfitShareLossProportional}, "-10", "-40", "-50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profit := d(tt.profit)
+			got, err := AllocateProfit(profit, config, tt.rule)
+			if err != nil {
+				t.Fatalf("AllocateProfit: %v", err)
+			}
+			if !got.PlatformShare.Equal(d(tt.platform)) || !got.OperatorShare.Equal(d(tt.operator)) || !got.ShopOwnerShare.Equal(d(tt.shopOwner)) {
+				t.Fatalf("got platform=%s operator=%s shop_owner=%s, want %s/%s/%s",
+					got.PlatformShare, got.OperatorShare, got.ShopOwnerShare, tt.platform, tt.operator, tt.shopOwner)
+			}
+			if sum := got.PlatformShare.Add(got.OperatorShare).Add(got.ShopOwnerShare); !sum.Equal(profit) {
+				t.Fatalf("shares sum %s != profit %s", sum, profit)
+			}
+		})
+	}
+}
+
+func TestValidateProfitShareRule(t *testing.T) {
+	d := decimal.RequireFromString
+	valid := []models.ProfitShareTier{
+		{UpTo: d("100"), PlatformShareRate: d("10"), OperatorShareRate: d("40"), ShopOwnerShareRate: d("50")},
+		{PlatformShareRate: d("20"), OperatorShareRate: d("30"), ShopOwnerShareRate: d("50")},
+	}
+	if err := ValidateProfitShareRule(&models.ProfitShareRule{TierList: valid, LossPolicy: models.ProfitShareLossOperator}); err != nil {
+		t.Fatalf("valid rule rejected: %v", err)
+	}
+
+	invalid := []*models.ProfitShareRule{
+		{TierList: []models.ProfitShareTier{valid[1], valid[0]}, LossPolicy: models.ProfitShareLossOperator},
+		{TierList: []models.ProfitShareTier{{UpTo: d("100"), PlatformShareRate: d("10"), OperatorShareRate: d("40"), ShopOwnerShareRate: d("40")}}, LossPolicy: models.ProfitShareLossOperator},
+		{MinPlatformFee: d("20"), MaxPlatformFee: d("10"), LossPolicy: models.ProfitShareLossOperator},
+		{FixedPlatformFee: d("-1"), LossPolicy: models.ProfitShareLossOperator},
+		{LossPolicy: "nobody"},
+	}
+	for i, rule := range invalid {
+		if err := ValidateProfitShareRule(rule); err == nil {
+			t.Fatalf("invalid rule %d accepted", i)
+		}
+	}
+}
+
+func TestAllocateAdjustment(t *testing.T) {
+	d := decimal.RequireFromString
+	config := &models.ProfitShareConfig{
+		PlatformShareRate:  d("5"),
+		OperatorShareRate:  d("45"),
+		ShopOwnerShareRate: d("50"),
+	}
+	tiers := []models.ProfitShareTier{
+		{UpTo: d("100"), PlatformShareRate: d("10"), OperatorShareRate: d("40"), ShopOwnerShareRate: d("50")},
+		{PlatformShareRate: d("20"), OperatorShareRate: d("30"), ShopOwnerShareRate: d("50")},
+	}
+
+	tests := []struct {
+		name                          string
+		profit, amount                string
+		rule                          *models.ProfitShareRule
+		platform, operator, shopOwner string
+	}{
+		{"无规则按比例", "100", "-20", nil, "-1", "-9", "-10"},
+		// 调账前 80 全在首档 8/32/40，调账后 120 为 14/46/60
+		{"跨档补款", "80", "40", &models.ProfitShareRule{TierList: tiers}, "6", "14", "20"},
+		// 调账前 8/43.58/48.42（含固定费用），调账后亏损 50 由运营承担
+		{"扣款转为亏损", "100", "-150", &models.ProfitShareRule{FixedPlatformFee: d("3"), LossPolicy: models.ProfitShareLossOperator}, "-8", "-93.58", "-48.42"},
+		{"亏损店主承担", "-80", "-20", &models.ProfitShareRule{LossPolicy: models.ProfitShareLossShopOwner}, "0", "0", "-20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount := d(tt.amount)
+			got, err := AllocateAdjustment(d(tt.profit), amount, config, tt.rule)
+			if err != nil {
+				t.Fatalf("AllocateAdjustment: %v", err)
+			}
+			if !got.PlatformShare.Equal(d(tt.platform)) || !got.OperatorShare.Equal(d(tt.operator)) || !got.ShopOwnerShare.Equal(d(tt.shopOwner)) {
+				t.Fatalf("got platform=%s operator=%s shop_owner=%s, want %s/%s/%s",
+					got.PlatformShare, got.OperatorShare, got.ShopOwnerShare, tt.platform, tt.operator, tt.shopOwner)
+			}
+			if sum := got.PlatformShare.Add(got.OperatorShare).Add(got.ShopOwnerShare); !sum.Equal(amount) {
+				t.Fatalf("shares sum %s != amount %s", sum, amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/middleware"
+	"balance/backend/internal/models"
+	"balance/backend/internal/utils"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	onlineRechargePollBatchSize   = 200              // 每轮最多查询的线上充值数
+	onlineRechargeDefaultPoll     = 10 * time.Minute // 待支付超过该时长后主动查询网关
+	onlineRechargeDefaultExpire   = 60 * time.Minute // 超过该时长仍未支付则关闭
+	onlineRechargeMismatchRecheck = 6 * time.Hour    // 网关与账务不一致的申请等待人工核对，间隔该时长再查询
+	onlineRechargeExpiredRemark   = "线上支付超时未完成，已关闭"
+	onlineRechargeAutoApproveNote = "线上支付成功，自动入账"
+)
+
+// ErrRechargeAmountMismatch 网关实付金额或币种与充值申请不符
+var ErrRechargeAmountMismatch = errors.New("实付金额与充值申请不符，需人工核对")
+
+// OnlineRechargeMismatch 网关结果与账务不一致的线上充值
+type OnlineRechargeMismatch struct {
+	ApplicationNo string `json:"application_no"`
+	PaymentMethod string `json:"payment_method"`
+	PaymentID     string `json:"payment_id"`
+	Reason        string `json:"reason"`
+}
+
+// OnlineRechargePollResult 待支付线上充值轮询结果
+type OnlineRechargePollResult struct {
+	Checked    int                      `json:"checked"`    // 查询网关的待支付申请数
+	Approved   int                      `json:"approved"`   // 补单入账数
+	Closed     int                      `json:"closed"`     // 网关显示失败/取消而关闭的申请数
+	Expired    int                      `json:"expired"`    // 超时未支付而关闭的申请数
+	Failed     int                      `json:"failed"`     // 查询或处理失败数（下轮重试）
+	Mismatches []OnlineRechargeMismatch `json:"mismatches"` // 网关与账务不一致
+	Failures   []OnlineRechargeMismatch `json:"failures"`   // 查询或处理失败的申请及原因
+}
+
+// RechargeIdempotencyKey 充值申请入账的幂等键（线下审核、线上支付回调与补单轮询共用，同一申请只入账一次）
+func RechargeIdempotencyKey(applicationNo string) string {
+	return fmt.Sprintf("recharge:%s", applicationNo)
+}
+
+// CreateOnlineRecharge 创建线上充值（预付款）：先向支付网关下单，再保存充值申请，返回含支付链接的申请
+func (s *AccountService) CreateOnlineRecharge(ctx context.Context, adminID int64, amount decimal.Decimal, currency string, paymentMethod string, remark string) (*models.RechargeRecord, error) {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("充值金额必须大于0")
+	}
+	currency = NormalizeCurrency(currency)
+	if !utils.IsOnlinePayment(paymentMethod) {
+		return nil, fmt.Errorf("%s 不是线上支付方式", paymentMethod)
+	}
+	if !utils.IsPaymentMethodEnabled(paymentMethod) {
+		return nil, utils.ErrPaymentNotImplemented
+	}
+	gw, err := utils.GetPaymentGateway(paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	rcID, idErr := s.idGen.GenerateRechargeRecordID(ctx)
+	if idErr != nil {
+		return nil, fmt.Errorf("生成充值申请ID失败: %w", idErr)
+	}
+	applicationNo := s.GenerateApplicationNo("RC")
+
+	// 先下单再落库：下单失败不留下无效申请；落库失败时网关订单无人付款，到期自动失效
+	payment, err := gw.CreatePayment(ctx, &utils.CreatePaymentRequest{
+		OrderNo:     applicationNo,
+		Amount:      amount,
+		Currency:    currency,
+		Description: "预付款充值",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建支付订单失败: %w", err)
+	}
+
+	record := &models.RechargeRecord{
+		ID:            uint64(rcID),
+		ApplicationNo: applicationNo,
+		AdminID:       adminID,
+		AccountType:   models.AccountTypePrepayment,
+		Amount:        amount,
+		Currency:      currency,
+		PaymentMethod: paymentMethod,
+		PaymentID:     payment.PaymentID,
+		PaymentURL:    payment.PaymentURL,
+		Status:        models.ApplicationStatusPending,
+		Remark:        remark,
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// HandlePaymentNotification 处理支付网关回调：验签/核实后按支付结果处理对应的线上充值
+func (s *AccountService) HandlePaymentNotification(ctx context.Context, paymentMethod string, rawBody []byte) (*models.RechargeRecord, error) {
+	gw, err := utils.GetPaymentGateway(paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	notification, err := gw.VerifyNotification(ctx, rawBody)
+	if err != nil {
+		return nil, fmt.Errorf("支付回调验证失败: %w", err)
+	}
+	return s.SettleOnlineRecharge(ctx, paymentMethod, notification)
+}
+
+// SettleOnlineRecharge 按第三方支付结果处理线上充值（幂等，已处理的申请直接返回）：
+//   - 已授权：需要请款的网关先按申请金额请款
+//   - 已支付：核对金额币种后自动审核通过并入账预付款
+//   - 失败/取消：关闭申请（状态置为已拒绝）
+//   - 其余状态：保持待支付
+func (s *AccountService) SettleOnlineRecharge(ctx context.Context, paymentMethod string, n *utils.PaymentNotification) (*models.RechargeRecord, error) {
+	var record models.RechargeRecord
+	if err := s.db.WithContext(ctx).Where("application_no = ?", n.OrderNo).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("充值申请 %s 不存在", n.OrderNo)
+		}
+		return nil, err
+	}
+	if record.PaymentMethod != paymentMethod {
+		return nil, fmt.Errorf("充值申请 %s 的支付方式不是 %s", record.ApplicationNo, paymentMethod)
+	}
+	if record.PaymentID != "" && n.PaymentID != "" && record.PaymentID != n.PaymentID {
+		return nil, fmt.Errorf("充值申请 %s 的第三方支付单号不符", record.ApplicationNo)
+	}
+	if record.Status != models.ApplicationStatusPending {
+		return &record, nil
+	}
+
+	if n.Status == utils.PaymentStatusAuthorized {
+		gw, err := utils.GetPaymentGateway(paymentMethod)
+		if err != nil {
+			return nil, err
+		}
+		confirmer, ok := gw.(utils.PaymentConfirmer)
+		if !ok {
+			return nil, fmt.Errorf("支付方式 %s 不支持请款", paymentMethod)
+		}
+		if n, err = confirmer.ConfirmPayment(ctx, record.PaymentID, record.Amount, record.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	switch n.Status {
+	case utils.PaymentStatusPaid:
+		if !n.Amount.Equal(record.Amount) || (n.Currency != "" && n.Currency != record.Currency) {
+			return nil, fmt.Errorf("充值申请 %s 实付 %s %s，申请 %s %s: %w",
+				record.ApplicationNo, n.Amount.String(), n.Currency, record.Amount.String(), record.Currency, ErrRechargeAmountMismatch)
+		}
+		return s.approveOnlineRecharge(ctx, record.ID)
+	case utils.PaymentStatusFailed, utils.PaymentStatusCancelled:
+		return s.closeOnlineRecharge(ctx, record.ID, fmt.Sprintf("线上支付%s", onlinePaymentStatusText(n.Status)))
+	default:
+		return &record, nil
+	}
+}
+
+// approveOnlineRecharge 线上支付成功，自动审核通过并入账预付款（与线下审核共用幂等键）
+func (s *AccountService) approveOnlineRecharge(ctx context.Context, applicationID uint64) (*models.RechargeRecord, error) {
+	var application models.RechargeRecord
+	err := s.db.Transaction(func(db *gorm.DB) error {
+		// 使用 FOR UPDATE 行锁防止回调与补单轮询并发入账
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", applicationID).First(&application).Error; err != nil {
+			return err
+		}
+		if application.Status != models.ApplicationStatusPending {
+			return nil
+		}
+
+		_, err := s.RechargePrepayment(ctx, application.AdminID, application.Amount, application.Currency,
+			fmt.Sprintf("线上充值到账: %s", application.ApplicationNo), 0, RechargeIdempotencyKey(application.ApplicationNo))
+		if err != nil {
+			return fmt.Errorf("充值失败: %w", err)
+		}
+
+		now := time.Now()
+		application.Status = models.ApplicationStatusApproved
+		application.PaidAt = &now
+		application.AuditAt = &now
+		application.AuditRemark = onlineRechargeAutoApproveNote
+		return db.Save(&application).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// closeOnlineRecharge 线上支付失败或取消，关闭充值申请
+func (s *AccountService) closeOnlineRecharge(ctx context.Context, applicationID uint64, reason string) (*models.RechargeRecord, error) {
+	var application models.RechargeRecord
+	err := s.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", applicationID).First(&application).Error; err != nil {
+			return err
+		}
+		if application.Status != models.ApplicationStatusPending {
+			return nil
+		}
+
+		now := time.Now()
+		application.Status = models.ApplicationStatusRejected
+		application.AuditAt = &now
+		application.AuditRemark = reason
+		return db.Save(&application).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// onlinePaymentStatusText 支付状态中文描述
+func onlinePaymentStatusText(status string) string {
+	switch status {
+	case utils.PaymentStatusFailed:
+		return "失败"
+	case utils.PaymentStatusCancelled:
+		return "已取消"
+	default:
+		return status
+	}
+}
+
+// onlineRechargeWindows 轮询与过期时长（取自支付配置，未配置时用默认值）
+func onlineRechargeWindows() (pollAfter, expireAfter time.Duration) {
+	pollAfter, expireAfter = onlineRechargeDefaultPoll, onlineRechargeDefaultExpire
+	if cfg := config.Get(); cfg != nil {
+		if cfg.Payment.PollAfterMinutes > 0 {
+			pollAfter = time.Duration(cfg.Payment.PollAfterMinutes) * time.Minute
+		}
+		if cfg.Payment.ExpireAfterMinutes > 0 {
+			expireAfter = time.Duration(cfg.Payment.ExpireAfterMinutes) * time.Minute
+		}
+	}
+	if expireAfter < pollAfter {
+		expireAfter = pollAfter
+	}
+	return pollAfter, expireAfter
+}
+
+// PollPendingOnlineRecharges 轮询待支付的线上充值（补漏支付回调）：
+//   - 向网关查询待支付超过 pollAfter 的申请，已支付/已授权的走与回调相同的入账路径，失败/取消的关闭
+//   - 超过 expireAfter 仍未支付的申请关闭
+//   - 网关订单号或实付金额与申请不符的保持待支付，记为不一致等待人工核对
+//   - 处理后仍待支付的申请推迟下次轮询时间（不一致的推迟 onlineRechargeMismatchRecheck），
+//     避免长期待支付的申请占满每轮批次、阻塞后续申请
+func (s *AccountService) PollPendingOnlineRecharges(ctx context.Context) (*OnlineRechargePollResult, error) {
+	pollAfter, expireAfter := onlineRechargeWindows()
+	now := time.Now()
+	result := &OnlineRechargePollResult{}
+
+	var pending []models.RechargeRecord
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND payment_id <> '' AND created_at <= ?", models.ApplicationStatusPending, now.Add(-pollAfter)).
+		Where("next_poll_at IS NULL OR next_poll_at <= ?", now).
+		Order("created_at ASC").Limit(onlineRechargePollBatchSize).Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		record := &pending[i]
+		result.Checked++
+		outcome := s.pollOnlineRecharge(ctx, record, now.Add(-expireAfter), result)
+		middleware.OnlineRechargePollResults.WithLabelValues(record.PaymentMethod, outcome).Inc()
+
+		var nextPollAt time.Time
+		switch outcome {
+		case "mismatch":
+			nextPollAt = now.Add(onlineRechargeMismatchRecheck)
+		case "pending", "error":
+			nextPollAt = now.Add(pollAfter)
+		default:
+			continue
+		}
+		if err := s.deferOnlineRechargePoll(ctx, record.ID, nextPollAt); err != nil {
+			result.Failures = append(result.Failures, OnlineRechargeMismatch{
+				ApplicationNo: record.ApplicationNo,
+				PaymentMethod: record.PaymentMethod,
+				PaymentID:     record.PaymentID,
+				Reason:        fmt.Sprintf("推迟下次轮询失败: %v", err),
+			})
+		}
+	}
+	return result, nil
+}
+
+// deferOnlineRechargePoll 推迟仍待支付的线上充值的下次轮询时间（不影响更新时间）
+func (s *AccountService) deferOnlineRechargePoll(ctx context.Context, applicationID uint64, nextPollAt time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.RechargeRecord{}).
+		Where("id = ? AND status = ?", applicationID, models.ApplicationStatusPending).
+		UpdateColumn("next_poll_at", nextPollAt).Error
+}
+
+// pollOnlineRecharge 查询一笔待支付线上充值并按网关结果处理，返回处理结果标签
+func (s *AccountService) pollOnlineRecharge(ctx context.Context, record *models.RechargeRecord, expireBefore time.Time, result *OnlineRechargePollResult) string {
+	mismatch := func(reason string) string {
+		result.Mismatches = append(result.Mismatches, OnlineRechargeMismatch{
+			ApplicationNo: record.ApplicationNo,
+			PaymentMethod: record.PaymentMethod,
+			PaymentID:     record.PaymentID,
+			Reason:        reason,
+		})
+		return "mismatch"
+	}
+	fail := func(err error) string {
+		result.Failed++
+		result.Failures = append(result.Failures, OnlineRechargeMismatch{
+			ApplicationNo: record.ApplicationNo,
+			PaymentMethod: record.PaymentMethod,
+			PaymentID:     record.PaymentID,
+			Reason:        err.Error(),
+		})
+		return "error"
+	}
+
+	gw, err := utils.GetPaymentGateway(record.PaymentMethod)
+	if err != nil {
+		return fail(err)
+	}
+	query, err := gw.QueryPayment(ctx, record.PaymentID)
+	if err != nil {
+		return fail(err)
+	}
+	if query.OrderNo != "" && query.OrderNo != record.ApplicationNo {
+		return mismatch(fmt.Sprintf("网关订单号 %s 与申请单号不符", query.OrderNo))
+	}
+
+	settled, err := s.SettleOnlineRecharge(ctx, record.PaymentMethod, &utils.PaymentNotification{
+		PaymentID: record.PaymentID,
+		OrderNo:   record.ApplicationNo,
+		Amount:    query.Amount,
+		Currency:  query.Currency,
+		Status:    query.Status,
+	})
+	if err != nil {
+		if errors.Is(err, ErrRechargeAmountMismatch) {
+			return mismatch(err.Error())
+		}
+		return fail(err)
+	}
+
+	switch settled.Status {
+	case models.ApplicationStatusApproved:
+		result.Approved++
+		return "approved"
+	case models.ApplicationStatusRejected:
+		result.Closed++
+		return "closed"
+	}
+
+	// 网关仍为待付款：超过有效期则关闭（已授权待请款的不关闭，由请款结果决定）
+	if query.Status != utils.PaymentStatusAuthorized && record.CreatedAt.Before(expireBefore) {
+		if _, err := s.closeOnlineRecharge(ctx, record.ID, onlineRechargeExpiredRemark); err != nil {
+			return fail(err)
+		}
+		result.Expired++
+		return "expired"
+	}
+	return "pending"
+}
+
+// AuditOnlineRecharges 核对 since 之后处理过的线上充值与网关结果是否一致（只报告，不自动调整）：
+// 已入账但网关未收款、已关闭但网关已收款、网关实付与申请金额不符
+func (s *AccountService) AuditOnlineRecharges(ctx context.Context, since time.Time) ([]OnlineRechargeMismatch, error) {
+	var records []models.RechargeRecord
+	if err := s.db.WithContext(ctx).
+		Where("status IN ? AND payment_id <> '' AND updated_at >= ?",
+			[]int8{models.ApplicationStatusApproved, models.ApplicationStatusRejected}, since).
+		Order("updated_at ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var mismatches []OnlineRechargeMismatch
+	for i := range records {
+		record := &records[i]
+		gw, err := utils.GetPaymentGateway(record.PaymentMethod)
+		if err != nil {
+			continue
+		}
+		query, err := gw.QueryPayment(ctx, record.PaymentID)
+		if err != nil {
+			continue
+		}
+
+		reason := ""
+		gatewayPaid := query.Status == utils.PaymentStatusPaid
+		switch {
+		case record.Status == models.ApplicationStatusApproved && !gatewayPaid:
+			reason = fmt.Sprintf("充值已入账，但网关状态为 %s", query.Status)
+		case record.Status == models.ApplicationStatusRejected && gatewayPaid:
+			reason = "网关已收款，但充值申请已关闭"
+		case gatewayPaid && !query.Amount.Equal(record.Amount):
+			reason = fmt.Sprintf("网关实付 %s，申请金额 %s", query.Amount.String(), record.Amount.String())
+		}
+		if reason == "" {
+			continue
+		}
+		mismatches = append(mismatches, OnlineRechargeMismatch{
+			ApplicationNo: record.ApplicationNo,
+			PaymentMethod: record.PaymentMethod,
+			PaymentID:     record.PaymentID,
+			Reason:        reason,
+		})
+		middleware.OnlineRechargePollResults.WithLabelValues(record.PaymentMethod, "audit_mismatch").Inc()
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/middleware"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// reconciledAccountTypes 参与对账的账户类型
+var reconciledAccountTypes = []string{
+	models.AccountTypePrepayment,
+	models.AccountTypeOperator,
+	models.AccountTypeShopOwnerCommission,
+	models.AccountTypePlatformCommission,
+}
+
+// ReconciliationService 账户对账服务（按账户流水分表重算余额，与账户表比对）
+type ReconciliationService struct {
+	db *gorm.DB
+}
+
+// NewReconciliationService 创建账户对账服务
+func NewReconciliationService() *ReconciliationService {
+	return &ReconciliationService{
+		db: database.GetDB(),
+	}
+}
+
+type ledgerKey struct {
+	AccountType string
+	AdminID     int64
+	Currency    string
+}
+
+// ledgerSnapshot 单个账户的账面值/重算值
+type ledgerSnapshot struct {
+	sums            map[string]decimal.Decimal // 按交易类型汇总的流水金额
+	txCount         int64
+	pendingWithdraw decimal.Decimal // 待审核/已通过未打款的提现申请金额
+
+	balance, pending, totalIn, totalOut decimal.Decimal
+}
+
+// expected 按流水重算 可用余额/待结算/累计入账/累计出账
+//   - 预付款: 可用余额=除 order_pay 外所有流水之和；待结算=冻结-返还-结算消耗；累计充值/累计消费
+//   - 收益类账户: 提现申请暂扣不写流水，净额=所有流水之和，待结算=未打款提现申请金额，可用余额=净额-待结算
+func (l *ledgerSnapshot) expected(accountType string) (balance, pending, totalIn, totalOut decimal.Decimal) {
+	sum := func(txType string) decimal.Decimal {
+		return l.sums[txType]
+	}
+	net := decimal.Zero
+	for _, amount := range l.sums {
+		net = net.Add(amount)
+	}
+
+	if accountType == models.AccountTypePrepayment {
+		balance = net.Sub(sum(models.TxTypeOrderPay))
+		pending = sum(models.TxTypeFreeze).Neg().Sub(sum(models.TxTypeOrderRefund)).Add(sum(models.TxTypeOrderPay))
+		totalIn = sum(models.TxTypeRecharge)
+		totalOut = sum(models.TxTypeOrderPay).Neg()
+		return
+	}
+
+	var incomeType string
+	switch accountType {
+	case models.AccountTypeOperator:
+		incomeType = models.TxTypeCostSettle
+	case models.AccountTypeShopOwnerCommission:
+		incomeType = models.TxTypeProfitShare
+	case models.AccountTypePlatformCommission:
+		incomeType = models.TxTypePlatformFee
+	}
+	pending = l.pendingWithdraw
+	balance = net.Sub(pending)
+	totalIn = sum(incomeType)
+	totalOut = sum(models.TxTypeWithdraw).Neg()
+	return
+}
+
+// ReconcileAccounts 执行一次全量对账
+// 所有读取在同一个只读一致性快照中完成，避免对账期间的正常记账被误判为差异
+func (s *ReconciliationService) ReconcileAccounts(ctx context.Context) (*models.AccountReconciliationRun, error) {
+	run := &models.AccountReconciliationRun{
+		RunNo:     fmt.Sprintf("RC%d%d", time.Now().UnixNano(), time.Now().UnixMicro()%1000),
+		Status:    models.ReconciliationRunning,
+		StartedAt: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("创建对账批次失败: %w", err)
+	}
+
+	var drifts []models.AccountReconciliationDrift
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ledgers, err := s.loadLedgers(tx)
+		if err != nil {
+			return err
+		}
+		run.CheckedAccounts = int64(len(ledgers))
+		for key, l := range ledgers {
+			if d := buildDrift(run.RunNo, key, l); d != nil {
+				drifts = append(drifts, *d)
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	now := time.Now()
+	run.FinishedAt = &now
+	if err == nil && len(drifts) > 0 {
+		err = s.db.WithContext(ctx).CreateInBatches(drifts, 100).Error
+	}
+	if err != nil {
+		run.Status = models.ReconciliationFailed
+		run.ErrorMessage = err.Error()
+		s.db.Save(run)
+		return run, err
+	}
+
+	run.Status = models.ReconciliationFinished
+	run.DriftAccounts = int64(len(drifts))
+	if err := s.db.WithContext(ctx).Save(run).Error; err != nil {
+		return run, err
+	}
+
+	driftByType := make(map[string]int)
+	for _, d := range drifts {
+		driftByType[d.AccountType]++
+	}
+	for _, accountType := range reconciledAccountTypes {
+		middleware.AccountReconciliationDriftAccounts.WithLabelValues(accountType).Set(float64(driftByType[accountType]))
+	}
+
+	return run, nil
+}
+
+// loadLedgers 汇总流水分表、未打款提现申请及各账户表，按 账户+币种 归并
+func (s *ReconciliationService) loadLedgers(tx *gorm.DB) (map[ledgerKey]*ledgerSnapshot, error) {
+	ledgers := make(map[ledgerKey]*ledgerSnapshot)
+	get := func(accountType string, adminID int64, currency string) *ledgerSnapshot {
+		key := ledgerKey{AccountType: accountType, AdminID: adminID, Currency: currency}
+		l, ok := ledgers[key]
+		if !ok {
+			l = &ledgerSnapshot{sums: make(map[string]decimal.Decimal)}
+			ledgers[key] = l
+		}
+		return l
+	}
+
+	// 1. 流水分表按 账户+币种+交易类型 汇总
+	for i := 0; i < database.ShardCount; i++ {
+		var rows []struct {
+			AccountType     string
+			AdminID         int64
+			Currency        string
+			TransactionType string
+			Amount          decimal.Decimal
+			Cnt             int64
+		}
+		txTable := fmt.Sprintf("account_transactions_%d", i)
+		if err := tx.Table(txTable).
+			Select("account_type, admin_id, currency, transaction_type, SUM(amount) AS amount, COUNT(*) AS cnt").
+			Where("account_type IN ? AND status = ?", reconciledAccountTypes, 1).
+			Group("account_type, admin_id, currency, transaction_type").
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("汇总%s失败: %w", txTable, err)
+		}
+		for _, r := range rows {
+			l := get(r.AccountType, r.AdminID, r.Currency)
+			l.sums[r.TransactionType] = l.sums[r.TransactionType].Add(r.Amount)
+			l.txCount += r.Cnt
+		}
+	}
+
+	// 2. 未打款的提现申请（申请时只暂扣余额，不写流水）
+	var withdraws []struct {
+		AccountType string
+		AdminID     int64
+		Currency    string
+		Amount      decimal.Decimal
+	}
+	if err := tx.Model(&models.WithdrawApplication{}).
+		Select("account_type, admin_id, currency, SUM(amount) AS amount").
+		Where("status IN ?", []int8{models.ApplicationStatusPending, models.ApplicationStatusApproved}).
+		Group("account_type, admin_id, currency").
+		Scan(&withdraws).Error; err != nil {
+		return nil, fmt.Errorf("汇总提现申请失败: %w", err)
+	}
+	for _, w := range withdraws {
+		if w.AccountType == models.AccountTypeDeposit {
+			continue
+		}
+		get(w.AccountType, w.AdminID, w.Currency).pendingWithdraw = w.Amount
+	}
+
+	// 3. 账户表账面值
+	var prepayments []models.PrepaymentAccount
+	if err := tx.Find(&prepayments).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range prepayments {
+		l := get(models.AccountTypePrepayment, a.AdminID, a.Currency)
+		l.balance, l.pending, l.totalIn, l.totalOut = a.Balance, a.PendingAmount, a.TotalRecharge, a.TotalConsume
+	}
+
+	var operators []models.OperatorAccount
+	if err := tx.Find(&operators).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range operators {
+		l := get(models.AccountTypeOperator, a.AdminID, a.Currency)
+		l.balance, l.pending, l.totalIn, l.totalOut = a.Balance, a.PendingAmount, a.TotalEarnings, a.TotalWithdrawn
+	}
+
+	var commissions []models.ShopOwnerCommissionAccount
+	if err := tx.Find(&commissions).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range commissions {
+		l := get(models.AccountTypeShopOwnerCommission, a.AdminID, a.Currency)
+		l.balance, l.pending, l.totalIn, l.totalOut = a.Balance, a.PendingAmount, a.TotalEarnings, a.TotalWithdrawn
+	}
+
+	// 平台佣金账户每个币种一个，流水中 admin_id = 0
+	var platforms []models.PlatformCommissionAccount
+	if err := tx.Find(&platforms).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range platforms {
+		l := get(models.AccountTypePlatformCommission, 0, a.Currency)
+		l.balance, l.pending, l.totalIn, l.totalOut = a.Balance, a.PendingAmount, a.TotalEarnings, a.TotalWithdrawn
+	}
+
+	return ledgers, nil
+}
+
+// buildDrift 比对账面值与重算值，无差异返回 nil
+func buildDrift(runNo string, key ledgerKey, l *ledgerSnapshot) *models.AccountReconciliationDrift {
+	balance, pending, totalIn, totalOut := l.expected(key.AccountType)
+
+	var fields []string
+	if !l.balance.Equal(balance) {
+		fields = append(fields, "balance")
+	}
+	if !l.pending.Equal(pending) {
+		fields = append(fields, "pending_amount")
+	}
+	if !l.totalIn.Equal(totalIn) {
+		fields = append(fields, "total_in")
+	}
+	if !l.totalOut.Equal(totalOut) {
+		fields = append(fields, "total_out")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &models.AccountReconciliationDrift{
+		RunNo:            runNo,
+		AccountType:      key.AccountType,
+		AdminID:          key.AdminID,
+		Currency:         key.Currency,
+		Balance:          l.balance,
+		ExpectedBalance:  balance,
+		PendingAmount:    l.pending,
+		ExpectedPending:  pending,
+		TotalIn:          l.totalIn,
+		ExpectedTotalIn:  totalIn,
+		TotalOut:         l.totalOut,
+		ExpectedTotalOut: totalOut,
+		DriftFields:      strings.Join(fields, ","),
+		TxCount:          l.txCount,
+	}
+}
+
+// GetDrifts 获取对账差异明细（runNo 为空时取最近一次已完成批次）
+func (s *ReconciliationService) GetDrifts(ctx context.Context, runNo string, accountType string, page, pageSize int) (*models.AccountReconciliationRun, []models.AccountReconciliationDrift, int64, error) {
+	var run models.AccountReconciliationRun
+	query := s.db.Model(&models.AccountReconciliationRun{})
+	if runNo != "" {
+		query = query.Where("run_no = ?", runNo)
+	} else {
+		query = query.Where("status = ?", models.ReconciliationFinished)
+	}
+	if err := query.Order("id DESC").First(&run).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, []models.AccountReconciliationDrift{}, 0, nil
+		}
+		return nil, nil, 0, err
+	}
+
+	var drifts []models.AccountReconciliationDrift
+	var total int64
+	driftQuery := s.db.Model(&models.AccountReconciliationDrift{}).Where("run_no = ?", run.RunNo)
+	if accountType != "" {
+		driftQuery = driftQuery.Where("account_type = ?", accountType)
+	}
+	driftQuery.Count(&total)
+	offset := (page - 1) * pageSize
+	err := driftQuery.Order("id ASC").Offset(offset).Limit(pageSize).Find(&drifts).Error
+	return &run, drifts, total, err
+}
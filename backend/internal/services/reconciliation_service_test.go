@@ -0,0 +1,122 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildDrift(t *testing.T) {
+	d := decimal.RequireFromString
+	cases := []struct {
+		name            string
+		accountType     string
+		sums            map[string]string
+		pendingWithdraw string
+		book            [4]string // 账面 可用余额/待结算/累计入账/累计出账
+		wantFields      string    // 为空表示无差异
+	}{
+		{
+			name:        "prepayment freeze and ship",
+			accountType: models.AccountTypePrepayment,
+			sums:        map[string]string{models.TxTypeRecharge: "1000", models.TxTypeFreeze: "-300", models.TxTypeOrderPay: "-200"},
+			book:        [4]string{"700", "100", "1000", "200"},
+		},
+		{
+			name:        "prepayment reversal keeps total recharge",
+			accountType: models.AccountTypePrepayment,
+			sums:        map[string]string{models.TxTypeRecharge: "1000", models.TxTypeReversal: "-1000"},
+			book:        [4]string{"0", "0", "1000", "0"},
+		},
+		{
+			name:            "withdraw pending writes no transaction",
+			accountType:     models.AccountTypeOperator,
+			sums:            map[string]string{models.TxTypeCostSettle: "1000"},
+			pendingWithdraw: "300",
+			book:            [4]string{"700", "300", "1000", "0"},
+		},
+		{
+			name:        "withdraw paid",
+			accountType: models.AccountTypeOperator,
+			sums:        map[string]string{models.TxTypeCostSettle: "1000", models.TxTypeWithdraw: "-300"},
+			book:        [4]string{"700", "0", "1000", "300"},
+		},
+		{
+			name:        "withdraw failed returns the freeze",
+			accountType: models.AccountTypeShopOwnerCommission,
+			sums:        map[string]string{models.TxTypeProfitShare: "1000"},
+			book:        [4]string{"1000", "0", "1000", "0"},
+		},
+		{
+			name:        "penalty",
+			accountType: models.AccountTypeOperator,
+			sums:        map[string]string{models.TxTypeCostSettle: "1000", models.TxTypePenalty: "-100"},
+			book:        [4]string{"900", "0", "1000", "0"},
+		},
+		{
+			name:        "negative adjustment",
+			accountType: models.AccountTypeOperator,
+			sums:        map[string]string{models.TxTypeCostSettle: "1000", models.TxTypeAdjustment: "-150"},
+			book:        [4]string{"850", "0", "1000", "0"},
+		},
+		{
+			name:            "withdraw freeze not applied to the account",
+			accountType:     models.AccountTypeShopOwnerCommission,
+			sums:            map[string]string{models.TxTypeProfitShare: "1000"},
+			pendingWithdraw: "300",
+			book:            [4]string{"1000", "0", "1000", "0"},
+			wantFields:      "balance,pending_amount",
+		},
+		{
+			name:        "withdraw paid without total withdrawn",
+			accountType: models.AccountTypePlatformCommission,
+			sums:        map[string]string{models.TxTypePlatformFee: "500", models.TxTypeWithdraw: "-100"},
+			book:        [4]string{"400", "0", "500", "0"},
+			wantFields:  "total_out",
+		},
+		{
+			name:        "negative adjustment missing from the account",
+			accountType: models.AccountTypeOperator,
+			sums:        map[string]string{models.TxTypeCostSettle: "1000", models.TxTypeAdjustment: "-150"},
+			book:        [4]string{"1000", "0", "1000", "0"},
+			wantFields:  "balance",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &ledgerSnapshot{sums: make(map[string]decimal.Decimal), txCount: int64(len(tc.sums))}
+			for txType, amount := range tc.sums {
+				l.sums[txType] = d(amount)
+			}
+			if tc.pendingWithdraw != "" {
+				l.pendingWithdraw = d(tc.pendingWithdraw)
+			}
+			l.balance, l.pending, l.totalIn, l.totalOut = d(tc.book[0]), d(tc.book[1]), d(tc.book[2]), d(tc.book[3])
+
+			key := ledgerKey{AccountType: tc.accountType, AdminID: 7, Currency: "TWD"}
+			drift := buildDrift("RC1", key, l)
+			if tc.wantFields == "" {
+				if drift != nil {
+					t.Fatalf("unexpected drift %s: %+v", drift.DriftFields, drift)
+				}
+				return
+			}
+			if drift == nil {
+				t.Fatalf("drift = nil, want %s", tc.wantFields)
+			}
+			if drift.DriftFields != tc.wantFields {
+				t.Errorf("DriftFields = %s, want %s", drift.DriftFields, tc.wantFields)
+			}
+			balance, pending, totalIn, totalOut := l.expected(tc.accountType)
+			if drift.RunNo != "RC1" || drift.AccountType != tc.accountType || drift.AdminID != 7 || drift.TxCount != l.txCount ||
+				!drift.ExpectedBalance.Equal(balance) || !drift.ExpectedPending.Equal(pending) ||
+				!drift.ExpectedTotalIn.Equal(totalIn) || !drift.ExpectedTotalOut.Equal(totalOut) ||
+				!drift.Balance.Equal(l.balance) {
+				t.Errorf("drift = %+v", drift)
+			}
+		})
+	}
+}
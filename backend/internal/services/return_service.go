@@ -22,6 +22,7 @@ import (
 //  1. 从 Shopee 拉取退货详情并保存到 returns_X 分表
 //  2. 当退货状态确认退款后（ACCEPTED / REFUND_PAID），自动解冻店铺老板的预付款
 //  3. 巡检时批量同步近期退货记录
+//  4. 退货关闭/取消（CLOSED / CANCELLED）时自动释放关联的风控暂扣
 type ReturnService struct {
 	db             *gorm.DB
 	rs             *redsync.Redsync
@@ -45,6 +46,7 @@ func NewReturnService() *ReturnService {
 //  1. 从 Shopee API 响应构建 Return 对象
 //  2. 在事务中：查找已有记录 → 不存在则创建，存在则更新状态/金额
 //  3. 如果退货状态变为已确认退款（ACCEPTED/REFUND_PAID），处理预付款解冻
+//  4. 如果退货已关闭/取消，释放关联的风控暂扣
 //
 // 并发安全：
 //   - 使用分布式锁保证同一退货单不会被并发处理
@@ -181,6 +183,16 @@ func (s *ReturnService) UpsertReturn(ctx context.Context, shopID uint64, detail
 		s.processRefund(ctx, shopID, detail)
 	}
 
+	// 争议结束，释放关联的风控暂扣（只处理生效中的暂扣，可重复执行）
+	if r := (&models.Return{Status: resp.Status}); r.IsClosed() {
+		count, err := s.accountService.ReleaseHoldsByReturn(ctx, shopID, resp.ReturnSN, fmt.Sprintf("退货单%s 状态%s 自动释放", resp.ReturnSN, resp.Status))
+		if err != nil {
+			fmt.Printf("[ReturnService] 店铺=%d 退货=%s 释放风控暂扣失败: %v\n", shopID, resp.ReturnSN, err)
+		} else if count > 0 {
+			fmt.Printf("[ReturnService] 店铺=%d 退货=%s 释放风控暂扣 %d 笔\n", shopID, resp.ReturnSN, count)
+		}
+	}
+
 	return nil
 }
 
@@ -204,7 +216,7 @@ func (s *ReturnService) processRefund(ctx context.Context, shopID uint64, detail
 
 	var order models.Order
 	if err := s.db.Table(orderTable).
-		Select("id", "shop_id", "order_sn", "total_amount", "prepayment_amount", "prepayment_status").
+		Select("id", "shop_id", "order_sn", "total_amount", "prepayment_amount", "prepayment_status", "currency").
 		Where("shop_id = ? AND order_sn = ?", shopID, orderSN).
 		First(&order).Error; err != nil {
 		fmt.Printf("[ReturnService] 店铺=%d 退货=%s 关联订单=%s 未找到: %v\n", shopID, returnSN, orderSN, err)
@@ -219,7 +231,7 @@ func (s *ReturnService) processRefund(ctx context.Context, shopID uint64, detail
 	}
 
 	var shop models.Shop
-	if err := s.db.Select("admin_id").Where("shop_id = ?", shopID).First(&shop).Error; err != nil || shop.AdminID == 0 {
+	if err := s.db.Select("admin_id", "currency").Where("shop_id = ?", shopID).First(&shop).Error; err != nil || shop.AdminID == 0 {
 		fmt.Printf("[ReturnService] 店铺=%d 查找店主失败\n", shopID)
 		s.markRefundStatus(returnTable, shopID, returnSN, models.ReturnRefundFailed)
 		return
@@ -248,8 +260,14 @@ func (s *ReturnService) processRefund(ctx context.Context, shopID uint64, detail
 			}
 		}
 
-		_, innerErr := s.accountService.RefundPrepaymentInTx(tx, ctx, shop.AdminID, refundPrepaymentAmount, orderSN,
-			fmt.Sprintf("退货退款返还-退货单%s 订单%s", returnSN, orderSN))
+		// 发货前全额退款与 Webhook 取消返还共用幂等键，其余按退货单返还
+		idempotencyKey := fmt.Sprintf("return_refund:%d:%s", shopID, returnSN)
+		if beforeShip && isFullRefund {
+			idempotencyKey = OrderCancelRefundIdempotencyKey(shopID, orderSN)
+		}
+		currency := s.accountService.OrderFreezeCurrencyInTx(tx, shop.AdminID, shopID, orderSN, orderCurrency(order.Currency, shop.Currency))
+		_, innerErr := s.accountService.RefundPrepaymentInTx(tx, ctx, shop.AdminID, refundPrepaymentAmount, currency, orderSN,
+			fmt.Sprintf("退货退款返还-退货单%s 订单%s", returnSN, orderSN), idempotencyKey)
 		if innerErr != nil {
 			return fmt.Errorf("返还预付款失败: %w", innerErr)
 		}
@@ -271,9 +289,25 @@ func (s *ReturnService) processRefund(ctx context.Context, shopID uint64, detail
 		}
 
 		now := time.Now()
-		return tx.Table(returnTable).
+		if err := tx.Table(returnTable).
 			Where("shop_id = ? AND return_sn = ? AND refund_status = ?", shopID, returnSN, models.ReturnRefundProcessing).
-			Updates(map[string]interface{}{"refund_status": models.ReturnRefundProcessed, "refund_processed_at": now}).Error
+			Updates(map[string]interface{}{"refund_status": models.ReturnRefundProcessed, "refund_processed_at": now}).Error; err != nil {
+			return err
+		}
+
+		// 写入 order.refunded 事件（店主及发货运营可收到）
+		return EnqueueOutboxEvent(tx, models.EventOrderRefunded, orderSN, []int64{shop.AdminID, shipmentRecord.OperatorID}, map[string]interface{}{
+			"shop_id":           shopID,
+			"order_sn":          orderSN,
+			"return_sn":         returnSN,
+			"shop_owner_id":     shop.AdminID,
+			"before_ship":       beforeShip,
+			"full_refund":       isFullRefund,
+			"refund_amount":     refundAmount,
+			"prepayment_refund": refundPrepaymentAmount,
+			"currency":          currency,
+			"processed_at":      now,
+		})
 	})
 
 	if err != nil {
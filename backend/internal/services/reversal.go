@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reversalCounterparts 支持冲正的交易类型及其记账对手科目
+// 订单扣款/返还/结算/调账/提现有各自的补偿流程，不允许直接冲正；冲正流水本身也不能再冲正
+var reversalCounterparts = map[string]string{
+	models.TxTypeRecharge:   models.ClearingAccountBank,
+	models.TxTypeDepositPay: models.ClearingAccountBank,
+	models.TxTypePenalty:    models.ClearingAccountPenaltyBonus,
+	models.TxTypeSubsidy:    models.ClearingAccountPenaltyBonus,
+}
+
+// reversalAccountTypes 支持冲正的账户类型（充值/保证金缴纳在预付款/保证金账户，罚补在运营/店主佣金账户）
+var reversalAccountTypes = map[string]bool{
+	models.AccountTypePrepayment:          true,
+	models.AccountTypeDeposit:             true,
+	models.AccountTypeOperator:            true,
+	models.AccountTypeShopOwnerCommission: true,
+}
+
+// ReversalIdempotencyKey 冲正流水的幂等键（同一笔流水最多冲正一次，由分表唯一索引兜底）
+func ReversalIdempotencyKey(transactionNo string) string {
+	return "reversal:" + transactionNo
+}
+
+// findTransactionByNo 按流水号查找流水（流水按 admin_id 分表，逐个分表查找）
+func (s *AccountService) findTransactionByNo(db *gorm.DB, transactionNo string) (*models.AccountTransaction, error) {
+	for i := 0; i < database.ShardCount; i++ {
+		var at models.AccountTransaction
+		err := db.Table(fmt.Sprintf("account_transactions_%d", i)).Where("transaction_no = ?", transactionNo).First(&at).Error
+		if err == nil {
+			return &at, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("流水不存在")
+}
+
+// RequestReversal 发起流水冲正申请（只支持整笔冲正，需另一名平台用户审批后入账）
+func (s *AccountService) RequestReversal(ctx context.Context, transactionNo string, reason string, requestedBy int64) (*models.TransactionReversal, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("请填写冲正原因")
+	}
+	original, err := s.findTransactionByNo(s.db, transactionNo)
+	if err != nil {
+		return nil, err
+	}
+	if original.TransactionType == models.TxTypeReversal {
+		return nil, fmt.Errorf("冲正流水不能再次冲正")
+	}
+	if _, ok := reversalCounterparts[original.TransactionType]; !ok {
+		return nil, fmt.Errorf("%s 类型流水不支持冲正，请走对应业务的退款/调账流程", original.TransactionType)
+	}
+	if !reversalAccountTypes[original.AccountType] {
+		return nil, fmt.Errorf("%s 账户不支持冲正", original.AccountType)
+	}
+	if original.Status != 1 {
+		return nil, fmt.Errorf("流水未完成，不能冲正")
+	}
+
+	rid, err := s.idGen.GenerateReversalApplicationID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("生成冲正申请ID失败: %w", err)
+	}
+	reversal := &models.TransactionReversal{
+		ID:              uint64(rid),
+		ApplicationNo:   s.GenerateApplicationNo("RV"),
+		TransactionNo:   original.TransactionNo,
+		TransactionID:   original.ID,
+		AccountType:     original.AccountType,
+		AdminID:         original.AdminID,
+		Currency:        NormalizeCurrency(original.Currency),
+		TransactionType: original.TransactionType,
+		Amount:          original.Amount.Neg(),
+		Reason:          reason,
+		Status:          models.ApplicationStatusPending,
+		RequestedBy:     requestedBy,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定原流水，同一流水的并发申请在此串行，避免重复创建未结束的冲正申请
+		var locked models.AccountTransaction
+		if err := tx.Table(database.GetAccountTransactionTableName(original.AdminID)).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", original.ID).First(&locked).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.TransactionReversal{}).
+			Where("transaction_no = ? AND status IN ?", transactionNo, []int8{models.ApplicationStatusPending, models.ApplicationStatusApproved}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("该流水已有冲正申请")
+		}
+		return tx.Create(reversal).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// ApproveReversal 审批通过冲正申请：按原流水相反金额写冲正流水并记账
+// 审批人不能是发起人；冲正后余额不能为负
+func (s *AccountService) ApproveReversal(ctx context.Context, reversalID uint64, auditBy int64, auditRemark string) (*models.TransactionReversal, error) {
+	var reversal models.TransactionReversal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reversal, reversalID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("冲正申请不存在")
+			}
+			return err
+		}
+		if reversal.Status != models.ApplicationStatusPending {
+			return fmt.Errorf("冲正申请状态不正确")
+		}
+		if reversal.RequestedBy == auditBy {
+			return fmt.Errorf("冲正申请需由发起人以外的平台用户审批")
+		}
+
+		at, err := s.postReversalInTx(tx, ctx, &reversal, auditBy)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		reversal.Status = models.ApplicationStatusApproved
+		reversal.AuditBy = auditBy
+		reversal.AuditRemark = auditRemark
+		reversal.AuditAt = &now
+		reversal.ReversalTransactionNo = at.TransactionNo
+		return tx.Save(&reversal).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reversal.AccountType == models.AccountTypeDeposit && reversal.Amount.IsPositive() {
+		s.backfillAfterDepositChange(reversal.AdminID)
+	}
+	return &reversal, nil
+}
+
+// RejectReversal 拒绝冲正申请
+func (s *AccountService) RejectReversal(ctx context.Context, reversalID uint64, auditBy int64, auditRemark string) error {
+	now := time.Now()
+	res := s.db.Model(&models.TransactionReversal{}).
+		Where("id = ? AND status = ?", reversalID, models.ApplicationStatusPending).
+		Updates(map[string]interface{}{
+			"status":       models.ApplicationStatusRejected,
+			"audit_by":     auditBy,
+			"audit_remark": auditRemark,
+			"audit_at":     now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("冲正申请不存在或状态不正确")
+	}
+	return nil
+}
+
+// postReversalInTx 对原账户写入冲正流水（事务参与版本）
+// 累计充值等统计字段保持不变，冲正以独立的 reversal 流水体现，与对账重算口径一致
+func (s *AccountService) postReversalInTx(db *gorm.DB, ctx context.Context, reversal *models.TransactionReversal, operatorID int64) (*models.AccountTransaction, error) {
+	counterpart, ok := reversalCounterparts[reversal.TransactionType]
+	if !ok {
+		return nil, fmt.Errorf("%s 类型流水不支持冲正", reversal.TransactionType)
+	}
+	amount := reversal.Amount
+
+	var balanceBefore, balanceAfter decimal.Decimal
+	switch reversal.AccountType {
+	case models.AccountTypePrepayment:
+		var account models.PrepaymentAccount
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("admin_id = ? AND currency = ?", reversal.AdminID, reversal.Currency).First(&account).Error; err != nil {
+			return nil, fmt.Errorf("预付款账户不存在")
+		}
+		if account.Balance.Add(amount).LessThan(decimal.Zero) {
+			return nil, fmt.Errorf("预付款可用余额不足，无法冲正")
+		}
+		balanceBefore = account.Balance
+		account.Balance = account.Balance.Add(amount)
+		balanceAfter = account.Balance
+		if err := db.Save(&account).Error; err != nil {
+			return nil, err
+		}
+	case models.AccountTypeDeposit:
+		var account models.DepositAccount
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("admin_id = ?", reversal.AdminID).First(&account).Error; err != nil {
+			return nil, fmt.Errorf("保证金账户不存在")
+		}
+		if account.Balance.Add(amount).LessThan(decimal.Zero) {
+			return nil, fmt.Errorf("保证金余额不足，无法冲正")
+		}
+		balanceBefore = account.Balance
+		account.Balance = account.Balance.Add(amount)
+		balanceAfter = account.Balance
+		refreshDepositStatus(&account)
+		if err := db.Save(&account).Error; err != nil {
+			return nil, err
+		}
+	case models.AccountTypeOperator, models.AccountTypeShopOwnerCommission:
+		var model interface{} = &models.OperatorAccount{}
+		if reversal.AccountType == models.AccountTypeShopOwnerCommission {
+			model = &models.ShopOwnerCommissionAccount{}
+		}
+		var held decimal.Decimal
+		row := db.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("balance, held_amount").Where("admin_id = ? AND currency = ?", reversal.AdminID, reversal.Currency).Row()
+		if err := row.Scan(&balanceBefore, &held); err != nil {
+			return nil, fmt.Errorf("账户不存在")
+		}
+		if WithdrawableBalance(balanceBefore, held).Add(amount).LessThan(decimal.Zero) {
+			return nil, fmt.Errorf("可提现余额不足（含风控暂扣），无法冲正")
+		}
+		balanceAfter = balanceBefore.Add(amount)
+		if err := db.Model(model).Where("admin_id = ? AND currency = ?", reversal.AdminID, reversal.Currency).
+			Update("balance", balanceAfter).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s 账户不支持冲正", reversal.AccountType)
+	}
+
+	// 罚款/补贴冲正时撤销对应的罚补单
+	if reversal.TransactionType == models.TxTypePenalty || reversal.TransactionType == models.TxTypeSubsidy {
+		if err := s.reversePenaltyBonusInTx(db, ctx, reversal); err != nil {
+			return nil, err
+		}
+	}
+
+	remark := fmt.Sprintf("冲正流水 %s: %s", reversal.TransactionNo, reversal.Reason)
+	at := &models.AccountTransaction{
+		TransactionNo:   s.GenerateTransactionNo(reversal.AccountType),
+		IdempotencyKey:  ReversalIdempotencyKey(reversal.TransactionNo),
+		AccountType:     reversal.AccountType,
+		AdminID:         reversal.AdminID,
+		Currency:        reversal.Currency,
+		TransactionType: models.TxTypeReversal,
+		Amount:          amount,
+		BalanceBefore:   balanceBefore,
+		BalanceAfter:    balanceAfter,
+		RelatedID:       reversal.TransactionID,
+		Remark:          remark,
+		OperatorID:      operatorID,
+		Status:          1,
+	}
+	if err := s.createTransaction(db, at); err != nil {
+		if database.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("流水 %s 已冲正", reversal.TransactionNo)
+		}
+		return nil, err
+	}
+
+	// 记账：与原流水方向相反，对手方为原业务的清算科目
+	journal := NewJournal(models.JournalBizReversal, "", reversal.ApplicationNo, remark).
+		SetCurrency(reversal.Currency).
+		SetOperator(operatorID).
+		AddTransaction(at)
+	if amount.GreaterThan(decimal.Zero) {
+		journal.Debit(counterpart, 0, models.JournalBucketAvailable, amount, "", remark)
+	} else {
+		journal.Credit(counterpart, 0, models.JournalBucketAvailable, amount.Neg(), "", remark)
+	}
+	if _, err := s.PostJournalInTx(db, ctx, journal); err != nil {
+		return nil, err
+	}
+	return at, nil
+}
+
+// GetReversals 获取冲正申请列表（status 为 -1 时不过滤状态）
+func (s *AccountService) GetReversals(ctx context.Context, status int8, transactionNo string, page, pageSize int) ([]models.TransactionReversal, int64, error) {
+	var reversals []models.TransactionReversal
+	var total int64
+
+	query := s.db.Model(&models.TransactionReversal{})
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if transactionNo != "" {
+		query = query.Where("transaction_no = ?", transactionNo)
+	}
+
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&reversals).Error
+	return reversals, total, err
+}
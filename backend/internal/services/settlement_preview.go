@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// settlementCalculation 订单分账计算结果（SettleOrder 与结算预览共用）
+type settlementCalculation struct {
+	Settlement       *models.OrderSettlement // 未分配ID/结算单号的结算记录
+	PrepaymentAmount decimal.Decimal         // 结算币种下需消耗的预付款
+}
+
+// calculateSettlementInTx 按发货记录、虾皮结算金额、分成配置与分成规则（可为 nil）计算分账，不写入任何数据
+// 结算币种与订单扣除预付款的子账户一致；与订单币种不同时按当前汇率折算金额和成本
+func (s *SettlementService) calculateSettlementInTx(db *gorm.DB, record *models.OrderShipmentRecord, escrowAmount decimal.Decimal, config *models.ProfitShareConfig, rule *models.ProfitShareRule) (*settlementCalculation, error) {
+	orderCurrency := NormalizeCurrency(record.Currency)
+	settleCurrency := orderCurrency
+	orderEscrowAmount := escrowAmount
+	goodsCost, shippingCost, totalCost := record.GoodsCost, record.ShippingCost, record.TotalCost
+	prepaymentAmount := record.PrepaymentAmount
+	fxRate, fxRateID := decimal.NewFromInt(1), uint64(0)
+	if freezeTx := s.accountService.FindOrderFreezeInTx(db, record.ShopOwnerID, record.ShopID, record.OrderSN); freezeTx != nil &&
+		freezeTx.Currency != "" && freezeTx.Currency != orderCurrency {
+		var err error
+		settleCurrency = freezeTx.Currency
+		fxRate, fxRateID, err = s.fxService.GetRateInTx(db, orderCurrency, settleCurrency, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("获取结算汇率失败: %w", err)
+		}
+		escrowAmount = escrowAmount.Mul(fxRate).Round(2)
+		goodsCost = goodsCost.Mul(fxRate).Round(2)
+		shippingCost = shippingCost.Mul(fxRate).Round(2)
+		totalCost = goodsCost.Add(shippingCost)
+		prepaymentAmount = freezeTx.Amount.Abs()
+	}
+
+	// 利润 = 结算金额 - 总成本，按分成规则（无规则时按配置比例）分配
+	profit := escrowAmount.Sub(totalCost)
+	allocation, err := AllocateProfit(profit, config, rule)
+	if err != nil {
+		return nil, err
+	}
+	ruleID := uint64(0)
+	if rule != nil {
+		ruleID = rule.ID
+	}
+
+	return &settlementCalculation{
+		Settlement: &models.OrderSettlement{
+			ShopID:                  record.ShopID,
+			OrderSN:                 record.OrderSN,
+			OrderID:                 record.OrderID,
+			ShopOwnerID:             record.ShopOwnerID,
+			OperatorID:              record.OperatorID,
+			Currency:                settleCurrency,
+			OrderCurrency:           orderCurrency,
+			OrderEscrowAmount:       orderEscrowAmount,
+			FxRate:                  fxRate,
+			FxRateID:                fxRateID,
+			EscrowAmount:            escrowAmount,
+			GoodsCost:               goodsCost,
+			ShippingCost:            shippingCost,
+			TotalCost:               totalCost,
+			Profit:                  profit,
+			PlatformShareRate:       config.PlatformShareRate,
+			OperatorShareRate:       config.OperatorShareRate,
+			ShopOwnerShareRate:      config.ShopOwnerShareRate,
+			ProfitShareConfigID:     config.ID,
+			ProfitShareRuleID:       ruleID,
+			ProfitShareRuleSnapshot: profitShareRuleSnapshot(rule),
+			PlatformShare:           allocation.PlatformShare,
+			OperatorShare:           allocation.OperatorShare,
+			ShopOwnerShare:          allocation.ShopOwnerShare,
+			OperatorIncome:          totalCost.Add(allocation.OperatorShare),
+		},
+		PrepaymentAmount: prepaymentAmount,
+	}, nil
+}
+
+// settlementEscrowAmount 订单结算时使用的虾皮结算金额（订单币种）
+// 依次取 orders_x.prepayment_amount、发货记录预付款，均为空时取虾皮回款金额
+func (s *SettlementService) settlementEscrowAmount(db *gorm.DB, record *models.OrderShipmentRecord, incomeAmount decimal.Decimal) (decimal.Decimal, error) {
+	var order models.Order
+	if err := db.Table(database.GetOrderTableName(record.ShopID)).
+		Where("shop_id = ? AND order_sn = ?", record.ShopID, record.OrderSN).
+		Select("prepayment_amount").First(&order).Error; err != nil {
+		return decimal.Zero, err
+	}
+	escrowAmount := order.PrepaymentAmount
+	if !escrowAmount.IsPositive() {
+		escrowAmount = record.PrepaymentAmount
+	}
+	if !escrowAmount.IsPositive() {
+		escrowAmount = incomeAmount
+	}
+	return escrowAmount, nil
+}
+
+// SettlementPreviewRequest 结算预览请求（what-if 字段为空时按实际结算口径计算）
+type SettlementPreviewRequest struct {
+	ShopID             uint64           `json:"shop_id" binding:"required"`
+	OrderSN            string           `json:"order_sn" binding:"required"`
+	EscrowAmount       *decimal.Decimal `json:"escrow_amount"`       // 假设的虾皮结算金额（订单币种）
+	PlatformShareRate  *decimal.Decimal `json:"platform_share_rate"` // 假设的分成比例，三项需同时提供
+	OperatorShareRate  *decimal.Decimal `json:"operator_share_rate"`
+	ShopOwnerShareRate *decimal.Decimal `json:"shop_owner_share_rate"`
+	RuleID             *uint64          `json:"rule_id"` // 假设的分成规则：为空取当前生效规则，0 表示不使用规则
+}
+
+// SettlementPreview 结算预览结果
+type SettlementPreview struct {
+	Settlement          *models.OrderSettlement `json:"settlement"`
+	PrepaymentAmount    decimal.Decimal         `json:"prepayment_amount"`
+	ProfitShareConfigID uint64                  `json:"profit_share_config_id"` // 0 表示默认比例或假设比例
+	ProfitShareVersion  int                     `json:"profit_share_version"`
+	ProfitShareRule     *models.ProfitShareRule `json:"profit_share_rule"`
+	ShopeeSettled       bool                    `json:"shopee_settled"` // 虾皮是否已回款（已回款时定时任务将按此结果结算）
+	WhatIf              bool                    `json:"what_if"`
+}
+
+// PreviewSettlement 预览订单结算分账（与 SettleOrder 相同的计算口径，不写入任何数据）
+// role 为 operator / shop_owner 时只能预览自己参与的订单，platform 不限制
+func (s *SettlementService) PreviewSettlement(ctx context.Context, adminID int64, role string, req *SettlementPreviewRequest) (*SettlementPreview, error) {
+	var record models.OrderShipmentRecord
+	if err := s.db.Table(database.GetOrderShipmentRecordTableName(req.ShopID)).
+		Where("shop_id = ? AND order_sn = ?", req.ShopID, req.OrderSN).
+		First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("未找到发货记录")
+		}
+		return nil, err
+	}
+	switch role {
+	case "operator":
+		if record.OperatorID != adminID {
+			return nil, fmt.Errorf("无权查看该订单")
+		}
+	case "shop_owner":
+		if record.ShopOwnerID != adminID {
+			return nil, fmt.Errorf("无权查看该订单")
+		}
+	}
+	if record.Status != models.ShipmentRecordStatusShipped {
+		return nil, fmt.Errorf("订单未发货或已结算，无法预览")
+	}
+
+	preview := &SettlementPreview{}
+	effectiveAt := s.profitShareEffectiveTime(s.db, &record)
+
+	// 分成比例：默认取订单支付时生效的配置版本，what-if 时使用传入比例
+	var config *models.ProfitShareConfig
+	hasRates := req.PlatformShareRate != nil || req.OperatorShareRate != nil || req.ShopOwnerShareRate != nil
+	if hasRates {
+		if req.PlatformShareRate == nil || req.OperatorShareRate == nil || req.ShopOwnerShareRate == nil {
+			return nil, fmt.Errorf("假设分成比例需同时提供平台、运营、店主三项")
+		}
+		if err := ValidateProfitShareRates(*req.PlatformShareRate, *req.OperatorShareRate, *req.ShopOwnerShareRate); err != nil {
+			return nil, err
+		}
+		config = &models.ProfitShareConfig{
+			ShopID:             record.ShopID,
+			OperatorID:         record.OperatorID,
+			PlatformShareRate:  *req.PlatformShareRate,
+			OperatorShareRate:  *req.OperatorShareRate,
+			ShopOwnerShareRate: *req.ShopOwnerShareRate,
+		}
+		preview.WhatIf = true
+	} else {
+		var err error
+		config, err = s.getProfitShareConfigAt(s.db, record.ShopID, record.OperatorID, effectiveAt)
+		if err != nil {
+			return nil, fmt.Errorf("获取分成配置失败: %w", err)
+		}
+		preview.ProfitShareConfigID = config.ID
+		preview.ProfitShareVersion = config.Version
+	}
+
+	// 分成规则：默认取订单支付时生效的规则，what-if 时可指定其他规则或不使用规则
+	var rule *models.ProfitShareRule
+	if req.RuleID != nil {
+		preview.WhatIf = true
+		if *req.RuleID > 0 {
+			var err error
+			if rule, err = s.getProfitShareRule(s.db, *req.RuleID, record.ShopID, record.OperatorID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		var err error
+		if rule, err = s.getProfitShareRuleAt(s.db, record.ShopID, record.OperatorID, effectiveAt); err != nil {
+			return nil, fmt.Errorf("获取分成规则失败: %w", err)
+		}
+	}
+	preview.ProfitShareRule = rule
+
+	// 虾皮结算金额：what-if 时使用传入金额，否则与定时结算任务取值一致
+	var income models.FinanceIncome
+	incomeAmount := decimal.Zero
+	if err := s.db.Table(database.GetFinanceIncomeTableName(record.ShopID)).
+		Where("order_sn = ? AND transaction_type = ?", record.OrderSN, models.TransactionTypeEscrowVerifiedAdd).
+		First(&income).Error; err == nil {
+		preview.ShopeeSettled = true
+		incomeAmount = income.Amount
+	}
+	var escrowAmount decimal.Decimal
+	if req.EscrowAmount != nil {
+		escrowAmount = *req.EscrowAmount
+		preview.WhatIf = true
+	} else {
+		var err error
+		escrowAmount, err = s.settlementEscrowAmount(s.db, &record, incomeAmount)
+		if err != nil {
+			return nil, fmt.Errorf("获取订单失败: %w", err)
+		}
+	}
+
+	calc, err := s.calculateSettlementInTx(s.db, &record, escrowAmount, config, rule)
+	if err != nil {
+		return nil, err
+	}
+	calc.Settlement.Status = models.OrderSettlementPending
+	preview.Settlement = calc.Settlement
+	preview.PrepaymentAmount = calc.PrepaymentAmount
+	return preview, nil
+}
@@ -0,0 +1,559 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+	"balance/backend/internal/utils"
+
+	"github.com/go-redsync/redsync/v4"
+	"gorm.io/gorm"
+)
+
+// settlementRunTimeout 单个批次的执行时长上限（超时后批次中断，可续跑）
+const settlementRunTimeout = 10 * time.Minute
+
+// ErrSettlementRunBusy 同类批次正在其他节点或后台执行
+var ErrSettlementRunBusy = errors.New("同类批次正在执行，请稍后重试")
+
+// settlementAdjustmentTypes 需要反向分账的虾皮调账交易类型
+var settlementAdjustmentTypes = []string{
+	models.TransactionTypeRefund,
+	models.TransactionTypeEscrowAdjustment,
+	models.TransactionTypeSellerAdjustment,
+	models.TransactionTypeCommissionAdjust,
+	models.TransactionTypeServiceFeeAdjust,
+	models.TransactionTypeShippingFeeAdjust,
+}
+
+// settlementRunLockKey 批次执行锁（与定时任务共用，同类批次同一时间只在一个节点执行）
+func settlementRunLockKey(runType string) string {
+	if runType == models.SettlementRunTypeAdjustment {
+		return "maintenance:adjustment"
+	}
+	return "maintenance:settlement"
+}
+
+// lockSettlementRun 获取同类批次的执行锁（执行期间自动续期），获取失败返回 ErrSettlementRunBusy
+func (s *SettlementService) lockSettlementRun(runType string) (func(), error) {
+	mutex := database.GetRedsync().NewMutex(settlementRunLockKey(runType),
+		redsync.WithExpiry(maintenanceLockTTL),
+		redsync.WithTries(1),
+	)
+	unlock, acquired := utils.TryLockWithAutoExtend(context.Background(), mutex, maintenanceLockTTL/3)
+	if !acquired {
+		return nil, ErrSettlementRunBusy
+	}
+	return unlock, nil
+}
+
+// runSettlementRunInBackground 在后台执行批次，结束后释放执行锁
+// 后台执行不受发起请求的上下文影响，超过 settlementRunTimeout 时批次中断
+func (s *SettlementService) runSettlementRunInBackground(run *models.SettlementRun, unlock func(), fn func(ctx context.Context) error) {
+	go func() {
+		defer unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), settlementRunTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			fmt.Printf("[Settlement] 批次%s 执行失败: %v\n", run.RunNo, err)
+		}
+	}()
+}
+
+// StartSettlementRun 创建并执行一个结算批次（调用方需持有 settlementRunLockKey 对应的执行锁）
+// 先遍历所有分表收集候选（结算批次为全部已发货订单，调账批次为全部待处理调账）写入批次明细，再逐条处理
+// 执行中途超时/退出时批次标记为已中断，可通过 ResumeSettlementRun 续跑未处理的明细
+func (s *SettlementService) StartSettlementRun(ctx context.Context, runType, trigger string, createdBy int64) (*models.SettlementRun, error) {
+	run, err := s.createSettlementRun(ctx, runType, trigger, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	return run, s.executeSettlementRun(ctx, run)
+}
+
+// StartSettlementRunAsync 手动创建批次并在后台执行，立即返回批次（同类批次执行中时返回 ErrSettlementRunBusy）
+func (s *SettlementService) StartSettlementRunAsync(ctx context.Context, runType string, createdBy int64) (*models.SettlementRun, error) {
+	if err := validateSettlementRunType(runType); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSettlementRun(runType)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.createSettlementRun(ctx, runType, models.SettlementRunTriggerManual, createdBy)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	s.runSettlementRunInBackground(run, unlock, func(ctx context.Context) error {
+		return s.executeSettlementRun(ctx, run)
+	})
+	return run, nil
+}
+
+// validateSettlementRunType 校验批次类型
+func validateSettlementRunType(runType string) error {
+	if runType != models.SettlementRunTypeSettlement && runType != models.SettlementRunTypeAdjustment {
+		return fmt.Errorf("不支持的批次类型: %s", runType)
+	}
+	return nil
+}
+
+// createSettlementRun 创建执行中的批次
+func (s *SettlementService) createSettlementRun(ctx context.Context, runType, trigger string, createdBy int64) (*models.SettlementRun, error) {
+	if err := validateSettlementRunType(runType); err != nil {
+		return nil, err
+	}
+
+	run := &models.SettlementRun{
+		RunNo:     fmt.Sprintf("SR%s%d", time.Now().Format("20060102150405"), time.Now().UnixNano()%1000),
+		RunType:   runType,
+		Trigger:   trigger,
+		Status:    models.SettlementRunRunning,
+		CreatedBy: createdBy,
+		StartedAt: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("创建结算批次失败: %w", err)
+	}
+	return run, nil
+}
+
+// executeSettlementRun 收集候选写入批次明细后逐条处理
+func (s *SettlementService) executeSettlementRun(ctx context.Context, run *models.SettlementRun) error {
+	var items []models.SettlementRunItem
+	var err error
+	if run.RunType == models.SettlementRunTypeSettlement {
+		items, err = s.collectSettlementCandidates(run.ID)
+	} else {
+		items, err = s.collectAdjustmentCandidates(run.ID)
+	}
+	if err == nil && len(items) > 0 {
+		err = s.db.WithContext(ctx).CreateInBatches(items, 200).Error
+	}
+	if err != nil {
+		s.interruptSettlementRun(run, fmt.Errorf("收集候选订单失败: %w", err))
+		return err
+	}
+
+	return s.processSettlementRun(ctx, run)
+}
+
+// ResumeSettlementRun 在后台续跑批次中尚未处理的明细（用于中断的批次），立即返回批次
+func (s *SettlementService) ResumeSettlementRun(ctx context.Context, runNo string) (*models.SettlementRun, error) {
+	run, err := s.getSettlementRun(runNo)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSettlementRun(run.RunType)
+	if err != nil {
+		return nil, err
+	}
+	// 持锁后重新读取，避免与刚结束的执行交错
+	if run, err = s.getSettlementRun(runNo); err != nil {
+		unlock()
+		return nil, err
+	}
+	if run.Status == models.SettlementRunRunning {
+		unlock()
+		return nil, fmt.Errorf("批次执行中，请稍后重试")
+	}
+	if run.Status == models.SettlementRunFinished && run.PendingCount == 0 {
+		unlock()
+		return nil, fmt.Errorf("批次已完成，无待处理明细")
+	}
+	if err := s.markSettlementRunRunning(ctx, run); err != nil {
+		unlock()
+		return nil, err
+	}
+	s.runSettlementRunInBackground(run, unlock, func(ctx context.Context) error {
+		return s.processSettlementRun(ctx, run)
+	})
+	return run, nil
+}
+
+// RetrySettlementRunFailures 在后台重试批次中处理失败的明细，立即返回批次
+func (s *SettlementService) RetrySettlementRunFailures(ctx context.Context, runNo string) (*models.SettlementRun, error) {
+	run, err := s.getSettlementRun(runNo)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSettlementRun(run.RunType)
+	if err != nil {
+		return nil, err
+	}
+	if run, err = s.getSettlementRun(runNo); err != nil {
+		unlock()
+		return nil, err
+	}
+	if run.Status == models.SettlementRunRunning {
+		unlock()
+		return nil, fmt.Errorf("批次执行中，请稍后重试")
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.SettlementRunItem{}).
+		Where("run_id = ? AND status = ?", run.ID, models.SettlementRunItemFailed).
+		Update("status", models.SettlementRunItemPending)
+	if result.Error != nil {
+		unlock()
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		unlock()
+		return nil, fmt.Errorf("批次没有失败的明细")
+	}
+	if err := s.markSettlementRunRunning(ctx, run); err != nil {
+		unlock()
+		return nil, err
+	}
+	s.runSettlementRunInBackground(run, unlock, func(ctx context.Context) error {
+		return s.processSettlementRun(ctx, run)
+	})
+	return run, nil
+}
+
+// markSettlementRunRunning 将批次标记为执行中
+func (s *SettlementService) markSettlementRunRunning(ctx context.Context, run *models.SettlementRun) error {
+	if err := s.db.WithContext(ctx).Model(run).Updates(map[string]interface{}{
+		"status":        models.SettlementRunRunning,
+		"error_message": "",
+	}).Error; err != nil {
+		return fmt.Errorf("更新批次状态失败: %w", err)
+	}
+	run.Status = models.SettlementRunRunning
+	return nil
+}
+
+// processSettlementRun 逐条处理批次中待处理的明细，结束后刷新批次统计
+func (s *SettlementService) processSettlementRun(ctx context.Context, run *models.SettlementRun) error {
+	var lastID uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			s.interruptSettlementRun(run, err)
+			return err
+		}
+
+		var items []models.SettlementRunItem
+		if err := s.db.Where("run_id = ? AND status = ? AND id > ?", run.ID, models.SettlementRunItemPending, lastID).
+			Order("id ASC").Limit(100).Find(&items).Error; err != nil {
+			s.interruptSettlementRun(run, err)
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		for i := range items {
+			if err := ctx.Err(); err != nil {
+				s.interruptSettlementRun(run, err)
+				return err
+			}
+			item := &items[i]
+			lastID = item.ID
+
+			var status int8
+			var reason, settlementNo string
+			if run.RunType == models.SettlementRunTypeSettlement {
+				status, reason, settlementNo = s.settleRunItem(ctx, item)
+			} else {
+				status, reason = s.adjustRunItem(ctx, item)
+			}
+			if status == models.SettlementRunItemFailed {
+				fmt.Printf("[Settlement] 批次%s 处理失败 order_sn=%s: %s\n", run.RunNo, item.OrderSN, reason)
+			}
+
+			now := time.Now()
+			if runes := []rune(reason); len(runes) > 500 {
+				reason = string(runes[:500])
+			}
+			if err := s.db.Model(item).Updates(map[string]interface{}{
+				"status":        status,
+				"reason":        reason,
+				"settlement_no": settlementNo,
+				"attempts":      gorm.Expr("attempts + 1"),
+				"processed_at":  &now,
+			}).Error; err != nil {
+				s.interruptSettlementRun(run, fmt.Errorf("保存明细结果失败 order_sn=%s: %w", item.OrderSN, err))
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	run.Status = models.SettlementRunFinished
+	run.FinishedAt = &now
+	run.ErrorMessage = ""
+	return s.refreshSettlementRunCounts(run)
+}
+
+// settleRunItem 结算批次单个订单：虾皮未回款/已处理的跳过，其余调用 SettleOrder
+func (s *SettlementService) settleRunItem(ctx context.Context, item *models.SettlementRunItem) (int8, string, string) {
+	var record models.OrderShipmentRecord
+	if err := s.db.Table(database.GetOrderShipmentRecordTableName(item.ShopID)).
+		Where("shop_id = ? AND order_sn = ?", item.ShopID, item.OrderSN).
+		First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return models.SettlementRunItemSkipped, "发货记录不存在", ""
+		}
+		return models.SettlementRunItemFailed, err.Error(), ""
+	}
+	if record.Status == models.ShipmentRecordStatusCompleted {
+		return models.SettlementRunItemSkipped, "订单已结算", ""
+	}
+	if record.Status != models.ShipmentRecordStatusShipped {
+		return models.SettlementRunItemSkipped, "订单不是已发货状态", ""
+	}
+
+	// 检查 Shopee 是否已结算 (通过 finance_incomes 分表，仅作触发条件)
+	financeTable := database.GetFinanceIncomeTableName(record.ShopID)
+	var income models.FinanceIncome
+	if err := s.db.Table(financeTable).
+		Where("order_sn = ? AND transaction_type = ?", record.OrderSN, models.TransactionTypeEscrowVerifiedAdd).
+		First(&income).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return models.SettlementRunItemSkipped, "虾皮未回款", ""
+		}
+		return models.SettlementRunItemFailed, err.Error(), ""
+	}
+	if income.SettlementHandleStatus == models.SettlementStatusCompleted {
+		return models.SettlementRunItemSkipped, "虾皮回款已处理", ""
+	}
+
+	// 结算金额以 orders_x.prepayment_amount 为准（订单入系统时已扣的预付款）
+	escrowAmount, err := s.settlementEscrowAmount(s.db, &record, income.Amount)
+	if err != nil {
+		return models.SettlementRunItemFailed, fmt.Sprintf("获取订单失败: %v", err), ""
+	}
+	settlement, err := s.SettleOrder(ctx, record.ShopID, record.OrderSN, escrowAmount)
+	if err != nil {
+		return models.SettlementRunItemFailed, err.Error(), ""
+	}
+
+	// 标记已处理
+	s.db.Table(financeTable).Where("id = ?", income.ID).Update("settlement_handle_status", models.SettlementStatusCompleted)
+	return models.SettlementRunItemSuccess, "", settlement.SettlementNo
+}
+
+// adjustRunItem 调账批次单笔调账：处理后将财务收入标记为已处理（无原结算单的调账同样标记，不再重复处理）
+func (s *SettlementService) adjustRunItem(ctx context.Context, item *models.SettlementRunItem) (int8, string) {
+	financeTable := database.GetFinanceIncomeTableName(item.ShopID)
+	var income models.FinanceIncome
+	if err := s.db.Table(financeTable).Where("id = ?", item.FinanceIncomeID).First(&income).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return models.SettlementRunItemSkipped, "财务收入记录不存在"
+		}
+		return models.SettlementRunItemFailed, err.Error()
+	}
+	if income.SettlementHandleStatus == models.SettlementStatusCompleted {
+		return models.SettlementRunItemSkipped, "调账已处理"
+	}
+
+	skipReason, err := s.handleAdjustment(ctx, &income)
+	if err != nil {
+		return models.SettlementRunItemFailed, err.Error()
+	}
+
+	// 标记已处理
+	s.db.Table(financeTable).Where("id = ?", income.ID).Update("settlement_handle_status", models.SettlementStatusCompleted)
+	if skipReason != "" {
+		return models.SettlementRunItemSkipped, skipReason
+	}
+	return models.SettlementRunItemSuccess, ""
+}
+
+// collectSettlementCandidates 遍历所有分表收集已发货未结算的订单
+func (s *SettlementService) collectSettlementCandidates(runID uint64) ([]models.SettlementRunItem, error) {
+	var items []models.SettlementRunItem
+	for i := 0; i < database.ShardCount; i++ {
+		var records []models.OrderShipmentRecord
+		if err := s.db.Table(fmt.Sprintf("order_shipment_records_%d", i)).
+			Select("shop_id", "order_sn").
+			Where("status = ?", models.ShipmentRecordStatusShipped).
+			Order("id ASC").
+			Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			items = append(items, models.SettlementRunItem{RunID: runID, ShopID: r.ShopID, OrderSN: r.OrderSN})
+		}
+	}
+	return items, nil
+}
+
+// collectAdjustmentCandidates 遍历所有分表收集未处理的虾皮调账
+func (s *SettlementService) collectAdjustmentCandidates(runID uint64) ([]models.SettlementRunItem, error) {
+	var items []models.SettlementRunItem
+	for i := 0; i < database.ShardCount; i++ {
+		var incomes []models.FinanceIncome
+		if err := s.db.Table(fmt.Sprintf("finance_incomes_%d", i)).
+			Where("settlement_handle_status = ?", models.SettlementStatusPending).
+			Where("order_sn != ''").
+			Where("transaction_type IN ?", settlementAdjustmentTypes).
+			Order("id ASC").
+			Find(&incomes).Error; err != nil {
+			return nil, err
+		}
+		for _, income := range incomes {
+			items = append(items, models.SettlementRunItem{
+				RunID:           runID,
+				ShopID:          income.ShopID,
+				OrderSN:         income.OrderSN,
+				FinanceIncomeID: income.ID,
+				TransactionID:   income.TransactionID,
+				TransactionType: income.TransactionType,
+			})
+		}
+	}
+	return items, nil
+}
+
+// interruptSettlementRun 标记批次中断并刷新统计（未处理明细保持待处理，可续跑）
+func (s *SettlementService) interruptSettlementRun(run *models.SettlementRun, cause error) {
+	now := time.Now()
+	run.Status = models.SettlementRunInterrupted
+	run.ErrorMessage = cause.Error()
+	if runes := []rune(run.ErrorMessage); len(runes) > 500 {
+		run.ErrorMessage = string(runes[:500])
+	}
+	run.FinishedAt = &now
+	s.refreshSettlementRunCounts(run)
+}
+
+// refreshSettlementRunCounts 按明细结果汇总批次统计并保存批次
+func (s *SettlementService) refreshSettlementRunCounts(run *models.SettlementRun) error {
+	var rows []struct {
+		Status int8
+		Count  int64
+	}
+	if err := s.db.Model(&models.SettlementRunItem{}).
+		Select("status, COUNT(*) AS count").
+		Where("run_id = ?", run.ID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	run.TotalCount, run.PendingCount, run.SuccessCount, run.SkippedCount, run.FailedCount = 0, 0, 0, 0, 0
+	for _, r := range rows {
+		run.TotalCount += r.Count
+		switch r.Status {
+		case models.SettlementRunItemPending:
+			run.PendingCount = r.Count
+		case models.SettlementRunItemSuccess:
+			run.SuccessCount = r.Count
+		case models.SettlementRunItemSkipped:
+			run.SkippedCount = r.Count
+		case models.SettlementRunItemFailed:
+			run.FailedCount = r.Count
+		}
+	}
+	return s.db.Save(run).Error
+}
+
+// getSettlementRun 按批次号获取批次
+func (s *SettlementService) getSettlementRun(runNo string) (*models.SettlementRun, error) {
+	var run models.SettlementRun
+	if err := s.db.Where("run_no = ?", runNo).First(&run).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("结算批次不存在")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetSettlementRuns 获取结算批次列表（按创建时间倒序）
+func (s *SettlementService) GetSettlementRuns(ctx context.Context, runType string, status int8, page, pageSize int) ([]models.SettlementRun, int64, error) {
+	query := s.db.Model(&models.SettlementRun{})
+	if runType != "" {
+		query = query.Where("run_type = ?", runType)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+	var runs []models.SettlementRun
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs).Error
+	return runs, total, err
+}
+
+// GetSettlementRunItems 获取结算批次明细（status 为 -1 时不过滤结果）
+func (s *SettlementService) GetSettlementRunItems(ctx context.Context, runNo string, status int8, page, pageSize int) (*models.SettlementRun, []models.SettlementRunItem, int64, error) {
+	run, err := s.getSettlementRun(runNo)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query := s.db.Model(&models.SettlementRunItem{}).Where("run_id = ?", run.ID)
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	query.Count(&total)
+	var items []models.SettlementRunItem
+	err = query.Order("id ASC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error
+	return run, items, total, err
+}
+
+// GetUnsettledOrders 获取指定时间以来各订单最近一次批次处理仍未成功的记录（失败或跳过），用于每日核对未结算原因
+func (s *SettlementService) GetUnsettledOrders(ctx context.Context, runType string, since time.Time, status int8, page, pageSize int) ([]models.SettlementRunItem, int64, error) {
+	// 每个订单（调账按交易ID）只取最近一次处理结果
+	latest := s.db.Model(&models.SettlementRunItem{}).
+		Select("MAX(settlement_run_items.id)").
+		Joins("JOIN settlement_runs r ON r.id = settlement_run_items.run_id").
+		Where("r.run_type = ? AND settlement_run_items.processed_at >= ?", runType, since).
+		Group("settlement_run_items.shop_id, settlement_run_items.order_sn, settlement_run_items.transaction_id")
+
+	query := s.db.Model(&models.SettlementRunItem{}).Where("id IN (?)", latest)
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", []int8{models.SettlementRunItemSkipped, models.SettlementRunItemFailed})
+	}
+
+	var total int64
+	query.Count(&total)
+	var items []models.SettlementRunItem
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error
+	return items, total, err
+}
+
+// CleanupSettlementRuns 清理指定天数前的已完成结算批次及明细
+func (s *SettlementService) CleanupSettlementRuns(ctx context.Context, keepDays int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -keepDays)
+	var runIDs []uint64
+	if err := s.db.Model(&models.SettlementRun{}).
+		Where("created_at < ? AND status = ?", cutoff, models.SettlementRunFinished).
+		Pluck("id", &runIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(runIDs) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	for start := 0; start < len(runIDs); start += 500 {
+		end := start + 500
+		if end > len(runIDs) {
+			end = len(runIDs)
+		}
+		batch := runIDs[start:end]
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("run_id IN ?", batch).Delete(&models.SettlementRunItem{}).Error; err != nil {
+				return err
+			}
+			result := tx.Where("id IN ?", batch).Delete(&models.SettlementRun{})
+			deleted += result.RowsAffected
+			return result.Error
+		}); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"balance/backend/internal/database"
 	"balance/backend/internal/models"
+	"balance/backend/internal/utils"
 
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -17,7 +19,9 @@ import (
 type SettlementService struct {
 	db             *gorm.DB
 	accountService *AccountService
+	fxService      *FxRateService
 	shardedDB      *database.ShardedDB
+	idGen          *utils.IDGenerator
 }
 
 // NewSettlementService 创建结算服务
@@ -26,7 +30,9 @@ func NewSettlementService() *SettlementService {
 	return &SettlementService{
 		db:             db,
 		accountService: NewAccountService(),
+		fxService:      NewFxRateService(),
 		shardedDB:      database.NewShardedDB(db),
+		idGen:          utils.NewIDGenerator(database.GetRedis()),
 	}
 }
 
@@ -40,9 +46,10 @@ func (s *SettlementService) GenerateSettlementNo() string {
 // 整个流程在一个事务中完成：
 //  1. FOR UPDATE 锁定发货记录 → 防止并发结算
 //  2. 检查是否已结算（幂等）
-//  3. 创建结算记录
-//  4. 执行资金划转（内部各账户操作有自己的事务和行锁）
-//  5. 更新结算状态 + 发货记录状态
+//  3. 按订单支付时间取当时生效的分成配置版本和分成规则分配利润（规则快照写入结算记录）；确定结算币种：与订单扣除预付款的子账户一致；与订单币种不同时按结算时汇率折算
+//  4. 创建结算记录
+//  5. 执行资金划转（内部各账户操作有自己的事务和行锁）
+//  6. 更新结算状态 + 写入 order.settled 事件 + 发货记录状态
 func (s *SettlementService) SettleOrder(ctx context.Context, shopID uint64, orderSN string, escrowAmount decimal.Decimal) (*models.OrderSettlement, error) {
 	shipmentRecordTable := database.GetOrderShipmentRecordTableName(shopID)
 	settlementTable := database.GetOrderSettlementTableName(shopID)
@@ -70,50 +77,40 @@ func (s *SettlementService) SettleOrder(ctx context.Context, shopID uint64, orde
 			}
 		}
 
-		// 3. 获取分成配置
-		config, err := s.getProfitShareConfig(shipmentRecord.ShopID, shipmentRecord.OperatorID)
+		// 3. 获取订单支付时生效的分成配置和分成规则（分成比例/规则调整不追溯已支付的订单）
+		effectiveAt := s.profitShareEffectiveTime(tx, &shipmentRecord)
+		config, err := s.getProfitShareConfigAt(tx, shipmentRecord.ShopID, shipmentRecord.OperatorID, effectiveAt)
 		if err != nil {
 			return fmt.Errorf("获取分成配置失败: %w", err)
 		}
 
-		// 4. 计算利润和分成
-		totalCost := shipmentRecord.TotalCost
-		profit := escrowAmount.Sub(totalCost)
-		hundred := decimal.NewFromInt(100)
-		platformShare := profit.Mul(config.PlatformShareRate).Div(hundred).Round(2)
-		operatorShare := profit.Mul(config.OperatorShareRate).Div(hundred).Round(2)
-		shopOwnerShare := profit.Sub(platformShare).Sub(operatorShare)
-		operatorIncome := totalCost.Add(operatorShare)
+		rule, err := s.getProfitShareRuleAt(tx, shipmentRecord.ShopID, shipmentRecord.OperatorID, effectiveAt)
+		if err != nil {
+			return fmt.Errorf("获取分成规则失败: %w", err)
+		}
+
+		// 4. 计算分账（币种折算、利润、按分成规则分配），与结算预览共用同一套计算
+		calc, err := s.calculateSettlementInTx(tx, &shipmentRecord, escrowAmount, config, rule)
+		if err != nil {
+			return err
+		}
+		prepaymentAmount := calc.PrepaymentAmount
 
 		// 5. 创建结算记录
-		settlement = &models.OrderSettlement{
-			SettlementNo:       s.GenerateSettlementNo(),
-			ShopID:             shipmentRecord.ShopID,
-			OrderSN:            orderSN,
-			OrderID:            shipmentRecord.OrderID,
-			ShopOwnerID:        shipmentRecord.ShopOwnerID,
-			OperatorID:         shipmentRecord.OperatorID,
-			Currency:           shipmentRecord.Currency,
-			EscrowAmount:       escrowAmount,
-			GoodsCost:          shipmentRecord.GoodsCost,
-			ShippingCost:       shipmentRecord.ShippingCost,
-			TotalCost:          totalCost,
-			Profit:             profit,
-			PlatformShareRate:  config.PlatformShareRate,
-			OperatorShareRate:  config.OperatorShareRate,
-			ShopOwnerShareRate: config.ShopOwnerShareRate,
-			PlatformShare:      platformShare,
-			OperatorShare:      operatorShare,
-			ShopOwnerShare:     shopOwnerShare,
-			OperatorIncome:     operatorIncome,
-			Status:             models.OrderSettlementPending,
+		settlement = calc.Settlement
+		settlement.SettlementNo = s.GenerateSettlementNo()
+		settlement.Status = models.OrderSettlementPending
+		settlementID, err := s.idGen.GenerateOrderSettlementID(ctx)
+		if err != nil {
+			return fmt.Errorf("生成结算记录ID失败: %w", err)
 		}
+		settlement.ID = uint64(settlementID)
 		if err := tx.Table(settlementTable).Create(settlement).Error; err != nil {
 			return fmt.Errorf("创建结算记录失败: %w", err)
 		}
 
 		// 6. 执行资金划转（使用 InTx 版本，复用同一事务连接，避免嵌套事务连接池死锁）
-		if err := s.executeSettlementInTx(tx, ctx, settlement, &shipmentRecord); err != nil {
+		if err := s.executeSettlementInTx(tx, ctx, settlement, prepaymentAmount); err != nil {
 			return fmt.Errorf("执行结算失败: %w", err)
 		}
 
@@ -128,7 +125,13 @@ func (s *SettlementService) SettleOrder(ctx context.Context, shopID uint64, orde
 		settlement.Status = models.OrderSettlementCompleted
 		settlement.SettledAt = &now
 
-		// 8. 更新发货记录状态为已结算
+		// 8. 写入 order.settled 事件（与结算同一事务提交）
+		if err := EnqueueOutboxEvent(tx, models.EventOrderSettled, settlement.OrderSN,
+			[]int64{settlement.ShopOwnerID, settlement.OperatorID}, settlement); err != nil {
+			return err
+		}
+
+		// 9. 更新发货记录状态为已结算
 		return tx.Table(shipmentRecordTable).Where("id = ?", shipmentRecord.ID).Updates(map[string]interface{}{
 			"status":        models.ShipmentRecordStatusCompleted,
 			"settlement_id": settlement.ID,
@@ -138,219 +141,283 @@ func (s *SettlementService) SettleOrder(ctx context.Context, shopID uint64, orde
 	if err != nil {
 		return nil, err
 	}
+
+	// 回款入账后抵扣该运营/店主已审核的罚补单；失败不影响结算结果，由定时任务补做
+	s.accountService.SettleApprovedPenaltyBonus(ctx, models.AccountTypeOperator, settlement.OperatorID, settlement.Currency)
+	if settlement.ShopOwnerShare.GreaterThan(decimal.Zero) {
+		s.accountService.SettleApprovedPenaltyBonus(ctx, models.AccountTypeShopOwnerCommission, settlement.ShopOwnerID, settlement.Currency)
+	}
 	return settlement, nil
 }
 
 // executeSettlementInTx 执行结算资金划转（复用调用方事务，避免嵌套独立事务导致连接池死锁）
 // 预付款在订单 READY_TO_SHIP 时已冻结，发货不转托管，结算直接按 orders_x/发货记录分账，不操作托管账户
-func (s *SettlementService) executeSettlementInTx(outerTx *gorm.DB, ctx context.Context, settlement *models.OrderSettlement, shipmentRecord *models.OrderShipmentRecord) error {
+// 所有分账均以 settlement.Currency 入账，prepaymentAmount 为该币种下需消耗的预付款
+func (s *SettlementService) executeSettlementInTx(outerTx *gorm.DB, ctx context.Context, settlement *models.OrderSettlement, prepaymentAmount decimal.Decimal) error {
+	// 幂等键按订单+分账方生成：重复结算同一订单时返回首次入账的流水
+	keyPrefix := fmt.Sprintf("settlement:%d:%s", settlement.ShopID, settlement.OrderSN)
+	journal := NewJournal(models.JournalBizSettlement, settlement.OrderSN, settlement.SettlementNo,
+		fmt.Sprintf("订单结算-虾皮结算%s", settlement.EscrowAmount.String())).
+		SetCurrency(settlement.Currency)
+
 	// 1. 从店铺老板冻结金额中扣除 (结算预付款消耗，与 orders_x 一致)
-	_, err := s.accountService.SettlePrepaymentInTx(outerTx, ctx, settlement.ShopOwnerID, shipmentRecord.PrepaymentAmount, settlement.OrderSN,
-		fmt.Sprintf("订单结算-成本%s", settlement.TotalCost.String()))
+	prepayTx, err := s.accountService.SettlePrepaymentInTx(outerTx, ctx, settlement.ShopOwnerID, prepaymentAmount, settlement.Currency, settlement.OrderSN,
+		fmt.Sprintf("订单结算-成本%s", settlement.TotalCost.String()),
+		keyPrefix+":prepayment")
 	if err != nil {
 		return fmt.Errorf("扣除店铺老板预付款失败: %w", err)
 	}
+	journal.AddTransaction(prepayTx)
 
-	// 2. 给运营账户增加收入 (成本 + 运营分成)
-	_, err = s.accountService.AddOperatorIncomeInTx(outerTx, ctx, settlement.OperatorID, settlement.OperatorIncome, settlement.OrderSN,
-		fmt.Sprintf("订单结算-成本%s+分成%s", settlement.TotalCost.String(), settlement.OperatorShare.String()))
-	if err != nil {
-		return fmt.Errorf("增加运营收入失败: %w", err)
+	// 承担方余额不足未扣回的亏损，计入未分摊亏损科目
+	uncoveredLoss := decimal.Zero
+
+	// 2. 给运营账户增加收入 (成本 + 运营分成)；运营承担的亏损超过成本时从运营余额扣回差额
+	if settlement.OperatorIncome.GreaterThan(decimal.Zero) {
+		operatorTx, err := s.accountService.AddOperatorIncomeInTx(outerTx, ctx, settlement.OperatorID, settlement.OperatorIncome, settlement.Currency, settlement.OrderSN,
+			fmt.Sprintf("订单结算-成本%s+分成%s", settlement.TotalCost.String(), settlement.OperatorShare.String()),
+			keyPrefix+":operator")
+		if err != nil {
+			return fmt.Errorf("增加运营收入失败: %w", err)
+		}
+		journal.AddTransaction(operatorTx)
+	} else if settlement.OperatorIncome.LessThan(decimal.Zero) {
+		operatorTx, uncovered, err := s.deductLossInTx(outerTx, ctx, models.AccountTypeOperator, settlement.OperatorID, settlement.OperatorIncome.Abs(), settlement.Currency, settlement.OrderSN,
+			fmt.Sprintf("订单结算-成本%s+承担亏损%s", settlement.TotalCost.String(), settlement.OperatorShare.String()),
+			keyPrefix+":operator")
+		if err != nil {
+			return fmt.Errorf("扣回运营承担的亏损失败: %w", err)
+		}
+		journal.AddTransaction(operatorTx)
+		uncoveredLoss = uncoveredLoss.Add(uncovered)
 	}
 
-	// 3. 给店主佣金账户增加收入 (店主分成)
+	// 3. 给店主佣金账户增加收入 (店主分成)；按分成规则由店主承担的亏损从佣金账户扣回
 	if settlement.ShopOwnerShare.GreaterThan(decimal.Zero) {
-		_, err = s.accountService.AddShopOwnerCommissionInTx(outerTx, ctx, settlement.ShopOwnerID, settlement.ShopOwnerShare, settlement.OrderSN,
-			fmt.Sprintf("订单结算-利润分成%s%%", settlement.ShopOwnerShareRate.String()))
+		shopOwnerTx, err := s.accountService.AddShopOwnerCommissionInTx(outerTx, ctx, settlement.ShopOwnerID, settlement.ShopOwnerShare, settlement.Currency, settlement.OrderSN,
+			fmt.Sprintf("订单结算-利润分成%s%%", settlement.ShopOwnerShareRate.String()),
+			keyPrefix+":shop_owner")
 		if err != nil {
 			return fmt.Errorf("增加店主佣金失败: %w", err)
 		}
+		journal.AddTransaction(shopOwnerTx)
+	} else if settlement.ShopOwnerShare.LessThan(decimal.Zero) && settlement.ProfitShareRuleID > 0 {
+		shopOwnerTx, uncovered, err := s.deductLossInTx(outerTx, ctx, models.AccountTypeShopOwnerCommission, settlement.ShopOwnerID, settlement.ShopOwnerShare.Abs(), settlement.Currency, settlement.OrderSN,
+			fmt.Sprintf("订单结算-承担亏损%s", settlement.ShopOwnerShare.Abs().String()),
+			keyPrefix+":shop_owner")
+		if err != nil {
+			return fmt.Errorf("扣回店主承担的亏损失败: %w", err)
+		}
+		journal.AddTransaction(shopOwnerTx)
+		uncoveredLoss = uncoveredLoss.Add(uncovered)
 	}
 
-	// 4. 给平台佣金账户增加收入 (平台分成)
+	// 4. 给平台佣金账户增加收入 (平台分成)；按分成规则由平台承担的亏损从平台佣金扣回
 	if settlement.PlatformShare.GreaterThan(decimal.Zero) {
-		_, err = s.accountService.AddPlatformCommissionInTx(outerTx, ctx, settlement.PlatformShare, settlement.OrderSN,
-			fmt.Sprintf("订单结算-平台分成%s%%", settlement.PlatformShareRate.String()))
+		platformTx, err := s.accountService.AddPlatformCommissionInTx(outerTx, ctx, settlement.PlatformShare, settlement.Currency, settlement.OrderSN,
+			fmt.Sprintf("订单结算-平台分成%s%%", settlement.PlatformShareRate.String()),
+			keyPrefix+":platform")
 		if err != nil {
 			return fmt.Errorf("增加平台佣金失败: %w", err)
 		}
+		journal.AddTransaction(platformTx)
+	} else if settlement.PlatformShare.LessThan(decimal.Zero) && settlement.ProfitShareRuleID > 0 {
+		platformTx, uncovered, err := s.deductLossInTx(outerTx, ctx, models.AccountTypePlatformCommission, 0, settlement.PlatformShare.Abs(), settlement.Currency, settlement.OrderSN,
+			fmt.Sprintf("订单结算-承担亏损%s", settlement.PlatformShare.Abs().String()),
+			keyPrefix+":platform")
+		if err != nil {
+			return fmt.Errorf("扣回平台承担的亏损失败: %w", err)
+		}
+		journal.AddTransaction(platformTx)
+		uncoveredLoss = uncoveredLoss.Add(uncovered)
 	}
 
-	return nil
-}
-
-// getProfitShareConfig 获取分成配置
-func (s *SettlementService) getProfitShareConfig(shopID uint64, operatorID int64) (*models.ProfitShareConfig, error) {
-	var config models.ProfitShareConfig
-	err := s.db.Where("shop_id = ? AND operator_id = ? AND status = 1", shopID, operatorID).
-		Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", time.Now(), time.Now()).
-		First(&config).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// 返回默认配置
-		return &models.ProfitShareConfig{
-			PlatformShareRate:  decimal.NewFromFloat(5),   // 平台 5%
-			OperatorShareRate:  decimal.NewFromFloat(45),  // 运营 45%
-			ShopOwnerShareRate: decimal.NewFromFloat(50),  // 店主 50%
-		}, nil
+	// 5. 对手方分录：虾皮结算金额与已消耗预付款的差额计入托管清算科目，
+	//    未扣回的亏损计入未分摊亏损科目（见 settlementUnallocatedLoss），保证凭证借贷平衡
+	escrowDiff := settlement.EscrowAmount.Sub(prepaymentAmount)
+	if escrowDiff.GreaterThan(decimal.Zero) {
+		journal.Debit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, escrowDiff, "", "虾皮结算入账")
+	} else {
+		journal.Credit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, escrowDiff.Abs(), "", "预付款超出虾皮结算部分")
 	}
-	if err != nil {
-		return nil, err
-	}
-	return &config, nil
-}
+	unallocatedLoss := settlementUnallocatedLoss(settlement, uncoveredLoss)
+	journal.Debit(models.ClearingAccountUnallocatedLoss, 0, models.JournalBucketAvailable, unallocatedLoss, "", "负利润未分摊亏损")
 
-// CreateProfitShareConfig 创建分成配置
-func (s *SettlementService) CreateProfitShareConfig(ctx context.Context, config *models.ProfitShareConfig) error {
-	// 验证比例总和为 100%
-	total := config.PlatformShareRate.Add(config.OperatorShareRate).Add(config.ShopOwnerShareRate)
-	if !total.Equal(decimal.NewFromInt(100)) {
-		return fmt.Errorf("分成比例总和必须为100%%，当前为%s%%", total.String())
+	if _, err := s.accountService.PostJournalInTx(outerTx, ctx, journal); err != nil {
+		return fmt.Errorf("结算记账失败: %w", err)
 	}
 
-	config.Status = 1
-	config.EffectiveFrom = time.Now()
-	return s.db.Create(config).Error
+	return nil
 }
 
-// ProcessShopeeSettlement 处理 Shopee 结算 (定时任务调用) - 遍历所有分表
-func (s *SettlementService) ProcessShopeeSettlement(ctx context.Context) (int, error) {
-	settledCount := 0
-
-	// 遍历所有分表
-	for i := 0; i < database.ShardCount; i++ {
-		shipmentRecordTable := fmt.Sprintf("order_shipment_records_%d", i)
-
-		// 查找已发货但未结算的订单
-		var records []models.OrderShipmentRecord
-		err := s.db.Table(shipmentRecordTable).Where("status = ?", models.ShipmentRecordStatusShipped).Find(&records).Error
-		if err != nil {
-			continue
+// settlementUnallocatedLoss 结算时计入未分摊亏损科目的金额：
+// 未使用分成规则时负利润中店主/平台的份额不扣回；另加各承担方余额不足未扣回的部分（uncovered）
+func settlementUnallocatedLoss(settlement *models.OrderSettlement, uncovered decimal.Decimal) decimal.Decimal {
+	loss := uncovered
+	if settlement.ProfitShareRuleID == 0 {
+		if settlement.ShopOwnerShare.LessThan(decimal.Zero) {
+			loss = loss.Add(settlement.ShopOwnerShare.Abs())
 		}
-
-		for _, record := range records {
-			// 检查 Shopee 是否已结算 (通过 finance_incomes 分表，仅作触发条件)
-			financeTable := database.GetFinanceIncomeTableName(record.ShopID)
-			var income models.FinanceIncome
-			err := s.db.Table(financeTable).Where("order_sn = ? AND transaction_type = ?", record.OrderSN, models.TransactionTypeEscrowVerifiedAdd).First(&income).Error
-			if err != nil {
-				continue // Shopee 还未结算
-			}
-
-			// 检查是否已处理
-			if income.SettlementHandleStatus == models.SettlementStatusCompleted {
-				continue
-			}
-
-			// 结算金额以 orders_x.prepayment_amount 为准（订单入系统时已扣的预付款）
-			orderTable := database.GetOrderTableName(record.ShopID)
-			var order models.Order
-			if err := s.db.Table(orderTable).Where("shop_id = ? AND order_sn = ?", record.ShopID, record.OrderSN).
-				Select("prepayment_amount").First(&order).Error; err != nil {
-				continue
-			}
-			escrowAmount := order.PrepaymentAmount
-			if !escrowAmount.IsPositive() {
-				escrowAmount = record.PrepaymentAmount
-			}
-			if !escrowAmount.IsPositive() {
-				escrowAmount = income.Amount
-			}
-
-			// 执行结算（按 prepayment_amount 分账）
-			_, err = s.SettleOrder(ctx, record.ShopID, record.OrderSN, escrowAmount)
-			if err != nil {
-				continue
-			}
-
-			// 标记已处理
-			s.db.Table(financeTable).Where("id = ?", income.ID).Update("settlement_handle_status", models.SettlementStatusCompleted)
-
-			settledCount++
+		if settlement.PlatformShare.LessThan(decimal.Zero) {
+			loss = loss.Add(settlement.PlatformShare.Abs())
 		}
 	}
+	return loss
+}
 
-	return settledCount, nil
+// coverLoss 按账户余额拆分应扣回的亏损：余额不足时只扣到 0，其余为未扣回部分
+func coverLoss(loss, balance decimal.Decimal) (covered, uncovered decimal.Decimal) {
+	covered = decimal.Min(loss, balance)
+	if covered.IsNegative() {
+		covered = decimal.Zero
+	}
+	return covered, loss.Sub(covered)
 }
 
-// ProcessShopeeAdjustments 处理 Shopee 调账 (定时任务调用) - 遍历所有分表
-// 当虾皮发生退款、扣款等调账时，需要反向分账
-func (s *SettlementService) ProcessShopeeAdjustments(ctx context.Context) (int, error) {
-	adjustedCount := 0
+// deductLossInTx 从承担方账户扣回其承担的亏损（事务参与版本），返回扣回流水（未扣款时为 nil）与未扣回金额
+// 账户不存在或余额不足时只扣可扣部分，未扣回部分由调用方计入未分摊亏损，避免订单因余额不足每次结算都失败
+func (s *SettlementService) deductLossInTx(db *gorm.DB, ctx context.Context, accountType string, adminID int64, loss decimal.Decimal, currency string, orderSN string, remark string, idempotencyKey string) (*models.AccountTransaction, decimal.Decimal, error) {
+	currency = NormalizeCurrency(currency)
+	query := db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("balance")
+	switch accountType {
+	case models.AccountTypeOperator:
+		query = query.Model(&models.OperatorAccount{}).Where("admin_id = ? AND currency = ?", adminID, currency)
+	case models.AccountTypeShopOwnerCommission:
+		query = query.Model(&models.ShopOwnerCommissionAccount{}).Where("admin_id = ? AND currency = ?", adminID, currency)
+	case models.AccountTypePlatformCommission:
+		query = query.Model(&models.PlatformCommissionAccount{}).Where("currency = ?", currency)
+	default:
+		return nil, decimal.Zero, fmt.Errorf("不支持的账户类型: %s", accountType)
+	}
 
-	// 遍历所有分表
-	for i := 0; i < database.ShardCount; i++ {
-		financeTable := fmt.Sprintf("finance_incomes_%d", i)
-
-		// 查找未处理的调账记录（退款、调账等）
-		var incomes []models.FinanceIncome
-		err := s.db.Table(financeTable).
-			Where("settlement_handle_status = ?", models.SettlementStatusPending).
-			Where("order_sn != ''").
-			Where("transaction_type IN ?", []string{
-				models.TransactionTypeRefund,
-				models.TransactionTypeEscrowAdjustment,
-				models.TransactionTypeSellerAdjustment,
-				models.TransactionTypeCommissionAdjust,
-				models.TransactionTypeServiceFeeAdjust,
-				models.TransactionTypeShippingFeeAdjust,
-			}).
-			Find(&incomes).Error
-		if err != nil {
-			continue
-		}
+	balance := decimal.Zero
+	if err := query.Row().Scan(&balance); err != nil && err != sql.ErrNoRows {
+		return nil, decimal.Zero, err
+	}
 
-		for _, income := range incomes {
-			// 处理调账
-			err := s.handleAdjustment(ctx, &income)
-			if err != nil {
-				fmt.Printf("[Settlement] 处理调账失败 order_sn=%s: %v\n", income.OrderSN, err)
-				continue
-			}
+	// 幂等：已扣回过时按原流水金额计算未扣回部分
+	existing, err := s.accountService.findIdempotentTransaction(db, adminID, idempotencyKey, accountType, models.TxTypeAdjustment)
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	if existing != nil {
+		return existing, loss.Add(existing.Amount), nil
+	}
 
-			// 标记已处理
-			s.db.Table(financeTable).Where("id = ?", income.ID).Update("settlement_handle_status", models.SettlementStatusCompleted)
-			adjustedCount++
-		}
+	covered, uncovered := coverLoss(loss, balance)
+	if !covered.IsPositive() {
+		return nil, uncovered, nil
+	}
+	var tx *models.AccountTransaction
+	switch accountType {
+	case models.AccountTypeOperator:
+		tx, err = s.accountService.DeductOperatorBalanceInTx(db, ctx, adminID, covered, currency, orderSN, remark, idempotencyKey)
+	case models.AccountTypeShopOwnerCommission:
+		tx, err = s.accountService.DeductShopOwnerCommissionInTx(db, ctx, adminID, covered, currency, orderSN, remark, idempotencyKey)
+	default:
+		tx, err = s.accountService.DeductPlatformCommissionInTx(db, ctx, covered, currency, orderSN, remark, idempotencyKey)
 	}
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	return tx, uncovered, nil
+}
+
+// handleAdjustment 处理单个调账记录（每笔调账写入一条结算调账明细，次数不限）
+// 返回的 skipReason 非空表示未动账（调账金额为0或无已结算的原结算单）
+func (s *SettlementService) handleAdjustment(ctx context.Context, income *models.FinanceIncome) (skipReason string, err error) {
+	return s.applySettlementAdjustment(ctx, settlementAdjustmentInput{
+		ShopID:          income.ShopID,
+		OrderSN:         income.OrderSN,
+		TransactionID:   income.TransactionID,
+		TransactionType: income.TransactionType,
+		OrderAmount:     income.Amount,
+		Remark:          fmt.Sprintf("虾皮调账: %s", income.TransactionType),
+	})
+}
 
-	return adjustedCount, nil
+// settlementAdjustmentInput 对已结算订单追加一笔调账（金额为订单币种）
+// TransactionID 为调账明细的幂等键：虾皮调账取虾皮交易ID，平台发起的调账取负数避免与虾皮交易ID冲突
+type settlementAdjustmentInput struct {
+	ShopID          uint64
+	OrderSN         string
+	TransactionID   int64
+	TransactionType string
+	OrderAmount     decimal.Decimal
+	Remark          string
 }
 
-// handleAdjustment 处理单个调账记录（写入原结算单的 adj1/adj2/adj3 预留字段，最多3次）
-func (s *SettlementService) handleAdjustment(ctx context.Context, income *models.FinanceIncome) error {
-	settlementTable := database.GetOrderSettlementTableName(income.ShopID)
+// applySettlementAdjustment 按原结算单的分成依据划转调账金额并写入调账明细
+// 原结算使用了分成规则时按规则快照重算调账前后利润的分成，差额即各方调账金额；否则按原结算比例分摊
+func (s *SettlementService) applySettlementAdjustment(ctx context.Context, in settlementAdjustmentInput) (skipReason string, err error) {
+	settlementTable := database.GetOrderSettlementTableName(in.ShopID)
+	adjustmentTable := database.GetOrderSettlementAdjustmentTableName(in.ShopID)
 
-	adjustAmount := income.Amount
-	if adjustAmount.IsZero() {
-		return nil
+	orderAmount := in.OrderAmount
+	if orderAmount.IsZero() {
+		return "调账金额为0", nil
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		// 1. 查找原结算记录并加锁
 		var original models.OrderSettlement
 		if err := tx.Table(settlementTable).Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("order_sn = ? AND status = ?", income.OrderSN, models.OrderSettlementCompleted).
+			Where("order_sn = ? AND status = ?", in.OrderSN, models.OrderSettlementCompleted).
 			First(&original).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
-				return nil // 无原结算则跳过
+				skipReason = "无已结算的原结算单" // 无原结算则跳过
+				return nil
 			}
 			return err
 		}
 
-		if original.AdjustmentCount >= 3 {
-			return fmt.Errorf("该订单调账已达3次上限")
+		// 同一笔交易已写入调账明细（上次处理后未能标记财务收入）则直接视为成功
+		var exists int64
+		if err := tx.Table(adjustmentTable).
+			Where("shop_id = ? AND transaction_id = ?", in.ShopID, in.TransactionID).
+			Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists > 0 {
+			return nil
 		}
 
-		hundred := decimal.NewFromInt(100)
-		platformAdjust := adjustAmount.Mul(original.PlatformShareRate).Div(hundred).Round(2)
-		operatorAdjust := adjustAmount.Mul(original.OperatorShareRate).Div(hundred).Round(2)
-		shopOwnerAdjust := adjustAmount.Sub(platformAdjust).Sub(operatorAdjust)
-		adjRemark := fmt.Sprintf("虾皮调账: %s", income.TransactionType)
+		// 原结算做过币种折算时，调账按结算时锁定的汇率折算到结算币种
+		adjustAmount := orderAmount
+		if original.OrderCurrency != "" && original.OrderCurrency != original.Currency {
+			adjustAmount = adjustAmount.Mul(original.FxRate).Round(2)
+		}
+
+		rule, err := parseProfitShareRuleSnapshot(original.ProfitShareRuleSnapshot)
+		if err != nil {
+			return err
+		}
+		var adjustedBefore struct {
+			Amount decimal.Decimal
+		}
+		if rule != nil {
+			if err := tx.Table(adjustmentTable).Select("COALESCE(SUM(amount), 0) AS amount").
+				Where("settlement_id = ?", original.ID).Scan(&adjustedBefore).Error; err != nil {
+				return err
+			}
+		}
+		config := &models.ProfitShareConfig{
+			PlatformShareRate:  original.PlatformShareRate,
+			OperatorShareRate:  original.OperatorShareRate,
+			ShopOwnerShareRate: original.ShopOwnerShareRate,
+		}
+		allocation, err := AllocateAdjustment(original.Profit.Add(adjustedBefore.Amount), adjustAmount, config, rule)
+		if err != nil {
+			return err
+		}
+		platformAdjust := allocation.PlatformShare
+		operatorAdjust := allocation.OperatorShare
+		shopOwnerAdjust := allocation.ShopOwnerShare
 
 		// 2. 执行调账资金划转
 		adjSettlement := &models.OrderSettlement{
-			OrderSN:     income.OrderSN,
+			OrderSN:     in.OrderSN,
+			Currency:    original.Currency,
 			ShopOwnerID: original.ShopOwnerID,
 			OperatorID:  original.OperatorID,
 			EscrowAmount: adjustAmount,
@@ -358,110 +425,174 @@ func (s *SettlementService) handleAdjustment(ctx context.Context, income *models
 			OperatorShare: operatorAdjust,
 			ShopOwnerShare: shopOwnerAdjust,
 		}
-		if err := s.executeAdjustmentInTx(tx, ctx, adjSettlement); err != nil {
+		// 幂等键按交易ID生成：同一笔调账重复处理时不重复动账
+		keyPrefix := fmt.Sprintf("adjustment:%d:%d", in.ShopID, in.TransactionID)
+		if err := s.executeAdjustmentInTx(tx, ctx, adjSettlement, keyPrefix); err != nil {
 			return fmt.Errorf("执行调账: %w", err)
 		}
 
-		// 3. 写入对应的 adjN 预留字段
-		now := time.Now()
-		slot := original.AdjustmentCount + 1
-		updates := map[string]interface{}{
-			"adjustment_count": slot,
-		}
-		switch slot {
-		case 1:
-			updates["adj1_amount"] = adjustAmount
-			updates["adj1_platform_share"] = platformAdjust
-			updates["adj1_operator_share"] = operatorAdjust
-			updates["adj1_shop_owner_share"] = shopOwnerAdjust
-			updates["adj1_at"] = now
-			updates["adj1_remark"] = adjRemark
-		case 2:
-			updates["adj2_amount"] = adjustAmount
-			updates["adj2_platform_share"] = platformAdjust
-			updates["adj2_operator_share"] = operatorAdjust
-			updates["adj2_shop_owner_share"] = shopOwnerAdjust
-			updates["adj2_at"] = now
-			updates["adj2_remark"] = adjRemark
-		case 3:
-			updates["adj3_amount"] = adjustAmount
-			updates["adj3_platform_share"] = platformAdjust
-			updates["adj3_operator_share"] = operatorAdjust
-			updates["adj3_shop_owner_share"] = shopOwnerAdjust
-			updates["adj3_at"] = now
-			updates["adj3_remark"] = adjRemark
-		}
-
-		return tx.Table(settlementTable).Where("id = ?", original.ID).Updates(updates).Error
+		// 3. 写入调账明细并累加原结算单的调账次数
+		id, err := s.idGen.GenerateSettlementAdjustmentID(ctx)
+		if err != nil {
+			return fmt.Errorf("生成调账明细ID失败: %w", err)
+		}
+		adjustment := &models.OrderSettlementAdjustment{
+			ID:              uint64(id),
+			SettlementID:    original.ID,
+			ShopID:          in.ShopID,
+			OrderSN:         in.OrderSN,
+			TransactionID:   in.TransactionID,
+			TransactionType: in.TransactionType,
+			Currency:        original.Currency,
+			OrderAmount:     orderAmount,
+			Amount:          adjustAmount,
+			PlatformShare:   platformAdjust,
+			OperatorShare:   operatorAdjust,
+			ShopOwnerShare:  shopOwnerAdjust,
+			Remark:          in.Remark,
+		}
+		if err := tx.Table(adjustmentTable).Create(adjustment).Error; err != nil {
+			return err
+		}
+
+		return tx.Table(settlementTable).Where("id = ?", original.ID).
+			Update("adjustment_count", gorm.Expr("adjustment_count + 1")).Error
 	})
+	return skipReason, err
+}
+
+// GetSettlementAdjustments 获取订单的全部调账明细（按发生时间正序）
+func (s *SettlementService) GetSettlementAdjustments(ctx context.Context, shopID uint64, orderSN string) ([]models.OrderSettlementAdjustment, error) {
+	var adjustments []models.OrderSettlementAdjustment
+	err := s.db.Table(database.GetOrderSettlementAdjustmentTableName(shopID)).
+		Where("shop_id = ? AND order_sn = ?", shopID, orderSN).
+		Order("created_at ASC, id ASC").
+		Find(&adjustments).Error
+	return adjustments, err
+}
+
+// fillSettlementAdjustments 批量填充结算记录的调账明细（按分表 IN 查询，避免逐条查询）
+func (s *SettlementService) fillSettlementAdjustments(settlements []models.OrderSettlement) {
+	shardIDs := make(map[int][]uint64)
+	shardShopID := make(map[int]uint64)
+	for _, st := range settlements {
+		if st.AdjustmentCount == 0 {
+			continue
+		}
+		idx := database.GetShardIndex(st.ShopID)
+		shardIDs[idx] = append(shardIDs[idx], st.ID)
+		shardShopID[idx] = st.ShopID
+	}
+
+	adjustmentMap := make(map[uint64][]models.OrderSettlementAdjustment)
+	for idx, ids := range shardIDs {
+		var adjustments []models.OrderSettlementAdjustment
+		s.db.Table(database.GetOrderSettlementAdjustmentTableName(shardShopID[idx])).
+			Where("settlement_id IN ?", ids).
+			Order("created_at ASC, id ASC").
+			Find(&adjustments)
+		for _, adj := range adjustments {
+			adjustmentMap[adj.SettlementID] = append(adjustmentMap[adj.SettlementID], adj)
+		}
+	}
+
+	for i := range settlements {
+		settlements[i].Adjustments = adjustmentMap[settlements[i].ID]
+	}
 }
 
 // executeAdjustmentInTx 执行调账资金划转（复用调用方事务）
 // 当调账为扣款（负数）时，需将扣款金额返还给店铺老板预付款账户
-func (s *SettlementService) executeAdjustmentInTx(outerTx *gorm.DB, ctx context.Context, settlement *models.OrderSettlement) error {
+func (s *SettlementService) executeAdjustmentInTx(outerTx *gorm.DB, ctx context.Context, settlement *models.OrderSettlement, keyPrefix string) error {
+	journal := NewJournal(models.JournalBizAdjustment, settlement.OrderSN, settlement.SettlementNo,
+		fmt.Sprintf("虾皮调账%s", settlement.EscrowAmount.String())).
+		SetCurrency(settlement.Currency)
+
 	// 0. 调账扣款时：返还金额到店铺老板预付款（虾皮多扣了，需退还给店主）
 	if settlement.EscrowAmount.LessThan(decimal.Zero) {
 		refundAmount := settlement.EscrowAmount.Abs()
 		if refundAmount.GreaterThan(decimal.Zero) {
-			_, err := s.accountService.RefundPrepaymentAdjustmentInTx(outerTx, ctx, settlement.ShopOwnerID, refundAmount, settlement.OrderSN,
-				fmt.Sprintf("虾皮调账返还-退%s至预付款", refundAmount.String()))
+			at, err := s.accountService.RefundPrepaymentAdjustmentInTx(outerTx, ctx, settlement.ShopOwnerID, refundAmount, settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账返还-退%s至预付款", refundAmount.String()),
+				keyPrefix+":prepayment")
 			if err != nil {
 				return fmt.Errorf("返还预付款失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		}
 	}
 
 	// 1. 调整运营账户
 	if !settlement.OperatorShare.IsZero() {
 		if settlement.OperatorShare.GreaterThan(decimal.Zero) {
-			_, err := s.accountService.AddOperatorIncomeInTx(outerTx, ctx, settlement.OperatorID, settlement.OperatorShare, settlement.OrderSN,
-				fmt.Sprintf("虾皮调账补款-运营分成%s", settlement.OperatorShare.String()))
+			at, err := s.accountService.AddOperatorIncomeInTx(outerTx, ctx, settlement.OperatorID, settlement.OperatorShare, settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账补款-运营分成%s", settlement.OperatorShare.String()),
+				keyPrefix+":operator")
 			if err != nil {
 				return fmt.Errorf("增加运营调账收入失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		} else {
-			_, err := s.accountService.DeductOperatorBalanceInTx(outerTx, ctx, settlement.OperatorID, settlement.OperatorShare.Abs(), settlement.OrderSN,
-				fmt.Sprintf("虾皮调账扣款-运营分成%s", settlement.OperatorShare.Abs().String()))
+			at, err := s.accountService.DeductOperatorBalanceInTx(outerTx, ctx, settlement.OperatorID, settlement.OperatorShare.Abs(), settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账扣款-运营分成%s", settlement.OperatorShare.Abs().String()),
+				keyPrefix+":operator")
 			if err != nil {
 				return fmt.Errorf("扣除运营调账金额失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		}
 	}
 
 	// 2. 调整店主佣金账户
 	if !settlement.ShopOwnerShare.IsZero() {
 		if settlement.ShopOwnerShare.GreaterThan(decimal.Zero) {
-			_, err := s.accountService.AddShopOwnerCommissionInTx(outerTx, ctx, settlement.ShopOwnerID, settlement.ShopOwnerShare, settlement.OrderSN,
-				fmt.Sprintf("虾皮调账补款-店主分成%s", settlement.ShopOwnerShare.String()))
+			at, err := s.accountService.AddShopOwnerCommissionInTx(outerTx, ctx, settlement.ShopOwnerID, settlement.ShopOwnerShare, settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账补款-店主分成%s", settlement.ShopOwnerShare.String()),
+				keyPrefix+":shop_owner")
 			if err != nil {
 				return fmt.Errorf("增加店主调账佣金失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		} else {
-			_, err := s.accountService.DeductShopOwnerCommissionInTx(outerTx, ctx, settlement.ShopOwnerID, settlement.ShopOwnerShare.Abs(), settlement.OrderSN,
-				fmt.Sprintf("虾皮调账扣款-店主分成%s", settlement.ShopOwnerShare.Abs().String()))
+			at, err := s.accountService.DeductShopOwnerCommissionInTx(outerTx, ctx, settlement.ShopOwnerID, settlement.ShopOwnerShare.Abs(), settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账扣款-店主分成%s", settlement.ShopOwnerShare.Abs().String()),
+				keyPrefix+":shop_owner")
 			if err != nil {
 				return fmt.Errorf("扣除店主调账佣金失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		}
 	}
 
 	// 3. 调整平台佣金账户
 	if !settlement.PlatformShare.IsZero() {
 		if settlement.PlatformShare.GreaterThan(decimal.Zero) {
-			_, err := s.accountService.AddPlatformCommissionInTx(outerTx, ctx, settlement.PlatformShare, settlement.OrderSN,
-				fmt.Sprintf("虾皮调账补款-平台分成%s", settlement.PlatformShare.String()))
+			at, err := s.accountService.AddPlatformCommissionInTx(outerTx, ctx, settlement.PlatformShare, settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账补款-平台分成%s", settlement.PlatformShare.String()),
+				keyPrefix+":platform")
 			if err != nil {
 				return fmt.Errorf("增加平台调账佣金失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		} else {
-			_, err := s.accountService.DeductPlatformCommissionInTx(outerTx, ctx, settlement.PlatformShare.Abs(), settlement.OrderSN,
-				fmt.Sprintf("虾皮调账扣款-平台分成%s", settlement.PlatformShare.Abs().String()))
+			at, err := s.accountService.DeductPlatformCommissionInTx(outerTx, ctx, settlement.PlatformShare.Abs(), settlement.Currency, settlement.OrderSN,
+				fmt.Sprintf("虾皮调账扣款-平台分成%s", settlement.PlatformShare.Abs().String()),
+				keyPrefix+":platform")
 			if err != nil {
 				return fmt.Errorf("扣除平台调账佣金失败: %w", err)
 			}
+			journal.AddTransaction(at)
 		}
 	}
 
+	// 4. 对手方分录：调账补款时虾皮补打入的金额计入托管清算科目（扣款时返还预付款与各方扣回已自平衡）
+	if settlement.EscrowAmount.GreaterThan(decimal.Zero) {
+		journal.Debit(models.ClearingAccountEscrow, 0, models.JournalBucketAvailable, settlement.EscrowAmount, "", "虾皮调账补款")
+	}
+
+	if _, err := s.accountService.PostJournalInTx(outerTx, ctx, journal); err != nil {
+		return fmt.Errorf("调账记账失败: %w", err)
+	}
 	return nil
 }
 
@@ -532,7 +663,36 @@ func (s *SettlementService) GetSettlements(ctx context.Context, adminID int64, r
 		end = len(allSettlements)
 	}
 
-	return allSettlements[offset:end], total, nil
+	pageSettlements := allSettlements[offset:end]
+	s.fillSettlementAdjustments(pageSettlements)
+	return pageSettlements, total, nil
+}
+
+// GetSettlementDetail 获取单个订单的结算记录（含全部调账明细），adminID 为 0 时不校验归属（平台）
+func (s *SettlementService) GetSettlementDetail(ctx context.Context, adminID int64, role string, shopID uint64, orderSN string) (*models.OrderSettlement, error) {
+	query := s.db.Table(database.GetOrderSettlementTableName(shopID)).
+		Where("shop_id = ? AND order_sn = ?", shopID, orderSN)
+	switch role {
+	case "shop_owner":
+		query = query.Where("shop_owner_id = ?", adminID)
+	case "operator":
+		query = query.Where("operator_id = ?", adminID)
+	}
+
+	var settlement models.OrderSettlement
+	if err := query.First(&settlement).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("结算记录不存在")
+		}
+		return nil, err
+	}
+
+	adjustments, err := s.GetSettlementAdjustments(ctx, shopID, orderSN)
+	if err != nil {
+		return nil, err
+	}
+	settlement.Adjustments = adjustments
+	return &settlement, nil
 }
 
 // GetSettlementStats 获取结算统计 - 遍历所有分表
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCoverLoss(t *testing.T) {
+	d := decimal.RequireFromString
+	cases := []struct {
+		name                       string
+		loss, balance              string
+		wantCovered, wantUncovered string
+	}{
+		{"empty account", "120", "0", "0", "120"},
+		{"partial balance", "120", "45.5", "45.5", "74.5"},
+		{"enough balance", "120", "500", "120", "0"},
+		{"negative balance", "120", "-10", "0", "120"},
+	}
+	for _, tc := range cases {
+		covered, uncovered := coverLoss(d(tc.loss), d(tc.balance))
+		if !covered.Equal(d(tc.wantCovered)) || !uncovered.Equal(d(tc.wantUncovered)) {
+			t.Errorf("%s: coverLoss = %s, %s, want %s, %s", tc.name, covered, uncovered, tc.wantCovered, tc.wantUncovered)
+		}
+	}
+}
+
+func TestSettlementUnallocatedLoss(t *testing.T) {
+	d := decimal.RequireFromString
+	cases := []struct {
+		name       string
+		settlement models.OrderSettlement
+		uncovered  string
+		want       string
+	}{
+		{
+			name:       "no rule keeps negative shares unallocated",
+			settlement: models.OrderSettlement{ShopOwnerShare: d("-30"), PlatformShare: d("-20")},
+			uncovered:  "0",
+			want:       "50",
+		},
+		{
+			name:       "rule with empty commission account",
+			settlement: models.OrderSettlement{ProfitShareRuleID: 3, ShopOwnerShare: d("-30"), PlatformShare: d("-20")},
+			uncovered:  "30",
+			want:       "30",
+		},
+		{
+			name:       "rule fully covered",
+			settlement: models.OrderSettlement{ProfitShareRuleID: 3, ShopOwnerShare: d("-30"), PlatformShare: d("-20")},
+			uncovered:  "0",
+			want:       "0",
+		},
+		{
+			name:       "positive shares",
+			settlement: models.OrderSettlement{ShopOwnerShare: d("30"), PlatformShare: d("20")},
+			uncovered:  "0",
+			want:       "0",
+		},
+	}
+	for _, tc := range cases {
+		if got := settlementUnallocatedLoss(&tc.settlement, d(tc.uncovered)); !got.Equal(d(tc.want)) {
+			t.Errorf("%s: settlementUnallocatedLoss = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
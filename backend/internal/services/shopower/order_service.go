@@ -374,17 +374,20 @@ func (s *OrderService) ComputeOrderStats(ctx context.Context, adminID int64) (*O
 		stats.SettledCount += settledCount
 		stats.SettledAmount += settledSum
 
-		// 账款调整：有 order_escrow 且 drc_adjustable_refund / seller_return_refund / reverse_shipping_fee 任一非 0
+		// 账款调整：order_escrow 的 drc_adjustable_refund / seller_return_refund / reverse_shipping_fee 任一非 0，或结算后发生过虾皮调账
+		adjustmentTable := fmt.Sprintf("order_settlement_adjustments_%d", idx)
+		adjCond := "o.shop_id IN ? AND ((e.id IS NOT NULL AND (e.drc_adjustable_refund != 0 OR e.seller_return_refund != 0 OR e.reverse_shipping_fee != 0)) OR " +
+			"EXISTS (SELECT 1 FROM " + adjustmentTable + " a WHERE a.shop_id = o.shop_id AND a.order_sn = o.order_sn))"
 		var adjCount int64
 		var adjSum float64
 		s.db.Table(orderTable+" AS o").
-			Joins("INNER JOIN "+escrowTable+" e ON o.shop_id = e.shop_id AND o.order_sn = e.order_sn").
-			Where("o.shop_id IN ? AND (e.drc_adjustable_refund != 0 OR e.seller_return_refund != 0 OR e.reverse_shipping_fee != 0)", sids).
+			Joins("LEFT JOIN "+escrowTable+" e ON o.shop_id = e.shop_id AND o.order_sn = e.order_sn").
+			Where(adjCond, sids).
 			Count(&adjCount)
 		s.db.Table(orderTable+" AS o").
 			Select("COALESCE(SUM(o.total_amount),0)").
-			Joins("INNER JOIN "+escrowTable+" e ON o.shop_id = e.shop_id AND o.order_sn = e.order_sn").
-			Where("o.shop_id IN ? AND (e.drc_adjustable_refund != 0 OR e.seller_return_refund != 0 OR e.reverse_shipping_fee != 0)", sids).
+			Joins("LEFT JOIN "+escrowTable+" e ON o.shop_id = e.shop_id AND o.order_sn = e.order_sn").
+			Where(adjCond, sids).
 			Scan(&adjSum)
 		stats.AdjustmentCount += adjCount
 		stats.AdjustmentAmount += adjSum
@@ -444,8 +447,9 @@ func (s *OrderService) batchFillOrderLabels(orders []models.Order) {
 		shardShopID[idx] = o.ShopID
 	}
 
-	// 批量查询每个分表的 escrow 数据
+	// 批量查询每个分表的 escrow 数据及结算调账明细
 	escrowMap := make(map[string]models.OrderEscrow) // key = "shopID:orderSN"
+	adjustmentMap := make(map[string][]models.OrderSettlementAdjustment)
 	for idx, orderSNs := range shardOrders {
 		escrowTable := database.GetOrderEscrowTableName(shardShopID[idx])
 		var escrows []models.OrderEscrow
@@ -454,15 +458,23 @@ func (s *OrderService) batchFillOrderLabels(orders []models.Order) {
 			key := fmt.Sprintf("%d:%s", e.ShopID, e.OrderSN)
 			escrowMap[key] = e
 		}
+
+		adjustmentTable := database.GetOrderSettlementAdjustmentTableName(shardShopID[idx])
+		var adjustments []models.OrderSettlementAdjustment
+		s.db.Table(adjustmentTable).Where("order_sn IN ?", orderSNs).Order("created_at ASC, id ASC").Find(&adjustments)
+		for _, adj := range adjustments {
+			key := fmt.Sprintf("%d:%s", adj.ShopID, adj.OrderSN)
+			adjustmentMap[key] = append(adjustmentMap[key], adj)
+		}
 	}
 
 	// 填充每个订单的标签
 	for i := range orders {
 		key := fmt.Sprintf("%d:%s", orders[i].ShopID, orders[i].OrderSN)
 		if escrow, ok := escrowMap[key]; ok {
-			s.fillOrderLabelsWithEscrow(&orders[i], &escrow)
+			s.fillOrderLabelsWithEscrow(&orders[i], &escrow, adjustmentMap[key])
 		} else {
-			s.fillOrderLabelsWithEscrow(&orders[i], nil)
+			s.fillOrderLabelsWithEscrow(&orders[i], nil, adjustmentMap[key])
 		}
 	}
 }
@@ -509,23 +521,29 @@ func (s *OrderService) batchFillOrderItems(orders []models.Order) {
 // fillOrderLabels 填充订单显示标签（单条查询版本，供 GetOrder 等单条场景使用）
 func (s *OrderService) fillOrderLabels(order *models.Order) {
 	escrowTable := database.GetOrderEscrowTableName(order.ShopID)
+	var adjustments []models.OrderSettlementAdjustment
+	s.db.Table(database.GetOrderSettlementAdjustmentTableName(order.ShopID)).
+		Where("shop_id = ? AND order_sn = ?", order.ShopID, order.OrderSN).
+		Order("created_at ASC, id ASC").
+		Find(&adjustments)
+
 	var escrow models.OrderEscrow
 	if err := s.db.Table(escrowTable).
 		Where("shop_id = ? AND order_sn = ?", order.ShopID, order.OrderSN).
 		First(&escrow).Error; err == nil {
-		s.fillOrderLabelsWithEscrow(order, &escrow)
+		s.fillOrderLabelsWithEscrow(order, &escrow, adjustments)
 	} else {
-		s.fillOrderLabelsWithEscrow(order, nil)
+		s.fillOrderLabelsWithEscrow(order, nil, adjustments)
 	}
 }
 
-// fillOrderLabelsWithEscrow 填充订单显示标签（核心逻辑，escrow 可为 nil）
+// fillOrderLabelsWithEscrow 填充订单显示标签（核心逻辑，escrow 可为 nil，adjustments 为结算后的虾皮调账明细）
 //
 // Label 含义：
 //   - AdjustmentLabel1: 佣金信息（头部右上角）
 //   - AdjustmentLabel2: 订单金额/账款调整（头部右上角）
 //   - AdjustmentLabel3: 虾皮订单金额/账款调整（商品列表下方）
-func (s *OrderService) fillOrderLabelsWithEscrow(order *models.Order, escrow *models.OrderEscrow) {
+func (s *OrderService) fillOrderLabelsWithEscrow(order *models.Order, escrow *models.OrderEscrow, adjustments []models.OrderSettlementAdjustment) {
 	currency := order.Currency
 	if currency == "" {
 		currency = "NT$"
@@ -533,14 +551,27 @@ func (s *OrderService) fillOrderLabelsWithEscrow(order *models.Order, escrow *mo
 	amount := order.TotalAmount.StringFixed(2)
 	hasEscrow := escrow != nil
 
+	// 结算后调账合计（订单币种，正数补款/负数扣款）
+	order.SettlementAdjustments = adjustments
+	settledAdjust := decimal.Zero
+	for _, adj := range adjustments {
+		settledAdjust = settledAdjust.Add(adj.OrderAmount)
+	}
+	hasAdjustment := len(adjustments) > 0
+
 	switch order.OrderStatus {
 	case consts.OrderStatusCompleted:
 		if hasEscrow {
 			commission := escrow.CommissionFee.Add(escrow.ServiceFee).Abs()
 			escrowAmt := escrow.EscrowAmount
 			order.AdjustmentLabel1 = fmt.Sprintf("已结算佣金：%s%s", currency, commission.StringFixed(2))
-			order.AdjustmentLabel2 = fmt.Sprintf("订单金额：%s%s", currency, amount)
-			order.AdjustmentLabel3 = fmt.Sprintf("虾皮订单结算：%s%s", currency, escrowAmt.StringFixed(2))
+			if hasAdjustment {
+				order.AdjustmentLabel2 = fmt.Sprintf("订单账款调整：%s%s（共%d笔）", currency, settledAdjust.StringFixed(2), len(adjustments))
+				order.AdjustmentLabel3 = fmt.Sprintf("虾皮订单结算：%s%s", currency, escrowAmt.Add(settledAdjust).StringFixed(2))
+			} else {
+				order.AdjustmentLabel2 = fmt.Sprintf("订单金额：%s%s", currency, amount)
+				order.AdjustmentLabel3 = fmt.Sprintf("虾皮订单结算：%s%s", currency, escrowAmt.StringFixed(2))
+			}
 		} else {
 			order.AdjustmentLabel1 = fmt.Sprintf("已结算佣金：%s--", currency)
 			order.AdjustmentLabel2 = fmt.Sprintf("订单金额：%s%s", currency, amount)
@@ -552,6 +583,10 @@ func (s *OrderService) fillOrderLabelsWithEscrow(order *models.Order, escrow *mo
 			adjustTotal := escrow.SellerReturnRefund.Add(escrow.DrcAdjustableRefund).Add(escrow.ReverseShippingFee)
 			commission := escrow.CommissionFee.Add(escrow.ServiceFee).Abs()
 			escrowAmt := escrow.EscrowAmount
+			if hasAdjustment {
+				adjustTotal = settledAdjust
+				escrowAmt = escrowAmt.Add(settledAdjust)
+			}
 			order.AdjustmentLabel1 = fmt.Sprintf("账款调整佣金：%s%s", currency, commission.StringFixed(2))
 			if adjustTotal.IsZero() {
 				order.AdjustmentLabel2 = fmt.Sprintf("订单账款调整：%s%s", currency, amount)
@@ -16,10 +16,16 @@ import (
 	"gorm.io/gorm"
 )
 
+// BindCheckFunc 绑定新店铺前的检查回调（由上层注入，避免循环依赖），返回 error 时拒绝绑定
+type BindCheckFunc func(ctx context.Context, adminID int64) error
+
 // ShopService 店铺服务（店主专用）
 type ShopService struct {
 	db          *gorm.DB
 	idGenerator *utils.IDGenerator
+
+	// 绑定新店铺前的检查回调（如保证金检查）
+	onBindCheck BindCheckFunc
 }
 
 // NewShopService 创建店铺服务
@@ -30,6 +36,19 @@ func NewShopService() *ShopService {
 	}
 }
 
+// SetBindCheckFunc 设置绑定新店铺前的检查回调（由上层在初始化时注入）
+func (s *ShopService) SetBindCheckFunc(fn BindCheckFunc) {
+	s.onBindCheck = fn
+}
+
+// checkBind 绑定新店铺前执行检查回调
+func (s *ShopService) checkBind(ctx context.Context, adminID int64) error {
+	if s.onBindCheck == nil || adminID <= 0 {
+		return nil
+	}
+	return s.onBindCheck(ctx, adminID)
+}
+
 // GetAuthURL 获取授权链接
 func (s *ShopService) GetAuthURL(ctx context.Context, adminID int64) (string, error) {
 	cfg := config.Get().Shopee
@@ -83,6 +102,9 @@ func (s *ShopService) HandleAuthCallback(ctx context.Context, code string, shopI
 			}
 			// 如果店铺未绑定用户且传入了有效的 adminID，则绑定
 			if existingShop.AdminID == 0 && adminID > 0 {
+				if err := s.checkBind(ctx, adminID); err != nil {
+					return err
+				}
 				updates["admin_id"] = adminID
 				fmt.Printf("[DEBUG] 绑定店铺 %d 到用户 %d\n", shopID, adminID)
 			}
@@ -90,6 +112,9 @@ func (s *ShopService) HandleAuthCallback(ctx context.Context, code string, shopI
 				return fmt.Errorf("更新店铺信息失败: %w", err)
 			}
 		} else if err == gorm.ErrRecordNotFound {
+			if err := s.checkBind(ctx, adminID); err != nil {
+				return err
+			}
 			shopId, _ := s.idGenerator.GenerateShopID(ctx)
 			authId = uint64(shopId)
 			shop := models.Shop{
@@ -117,12 +142,12 @@ func (s *ShopService) HandleAuthCallback(ctx context.Context, code string, shopI
 
 		var existingAuth models.ShopAuthorization
 		if err := tx.Where("shop_id = ?", shopID).First(&existingAuth).Error; err == nil {
-			// 更新已有授权信息，只更新需要的字段
-			if err := tx.Model(&existingAuth).Updates(map[string]interface{}{
-				"access_token":       tokenResp.AccessToken,
-				"refresh_token":      tokenResp.RefreshToken,
-				"expires_at":         expiresAt,
-				"refresh_expires_at": refreshExpiresAt,
+			// 更新已有授权信息，只更新需要的字段（令牌为加密字段，须用结构体更新才会经过加密序列化器）
+			if err := tx.Model(&existingAuth).Select("access_token", "refresh_token", "expires_at", "refresh_expires_at").Updates(&models.ShopAuthorization{
+				AccessToken:      tokenResp.AccessToken,
+				RefreshToken:     tokenResp.RefreshToken,
+				ExpiresAt:        expiresAt,
+				RefreshExpiresAt: refreshExpiresAt,
 			}).Error; err != nil {
 				return fmt.Errorf("更新授权信息失败: %w", err)
 			}
@@ -146,9 +171,17 @@ func (s *ShopService) HandleAuthCallback(ctx context.Context, code string, shopI
 	})
 }
 
+// cacheToken 缓存店铺令牌（令牌加密后写入 Redis）
 func (s *ShopService) cacheToken(ctx context.Context, shopID uint64, accessToken, refreshToken string, expiresAt time.Time) {
 	rdb := database.GetRedis()
 	key := fmt.Sprintf(consts.KeyShopToken, shopID)
+	var err error
+	if accessToken, err = utils.EncryptField(accessToken); err != nil {
+		return
+	}
+	if refreshToken, err = utils.EncryptField(refreshToken); err != nil {
+		return
+	}
 	data, _ := json.Marshal(map[string]interface{}{
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
@@ -179,7 +212,10 @@ func (s *ShopService) GetAccessToken(ctx context.Context, shopID uint64) (string
 		var tokenData map[string]interface{}
 		if json.Unmarshal([]byte(data), &tokenData) == nil {
 			if token, ok := tokenData["access_token"].(string); ok {
-				return token, nil
+				// 解密失败（如旧主密钥已删除）时回源数据库
+				if token, err := utils.DecryptField(token); err == nil {
+					return token, nil
+				}
 			}
 		}
 	}
@@ -343,6 +379,11 @@ func (s *ShopService) BindShop(ctx context.Context, adminID int64, shopID int64)
 	if shop.AdminID > 0 && shop.AdminID != adminID {
 		return utils.ErrShopAlreadyBound
 	}
+	if shop.AdminID != adminID {
+		if err := s.checkBind(ctx, adminID); err != nil {
+			return err
+		}
+	}
 	return s.db.Model(&shop).Update("admin_id", adminID).Error
 }
 
@@ -0,0 +1,575 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/backend/internal/database"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// statementAccountTypes 生成月结账单的账户类型
+var statementAccountTypes = []string{
+	models.AccountTypePrepayment,
+	models.AccountTypeDeposit,
+	models.AccountTypeOperator,
+	models.AccountTypeShopOwnerCommission,
+	models.AccountTypePlatformCommission,
+}
+
+// StatementService 月结账单服务（按流水分表生成账户月结账单，签核后可校验账单未被篡改）
+type StatementService struct {
+	db *gorm.DB
+}
+
+// NewStatementService 创建月结账单服务
+func NewStatementService() *StatementService {
+	return &StatementService{
+		db: database.GetDB(),
+	}
+}
+
+// StatementQuery 账单查询条件（AdminID 为空时不按用户过滤）
+type StatementQuery struct {
+	Period       string
+	Currency     string
+	AccountTypes []string
+	AdminID      *int64
+}
+
+// StatementVerifyResult 账期校验结果
+type StatementVerifyResult struct {
+	Period             *models.AccountStatementPeriod `json:"period"`
+	Verified           bool                           `json:"verified"`
+	DigestMatched      bool                           `json:"digest_matched"`      // 账期摘要与账单校验和一致
+	TamperedStatements []string                       `json:"tampered_statements"` // 内容与校验和不符的账单号
+	LedgerMismatches   []string                       `json:"ledger_mismatches"`   // 与流水重算结果不符的账户
+}
+
+// ParseStatementPeriod 解析账期（YYYY-MM），返回 [start, end) 时间范围
+func ParseStatementPeriod(period string) (start, end time.Time, err error) {
+	start, err = time.ParseInLocation("2006-01", period, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("账期格式错误，应为 YYYY-MM")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// PreviousStatementPeriod 上一个自然月的账期
+func PreviousStatementPeriod(now time.Time) string {
+	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return firstDay.AddDate(0, -1, 0).Format("2006-01")
+}
+
+// GenerateMonthlyStatements 生成指定账期的月结账单
+// 已生成或已签核的账期不会重新生成；生成失败的账期可重新执行
+func (s *StatementService) GenerateMonthlyStatements(ctx context.Context, period string) (*models.AccountStatementPeriod, error) {
+	start, end, err := ParseStatementPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	if end.After(time.Now()) {
+		return nil, fmt.Errorf("账期 %s 尚未结束", period)
+	}
+
+	var p models.AccountStatementPeriod
+	err = s.db.WithContext(ctx).Where("period = ?", period).First(&p).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		p = models.AccountStatementPeriod{Period: period, Status: models.StatementPeriodGenerating}
+		if err := s.db.WithContext(ctx).Create(&p).Error; err != nil {
+			return nil, fmt.Errorf("创建账期失败: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	case p.Status == models.StatementPeriodGenerated || p.Status == models.StatementPeriodSignedOff:
+		return &p, fmt.Errorf("账期 %s 账单已生成", period)
+	}
+
+	var statements []models.AccountStatement
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var buildErr error
+		statements, buildErr = s.buildStatements(tx, period, start, end)
+		return buildErr
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	if err == nil {
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var lines []models.AccountStatementLine
+			for i := range statements {
+				lines = append(lines, statements[i].Lines...)
+			}
+			if len(statements) > 0 {
+				if err := tx.CreateInBatches(statements, 100).Error; err != nil {
+					return fmt.Errorf("保存账单失败: %w", err)
+				}
+			}
+			if len(lines) > 0 {
+				if err := tx.CreateInBatches(lines, 500).Error; err != nil {
+					return fmt.Errorf("保存账单明细失败: %w", err)
+				}
+			}
+			now := time.Now()
+			p.Status = models.StatementPeriodGenerated
+			p.StatementCount = int64(len(statements))
+			p.Digest = statementDigest(statements)
+			p.ErrorMessage = ""
+			p.GeneratedAt = &now
+			return tx.Save(&p).Error
+		})
+	}
+	if err != nil {
+		p.Status = models.StatementPeriodFailed
+		p.ErrorMessage = err.Error()
+		s.db.Save(&p)
+		return &p, err
+	}
+	return &p, nil
+}
+
+// statementLedger 单个账户在账期内的流水汇总
+type statementLedger struct {
+	opening map[string]decimal.Decimal // 账期开始前按交易类型汇总
+	period  map[string]decimal.Decimal // 账期内按交易类型汇总
+	counts  map[string]int64           // 账期内按交易类型笔数
+}
+
+// buildStatements 按流水分表重算账期内每个账户的期初/期末余额及分类汇总
+func (s *StatementService) buildStatements(tx *gorm.DB, period string, start, end time.Time) ([]models.AccountStatement, error) {
+	ledgers := make(map[ledgerKey]*statementLedger)
+	get := func(accountType string, adminID int64, currency string) *statementLedger {
+		key := ledgerKey{AccountType: accountType, AdminID: adminID, Currency: currency}
+		l, ok := ledgers[key]
+		if !ok {
+			l = &statementLedger{
+				opening: make(map[string]decimal.Decimal),
+				period:  make(map[string]decimal.Decimal),
+				counts:  make(map[string]int64),
+			}
+			ledgers[key] = l
+		}
+		return l
+	}
+
+	// 1. 流水分表按 账户+币种+交易类型 分别汇总期初与本期
+	for i := 0; i < database.ShardCount; i++ {
+		var rows []struct {
+			AccountType     string
+			AdminID         int64
+			Currency        string
+			TransactionType string
+			OpeningAmount   decimal.Decimal
+			PeriodAmount    decimal.Decimal
+			PeriodCount     int64
+		}
+		txTable := fmt.Sprintf("account_transactions_%d", i)
+		if err := tx.Table(txTable).
+			Select("account_type, admin_id, currency, transaction_type, "+
+				"SUM(CASE WHEN created_at < ? THEN amount ELSE 0 END) AS opening_amount, "+
+				"SUM(CASE WHEN created_at >= ? THEN amount ELSE 0 END) AS period_amount, "+
+				"SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END) AS period_count", start, start, start).
+			Where("account_type IN ? AND status = ? AND created_at < ?", statementAccountTypes, 1, end).
+			Group("account_type, admin_id, currency, transaction_type").
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("汇总%s失败: %w", txTable, err)
+		}
+		for _, r := range rows {
+			l := get(r.AccountType, r.AdminID, r.Currency)
+			l.opening[r.TransactionType] = l.opening[r.TransactionType].Add(r.OpeningAmount)
+			l.period[r.TransactionType] = l.period[r.TransactionType].Add(r.PeriodAmount)
+			l.counts[r.TransactionType] += r.PeriodCount
+		}
+	}
+
+	// 2. 账期结束前已开户但没有流水的账户也出零余额账单
+	accountTables := []struct {
+		accountType string
+		model       interface{}
+		adminColumn string
+	}{
+		{models.AccountTypePrepayment, &models.PrepaymentAccount{}, "admin_id"},
+		{models.AccountTypeDeposit, &models.DepositAccount{}, "admin_id"},
+		{models.AccountTypeOperator, &models.OperatorAccount{}, "admin_id"},
+		{models.AccountTypeShopOwnerCommission, &models.ShopOwnerCommissionAccount{}, "admin_id"},
+		{models.AccountTypePlatformCommission, &models.PlatformCommissionAccount{}, "0"},
+	}
+	for _, t := range accountTables {
+		var accounts []struct {
+			AdminID  int64
+			Currency string
+		}
+		if err := tx.Model(t.model).Select(t.adminColumn+" AS admin_id, currency").
+			Where("created_at < ?", end).Scan(&accounts).Error; err != nil {
+			return nil, err
+		}
+		for _, a := range accounts {
+			get(t.accountType, a.AdminID, a.Currency)
+		}
+	}
+
+	keys := make([]ledgerKey, 0, len(ledgers))
+	for key := range ledgers {
+		keys = append(keys, key)
+	}
+	typeOrder := make(map[string]int, len(statementAccountTypes))
+	for i, t := range statementAccountTypes {
+		typeOrder[t] = i
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.AccountType != b.AccountType {
+			return typeOrder[a.AccountType] < typeOrder[b.AccountType]
+		}
+		if a.AdminID != b.AdminID {
+			return a.AdminID < b.AdminID
+		}
+		return a.Currency < b.Currency
+	})
+
+	statements := make([]models.AccountStatement, 0, len(keys))
+	for i, key := range keys {
+		st := buildStatement(period, key, ledgers[key])
+		st.StatementNo = fmt.Sprintf("ST%s%06d", strings.Replace(period, "-", "", 1), i+1)
+		st.PeriodStart, st.PeriodEnd = start, end
+		for j := range st.Lines {
+			st.Lines[j].StatementNo = st.StatementNo
+		}
+		st.Checksum = statementChecksum(&st)
+		statements = append(statements, st)
+	}
+	return statements, nil
+}
+
+// buildStatement 计算单个账户的账单金额（余额口径复用对账的重算规则）
+func buildStatement(period string, key ledgerKey, l *statementLedger) models.AccountStatement {
+	closing := make(map[string]decimal.Decimal, len(l.opening))
+	for txType, amount := range l.opening {
+		closing[txType] = amount
+	}
+	for txType, amount := range l.period {
+		closing[txType] = closing[txType].Add(amount)
+	}
+	openingBalance, _, _, _ := (&ledgerSnapshot{sums: l.opening}).expected(key.AccountType)
+	closingBalance, _, _, _ := (&ledgerSnapshot{sums: closing}).expected(key.AccountType)
+
+	st := models.AccountStatement{
+		Period:         period,
+		AccountType:    key.AccountType,
+		AdminID:        key.AdminID,
+		Currency:       key.Currency,
+		OpeningBalance: openingBalance,
+		ClosingBalance: closingBalance,
+		TotalIn:        decimal.Zero,
+		TotalOut:       decimal.Zero,
+	}
+
+	txTypes := make([]string, 0, len(l.counts))
+	for txType, cnt := range l.counts {
+		if cnt > 0 {
+			txTypes = append(txTypes, txType)
+		}
+	}
+	sort.Strings(txTypes)
+	for _, txType := range txTypes {
+		amount := l.period[txType]
+		st.TxCount += l.counts[txType]
+		st.Lines = append(st.Lines, models.AccountStatementLine{
+			TransactionType: txType,
+			Amount:          amount,
+			TxCount:         l.counts[txType],
+		})
+		// 预付款的 order_pay 从待结算扣除，不影响可用余额
+		if key.AccountType == models.AccountTypePrepayment && txType == models.TxTypeOrderPay {
+			continue
+		}
+		if amount.GreaterThan(decimal.Zero) {
+			st.TotalIn = st.TotalIn.Add(amount)
+		} else {
+			st.TotalOut = st.TotalOut.Sub(amount)
+		}
+	}
+	return st
+}
+
+// statementChecksum 账单内容校验和（账单号、金额及全部明细参与计算）
+func statementChecksum(st *models.AccountStatement) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s|%d|%s|%s|%s|%s|%s|%d",
+		st.StatementNo, st.Period, st.AccountType, st.AdminID, st.Currency,
+		st.OpeningBalance.StringFixed(2), st.TotalIn.StringFixed(2), st.TotalOut.StringFixed(2),
+		st.ClosingBalance.StringFixed(2), st.TxCount)
+	for _, line := range st.Lines {
+		fmt.Fprintf(&b, "|%s:%s:%d", line.TransactionType, line.Amount.StringFixed(2), line.TxCount)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// statementDigest 账期摘要（按账单号顺序串联全部账单校验和）
+func statementDigest(statements []models.AccountStatement) string {
+	h := sha256.New()
+	for _, st := range statements {
+		h.Write([]byte(st.StatementNo + ":" + st.Checksum + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyPeriod 校验账期账单：账单内容与校验和一致、账期摘要一致、按当前流水重算结果一致
+func (s *StatementService) VerifyPeriod(ctx context.Context, period string) (*StatementVerifyResult, error) {
+	start, end, err := ParseStatementPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	var p models.AccountStatementPeriod
+	if err := s.db.WithContext(ctx).Where("period = ?", period).First(&p).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("账期 %s 尚未生成账单", period)
+		}
+		return nil, err
+	}
+	if p.Status != models.StatementPeriodGenerated && p.Status != models.StatementPeriodSignedOff {
+		return nil, fmt.Errorf("账期 %s 账单未生成完成", period)
+	}
+
+	stored, err := s.loadStatements(s.db.WithContext(ctx), StatementQuery{Period: period})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StatementVerifyResult{
+		Period:             &p,
+		TamperedStatements: []string{},
+		LedgerMismatches:   []string{},
+	}
+	for i := range stored {
+		if statementChecksum(&stored[i]) != stored[i].Checksum {
+			result.TamperedStatements = append(result.TamperedStatements, stored[i].StatementNo)
+		}
+	}
+	result.DigestMatched = int64(len(stored)) == p.StatementCount && statementDigest(stored) == p.Digest
+
+	var rebuilt []models.AccountStatement
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var buildErr error
+		rebuilt, buildErr = s.buildStatements(tx, period, start, end)
+		return buildErr
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	storedByKey := make(map[ledgerKey]*models.AccountStatement, len(stored))
+	for i := range stored {
+		st := &stored[i]
+		storedByKey[ledgerKey{AccountType: st.AccountType, AdminID: st.AdminID, Currency: st.Currency}] = st
+	}
+	for i := range rebuilt {
+		r := &rebuilt[i]
+		key := ledgerKey{AccountType: r.AccountType, AdminID: r.AdminID, Currency: r.Currency}
+		st, ok := storedByKey[key]
+		if !ok {
+			result.LedgerMismatches = append(result.LedgerMismatches, fmt.Sprintf("%s/%d/%s: 账单缺失", key.AccountType, key.AdminID, key.Currency))
+			continue
+		}
+		delete(storedByKey, key)
+		// 账单号按生成顺序编号，比对时以原账单号重新计算校验和
+		r.StatementNo = st.StatementNo
+		for j := range r.Lines {
+			r.Lines[j].StatementNo = st.StatementNo
+		}
+		if statementChecksum(r) != st.Checksum {
+			result.LedgerMismatches = append(result.LedgerMismatches, fmt.Sprintf("%s: 期初%s/期末%s，流水重算为 期初%s/期末%s",
+				st.StatementNo, st.OpeningBalance.StringFixed(2), st.ClosingBalance.StringFixed(2),
+				r.OpeningBalance.StringFixed(2), r.ClosingBalance.StringFixed(2)))
+		}
+	}
+	for _, st := range storedByKey {
+		result.LedgerMismatches = append(result.LedgerMismatches, fmt.Sprintf("%s: 流水中已无对应账户", st.StatementNo))
+	}
+	sort.Strings(result.LedgerMismatches)
+
+	result.Verified = result.DigestMatched && len(result.TamperedStatements) == 0 && len(result.LedgerMismatches) == 0
+	return result, nil
+}
+
+// SignOffPeriod 签核账期（签核前重新校验，校验不通过不允许签核）
+func (s *StatementService) SignOffPeriod(ctx context.Context, period string, signedBy int64, remark string) (*StatementVerifyResult, error) {
+	result, err := s.VerifyPeriod(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	if result.Period.Status == models.StatementPeriodSignedOff {
+		return result, fmt.Errorf("账期 %s 已签核", period)
+	}
+	if !result.Verified {
+		return result, fmt.Errorf("账期 %s 账单校验未通过，不能签核", period)
+	}
+
+	now := time.Now()
+	res := s.db.WithContext(ctx).Model(&models.AccountStatementPeriod{}).
+		Where("id = ? AND status = ?", result.Period.ID, models.StatementPeriodGenerated).
+		Updates(map[string]interface{}{
+			"status":      models.StatementPeriodSignedOff,
+			"signed_by":   signedBy,
+			"signed_at":   now,
+			"sign_remark": remark,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("账期 %s 状态已变更，请刷新后重试", period)
+	}
+	result.Period.Status = models.StatementPeriodSignedOff
+	result.Period.SignedBy = signedBy
+	result.Period.SignedAt = &now
+	result.Period.SignRemark = remark
+	return result, nil
+}
+
+// ListPeriods 获取账期列表
+func (s *StatementService) ListPeriods(ctx context.Context, page, pageSize int) ([]models.AccountStatementPeriod, int64, error) {
+	var periods []models.AccountStatementPeriod
+	var total int64
+
+	query := s.db.WithContext(ctx).Model(&models.AccountStatementPeriod{})
+	query.Count(&total)
+	offset := (page - 1) * pageSize
+	err := query.Order("period DESC").Offset(offset).Limit(pageSize).Find(&periods).Error
+	return periods, total, err
+}
+
+func (s *StatementService) buildQuery(db *gorm.DB, q StatementQuery) *gorm.DB {
+	query := db.Model(&models.AccountStatement{})
+	if q.Period != "" {
+		query = query.Where("period = ?", q.Period)
+	}
+	if q.Currency != "" {
+		query = query.Where("currency = ?", NormalizeCurrency(q.Currency))
+	}
+	if len(q.AccountTypes) > 0 {
+		query = query.Where("account_type IN ?", q.AccountTypes)
+	}
+	if q.AdminID != nil {
+		query = query.Where("admin_id = ?", *q.AdminID)
+	}
+	return query
+}
+
+// ListStatements 获取账单列表（不含明细）
+func (s *StatementService) ListStatements(ctx context.Context, q StatementQuery, page, pageSize int) ([]models.AccountStatement, int64, error) {
+	var statements []models.AccountStatement
+	var total int64
+
+	if err := s.buildQuery(s.db.WithContext(ctx), q).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	err := s.buildQuery(s.db.WithContext(ctx), q).Order("period DESC, id ASC").Offset(offset).Limit(pageSize).Find(&statements).Error
+	return statements, total, err
+}
+
+// GetStatement 获取账单及明细（adminID 非空时校验账单归属）
+func (s *StatementService) GetStatement(ctx context.Context, statementNo string, adminID *int64, accountTypes []string) (*models.AccountStatement, error) {
+	statements, err := s.loadStatements(s.db.WithContext(ctx).Where("statement_no = ?", statementNo),
+		StatementQuery{AdminID: adminID, AccountTypes: accountTypes})
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("账单不存在")
+	}
+	return &statements[0], nil
+}
+
+// ExportStatements 获取导出用账单（含明细，按账单号排序）
+func (s *StatementService) ExportStatements(ctx context.Context, q StatementQuery) ([]models.AccountStatement, error) {
+	return s.loadStatements(s.db.WithContext(ctx), q)
+}
+
+// loadStatements 查询账单并挂载明细
+func (s *StatementService) loadStatements(db *gorm.DB, q StatementQuery) ([]models.AccountStatement, error) {
+	var statements []models.AccountStatement
+	if err := s.buildQuery(db, q).Order("statement_no ASC").Find(&statements).Error; err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return statements, nil
+	}
+
+	index := make(map[string]int, len(statements))
+	nos := make([]string, len(statements))
+	for i, st := range statements {
+		index[st.StatementNo] = i
+		nos[i] = st.StatementNo
+	}
+	for i := 0; i < len(nos); i += 1000 {
+		batch := nos[i:min(i+1000, len(nos))]
+		var lines []models.AccountStatementLine
+		if err := s.db.Where("statement_no IN ?", batch).Order("statement_no ASC, transaction_type ASC").Find(&lines).Error; err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			st := &statements[index[line.StatementNo]]
+			st.Lines = append(st.Lines, line)
+		}
+	}
+	return statements, nil
+}
+
+// 账单导出格式
+const (
+	StatementFormatCSV  = "csv"
+	StatementFormatJSON = "json"
+)
+
+// EncodeStatements 将账单编码为导出文件内容，返回内容及 Content-Type
+// CSV 每行一条交易类型明细（账单字段重复列出），无流水的账单输出一行空明细
+func EncodeStatements(statements []models.AccountStatement, format string) ([]byte, string, error) {
+	switch format {
+	case StatementFormatJSON:
+		data, err := json.MarshalIndent(statements, "", "  ")
+		return data, "application/json; charset=utf-8", err
+	case StatementFormatCSV, "":
+		var buf bytes.Buffer
+		buf.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，兼容 Excel 打开
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{
+			"statement_no", "period", "account_type", "admin_id", "currency",
+			"opening_balance", "total_in", "total_out", "closing_balance", "tx_count", "checksum",
+			"transaction_type", "type_amount", "type_tx_count",
+		})
+		for _, st := range statements {
+			head := []string{
+				st.StatementNo, st.Period, st.AccountType, strconv.FormatInt(st.AdminID, 10), st.Currency,
+				st.OpeningBalance.StringFixed(2), st.TotalIn.StringFixed(2), st.TotalOut.StringFixed(2),
+				st.ClosingBalance.StringFixed(2), strconv.FormatInt(st.TxCount, 10), st.Checksum,
+			}
+			if len(st.Lines) == 0 {
+				_ = w.Write(append(head, "", "", ""))
+				continue
+			}
+			for _, line := range st.Lines {
+				row := append(append([]string{}, head...), line.TransactionType, line.Amount.StringFixed(2), strconv.FormatInt(line.TxCount, 10))
+				_ = w.Write(row)
+			}
+		}
+		w.Flush()
+		return buf.Bytes(), "text/csv; charset=utf-8", w.Error()
+	default:
+		return nil, "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
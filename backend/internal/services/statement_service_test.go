@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildStatement(t *testing.T) {
+	d := decimal.RequireFromString
+	l := &statementLedger{
+		opening: map[string]decimal.Decimal{
+			models.TxTypeRecharge: d("1000"),
+			models.TxTypeFreeze:   d("-200"),
+			models.TxTypeOrderPay: d("-150"),
+		},
+		period: map[string]decimal.Decimal{
+			models.TxTypeRecharge:    d("500"),
+			models.TxTypeFreeze:      d("-300"),
+			models.TxTypeOrderRefund: d("50"),
+			models.TxTypeOrderPay:    d("-250"),
+		},
+		counts: map[string]int64{
+			models.TxTypeRecharge:    1,
+			models.TxTypeFreeze:      3,
+			models.TxTypeOrderRefund: 1,
+			models.TxTypeOrderPay:    2,
+		},
+	}
+
+	st := buildStatement("2026-09", ledgerKey{AccountType: models.AccountTypePrepayment, AdminID: 1, Currency: "TWD"}, l)
+
+	// 预付款可用余额不含 order_pay：期初 1000-200=800，本期 +500-300+50
+	if !st.OpeningBalance.Equal(d("800")) || !st.ClosingBalance.Equal(d("1050")) {
+		t.Fatalf("opening/closing = %s/%s, want 800/1050", st.OpeningBalance, st.ClosingBalance)
+	}
+	if !st.OpeningBalance.Add(st.TotalIn).Sub(st.TotalOut).Equal(st.ClosingBalance) {
+		t.Errorf("opening %s + in %s - out %s != closing %s", st.OpeningBalance, st.TotalIn, st.TotalOut, st.ClosingBalance)
+	}
+	if st.TxCount != 7 || len(st.Lines) != 4 {
+		t.Errorf("tx_count = %d, lines = %d, want 7/4", st.TxCount, len(st.Lines))
+	}
+
+	st.StatementNo = "ST202609000001"
+	sum := statementChecksum(&st)
+	st.Lines[0].Amount = st.Lines[0].Amount.Add(d("0.01"))
+	if statementChecksum(&st) == sum {
+		t.Error("checksum did not change after line amount was modified")
+	}
+}
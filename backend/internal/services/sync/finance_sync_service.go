@@ -306,14 +306,14 @@ func (s *FinanceSyncService) refreshToken(ctx context.Context, shopID uint64, au
 	}
 
 	now := time.Now()
-	updates := map[string]interface{}{
-		"access_token":  tokenResp.AccessToken,
-		"refresh_token": tokenResp.RefreshToken,
-		"expires_at":    now.Add(time.Duration(tokenResp.ExpireIn) * time.Second),
-		"updated_at":    now,
+	// 令牌为加密字段，须用结构体更新才会经过加密序列化器
+	updates := &models.ShopAuthorization{
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		ExpiresAt:    now.Add(time.Duration(tokenResp.ExpireIn) * time.Second),
 	}
 
-	if err := s.db.Model(auth).Updates(updates).Error; err != nil {
+	if err := s.db.Model(auth).Select("access_token", "refresh_token", "expires_at").Updates(updates).Error; err != nil {
 		return err
 	}
 
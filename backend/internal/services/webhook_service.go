@@ -327,7 +327,7 @@ func (s *WebhookService) handleOrderCancelRefund(ctx context.Context, shopID uin
 	orderTable := database.GetOrderTableName(shopID)
 	var order models.Order
 	if err := s.db.Table(orderTable).Where("shop_id = ? AND order_sn = ?", shopID, orderSN).
-		Select("id", "shop_id", "order_sn", "prepayment_status", "prepayment_amount", "total_amount").First(&order).Error; err != nil {
+		Select("id", "shop_id", "order_sn", "prepayment_status", "prepayment_amount", "total_amount", "currency").First(&order).Error; err != nil {
 		return
 	}
 	// 仅当预付款已扣除时返还（prepayment_status=1）
@@ -340,13 +340,14 @@ func (s *WebhookService) handleOrderCancelRefund(ctx context.Context, shopID uin
 	}
 	// 获取店主 ID（从店铺表）
 	var shop models.Shop
-	if err := s.db.Where("shop_id = ?", shopID).Select("admin_id").First(&shop).Error; err != nil || shop.AdminID == 0 {
+	if err := s.db.Where("shop_id = ?", shopID).Select("admin_id", "currency").First(&shop).Error; err != nil || shop.AdminID == 0 {
 		return
 	}
 
 	accountService := NewAccountService()
-	_, err := accountService.RefundPrepayment(ctx, shop.AdminID, unfreezeAmount, orderSN,
-		fmt.Sprintf("发货前取消返还: %s - %s", cancelBy, cancelReason))
+	currency := accountService.OrderFreezeCurrencyInTx(s.db, shop.AdminID, shopID, orderSN, orderCurrency(order.Currency, shop.Currency))
+	_, err := accountService.RefundPrepayment(ctx, shop.AdminID, unfreezeAmount, currency, orderSN,
+		fmt.Sprintf("发货前取消返还: %s - %s", cancelBy, cancelReason), OrderCancelRefundIdempotencyKey(shopID, orderSN))
 	if err != nil {
 		s.logError(ctx, shopID, consts.WebhookBuyerCancelOrder, "refund_error", err)
 		return
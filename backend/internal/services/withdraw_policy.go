@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// WithdrawPolicy 提现策略（金额字段为 0 表示不限制）
+type WithdrawPolicy struct {
+	AccountType   string          `json:"account_type"`
+	Currency      string          `json:"currency"`
+	FeeFlat       decimal.Decimal `json:"fee_flat"`       // 每笔固定手续费
+	FeeRate       decimal.Decimal `json:"fee_rate"`       // 按比例手续费
+	FeeMin        decimal.Decimal `json:"fee_min"`        // 手续费下限
+	FeeMax        decimal.Decimal `json:"fee_max"`        // 手续费上限
+	MinAmount     decimal.Decimal `json:"min_amount"`     // 单笔最低提现金额
+	MaxAmount     decimal.Decimal `json:"max_amount"`     // 单笔最高提现金额
+	DailyLimit    decimal.Decimal `json:"daily_limit"`    // 每日累计上限
+	MonthlyLimit  decimal.Decimal `json:"monthly_limit"`  // 每月累计上限
+	DailyCount    int             `json:"daily_count"`    // 每日次数上限
+	CooldownHours int             `json:"cooldown_hours"` // 收款账户变更后的冷却小时数
+}
+
+// WithdrawPolicyFor 取账户类型 + 币种适用的提现策略：优先匹配币种，其次该账户类型的默认策略，都没有时不收费不限额
+func WithdrawPolicyFor(accountType, currency string) WithdrawPolicy {
+	var policies []config.WithdrawPolicyConfig
+	if cfg := config.Get(); cfg != nil {
+		policies = cfg.Withdraw.Policies
+	}
+	return matchWithdrawPolicy(policies, accountType, currency)
+}
+
+// matchWithdrawPolicy 在策略列表中匹配账户类型 + 币种
+func matchWithdrawPolicy(policies []config.WithdrawPolicyConfig, accountType, currency string) WithdrawPolicy {
+	currency = NormalizeCurrency(currency)
+	var fallback *config.WithdrawPolicyConfig
+	for i := range policies {
+		p := &policies[i]
+		if p.AccountType != accountType {
+			continue
+		}
+		if p.Currency == "" {
+			if fallback == nil {
+				fallback = p
+			}
+			continue
+		}
+		if NormalizeCurrency(p.Currency) == currency {
+			return newWithdrawPolicy(p, accountType, currency)
+		}
+	}
+	if fallback != nil {
+		return newWithdrawPolicy(fallback, accountType, currency)
+	}
+	return WithdrawPolicy{AccountType: accountType, Currency: currency}
+}
+
+func newWithdrawPolicy(p *config.WithdrawPolicyConfig, accountType, currency string) WithdrawPolicy {
+	money := func(v float64) decimal.Decimal {
+		return decimal.NewFromFloat(v).Round(2)
+	}
+	return WithdrawPolicy{
+		AccountType:   accountType,
+		Currency:      currency,
+		FeeFlat:       money(p.FeeFlat),
+		FeeRate:       decimal.NewFromFloat(p.FeeRate),
+		FeeMin:        money(p.FeeMin),
+		FeeMax:        money(p.FeeMax),
+		MinAmount:     money(p.MinAmount),
+		MaxAmount:     money(p.MaxAmount),
+		DailyLimit:    money(p.DailyLimit),
+		MonthlyLimit:  money(p.MonthlyLimit),
+		DailyCount:    p.DailyCount,
+		CooldownHours: p.CooldownHours,
+	}
+}
+
+// Fee 计算手续费：固定 + 比例，再按上下限截取，保留两位小数
+func (p WithdrawPolicy) Fee(amount decimal.Decimal) decimal.Decimal {
+	fee := p.FeeFlat.Add(amount.Mul(p.FeeRate))
+	if p.FeeMin.IsPositive() && fee.LessThan(p.FeeMin) {
+		fee = p.FeeMin
+	}
+	if p.FeeMax.IsPositive() && fee.GreaterThan(p.FeeMax) {
+		fee = p.FeeMax
+	}
+	return fee.Round(2)
+}
+
+// checkAmount 校验单笔金额与手续费，不通过时返回原因
+func (p WithdrawPolicy) checkAmount(amount, fee decimal.Decimal) string {
+	if p.MinAmount.IsPositive() && amount.LessThan(p.MinAmount) {
+		return fmt.Sprintf("单笔最低提现 %s %s", p.MinAmount.StringFixed(2), p.Currency)
+	}
+	if p.MaxAmount.IsPositive() && amount.GreaterThan(p.MaxAmount) {
+		return fmt.Sprintf("单笔最高提现 %s %s", p.MaxAmount.StringFixed(2), p.Currency)
+	}
+	if fee.GreaterThanOrEqual(amount) {
+		return fmt.Sprintf("提现金额不足以支付手续费 %s %s", fee.StringFixed(2), p.Currency)
+	}
+	return ""
+}
+
+// checkUsage 校验当日/当月累计（已含本笔），不通过时返回原因
+func (p WithdrawPolicy) checkUsage(amount decimal.Decimal, dailyUsed decimal.Decimal, dailyCount int, monthlyUsed decimal.Decimal) string {
+	if p.DailyCount > 0 && dailyCount+1 > p.DailyCount {
+		return fmt.Sprintf("每日最多提现 %d 次", p.DailyCount)
+	}
+	if p.DailyLimit.IsPositive() && dailyUsed.Add(amount).GreaterThan(p.DailyLimit) {
+		return fmt.Sprintf("超过每日提现上限 %s %s，今日已提现 %s", p.DailyLimit.StringFixed(2), p.Currency, dailyUsed.StringFixed(2))
+	}
+	if p.MonthlyLimit.IsPositive() && monthlyUsed.Add(amount).GreaterThan(p.MonthlyLimit) {
+		return fmt.Sprintf("超过每月提现上限 %s %s，本月已提现 %s", p.MonthlyLimit.StringFixed(2), p.Currency, monthlyUsed.StringFixed(2))
+	}
+	return ""
+}
+
+// cooldownUntil 收款账户冷却期结束时间（无冷却期或已过期时返回 nil）
+func (p WithdrawPolicy) cooldownUntil(account *models.CollectionAccount, now time.Time) *time.Time {
+	if p.CooldownHours <= 0 || account == nil {
+		return nil
+	}
+	changedAt := account.CreatedAt
+	if account.ChangedAt != nil && account.ChangedAt.After(changedAt) {
+		changedAt = *account.ChangedAt
+	}
+	until := changedAt.Add(time.Duration(p.CooldownHours) * time.Hour)
+	if !until.After(now) {
+		return nil
+	}
+	return &until
+}
+
+// WithdrawQuote 提现试算结果（申请前展示手续费、到账金额与额度，Allowed 为 false 时 Reason 为不能提现的原因）
+type WithdrawQuote struct {
+	AccountType      string          `json:"account_type"`
+	Currency         string          `json:"currency"`
+	PayoutCurrency   string          `json:"payout_currency"`
+	Amount           decimal.Decimal `json:"amount"`
+	Fee              decimal.Decimal `json:"fee"`
+	NetAmount        decimal.Decimal `json:"net_amount"`    // 扣除手续费后的金额（提现币种）
+	ActualAmount     decimal.Decimal `json:"actual_amount"` // 实际到账金额（到账币种）
+	FxRate           decimal.Decimal `json:"fx_rate"`
+	FxRateID         uint64          `json:"fx_rate_id"`
+	AvailableBalance decimal.Decimal `json:"available_balance"`
+	DailyUsed        decimal.Decimal `json:"daily_used"`
+	DailyCount       int             `json:"daily_count"`
+	MonthlyUsed      decimal.Decimal `json:"monthly_used"`
+	CooldownUntil    *time.Time      `json:"cooldown_until"`
+	Policy           WithdrawPolicy  `json:"policy"`
+	Allowed          bool            `json:"allowed"`
+	Reason           string          `json:"reason"`
+}
+
+// withdrawUsageInTx 统计 since 之后的提现累计金额与次数（待审核、已通过、已打款的申请计入，拒绝与打款失败的不计）
+func withdrawUsageInTx(db *gorm.DB, adminID int64, accountType, currency string, since time.Time) (decimal.Decimal, int, error) {
+	var row struct {
+		Total decimal.Decimal
+		Count int
+	}
+	err := db.Model(&models.WithdrawApplication{}).
+		Select("COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("admin_id = ? AND account_type = ? AND currency = ? AND created_at >= ?", adminID, accountType, currency, since).
+		Where("status IN ?", []int8{models.ApplicationStatusPending, models.ApplicationStatusApproved, models.ApplicationStatusPaid}).
+		Scan(&row).Error
+	return row.Total, row.Count, err
+}
+
+// withdrawPeriodStarts 当日与当月的起始时间（每日/每月限额的统计区间）
+func withdrawPeriodStarts(now time.Time) (dayStart, monthStart time.Time) {
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return dayStart, monthStart
+}
+
+// withdrawUsageOfPeriodsInTx 统计当日累计金额、次数与当月累计金额
+func withdrawUsageOfPeriodsInTx(db *gorm.DB, adminID int64, accountType, currency string, now time.Time) (dailyUsed decimal.Decimal, dailyCount int, monthlyUsed decimal.Decimal, err error) {
+	dayStart, monthStart := withdrawPeriodStarts(now)
+	if dailyUsed, dailyCount, err = withdrawUsageInTx(db, adminID, accountType, currency, dayStart); err != nil {
+		return
+	}
+	monthlyUsed, _, err = withdrawUsageInTx(db, adminID, accountType, currency, monthStart)
+	return
+}
+
+// checkWithdrawUsageInTx 申请事务内（已持有账户行锁）重新统计并校验每日/每月限额与次数，
+// 防止同一账户的并发申请都通过试算后合计突破限额
+func checkWithdrawUsageInTx(db *gorm.DB, adminID int64, accountType, currency string, amount decimal.Decimal, policy WithdrawPolicy) error {
+	dailyUsed, dailyCount, monthlyUsed, err := withdrawUsageOfPeriodsInTx(db, adminID, accountType, currency, time.Now())
+	if err != nil {
+		return err
+	}
+	if reason := policy.checkUsage(amount, dailyUsed, dailyCount, monthlyUsed); reason != "" {
+		return errors.New(reason)
+	}
+	return nil
+}
+
+// QuoteWithdraw 提现试算：按提现策略计算手续费与到账金额，并校验余额、单笔/每日/每月限额与收款账户冷却期
+// collectionAccountID 为 0 时不校验冷却期
+func (s *AccountService) QuoteWithdraw(ctx context.Context, adminID int64, accountType string, amount decimal.Decimal, currency string, payoutCurrency string, collectionAccountID uint64) (*WithdrawQuote, error) {
+	if accountType == models.AccountTypeDeposit {
+		return nil, ErrDepositWithdraw
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, fmt.Errorf("提现金额必须大于0")
+	}
+	currency = NormalizeCurrency(currency)
+	if payoutCurrency == "" {
+		payoutCurrency = currency
+	}
+	payoutCurrency = NormalizeCurrency(payoutCurrency)
+
+	available, err := s.withdrawableBalance(ctx, adminID, accountType, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var collectionAccount *models.CollectionAccount
+	if collectionAccountID > 0 {
+		var account models.CollectionAccount
+		if err := s.db.WithContext(ctx).Where("id = ? AND admin_id = ?", collectionAccountID, adminID).First(&account).Error; err != nil {
+			return nil, fmt.Errorf("收款账户不存在")
+		}
+		collectionAccount = &account
+	}
+
+	now := time.Now()
+	dailyUsed, dailyCount, monthlyUsed, err := withdrawUsageOfPeriodsInTx(s.db.WithContext(ctx), adminID, accountType, currency, now)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := WithdrawPolicyFor(accountType, currency)
+	fee := policy.Fee(amount)
+	quote := &WithdrawQuote{
+		AccountType:      accountType,
+		Currency:         currency,
+		PayoutCurrency:   payoutCurrency,
+		Amount:           amount,
+		Fee:              fee,
+		NetAmount:        amount.Sub(fee),
+		AvailableBalance: available,
+		DailyUsed:        dailyUsed,
+		DailyCount:       dailyCount,
+		MonthlyUsed:      monthlyUsed,
+		CooldownUntil:    policy.cooldownUntil(collectionAccount, now),
+		Policy:           policy,
+	}
+
+	quote.Reason = policy.checkAmount(amount, fee)
+	if quote.Reason == "" && available.LessThan(amount) {
+		quote.Reason = fmt.Sprintf("可提现余额不足，当前可提现: %s", available.String())
+	}
+	if quote.Reason == "" {
+		quote.Reason = policy.checkUsage(amount, dailyUsed, dailyCount, monthlyUsed)
+	}
+	if quote.Reason == "" && quote.CooldownUntil != nil {
+		quote.Reason = fmt.Sprintf("收款账户变更后 %d 小时内不能提现，%s 后可提现", policy.CooldownHours, quote.CooldownUntil.Format("2006-01-02 15:04"))
+	}
+	if quote.Reason != "" {
+		return quote, nil
+	}
+
+	// 到账币种不同时按当前汇率折算扣除手续费后的金额
+	quote.ActualAmount, quote.FxRate, quote.FxRateID, err = s.fxService.ConvertInTx(s.db, quote.NetAmount, currency, payoutCurrency, now)
+	if err != nil {
+		return nil, err
+	}
+	quote.Allowed = true
+	return quote, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestWithdrawPolicy(t *testing.T) {
+	d := decimal.RequireFromString
+	policies := []config.WithdrawPolicyConfig{
+		{AccountType: models.AccountTypeOperator, FeeFlat: 30},
+		{AccountType: models.AccountTypeOperator, Currency: "twd", FeeFlat: 10, FeeRate: 0.005, FeeMin: 15, FeeMax: 100,
+			MinAmount: 100, MaxAmount: 50000, DailyLimit: 60000, MonthlyLimit: 200000, DailyCount: 3, CooldownHours: 24},
+	}
+
+	twd := matchWithdrawPolicy(policies, models.AccountTypeOperator, "TWD")
+	if !twd.FeeFlat.Equal(d("10")) || twd.Currency != "TWD" {
+		t.Fatalf("TWD policy = %+v", twd)
+	}
+	if usd := matchWithdrawPolicy(policies, models.AccountTypeOperator, "USD"); !usd.FeeFlat.Equal(d("30")) || usd.Currency != "USD" {
+		t.Errorf("USD should fall back to account type default, got %+v", usd)
+	}
+	if none := matchWithdrawPolicy(policies, models.AccountTypeDeposit, "TWD"); !none.Fee(d("1000")).IsZero() {
+		t.Errorf("unconfigured account type should be free, got %+v", none)
+	}
+
+	fees := []struct{ amount, fee string }{
+		{"200", "15"},     // 10 + 1 = 11，不足下限 15
+		{"1000", "15"},    // 10 + 5
+		{"3333", "26.67"}, // 10 + 16.665
+		{"50000", "100"},  // 10 + 250，封顶 100
+	}
+	for _, tt := range fees {
+		if got := twd.Fee(d(tt.amount)); !got.Equal(d(tt.fee)) {
+			t.Errorf("Fee(%s) = %s, want %s", tt.amount, got, tt.fee)
+		}
+	}
+
+	if r := twd.checkAmount(d("99"), d("15")); r == "" {
+		t.Error("amount below minimum accepted")
+	}
+	if r := twd.checkAmount(d("50001"), d("100")); r == "" {
+		t.Error("amount above maximum accepted")
+	}
+	if r := (WithdrawPolicy{FeeFlat: d("20")}).checkAmount(d("20"), d("20")); r == "" {
+		t.Error("amount not covering fee accepted")
+	}
+	if r := twd.checkAmount(d("1000"), d("15")); r != "" {
+		t.Errorf("valid amount rejected: %s", r)
+	}
+
+	usage := []struct {
+		name                 string
+		amount, daily, month string
+		count                int
+		ok                   bool
+	}{
+		{"额度内", "10000", "50000", "100000", 2, true},
+		{"超过每日次数", "100", "0", "0", 3, false},
+		{"超过每日上限", "10001", "50000", "50000", 1, false},
+		{"超过每月上限", "1000", "0", "199500", 0, false},
+	}
+	for _, tt := range usage {
+		r := twd.checkUsage(d(tt.amount), d(tt.daily), tt.count, d(tt.month))
+		if (r == "") != tt.ok {
+			t.Errorf("%s: reason = %q", tt.name, r)
+		}
+	}
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	changed := now.Add(-2 * time.Hour)
+	account := &models.CollectionAccount{CreatedAt: now.AddDate(0, -1, 0), ChangedAt: &changed}
+	if until := twd.cooldownUntil(account, now); until == nil || !until.Equal(changed.Add(24*time.Hour)) {
+		t.Errorf("cooldownUntil = %v", until)
+	}
+	account.ChangedAt = nil
+	if until := twd.cooldownUntil(account, now); until != nil {
+		t.Errorf("old account still cooling down until %v", until)
+	}
+}
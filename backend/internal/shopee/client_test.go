@@ -0,0 +1,246 @@
+package shopee_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/shopee"
+	"balance/backend/internal/shopee/shopeetest"
+)
+
+const (
+	testPartnerID  = 1203446
+	testPartnerKey = "test-partner-key"
+	testShopID     = 226516274
+	testToken      = "fixture-access-token"
+)
+
+// newFakeShopee 启动加载了 testdata 夹具的模拟服务，并让全局配置指向它
+func newFakeShopee(t *testing.T) *shopeetest.Server {
+	t.Helper()
+	srv := shopeetest.NewServer(t, testPartnerID, testPartnerKey)
+	if err := srv.LoadFixtureFile("testdata/shop_fixtures.json"); err != nil {
+		t.Fatalf("LoadFixtureFile: %v", err)
+	}
+	t.Cleanup(srv.Install())
+	return srv
+}
+
+func TestOrderListPaginationAndDetail(t *testing.T) {
+	srv := newFakeShopee(t)
+	client := shopee.NewClient("TW")
+
+	var orderSNs []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		resp, err := client.GetOrderList(testToken, testShopID, "create_time", 1759000000, 1760000000, 2, cursor, "")
+		if err != nil {
+			t.Fatalf("GetOrderList: %v", err)
+		}
+		for _, o := range resp.Response.OrderList {
+			orderSNs = append(orderSNs, o.OrderSN)
+		}
+		if !resp.Response.More {
+			break
+		}
+		cursor = resp.Response.NextCursor
+	}
+	want := []string{"251002AAAA0003", "251001AAAA0002", "251001AAAA0001"}
+	if strings.Join(orderSNs, ",") != strings.Join(want, ",") {
+		t.Fatalf("order list = %v, want %v", orderSNs, want)
+	}
+	if got := srv.CallCount(shopeetest.PathGetOrderList); got != 2 {
+		t.Fatalf("order list calls = %d, want 2", got)
+	}
+
+	resp, err := client.GetOrderList(testToken, testShopID, "create_time", 1759000000, 1760000000, 10, "", "COMPLETED")
+	if err != nil || len(resp.Response.OrderList) != 1 || resp.Response.OrderList[0].OrderSN != "251002AAAA0003" {
+		t.Fatalf("status filter = %+v, %v", resp, err)
+	}
+
+	// 虾皮单次查询时间范围不能超过 15 天
+	if _, err := client.GetOrderList(testToken, testShopID, "create_time", 1750000000, 1760000000, 10, "", ""); err == nil {
+		t.Fatal("time range over 15 days should be rejected")
+	}
+
+	detail, err := client.GetOrderDetail(testToken, testShopID, []string{"251001AAAA0001", "UNKNOWN"})
+	if err != nil {
+		t.Fatalf("GetOrderDetail: %v", err)
+	}
+	if len(detail.Response.OrderList) != 1 {
+		t.Fatalf("order detail count = %d, want 1", len(detail.Response.OrderList))
+	}
+	order := detail.Response.OrderList[0]
+	if order.TotalAmount != 590 || len(order.ItemList) != 1 || order.ItemList[0].ModelQuantity != 2 {
+		t.Fatalf("order detail = %+v", order)
+	}
+}
+
+func TestEscrowReturnsAndWallet(t *testing.T) {
+	newFakeShopee(t)
+	client := shopee.NewClient("TW")
+
+	escrow, err := client.GetEscrowDetail(testToken, testShopID, "251002AAAA0003")
+	if err != nil || escrow.Response.OrderIncome.EscrowAmount != 301.5 || escrow.Response.OrderIncome.CommissionFee != 21 {
+		t.Fatalf("fixture escrow = %+v, %v", escrow, err)
+	}
+	// 未配置结算明细时按订单金额生成
+	escrow, err = client.GetEscrowDetail(testToken, testShopID, "251001AAAA0001")
+	if err != nil || escrow.Response.OrderIncome.EscrowAmount != 590 || len(escrow.Response.Items) != 1 {
+		t.Fatalf("generated escrow = %+v, %v", escrow, err)
+	}
+	if _, err := client.GetEscrowDetail(testToken, testShopID, "UNKNOWN"); err == nil {
+		t.Fatal("escrow of unknown order should fail")
+	}
+
+	returns, err := client.GetReturnList(testToken, testShopID, 1759000000, 1760000000, 50, "")
+	if err != nil || len(returns.Response.ReturnList) != 1 || returns.Response.More {
+		t.Fatalf("return list = %+v, %v", returns, err)
+	}
+	ret, err := client.GetReturnDetail(testToken, testShopID, returns.Response.ReturnList[0].ReturnSN)
+	if err != nil || ret.Response.OrderSN != "251002AAAA0003" || ret.Response.RefundAmount != 350 || len(ret.Response.Item) != 1 {
+		t.Fatalf("return detail = %+v, %v", ret, err)
+	}
+
+	// 钱包流水按时间倒序、page_no 从 1 开始
+	first, err := client.GetWalletTransactionList(testToken, testShopID, 1, 2, "")
+	if err != nil || len(first.Response.TransactionList) != 2 || !first.Response.More ||
+		first.Response.TransactionList[0].TransactionID != 70003 {
+		t.Fatalf("wallet page 1 = %+v, %v", first, err)
+	}
+	second, err := client.GetWalletTransactionList(testToken, testShopID, 2, 2, "")
+	if err != nil || len(second.Response.TransactionList) != 1 || second.Response.More ||
+		second.Response.TransactionList[0].TransactionID != 70001 {
+		t.Fatalf("wallet page 2 = %+v, %v", second, err)
+	}
+
+	info, err := client.GetShopInfo(testToken, testShopID)
+	if err != nil || info.Response.ShopName != "Fake TW Shop" || info.Response.Region != "TW" {
+		t.Fatalf("shop info = %+v, %v", info, err)
+	}
+}
+
+func TestSignatureAndAccessTokenValidation(t *testing.T) {
+	srv := newFakeShopee(t)
+
+	wrong := srv.ShopeeConfig()
+	wrong.PartnerKey = "wrong-partner-key"
+	config.Set(&config.Config{Shopee: wrong})
+	_, err := shopee.NewClient("TW").GetOrderList(testToken, testShopID, "create_time", 1759000000, 1760000000, 10, "", "")
+	if err == nil || !strings.Contains(err.Error(), shopeetest.ErrSign) {
+		t.Fatalf("wrong partner key err = %v, want %s", err, shopeetest.ErrSign)
+	}
+
+	srv.Install()
+	client := shopee.NewClient("TW")
+	if _, err := client.GetShopInfo("not-a-token", testShopID); err == nil || !strings.Contains(err.Error(), shopeetest.ErrInvalidToken) {
+		t.Fatalf("unknown token err = %v", err)
+	}
+
+	// access_token 过期后用 refresh_token 换新令牌，旧令牌失效
+	if err := srv.ExpireAccessToken(testShopID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetShopInfo(testToken, testShopID); err == nil {
+		t.Fatal("expired access token should be rejected")
+	}
+	tokens, err := client.RefreshAccessToken("fixture-refresh-token", testShopID)
+	if err != nil || tokens.AccessToken == "" || tokens.ExpireIn != 4*3600 {
+		t.Fatalf("RefreshAccessToken = %+v, %v", tokens, err)
+	}
+	if _, err := client.RefreshAccessToken("fixture-refresh-token", testShopID); err == nil {
+		t.Fatal("used refresh token should be rejected")
+	}
+	if _, err := client.GetShopInfo(tokens.AccessToken, testShopID); err != nil {
+		t.Fatalf("GetShopInfo with refreshed token: %v", err)
+	}
+
+	// 授权码换取令牌，只能使用一次
+	byCode, err := client.GetAccessToken("fixture-auth-code", testShopID)
+	if err != nil || byCode.AccessToken == tokens.AccessToken || len(byCode.ShopIDList) != 1 {
+		t.Fatalf("GetAccessToken = %+v, %v", byCode, err)
+	}
+	if _, err := client.GetAccessToken("fixture-auth-code", testShopID); err == nil {
+		t.Fatal("auth code should be single use")
+	}
+	if access, _ := srv.Tokens(testShopID); access != byCode.AccessToken {
+		t.Fatalf("server token = %s, want %s", access, byCode.AccessToken)
+	}
+}
+
+func TestShipOrder(t *testing.T) {
+	srv := newFakeShopee(t)
+	client := shopee.NewClient("TW")
+
+	// 自发货物流单号
+	if _, err := client.ShipOrder(testToken, testShopID, "251001AAAA0001", "TW123456789"); err != nil {
+		t.Fatalf("ShipOrder: %v", err)
+	}
+	order, _ := srv.Order(testShopID, "251001AAAA0001")
+	if order.OrderStatus != shopeetest.OrderStatusProcessed || order.TrackingNo != "TW123456789" {
+		t.Fatalf("shipped order = %+v", order)
+	}
+	if _, err := client.ShipOrder(testToken, testShopID, "251001AAAA0001", "TW123456789"); err == nil {
+		t.Fatal("shipping a processed order should fail")
+	}
+
+	// 未提供参数时客户端先查询发货参数，按上门取件发货
+	if _, err := client.ShipOrderWithParamsContext(context.Background(), testToken, testShopID, "251001AAAA0002", nil, ""); err != nil {
+		t.Fatalf("ShipOrder with pickup: %v", err)
+	}
+	if srv.CallCount(shopeetest.PathGetShippingParameter) != 1 {
+		t.Fatal("shipping parameter should be queried once")
+	}
+	shipRequests := srv.Requests(shopeetest.PathShipOrder)
+	body := shipRequests[len(shipRequests)-1].Body
+	if _, ok := body["pickup"]; !ok {
+		t.Fatalf("ship_order body = %v, want pickup", body)
+	}
+	tracking, err := client.GetTrackingNumber(testToken, testShopID, "251001AAAA0002")
+	if err != nil || tracking.Response.TrackingNumber == "" {
+		t.Fatalf("GetTrackingNumber = %+v, %v", tracking, err)
+	}
+}
+
+func TestInjectedFaults(t *testing.T) {
+	srv := newFakeShopee(t)
+	client := shopee.NewClient("TW")
+
+	// 限流错误由 RetryWithBackoff 退避重试
+	srv.InjectRateLimit(shopeetest.PathGetOrderList, 1)
+	var resp *shopee.OrderListResponse
+	err := shopee.RetryWithBackoff(context.Background(), 3, func() error {
+		var err error
+		resp, err = client.GetOrderList(testToken, testShopID, "create_time", 1759000000, 1760000000, 10, "", "")
+		return err
+	})
+	if err != nil || len(resp.Response.OrderList) != 3 {
+		t.Fatalf("retry after rate limit = %+v, %v", resp, err)
+	}
+	if got := srv.CallCount(shopeetest.PathGetOrderList); got != 2 {
+		t.Fatalf("order list calls = %d, want 2", got)
+	}
+
+	// 临时错误只影响注入的次数
+	srv.InjectTransientError(shopeetest.PathGetReturnDetail, 1)
+	if _, err := client.GetReturnDetail(testToken, testShopID, "2510050000001"); err == nil || !strings.Contains(err.Error(), "Inner error") {
+		t.Fatalf("transient err = %v", err)
+	}
+	if _, err := client.GetReturnDetail(testToken, testShopID, "2510050000001"); err != nil {
+		t.Fatalf("GetReturnDetail after transient error: %v", err)
+	}
+
+	// 不指定接口时对任意接口生效
+	srv.InjectFault("", 1, shopeetest.Fault{Error: "error_server", Message: "System error."})
+	if _, err := client.GetShopInfo(testToken, testShopID); err == nil || !strings.Contains(err.Error(), "error_server") {
+		t.Fatalf("wildcard fault err = %v", err)
+	}
+	if _, err := client.GetShopInfo(testToken, testShopID); err != nil {
+		t.Fatalf("GetShopInfo after fault: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package shopeetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"balance/backend/internal/shopee"
+)
+
+// Fixtures 模拟服务夹具（可从 JSON 文件加载）
+type Fixtures struct {
+	Shops []ShopFixture `json:"shops"`
+}
+
+// ShopFixture 店铺夹具：授权令牌及店铺下的订单、结算、退货、钱包流水
+type ShopFixture struct {
+	ShopID       uint64 `json:"shop_id"`
+	ShopName     string `json:"shop_name"`
+	Region       string `json:"region"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	AuthCode     string `json:"auth_code"` // 授权回调 code，用于 token/get 换取令牌
+
+	Orders       []shopee.OrderDetail       `json:"orders"`
+	Escrows      map[string]json.RawMessage `json:"escrows"` // order_sn → get_escrow_detail 的 response 内容，未配置时按订单生成
+	Returns      []ReturnFixture            `json:"returns"`
+	Transactions []shopee.Transaction       `json:"transactions"`
+	Tracking     map[string]string          `json:"tracking"`           // order_sn → 运单号
+	ShipParams   map[string]json.RawMessage `json:"shipping_parameter"` // order_sn → get_shipping_parameter 的 response 内容
+}
+
+// ReturnFixture 退货夹具
+type ReturnFixture struct {
+	ReturnSN        string              `json:"return_sn"`
+	OrderSN         string              `json:"order_sn"`
+	Status          string              `json:"status"`
+	Reason          string              `json:"reason"`
+	TextReason      string              `json:"text_reason"`
+	RefundAmount    float64             `json:"refund_amount"`
+	Currency        string              `json:"currency"`
+	CreateTime      int64               `json:"create_time"`
+	UpdateTime      int64               `json:"update_time"`
+	DueDate         int64               `json:"due_date"`
+	TrackingNumber  string              `json:"tracking_number"`
+	LogisticsStatus string              `json:"logistics_status"`
+	Items           []ReturnItemFixture `json:"items"`
+}
+
+// ReturnItemFixture 退货商品夹具
+type ReturnItemFixture struct {
+	ItemID    int64   `json:"item_id"`
+	ModelID   int64   `json:"model_id"`
+	Name      string  `json:"name"`
+	ItemSKU   string  `json:"item_sku"`
+	Amount    int     `json:"amount"`
+	ItemPrice float64 `json:"item_price"`
+}
+
+// shopState 店铺运行时状态
+type shopState struct {
+	fixture          ShopFixture
+	accessToken      string
+	refreshToken     string
+	accessExpiresAt  time.Time
+	refreshExpiresAt time.Time
+	orders           map[string]*shopee.OrderDetail
+	escrows          map[string]json.RawMessage
+	tracking         map[string]string
+	shipParams       map[string]json.RawMessage
+}
+
+// 模拟令牌有效期（与虾皮一致：access_token 4 小时，refresh_token 30 天）
+const (
+	accessTokenTTL  = 4 * time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// LoadFixtureFile 从 JSON 文件加载夹具
+func (s *Server) LoadFixtureFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.LoadFixtures(f)
+}
+
+// LoadFixtures 从 JSON 加载夹具
+func (s *Server) LoadFixtures(r io.Reader) error {
+	var fixtures Fixtures
+	if err := json.NewDecoder(r).Decode(&fixtures); err != nil {
+		return fmt.Errorf("解析夹具失败: %w", err)
+	}
+	for _, shop := range fixtures.Shops {
+		if err := s.AddShop(shop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddShop 添加（或替换）店铺夹具
+func (s *Server) AddShop(fixture ShopFixture) error {
+	if fixture.ShopID == 0 {
+		return fmt.Errorf("店铺夹具缺少 shop_id")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	state := &shopState{
+		fixture:          fixture,
+		accessToken:      fixture.AccessToken,
+		refreshToken:     fixture.RefreshToken,
+		accessExpiresAt:  now.Add(accessTokenTTL),
+		refreshExpiresAt: now.Add(refreshTokenTTL),
+		orders:           make(map[string]*shopee.OrderDetail, len(fixture.Orders)),
+		escrows:          make(map[string]json.RawMessage),
+		tracking:         make(map[string]string),
+		shipParams:       make(map[string]json.RawMessage),
+	}
+	for i := range fixture.Orders {
+		order := fixture.Orders[i]
+		state.orders[order.OrderSN] = &order
+	}
+	for k, v := range fixture.Escrows {
+		state.escrows[k] = v
+	}
+	for k, v := range fixture.Tracking {
+		state.tracking[k] = v
+	}
+	for k, v := range fixture.ShipParams {
+		state.shipParams[k] = v
+	}
+	if fixture.AuthCode != "" {
+		s.authCodes[fixture.AuthCode] = fixture.ShopID
+	}
+	s.shops[fixture.ShopID] = state
+	return nil
+}
+
+// shop 获取店铺状态（调用方持有锁）
+func (s *Server) shop(shopID uint64) (*shopState, error) {
+	state, ok := s.shops[shopID]
+	if !ok {
+		return nil, fmt.Errorf("店铺 %d 不存在，请先 AddShop", shopID)
+	}
+	return state, nil
+}
+
+// AddOrder 添加（或替换）订单
+func (s *Server) AddOrder(shopID uint64, order shopee.OrderDetail) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.shop(shopID)
+	if err != nil {
+		return err
+	}
+	if _, exists := state.orders[order.OrderSN]; !exists {
+		state.fixture.Orders = append(state.fixture.Orders, order)
+	}
+	state.orders[order.OrderSN] = &order
+	return nil
+}
+
+// SetOrderStatus 修改订单状态并刷新更新时间（模拟虾皮侧状态变化）
+func (s *Server) SetOrderStatus(shopID uint64, orderSN, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.shop(shopID)
+	if err != nil {
+		return err
+	}
+	order, ok := state.orders[orderSN]
+	if !ok {
+		return fmt.Errorf("订单 %s 不存在", orderSN)
+	}
+	order.OrderStatus = status
+	order.UpdateTime = time.Now().Unix()
+	return nil
+}
+
+// Order 获取订单当前状态（用于断言发货等写操作的结果）
+func (s *Server) Order(shopID uint64, orderSN string) (shopee.OrderDetail, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.shops[shopID]
+	if !ok {
+		return shopee.OrderDetail{}, false
+	}
+	order, ok := state.orders[orderSN]
+	if !ok {
+		return shopee.OrderDetail{}, false
+	}
+	return *order, true
+}
+
+// SetEscrow 设置订单结算明细（response 为 get_escrow_detail 的 response 内容）
+func (s *Server) SetEscrow(shopID uint64, orderSN string, response interface{}) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.shop(shopID)
+	if err != nil {
+		return err
+	}
+	state.escrows[orderSN] = data
+	return nil
+}
+
+// AddReturn 添加（或替换）退货
+func (s *Server) AddReturn(shopID uint64, ret ReturnFixture) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.shop(shopID)
+	if err != nil {
+		return err
+	}
+	for i := range state.fixture.Returns {
+		if state.fixture.Returns[i].ReturnSN == ret.ReturnSN {
+			state.fixture.Returns[i] = ret
+			return nil
+		}
+	}
+	state.fixture.Returns = append(state.fixture.Returns, ret)
+	return nil
+}
+
+// AddTransaction 添加钱包流水
+func (s *Server) AddTransaction(shopID uint64, tx shopee.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.shop(shopID)
+	if err != nil {
+		return err
+	}
+	state.fixture.Transactions = append(state.fixture.Transactions, tx)
+	return nil
+}
+
+// ExpireAccessToken 使店铺当前 access_token 立即过期（用于测试令牌刷新）
+func (s *Server) ExpireAccessToken(shopID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.shop(shopID)
+	if err != nil {
+		return err
+	}
+	state.accessExpiresAt = time.Now().Add(-time.Second)
+	return nil
+}
+
+// Tokens 店铺当前有效的令牌
+func (s *Server) Tokens(shopID uint64) (accessToken, refreshToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.shops[shopID]; ok {
+		return state.accessToken, state.refreshToken
+	}
+	return "", ""
+}
@@ -0,0 +1,461 @@
+package shopeetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/backend/internal/shopee"
+)
+
+// 与虾皮接口一致的参数限制
+const (
+	maxPageSize        = 100
+	maxOrderDetailSize = 50
+	maxOrderTimeRange  = 15 * 24 * 3600
+)
+
+// 订单状态
+const (
+	OrderStatusReadyToShip = "READY_TO_SHIP"
+	OrderStatusProcessed   = "PROCESSED"
+)
+
+func (s *Server) buildRoutes() map[string]routeHandler {
+	return map[string]routeHandler{
+		PathTokenGet:             {method: http.MethodPost, handle: s.handleTokenGet},
+		PathAccessTokenGet:       {method: http.MethodPost, handle: s.handleAccessTokenGet},
+		PathGetShopInfo:          {method: http.MethodGet, shopLevel: true, handle: s.handleShopInfo},
+		PathGetOrderList:         {method: http.MethodGet, shopLevel: true, handle: s.handleOrderList},
+		PathGetOrderDetail:       {method: http.MethodGet, shopLevel: true, handle: s.handleOrderDetail},
+		PathGetEscrowDetail:      {method: http.MethodGet, shopLevel: true, handle: s.handleEscrowDetail},
+		PathGetWalletTransaction: {method: http.MethodGet, shopLevel: true, handle: s.handleWalletTransactions},
+		PathGetReturnList:        {method: http.MethodGet, shopLevel: true, handle: s.handleReturnList},
+		PathGetReturnDetail:      {method: http.MethodGet, shopLevel: true, handle: s.handleReturnDetail},
+		PathGetShippingParameter: {method: http.MethodGet, shopLevel: true, handle: s.handleShippingParameter},
+		PathGetChannelList:       {method: http.MethodGet, shopLevel: true, handle: s.handleChannelList},
+		PathGetTrackingNumber:    {method: http.MethodGet, shopLevel: true, handle: s.handleTrackingNumber},
+		PathShipOrder:            {method: http.MethodPost, shopLevel: true, handle: s.handleShipOrder},
+	}
+}
+
+func paramError(format string, args ...interface{}) *Fault {
+	return &Fault{Error: ErrParam, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) *Fault {
+	return &Fault{Error: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// bodyUint 读取请求体中的数字字段
+func bodyUint(req *Request, key string) uint64 {
+	if v, ok := req.Body[key].(float64); ok && v > 0 {
+		return uint64(v)
+	}
+	return 0
+}
+
+// bodyString 读取请求体中的字符串字段
+func bodyString(req *Request, key string) string {
+	v, _ := req.Body[key].(string)
+	return v
+}
+
+// pageSize 解析 page_size（1 ~ 100）
+func pageSize(req *Request) (int, *Fault) {
+	size, err := strconv.Atoi(req.Query.Get("page_size"))
+	if err != nil || size < 1 || size > maxPageSize {
+		return 0, paramError("page_size should be between 1 and %d.", maxPageSize)
+	}
+	return size, nil
+}
+
+// cursorOffset 解析游标（模拟服务的游标即偏移量）
+func cursorOffset(req *Request) (int, *Fault) {
+	cursor := req.Query.Get("cursor")
+	if cursor == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 {
+		return 0, paramError("Invalid cursor.")
+	}
+	return offset, nil
+}
+
+// timeRange 解析时间范围参数
+func timeRange(req *Request, fromKey, toKey string) (int64, int64, *Fault) {
+	from, err1 := strconv.ParseInt(req.Query.Get(fromKey), 10, 64)
+	to, err2 := strconv.ParseInt(req.Query.Get(toKey), 10, 64)
+	if err1 != nil || err2 != nil || from > to {
+		return 0, 0, paramError("Invalid %s/%s.", fromKey, toKey)
+	}
+	return from, to, nil
+}
+
+// page 按偏移量分页，返回本页区间、是否还有下一页及下一页游标
+func page(total, offset, size int) (start, end int, more bool, nextCursor string) {
+	start = offset
+	if start > total {
+		start = total
+	}
+	end = start + size
+	if end > total {
+		end = total
+	}
+	more = end < total
+	if more {
+		nextCursor = strconv.Itoa(end)
+	}
+	return start, end, more, nextCursor
+}
+
+// issueTokens 为店铺签发新令牌（旧令牌立即失效）
+func (s *Server) issueTokens(state *shopState) topLevel {
+	s.tokenSeq++
+	now := time.Now()
+	state.accessToken = fmt.Sprintf("fake-access-%d-%d", state.fixture.ShopID, s.tokenSeq)
+	state.refreshToken = fmt.Sprintf("fake-refresh-%d-%d", state.fixture.ShopID, s.tokenSeq)
+	state.accessExpiresAt = now.Add(accessTokenTTL)
+	state.refreshExpiresAt = now.Add(refreshTokenTTL)
+	return topLevel{
+		"access_token":            state.accessToken,
+		"refresh_token":           state.refreshToken,
+		"expire_in":               int64(accessTokenTTL.Seconds()),
+		"refresh_token_expire_in": int64(refreshTokenTTL.Seconds()),
+		"partner_id":              s.PartnerID,
+		"shop_id_list":            []uint64{state.fixture.ShopID},
+	}
+}
+
+func (s *Server) handleTokenGet(req *Request) (interface{}, *Fault) {
+	code := bodyString(req, "code")
+	shopID := bodyUint(req, "shop_id")
+	if bound, ok := s.authCodes[code]; !ok || bound != shopID {
+		return nil, &Fault{Error: ErrAuth, Message: "Invalid code."}
+	}
+	// 授权码只能使用一次
+	delete(s.authCodes, code)
+	return s.issueTokens(s.shops[shopID]), nil
+}
+
+func (s *Server) handleAccessTokenGet(req *Request) (interface{}, *Fault) {
+	state, ok := s.shops[bodyUint(req, "shop_id")]
+	if !ok || bodyString(req, "refresh_token") != state.refreshToken || time.Now().After(state.refreshExpiresAt) {
+		return nil, &Fault{Error: ErrAuth, Message: "Invalid refresh_token."}
+	}
+	return s.issueTokens(state), nil
+}
+
+func (s *Server) handleShopInfo(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	return map[string]interface{}{
+		"shop_name": state.fixture.ShopName,
+		"region":    state.fixture.Region,
+		"status":    "NORMAL",
+	}, nil
+}
+
+func (s *Server) handleOrderList(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	field := req.Query.Get("time_range_field")
+	if field != "create_time" && field != "update_time" {
+		return nil, paramError("time_range_field should be create_time or update_time.")
+	}
+	from, to, fault := timeRange(req, "time_from", "time_to")
+	if fault != nil {
+		return nil, fault
+	}
+	if to-from > maxOrderTimeRange {
+		return nil, paramError("The time range should be less than 15 days.")
+	}
+	size, fault := pageSize(req)
+	if fault != nil {
+		return nil, fault
+	}
+	offset, fault := cursorOffset(req)
+	if fault != nil {
+		return nil, fault
+	}
+	status := req.Query.Get("order_status")
+
+	var matched []*shopee.OrderDetail
+	for _, fixture := range state.fixture.Orders {
+		order := state.orders[fixture.OrderSN]
+		t := order.CreateTime
+		if field == "update_time" {
+			t = order.UpdateTime
+		}
+		if t < from || t > to || (status != "" && order.OrderStatus != status) {
+			continue
+		}
+		matched = append(matched, order)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if field == "update_time" {
+			return matched[i].UpdateTime > matched[j].UpdateTime
+		}
+		return matched[i].CreateTime > matched[j].CreateTime
+	})
+
+	start, end, more, next := page(len(matched), offset, size)
+	list := make([]map[string]string, 0, end-start)
+	for _, order := range matched[start:end] {
+		list = append(list, map[string]string{"order_sn": order.OrderSN, "order_status": order.OrderStatus})
+	}
+	return map[string]interface{}{"more": more, "next_cursor": next, "order_list": list}, nil
+}
+
+func (s *Server) handleOrderDetail(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	raw := req.Query.Get("order_sn_list")
+	if raw == "" {
+		return nil, paramError("order_sn_list is required.")
+	}
+	orderSNs := strings.Split(raw, ",")
+	if len(orderSNs) > maxOrderDetailSize {
+		return nil, paramError("order_sn_list should be no more than %d.", maxOrderDetailSize)
+	}
+	// 不存在的订单不返回（与虾皮一致）
+	list := make([]shopee.OrderDetail, 0, len(orderSNs))
+	for _, sn := range orderSNs {
+		if order, ok := state.orders[sn]; ok {
+			list = append(list, *order)
+		}
+	}
+	return map[string]interface{}{"order_list": list}, nil
+}
+
+func (s *Server) handleEscrowDetail(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	orderSN := req.Query.Get("order_sn")
+	if escrow, ok := state.escrows[orderSN]; ok {
+		return json.RawMessage(escrow), nil
+	}
+	order, ok := state.orders[orderSN]
+	if !ok {
+		return nil, notFound("Order %s not found.", orderSN)
+	}
+
+	// 未配置结算明细时按订单生成：结算金额等于订单金额，无费用
+	items := make([]map[string]interface{}, 0, len(order.ItemList))
+	count := 0
+	for _, item := range order.ItemList {
+		count += item.ModelQuantity
+		items = append(items, map[string]interface{}{
+			"item_id":            item.ItemID,
+			"item_name":          item.ItemName,
+			"item_sku":           item.ItemSKU,
+			"model_id":           item.ModelID,
+			"model_name":         item.ModelName,
+			"model_sku":          item.ModelSKU,
+			"original_price":     item.ModelOriginalPrice,
+			"discounted_price":   item.ModelOriginalPrice,
+			"quantity_purchased": item.ModelQuantity,
+		})
+	}
+	return map[string]interface{}{
+		"order_sn":      order.OrderSN,
+		"buyer_user_id": order.BuyerUserID,
+		"order_income": map[string]interface{}{
+			"escrow_amount":      order.TotalAmount,
+			"buyer_total_amount": order.TotalAmount,
+			"original_price":     order.TotalAmount,
+			"items_count":        count,
+		},
+		"items": items,
+	}, nil
+}
+
+func (s *Server) handleWalletTransactions(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	pageNo, err := strconv.Atoi(req.Query.Get("page_no"))
+	if err != nil || pageNo < 1 {
+		return nil, paramError("page_no should be greater than 0.")
+	}
+	size, fault := pageSize(req)
+	if fault != nil {
+		return nil, fault
+	}
+	walletType := req.Query.Get("wallet_type")
+
+	var matched []shopee.Transaction
+	for _, tx := range state.fixture.Transactions {
+		if walletType == "" || tx.WalletType == walletType {
+			matched = append(matched, tx)
+		}
+	}
+	// 按交易时间倒序（与虾皮一致）
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreateTime > matched[j].CreateTime })
+
+	start, end, more, _ := page(len(matched), (pageNo-1)*size, size)
+	return map[string]interface{}{"more": more, "transaction_list": append([]shopee.Transaction{}, matched[start:end]...)}, nil
+}
+
+func (s *Server) handleReturnList(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	from, to, fault := timeRange(req, "create_time_from", "create_time_to")
+	if fault != nil {
+		return nil, fault
+	}
+	size, fault := pageSize(req)
+	if fault != nil {
+		return nil, fault
+	}
+	offset, fault := cursorOffset(req)
+	if fault != nil {
+		return nil, fault
+	}
+
+	var matched []ReturnFixture
+	for _, ret := range state.fixture.Returns {
+		if ret.CreateTime >= from && ret.CreateTime <= to {
+			matched = append(matched, ret)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreateTime > matched[j].CreateTime })
+
+	start, end, more, next := page(len(matched), offset, size)
+	list := make([]map[string]interface{}, 0, end-start)
+	for _, ret := range matched[start:end] {
+		list = append(list, map[string]interface{}{
+			"return_sn":   ret.ReturnSN,
+			"order_sn":    ret.OrderSN,
+			"status":      ret.Status,
+			"update_time": ret.UpdateTime,
+		})
+	}
+	return map[string]interface{}{"more": more, "next_cursor": next, "return_list": list}, nil
+}
+
+func (s *Server) handleReturnDetail(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	returnSN := req.Query.Get("return_sn")
+	for _, ret := range state.fixture.Returns {
+		if ret.ReturnSN != returnSN {
+			continue
+		}
+		items := make([]map[string]interface{}, 0, len(ret.Items))
+		for _, item := range ret.Items {
+			items = append(items, map[string]interface{}{
+				"item_id":      item.ItemID,
+				"model_id":     item.ModelID,
+				"name":         item.Name,
+				"item_sku":     item.ItemSKU,
+				"amount":       item.Amount,
+				"item_price":   item.ItemPrice,
+				"is_main_item": true,
+			})
+		}
+		return map[string]interface{}{
+			"return_sn":        ret.ReturnSN,
+			"order_sn":         ret.OrderSN,
+			"status":           ret.Status,
+			"reason":           ret.Reason,
+			"text_reason":      ret.TextReason,
+			"refund_amount":    ret.RefundAmount,
+			"currency":         ret.Currency,
+			"create_time":      ret.CreateTime,
+			"update_time":      ret.UpdateTime,
+			"due_date":         ret.DueDate,
+			"tracking_number":  ret.TrackingNumber,
+			"logistics_status": ret.LogisticsStatus,
+			"item":             items,
+		}, nil
+	}
+	return nil, notFound("Return %s not found.", returnSN)
+}
+
+func (s *Server) handleShippingParameter(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	orderSN := req.Query.Get("order_sn")
+	if _, ok := state.orders[orderSN]; !ok {
+		return nil, notFound("Order %s not found.", orderSN)
+	}
+	if param, ok := state.shipParams[orderSN]; ok {
+		return json.RawMessage(param), nil
+	}
+	// 默认只支持上门取件：一个取件地址、一个时间段
+	return map[string]interface{}{
+		"info_needed": map[string]interface{}{"pickup": []string{"address_id", "pickup_time_id"}},
+		"pickup": map[string]interface{}{
+			"address_list": []map[string]interface{}{{
+				"address_id": 1,
+				"region":     state.fixture.Region,
+				"address":    "fake pickup address",
+				"time_slot_list": []map[string]interface{}{{
+					"date":           time.Now().Add(24 * time.Hour).Format("2006-01-02"),
+					"pickup_time_id": "fake-slot-1",
+				}},
+			}},
+		},
+	}, nil
+}
+
+func (s *Server) handleChannelList(req *Request) (interface{}, *Fault) {
+	return map[string]interface{}{
+		"logistics_channel_list": []map[string]interface{}{
+			{"logistics_channel_id": 30001, "logistics_channel_name": "Fake Express", "cod_enabled": true, "enabled": true},
+		},
+	}, nil
+}
+
+func (s *Server) handleTrackingNumber(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	orderSN := req.Query.Get("order_sn")
+	if _, ok := state.orders[orderSN]; !ok {
+		return nil, notFound("Order %s not found.", orderSN)
+	}
+	return map[string]interface{}{"tracking_number": state.tracking[orderSN]}, nil
+}
+
+func (s *Server) handleShipOrder(req *Request) (interface{}, *Fault) {
+	state := s.shops[req.ShopID]
+	orderSN := bodyString(req, "order_sn")
+	order, ok := state.orders[orderSN]
+	if !ok {
+		return nil, notFound("Order %s not found.", orderSN)
+	}
+	if order.OrderStatus != OrderStatusReadyToShip {
+		return nil, paramError("Order status %s can not be shipped.", order.OrderStatus)
+	}
+
+	var trackingNo string
+	methods := 0
+	if v, ok := req.Body["pickup"].(map[string]interface{}); ok {
+		methods++
+		if v["address_id"] == nil || v["pickup_time_id"] == nil {
+			return nil, paramError("pickup.address_id and pickup.pickup_time_id are required.")
+		}
+	}
+	if v, ok := req.Body["dropoff"].(map[string]interface{}); ok {
+		methods++
+		if v["branch_id"] == nil {
+			return nil, paramError("dropoff.branch_id is required.")
+		}
+	}
+	if v, ok := req.Body["non_integrated"].(map[string]interface{}); ok {
+		methods++
+		trackingNo, _ = v["tracking_no"].(string)
+		if trackingNo == "" {
+			return nil, paramError("non_integrated.tracking_no is required.")
+		}
+	}
+	if methods != 1 {
+		return nil, paramError("Exactly one of pickup, dropoff and non_integrated is required.")
+	}
+
+	// 集成物流由虾皮生成运单号
+	if trackingNo == "" {
+		trackingNo = "FAKE" + orderSN
+	}
+	state.tracking[orderSN] = trackingNo
+	order.OrderStatus = OrderStatusProcessed
+	order.TrackingNo = trackingNo
+	order.UpdateTime = time.Now().Unix()
+	return map[string]interface{}{}, nil
+}
@@ -0,0 +1,329 @@
+// Package shopeetest 离线虾皮开放平台 v2 模拟服务（基于 httptest），供集成测试使用，不访问真实沙箱。
+//
+// 模拟服务实现 shopee 包调用的接口（授权、订单列表/详情、结算明细、退货、钱包流水、物流、发货），
+// 按 shopee.Client 的签名规则校验 partner_id/sign/timestamp 及店铺级 access_token，
+// 数据由夹具（Go 代码或 JSON 文件）提供，并可按接口注入限流和临时错误。
+//
+// 用法:
+//
+//	srv := shopeetest.NewServer(t, 1001, "test-partner-key")
+//	srv.LoadFixtureFile("testdata/shop.json")
+//	srv.InjectRateLimit(shopeetest.PathGetOrderList, 1)
+//	defer srv.Install()() // 全局配置的虾皮 Host 指向模拟服务，服务层 shopee.NewClient 即访问模拟服务
+//
+// 订单巡检（OrderService.PatrolOrders）、退货同步（ReturnService.SyncReturns）、财务同步（FinanceSyncService）
+// 在 Install 后访问模拟服务，所需的 MySQL/Redis 仍由测试环境提供。
+package shopeetest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"balance/backend/internal/config"
+)
+
+// 模拟的接口路径
+const (
+	PathTokenGet             = "/api/v2/auth/token/get"
+	PathAccessTokenGet       = "/api/v2/auth/access_token/get"
+	PathGetShopInfo          = "/api/v2/shop/get_shop_info"
+	PathGetOrderList         = "/api/v2/order/get_order_list"
+	PathGetOrderDetail       = "/api/v2/order/get_order_detail"
+	PathGetEscrowDetail      = "/api/v2/payment/get_escrow_detail"
+	PathGetWalletTransaction = "/api/v2/payment/get_wallet_transaction_list"
+	PathGetReturnList        = "/api/v2/returns/get_return_list"
+	PathGetReturnDetail      = "/api/v2/returns/get_return_detail"
+	PathGetShippingParameter = "/api/v2/logistics/get_shipping_parameter"
+	PathGetChannelList       = "/api/v2/logistics/get_channel_list"
+	PathGetTrackingNumber    = "/api/v2/logistics/get_tracking_number"
+	PathShipOrder            = "/api/v2/logistics/ship_order"
+)
+
+// 模拟服务返回的错误码（与 shopee.RetryWithBackoff 识别的限流/临时错误一致）
+const (
+	ErrTooManyRequest = "error.too_many_request"
+	ErrTransient      = "error_data"
+	ErrSign           = "error_sign"
+	ErrAuth           = "error_auth"
+	ErrInvalidToken   = "invalid_access_token"
+	ErrParam          = "error_param"
+	ErrNotFound       = "error_not_found"
+)
+
+// signTolerance 请求 timestamp 与服务端时间允许的误差
+const signTolerance = 5 * time.Minute
+
+// Fault 注入的错误响应
+type Fault struct {
+	Status  int    // HTTP 状态码，0 为 200
+	Error   string // 响应 error 字段
+	Message string // 响应 message 字段
+}
+
+// Request 模拟服务收到的请求（签名校验通过或失败的都会记录）
+type Request struct {
+	Method string
+	Path   string
+	ShopID uint64
+	Query  url.Values
+	Body   map[string]interface{}
+}
+
+type pendingFault struct {
+	fault Fault
+	times int
+}
+
+// Server 离线虾皮模拟服务
+type Server struct {
+	PartnerID  int64
+	PartnerKey string
+
+	httpServer *httptest.Server
+	routes     map[string]routeHandler
+
+	mu        sync.Mutex
+	shops     map[uint64]*shopState
+	authCodes map[string]uint64
+	faults    map[string][]*pendingFault
+	requests  []Request
+	tokenSeq  int
+	requestID int
+}
+
+// NewServer 启动模拟服务，测试结束时自动关闭
+func NewServer(t testing.TB, partnerID int64, partnerKey string) *Server {
+	s := &Server{
+		PartnerID:  partnerID,
+		PartnerKey: partnerKey,
+		shops:      make(map[uint64]*shopState),
+		authCodes:  make(map[string]uint64),
+		faults:     make(map[string][]*pendingFault),
+	}
+	s.routes = s.buildRoutes()
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.httpServer.Close)
+	return s
+}
+
+// URL 模拟服务地址
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// ShopeeConfig 指向模拟服务的虾皮配置（所有地区都回落到 SG Host）
+func (s *Server) ShopeeConfig() config.ShopeeConfig {
+	hosts := map[string]string{"SG": s.httpServer.URL}
+	return config.ShopeeConfig{
+		PartnerID:    s.PartnerID,
+		PartnerKey:   s.PartnerKey,
+		Hosts:        hosts,
+		SandboxHosts: hosts,
+	}
+}
+
+// Install 将全局配置的虾皮配置指向模拟服务，返回恢复原配置的函数
+func (s *Server) Install() func() {
+	prev := config.Get()
+	cfg := &config.Config{}
+	if prev != nil {
+		copied := *prev
+		cfg = &copied
+	}
+	cfg.Shopee = s.ShopeeConfig()
+	config.Set(cfg)
+	return func() { config.Set(prev) }
+}
+
+// InjectFault 接下来 times 次请求 path（为空表示任意接口）时返回指定错误，签名校验之后生效
+func (s *Server) InjectFault(path string, times int, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], &pendingFault{fault: fault, times: times})
+}
+
+// InjectRateLimit 接下来 times 次请求 path 时返回限流错误（HTTP 429）
+func (s *Server) InjectRateLimit(path string, times int) {
+	s.InjectFault(path, times, Fault{Status: http.StatusTooManyRequests, Error: ErrTooManyRequest, Message: "Too many requests, please try again later."})
+}
+
+// InjectTransientError 接下来 times 次请求 path 时返回虾皮临时错误（Inner error）
+func (s *Server) InjectTransientError(path string, times int) {
+	s.InjectFault(path, times, Fault{Error: ErrTransient, Message: "Inner error, please try later."})
+}
+
+// Requests 已收到的请求（path 为空时返回全部）
+func (s *Server) Requests(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []Request
+	for _, r := range s.requests {
+		if path == "" || r.Path == path {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// CallCount 接口被调用的次数
+func (s *Server) CallCount(path string) int {
+	return len(s.Requests(path))
+}
+
+// topLevel 直接放在响应顶层的字段
+type topLevel map[string]interface{}
+
+// routeHandler 接口处理函数，返回 response 字段内容（或 topLevel 顶层字段）或错误
+type routeHandler struct {
+	method    string
+	shopLevel bool // 店铺级接口（签名包含 access_token 和 shop_id）
+	handle    func(req *Request) (interface{}, *Fault)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := Request{Method: r.Method, Path: r.URL.Path, Query: query}
+	req.ShopID, _ = strconv.ParseUint(query.Get("shop_id"), 10, 64)
+	var bodyErr error
+	if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+		bodyErr = json.Unmarshal(data, &req.Body)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+
+	if bodyErr != nil {
+		s.writeError(w, &Fault{Status: http.StatusBadRequest, Error: ErrParam, Message: "Invalid request body."})
+		return
+	}
+
+	route, ok := s.routes[r.URL.Path]
+	if !ok {
+		s.writeError(w, &Fault{Status: http.StatusNotFound, Error: ErrNotFound, Message: "Api not found."})
+		return
+	}
+	if r.Method != route.method {
+		s.writeError(w, &Fault{Status: http.StatusMethodNotAllowed, Error: ErrParam, Message: "Method not allowed."})
+		return
+	}
+	if fault := s.checkSign(&req, route.shopLevel); fault != nil {
+		s.writeError(w, fault)
+		return
+	}
+	if fault := s.takeFault(r.URL.Path); fault != nil {
+		s.writeError(w, fault)
+		return
+	}
+
+	response, fault := route.handle(&req)
+	if fault != nil {
+		s.writeError(w, fault)
+		return
+	}
+	payload := map[string]interface{}{
+		"error":      "",
+		"message":    "",
+		"request_id": s.nextRequestID(),
+	}
+	// 授权接口的字段在响应顶层，其余接口在 response 字段中
+	if fields, ok := response.(topLevel); ok {
+		for k, v := range fields {
+			payload[k] = v
+		}
+	} else {
+		payload["response"] = response
+	}
+	s.writeJSON(w, http.StatusOK, payload)
+}
+
+// checkSign 按 shopee.Client 的规则校验签名：partner_id+path+timestamp[+access_token+shop_id]，HMAC-SHA256(partner_key)
+func (s *Server) checkSign(req *Request, shopLevel bool) *Fault {
+	partnerID, _ := strconv.ParseInt(req.Query.Get("partner_id"), 10, 64)
+	if partnerID != s.PartnerID {
+		return &Fault{Status: http.StatusForbidden, Error: ErrAuth, Message: "Invalid partner_id."}
+	}
+	timestamp, err := strconv.ParseInt(req.Query.Get("timestamp"), 10, 64)
+	if err != nil {
+		return &Fault{Status: http.StatusBadRequest, Error: ErrParam, Message: "Invalid timestamp."}
+	}
+	if d := time.Since(time.Unix(timestamp, 0)); d > signTolerance || d < -signTolerance {
+		return &Fault{Status: http.StatusForbidden, Error: ErrSign, Message: "Timestamp is expired."}
+	}
+
+	accessToken := req.Query.Get("access_token")
+	baseStr := fmt.Sprintf("%d%s%d", partnerID, req.Path, timestamp)
+	if shopLevel {
+		if accessToken == "" || req.ShopID == 0 {
+			return &Fault{Status: http.StatusForbidden, Error: ErrParam, Message: "access_token and shop_id are required."}
+		}
+		baseStr = fmt.Sprintf("%d%s%d%s%d", partnerID, req.Path, timestamp, accessToken, req.ShopID)
+	}
+	mac := hmac.New(sha256.New, []byte(s.PartnerKey))
+	mac.Write([]byte(baseStr))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(req.Query.Get("sign"))) {
+		return &Fault{Status: http.StatusForbidden, Error: ErrSign, Message: "Wrong sign."}
+	}
+
+	if shopLevel {
+		shop, ok := s.shops[req.ShopID]
+		if !ok {
+			return &Fault{Status: http.StatusForbidden, Error: ErrAuth, Message: "Shop not authorized."}
+		}
+		if accessToken != shop.accessToken || time.Now().After(shop.accessExpiresAt) {
+			return &Fault{Status: http.StatusForbidden, Error: ErrInvalidToken, Message: "Invalid access_token."}
+		}
+	}
+	return nil
+}
+
+// takeFault 取出一个待注入的错误（先匹配接口，再匹配任意接口）
+func (s *Server) takeFault(path string) *Fault {
+	for _, key := range []string{path, ""} {
+		queue := s.faults[key]
+		if len(queue) == 0 {
+			continue
+		}
+		fault := queue[0].fault
+		queue[0].times--
+		if queue[0].times <= 0 {
+			s.faults[key] = queue[1:]
+		}
+		return &fault
+	}
+	return nil
+}
+
+func (s *Server) nextRequestID() string {
+	s.requestID++
+	return fmt.Sprintf("fake-%06d", s.requestID)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, fault *Fault) {
+	status := fault.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	s.writeJSON(w, status, map[string]interface{}{
+		"error":      fault.Error,
+		"message":    fault.Message,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"balance/backend/internal/config"
+)
+
+// 字段密文前缀，没有该前缀的值视为加密上线前写入的明文
+const fieldCipherPrefix = "enc:v1:"
+
+// FieldCipher 字段级信封加密：每个值使用随机数据密钥 AES-256-GCM 加密，数据密钥再由主密钥 AES-GCM 包裹。
+// 密文格式 enc:v1:<主密钥ID>:<base64(包裹后的数据密钥)>:<base64(nonce+密文)>，
+// 密文自带主密钥 ID，轮换主密钥后旧密文仍可用旧密钥解密。
+type FieldCipher struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+}
+
+// NewFieldCipher 按配置创建字段加密器（未配置 active_key_id 时返回 nil，表示不加密）
+func NewFieldCipher(cfg *config.CryptoConfig) (*FieldCipher, error) {
+	if cfg == nil || cfg.ActiveKeyID == "" {
+		return nil, nil
+	}
+	c := &FieldCipher{activeKeyID: cfg.ActiveKeyID, keys: make(map[string]cipher.AEAD, len(cfg.Keys))}
+	for id, encoded := range cfg.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("主密钥 ID 不合法: %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("主密钥 %s 不是合法的 base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("主密钥 %s 长度必须为 32 字节", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		c.keys[id] = aead
+	}
+	if _, ok := c.keys[cfg.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("未找到当前主密钥 %s", cfg.ActiveKeyID)
+	}
+	return c, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealField 使用 AEAD 加密，返回 nonce+密文
+func sealField(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openField 解密 nonce+密文
+func openField(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("密文长度不正确")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
+
+// ActiveKeyID 当前主密钥 ID
+func (c *FieldCipher) ActiveKeyID() string {
+	return c.activeKeyID
+}
+
+// Encrypt 用当前主密钥加密（空字符串不加密）
+func (c *FieldCipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	dataAEAD, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	// 主密钥 ID 作为附加数据，防止密文被替换到其他密钥下
+	keyID := []byte(c.activeKeyID)
+	wrapped, err := sealField(c.keys[c.activeKeyID], dataKey, keyID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealField(dataAEAD, []byte(plaintext), keyID)
+	if err != nil {
+		return "", err
+	}
+	return fieldCipherPrefix + c.activeKeyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密字段值（不是密文时原样返回）
+func (c *FieldCipher) Decrypt(value string) (string, error) {
+	if !IsEncryptedField(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, fieldCipherPrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("字段密文格式不正确")
+	}
+	keyAEAD, ok := c.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("未配置主密钥 %s", parts[0])
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("字段密文格式不正确: %w", err)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("字段密文格式不正确: %w", err)
+	}
+	keyID := []byte(parts[0])
+	dataKey, err := openField(keyAEAD, wrapped, keyID)
+	if err != nil {
+		return "", fmt.Errorf("数据密钥解密失败: %w", err)
+	}
+	dataAEAD, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openField(dataAEAD, sealed, keyID)
+	if err != nil {
+		return "", fmt.Errorf("字段解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 字段值是否需要用当前主密钥重新加密（明文或使用了旧主密钥）
+func (c *FieldCipher) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsEncryptedField(value) {
+		return true
+	}
+	return FieldKeyID(value) != c.activeKeyID
+}
+
+// Rotate 用当前主密钥重新加密字段值，返回新值及是否发生了变化（已是当前主密钥的密文或空值原样返回）
+func (c *FieldCipher) Rotate(value string) (string, bool, error) {
+	if !c.NeedsRotation(value) {
+		return value, false, nil
+	}
+	plaintext, err := c.Decrypt(value)
+	if err != nil {
+		return "", false, err
+	}
+	rotated, err := c.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return rotated, true, nil
+}
+
+// IsEncryptedField 是否为字段密文
+func IsEncryptedField(value string) bool {
+	return strings.HasPrefix(value, fieldCipherPrefix)
+}
+
+// FieldKeyID 字段密文使用的主密钥 ID（不是密文时返回空）
+func FieldKeyID(value string) string {
+	if !IsEncryptedField(value) {
+		return ""
+	}
+	rest := strings.TrimPrefix(value, fieldCipherPrefix)
+	if i := strings.IndexByte(rest, ':'); i >= 0 {
+		return rest[:i]
+	}
+	return ""
+}
+
+var fieldCipher *FieldCipher
+
+// InitFieldCipher 按配置初始化全局字段加密器（服务启动时调用）
+func InitFieldCipher(cfg *config.CryptoConfig) error {
+	c, err := NewFieldCipher(cfg)
+	if err != nil {
+		return err
+	}
+	fieldCipher = c
+	return nil
+}
+
+// GetFieldCipher 获取全局字段加密器（未配置加密时为 nil）
+func GetFieldCipher() *FieldCipher {
+	return fieldCipher
+}
+
+// EncryptField 用全局字段加密器加密，未配置加密时原样返回
+func EncryptField(plaintext string) (string, error) {
+	if fieldCipher == nil {
+		return plaintext, nil
+	}
+	return fieldCipher.Encrypt(plaintext)
+}
+
+// DecryptField 用全局字段加密器解密，明文原样返回；遇到密文但未配置加密时报错
+func DecryptField(value string) (string, error) {
+	if !IsEncryptedField(value) {
+		return value, nil
+	}
+	if fieldCipher == nil {
+		return "", fmt.Errorf("字段已加密但未配置加密密钥")
+	}
+	return fieldCipher.Decrypt(value)
+}
+
+// MaskAccountNo 账户号码脱敏，只保留末4位（如 ********5678）
+func MaskAccountNo(accountNo string) string {
+	runes := []rune(accountNo)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+// MaskName 姓名脱敏，只保留首字（如 王**）
+func MaskName(name string) string {
+	if name == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(first) + strings.Repeat("*", utf8.RuneCountInString(name[size:]))
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"balance/backend/internal/config"
+)
+
+const (
+	testFieldKey1 = "GSIw5xtQc/nHz+ssSEPNsy6zpPW+Aekt9FCU35ahsz0="
+	testFieldKey2 = "q3Vb0x0cYl6n2m8Z1pQyJm9kGm3D4bA7uS0v5nW2eE8="
+)
+
+func newTestFieldCipher(t *testing.T, active string, keys map[string]string) *FieldCipher {
+	t.Helper()
+	c, err := NewFieldCipher(&config.CryptoConfig{ActiveKeyID: active, Keys: keys})
+	if err != nil {
+		t.Fatalf("NewFieldCipher: %v", err)
+	}
+	return c
+}
+
+func TestFieldCipherRoundTrip(t *testing.T) {
+	c := newTestFieldCipher(t, "k1", map[string]string{"k1": testFieldKey1})
+
+	for _, plaintext := range []string{"0123456789012", "王小明"} {
+		encrypted, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if !IsEncryptedField(encrypted) || FieldKeyID(encrypted) != "k1" || strings.Contains(encrypted, plaintext) {
+			t.Fatalf("Encrypt(%q) = %q, want ciphertext under k1", plaintext, encrypted)
+		}
+		decrypted, err := c.Decrypt(encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Fatalf("Decrypt = %q, %v, want %q", decrypted, err, plaintext)
+		}
+	}
+
+	// 同一明文每次使用不同数据密钥，密文不同
+	a, _ := c.Encrypt("same")
+	b, _ := c.Encrypt("same")
+	if a == b {
+		t.Fatal("ciphertexts of the same plaintext should differ")
+	}
+
+	if encrypted, _ := c.Encrypt(""); encrypted != "" {
+		t.Fatalf("Encrypt(\"\") = %q, want empty", encrypted)
+	}
+}
+
+func TestFieldCipherPlaintextPassthrough(t *testing.T) {
+	c := newTestFieldCipher(t, "k1", map[string]string{"k1": testFieldKey1})
+
+	got, err := c.Decrypt("legacy-plaintext")
+	if err != nil || got != "legacy-plaintext" {
+		t.Fatalf("Decrypt(plaintext) = %q, %v", got, err)
+	}
+	if !c.NeedsRotation("legacy-plaintext") || c.NeedsRotation("") {
+		t.Fatal("plaintext should need rotation, empty value should not")
+	}
+}
+
+func TestFieldCipherTamperAndUnknownKey(t *testing.T) {
+	c := newTestFieldCipher(t, "k1", map[string]string{"k1": testFieldKey1})
+	encrypted, _ := c.Encrypt("0123456789012")
+
+	// 篡改主密钥 ID（附加数据）后无法解密
+	other := newTestFieldCipher(t, "k2", map[string]string{"k1": testFieldKey1, "k2": testFieldKey1})
+	if _, err := other.Decrypt(strings.Replace(encrypted, ":k1:", ":k2:", 1)); err == nil {
+		t.Fatal("ciphertext moved to another key id should not decrypt")
+	}
+
+	// 篡改密文
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if tampered != encrypted {
+		if _, err := c.Decrypt(tampered); err == nil {
+			t.Fatal("tampered ciphertext should not decrypt")
+		}
+	}
+
+	// 旧主密钥已删除
+	onlyK2 := newTestFieldCipher(t, "k2", map[string]string{"k2": testFieldKey2})
+	if _, err := onlyK2.Decrypt(encrypted); err == nil {
+		t.Fatal("ciphertext under removed key should not decrypt")
+	}
+}
+
+func TestFieldCipherRotate(t *testing.T) {
+	old := newTestFieldCipher(t, "k1", map[string]string{"k1": testFieldKey1})
+	encrypted, _ := old.Encrypt("0123456789012")
+
+	c := newTestFieldCipher(t, "k2", map[string]string{"k1": testFieldKey1, "k2": testFieldKey2})
+	if !c.NeedsRotation(encrypted) {
+		t.Fatal("ciphertext under k1 should need rotation after switching to k2")
+	}
+	rotated, changed, err := c.Rotate(encrypted)
+	if err != nil || !changed || FieldKeyID(rotated) != "k2" {
+		t.Fatalf("Rotate = %q, %v, %v", rotated, changed, err)
+	}
+	if plaintext, err := c.Decrypt(rotated); err != nil || plaintext != "0123456789012" {
+		t.Fatalf("Decrypt(rotated) = %q, %v", plaintext, err)
+	}
+
+	// 重复执行不再改写
+	again, changed, err := c.Rotate(rotated)
+	if err != nil || changed || again != rotated {
+		t.Fatalf("Rotate(rotated) = %q, %v, %v, want unchanged", again, changed, err)
+	}
+
+	// 明文首次加密
+	fromPlain, changed, err := c.Rotate("王小明")
+	if err != nil || !changed || FieldKeyID(fromPlain) != "k2" {
+		t.Fatalf("Rotate(plaintext) = %q, %v, %v", fromPlain, changed, err)
+	}
+}
+
+func TestNewFieldCipherConfig(t *testing.T) {
+	if c, err := NewFieldCipher(&config.CryptoConfig{}); c != nil || err != nil {
+		t.Fatalf("empty config = %v, %v, want disabled", c, err)
+	}
+	bad := []config.CryptoConfig{
+		{ActiveKeyID: "k1", Keys: map[string]string{"k2": testFieldKey2}},
+		{ActiveKeyID: "k1", Keys: map[string]string{"k1": "c2hvcnQ="}},
+		{ActiveKeyID: "k1", Keys: map[string]string{"k1": "not base64!"}},
+		{ActiveKeyID: "k:1", Keys: map[string]string{"k:1": testFieldKey1}},
+	}
+	for i, cfg := range bad {
+		if _, err := NewFieldCipher(&cfg); err == nil {
+			t.Errorf("config %d should be rejected", i)
+		}
+	}
+}
+
+func TestMaskFields(t *testing.T) {
+	cases := []struct {
+		got, want string
+	}{
+		{MaskAccountNo("0123456789012"), "*********9012"},
+		{MaskAccountNo("123"), "***"},
+		{MaskAccountNo(""), ""},
+		{MaskName("王小明"), "王**"},
+		{MaskName("Alice"), "A****"},
+		{MaskName(""), ""},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("mask = %q, want %q", tc.got, tc.want)
+		}
+	}
+}
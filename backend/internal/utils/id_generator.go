@@ -153,11 +153,18 @@ const (
 	IDInitialShipmentRecord  int64 = 1600000000000
 	IDInitialShipment        int64 = 1700000000000
 	IDInitialReturn          int64 = 1800000000000 // 退货退款
-
-	IDInitialFinanceIncome int64 = 2000000000000 // 财务相关 2xxx
-	IDInitialAccountTx     int64 = 2100000000000
-	IDInitialWithdrawApp   int64 = 2200000000000
-	IDInitialRechargeApp   int64 = 2300000000000
+	IDInitialSettlementAdj   int64 = 1900000000000 // 结算调账明细
+
+	IDInitialFinanceIncome  int64 = 2000000000000 // 财务相关 2xxx
+	IDInitialAccountTx      int64 = 2100000000000
+	IDInitialWithdrawApp    int64 = 2200000000000
+	IDInitialRechargeApp    int64 = 2300000000000
+	IDInitialAccountJournal int64 = 2400000000000
+	IDInitialJournalPosting int64 = 2500000000000
+	IDInitialReversalApp    int64 = 2600000000000
+	IDInitialAccountHold    int64 = 2700000000000
+	IDInitialDepositRefund  int64 = 2800000000000
+	IDInitialPenaltyBonus   int64 = 2900000000000
 
 	IDInitialShop             int64 = 3000000000000 // 店铺相关 3xxx
 	IDInitialShopAuth         int64 = 3100000000000
@@ -166,6 +173,7 @@ const (
 	IDInitialProfitShareCfg   int64 = 3400000000000
 	IDInitialLogisticsChannel int64 = 3500000000000
 	IDInitialCollectionAcct   int64 = 3600000000000
+	IDInitialProfitShareRule  int64 = 3700000000000
 
 	IDInitialPrepaymentAcct    int64 = 4000000000000 // 账户相关 4xxx
 	IDInitialDepositAcct       int64 = 4100000000000
@@ -228,6 +236,10 @@ func (g *IDGenerator) GenerateReturnID(ctx context.Context) (int64, error) {
 	return g.generateBusinessID(ctx, "id:gen:return", IDInitialReturn)
 }
 
+func (g *IDGenerator) GenerateSettlementAdjustmentID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:settlement_adjustment", IDInitialSettlementAdj)
+}
+
 // ==================== 财务相关ID ====================
 
 func (g *IDGenerator) GenerateFinanceIncomeID(ctx context.Context) (int64, error) {
@@ -246,6 +258,30 @@ func (g *IDGenerator) GenerateRechargeRecordID(ctx context.Context) (int64, erro
 	return g.generateBusinessID(ctx, "id:gen:recharge_app", IDInitialRechargeApp)
 }
 
+func (g *IDGenerator) GenerateAccountJournalID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:account_journal", IDInitialAccountJournal)
+}
+
+func (g *IDGenerator) GenerateJournalPostingID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:journal_posting", IDInitialJournalPosting)
+}
+
+func (g *IDGenerator) GenerateReversalApplicationID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:reversal_app", IDInitialReversalApp)
+}
+
+func (g *IDGenerator) GenerateAccountHoldID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:account_hold", IDInitialAccountHold)
+}
+
+func (g *IDGenerator) GenerateDepositRefundID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:deposit_refund", IDInitialDepositRefund)
+}
+
+func (g *IDGenerator) GeneratePenaltyBonusEntryID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:penalty_bonus", IDInitialPenaltyBonus)
+}
+
 // ==================== 店铺相关ID ====================
 
 func (g *IDGenerator) GenerateShopID(ctx context.Context) (int64, error) {
@@ -276,6 +312,10 @@ func (g *IDGenerator) GenerateCollectionAccountID(ctx context.Context) (int64, e
 	return g.generateBusinessID(ctx, "id:gen:collection_acct", IDInitialCollectionAcct)
 }
 
+func (g *IDGenerator) GenerateProfitShareRuleID(ctx context.Context) (int64, error) {
+	return g.generateBusinessID(ctx, "id:gen:profit_share_rule", IDInitialProfitShareRule)
+}
+
 // ==================== 账户相关ID ====================
 
 func (g *IDGenerator) GeneratePrepaymentAccountID(ctx context.Context) (int64, error) {
@@ -4,13 +4,15 @@ import (
 	"context"
 	"fmt"
 
+	"balance/backend/internal/config"
+
 	"github.com/shopspring/decimal"
 )
 
 // ==================== 支付网关统一接口 ====================
 //
 // 所有第三方支付（PayPal、支付宝、LINE Pay、VISA 等）统一抽象为 PaymentGateway 接口。
-// 当前为预留封装，各实现返回 ErrPaymentNotImplemented，
+// LINE Pay 已对接（见 payment_linepay.go），其余渠道为预留封装，返回 ErrPaymentNotImplemented，
 // 待正式对接时替换为真实 SDK 调用即可，业务层无需改动。
 
 // ErrPaymentNotImplemented 支付渠道尚未对接
@@ -27,6 +29,16 @@ const (
 	PayMethodWechat       = "wechat"        // 微信支付
 )
 
+// 第三方支付状态
+const (
+	PaymentStatusCreated    = "created"    // 已创建，等待用户付款
+	PaymentStatusPending    = "pending"    // 付款中
+	PaymentStatusAuthorized = "authorized" // 用户已授权，等待商家请款（见 PaymentConfirmer）
+	PaymentStatusPaid       = "paid"       // 已支付
+	PaymentStatusFailed     = "failed"     // 付款失败
+	PaymentStatusCancelled  = "cancelled"  // 用户取消或已过期
+)
+
 // PaymentMethodInfo 支付方式元信息
 type PaymentMethodInfo struct {
 	Code    string `json:"code"`    // 支付方式编码
@@ -41,11 +53,11 @@ func AllPaymentMethods() []PaymentMethodInfo {
 	return []PaymentMethodInfo{
 		{Code: PayMethodBankTransfer, Name: "银行转账", Enabled: true, Online: false, Icon: ""},
 		{Code: PayMethodCash, Name: "现金", Enabled: true, Online: false, Icon: ""},
-		{Code: PayMethodPayPal, Name: "PayPal", Enabled: false, Online: true, Icon: ""},
-		{Code: PayMethodAlipay, Name: "支付宝", Enabled: false, Online: true, Icon: ""},
-		{Code: PayMethodLinePay, Name: "LINE Pay", Enabled: false, Online: true, Icon: ""},
-		{Code: PayMethodVisa, Name: "VISA", Enabled: false, Online: true, Icon: ""},
-		{Code: PayMethodWechat, Name: "微信支付", Enabled: false, Online: true, Icon: ""},
+		{Code: PayMethodPayPal, Name: "PayPal", Enabled: enabledOnlineMethods[PayMethodPayPal], Online: true, Icon: ""},
+		{Code: PayMethodAlipay, Name: "支付宝", Enabled: enabledOnlineMethods[PayMethodAlipay], Online: true, Icon: ""},
+		{Code: PayMethodLinePay, Name: "LINE Pay", Enabled: enabledOnlineMethods[PayMethodLinePay], Online: true, Icon: ""},
+		{Code: PayMethodVisa, Name: "VISA", Enabled: enabledOnlineMethods[PayMethodVisa], Online: true, Icon: ""},
+		{Code: PayMethodWechat, Name: "微信支付", Enabled: enabledOnlineMethods[PayMethodWechat], Online: true, Icon: ""},
 	}
 }
 
@@ -136,6 +148,12 @@ type PaymentGateway interface {
 	VerifyNotification(ctx context.Context, rawBody []byte) (*PaymentNotification, error)
 }
 
+// PaymentConfirmer 需要商家主动请款的网关（如 LINE Pay：用户授权后须按原金额调用 Confirm API 才完成扣款）
+type PaymentConfirmer interface {
+	// ConfirmPayment 按原金额请款，成功返回已支付通知
+	ConfirmPayment(ctx context.Context, paymentID string, amount decimal.Decimal, currency string) (*PaymentNotification, error)
+}
+
 // ==================== 各渠道占位实现 ====================
 
 // PayPalGateway PayPal 支付（占位）
@@ -180,27 +198,6 @@ func (g *AlipayGateway) VerifyNotification(_ context.Context, _ []byte) (*Paymen
 	return nil, ErrPaymentNotImplemented
 }
 
-// LinePayGateway LINE Pay（占位）
-type LinePayGateway struct{}
-
-func NewLinePayGateway() *LinePayGateway { return &LinePayGateway{} }
-
-func (g *LinePayGateway) Name() string { return "LINE Pay" }
-
-func (g *LinePayGateway) CreatePayment(_ context.Context, _ *CreatePaymentRequest) (*CreatePaymentResponse, error) {
-	// TODO: 对接 LINE Pay API v3
-	// https://pay.line.me/documents/online_v3.html
-	return nil, ErrPaymentNotImplemented
-}
-
-func (g *LinePayGateway) QueryPayment(_ context.Context, _ string) (*QueryPaymentResponse, error) {
-	return nil, ErrPaymentNotImplemented
-}
-
-func (g *LinePayGateway) VerifyNotification(_ context.Context, _ []byte) (*PaymentNotification, error) {
-	return nil, ErrPaymentNotImplemented
-}
-
 // VisaGateway VISA 信用卡（占位）
 type VisaGateway struct{}
 
@@ -248,11 +245,25 @@ func (g *WechatPayGateway) VerifyNotification(_ context.Context, _ []byte) (*Pay
 var paymentGateways = map[string]PaymentGateway{
 	PayMethodPayPal:  NewPayPalGateway(),
 	PayMethodAlipay:  NewAlipayGateway(),
-	PayMethodLinePay: NewLinePayGateway(),
+	PayMethodLinePay: NewLinePayGateway(config.LinePayConfig{}),
 	PayMethodVisa:    NewVisaGateway(),
 	PayMethodWechat:  NewWechatPayGateway(),
 }
 
+// enabledOnlineMethods 已启用的线上支付方式（由 InitPaymentGateways 按配置设置）
+var enabledOnlineMethods = map[string]bool{}
+
+// InitPaymentGateways 按配置初始化已对接的支付网关，进程启动时调用一次
+func InitPaymentGateways(cfg *config.PaymentConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.LinePay.Enabled && cfg.LinePay.ChannelID != "" && cfg.LinePay.ChannelSecret != "" {
+		RegisterPaymentGateway(PayMethodLinePay, NewLinePayGateway(cfg.LinePay))
+		enabledOnlineMethods[PayMethodLinePay] = true
+	}
+}
+
 // GetPaymentGateway 根据支付方式获取对应网关
 func GetPaymentGateway(method string) (PaymentGateway, error) {
 	gw, ok := paymentGateways[method]
@@ -0,0 +1,398 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"balance/backend/internal/config"
+
+	"github.com/shopspring/decimal"
+)
+
+// ==================== LINE Pay（Online API v3） ====================
+//
+// 付款流程：
+//  1. CreatePayment 调用 Request API 取得付款页面，用户在 LINE Pay 授权付款
+//  2. LINE Pay 将用户跳转回 confirmUrl（附 transactionId、orderId），由 VerifyNotification 解析，
+//     并通过 Check API 向 LINE Pay 核实交易状态（跳转参数本身不带签名，不能直接信任）
+//  3. 授权完成（authorized）后调用 ConfirmPayment 按原金额请款，成功即为已支付
+// https://pay.line.me/documents/online_v3.html
+
+const (
+	linePayProductionURL = "https://api-pay.line.me"
+	linePaySandboxURL    = "https://sandbox-api-pay.line.me"
+	linePayTimeout       = 20 * time.Second
+
+	linePayCodeSuccess    = "0000" // 成功（Check API 中表示尚在授权中）
+	linePayCodeAuthorized = "0110" // 已授权，等待商家请款
+	linePayCodeCancelled  = "0121" // 用户取消或授权已过期
+	linePayCodeFailed     = "0122" // 付款失败
+	linePayCodeCompleted  = "0123" // 已请款完成
+)
+
+// LinePayGateway LINE Pay 支付（未配置渠道 ID/密钥时各方法返回 ErrPaymentNotImplemented）
+type LinePayGateway struct {
+	channelID     string
+	channelSecret string
+	baseURL       string
+	confirmURL    string
+	cancelURL     string
+	client        *http.Client
+}
+
+// NewLinePayGateway 根据配置创建 LINE Pay 网关
+func NewLinePayGateway(cfg config.LinePayConfig) *LinePayGateway {
+	baseURL := cfg.APIBaseURL
+	if baseURL == "" {
+		baseURL = linePayProductionURL
+		if cfg.Sandbox {
+			baseURL = linePaySandboxURL
+		}
+	}
+	return &LinePayGateway{
+		channelID:     cfg.ChannelID,
+		channelSecret: cfg.ChannelSecret,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		confirmURL:    cfg.NotifyURL,
+		cancelURL:     cfg.CancelURL,
+		client:        &http.Client{Timeout: linePayTimeout},
+	}
+}
+
+func (g *LinePayGateway) Name() string { return "LINE Pay" }
+
+// configured 是否已配置渠道凭证
+func (g *LinePayGateway) configured() bool {
+	return g.channelID != "" && g.channelSecret != ""
+}
+
+// linePayResponse LINE Pay 接口统一响应
+type linePayResponse struct {
+	ReturnCode    string          `json:"returnCode"`
+	ReturnMessage string          `json:"returnMessage"`
+	Info          json.RawMessage `json:"info"`
+}
+
+// linePayPayInfo 付款方式明细
+type linePayPayInfo struct {
+	Method string      `json:"method"`
+	Amount json.Number `json:"amount"`
+}
+
+// SignLinePayRequest LINE Pay 请求签名：Base64(HMAC-SHA256(channelSecret, channelSecret + uri + payload + nonce))，
+// POST 的 payload 为请求体，GET 的 payload 为查询字符串
+func SignLinePayRequest(channelSecret, uri, payload, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(channelSecret))
+	mac.Write([]byte(channelSecret + uri + payload + nonce))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// linePayNonce 生成请求随机串
+func linePayNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// linePayAmount 金额转为 LINE Pay 数值（TWD/JPY 只支持整数）
+func linePayAmount(amount decimal.Decimal, currency string) (json.Number, error) {
+	if (currency == "TWD" || currency == "JPY") && !amount.IsInteger() {
+		return "", fmt.Errorf("LINE Pay %s 只支持整数金额: %s", currency, amount.String())
+	}
+	return json.Number(amount.String()), nil
+}
+
+// call 发送签名请求，返回 LINE Pay 统一响应（returnCode 由调用方判断）
+func (g *LinePayGateway) call(ctx context.Context, method, uri string, query url.Values, body any) (*linePayResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	signPayload := string(payload)
+	target := g.baseURL + uri
+	if len(query) > 0 {
+		signPayload = query.Encode()
+		target += "?" + signPayload
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	nonce := linePayNonce()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-LINE-ChannelId", g.channelID)
+	req.Header.Set("X-LINE-Authorization-Nonce", nonce)
+	req.Header.Set("X-LINE-Authorization", SignLinePayRequest(g.channelSecret, uri, signPayload, nonce))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 LINE Pay 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取 LINE Pay 响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LINE Pay 返回状态码 %d", resp.StatusCode)
+	}
+
+	var result linePayResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析 LINE Pay 响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// CreatePayment 调用 Request API 创建付款，返回付款页面链接
+func (g *LinePayGateway) CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*CreatePaymentResponse, error) {
+	if !g.configured() {
+		return nil, ErrPaymentNotImplemented
+	}
+	amount, err := linePayAmount(req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	confirmURL := req.NotifyURL
+	if confirmURL == "" {
+		confirmURL = g.confirmURL
+	}
+	if confirmURL == "" {
+		return nil, fmt.Errorf("LINE Pay 未配置 notify_url")
+	}
+	cancelURL := req.CancelURL
+	if cancelURL == "" {
+		cancelURL = g.cancelURL
+	}
+	if cancelURL == "" {
+		cancelURL = confirmURL
+	}
+
+	type product struct {
+		Name     string      `json:"name"`
+		Quantity int         `json:"quantity"`
+		Price    json.Number `json:"price"`
+	}
+	type pkg struct {
+		ID       string      `json:"id"`
+		Amount   json.Number `json:"amount"`
+		Name     string      `json:"name"`
+		Products []product   `json:"products"`
+	}
+	body := map[string]any{
+		"amount":   amount,
+		"currency": req.Currency,
+		"orderId":  req.OrderNo,
+		"packages": []pkg{{
+			ID:       req.OrderNo,
+			Amount:   amount,
+			Name:     req.Description,
+			Products: []product{{Name: req.Description, Quantity: 1, Price: amount}},
+		}},
+		"redirectUrls": map[string]string{
+			"confirmUrl": confirmURL,
+			"cancelUrl":  cancelURL,
+		},
+	}
+
+	resp, err := g.call(ctx, http.MethodPost, "/v3/payments/request", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ReturnCode != linePayCodeSuccess {
+		return nil, fmt.Errorf("LINE Pay 创建付款失败 %s: %s", resp.ReturnCode, resp.ReturnMessage)
+	}
+	var info struct {
+		TransactionID json.Number `json:"transactionId"`
+		PaymentURL    struct {
+			Web string `json:"web"`
+			App string `json:"app"`
+		} `json:"paymentUrl"`
+	}
+	if err := json.Unmarshal(resp.Info, &info); err != nil {
+		return nil, fmt.Errorf("解析 LINE Pay 付款信息失败: %w", err)
+	}
+	return &CreatePaymentResponse{
+		PaymentID:  info.TransactionID.String(),
+		PaymentURL: info.PaymentURL.Web,
+		Status:     PaymentStatusCreated,
+	}, nil
+}
+
+// QueryPayment 调用 Check API 查询付款状态，已请款完成时补充订单号与实付金额
+func (g *LinePayGateway) QueryPayment(ctx context.Context, paymentID string) (*QueryPaymentResponse, error) {
+	if !g.configured() {
+		return nil, ErrPaymentNotImplemented
+	}
+	resp, err := g.call(ctx, http.MethodGet, "/v3/payments/requests/"+url.PathEscape(paymentID)+"/check", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryPaymentResponse{PaymentID: paymentID}
+	switch resp.ReturnCode {
+	case linePayCodeSuccess:
+		result.Status = PaymentStatusPending
+	case linePayCodeAuthorized:
+		result.Status = PaymentStatusAuthorized
+	case linePayCodeCancelled:
+		result.Status = PaymentStatusCancelled
+	case linePayCodeFailed:
+		result.Status = PaymentStatusFailed
+	case linePayCodeCompleted:
+		result.Status = PaymentStatusPaid
+		if err := g.fillPaymentDetail(ctx, result); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("LINE Pay 查询付款状态失败 %s: %s", resp.ReturnCode, resp.ReturnMessage)
+	}
+	return result, nil
+}
+
+// fillPaymentDetail 调用 Payment Details API 补充订单号、币种与实付金额
+func (g *LinePayGateway) fillPaymentDetail(ctx context.Context, result *QueryPaymentResponse) error {
+	resp, err := g.call(ctx, http.MethodGet, "/v3/payments", url.Values{"transactionId": {result.PaymentID}}, nil)
+	if err != nil {
+		return err
+	}
+	if resp.ReturnCode != linePayCodeSuccess {
+		return fmt.Errorf("LINE Pay 查询付款明细失败 %s: %s", resp.ReturnCode, resp.ReturnMessage)
+	}
+	var details []struct {
+		OrderID  string           `json:"orderId"`
+		Currency string           `json:"currency"`
+		PayInfo  []linePayPayInfo `json:"payInfo"`
+	}
+	if err := json.Unmarshal(resp.Info, &details); err != nil {
+		return fmt.Errorf("解析 LINE Pay 付款明细失败: %w", err)
+	}
+	if len(details) == 0 {
+		return fmt.Errorf("LINE Pay 付款明细为空: %s", result.PaymentID)
+	}
+	amount, err := sumLinePayInfo(details[0].PayInfo)
+	if err != nil {
+		return err
+	}
+	result.OrderNo = details[0].OrderID
+	result.Currency = details[0].Currency
+	result.Amount = amount
+	return nil
+}
+
+// sumLinePayInfo 汇总各付款方式金额（LINE Points 折抵与信用卡等可能拆成多条）
+func sumLinePayInfo(items []linePayPayInfo) (decimal.Decimal, error) {
+	total := decimal.Zero
+	for _, item := range items {
+		amount, err := decimal.NewFromString(item.Amount.String())
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("LINE Pay 付款金额格式错误: %s", item.Amount)
+		}
+		total = total.Add(amount)
+	}
+	return total, nil
+}
+
+// VerifyNotification 解析 confirmUrl 跳转参数（查询字符串 transactionId=...&orderId=...），
+// 并通过 Check API 核实交易确属本渠道且状态有效
+func (g *LinePayGateway) VerifyNotification(ctx context.Context, rawBody []byte) (*PaymentNotification, error) {
+	if !g.configured() {
+		return nil, ErrPaymentNotImplemented
+	}
+	values, err := url.ParseQuery(string(rawBody))
+	if err != nil {
+		return nil, fmt.Errorf("LINE Pay 回调参数格式错误: %w", err)
+	}
+	transactionID := values.Get("transactionId")
+	orderID := values.Get("orderId")
+	if transactionID == "" || orderID == "" {
+		return nil, fmt.Errorf("LINE Pay 回调缺少 transactionId 或 orderId")
+	}
+
+	query, err := g.QueryPayment(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if query.OrderNo != "" && query.OrderNo != orderID {
+		return nil, fmt.Errorf("LINE Pay 回调订单号与交易不符: %s", orderID)
+	}
+	return &PaymentNotification{
+		PaymentID: transactionID,
+		OrderNo:   orderID,
+		Amount:    query.Amount,
+		Currency:  query.Currency,
+		Status:    query.Status,
+		RawData:   string(rawBody),
+	}, nil
+}
+
+// ConfirmPayment 调用 Confirm API 按原金额请款；重复请款时以 Check API 结果为准
+func (g *LinePayGateway) ConfirmPayment(ctx context.Context, paymentID string, amount decimal.Decimal, currency string) (*PaymentNotification, error) {
+	if !g.configured() {
+		return nil, ErrPaymentNotImplemented
+	}
+	value, err := linePayAmount(amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.call(ctx, http.MethodPost, "/v3/payments/"+url.PathEscape(paymentID)+"/confirm", nil, map[string]any{
+		"amount":   value,
+		"currency": currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ReturnCode != linePayCodeSuccess {
+		query, queryErr := g.QueryPayment(ctx, paymentID)
+		if queryErr != nil || query.Status != PaymentStatusPaid {
+			return nil, fmt.Errorf("LINE Pay 请款失败 %s: %s", resp.ReturnCode, resp.ReturnMessage)
+		}
+		return &PaymentNotification{
+			PaymentID: paymentID,
+			OrderNo:   query.OrderNo,
+			Amount:    query.Amount,
+			Currency:  query.Currency,
+			Status:    PaymentStatusPaid,
+			RawData:   string(resp.Info),
+		}, nil
+	}
+
+	var info struct {
+		OrderID string           `json:"orderId"`
+		PayInfo []linePayPayInfo `json:"payInfo"`
+	}
+	if err := json.Unmarshal(resp.Info, &info); err != nil {
+		return nil, fmt.Errorf("解析 LINE Pay 请款结果失败: %w", err)
+	}
+	paid, err := sumLinePayInfo(info.PayInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentNotification{
+		PaymentID: paymentID,
+		OrderNo:   info.OrderID,
+		Amount:    paid,
+		Currency:  currency,
+		Status:    PaymentStatusPaid,
+		RawData:   string(resp.Info),
+	}, nil
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"balance/backend/internal/config"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testLinePayChannelID     = "1650000000"
+	testLinePayChannelSecret = "test-channel-secret"
+	testLinePayTransactionID = "2026101800000000001"
+)
+
+// linePayStub 模拟 LINE Pay API：校验签名，按交易状态返回 Request/Check/Confirm/Details 结果
+type linePayStub struct {
+	t         *testing.T
+	confirmed bool
+	requested map[string]any
+}
+
+func (s *linePayStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	payload := string(body)
+	if r.Method == http.MethodGet {
+		payload = r.URL.RawQuery
+	}
+	if r.Header.Get("X-LINE-ChannelId") != testLinePayChannelID {
+		s.t.Errorf("%s: channel id = %q", r.URL.Path, r.Header.Get("X-LINE-ChannelId"))
+	}
+	want := SignLinePayRequest(testLinePayChannelSecret, r.URL.Path, payload, r.Header.Get("X-LINE-Authorization-Nonce"))
+	if r.Header.Get("X-LINE-Authorization") != want {
+		s.t.Errorf("%s: bad signature", r.URL.Path)
+	}
+
+	reply := func(code string, info string) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"returnCode":"`+code+`","returnMessage":"stub","info":`+info+`}`)
+	}
+	switch {
+	case r.URL.Path == "/v3/payments/request":
+		json.Unmarshal(body, &s.requested)
+		// transactionId 为 19 位整数，必须按数字原样解析
+		reply("0000", `{"transactionId":`+testLinePayTransactionID+`,"paymentAccessToken":"187568751124","paymentUrl":{"web":"https://stub/pay","app":"line://pay"}}`)
+	case r.URL.Path == "/v3/payments/requests/"+testLinePayTransactionID+"/check":
+		if s.confirmed {
+			reply("0123", `null`)
+		} else {
+			reply("0110", `null`)
+		}
+	case r.URL.Path == "/v3/payments/"+testLinePayTransactionID+"/confirm":
+		var req struct {
+			Amount json.Number `json:"amount"`
+		}
+		json.Unmarshal(body, &req)
+		if s.confirmed {
+			reply("1172", `null`)
+			return
+		}
+		if req.Amount.String() != "1500" {
+			reply("1106", `null`)
+			return
+		}
+		s.confirmed = true
+		reply("0000", `{"orderId":"RC001","transactionId":`+testLinePayTransactionID+`,"payInfo":[{"method":"BALANCE","amount":1000},{"method":"POINT","amount":500}]}`)
+	case r.URL.Path == "/v3/payments":
+		reply("0000", `[{"transactionId":`+testLinePayTransactionID+`,"orderId":"RC001","currency":"TWD","payInfo":[{"method":"BALANCE","amount":1500}]}]`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestLinePayGateway(t *testing.T) {
+	stub := &linePayStub{t: t}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	gw := NewLinePayGateway(config.LinePayConfig{
+		ChannelID:     testLinePayChannelID,
+		ChannelSecret: testLinePayChannelSecret,
+		APIBaseURL:    srv.URL,
+		NotifyURL:     "https://api.example.com/payment/notify/linepay",
+	})
+	ctx := context.Background()
+	amount := decimal.RequireFromString("1500.00")
+
+	created, err := gw.CreatePayment(ctx, &CreatePaymentRequest{OrderNo: "RC001", Amount: amount, Currency: "TWD", Description: "预付款充值"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.PaymentID != testLinePayTransactionID || created.PaymentURL != "https://stub/pay" {
+		t.Errorf("created = %+v", created)
+	}
+	if stub.requested["orderId"] != "RC001" || stub.requested["amount"] != float64(1500) {
+		t.Errorf("request body = %v", stub.requested)
+	}
+	redirect := stub.requested["redirectUrls"].(map[string]any)
+	if redirect["confirmUrl"] != "https://api.example.com/payment/notify/linepay" {
+		t.Errorf("confirmUrl = %v", redirect["confirmUrl"])
+	}
+
+	if _, err := gw.CreatePayment(ctx, &CreatePaymentRequest{OrderNo: "RC002", Amount: decimal.RequireFromString("10.5"), Currency: "TWD"}); err == nil {
+		t.Error("fractional TWD amount accepted")
+	}
+
+	n, err := gw.VerifyNotification(ctx, []byte("transactionId="+testLinePayTransactionID+"&orderId=RC001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Status != PaymentStatusAuthorized || n.PaymentID != testLinePayTransactionID || n.OrderNo != "RC001" {
+		t.Errorf("notification = %+v", n)
+	}
+	if _, err := gw.VerifyNotification(ctx, []byte("orderId=RC001")); err == nil {
+		t.Error("notification without transactionId accepted")
+	}
+
+	if _, err := gw.ConfirmPayment(ctx, testLinePayTransactionID, decimal.RequireFromString("1499"), "TWD"); err == nil {
+		t.Error("confirm with wrong amount succeeded")
+	}
+	paid, err := gw.ConfirmPayment(ctx, testLinePayTransactionID, amount, "TWD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid.Status != PaymentStatusPaid || !paid.Amount.Equal(amount) || paid.OrderNo != "RC001" {
+		t.Errorf("confirmed = %+v", paid)
+	}
+
+	// 重复请款以 Check API 为准，仍返回已支付
+	again, err := gw.ConfirmPayment(ctx, testLinePayTransactionID, amount, "TWD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Status != PaymentStatusPaid || !again.Amount.Equal(amount) || again.Currency != "TWD" {
+		t.Errorf("reconfirmed = %+v", again)
+	}
+
+	query, err := gw.QueryPayment(ctx, testLinePayTransactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Status != PaymentStatusPaid || query.OrderNo != "RC001" || !query.Amount.Equal(amount) {
+		t.Errorf("query = %+v", query)
+	}
+}
+
+func TestLinePayGatewayNotConfigured(t *testing.T) {
+	gw := NewLinePayGateway(config.LinePayConfig{Sandbox: true})
+	if gw.baseURL != linePaySandboxURL {
+		t.Errorf("baseURL = %s", gw.baseURL)
+	}
+	_, err := gw.CreatePayment(context.Background(), &CreatePaymentRequest{OrderNo: "RC001", Amount: decimal.NewFromInt(1), Currency: "TWD"})
+	if err != ErrPaymentNotImplemented {
+		t.Errorf("err = %v", err)
+	}
+	if !strings.HasPrefix(NewLinePayGateway(config.LinePayConfig{}).baseURL, linePayProductionURL) {
+		t.Error("production base URL not used")
+	}
+}
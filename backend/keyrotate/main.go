@@ -0,0 +1,49 @@
+// keyrotate 字段加密主密钥轮换工具（一次性命令）
+// 将收款账户、代付明细、店铺授权令牌中的明文和旧主密钥密文按批用当前主密钥重新加密，可重复执行
+//
+// 轮换步骤: 在各服务配置 crypto.keys 中新增密钥并切换 active_key_id -> 重启服务 -> 执行本命令 -> 删除旧密钥
+// 运行方式: cd backend && go run ./keyrotate -config cron/config/config.yaml [-batch 500] [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"balance/backend/internal/config"
+	"balance/backend/internal/database"
+	"balance/backend/internal/services"
+	"balance/backend/internal/utils"
+)
+
+func main() {
+	configPath := flag.String("config", "cron/config/config.yaml", "配置文件路径")
+	batchSize := flag.Int("batch", 500, "每批处理行数")
+	dryRun := flag.Bool("dry-run", false, "只统计需要重新加密的行数，不写入")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	if err := utils.InitFieldCipher(&cfg.Crypto); err != nil {
+		log.Fatalf("初始化字段加密失败: %v", err)
+	}
+
+	if err := database.InitMySQL(&cfg.MySQL); err != nil {
+		log.Fatalf("初始化MySQL失败: %v", err)
+	}
+	defer database.Close()
+	log.Println("MySQL连接成功")
+
+	log.Printf("[KeyRotate] 当前主密钥 %s，每批 %d 行，dry-run=%v", utils.GetFieldCipher().ActiveKeyID(), *batchSize, *dryRun)
+	results, err := services.NewFieldEncryptionService().RotateAll(context.Background(), *batchSize, *dryRun)
+	for _, r := range results {
+		log.Printf("[KeyRotate] %s: 扫描 %d 行，重新加密 %d 行", r.Table, r.Scanned, r.Rotated)
+	}
+	if err != nil {
+		log.Fatalf("[KeyRotate] 重新加密失败: %v", err)
+	}
+	log.Println("[KeyRotate] 完成")
+}
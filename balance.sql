@@ -13,7 +13,7 @@ SET NAMES utf8mb4;
 SET FOREIGN_KEY_CHECKS = 0;
 
 -- ============================================================================
--- 第一部分：基础表（不分表）共 21 张
+-- 第一部分：基础表（不分表）共 47 张
 -- ============================================================================
 
 -- ----------------------------
@@ -118,8 +118,8 @@ DROP TABLE IF EXISTS `shop_authorizations`;
 CREATE TABLE `shop_authorizations` (
   `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
   `shop_id` bigint unsigned NOT NULL COMMENT 'Shopee店铺ID',
-  `access_token` varchar(512) NOT NULL COMMENT '访问令牌',
-  `refresh_token` varchar(512) NOT NULL COMMENT '刷新令牌',
+  `access_token` varchar(512) NOT NULL COMMENT '访问令牌(加密存储)',
+  `refresh_token` varchar(512) NOT NULL COMMENT '刷新令牌(加密存储)',
   `token_type` varchar(50) NOT NULL DEFAULT 'Bearer' COMMENT '令牌类型',
   `expires_at` datetime NOT NULL COMMENT '访问令牌过期时间',
   `refresh_expires_at` datetime NOT NULL COMMENT '刷新令牌过期时间',
@@ -206,24 +206,26 @@ CREATE TABLE `shop_sync_escrow_records` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='店铺结算明细同步记录表';
 
 -- ----------------------------
--- 6. 利润分成配置表
+-- 6. 利润分成配置表（按生效时间分版本，结算按订单支付时间取版本）
 -- ----------------------------
 DROP TABLE IF EXISTS `profit_share_configs`;
 CREATE TABLE `profit_share_configs` (
   `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
   `shop_id` bigint unsigned NOT NULL COMMENT 'Shopee店铺ID',
   `operator_id` bigint NOT NULL COMMENT '运营老板ID',
+  `version` int NOT NULL DEFAULT 1 COMMENT '版本号(同一店铺+运营递增)',
   `platform_share_rate` decimal(5,2) NOT NULL DEFAULT 5.00 COMMENT '平台分成比例(%)',
   `operator_share_rate` decimal(5,2) NOT NULL DEFAULT 45.00 COMMENT '运营分成比例(%)',
   `shop_owner_share_rate` decimal(5,2) NOT NULL DEFAULT 50.00 COMMENT '店主分成比例(%)',
-  `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=生效 2=失效',
+  `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=生效(含已排期) 2=已撤销',
   `effective_from` datetime NOT NULL COMMENT '生效开始时间',
-  `effective_to` datetime DEFAULT NULL COMMENT '生效结束时间',
+  `effective_to` datetime DEFAULT NULL COMMENT '生效结束时间(下一版本的生效时间，NULL为当前最新版本)',
   `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '创建人ID',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uk_shop_operator_config` (`shop_id`, `operator_id`)
+  KEY `idx_shop_operator_effective` (`shop_id`, `operator_id`, `effective_from`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='利润分成配置表';
 
 -- ----------------------------
@@ -245,7 +247,7 @@ CREATE TABLE `logistics_channels` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='物流渠道表';
 
 -- ----------------------------
--- 8. 预付款账户表（店主发货前预付成本）
+-- 8. 预付款账户表（店主发货前预付成本，按币种分账户）
 -- ----------------------------
 DROP TABLE IF EXISTS `prepayment_accounts`;
 CREATE TABLE `prepayment_accounts` (
@@ -260,7 +262,7 @@ CREATE TABLE `prepayment_accounts` (
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uk_admin_id` (`admin_id`)
+  UNIQUE KEY `uk_admin_currency` (`admin_id`, `currency`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='预付款账户表';
 
 -- ----------------------------
@@ -271,7 +273,7 @@ CREATE TABLE `deposit_accounts` (
   `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
   `admin_id` bigint NOT NULL COMMENT '店铺老板ID(关联admin表)',
   `balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '保证金余额',
-  `required_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '应缴保证金金额',
+  `required_amount` decimal(15,2) NOT NULL DEFAULT 15000.00 COMMENT '应缴保证金金额',
   `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
   `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=正常 2=不足 3=暂停',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
@@ -281,7 +283,7 @@ CREATE TABLE `deposit_accounts` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='保证金账户表';
 
 -- ----------------------------
--- 10. 运营老板账户表（运营收到的成本+分成）
+-- 10. 运营老板账户表（运营收到的成本+分成，按币种分账户）
 -- ----------------------------
 DROP TABLE IF EXISTS `operator_accounts`;
 CREATE TABLE `operator_accounts` (
@@ -289,6 +291,7 @@ CREATE TABLE `operator_accounts` (
   `admin_id` bigint NOT NULL COMMENT '运营老板ID(关联admin表)',
   `balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '可用余额',
   `pending_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '待结算金额(提现申请时暂扣，打款后扣除)',
+  `held_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '风控暂扣金额(生效中的暂扣合计，计入余额但不可提现)',
   `total_earnings` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '累计收益金额',
   `total_withdrawn` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '累计提现金额',
   `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
@@ -296,11 +299,11 @@ CREATE TABLE `operator_accounts` (
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uk_admin_id` (`admin_id`)
+  UNIQUE KEY `uk_admin_currency` (`admin_id`, `currency`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='运营老板账户表';
 
 -- ----------------------------
--- 11. 店主佣金账户表（店主利润分成）
+-- 11. 店主佣金账户表（店主利润分成，按币种分账户）
 -- ----------------------------
 DROP TABLE IF EXISTS `shop_owner_commission_accounts`;
 CREATE TABLE `shop_owner_commission_accounts` (
@@ -308,6 +311,7 @@ CREATE TABLE `shop_owner_commission_accounts` (
   `admin_id` bigint NOT NULL COMMENT '店铺老板ID(关联admin表)',
   `balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '可用余额',
   `pending_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '待结算金额(提现申请时暂扣，打款后扣除)',
+  `held_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '风控暂扣金额(生效中的暂扣合计，计入余额但不可提现)',
   `total_earnings` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '累计收益金额',
   `total_withdrawn` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '累计提现金额',
   `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
@@ -315,15 +319,15 @@ CREATE TABLE `shop_owner_commission_accounts` (
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uk_admin_id` (`admin_id`)
+  UNIQUE KEY `uk_admin_currency` (`admin_id`, `currency`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='店主佣金账户表';
 
 -- ----------------------------
--- 12. 平台佣金账户表（平台利润分成，单例记录）
+-- 12. 平台佣金账户表（平台利润分成，每个币种一条记录）
 -- ----------------------------
 DROP TABLE IF EXISTS `platform_commission_accounts`;
 CREATE TABLE `platform_commission_accounts` (
-  `id` bigint unsigned NOT NULL COMMENT '主键ID(单例,固定为1)',
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
   `balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '可用余额',
   `pending_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '待结算金额(提现申请时暂扣)',
   `total_earnings` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '累计收益金额',
@@ -332,15 +336,16 @@ CREATE TABLE `platform_commission_accounts` (
   `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=正常 2=暂停',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_currency` (`currency`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='平台佣金账户表';
 
 -- ----------------------------
--- 13. 罚补账户表（运营罚款和补贴）
+-- 13. 罚补账户表（每个用户每币种一条，汇总已审核罚补单的待结算金额）
 -- ----------------------------
 DROP TABLE IF EXISTS `penalty_bonus_accounts`;
 CREATE TABLE `penalty_bonus_accounts` (
-  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
   `admin_id` bigint NOT NULL COMMENT '用户ID(关联admin表)',
   `balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '余额(正=待付罚款,负=待发补贴)',
   `total_penalty` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '累计罚款金额',
@@ -350,7 +355,7 @@ CREATE TABLE `penalty_bonus_accounts` (
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uk_admin_id` (`admin_id`)
+  UNIQUE KEY `uk_admin_currency` (`admin_id`, `currency`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='罚补账户表';
 
 
@@ -364,12 +369,13 @@ CREATE TABLE `collection_accounts` (
   `admin_id` bigint NOT NULL COMMENT '用户ID(关联admin表)',
   `account_type` varchar(20) NOT NULL COMMENT '账户类型: wallet=电子钱包 bank=银行账户',
   `account_name` varchar(100) NOT NULL COMMENT '账户名称',
-  `account_no` varchar(100) NOT NULL COMMENT '账户号码',
+  `account_no` varchar(512) NOT NULL COMMENT '账户号码(加密存储)',
   `bank_name` varchar(100) NOT NULL DEFAULT '' COMMENT '银行名称',
   `bank_branch` varchar(200) NOT NULL DEFAULT '' COMMENT '银行支行',
-  `payee` varchar(100) NOT NULL COMMENT '收款人姓名',
+  `payee` varchar(512) NOT NULL COMMENT '收款人姓名(加密存储)',
   `is_default` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否默认账户',
   `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=正常 2=未激活',
+  `changed_at` datetime DEFAULT NULL COMMENT '收款信息最近变更时间(为空取创建时间，提现冷却期据此计算)',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
@@ -386,11 +392,15 @@ CREATE TABLE `withdraw_applications` (
   `admin_id` bigint NOT NULL COMMENT '申请人ID(关联admin表)',
   `account_type` varchar(30) NOT NULL COMMENT '账户类型: operator/shop_owner_commission等',
   `amount` decimal(15,2) NOT NULL COMMENT '提现金额',
-  `fee` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '手续费',
-  `actual_amount` decimal(15,2) NOT NULL COMMENT '实际到账金额',
-  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
+  `fee` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '手续费（按提现策略计算，打款时转入平台佣金账户）',
+  `actual_amount` decimal(15,2) NOT NULL COMMENT '实际到账金额(到账币种)',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '提现账户币种',
+  `payout_currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '到账币种',
+  `fx_rate` decimal(18,8) NOT NULL DEFAULT 1.00000000 COMMENT '申请时锁定的汇率(提现币种→到账币种)',
+  `fx_rate_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '使用的汇率记录ID(同币种为0)',
   `collection_account_id` bigint unsigned NOT NULL COMMENT '收款账户ID(关联collection_accounts)',
-  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核 1=已通过 2=已拒绝 3=已打款',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核 1=已通过 2=已拒绝 3=已打款 4=打款失败(暂扣金额已退回)',
+  `payout_batch_no` varchar(64) NOT NULL DEFAULT '' COMMENT '锁定的代付批次号(空为未入批次，入批次后只能通过导入银行回单确认打款)',
   `audit_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '审核备注',
   `audit_by` bigint NOT NULL DEFAULT 0 COMMENT '审核人ID',
   `audit_at` datetime DEFAULT NULL COMMENT '审核时间',
@@ -403,6 +413,7 @@ CREATE TABLE `withdraw_applications` (
   KEY `idx_admin_id` (`admin_id`),
   KEY `idx_account_type` (`account_type`),
   KEY `idx_status` (`status`),
+  KEY `idx_payout_batch_no` (`payout_batch_no`),
   KEY `idx_created_at` (`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提现申请表';
 
@@ -417,9 +428,13 @@ CREATE TABLE `recharge_record` (
   `account_type` varchar(30) NOT NULL COMMENT '账户类型: prepayment=预付款 deposit=保证金',
   `amount` decimal(15,2) NOT NULL COMMENT '充值金额',
   `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
-  `payment_method` varchar(30) NOT NULL COMMENT '支付方式: bank_transfer=银行转账 cash=现金',
+  `payment_method` varchar(30) NOT NULL COMMENT '支付方式: bank_transfer=银行转账 cash=现金 linepay=LINE Pay(线上)',
   `payment_proof` varchar(500) NOT NULL DEFAULT '' COMMENT '支付凭证图片URL',
-  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核 1=已通过 2=已拒绝',
+  `payment_id` varchar(100) NOT NULL DEFAULT '' COMMENT '第三方支付单号(线上充值)',
+  `payment_url` varchar(1000) NOT NULL DEFAULT '' COMMENT '第三方支付页面链接(线上充值)',
+  `paid_at` datetime DEFAULT NULL COMMENT '线上支付完成时间',
+  `next_poll_at` datetime DEFAULT NULL COMMENT '线上充值下次轮询网关的时间(仍待支付/金额不符的推迟，为空时按创建时间)',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核(线上充值为待支付) 1=已通过 2=已拒绝',
   `audit_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '审核备注',
   `audit_by` bigint NOT NULL DEFAULT 0 COMMENT '审核人ID',
   `audit_at` datetime DEFAULT NULL COMMENT '审核时间',
@@ -430,6 +445,7 @@ CREATE TABLE `recharge_record` (
   UNIQUE KEY `uk_application_no` (`application_no`),
   KEY `idx_admin_id` (`admin_id`),
   KEY `idx_account_type` (`account_type`),
+  KEY `idx_payment_id` (`payment_id`),
   KEY `idx_status` (`status`),
   KEY `idx_created_at` (`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='充值记录表';
@@ -513,14 +529,703 @@ CREATE TABLE `platform_daily_stats` (
   UNIQUE KEY `uk_stat_date` (`stat_date`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='平台每日统计表';
 
+-- ----------------------------
+-- 21. 记账凭证表（一次业务事件一张凭证，借贷合计必须相等）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_journals`;
+CREATE TABLE `account_journals` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `journal_no` varchar(64) NOT NULL COMMENT '凭证号(唯一)',
+  `biz_type` varchar(30) NOT NULL COMMENT '业务类型: recharge/deposit_pay/order_freeze/order_refund/settlement/adjustment/withdraw_apply/withdraw_reject/withdraw_paid',
+  `order_sn` varchar(64) NOT NULL DEFAULT '' COMMENT '关联订单号',
+  `biz_no` varchar(64) NOT NULL DEFAULT '' COMMENT '关联业务单号(结算单/申请单)',
+  `total_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '借方合计(=贷方合计)',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注',
+  `operator_id` bigint NOT NULL DEFAULT 0 COMMENT '操作人ID',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_journal_no` (`journal_no`),
+  KEY `idx_biz_type` (`biz_type`),
+  KEY `idx_order_sn` (`order_sn`),
+  KEY `idx_biz_no` (`biz_no`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='记账凭证表';
+
+-- ----------------------------
+-- 22. 凭证分录表（单个账户的一笔借/贷，用户账户贷方=余额增加）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_journal_postings`;
+CREATE TABLE `account_journal_postings` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `journal_no` varchar(64) NOT NULL COMMENT '凭证号',
+  `account_type` varchar(30) NOT NULL COMMENT '账户类型(含清算科目 bank/escrow/unallocated_loss)',
+  `admin_id` bigint NOT NULL COMMENT '账户所属用户ID(平台/清算科目为0)',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '币种(凭证按币种分别借贷平衡)',
+  `bucket` varchar(20) NOT NULL COMMENT '余额分类: available=可用余额 pending=待结算/暂扣',
+  `direction` varchar(10) NOT NULL COMMENT '借贷方向: debit=借 credit=贷',
+  `amount` decimal(15,2) NOT NULL COMMENT '金额(恒为正)',
+  `transaction_no` varchar(64) NOT NULL DEFAULT '' COMMENT '关联账户流水号(清算科目为空)',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_journal_no` (`journal_no`),
+  KEY `idx_account` (`account_type`, `admin_id`),
+  KEY `idx_transaction_no` (`transaction_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='凭证分录表';
+
+-- ----------------------------
+-- 23. 账户对账批次表（每日按流水重算账户余额）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_reconciliation_runs`;
+CREATE TABLE `account_reconciliation_runs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `run_no` varchar(64) NOT NULL COMMENT '对账批次号',
+  `checked_accounts` bigint NOT NULL DEFAULT 0 COMMENT '已核对账户数',
+  `drift_accounts` bigint NOT NULL DEFAULT 0 COMMENT '存在差异账户数',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=执行中 1=已完成 2=失败',
+  `error_message` varchar(500) NOT NULL DEFAULT '' COMMENT '失败原因',
+  `started_at` datetime NOT NULL COMMENT '开始时间',
+  `finished_at` datetime DEFAULT NULL COMMENT '完成时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_run_no` (`run_no`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='账户对账批次表';
+
+-- ----------------------------
+-- 24. 账户对账差异表（账户余额字段与流水重算结果不一致的账户）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_reconciliation_drifts`;
+CREATE TABLE `account_reconciliation_drifts` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `run_no` varchar(64) NOT NULL COMMENT '对账批次号',
+  `account_type` varchar(30) NOT NULL COMMENT '账户类型: prepayment/operator/shop_owner_commission/platform_commission',
+  `admin_id` bigint NOT NULL COMMENT '账户所属用户ID(平台为0)',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '账户币种',
+  `balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '账户可用余额',
+  `expected_balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '流水重算可用余额',
+  `pending_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '账户待结算金额',
+  `expected_pending` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '流水重算待结算金额',
+  `total_in` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '账户累计入账(累计充值/累计收益)',
+  `expected_total_in` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '流水重算累计入账',
+  `total_out` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '账户累计出账(累计消费/累计提现)',
+  `expected_total_out` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '流水重算累计出账',
+  `drift_fields` varchar(200) NOT NULL DEFAULT '' COMMENT '差异字段(逗号分隔)',
+  `tx_count` bigint NOT NULL DEFAULT 0 COMMENT '参与重算的流水笔数',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_run_no` (`run_no`),
+  KEY `idx_account` (`account_type`, `admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='账户对账差异表';
+
+-- ----------------------------
+-- 25. 汇率表（同一币种对按 effective_from 取业务发生时刻生效的最新一条）
+-- ----------------------------
+DROP TABLE IF EXISTS `fx_rates`;
+CREATE TABLE `fx_rates` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `base_currency` varchar(10) NOT NULL COMMENT '基准币种',
+  `quote_currency` varchar(10) NOT NULL COMMENT '报价币种',
+  `rate` decimal(18,8) NOT NULL COMMENT '汇率(1单位基准币种可兑换的报价币种数量)',
+  `effective_from` datetime NOT NULL COMMENT '生效时间',
+  `source` varchar(30) NOT NULL DEFAULT 'manual' COMMENT '来源(manual/bank等)',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '录入人ID',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_pair_effective` (`base_currency`, `quote_currency`, `effective_from`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='汇率表';
+
+-- ----------------------------
+-- 26. 月结账单期间表（每个自然月一条，签核后账单不可重新生成）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_statement_periods`;
+CREATE TABLE `account_statement_periods` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `period` varchar(7) NOT NULL COMMENT '账期(YYYY-MM)',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=生成中 1=已生成 2=已签核 3=失败',
+  `statement_count` bigint NOT NULL DEFAULT 0 COMMENT '账单数',
+  `digest` varchar(64) NOT NULL DEFAULT '' COMMENT '全部账单校验和的汇总摘要(SHA-256)',
+  `error_message` varchar(500) NOT NULL DEFAULT '' COMMENT '失败原因',
+  `generated_at` datetime DEFAULT NULL COMMENT '生成完成时间',
+  `signed_by` bigint NOT NULL DEFAULT 0 COMMENT '签核人ID',
+  `signed_at` datetime DEFAULT NULL COMMENT '签核时间',
+  `sign_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '签核备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_period` (`period`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='月结账单期间表';
+
+-- ----------------------------
+-- 27. 月结账单表（账户+币种+账期一条，生成后只读，checksum 用于证明账单未被修改）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_statements`;
+CREATE TABLE `account_statements` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `statement_no` varchar(64) NOT NULL COMMENT '账单号',
+  `period` varchar(7) NOT NULL COMMENT '账期(YYYY-MM)',
+  `account_type` varchar(30) NOT NULL COMMENT '账户类型: prepayment/deposit/operator/shop_owner_commission/platform_commission',
+  `admin_id` bigint NOT NULL COMMENT '账户所属用户ID(平台为0)',
+  `currency` varchar(10) NOT NULL COMMENT '货币代码',
+  `period_start` datetime NOT NULL COMMENT '账期开始时间(含)',
+  `period_end` datetime NOT NULL COMMENT '账期结束时间(不含)',
+  `opening_balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '期初余额',
+  `total_in` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '本期入账合计',
+  `total_out` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '本期出账合计(正数)',
+  `closing_balance` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '期末余额',
+  `tx_count` bigint NOT NULL DEFAULT 0 COMMENT '本期流水笔数',
+  `checksum` varchar(64) NOT NULL COMMENT '账单内容校验和(SHA-256)',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_statement_no` (`statement_no`),
+  UNIQUE KEY `uk_period_account` (`period`, `account_type`, `admin_id`, `currency`),
+  KEY `idx_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='月结账单表';
+
+-- ----------------------------
+-- 28. 月结账单明细表（按交易类型汇总本期流水）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_statement_lines`;
+CREATE TABLE `account_statement_lines` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `statement_no` varchar(64) NOT NULL COMMENT '账单号',
+  `transaction_type` varchar(30) NOT NULL COMMENT '交易类型',
+  `amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '本期金额合计(正=入账,负=出账)',
+  `tx_count` bigint NOT NULL DEFAULT 0 COMMENT '本期流水笔数',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_statement_no` (`statement_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='月结账单明细表';
+
+-- ----------------------------
+-- 29. 流水冲正申请表（发起人与审批人必须不同，审批通过后生成 reversal 流水）
+-- ----------------------------
+DROP TABLE IF EXISTS `transaction_reversals`;
+CREATE TABLE `transaction_reversals` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `application_no` varchar(64) NOT NULL COMMENT '申请单号',
+  `transaction_no` varchar(64) NOT NULL COMMENT '被冲正的流水号',
+  `transaction_id` bigint unsigned NOT NULL COMMENT '被冲正的流水ID',
+  `account_type` varchar(30) NOT NULL COMMENT '账户类型',
+  `admin_id` bigint NOT NULL COMMENT '账户所属用户ID',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
+  `transaction_type` varchar(30) NOT NULL COMMENT '被冲正流水的交易类型',
+  `amount` decimal(15,2) NOT NULL COMMENT '冲正金额(与原流水金额相反)',
+  `reason` varchar(500) NOT NULL COMMENT '冲正原因',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核 1=已通过 2=已拒绝',
+  `requested_by` bigint NOT NULL COMMENT '发起人ID',
+  `audit_by` bigint NOT NULL DEFAULT 0 COMMENT '审核人ID',
+  `audit_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '审核备注',
+  `audit_at` datetime DEFAULT NULL COMMENT '审核时间',
+  `reversal_transaction_no` varchar(64) NOT NULL DEFAULT '' COMMENT '冲正流水号(审批通过后生成)',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_application_no` (`application_no`),
+  KEY `idx_transaction_no` (`transaction_no`),
+  KEY `idx_admin_id` (`admin_id`),
+  KEY `idx_status` (`status`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='流水冲正申请表';
+
+-- ----------------------------
+-- 30. 风控暂扣表（争议期间暂扣运营/店主佣金账户的部分金额；退货关闭/取消或到期时自动释放）
+-- ----------------------------
+DROP TABLE IF EXISTS `account_holds`;
+CREATE TABLE `account_holds` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `hold_no` varchar(64) NOT NULL COMMENT '暂扣单号',
+  `account_type` varchar(30) NOT NULL COMMENT '账户类型: operator/shop_owner_commission',
+  `admin_id` bigint NOT NULL COMMENT '账户所属用户ID',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
+  `amount` decimal(15,2) NOT NULL COMMENT '暂扣金额',
+  `shop_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '店铺ID',
+  `order_sn` varchar(64) NOT NULL DEFAULT '' COMMENT '关联订单号',
+  `return_sn` varchar(64) NOT NULL DEFAULT '' COMMENT '关联退货单号',
+  `reason` varchar(500) NOT NULL COMMENT '暂扣原因',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=生效中 1=已释放 2=已过期',
+  `expires_at` datetime DEFAULT NULL COMMENT '到期时间(为空则不自动过期)',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '创建人ID(0为系统)',
+  `released_by` bigint NOT NULL DEFAULT 0 COMMENT '释放人ID(0为系统)',
+  `release_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '释放说明',
+  `released_at` datetime DEFAULT NULL COMMENT '释放/过期时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_hold_no` (`hold_no`),
+  KEY `idx_account` (`account_type`, `admin_id`, `currency`),
+  KEY `idx_order_sn` (`order_sn`),
+  KEY `idx_return_sn` (`return_sn`),
+  KEY `idx_status_expires` (`status`, `expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='风控暂扣表';
+
+-- ----------------------------
+-- 31. 保证金退还申请表（店主申请退还超出应缴金额的保证金，平台审核通过后出账）
+-- ----------------------------
+DROP TABLE IF EXISTS `deposit_refund_applications`;
+CREATE TABLE `deposit_refund_applications` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `application_no` varchar(64) NOT NULL COMMENT '申请单号',
+  `admin_id` bigint NOT NULL COMMENT '店铺老板ID',
+  `amount` decimal(15,2) NOT NULL COMMENT '退还金额',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
+  `collection_account_id` bigint unsigned NOT NULL COMMENT '收款账户ID',
+  `reason` varchar(500) NOT NULL DEFAULT '' COMMENT '申请原因',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核 1=已退还 2=已拒绝',
+  `audit_by` bigint NOT NULL DEFAULT 0 COMMENT '审核人ID',
+  `audit_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '审核备注',
+  `audit_at` datetime DEFAULT NULL COMMENT '审核时间',
+  `transaction_no` varchar(64) NOT NULL DEFAULT '' COMMENT '退还流水号',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_application_no` (`application_no`),
+  KEY `idx_admin_id` (`admin_id`),
+  KEY `idx_status` (`status`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='保证金退还申请表';
+
+-- ----------------------------
+-- 32. 罚补单表（对运营/店主的罚款或补贴，审核通过后从其回款中扣除/随回款发放）
+-- ----------------------------
+DROP TABLE IF EXISTS `penalty_bonus_entries`;
+CREATE TABLE `penalty_bonus_entries` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `entry_no` varchar(64) NOT NULL COMMENT '罚补单号',
+  `dedup_key` varchar(128) NOT NULL COMMENT '去重键(手工单=单号，超时发货提案=late_ship:店铺ID:订单号)',
+  `entry_type` varchar(20) NOT NULL COMMENT '类型: penalty=罚款 subsidy=补贴',
+  `account_type` varchar(30) NOT NULL COMMENT '结算账户类型: operator/shop_owner_commission',
+  `admin_id` bigint NOT NULL COMMENT '对象用户ID',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '货币代码',
+  `amount` decimal(15,2) NOT NULL COMMENT '金额(恒为正)',
+  `reason_code` varchar(30) NOT NULL COMMENT '原因代码: late_ship/fake_ship/quality/service/compensate/incentive/other',
+  `shop_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '店铺ID',
+  `order_sn` varchar(64) NOT NULL DEFAULT '' COMMENT '关联订单号',
+  `evidence` varchar(1000) NOT NULL DEFAULT '' COMMENT '证据(说明/图片链接)',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注',
+  `source` varchar(20) NOT NULL DEFAULT 'manual' COMMENT '来源: manual=手工 auto=系统',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待审核 1=待结算 2=已拒绝 3=已结算 4=已冲正',
+  `requested_by` bigint NOT NULL DEFAULT 0 COMMENT '发起人ID(0为系统)',
+  `audit_by` bigint NOT NULL DEFAULT 0 COMMENT '审核人ID',
+  `audit_remark` varchar(500) NOT NULL DEFAULT '' COMMENT '审核备注',
+  `audit_at` datetime DEFAULT NULL COMMENT '审核时间',
+  `transaction_no` varchar(64) NOT NULL DEFAULT '' COMMENT '结算流水号',
+  `settled_at` datetime DEFAULT NULL COMMENT '结算时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_entry_no` (`entry_no`),
+  UNIQUE KEY `uk_dedup_key` (`dedup_key`),
+  KEY `idx_entry_type` (`entry_type`),
+  KEY `idx_account` (`account_type`, `admin_id`),
+  KEY `idx_reason_code` (`reason_code`),
+  KEY `idx_order_sn` (`order_sn`),
+  KEY `idx_status` (`status`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='罚补单表';
+
+-- ----------------------------
+-- 33. 利润分成规则表（挂在合作关系上：利润分档、平台保底/封顶/固定费用、亏损承担方）
+-- ----------------------------
+DROP TABLE IF EXISTS `profit_share_rules`;
+CREATE TABLE `profit_share_rules` (
+  `id` bigint unsigned NOT NULL COMMENT '主键ID(Redis分布式ID)',
+  `relation_id` bigint unsigned NOT NULL COMMENT '合作关系ID(shop_operator_relations.id)',
+  `shop_id` bigint unsigned NOT NULL COMMENT 'Shopee店铺ID',
+  `operator_id` bigint NOT NULL COMMENT '运营老板ID',
+  `name` varchar(100) NOT NULL DEFAULT '' COMMENT '规则名称',
+  `tiers` text COMMENT '利润分档JSON[{up_to,platform_share_rate,operator_share_rate,shop_owner_share_rate}]，为空按分成配置比例',
+  `min_platform_fee` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '平台每单最低分成',
+  `max_platform_fee` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '平台每单最高分成(0为不封顶)',
+  `fixed_platform_fee` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '平台每单固定费用',
+  `loss_policy` varchar(32) NOT NULL DEFAULT 'proportional' COMMENT '亏损承担方: proportional/operator/shop_owner/platform/operator_shop_owner',
+  `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=生效 2=已停用',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '创建人ID',
+  `disabled_at` datetime DEFAULT NULL COMMENT '停用时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_relation_id` (`relation_id`),
+  KEY `idx_shop_operator` (`shop_id`, `operator_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='利润分成规则表';
+
+-- ----------------------------
+-- 34. 结算批次表（每次虾皮结算/调账处理一条，可续跑/重试失败明细）
+-- ----------------------------
+DROP TABLE IF EXISTS `settlement_runs`;
+CREATE TABLE `settlement_runs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `run_no` varchar(64) NOT NULL COMMENT '批次号',
+  `run_type` varchar(20) NOT NULL COMMENT '批次类型: settlement=结算 adjustment=调账',
+  `trigger` varchar(20) NOT NULL DEFAULT 'cron' COMMENT '触发方式: cron=定时任务 manual=手动',
+  `total_count` bigint NOT NULL DEFAULT 0 COMMENT '候选订单数',
+  `pending_count` bigint NOT NULL DEFAULT 0 COMMENT '待处理数',
+  `success_count` bigint NOT NULL DEFAULT 0 COMMENT '成功数',
+  `skipped_count` bigint NOT NULL DEFAULT 0 COMMENT '跳过数',
+  `failed_count` bigint NOT NULL DEFAULT 0 COMMENT '失败数',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=执行中 1=已完成 2=已中断',
+  `error_message` varchar(500) NOT NULL DEFAULT '' COMMENT '中断原因',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '触发人ID(0为系统)',
+  `started_at` datetime NOT NULL COMMENT '开始时间',
+  `finished_at` datetime DEFAULT NULL COMMENT '完成时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_run_no` (`run_no`),
+  KEY `idx_run_type` (`run_type`),
+  KEY `idx_status` (`status`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='结算批次表';
+
+-- ----------------------------
+-- 35. 结算批次明细表（批次的每个候选订单/调账一条，记录成功/跳过/失败及原因）
+-- ----------------------------
+DROP TABLE IF EXISTS `settlement_run_items`;
+CREATE TABLE `settlement_run_items` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `run_id` bigint unsigned NOT NULL COMMENT '批次ID',
+  `shop_id` bigint unsigned NOT NULL COMMENT 'Shopee店铺ID',
+  `order_sn` varchar(64) NOT NULL COMMENT '订单编号',
+  `finance_income_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '财务收入ID(调账批次)',
+  `transaction_id` bigint NOT NULL DEFAULT 0 COMMENT '虾皮交易ID(调账批次)',
+  `transaction_type` varchar(64) NOT NULL DEFAULT '' COMMENT '交易类型(调账批次)',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '结果: 0=待处理 1=成功 2=跳过 3=失败',
+  `reason` varchar(500) NOT NULL DEFAULT '' COMMENT '跳过/失败原因',
+  `settlement_no` varchar(64) NOT NULL DEFAULT '' COMMENT '结算单号',
+  `attempts` int NOT NULL DEFAULT 0 COMMENT '处理次数',
+  `processed_at` datetime DEFAULT NULL COMMENT '最近处理时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_run_status` (`run_id`, `status`),
+  KEY `idx_order_sn` (`order_sn`),
+  KEY `idx_processed_at` (`processed_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='结算批次明细表';
+
+-- ----------------------------
+-- 36. 订单对账批次表（每日比对虾皮结算明细、钱包入账与平台结算单）
+-- ----------------------------
+DROP TABLE IF EXISTS `order_reconciliation_runs`;
+CREATE TABLE `order_reconciliation_runs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `run_no` varchar(64) NOT NULL COMMENT '对账批次号',
+  `window_start` datetime NOT NULL COMMENT '对账窗口开始时间',
+  `window_end` datetime NOT NULL COMMENT '对账窗口结束时间',
+  `checked_orders` bigint NOT NULL DEFAULT 0 COMMENT '已核对订单数',
+  `mismatch_orders` bigint NOT NULL DEFAULT 0 COMMENT '存在差异订单数',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=执行中 1=已完成 2=失败',
+  `error_message` varchar(500) NOT NULL DEFAULT '' COMMENT '失败原因',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '触发人ID(0为系统)',
+  `started_at` datetime NOT NULL COMMENT '开始时间',
+  `finished_at` datetime DEFAULT NULL COMMENT '完成时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_run_no` (`run_no`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='订单对账批次表';
+
+-- ----------------------------
+-- 37. 订单对账差异表（金额均为订单币种，可一键发起调账或忽略）
+-- ----------------------------
+DROP TABLE IF EXISTS `order_reconciliation_mismatches`;
+CREATE TABLE `order_reconciliation_mismatches` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `run_no` varchar(64) NOT NULL COMMENT '对账批次号',
+  `shop_id` bigint unsigned NOT NULL COMMENT 'Shopee店铺ID',
+  `order_sn` varchar(64) NOT NULL COMMENT '订单编号',
+  `mismatch_type` varchar(30) NOT NULL COMMENT '差异类型: amount_diff/missing_escrow/missing_income/settled_without_income',
+  `currency` varchar(10) NOT NULL DEFAULT '' COMMENT '订单币种',
+  `has_escrow` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否有虾皮结算明细',
+  `escrow_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '虾皮结算明细金额',
+  `has_income` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否有钱包入账',
+  `income_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '钱包入账金额(ESCROW_VERIFIED_ADD)',
+  `settlement_no` varchar(64) NOT NULL DEFAULT '' COMMENT '平台结算单号(空为未结算)',
+  `settled_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '平台结算金额(含对账调账)',
+  `diff_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '钱包入账-平台结算金额',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '处理状态: 0=待处理 1=已发起调账 2=已忽略',
+  `adjustment_tx_id` bigint NOT NULL DEFAULT 0 COMMENT '发起调账的调账明细交易ID(差异ID取负)',
+  `handled_by` bigint NOT NULL DEFAULT 0 COMMENT '处理人ID',
+  `handled_at` datetime DEFAULT NULL COMMENT '处理时间',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '处理备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_run_no` (`run_no`),
+  KEY `idx_shop_order` (`shop_id`, `order_sn`),
+  KEY `idx_mismatch_type` (`mismatch_type`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='订单对账差异表';
+
+-- ----------------------------
+-- 38. 结算发票表（运营/店主 + 周期 + 币种一张，作废后可重新开具）
+-- ----------------------------
+DROP TABLE IF EXISTS `settlement_invoices`;
+CREATE TABLE `settlement_invoices` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `invoice_no` varchar(64) NOT NULL COMMENT '发票号(INV-O/S-周期-序号)',
+  `party_type` varchar(20) NOT NULL COMMENT '开票对象类型: operator/shop_owner',
+  `admin_id` bigint NOT NULL COMMENT '开票对象用户ID',
+  `period_type` varchar(10) NOT NULL COMMENT '周期类型: weekly/monthly',
+  `period` varchar(10) NOT NULL COMMENT '周期(YYYY-MM 或 YYYY-Www)',
+  `period_start` datetime NOT NULL COMMENT '周期开始时间(含)',
+  `period_end` datetime NOT NULL COMMENT '周期结束时间(不含)',
+  `currency` varchar(10) NOT NULL COMMENT '结算币种',
+  `order_count` bigint NOT NULL DEFAULT 0 COMMENT '结算订单数',
+  `adjustment_count` bigint NOT NULL DEFAULT 0 COMMENT '调账笔数',
+  `escrow_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT 'Shopee结算金额合计',
+  `cost_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '成本合计(仅运营)',
+  `share_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '分成合计(含调账)',
+  `total_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '发票金额(运营=成本+运营分成，店主=店主分成)',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=已开具 1=已付款 2=已作废',
+  `issued_at` datetime NOT NULL COMMENT '开具时间',
+  `paid_at` datetime DEFAULT NULL COMMENT '付款时间',
+  `payment_ref` varchar(100) NOT NULL DEFAULT '' COMMENT '付款凭证号',
+  `voided_by` bigint NOT NULL DEFAULT 0 COMMENT '作废人ID',
+  `voided_at` datetime DEFAULT NULL COMMENT '作废时间',
+  `void_reason` varchar(500) NOT NULL DEFAULT '' COMMENT '作废原因',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '开具人ID(0为系统)',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_invoice_no` (`invoice_no`),
+  KEY `idx_party` (`party_type`, `admin_id`),
+  KEY `idx_period` (`period_type`, `period`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='结算发票表';
+
+-- ----------------------------
+-- 39. 结算发票明细表（结算单或调账明细一条）
+-- ----------------------------
+DROP TABLE IF EXISTS `settlement_invoice_lines`;
+CREATE TABLE `settlement_invoice_lines` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `invoice_no` varchar(64) NOT NULL COMMENT '发票号',
+  `line_type` varchar(20) NOT NULL COMMENT '明细类型: settlement/adjustment',
+  `shop_id` bigint unsigned NOT NULL COMMENT 'Shopee店铺ID',
+  `order_sn` varchar(64) NOT NULL COMMENT '订单编号',
+  `settlement_no` varchar(64) NOT NULL DEFAULT '' COMMENT '结算单号',
+  `transaction_type` varchar(64) NOT NULL DEFAULT '' COMMENT '调账类型',
+  `escrow_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT 'Shopee结算金额(调账为调账金额)',
+  `cost_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '成本(仅运营结算单)',
+  `share_rate` decimal(5,2) NOT NULL DEFAULT 0.00 COMMENT '分成比例%',
+  `share_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '分成金额',
+  `amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '明细金额',
+  `occurred_at` datetime NOT NULL COMMENT '结算/调账时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_invoice_no` (`invoice_no`),
+  KEY `idx_order_sn` (`order_sn`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='结算发票明细表';
+
+-- ----------------------------
+-- 40. 领域事件发件箱表（与业务数据同一事务写入，由 cron 事件投递器分发）
+-- ----------------------------
+DROP TABLE IF EXISTS `outbox_events`;
+CREATE TABLE `outbox_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `event_id` varchar(64) NOT NULL COMMENT '事件ID(随事件下发，供订阅方去重)',
+  `event_type` varchar(50) NOT NULL COMMENT '事件类型: order.settled/order.refunded/withdraw.approved/withdraw.paid/prepayment.insufficient',
+  `aggregate_id` varchar(64) NOT NULL DEFAULT '' COMMENT '业务单号(订单号/提现申请号等)',
+  `admin_ids` varchar(200) NOT NULL DEFAULT '' COMMENT '事件相关用户ID(逗号分隔，平台订阅接收全部事件)',
+  `payload` text NOT NULL COMMENT '事件报文(JSON)',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待分发 1=已分发',
+  `dispatched_at` datetime DEFAULT NULL COMMENT '分发时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_event_id` (`event_id`),
+  KEY `idx_event_type` (`event_type`),
+  KEY `idx_status_id` (`status`, `id`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='领域事件发件箱表';
+
+-- ----------------------------
+-- 41. 事件订阅表（店主/运营/平台登记的回调地址）
+-- ----------------------------
+DROP TABLE IF EXISTS `webhook_subscriptions`;
+CREATE TABLE `webhook_subscriptions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `admin_id` bigint NOT NULL COMMENT '订阅用户ID',
+  `user_type` tinyint NOT NULL DEFAULT 1 COMMENT '订阅用户类型: 1=店主 5=运营 9=平台(接收全部事件)',
+  `url` varchar(500) NOT NULL COMMENT '回调地址',
+  `secret` varchar(128) NOT NULL COMMENT '签名密钥(HMAC-SHA256)',
+  `event_types` varchar(500) NOT NULL DEFAULT '*' COMMENT '订阅的事件类型(逗号分隔，*为全部)',
+  `status` tinyint NOT NULL DEFAULT 1 COMMENT '状态: 1=启用 0=停用',
+  `description` varchar(200) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='事件订阅表';
+
+-- ----------------------------
+-- 42. 事件投递记录表（事件 × 订阅一条，失败按指数退避重试，超过10次进入死信）
+-- ----------------------------
+DROP TABLE IF EXISTS `webhook_deliveries`;
+CREATE TABLE `webhook_deliveries` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `subscription_id` bigint unsigned NOT NULL COMMENT '订阅ID',
+  `admin_id` bigint NOT NULL COMMENT '订阅用户ID',
+  `event_id` varchar(64) NOT NULL COMMENT '事件ID',
+  `event_type` varchar(50) NOT NULL COMMENT '事件类型',
+  `url` varchar(500) NOT NULL COMMENT '回调地址(分发时快照)',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待投递(含等待重试) 1=已送达 2=死信',
+  `attempts` int NOT NULL DEFAULT 0 COMMENT '已尝试次数',
+  `next_attempt_at` datetime NOT NULL COMMENT '下次投递时间',
+  `last_status_code` int NOT NULL DEFAULT 0 COMMENT '最近一次响应状态码',
+  `last_error` varchar(500) NOT NULL DEFAULT '' COMMENT '最近一次失败原因',
+  `delivered_at` datetime DEFAULT NULL COMMENT '送达时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_subscription_event` (`subscription_id`, `event_id`),
+  KEY `idx_admin_id` (`admin_id`),
+  KEY `idx_status_next` (`status`, `next_attempt_at`),
+  KEY `idx_event_id` (`event_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='事件投递记录表';
+
+-- ----------------------------
+-- 43. 提现代付批次表（同一代付格式 + 到账币种的已审核提现锁定为一批，生成银行代付文件）
+-- ----------------------------
+DROP TABLE IF EXISTS `payout_batches`;
+CREATE TABLE `payout_batches` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `batch_no` varchar(64) NOT NULL COMMENT '批次号(PB+时间)',
+  `layout` varchar(50) NOT NULL COMMENT '代付文件格式名(配置 payout.layouts)',
+  `currency` varchar(10) NOT NULL COMMENT '到账币种',
+  `item_count` int NOT NULL DEFAULT 0 COMMENT '明细笔数',
+  `total_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '代付总金额',
+  `paid_count` int NOT NULL DEFAULT 0 COMMENT '已打款笔数',
+  `paid_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '已打款金额',
+  `failed_count` int NOT NULL DEFAULT 0 COMMENT '打款失败笔数',
+  `failed_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '打款失败金额',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待回单 1=已完成 2=已取消',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '备注(代付文件 remark 字段)',
+  `created_by` bigint NOT NULL DEFAULT 0 COMMENT '创建人ID',
+  `result_imported_by` bigint NOT NULL DEFAULT 0 COMMENT '最近导入回单人ID',
+  `result_imported_at` datetime DEFAULT NULL COMMENT '最近导入回单时间',
+  `completed_at` datetime DEFAULT NULL COMMENT '完成/取消时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_batch_no` (`batch_no`),
+  KEY `idx_status` (`status`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提现代付批次表';
+
+-- ----------------------------
+-- 44. 提现代付批次明细表（一笔提现申请一条，收款账户信息在入批次时快照）
+-- ----------------------------
+DROP TABLE IF EXISTS `payout_batch_items`;
+CREATE TABLE `payout_batch_items` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `batch_no` varchar(64) NOT NULL COMMENT '批次号',
+  `line_no` int NOT NULL COMMENT '行号',
+  `application_id` bigint unsigned NOT NULL COMMENT '提现申请ID',
+  `application_no` varchar(64) NOT NULL COMMENT '提现申请单号(代付参考号，回单按此匹配)',
+  `admin_id` bigint NOT NULL COMMENT '申请人ID',
+  `account_type` varchar(30) NOT NULL COMMENT '提现账户类型',
+  `collection_account_id` bigint unsigned NOT NULL COMMENT '收款账户ID',
+  `bank_name` varchar(100) NOT NULL DEFAULT '' COMMENT '银行名称',
+  `bank_branch` varchar(200) NOT NULL DEFAULT '' COMMENT '银行支行',
+  `account_no` varchar(512) NOT NULL COMMENT '账户号码(加密存储)',
+  `account_name` varchar(100) NOT NULL DEFAULT '' COMMENT '账户名称',
+  `payee` varchar(512) NOT NULL COMMENT '收款人姓名(加密存储)',
+  `amount` decimal(15,2) NOT NULL COMMENT '代付金额(到账币种)',
+  `currency` varchar(10) NOT NULL COMMENT '到账币种',
+  `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态: 0=待回单 1=已打款 2=打款失败(暂扣金额已退回) 3=已取消',
+  `bank_ref` varchar(100) NOT NULL DEFAULT '' COMMENT '银行流水号',
+  `fail_reason` varchar(500) NOT NULL DEFAULT '' COMMENT '失败原因',
+  `processed_at` datetime DEFAULT NULL COMMENT '回单处理时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_batch_line` (`batch_no`, `line_no`),
+  KEY `idx_application_id` (`application_id`),
+  KEY `idx_application_no` (`application_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提现代付批次明细表';
+
+-- ----------------------------
+-- 45. 自动提现设置表（按周/月定时将可提现余额扫入指定收款账户，每个用户每个账户类型每个币种一条）
+-- ----------------------------
+DROP TABLE IF EXISTS `auto_withdraw_settings`;
+CREATE TABLE `auto_withdraw_settings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `admin_id` bigint NOT NULL COMMENT '用户ID',
+  `account_type` varchar(30) NOT NULL COMMENT '提现账户类型: operator/shop_owner_commission',
+  `currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '提现币种',
+  `payout_currency` varchar(10) NOT NULL DEFAULT 'TWD' COMMENT '到账币种',
+  `collection_account_id` bigint unsigned NOT NULL COMMENT '收款账户ID',
+  `frequency` varchar(10) NOT NULL COMMENT '频率: weekly/monthly',
+  `day_of_week` int NOT NULL DEFAULT 1 COMMENT '每周几执行(0周日~6周六)',
+  `day_of_month` int NOT NULL DEFAULT 1 COMMENT '每月几号执行(1~28)',
+  `threshold` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '触发金额(可提取金额达到该值才提现)',
+  `keep_minimum` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '账户保留金额(可提取金额 = 可提现余额 - 保留金额)',
+  `enabled` tinyint(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+  `next_run_at` datetime NOT NULL COMMENT '下次执行时间(计划日 9:00)',
+  `last_run_at` datetime DEFAULT NULL COMMENT '最近执行时间',
+  `last_result` varchar(20) NOT NULL DEFAULT '' COMMENT '最近执行结果: created/skipped/failed',
+  `last_reason` varchar(500) NOT NULL DEFAULT '' COMMENT '最近跳过/失败原因',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_admin_account_currency` (`admin_id`, `account_type`, `currency`),
+  KEY `idx_enabled` (`enabled`),
+  KEY `idx_next_run_at` (`next_run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='自动提现设置表';
+
+-- ----------------------------
+-- 46. 自动提现执行记录表（每个设置每个计划时间一条，记录创建的提现申请或跳过原因）
+-- ----------------------------
+DROP TABLE IF EXISTS `auto_withdraw_runs`;
+CREATE TABLE `auto_withdraw_runs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `setting_id` bigint unsigned NOT NULL COMMENT '自动提现设置ID',
+  `scheduled_at` datetime NOT NULL COMMENT '计划执行时间',
+  `admin_id` bigint NOT NULL COMMENT '用户ID',
+  `account_type` varchar(30) NOT NULL COMMENT '提现账户类型',
+  `currency` varchar(10) NOT NULL COMMENT '提现币种',
+  `result` varchar(20) NOT NULL COMMENT '结果: created=已创建提现申请 skipped=不满足条件跳过 failed=申请失败',
+  `amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT '提现金额',
+  `application_no` varchar(64) NOT NULL DEFAULT '' COMMENT '提现申请单号',
+  `reason` varchar(500) NOT NULL DEFAULT '' COMMENT '跳过/失败原因(账户冻结、余额不足、存在风控暂扣、超过提现限额等)',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_setting_scheduled` (`setting_id`, `scheduled_at`),
+  KEY `idx_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='自动提现执行记录表';
+
+-- ----------------------------
+-- 47. 审批记录表（提现/线下充值/财务审核流水的多级审批轨迹，同一申请每个审批人只能审批一次）
+-- ----------------------------
+DROP TABLE IF EXISTS `approval_records`;
+CREATE TABLE `approval_records` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID(自增)',
+  `operation` varchar(30) NOT NULL COMMENT '操作类型: withdraw=提现申请 recharge=线下充值申请 finance_audit=财务审核流水',
+  `target_no` varchar(64) NOT NULL COMMENT '申请单号/流水号',
+  `approver_id` bigint NOT NULL COMMENT '审批人ID',
+  `admin_id` bigint NOT NULL COMMENT '申请人ID(不能与审批人相同)',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `amount` decimal(15,2) NOT NULL COMMENT '审批金额',
+  `action` varchar(10) NOT NULL COMMENT '审批动作: approve/reject',
+  `level` int NOT NULL COMMENT '第几级审批',
+  `required` int NOT NULL COMMENT '审批时需要的审批人数(按 approval.policies 配置计算)',
+  `remark` varchar(500) NOT NULL DEFAULT '' COMMENT '审批备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '审批时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_operation_target_approver` (`operation`, `target_no`, `approver_id`),
+  KEY `idx_approver_id` (`approver_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='审批记录表';
+
 
 -- ============================================================================
 -- 第二部分：分表（使用存储过程批量创建）
 -- ============================================================================
 -- 分表路由规则:
 --   按 shop_id % 10 分表的表: orders, order_items, order_addresses, order_escrows,
---     order_escrow_items, order_settlements, order_shipment_records, shipments,
---     finance_incomes, operation_logs, operation_logs_archive, returns (共12种×10=120张)
+--     order_escrow_items, order_settlements, order_settlement_adjustments, order_shipment_records,
+--     shipments, finance_incomes, operation_logs, operation_logs_archive, returns (共13种×10=130张)
 --   按 admin_id % 10 分表的表: account_transactions (1种×10=10张)
 
 
@@ -570,7 +1275,7 @@ BEGIN
           `escrow_fee_y` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''预留费用Y'',
           `escrow_fee_z` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''预留费用Z'',
           `prepayment_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''实际预付款扣除金额(=escrow_amount_snapshot)'',
-          `prepayment_status` tinyint NOT NULL DEFAULT 0 COMMENT ''预付款状态: 0=未检查 1=充足 2=不足'',
+          `prepayment_status` tinyint NOT NULL DEFAULT 0 COMMENT ''预付款状态: 0=未检查 1=充足 2=不足 3=保证金不足'',
           `prepayment_snapshot` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''检查时预付款总余额快照'',
           `prepayment_checked_at` datetime DEFAULT NULL COMMENT ''预付款检查时间'',
           PRIMARY KEY (`id`),
@@ -838,8 +1543,12 @@ BEGIN
           `order_id` bigint unsigned NOT NULL COMMENT ''订单ID'',
           `shop_owner_id` bigint NOT NULL COMMENT ''店铺老板ID'',
           `operator_id` bigint NOT NULL COMMENT ''运营老板ID'',
-          `currency` varchar(10) NOT NULL DEFAULT ''TWD'' COMMENT ''货币代码'',
-          `escrow_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''Shopee结算金额'',
+          `currency` varchar(10) NOT NULL DEFAULT ''TWD'' COMMENT ''结算币种(预付款冻结子账户币种)'',
+          `order_currency` varchar(10) NOT NULL DEFAULT ''TWD'' COMMENT ''订单币种'',
+          `order_escrow_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''Shopee结算金额(订单币种)'',
+          `fx_rate` decimal(18,8) NOT NULL DEFAULT 1.00000000 COMMENT ''订单币种→结算币种汇率'',
+          `fx_rate_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT ''使用的汇率记录ID(同币种为0)'',
+          `escrow_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''Shopee结算金额(结算币种)'',
           `goods_cost` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''商品成本'',
           `shipping_cost` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''运费成本'',
           `total_cost` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''总成本'',
@@ -847,6 +1556,9 @@ BEGIN
           `platform_share_rate` decimal(5,2) NOT NULL DEFAULT 0.00 COMMENT ''平台分成比例(%)'',
           `operator_share_rate` decimal(5,2) NOT NULL DEFAULT 0.00 COMMENT ''运营分成比例(%)'',
           `shop_owner_share_rate` decimal(5,2) NOT NULL DEFAULT 0.00 COMMENT ''店主分成比例(%)'',
+          `profit_share_config_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT ''使用的分成配置版本ID(0为默认比例)'',
+          `profit_share_rule_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT ''使用的分成规则ID(0为按比例直接分成)'',
+          `profit_share_rule_snapshot` text COMMENT ''分成规则快照(JSON)'',
           `platform_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''平台分成金额'',
           `operator_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''运营分成金额'',
           `shop_owner_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''店主分成金额'',
@@ -854,25 +1566,7 @@ BEGIN
           `status` tinyint NOT NULL DEFAULT 0 COMMENT ''状态: 0=待结算 1=已结算 2=已取消'',
           `settled_at` datetime DEFAULT NULL COMMENT ''结算时间'',
           `remark` varchar(500) NOT NULL DEFAULT '''' COMMENT ''备注'',
-          `adjustment_count` tinyint NOT NULL DEFAULT 0 COMMENT ''已发生调账次数(0~3)'',
-          `adj1_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第1次调账金额(正补款/负扣款)'',
-          `adj1_platform_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第1次调账-平台分成'',
-          `adj1_operator_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第1次调账-运营分成'',
-          `adj1_shop_owner_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第1次调账-店主分成'',
-          `adj1_at` datetime DEFAULT NULL COMMENT ''第1次调账时间'',
-          `adj1_remark` varchar(200) NOT NULL DEFAULT '''' COMMENT ''第1次调账备注'',
-          `adj2_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第2次调账金额'',
-          `adj2_platform_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第2次调账-平台分成'',
-          `adj2_operator_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第2次调账-运营分成'',
-          `adj2_shop_owner_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第2次调账-店主分成'',
-          `adj2_at` datetime DEFAULT NULL COMMENT ''第2次调账时间'',
-          `adj2_remark` varchar(200) NOT NULL DEFAULT '''' COMMENT ''第2次调账备注'',
-          `adj3_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第3次调账金额'',
-          `adj3_platform_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第3次调账-平台分成'',
-          `adj3_operator_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第3次调账-运营分成'',
-          `adj3_shop_owner_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''第3次调账-店主分成'',
-          `adj3_at` datetime DEFAULT NULL COMMENT ''第3次调账时间'',
-          `adj3_remark` varchar(200) NOT NULL DEFAULT '''' COMMENT ''第3次调账备注'',
+          `adjustment_count` int NOT NULL DEFAULT 0 COMMENT ''已发生调账次数(明细见order_settlement_adjustments)'',
           `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''创建时间'',
           `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT ''更新时间'',
           PRIMARY KEY (`id`),
@@ -895,6 +1589,53 @@ DELIMITER ;
 CALL create_order_settlements_shards();
 DROP PROCEDURE IF EXISTS create_order_settlements_shards;
 
+-- ----------------------------
+-- 6b. 结算调账明细表分表 (order_settlement_adjustments_0 ~ order_settlement_adjustments_9)
+-- 分表键: shop_id % 10（与 order_settlements 同分片，每笔虾皮调账一条，条数不限）
+-- ----------------------------
+DROP PROCEDURE IF EXISTS create_order_settlement_adjustments_shards;
+DELIMITER //
+CREATE PROCEDURE create_order_settlement_adjustments_shards()
+BEGIN
+    DECLARE i INT DEFAULT 0;
+    WHILE i < 10 DO
+        SET @drop_sql = CONCAT('DROP TABLE IF EXISTS `order_settlement_adjustments_', i, '`');
+        PREPARE drop_stmt FROM @drop_sql;
+        EXECUTE drop_stmt;
+        DEALLOCATE PREPARE drop_stmt;
+        
+        SET @create_sql = CONCAT('CREATE TABLE `order_settlement_adjustments_', i, '` (
+          `id` bigint unsigned NOT NULL COMMENT ''主键ID(Redis分布式ID)'',
+          `settlement_id` bigint unsigned NOT NULL COMMENT ''原结算记录ID'',
+          `shop_id` bigint unsigned NOT NULL COMMENT ''Shopee店铺ID'',
+          `order_sn` varchar(64) NOT NULL COMMENT ''订单编号'',
+          `transaction_id` bigint NOT NULL COMMENT ''虾皮交易ID(来源finance_incomes；对账发起的调账为负的差异ID)'',
+          `transaction_type` varchar(64) NOT NULL DEFAULT '''' COMMENT ''调账类型'',
+          `currency` varchar(10) NOT NULL DEFAULT ''TWD'' COMMENT ''结算币种'',
+          `order_amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''调账金额(订单币种)'',
+          `amount` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''调账金额(结算币种,正补款/负扣款)'',
+          `platform_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''平台分成'',
+          `operator_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''运营分成'',
+          `shop_owner_share` decimal(15,2) NOT NULL DEFAULT 0.00 COMMENT ''店主分成'',
+          `remark` varchar(200) NOT NULL DEFAULT '''' COMMENT ''备注'',
+          `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''创建时间'',
+          PRIMARY KEY (`id`),
+          UNIQUE KEY `uk_shop_transaction` (`shop_id`, `transaction_id`),
+          KEY `idx_settlement_id` (`settlement_id`),
+          KEY `idx_order_sn` (`order_sn`),
+          KEY `idx_created_at` (`created_at`)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT=''结算调账明细表分表', i, '''');
+        PREPARE create_stmt FROM @create_sql;
+        EXECUTE create_stmt;
+        DEALLOCATE PREPARE create_stmt;
+        
+        SET i = i + 1;
+    END WHILE;
+END //
+DELIMITER ;
+CALL create_order_settlement_adjustments_shards();
+DROP PROCEDURE IF EXISTS create_order_settlement_adjustments_shards;
+
 -- ----------------------------
 -- 7. 订单发货记录表分表 (order_shipment_records_0 ~ order_shipment_records_9)
 -- 分表键: shop_id % 10
@@ -1067,9 +1808,11 @@ BEGIN
         SET @create_sql = CONCAT('CREATE TABLE `account_transactions_', i, '` (
           `id` bigint unsigned NOT NULL COMMENT ''主键ID(Redis分布式ID)'',
           `transaction_no` varchar(64) NOT NULL COMMENT ''流水号(唯一)'',
+          `idempotency_key` varchar(128) NOT NULL COMMENT ''幂等键(调用方未指定时等于流水号)'',
           `account_type` varchar(20) NOT NULL COMMENT ''账户类型: prepayment/deposit/operator等'',
           `admin_id` bigint NOT NULL COMMENT ''账户所属用户ID'',
-          `transaction_type` varchar(30) NOT NULL COMMENT ''交易类型: recharge/consume/freeze/order_refund等'',
+          `currency` varchar(10) NOT NULL DEFAULT ''TWD'' COMMENT ''账户币种'',
+          `transaction_type` varchar(30) NOT NULL COMMENT ''交易类型: recharge/consume/freeze/order_refund/reversal等'',
           `amount` decimal(15,2) NOT NULL COMMENT ''金额(正=入账,负=出账)'',
           `balance_before` decimal(15,2) NOT NULL COMMENT ''交易前余额'',
           `balance_after` decimal(15,2) NOT NULL COMMENT ''交易后余额'',
@@ -1081,6 +1824,7 @@ BEGIN
           `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT ''创建时间'',
           PRIMARY KEY (`id`),
           UNIQUE KEY `uk_transaction_no` (`transaction_no`),
+          UNIQUE KEY `uk_idempotency_key` (`idempotency_key`),
           KEY `idx_account_type` (`account_type`),
           KEY `idx_admin_id` (`admin_id`),
           KEY `idx_transaction_type` (`transaction_type`),
@@ -1255,7 +1999,7 @@ SET FOREIGN_KEY_CHECKS = 1;
 -- 附录：表清单与分表规则说明
 -- ============================================================================
 --
--- 一、基础表（不分表）共 20 张:
+-- 一、基础表（不分表）共 47 张:
 --   序号  表名                             说明
 --   1     admin                            管理员/用户表
 --   2     shops                            店铺表
@@ -1270,7 +2014,7 @@ SET FOREIGN_KEY_CHECKS = 1;
 --   9     deposit_accounts                 保证金账户表
 --   10    operator_accounts                运营老板账户表
 --   11    shop_owner_commission_accounts   店主佣金账户表
---   12    platform_commission_accounts     平台佣金账户表(单例)
+--   12    platform_commission_accounts     平台佣金账户表(每币种一条)
 --   13    penalty_bonus_accounts           罚补账户表
 --   14    collection_accounts              收款账户表
 --   15    withdraw_applications            提现申请表
@@ -1279,16 +2023,44 @@ SET FOREIGN_KEY_CHECKS = 1;
 --   18    order_daily_stats                订单每日统计表
 --   19    finance_daily_stats              财务每日统计表
 --   20    platform_daily_stats             平台每日统计表
+--   21    account_journals                 记账凭证表
+--   22    account_journal_postings         凭证分录表
+--   23    account_reconciliation_runs      账户对账批次表
+--   24    account_reconciliation_drifts    账户对账差异表
+--   25    fx_rates                         汇率表
+--   26    account_statement_periods        月结账单期间表
+--   27    account_statements               月结账单表
+--   28    account_statement_lines          月结账单明细表
+--   29    transaction_reversals            流水冲正申请表
+--   30    account_holds                    风控暂扣表
+--   31    deposit_refund_applications      保证金退还申请表
+--   32    penalty_bonus_entries            罚补单表
+--   33    profit_share_rules               利润分成规则表
+--   34    settlement_runs                  结算批次表
+--   35    settlement_run_items             结算批次明细表
+--   36    order_reconciliation_runs        订单对账批次表
+--   37    order_reconciliation_mismatches  订单对账差异表
+--   38    settlement_invoices              结算发票表
+--   39    settlement_invoice_lines         结算发票明细表
+--   40    outbox_events                    领域事件发件箱表
+--   41    webhook_subscriptions            事件订阅表
+--   42    webhook_deliveries               事件投递记录表
+--   43    payout_batches                   提现代付批次表
+--   44    payout_batch_items               提现代付批次明细表
+--   45    auto_withdraw_settings           自动提现设置表
+--   46    auto_withdraw_runs               自动提现执行记录表
+--   47    approval_records                 审批记录表
 --
--- 二、分表（共 13 种基础表 × 10 个分片 = 130 张）:
+-- 二、分表（共 14 种基础表 × 10 个分片 = 140 张）:
 --
---   按 shop_id % 10 分表（12种 × 10 = 120张）:
+--   按 shop_id % 10 分表（13种 × 10 = 130张）:
 --   1     orders_0 ~ orders_9                             订单表
 --   2     order_items_0 ~ order_items_9                   订单商品表
 --   3     order_addresses_0 ~ order_addresses_9           订单地址表
 --   4     order_escrows_0 ~ order_escrows_9               订单结算表
 --   5     order_escrow_items_0 ~ order_escrow_items_9     订单结算商品表
 --   6     order_settlements_0 ~ order_settlements_9       订单结算记录表
+--   6b    order_settlement_adjustments_0 ~ order_settlement_adjustments_9  结算调账明细表
 --   7     order_shipment_records_0 ~ order_shipment_records_9  订单发货记录表
 --   8     shipments_0 ~ shipments_9                       发货记录表
 --   9     finance_incomes_0 ~ finance_incomes_9           财务收入表
@@ -1299,7 +2071,7 @@ SET FOREIGN_KEY_CHECKS = 1;
 --   按 admin_id % 10 分表（1种 × 10 = 10张）:
 --   13    account_transactions_0 ~ account_transactions_9  账户流水表
 --
--- 三、总计物理表数量: 20 + 130 = 150 张
+-- 三、总计物理表数量: 47 + 140 = 187 张
 --
 -- 四、分表路由示例:
 --   shop_id  = 12345 → 12345 % 10 = 5 → orders_5, order_items_5, ...
@@ -1308,7 +2080,19 @@ SET FOREIGN_KEY_CHECKS = 1;
 -- 五、定时维护任务:
 --   每天凌晨 2:00  归档90天前的操作日志到 operation_logs_archive_X
 --   每天凌晨 3:00  生成前一天的统计数据（order_daily_stats / finance_daily_stats / platform_daily_stats）
---   每月1号  4:00  清理365天前的归档数据
+--   每天凌晨 5:00  账户对账：按 account_transactions_X 重算余额，差异写入 account_reconciliation_drifts
+--   每天凌晨 5:15  线上充值核对：前一天处理过的 recharge_record 与支付网关结果比对，不一致写日志
+--   每天凌晨 5:30  订单三方对账：最近30天订单的虾皮结算明细/钱包入账/结算单比对，差异写入 order_reconciliation_mismatches
+--   每 5 秒        领域事件投递：outbox_events 按订阅展开为 webhook_deliveries 并签名回调，失败指数退避重试，10次后转死信
+--   每 5 分钟      轮询待支付的线上充值（recharge_record.payment_id），补漏支付回调入账，超时未支付的关闭
+--   每 10 分钟     虾皮结算/调账批次（settlement_runs / settlement_run_items 记录每个订单的结果与失败原因）
+--   每 10 分钟     释放到期的风控暂扣（account_holds），退货关闭/取消时由退货同步即时释放
+--   每 10 分钟     结算已审核的罚补单（penalty_bonus_entries），罚款在账户可提现余额足够时扣除
+--   每 10 分钟     执行到期的自动提现（auto_withdraw_settings），创建提现申请或记录跳过原因到 auto_withdraw_runs，并通知用户
+--   每小时 30 分   扫描超过最晚发货时间的订单，生成待审核的超时发货罚款提案
+--   每月1号  4:00  清理365天前的归档数据、90天前已完成的结算批次、90天前已送达的事件投递记录及事件
+--   每月1号  6:00  生成上月月结账单（account_statements / account_statement_lines）
+--   每周一   6:30  开具上周运营/店主结算发票（settlement_invoices / settlement_invoice_lines），每月1号 6:30 开具上月发票
 --
 -- 六、注意事项:
 --   1. 同一店铺的所有订单数据都在同一组分表中，保证关联查询高效
@@ -1317,3 +2101,5 @@ SET FOREIGN_KEY_CHECKS = 1;
 --   4. account_transactions 按 admin_id 分表，同一用户的流水在同一表中
 --   5. 平台级统计查询优先使用汇总表，避免遍历分表
 --   6. 充值（预付款/保证金）无需审核，直接入账；仅提现需要审核流程
+--   7. collection_accounts / payout_batch_items 的账户号码、收款人及 shop_authorizations 的令牌为字段级加密存储
+--      （enc:v1:<主密钥ID>:...），主密钥轮换后执行 go run ./keyrotate 重新加密存量数据